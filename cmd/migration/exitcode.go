@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/migration"
+)
+
+// Exit codes, so automation can tell failure kinds apart.
+const (
+	exitSuccess     = 0
+	exitFailure     = 1 // Any failure not covered below
+	exitConfigError = 2 // Invalid flags or configuration
+	exitSourceError = 3 // MariaDB unreachable, or the export from it failed
+	exitS3Error     = 4 // S3 unreachable, or the SQL file upload or sample verify failed
+	exitSQLFailure  = 5 // LOAD DATA statements failed or only partly ran (-execute-sql)
+	exitTargetError = 6 // Aurora or Secrets Manager unreachable, or the Aurora table schema is incompatible
+	exitTimeBudget  = 7 // -max-runtime ran out before every segment was exported (re-run with -resume)
+)
+
+// exitCodeDescriptions explains each exit code in the summary output.
+var exitCodeDescriptions = map[int]string{
+	exitSuccess:     "success",
+	exitFailure:     "failure",
+	exitConfigError: "configuration error",
+	exitSourceError: "source MariaDB error",
+	exitS3Error:     "S3 error",
+	exitSQLFailure:  "SQL execution failed or partially failed",
+	exitTargetError: "Aurora or Secrets Manager error",
+	exitTimeBudget:  "time budget exhausted",
+}
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so the process exits with code if it fails the run.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// kindExitCodes maps error kinds to exit codes, for errors without an attached exit code.
+var kindExitCodes = map[error]int{
+	errs.ErrConfig:        exitConfigError,
+	errs.ErrSourceConnect: exitSourceError,
+	errs.ErrSourceQuery:   exitSourceError,
+	errs.ErrUploadFailed:  exitS3Error,
+	errs.ErrTargetConnect: exitTargetError,
+	errs.ErrSQLExec:       exitSQLFailure,
+}
+
+// stepExitCodes maps the failed step of a migration.Run to exit codes, for errors without an attached exit code or kind.
+var stepExitCodes = map[string]int{
+	migration.StepSchemaCheck:  exitTargetError,
+	migration.StepSince:        exitTargetError,
+	migration.StepExport:       exitSourceError,
+	migration.StepCoverage:     exitSourceError,
+	migration.StepManifest:     exitS3Error,
+	migration.StepSQLUpload:    exitS3Error,
+	migration.StepSampleVerify: exitS3Error,
+	migration.StepOrphans:      exitS3Error,
+	migration.StepExecute:      exitSQLFailure,
+	migration.StepAuditUpload:  exitS3Error,
+}
+
+// exitCode returns the exit code attached to err, else the one of its kind (errs.KindOf), else the one
+// of its failed migration.Run step, exitFailure if it has none of these, or exitSuccess for nil.
+// The kind goes first: a step such as StepExport also fails on S3 uploads and invalid settings.
+func exitCode(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if code, ok := kindExitCodes[errs.KindOf(err)]; ok {
+		return code
+	}
+	var stepErr *migration.StepError
+	if errors.As(err, &stepErr) {
+		if code, ok := stepExitCodes[stepErr.Step]; ok {
+			return code
+		}
+	}
+	return exitFailure
+}
+
+// preflightExitCode returns the exit code for a failed preflight: the first failed dependency
+// in the order MariaDB, S3, then Secrets Manager and Aurora.
+func preflightExitCode(report *migration.PreflightReport) int {
+	code := exitSuccess
+	for _, check := range report.Failed() {
+		var checkCode int
+		switch check.Name {
+		case "mariadb":
+			checkCode = exitSourceError
+		case "s3":
+			checkCode = exitS3Error
+		default:
+			checkCode = exitTargetError
+		}
+		if code == exitSuccess || checkCode < code {
+			code = checkCode
+		}
+	}
+	return code
+}
+
+// exit prints the exit code with the scheme, then exits with it.
+func exit(code int) {
+	fmt.Printf("\nExit code: %d (%s)\n", code, exitCodeDescriptions[code])
+	fmt.Printf("Exit codes: 0 success, 1 other failure, 2 configuration, 3 source MariaDB, 4 S3, 5 SQL execution, 6 Aurora or Secrets Manager, 7 time budget exhausted\n")
+	os.Exit(code)
+}
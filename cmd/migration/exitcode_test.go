@@ -0,0 +1,158 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/migration"
+	"github.com/netSkope/fis-migration-tool/internal/notify"
+)
+
+// runAsBinaryEnv makes the test binary run main() instead of the tests (see TestMain).
+const runAsBinaryEnv = "FIS_MIGRATION_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runAsBinaryEnv) == "1" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runBinary runs the migration binary (this test binary running main) with args.
+// Returns its exit code and combined output.
+func runBinary(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runAsBinaryEnv+"=1")
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(output)
+	}
+	if err != nil {
+		t.Fatalf("failed to run binary: %v", err)
+	}
+	return 0, string(output)
+}
+
+func TestExitCodes_Binary(t *testing.T) {
+	logDir := t.TempDir()
+	outputDir := filepath.Join(t.TempDir(), "out")
+	// Nothing listens on port 1, so MariaDB is unreachable
+	unreachable := []string{"-config-file", "does-not-exist.yaml", "-log-dir", logDir, "-tenant-id", "1",
+		"-mariadb-host", "127.0.0.1", "-mariadb-port", "1", "-output-dir", outputDir}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{"missing tenant", []string{"-config-file", "does-not-exist.yaml", "-log-dir", logDir, "-mariadb-host", "localhost", "-output-dir", outputDir}, exitConfigError},
+		{"invalid flag value", append(append([]string{}, unreachable...), "-segment-order", "random"), exitConfigError},
+		{"invalid extra clauses", append(append([]string{}, unreachable...), "-load-extra-clauses", "DROP TABLE x"), exitConfigError},
+		{"MariaDB unreachable at preflight", unreachable, exitSourceError},
+		{"MariaDB unreachable during export", append(append([]string{}, unreachable...), "-skip-preflight"), exitSourceError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, output := runBinary(t, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d; output:\n%s", code, tt.wantCode, output)
+			}
+			if want := fmt.Sprintf("Exit code: %d (%s)", tt.wantCode, exitCodeDescriptions[tt.wantCode]); !strings.Contains(output, want) {
+				t.Errorf("output should document %q, got:\n%s", want, output)
+			}
+		})
+	}
+}
+
+func TestNotifyWebhook_Failure(t *testing.T) {
+	payloads := make(chan notify.Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notify.Payload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid webhook payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	// Nothing listens on port 1, so the preflight fails on MariaDB
+	code, output := runBinary(t, "-config-file", "does-not-exist.yaml", "-log-dir", t.TempDir(), "-tenant-id", "42",
+		"-mariadb-host", "127.0.0.1", "-mariadb-port", "1", "-output-dir", t.TempDir(), "-notify-webhook", server.URL)
+	if code != exitSourceError {
+		t.Fatalf("exit code = %d, want %d; output:\n%s", code, exitSourceError, output)
+	}
+
+	select {
+	case payload := <-payloads:
+		if payload.Status != "failure" || payload.ExitCode != exitSourceError || !strings.Contains(payload.Error, "mariadb") {
+			t.Errorf("unexpected failure payload %+v", payload)
+		}
+		if len(payload.TenantIDs) != 1 || payload.TenantIDs[0] != 42 || len(payload.Tables) != 1 || payload.Tables[0] != "fis_aggr" {
+			t.Errorf("payload tenants %v tables %v, want [42] [fis_aggr]", payload.TenantIDs, payload.Tables)
+		}
+	default:
+		t.Fatal("no webhook notification was sent")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitSuccess},
+		{"unclassified", errors.New("boom"), exitFailure},
+		{"attached", withExitCode(exitS3Error, errors.New("upload failed")), exitS3Error},
+		{"wrapped", fmt.Errorf("tenant 1: %w", withExitCode(exitSQLFailure, errors.New("load failed"))), exitSQLFailure},
+		{"kind", fmt.Errorf("tenant 1: %w", errs.Wrap(errs.ErrUploadFailed, errors.New("upload failed"))), exitS3Error},
+		{"config kind", errs.Wrap(errs.ErrConfig, errors.New("invalid concurrency budget")), exitConfigError},
+		{"attached wins over kind", withExitCode(exitSourceError, errs.Wrap(errs.ErrUploadFailed, errors.New("part failed"))), exitSourceError},
+		{"step", fmt.Errorf("tenant 1: %w", &migration.StepError{Step: migration.StepExecute, Err: errors.New("load failed")}), exitSQLFailure},
+		{"step without kind", &migration.StepError{Step: migration.StepExport, Err: errors.New("cursor stuck")}, exitSourceError},
+		{"kind wins over step: upload", &migration.StepError{Step: migration.StepExport, Err: errs.Wrap(errs.ErrUploadFailed, errors.New("part failed"))}, exitS3Error},
+		{"kind wins over step: config", fmt.Errorf("tenant 1: %w", &migration.StepError{Step: migration.StepExport, Err: errs.Wrap(errs.ErrConfig, errors.New("invalid compression"))}), exitConfigError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreflightExitCode(t *testing.T) {
+	failed := errors.New("unreachable")
+	tests := []struct {
+		name   string
+		checks []migration.PreflightCheck
+		want   int
+	}{
+		{"s3", []migration.PreflightCheck{{Name: "mariadb"}, {Name: "s3", Err: failed}}, exitS3Error},
+		{"mariadb before s3", []migration.PreflightCheck{{Name: "mariadb", Err: failed}, {Name: "s3", Err: failed}}, exitSourceError},
+		{"aurora", []migration.PreflightCheck{{Name: "mariadb"}, {Name: "s3"}, {Name: "aurora", Err: failed}}, exitTargetError},
+		{"secretsmanager", []migration.PreflightCheck{{Name: "secretsmanager", Err: failed}, {Name: "aurora", Err: failed}}, exitTargetError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preflightExitCode(&migration.PreflightReport{Checks: tt.checks}); got != tt.want {
+				t.Errorf("preflightExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
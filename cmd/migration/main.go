@@ -3,131 +3,757 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
 	fislog "github.com/netSkope/fis-migration-tool/internal/log"
 	"github.com/netSkope/fis-migration-tool/internal/migration"
+	"github.com/netSkope/fis-migration-tool/internal/notify"
 	"github.com/netSkope/fis-migration-tool/internal/s3"
-	"github.com/netSkope/fis-migration-tool/internal/segment"
 	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
 	"go.uber.org/zap"
 )
 
 func main() {
+	start := time.Now()
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0) // Usage already printed
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
+		exit(exitConfigError)
 	}
 
-	// Initialize logger
-	logger, err := fislog.NewLogger("/tmp", "migration", false, false)
+	// Initialize logger (known passwords and AWS keys are scrubbed from all log output)
+	logger, err := fislog.NewLogger(cfg.LogDir, "migration", cfg.LogLevel, cfg.LogStdout, cfg.Secrets()...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
-		os.Exit(1)
+		exit(exitFailure)
 	}
 
-	logger.Info("Starting migration tool",
-		zap.Int("tenant_id", cfg.TenantID),
-		zap.String("table_name", cfg.TableName))
+	// Validate extra LOAD DATA clauses before exporting so a typo doesn't waste the export
+	if _, err := sqlgen.ParseLoadExtraClauses(cfg.LoadExtraClauses); err != nil {
+		logger.Error("Invalid load-extra-clauses", zap.Error(err))
+		notifyAndExit(cfg, logger, start, nil, exitConfigError, err)
+	}
 
-	// Generate segments
-	segments, err := segment.SegmentHashSpace(cfg.Segments)
-	if err != nil {
-		logger.Error("Failed to generate segments", zap.Error(err))
-		os.Exit(1)
+	// SQL preview: the LOAD DATA statements a run would generate, no export, upload or database connection
+	if cfg.PreviewSQL {
+		if err := runPreviewSQL(cfg, os.Stdout, logger); err != nil {
+			logger.Error("SQL preview failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
 	}
 
-	logger.Info("Generated segments",
-		zap.Int("count", len(segments)),
-		zap.Int("max_parallel", cfg.MaxParallelSegs))
+	// Debugging aid: dump one row, no segmentation or upload
+	if cfg.SingleHash != "" {
+		if err := runSingleHash(cfg, logger); err != nil {
+			logger.Error("Single hash export failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
+	}
 
-	// Process segments (export + upload)
-	csvFiles, err := migration.ProcessSegments(segments, cfg, logger)
-	if err != nil {
-		logger.Error("Failed to process segments", zap.Error(err))
-		os.Exit(1)
+	// Distribution report: row count per segment, nothing exported
+	if cfg.Report {
+		if err := runReport(cfg, logger); err != nil {
+			logger.Error("Segment report failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
+	}
+
+	// Duplicate check: hashes held by several rows, nothing exported
+	if cfg.DetectDuplicates {
+		if err := runDetectDuplicates(cfg, logger); err != nil {
+			logger.Error("Duplicate check failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
+	}
+
+	// Schema dump: the source DDL to bootstrap the target, no rows exported
+	if cfg.DumpSchema {
+		if err := runDumpSchema(cfg, logger); err != nil {
+			logger.Error("Schema dump failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
 	}
 
-	logger.Info("All segments processed",
-		zap.Int("total_csv_files", len(csvFiles)))
+	// Manifest verify: check the loaded Aurora table against an earlier run's manifest, nothing exported
+	if cfg.VerifyManifest {
+		if err := runVerifyManifest(cfg, logger); err != nil {
+			logger.Error("Manifest verify failed", zap.Error(err))
+			exit(exitCode(err))
+		}
+		return
+	}
+
+	// Check every dependency up front so one error names all that are unreachable
+	if !cfg.SkipPreflight {
+		report := migration.Preflight(cfg, logger)
+		if !cfg.SQLOnlyStdout() {
+			printPreflightReport(report)
+		}
+		if err := report.Err(); err != nil {
+			logger.Error("Preflight failed, use -skip-preflight to run anyway", zap.Error(err))
+			notifyAndExit(cfg, logger, start, nil, preflightExitCode(report), err)
+		}
+	}
+
+	tenantIDs := cfg.TenantIDs
+	if len(tenantIDs) == 0 {
+		tenantIDs = []int{cfg.TenantID}
+	}
 
-	// Generate SQL file and upload to S3
-	s3Uploader, err := s3.NewUploader(cfg, logger)
+	// -max-runtime: no segment is dispatched once the wall-clock budget is about to run out
+	ctx := context.Background()
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(cfg.MaxRuntime))
+		defer cancel()
+	}
+
+	results := runTenants(ctx, tenantIDs, cfg, logger, runMigration)
+
+	if runs := countRuns(tenantIDs, cfg); runs > 1 && !cfg.SQLOnlyStdout() {
+		printAggregateSummary(results, runs)
+	}
+
+	// The first failed run decides the exit code
+	for _, result := range results {
+		if result.Err != nil {
+			notifyAndExit(cfg, logger, start, results, exitCode(result.Err), result.Err)
+		}
+	}
+
+	logger.Info("Migration completed successfully",
+		zap.Int("tenants", len(results)))
+	notifyAndExit(cfg, logger, start, results, exitSuccess, nil)
+}
+
+// notifyAndExit posts the outcome to -notify-webhook (if set), then exits with code.
+// err is the failure (nil on success); results holds the runs done so far.
+func notifyAndExit(cfg *config.Config, logger *zap.Logger, start time.Time, results []tenantResult, code int, err error) {
+	if cfg.NotifyWebhook != "" {
+		tenantIDs := cfg.TenantIDs
+		if len(tenantIDs) == 0 {
+			tenantIDs = []int{cfg.TenantID}
+		}
+		totalRows := 0
+		for _, result := range results {
+			if result.Result != nil {
+				totalRows += result.Result.TotalRows
+			}
+		}
+		payload := notify.NewPayload(tenantIDs, migrationTables(cfg), totalRows, time.Since(start), code, err)
+		notify.Send(cfg.NotifyWebhook, payload, logger)
+	}
+	exit(code)
+}
+
+// runSingleHash prints the tenant's row with -single-hash as CSV, or writes it to -output-dir.
+func runSingleHash(cfg *config.Config, logger *zap.Logger) error {
+	exp, err := exporter.NewExporter(cfg, logger)
 	if err != nil {
-		logger.Error("Failed to create S3 uploader for SQL", zap.Error(err))
-		os.Exit(1)
+		return withExitCode(exitSourceError, fmt.Errorf("failed to create exporter: %w", err))
 	}
+	defer exp.Close()
 
-	sqlS3Key, err := sqlgen.GenerateAndUploadSQL(csvFiles, cfg, s3Uploader, logger)
+	row, err := exp.ExportSingleHash(cfg.SingleHash)
+	if err != nil {
+		return withExitCode(exitSourceError, err)
+	}
+	if row == nil {
+		return fmt.Errorf("tenant %d has no row with hash %s in %s", cfg.TenantID, cfg.SingleHash, cfg.TableName)
+	}
+	data, err := exp.FormatCSV([]exporter.Row{*row})
 	if err != nil {
-		logger.Error("Failed to generate and upload SQL file", zap.Error(err))
-		os.Exit(1)
+		return err
 	}
 
-	logger.Info("SQL file generated and uploaded to S3",
-		zap.String("s3_key", sqlS3Key))
+	if cfg.OutputDir == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file := filepath.Join(cfg.OutputDir, fmt.Sprintf("tenant-%d-hash-%s.csv", cfg.TenantID, cfg.SingleHash))
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	fmt.Printf("Row written to %s\n", file)
+	return nil
+}
 
-	// Execute SQL if requested
-	if cfg.ExecuteSQL {
-		logger.Info("Executing LOAD DATA FROM S3 on Aurora MySQL")
+// runDumpSchema writes the CREATE TABLE of each table to <output-dir>/<table>.sql and/or uploads it to
+// <s3-prefix>/schema/<table>.sql. With -dump-schema-aurora the DDL is translated for Aurora MySQL first.
+func runDumpSchema(cfg *config.Config, logger *zap.Logger) error {
+	var uploader *s3.Uploader
+	if cfg.S3Bucket != "" {
+		var err error
+		if uploader, err = s3.NewUploader(cfg, logger); err != nil {
+			return fmt.Errorf("failed to create S3 uploader: %w", err)
+		}
+	}
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	for _, table := range migrationTables(cfg) {
+		tableCfg := *cfg
+		tableCfg.TableName = table
 
-		sqlStatements, err := sqlgen.GenerateLoadDataSQL(csvFiles, cfg)
+		exp, err := exporter.NewExporter(&tableCfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create exporter: %w", err)
+		}
+		ddl, err := exp.ShowCreateTable()
+		exp.Close()
 		if err != nil {
-			logger.Error("Failed to generate SQL statements", zap.Error(err))
-			os.Exit(1)
+			return errs.Wrap(errs.ErrSourceQuery, err)
+		}
+		if cfg.DumpSchemaAurora {
+			ddl = exporter.TranslateDDLForAurora(ddl)
 		}
+		data := []byte(ddl + ";\n")
 
-		if err := sqlgen.ExecuteLoadDataSQL(sqlStatements, cfg, logger); err != nil {
-			logger.Error("Failed to execute SQL statements", zap.Error(err))
-			// Don't exit on error - log it but continue
-			logger.Warn("Some SQL statements may have failed, check logs above")
-		} else {
-			logger.Info("All SQL statements executed successfully")
+		// The upload reads from a file: the -output-dir copy, or a temporary one
+		file := filepath.Join(cfg.OutputDir, table+".sql")
+		if cfg.OutputDir == "" {
+			tmp, err := os.CreateTemp("", table+"-*.sql")
+			if err != nil {
+				return fmt.Errorf("failed to create temp file: %w", err)
+			}
+			tmp.Close()
+			file = tmp.Name()
+			defer os.Remove(file)
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		if cfg.OutputDir != "" {
+			fmt.Printf("Schema of %s written to %s\n", table, file)
+		}
+
+		if uploader != nil {
+			s3Key := fmt.Sprintf("%s/schema/%s.sql", cfg.S3Prefix, table)
+			if err := uploader.UploadFileWithRetry(file, s3Key); err != nil {
+				return fmt.Errorf("failed to upload schema of %s: %w", table, err)
+			}
+			fmt.Printf("Schema of %s uploaded to s3://%s/%s\n", table, cfg.S3Bucket, s3Key)
+		}
+		logger.Info("Dumped source table schema",
+			zap.String("table", table),
+			zap.Bool("aurora", cfg.DumpSchemaAurora))
+	}
+	return nil
+}
+
+// runVerifyManifest checks each tenant's tables in Aurora against their -manifest (-verify-manifest).
+// Fails if any segment's rows differ from the manifest.
+func runVerifyManifest(cfg *config.Config, logger *zap.Logger) error {
+	var store migration.ManifestStore
+	if cfg.OutputDir == "" {
+		uploader, err := s3.NewUploader(cfg, logger)
+		if err != nil {
+			return withExitCode(exitS3Error, fmt.Errorf("failed to create S3 client for manifest: %w", err))
+		}
+		store = uploader
+	}
+
+	tenantIDs := cfg.TenantIDs
+	if len(tenantIDs) == 0 {
+		tenantIDs = []int{cfg.TenantID}
+	}
+
+	mismatched := 0
+	for _, tenantID := range tenantIDs {
+		tenantBase := cfg.ForTenant(tenantID)
+		for _, table := range migrationTables(tenantBase) {
+			tenantCfg := *tenantBase
+			tenantCfg.TableName = table
+
+			report, err := migration.VerifyManifest(&tenantCfg, store, logger)
+			if err != nil {
+				return err
+			}
+			printManifestReport(&tenantCfg, report)
+			mismatched += len(report.Mismatched)
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("verify manifest: %d segment(s) in Aurora differ from the manifest", mismatched)
+	}
+	return nil
+}
+
+// printManifestReport prints the -verify-manifest result, listing every segment whose rows differ.
+func printManifestReport(cfg *config.Config, report *migration.ManifestReport) {
+	if len(report.Mismatched) == 0 {
+		fmt.Printf("Verify manifest: tenant %d %s, all %d segments match the manifest\n", cfg.TenantID, cfg.TableName, report.Segments)
+		return
+	}
+	fmt.Printf("Verify manifest: tenant %d %s, %d of %d segments differ from the manifest:\n",
+		cfg.TenantID, cfg.TableName, len(report.Mismatched), report.Segments)
+	for _, m := range report.Mismatched {
+		fmt.Printf("  segment %d (%s): manifest %d rows checksum %s, Aurora %d rows checksum %s\n",
+			m.Segment.Index, m.Segment.Segment().Label(),
+			m.Segment.Rows, m.Segment.Checksum, m.TargetRows, m.TargetChecksum)
+	}
+}
+
+// runReport prints the row count of every segment for each tenant's tables (-report), without exporting.
+func runReport(cfg *config.Config, logger *zap.Logger) error {
+	tenantIDs := cfg.TenantIDs
+	if len(tenantIDs) == 0 {
+		tenantIDs = []int{cfg.TenantID}
+	}
+
+	for _, tenantID := range tenantIDs {
+		tenantBase := cfg.ForTenant(tenantID)
+		for _, table := range migrationTables(tenantBase) {
+			tenantCfg := *tenantBase
+			tenantCfg.TableName = table
+
+			segments, err := migration.GenerateSegments(&tenantCfg, logger)
+			if err != nil {
+				return err
+			}
+			exp, err := exporter.NewExporter(&tenantCfg, logger)
+			if err != nil {
+				return withExitCode(exitSourceError, fmt.Errorf("failed to create exporter: %w", err))
+			}
+			report, err := migration.CountSegments(segments, exp, tenantCfg.MaxParallelSegs, logger)
+			exp.Close()
+			if err != nil {
+				return withExitCode(exitSourceError, err)
+			}
+			printDistributionReport(&tenantCfg, report)
 		}
 	}
+	return nil
+}
+
+// runDetectDuplicates prints the duplicate hashes of every segment of each tenant and table.
+func runDetectDuplicates(cfg *config.Config, logger *zap.Logger) error {
+	tenantIDs := cfg.TenantIDs
+	if len(tenantIDs) == 0 {
+		tenantIDs = []int{cfg.TenantID}
+	}
+
+	for _, tenantID := range tenantIDs {
+		tenantBase := cfg.ForTenant(tenantID)
+		for _, table := range migrationTables(tenantBase) {
+			tenantCfg := *tenantBase
+			tenantCfg.TableName = table
 
-	// Print summary
+			segments, err := migration.GenerateSegments(&tenantCfg, logger)
+			if err != nil {
+				return err
+			}
+			exp, err := exporter.NewExporter(&tenantCfg, logger)
+			if err != nil {
+				return withExitCode(exitSourceError, fmt.Errorf("failed to create exporter: %w", err))
+			}
+			report, err := migration.DetectDuplicates(segments, exp, tenantCfg.MaxParallelSegs, logger)
+			exp.Close()
+			if err != nil {
+				return withExitCode(exitSourceError, err)
+			}
+			printDuplicateReport(&tenantCfg, report)
+		}
+	}
+	return nil
+}
+
+// runPreviewSQL writes to w the LOAD DATA statements each tenant and table would load, one per segment's CSV file,
+// preceded by a comment naming the tenant and table. The CSV files are the ones an export would write,
+// of which segments without rows are skipped in a real run.
+func runPreviewSQL(cfg *config.Config, w io.Writer, logger *zap.Logger) error {
+	tenantIDs := cfg.TenantIDs
+	if len(tenantIDs) == 0 {
+		tenantIDs = []int{cfg.TenantID}
+	}
+
+	for _, tenantID := range tenantIDs {
+		tenantBase := cfg.ForTenant(tenantID)
+		for _, table := range migrationTables(tenantBase) {
+			tenantCfg := *tenantBase
+			tenantCfg.TableName = table
+
+			segments, err := migration.GenerateSegments(&tenantCfg, logger)
+			if err != nil {
+				return err
+			}
+			csvFiles, err := exporter.PlannedCSVFiles(&tenantCfg, segments)
+			if err != nil {
+				return err
+			}
+			sqlStatements, err := sqlgen.GenerateLoadDataSQL(csvFiles, &tenantCfg)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "-- tenant %d, %s: %d statements\n\n", tenantID, table, len(sqlStatements)); err != nil {
+				return fmt.Errorf("failed to write SQL: %w", err)
+			}
+			if err := sqlgen.WriteSQL(w, sqlStatements); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printDistributionReport prints the per-segment row counts with min/max/avg and any hotspots.
+func printDistributionReport(cfg *config.Config, report *migration.DistributionReport) {
+	fmt.Printf("\n=== Segment Report: tenant %d, %s ===\n", cfg.TenantID, cfg.TableName)
+	fmt.Printf("%-8s %-20s %12s\n", "Segment", "Range", "Rows")
+	for _, count := range report.Counts {
+		start, end := count.Segment.Bounds()
+		fmt.Printf("%-8d %-20s %12d\n", count.Segment.Index, start+"-"+end, count.Rows)
+	}
+	fmt.Printf("Segments: %d\n", len(report.Counts))
+	fmt.Printf("Total rows: %d\n", report.Total)
+	fmt.Printf("Min: %d, max: %d, avg: %.1f\n", report.Min, report.Max, report.Mean)
+	if len(report.Hotspots) > 0 {
+		fmt.Printf("WARNING: %d segment(s) hold more than %dx the mean:", len(report.Hotspots), migration.SkewFactor)
+		for _, count := range report.Hotspots {
+			fmt.Printf(" %d (%d rows)", count.Segment.Index, count.Rows)
+		}
+		fmt.Printf("\nConsider increasing -segments to split the hotspots\n")
+	}
+	fmt.Printf("==========================\n")
+}
+
+// printDuplicateReport prints the duplicate hashes of every segment holding any.
+func printDuplicateReport(cfg *config.Config, report *migration.DuplicateReport) {
+	fmt.Printf("\n=== Duplicate Hashes: tenant %d, %s ===\n", cfg.TenantID, cfg.TableName)
+	if len(report.Segments) > 0 {
+		fmt.Printf("%-8s %-20s %-40s %8s\n", "Segment", "Range", "Hash", "Rows")
+	}
+	for _, segDuplicates := range report.Segments {
+		start, end := segDuplicates.Segment.Bounds()
+		for _, duplicate := range segDuplicates.Hashes {
+			fmt.Printf("%-8d %-20s %-40s %8d\n", segDuplicates.Segment.Index, start+"-"+end, duplicate.Hash, duplicate.Rows)
+		}
+		if segDuplicates.Truncated {
+			fmt.Printf("%-8d (more than %d duplicate hashes, only the first are listed)\n", segDuplicates.Segment.Index, exporter.MaxDuplicateHashes)
+		}
+	}
+	fmt.Printf("Segments checked: %d\n", report.Checked)
+	fmt.Printf("Duplicate hashes: %d in %d segment(s)\n", report.Hashes, len(report.Segments))
+	if report.Hashes > 0 {
+		fmt.Printf("WARNING: the target's unique (tenantid, hash) key keeps one row per hash, %d row(s) won't load\n", report.Extra)
+		fmt.Printf("Restore the unique (tenantid, hash) constraint or deduplicate the rows before migrating\n")
+	}
+	fmt.Printf("==========================\n")
+}
+
+// migrationResult summarizes a completed single-tenant migration.
+type migrationResult struct {
+	TotalRows int
+	CSVFiles  int
+	SQLS3Key  string
+}
+
+// tenantResult is the outcome of migrating one tenant's table in a batch.
+type tenantResult struct {
+	TenantID int
+	Table    string
+	Result   *migrationResult
+	Err      error
+}
+
+// migrateFunc runs the full segment/export/SQL flow for the tenant in cfg, dispatching segments until ctx is done.
+type migrateFunc func(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*migrationResult, error)
+
+// migrationTables returns the tables to migrate: -tables if set, otherwise -table-name.
+func migrationTables(cfg *config.Config) []string {
+	if len(cfg.Tables) > 0 {
+		return cfg.Tables
+	}
+	return []string{cfg.TableName}
+}
+
+// countRuns returns the number of tenant/table migrations of a batch. A tenant with a table_name override migrates one table.
+func countRuns(tenantIDs []int, cfg *config.Config) int {
+	runs := 0
+	for _, tenantID := range tenantIDs {
+		runs += len(migrationTables(cfg.ForTenant(tenantID)))
+	}
+	return runs
+}
+
+// runTenants migrates each tenant's tables in order, each with its own copy of cfg
+// and the tenant's overrides from the config file's tenants list.
+// A failed run doesn't stop the rest unless cfg.FailFast is set, or it ran out of -max-runtime.
+func runTenants(ctx context.Context, tenantIDs []int, cfg *config.Config, logger *zap.Logger, migrate migrateFunc) []tenantResult {
+	totalRuns := countRuns(tenantIDs, cfg)
+
+	var results []tenantResult
+	for i, tenantID := range tenantIDs {
+		if len(tenantIDs) > 1 {
+			logger.Info("Migrating tenant",
+				zap.Int("tenant_id", tenantID),
+				zap.Int("tenant", i+1),
+				zap.Int("total_tenants", len(tenantIDs)))
+		}
+
+		tenantBase := cfg.ForTenant(tenantID)
+		tables := migrationTables(tenantBase)
+		for j, table := range tables {
+			tenantCfg := *tenantBase
+			tenantCfg.TableName = table
+
+			if len(tables) > 1 {
+				logger.Info("Migrating table",
+					zap.Int("tenant_id", tenantID),
+					zap.String("table_name", table),
+					zap.Int("table", j+1),
+					zap.Int("total_tables", len(tables)))
+			}
+
+			result, err := migrate(ctx, &tenantCfg, logger)
+			results = append(results, tenantResult{TenantID: tenantID, Table: table, Result: result, Err: err})
+			if err != nil {
+				logger.Error("Migration failed",
+					zap.Int("tenant_id", tenantID),
+					zap.String("table_name", table),
+					zap.Error(err))
+				if errors.Is(err, migration.ErrTimeBudgetExhausted) {
+					logger.Warn("Stopping, time budget exhausted (-max-runtime)",
+						zap.Int("remaining_runs", totalRuns-len(results)))
+					return results
+				}
+				if errors.Is(err, errs.ErrConfig) {
+					// Every tenant and table shares the configuration, the rest would fail the same way
+					logger.Warn("Stopping at configuration error",
+						zap.Int("remaining_runs", totalRuns-len(results)))
+					return results
+				}
+				if cfg.FailFast {
+					logger.Warn("Stopping at first failed migration (-fail-fast)",
+						zap.Int("remaining_runs", totalRuns-len(results)))
+					return results
+				}
+			}
+		}
+	}
+	return results
+}
+
+// printPreflightReport prints each dependency check with its latency.
+func printPreflightReport(report *migration.PreflightReport) {
+	fmt.Printf("\n=== Preflight ===\n")
+	for _, check := range report.Checks {
+		if check.Err != nil {
+			fmt.Printf("%-15s FAIL (%s): %v\n", check.Name, check.Latency.Round(time.Millisecond), check.Err)
+			continue
+		}
+		fmt.Printf("%-15s ok (%s)\n", check.Name, check.Latency.Round(time.Millisecond))
+	}
+	fmt.Printf("=================\n")
+}
+
+// printAggregateSummary prints totals across all tenant/table runs of a batch migration.
+func printAggregateSummary(results []tenantResult, totalRuns int) {
+	succeeded := 0
 	totalRows := 0
-	for _, csvFile := range csvFiles {
-		totalRows += csvFile.RowCount
+	totalFiles := 0
+	var failed []tenantResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+			continue
+		}
+		succeeded++
+		totalRows += result.Result.TotalRows
+		totalFiles += result.Result.CSVFiles
 	}
 
+	fmt.Printf("\n=== Aggregate Summary ===\n")
+	fmt.Printf("Migrations: %d\n", totalRuns)
+	fmt.Printf("Succeeded: %d\n", succeeded)
+	fmt.Printf("Failed: %d\n", len(failed))
+	if skipped := totalRuns - len(results); skipped > 0 {
+		fmt.Printf("Not attempted (fail-fast or -max-runtime): %d\n", skipped)
+	}
+	fmt.Printf("Total rows exported: %d\n", totalRows)
+	fmt.Printf("Total CSV files: %d\n", totalFiles)
+	for _, result := range failed {
+		fmt.Printf("  tenant %d table %s: %v\n", result.TenantID, result.Table, result.Err)
+	}
+	fmt.Printf("=======================\n")
+}
+
+// runMigration runs the full segment/export/SQL flow for a single tenant (migration.Run) and prints its summary.
+func runMigration(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*migrationResult, error) {
+	result, err := migration.Run(ctx, cfg, logger)
+	if result != nil {
+		// With -print-sql -quiet stdout carries only the SQL
+		if result.Coverage != nil && !cfg.SQLOnlyStdout() {
+			printCoverageReport(result.Coverage)
+		}
+		// -print-sql: the uploaded statements on stdout, e.g. for -print-sql -quiet | mysql
+		if cfg.PrintSQL && result.SQLS3Key != "" {
+			sqlStatements, err := sqlgen.GenerateLoadDataSQL(result.CSVFiles, cfg)
+			if err != nil {
+				return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to generate SQL statements: %w", err))
+			}
+			if err := sqlgen.WriteSQL(os.Stdout, sqlStatements); err != nil {
+				return nil, fmt.Errorf("failed to print SQL: %w", err)
+			}
+		}
+		if result.Sample != nil && len(result.Sample.Failed) == 0 && !cfg.SQLOnlyStdout() {
+			fmt.Printf("S3 sample verify: %d object(s) parsed correctly\n", len(result.Sample.Objects))
+		}
+	}
+	if errors.Is(err, migration.ErrTimeBudgetExhausted) {
+		fmt.Printf("\nTime budget exhausted (-max-runtime %s) for tenant %d table %s\n", cfg.MaxRuntime, cfg.TenantID, cfg.TableName)
+		fmt.Printf("Completed segments are checkpointed in %s, re-run with -resume to continue\n", migration.CheckpointPath(cfg))
+		return nil, withExitCode(exitTimeBudget, err)
+	}
+	if result == nil || !result.Completed {
+		return nil, err
+	}
+
+	if !cfg.SQLOnlyStdout() {
+		printMigrationSummary(cfg, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrationResult{
+		TotalRows: result.TotalRows,
+		CSVFiles:  len(result.CSVFiles),
+		SQLS3Key:  result.SQLS3Key,
+	}, nil
+}
+
+// printMigrationSummary prints what a completed run exported and where to, and the results of its checks.
+func printMigrationSummary(cfg *config.Config, result *migration.Result) {
+	csvFiles := result.CSVFiles
 	fmt.Printf("\n=== Migration Summary ===\n")
 	fmt.Printf("Tenant ID: %d\n", cfg.TenantID)
 	fmt.Printf("Table: %s\n", cfg.TableName)
-	fmt.Printf("Total rows exported: %d\n", totalRows)
-	fmt.Printf("Total CSV files: %d\n", len(csvFiles))
-	fmt.Printf("S3 bucket: %s\n", cfg.S3Bucket)
-	fmt.Printf("S3 prefix: %s\n", cfg.S3Prefix)
-	fmt.Printf("SQL file S3 key: %s\n", sqlS3Key)
+	fmt.Printf("Total rows exported: %d\n", result.TotalRows)
+	if cfg.DirectLoad {
+		fmt.Printf("Direct load: rows inserted into %s on %s\n", cfg.TableName, cfg.AuroraHost)
+	} else {
+		fmt.Printf("Total CSV files: %d\n", len(csvFiles))
+		if cfg.LocalOutputOnly() {
+			fmt.Printf("Total bytes written: %s\n", bytesSummary(result.TotalBytes, result.ExportDuration))
+		} else {
+			fmt.Printf("Total bytes uploaded: %s\n", bytesSummary(result.TotalBytes, result.ExportDuration))
+		}
+	}
+	if summary := timingSummary(result.Timing, cfg.DirectLoad); summary != "" {
+		fmt.Printf("Export time by step (summed over segments): %s\n", summary)
+	}
+	if cfg.OutputDir != "" {
+		fmt.Printf("Output directory: %s\n", cfg.OutputDir)
+	}
+	if !cfg.LocalOutputOnly() && !cfg.DirectLoad {
+		fmt.Printf("S3 bucket: %s\n", cfg.S3Bucket)
+		fmt.Printf("S3 prefix: %s\n", cfg.S3Prefix)
+		if cfg.PartitionByDate {
+			fmt.Printf("Date partition: dt=%s\n", cfg.RunDate)
+		}
+		if cfg.TimestampKeys {
+			fmt.Printf("Run prefix: run-%s\n", cfg.RunTimestamp)
+		}
+		if result.SQLS3Key != "" {
+			fmt.Printf("SQL file S3 key: %s\n", result.SQLS3Key)
+		}
+	}
+	for _, location := range result.ManifestLocations {
+		fmt.Printf("Manifest: %s\n", location)
+	}
 
-	// Print CSV file S3 keys
+	// Print CSV file S3 keys (local paths in local-only mode)
 	if len(csvFiles) > 0 {
-		fmt.Printf("\nCSV files uploaded to S3:\n")
+		if cfg.DirectLoad {
+			fmt.Printf("\nSegments loaded into Aurora:\n")
+		} else if cfg.LocalOutputOnly() {
+			fmt.Printf("\nCSV files written locally:\n")
+		} else {
+			fmt.Printf("\nCSV files uploaded to S3:\n")
+		}
 		if len(csvFiles) <= 10 {
 			// Print all if 10 or fewer
 			for i, csvFile := range csvFiles {
-				fmt.Printf("  %d. s3://%s/%s (%d rows)\n", i+1, cfg.S3Bucket, csvFile.S3Key, csvFile.RowCount)
+				fmt.Printf("  %d. %s (%d rows)\n", i+1, csvFileLocation(cfg, csvFile), csvFile.RowCount)
 			}
 		} else {
 			// Print first 5 and last 5 if more than 10
 			for i := 0; i < 5; i++ {
-				fmt.Printf("  %d. s3://%s/%s (%d rows)\n", i+1, cfg.S3Bucket, csvFiles[i].S3Key, csvFiles[i].RowCount)
+				fmt.Printf("  %d. %s (%d rows)\n", i+1, csvFileLocation(cfg, csvFiles[i]), csvFiles[i].RowCount)
 			}
 			fmt.Printf("  ... (%d more files) ...\n", len(csvFiles)-10)
 			for i := len(csvFiles) - 5; i < len(csvFiles); i++ {
-				fmt.Printf("  %d. s3://%s/%s (%d rows)\n", i+1, cfg.S3Bucket, csvFiles[i].S3Key, csvFiles[i].RowCount)
+				fmt.Printf("  %d. %s (%d rows)\n", i+1, csvFileLocation(cfg, csvFiles[i]), csvFiles[i].RowCount)
+			}
+		}
+		if cfg.DetectSourceChanges {
+			var changed []exporter.CSVFile
+			for _, csvFile := range csvFiles {
+				if csvFile.SourceChanged {
+					changed = append(changed, csvFile)
+				}
+			}
+			if len(changed) > 0 {
+				fmt.Printf("\nWARNING: source data changed during export for %d segment(s), consider re-running them:\n", len(changed))
+				for _, csvFile := range changed {
+					fmt.Printf("  segment %d (%s): %s\n",
+						csvFile.Segment.Index, csvFile.Segment.Label(), csvFileLocation(cfg, csvFile))
+				}
+			} else {
+				fmt.Printf("\nSource change detection: no changes detected during export\n")
+			}
+		}
+		if !cfg.LocalOutputOnly() && !cfg.DirectLoad {
+			fmt.Printf("\nTo verify all CSV files in S3:\n")
+			if cfg.S3KeyTemplate != "" {
+				fmt.Printf("  aws s3 ls s3://%s/%s --recursive --region %s\n",
+					cfg.S3Bucket, commonKeyDir(csvFiles), cfg.AWSRegion)
+			} else {
+				fmt.Printf("  aws s3 ls s3://%s/%s/tenant-%d/%s/ --recursive --region %s\n",
+					cfg.S3Bucket, cfg.CSVKeyPrefix(), cfg.TenantID, cfg.TableName, cfg.AWSRegion)
 			}
 		}
-		fmt.Printf("\nTo verify all CSV files in S3:\n")
-		fmt.Printf("  aws s3 ls s3://%s/%s/tenant-%d/%s/ --recursive --region %s\n",
-			cfg.S3Bucket, cfg.S3Prefix, cfg.TenantID, cfg.TableName, cfg.AWSRegion)
 	}
-	if cfg.ExecuteSQL {
+	if cfg.DirectLoad {
+		fmt.Printf("SQL generation: Skipped (-direct-load inserted the rows)\n")
+	} else if cfg.LocalOutputOnly() {
+		fmt.Printf("SQL generation: Skipped (local-only output, no -s3-bucket)\n")
+	} else if cfg.SkipSQLGen {
+		fmt.Printf("SQL generation: Skipped (-skip-sql-gen)\n")
+	} else if cfg.Format == exporter.FormatParquet {
+		fmt.Printf("SQL generation: Skipped (-format parquet, LOAD DATA FROM S3 can't read Parquet)\n")
+	} else if cfg.ExecuteSQL && result.SQLErr != nil {
+		fmt.Printf("SQL execution: FAILED (%v)\n", result.SQLErr)
+	} else if cfg.ExecuteSQL {
 		fmt.Printf("SQL execution: Completed\n")
 	} else {
 		fmt.Printf("SQL execution: Skipped (use -execute-sql to enable)\n")
@@ -138,7 +764,7 @@ func main() {
 			fmt.Printf("The SQL file has been uploaded to S3. To load data into Aurora MySQL:\n")
 			fmt.Printf("\n")
 			fmt.Printf("1. Download SQL file from S3:\n")
-			fmt.Printf("   aws s3 cp s3://%s/%s ./load-data-tenant-%d.sql\n", cfg.S3Bucket, sqlS3Key, cfg.TenantID)
+			fmt.Printf("   aws s3 cp s3://%s/%s ./%s\n", cfg.S3Bucket, result.SQLS3Key, path.Base(result.SQLS3Key))
 			fmt.Printf("\n")
 			fmt.Printf("2. Connect to Aurora MySQL (on EC2 or locally):\n")
 			if cfg.AuroraHost != "" {
@@ -156,9 +782,9 @@ func main() {
 			}
 			fmt.Printf("\n")
 			fmt.Printf("3. Execute SQL file:\n")
-			fmt.Printf("   source ./load-data-tenant-%d.sql\n", cfg.TenantID)
+			fmt.Printf("   source ./%s\n", path.Base(result.SQLS3Key))
 			fmt.Printf("   # OR\n")
-			fmt.Printf("   mysql ... < ./load-data-tenant-%d.sql\n", cfg.TenantID)
+			fmt.Printf("   mysql ... < ./%s\n", path.Base(result.SQLS3Key))
 			fmt.Printf("\n")
 			fmt.Printf("⚠️  IMPORTANT: Aurora MySQL IAM Role Required\n")
 			fmt.Printf("   Before executing SQL, ensure Aurora MySQL cluster has IAM role configured:\n")
@@ -166,13 +792,136 @@ func main() {
 			fmt.Printf("   - IAM role must have S3 read permissions for bucket: %s\n", cfg.S3Bucket)
 			fmt.Printf("   - See README.md for detailed IAM role setup instructions\n")
 			fmt.Printf("   - Error 63985 indicates IAM role is not configured\n")
+			fmt.Printf("   - Without the role, -execute-sql -load-via-local loads the CSVs through this machine instead\n")
 			fmt.Printf("\n")
 			fmt.Printf("=======================\n")
 		}
 	}
+	if result.Orphans != nil {
+		printOrphanReport(cfg, result.Orphans)
+	}
+	if result.Verify != nil {
+		printChecksumReport(result.Verify)
+	}
+	if result.Diff != nil {
+		printDiffReport(result.Diff)
+	}
 	if !cfg.Quiet {
 		fmt.Printf("=======================\n")
 	}
+}
+
+// printOrphanReport prints the -list-orphan-objects result, listing every object the run didn't produce.
+func printOrphanReport(cfg *config.Config, report *migration.OrphanReport) {
+	if len(report.Orphans) == 0 {
+		fmt.Printf("\nOrphan objects: none of the %d object(s) under s3://%s/%s\n", report.Listed, cfg.S3Bucket, report.Prefix)
+		return
+	}
+	action := "not part of this run"
+	if report.Pruned {
+		action = "not part of this run, deleted (-prune)"
+	}
+	fmt.Printf("\nOrphan objects: %d of %d object(s) under s3://%s/%s are %s:\n",
+		len(report.Orphans), report.Listed, cfg.S3Bucket, report.Prefix, action)
+	for _, key := range report.Orphans {
+		fmt.Printf("  %s\n", key)
+	}
+}
+
+// printCoverageReport prints the -validate-coverage result.
+func printCoverageReport(report *migration.CoverageReport) {
+	if report.Delta() == 0 {
+		fmt.Printf("Coverage: %d segments exported all %d rows of the tenant\n", report.Segments, report.Source)
+		return
+	}
+	fmt.Printf("Coverage: MISMATCH, %d segments exported %d rows, the tenant has %d (delta %+d)\n",
+		report.Segments, report.Exported, report.Source, report.Delta())
+	if report.Delta() < 0 {
+		fmt.Printf("  Rows were missed: a segment boundary gap, a failed segment (-continue-on-segment-error), rows skipped by row policies or rows inserted during the export\n")
+	} else {
+		fmt.Printf("  Rows were exported twice or deleted during the export: overlapping segment boundaries or concurrent deletes\n")
+	}
+}
+
+// printChecksumReport prints the -full-verify result, listing every segment whose checksums differ.
+func printChecksumReport(report *exporter.ChecksumReport) {
+	if len(report.Mismatched) == 0 {
+		fmt.Printf("\nFull verify: all %d segments match between source and Aurora\n", len(report.Segments))
+		return
+	}
+	fmt.Printf("\nFull verify: %d of %d segments differ between source and Aurora:\n",
+		len(report.Mismatched), len(report.Segments))
+	for _, c := range report.Mismatched {
+		fmt.Printf("  segment %d (%s): source %d rows checksum %016x, target %d rows checksum %016x\n",
+			c.Segment.Index, c.Segment.Label(),
+			c.Source.Rows, c.Source.Checksum, c.Target.Rows, c.Target.Checksum)
+	}
+}
+
+// printDiffReport prints the -verify-diff result, listing the rows that differ.
+func printDiffReport(report *exporter.DiffReport) {
+	if report.Total() == 0 {
+		fmt.Printf("\nVerify diff: all %d rows in %d segments match between source and Aurora\n", report.SourceRows, report.Segments)
+		return
+	}
+	fmt.Printf("\nVerify diff: %d rows differ between source (%d rows) and Aurora (%d rows): %d missing in Aurora, %d only in Aurora, %d with different content\n",
+		report.Total(), report.SourceRows, report.TargetRows,
+		report.Counts[exporter.DiffMissingInTarget], report.Counts[exporter.DiffExtraInTarget], report.Counts[exporter.DiffContent])
+	for _, d := range report.Diffs {
+		fmt.Printf("  segment %d: %s %s\n", d.Segment.Index, d.Hash, d.Kind)
+	}
+	if report.DiffsCapped {
+		fmt.Printf("  ... and %d more not listed\n", report.Total()-int64(len(report.Diffs)))
+	}
+}
 
-	logger.Info("Migration completed successfully")
+// commonKeyDir returns the longest directory prefix (ending in /) shared by all CSV file keys.
+func commonKeyDir(csvFiles []exporter.CSVFile) string {
+	prefix := csvFiles[0].S3Key
+	for _, csvFile := range csvFiles[1:] {
+		for !strings.HasPrefix(csvFile.S3Key, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix[:strings.LastIndex(prefix, "/")+1]
+}
+
+// bytesSummary describes the bytes a run exported in elapsed: the total and the average throughput.
+// Segments resumed from a checkpoint count neither towards the bytes nor the time.
+func bytesSummary(bytes int64, elapsed time.Duration) string {
+	mb := float64(bytes) / 1e6
+	if elapsed <= 0 {
+		return fmt.Sprintf("%d (%.1f MB)", bytes, mb)
+	}
+	return fmt.Sprintf("%d (%.1f MB in %s, %.2f MB/s)", bytes, mb, elapsed.Round(time.Millisecond), mb/elapsed.Seconds())
+}
+
+// timingSummary describes where the export spent its time: query, serialization and upload (insert with
+// -direct-load), each with its share, or "" if nothing was timed (e.g. all segments resumed from a checkpoint).
+// Segments run in parallel, so the sum can exceed the run's duration.
+func timingSummary(timing exporter.SegmentTiming, directLoad bool) string {
+	total := timing.Query + timing.Serialize + timing.Upload
+	if total <= 0 {
+		return ""
+	}
+	upload := "upload"
+	if directLoad {
+		upload = "insert"
+	}
+	share := func(d time.Duration) string {
+		return fmt.Sprintf("%s (%.0f%%)", d.Round(time.Millisecond), 100*d.Seconds()/total.Seconds())
+	}
+	return fmt.Sprintf("query %s, serialization %s, %s %s", share(timing.Query), share(timing.Serialize), upload, share(timing.Upload))
+}
+
+// csvFileLocation returns where a CSV file was written: its S3 URL, or its local path in local-only mode.
+// With -direct-load there is no file, so it names the loaded segment.
+func csvFileLocation(cfg *config.Config, csvFile exporter.CSVFile) string {
+	if cfg.DirectLoad {
+		return fmt.Sprintf("segment %d (%s)", csvFile.Segment.Index, csvFile.Segment.Label())
+	}
+	if csvFile.S3Key == "" {
+		return csvFile.FilePath
+	}
+	return fmt.Sprintf("s3://%s/%s", cfg.S3Bucket, csvFile.S3Key)
 }
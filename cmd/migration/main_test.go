@@ -0,0 +1,321 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/migration"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRunTenants_TenantIDsFile(t *testing.T) {
+	tenantFile := filepath.Join(t.TempDir(), "tenants.txt")
+	if err := os.WriteFile(tenantFile, []byte("1001\n# comment\n1002\n\n1003\n"), 0644); err != nil {
+		t.Fatalf("failed to write tenant file: %v", err)
+	}
+
+	tenantIDs, err := config.ReadTenantIDsFile(tenantFile)
+	if err != nil {
+		t.Fatalf("ReadTenantIDsFile() error = %v", err)
+	}
+
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name      string
+		failFast  bool
+		wantRuns  []int
+		wantFails int
+	}{
+		{"failure does not abort the rest", false, []int{1001, 1002, 1003}, 1},
+		{"fail-fast stops at first failure", true, []int{1001, 1002}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{TableName: "fis_aggr", FailFast: tt.failFast}
+
+			var runs []int
+			migrate := func(ctx context.Context, tenantCfg *config.Config, logger *zap.Logger) (*migrationResult, error) {
+				runs = append(runs, tenantCfg.TenantID)
+				if tenantCfg.TenantID == 1002 {
+					return nil, fmt.Errorf("export failed")
+				}
+				return &migrationResult{TotalRows: 10, CSVFiles: 1}, nil
+			}
+
+			results := runTenants(context.Background(), tenantIDs, cfg, logger, migrate)
+
+			if fmt.Sprint(runs) != fmt.Sprint(tt.wantRuns) {
+				t.Errorf("expected runs %v, got %v", tt.wantRuns, runs)
+			}
+			if len(results) != len(tt.wantRuns) {
+				t.Errorf("expected %d results, got %d", len(tt.wantRuns), len(results))
+			}
+			fails := 0
+			for _, result := range results {
+				if result.Err != nil {
+					fails++
+				}
+			}
+			if fails != tt.wantFails {
+				t.Errorf("expected %d failures, got %d", tt.wantFails, fails)
+			}
+			// Each run gets its own config copy
+			if cfg.TenantID != 0 {
+				t.Errorf("runTenants should not modify the shared config, got tenant %d", cfg.TenantID)
+			}
+		})
+	}
+}
+
+func TestRunTenants_Tables(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name     string
+		failFast bool
+		wantRuns []string
+	}{
+		{"every tenant migrates every table", false, []string{"1001/fis_aggr", "1001/fis_aggr_v2", "1002/fis_aggr", "1002/fis_aggr_v2"}},
+		{"fail-fast stops at first failed table", true, []string{"1001/fis_aggr", "1001/fis_aggr_v2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{TableName: "fis_aggr", Tables: []string{"fis_aggr", "fis_aggr_v2"}, FailFast: tt.failFast}
+
+			var runs []string
+			migrate := func(ctx context.Context, runCfg *config.Config, logger *zap.Logger) (*migrationResult, error) {
+				runs = append(runs, fmt.Sprintf("%d/%s", runCfg.TenantID, runCfg.TableName))
+				if runCfg.TenantID == 1001 && runCfg.TableName == "fis_aggr_v2" {
+					return nil, fmt.Errorf("export failed")
+				}
+				return &migrationResult{TotalRows: 10, CSVFiles: 1}, nil
+			}
+
+			results := runTenants(context.Background(), []int{1001, 1002}, cfg, logger, migrate)
+
+			if fmt.Sprint(runs) != fmt.Sprint(tt.wantRuns) {
+				t.Errorf("expected runs %v, got %v", tt.wantRuns, runs)
+			}
+			if len(results) != len(tt.wantRuns) {
+				t.Fatalf("expected %d results, got %d", len(tt.wantRuns), len(results))
+			}
+			if results[1].Table != "fis_aggr_v2" || results[1].Err == nil {
+				t.Errorf("expected second result to be the failed fis_aggr_v2 run, got %+v", results[1])
+			}
+			if cfg.TableName != "fis_aggr" {
+				t.Errorf("runTenants should not modify the shared config, got table %s", cfg.TableName)
+			}
+		})
+	}
+}
+
+func TestRunTenants_TenantOverrides(t *testing.T) {
+	// As loaded from a config file's tenants list, one tenant overriding segments
+	cfg := &config.Config{TableName: "fis_aggr", Segments: 16, BatchSize: 5000,
+		Tenants: []config.TenantOverride{{TenantID: 1001}, {TenantID: 1002, Segments: 64}}}
+
+	runs := make(map[int]*config.Config)
+	migrate := func(ctx context.Context, tenantCfg *config.Config, logger *zap.Logger) (*migrationResult, error) {
+		runs[tenantCfg.TenantID] = tenantCfg
+		return &migrationResult{TotalRows: 10, CSVFiles: 1}, nil
+	}
+
+	results := runTenants(context.Background(), []int{1001, 1002}, cfg, zaptest.NewLogger(t), migrate)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for tenantID, wantSegments := range map[int]int{1001: 16, 1002: 64} {
+		runCfg := runs[tenantID]
+		if runCfg == nil {
+			t.Fatalf("tenant %d was not migrated", tenantID)
+		}
+		if runCfg.Segments != wantSegments || runCfg.BatchSize != 5000 || runCfg.TableName != "fis_aggr" {
+			t.Errorf("tenant %d ran with %d segments, batch size %d, table %s, want %d segments, batch size 5000, table fis_aggr",
+				tenantID, runCfg.Segments, runCfg.BatchSize, runCfg.TableName, wantSegments)
+		}
+	}
+	if cfg.Segments != 16 {
+		t.Errorf("runTenants should not modify the shared config, got %d segments", cfg.Segments)
+	}
+}
+
+func TestRunPreviewSQL(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-mariadb-host", "unreachable.invalid",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-preview-sql"}
+	tests := []struct {
+		name           string
+		args           []string
+		wantStatements int
+		wantContains   []string
+	}{
+		{"segment per statement", []string{"-tenant-id", "1", "-segments", "4"}, 4,
+			[]string{"-- tenant 1, fis_aggr: 4 statements", "LOAD DATA FROM S3 's3://bucket/fis-migration/tenant-1/fis_aggr/tenant-1.fis_aggr.hash-00-40.csv'", "\nIGNORE\n"}},
+		{"replace mode and header", []string{"-tenant-id", "1", "-segments", "2", "-sql-duplicate-mode", "replace", "-csv-header"}, 2,
+			[]string{"\nREPLACE\n", "IGNORE 1 LINES"}},
+		{"every tenant and table", []string{"-tenant-id", "1", "-tables", "fis_aggr,fis_aggr_v2", "-segments", "3"}, 6,
+			[]string{"-- tenant 1, fis_aggr: 3 statements", "-- tenant 1, fis_aggr_v2: 3 statements"}},
+		{"single file", []string{"-tenant-id", "1", "-segments", "8", "-single-file"}, 1,
+			[]string{"tenant-1/fis_aggr/tenant-1.fis_aggr.csv'"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+			if err != nil {
+				t.Fatalf("LoadConfigFromArgs() error = %v", err)
+			}
+			// No database is reachable: the preview must not need one
+			var out strings.Builder
+			if err := runPreviewSQL(cfg, &out, zaptest.NewLogger(t)); err != nil {
+				t.Fatalf("runPreviewSQL() error = %v", err)
+			}
+			if got := strings.Count(out.String(), "LOAD DATA FROM S3"); got != tt.wantStatements {
+				t.Errorf("preview has %d statements, want one per CSV file (%d):\n%s", got, tt.wantStatements, out.String())
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("preview doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunPreviewSQL_TimestampKeys(t *testing.T) {
+	args := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "unreachable.invalid",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-segments", "4", "-timestamp-keys", "-preview-sql"}
+
+	// Two runs of the same migration, a second apart
+	var keys [][]string
+	for i, runTimestamp := range []string{"20240601T120000Z", "20240601T120001Z"} {
+		cfg, err := config.LoadConfigFromArgs(append([]string{}, args...))
+		if err != nil {
+			t.Fatalf("LoadConfigFromArgs() error = %v", err)
+		}
+		cfg.RunTimestamp = runTimestamp
+
+		segments, err := migration.GenerateSegments(cfg, zaptest.NewLogger(t))
+		if err != nil {
+			t.Fatalf("GenerateSegments() error = %v", err)
+		}
+		csvFiles, err := exporter.PlannedCSVFiles(cfg, segments)
+		if err != nil {
+			t.Fatalf("PlannedCSVFiles() error = %v", err)
+		}
+		var out strings.Builder
+		if err := runPreviewSQL(cfg, &out, zaptest.NewLogger(t)); err != nil {
+			t.Fatalf("runPreviewSQL() error = %v", err)
+		}
+
+		// The SQL loads exactly the run's timestamped keys
+		var runKeys []string
+		for _, csvFile := range csvFiles {
+			if !strings.HasPrefix(csvFile.S3Key, "fis-migration/run-"+runTimestamp+"/tenant-1/fis_aggr/") {
+				t.Errorf("run %d: S3 key %q, want it under the run's timestamp", i+1, csvFile.S3Key)
+			}
+			if !strings.Contains(out.String(), "LOAD DATA FROM S3 's3://bucket/"+csvFile.S3Key+"'") {
+				t.Errorf("run %d: SQL doesn't load %s:\n%s", i+1, csvFile.S3Key, out.String())
+			}
+			runKeys = append(runKeys, csvFile.S3Key)
+		}
+		if got := strings.Count(out.String(), "LOAD DATA FROM S3"); got != len(runKeys) {
+			t.Errorf("run %d: SQL has %d statements, want %d", i+1, got, len(runKeys))
+		}
+		keys = append(keys, runKeys)
+	}
+
+	// The second run doesn't overwrite any object of the first
+	first := make(map[string]bool)
+	for _, key := range keys[0] {
+		first[key] = true
+	}
+	for _, key := range keys[1] {
+		if first[key] {
+			t.Errorf("both runs write %s", key)
+		}
+	}
+}
+
+func TestRunTenants_ConfigError(t *testing.T) {
+	cfg := &config.Config{TableName: "fis_aggr"}
+
+	var runs []int
+	migrate := func(ctx context.Context, tenantCfg *config.Config, logger *zap.Logger) (*migrationResult, error) {
+		runs = append(runs, tenantCfg.TenantID)
+		if tenantCfg.TenantID == 1002 {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid concurrency budget"))
+		}
+		return nil, errs.Wrap(errs.ErrSourceConnect, fmt.Errorf("failed to ping database"))
+	}
+
+	// Other failures continue with the next tenant, a configuration error stops the run without -fail-fast
+	results := runTenants(context.Background(), []int{1001, 1002, 1003}, cfg, zaptest.NewLogger(t), migrate)
+	if fmt.Sprint(runs) != "[1001 1002]" || len(results) != 2 {
+		t.Errorf("runs = %v (%d results), want the run to stop after tenant 1002", runs, len(results))
+	}
+}
+
+func TestCommonKeyDir(t *testing.T) {
+	csvFiles := []exporter.CSVFile{
+		{S3Key: "lake/fis_aggr/tenant=1/00-80.csv"},
+		{S3Key: "lake/fis_aggr/tenant=1/80-ff.csv"},
+	}
+	if got := commonKeyDir(csvFiles); got != "lake/fis_aggr/tenant=1/" {
+		t.Errorf("commonKeyDir() = %q, want %q", got, "lake/fis_aggr/tenant=1/")
+	}
+	if got := commonKeyDir(csvFiles[:1]); got != "lake/fis_aggr/tenant=1/" {
+		t.Errorf("commonKeyDir() single file = %q", got)
+	}
+	if got := commonKeyDir(append(csvFiles, exporter.CSVFile{S3Key: "other.csv"})); got != "" {
+		t.Errorf("commonKeyDir() with no shared directory = %q, want empty", got)
+	}
+}
+
+func TestBytesSummary(t *testing.T) {
+	tests := []struct {
+		bytes   int64
+		elapsed time.Duration
+		want    string
+	}{
+		{250_000_000, 10 * time.Second, "250000000 (250.0 MB in 10s, 25.00 MB/s)"},
+		{1_500_000, 1500 * time.Millisecond, "1500000 (1.5 MB in 1.5s, 1.00 MB/s)"},
+		{0, 0, "0 (0.0 MB)"},
+	}
+	for _, tt := range tests {
+		if got := bytesSummary(tt.bytes, tt.elapsed); got != tt.want {
+			t.Errorf("bytesSummary(%d, %s) = %q, want %q", tt.bytes, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestTimingSummary(t *testing.T) {
+	tests := []struct {
+		timing     exporter.SegmentTiming
+		directLoad bool
+		want       string
+	}{
+		{exporter.SegmentTiming{Query: 6 * time.Second, Serialize: 1500 * time.Millisecond, Upload: 2500 * time.Millisecond}, false,
+			"query 6s (60%), serialization 1.5s (15%), upload 2.5s (25%)"},
+		{exporter.SegmentTiming{Query: time.Second, Upload: 3 * time.Second}, true,
+			"query 1s (25%), serialization 0s (0%), insert 3s (75%)"},
+		{exporter.SegmentTiming{}, false, ""},
+	}
+	for _, tt := range tests {
+		if got := timingSummary(tt.timing, tt.directLoad); got != tt.want {
+			t.Errorf("timingSummary(%+v, %v) = %q, want %q", tt.timing, tt.directLoad, got, tt.want)
+		}
+	}
+}
@@ -4,19 +4,35 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"mime"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
-	"gopkg.in/yaml.v3"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/util"
 )
 
 // Config holds all configuration for the migration tool.
 type Config struct {
 	// Tenant & Table
-	TenantID  int
-	TableName string
+	TenantID      int
+	TenantIDsFile string           // Newline-separated tenant IDs for batch migrations (overrides TenantID)
+	TenantIDs     []int            // Loaded from TenantIDsFile, or the tenant IDs of Tenants
+	Tenants       []TenantOverride // Config file tenants list: the tenants to migrate, with per-tenant overrides
+	FailFast      bool             // Stop batch migrations at the first failed tenant
+	TableName     string
+	Tables        []string // Tables to migrate one after another (overrides TableName)
+	TenantColumn  string   // Name of the tenant ID column in source and target tables. Default: tenantid
 
 	// MariaDB Connection
 	MariaDBHost     string
@@ -24,11 +40,61 @@ type Config struct {
 	MariaDBUser     string
 	MariaDBPassword string
 	MariaDBDatabase string
+	MariaDBParams   string // Extra DSN parameters as a query string, e.g. "charset=utf8mb4&readTimeout=30s" (parseTime=true is always set)
+
+	// Optional: export from a read replica instead of the primary (same port, credentials and database)
+	MariaDBReplicaHost string
+	ReplicaMaxLag      time.Duration // Default: 0 (not checked); Seconds_Behind_Master allowed before export
+	ReplicaLagAction   string        // "warn" or "abort" when the replica lags more than ReplicaMaxLag. Default: "abort"
+
+	// Optional: resolve MariaDBPassword from AWS Secrets Manager (CLI -mariadb-password overrides it)
+	MariaDBSecret       string // Secret name; the secret JSON must contain a "password" field
+	MariaDBSecretRegion string // Default: AWSRegion
+
+	// Exporter connection pool (MariaDB)
+	DBMaxOpenConns    int           // Default: 0 (unlimited)
+	DBMaxIdleConns    int           // Default: 0 (driver default of 2)
+	DBConnMaxLifetime int           // Default: 0 (seconds, connections are reused forever)
+	DBConnectRetries  int           // Default: 0; times the exporter pings again after a failed ping, for a database still starting up
+	DBConnectBackoff  time.Duration // Default: 1s; wait between the exporter's pings
 
 	// S3 Configuration
-	S3Bucket  string
-	S3Prefix  string
-	AWSRegion string
+	S3Bucket            string
+	S3Prefix            string
+	S3KeyTemplate       string // Default: "" (<prefix>/tenant-<id>/<table>/<filename>), Go text/template
+	PartitionByDate     bool   // Put CSV objects under <prefix>/dt=<RunDate>/ for data-lake crawlers
+	RunDate             string // Run start date (UTC, YYYY-MM-DD), set once by LoadConfigFromArgs so all segments share it
+	TimestampKeys       bool   // Put CSV objects under <prefix>/run-<RunTimestamp>/ so runs don't overwrite each other
+	RunTimestamp        string // Run start time (UTC, YYYYMMDDTHHMMSSZ), set once by LoadConfigFromArgs like RunDate
+	AWSRegion           string // Empty resolves it in s3.NewUploader (AWS_REGION, shared config, instance metadata)
+	S3Tags              string // Comma-separated key=value object tags (e.g. "team=fis,env=prod")
+	S3StorageClass      string // e.g. STANDARD_IA (empty uses the bucket default)
+	ContentType         string // Content-Type of the exported CSV and Parquet objects (empty: text/csv or the Parquet type)
+	S3Endpoint          string // Custom S3 endpoint URL, e.g. MinIO (empty falls back to AWS_ENDPOINT_URL)
+	S3PathStyle         bool   // Use path-style addressing (bucket in the path, not the hostname)
+	S3Addressing        string // auto (S3PathStyle, always path-style for AWS_ENDPOINT_URL), path or virtual. Default: auto
+	UploadRateLimitMbps int    // Default: 0 (unlimited), shared by all concurrent part uploads
+	MaxParallelUploads  int    // Cap on concurrent S3 part uploads across all segments. Default: 0 (unlimited)
+	S3CircuitThreshold  int    // Consecutive part upload failures that trip the S3 circuit breaker. Default: 0 (disabled)
+	ResumeUploads       bool   // Keep failed multipart uploads and resume them on re-run (state in <log-dir>/upload-state)
+
+	// Local output: also write each segment's CSV to this directory.
+	// Without -s3-bucket the migration runs local-only (no S3 upload, no SQL generation).
+	OutputDir string
+
+	// Debugging: print (or write to -output-dir) only the tenant's row with this hash.
+	// Bypasses segmentation, S3 upload and SQL generation.
+	SingleHash string
+
+	// Print each segment's row count and skew, without exporting anything
+	Report bool
+
+	// Print the hashes held by more than one row of a segment, of which the target keeps one, without exporting anything
+	DetectDuplicates bool
+
+	// Write the source table's CREATE TABLE to -output-dir and/or <s3-prefix>/schema/<table>.sql, without exporting rows
+	DumpSchema       bool
+	DumpSchemaAurora bool // Translate MariaDB-specific syntax so Aurora MySQL accepts the DDL
 
 	// AWS Credentials (optional - for S3 and Secrets Manager access)
 	// Priority: CLI flags > Environment variables > AWS CLI > Vault files
@@ -41,67 +107,340 @@ type Config struct {
 	AuroraPort                 int
 	AuroraUser                 string
 	AuroraSecretsManagerSecret string // AWS Secrets Manager secret name (e.g., "rds!cluster-xxx")
+	AuroraPassword             string // Fallback Aurora password, used with a warning when Secrets Manager fails
+	AuroraAuthMode             string // "secretsmanager" or "iam" (RDS IAM auth token over TLS). Default: "secretsmanager"
 	AuroraRegion               string // AWS region for Secrets Manager
+	SecretsMaxAttempts         int    // Secrets Manager lookup attempts, retrying throttling and service errors. Default: 5
 	AuroraDatabase             string
-	ExecuteSQL                 bool // Flag to execute LOAD DATA FROM S3
+	AuroraParams               string // Extra DSN parameters for the Aurora connection, as -mariadb-params
+	ExecuteSQL                 bool   // Flag to execute LOAD DATA FROM S3
+	SkipSQLGen                 bool   // Don't generate or upload the load-data SQL file (loading is done elsewhere)
+	DirectLoad                 bool   // INSERT the exported rows straight into Aurora, without S3 or LOAD DATA
+	DirectLoadWarnRows         int    // Default: 1000000; tenants with more rows log a warning with -direct-load
+	FullVerify                 bool   // Compare per-segment content checksums of source and Aurora after load
+	VerifyDiff                 bool   // Compare source and Aurora row by row after load, listing the differing hashes
+	Manifest                   bool   // Write a manifest with each segment's row count and row digest
+	VerifyManifest             bool   // Check the loaded Aurora table against the manifest of an earlier run, without exporting
+	ListOrphanObjects          bool   // After upload, report objects under the tenant table prefix that the run didn't produce
+	Prune                      bool   // With ListOrphanObjects, delete the reported objects
+	ValidateCoverage           bool   // After export, compare the segments' summed row counts with a COUNT(*) of the tenant's rows
+	CheckSchema                bool   // With -execute-sql, check the Aurora table columns before exporting. Default: true
+	VerifySample               int    // CSV objects to re-download and parse after upload (Default: 0 = off)
+	SkipPreflight              bool   // Skip the startup connectivity check of MariaDB, S3, Secrets Manager and Aurora
 
 	// Segmentation & Parallelism
-	Segments        int // Default: 16
-	MaxParallelSegs int // Default: 8
-	BatchSize       int // Default: 100000
+	Segments                int    // Default: 16
+	AutoSegments            bool   // Pick Segments per tenant from its row count, targeting RowsPerSegment
+	RowsPerSegment          int    // Target rows per segment for AutoSegments. Default: 500000
+	MaxParallelSegs         int    // Default: 8
+	AdaptiveParallelism     bool   // Start at 1 parallel segment and adapt up to MaxParallelSegs to segment latency (AIMD)
+	AdaptiveTargetLatency   int    // Seconds. Default: 120; with AdaptiveParallelism, slower segments halve the parallelism
+	BatchSize               int    // Default: 100000
+	BatchBytes              int    // Default: 0 (off); upload a part each time the CSV reaches this many bytes, instead of one per batch
+	IsolationLevel          string // Default: "repeatable-read" (repeatable-read, read-committed, snapshot)
+	SegmentTimeout          int    // Seconds. Default: 600 (10 minutes); a segment's export transaction is cancelled after it
+	QueryTimeout            int    // Seconds. Default: 0 (off); a single batch query is cancelled after it, within SegmentTimeout
+	LockRetries             int    // Default: 3; times a segment is exported again after a deadlock or lock wait timeout
+	MaxEmptyBatches         int    // Default: 1 (consecutive batches with no rows past the cursor before failing the segment)
+	MaxBatchesPerSegment    int    // Default: 10000 (safety limit; exceeding it fails the segment)
+	SegmentOrder            string // Default: "natural" (natural, largest-first, smallest-first)
+	PartitionStrategy       string // Default: "hash" (hash prefixes), or "range" (value ranges of PartitionColumn)
+	PartitionColumn         string // Numeric column split into value ranges with PartitionStrategy range, e.g. id
+	CheckSegmentCardinality bool   // Warn when Segments is far from the distinct hash prefixes present
+	ContinueOnSegmentError  bool   // Keep partial results when segments fail (default: fail the run)
+	FailFastAbort           bool   // At the first failed segment, stop dispatching and abort the other segments' uploads
+	OnlySegments            string // Segment indices/ranges to migrate, e.g. "0,2,5-7" (default: all)
+	SkipSegments            string // Segment indices/ranges to leave out
+
+	// Wall-clock budget, and resuming from the checkpoint of completed segments
+	MaxRuntime         time.Duration // Default: 0 (unlimited); stop dispatching segments before the budget runs out
+	Resume             bool          // Skip segments completed by a previous run (checkpoint in <log-dir>/checkpoints)
+	CheckpointInterval int           // Default: 0 (disabled); also checkpoint a segment's cursor and parts every N batches
+
+	// Overall concurrency budget shared by segment exports, S3 part uploads and Aurora loads
+	ConcurrencyBudget  int    // Default: 0 (disabled, only max-parallel-segments applies)
+	ConcurrencyWeights string // Default: "export=2,upload=1,load=1"
 
 	// CSV Options
-	CSVDelimiter string // Default: ","
-	CSVQuote     string // Default: "\""
+	CSVDelimiter string // Default: "," (one character, written by the exporter and used in LOAD DATA FIELDS TERMINATED BY)
+	CSVQuote     string // Default: "\"" (the only quote encoding/csv writes; used in LOAD DATA OPTIONALLY ENCLOSED BY)
+	NullMarker   string // Default: `\N` (written for NULL last_modified/version, loaded back as NULL)
+	CSVHeader    bool   // Write a header line to each CSV file, skipped by LOAD DATA with IGNORE 1 LINES. Default: true
 
 	// SQL Execution Timeout (seconds)
-	SQLExecTimeout int // Default: 300 (5 minutes)
+	SQLExecTimeout      int // Default: 300 (5 minutes). Connection timeout, and per-statement unless SQLStatementTimeout is set
+	SQLStatementTimeout int // Per LOAD DATA statement. Default: 0 (use SQLExecTimeout)
+	SQLTotalTimeout     int // Whole statement run; statements not started when it expires are not run. Default: 0 (unbounded)
+
+	// Reconnects to Aurora per statement after a dropped connection
+	SQLReconnectRetries int // Default: 3 (0 with SQLTransactional)
+
+	// All-or-nothing load: one transaction for all statements instead of continuing past failures
+	SQLTransactional bool
+
+	// Without an Aurora IAM role for S3 (error 63985), load each CSV with LOAD DATA LOCAL INFILE through this machine
+	LoadViaLocal bool
+
+	// Compliance record of every statement run on Aurora, as JSON lines apart from the zap log
+	AuditLog       string // Appended to, never truncated
+	AuditLogUpload bool   // Upload it to <s3-prefix>/audit/<file name> after the statements ran
+
+	// Duplicate key handling in LOAD DATA: ignore (IGNORE), replace (REPLACE) or error (neither)
+	SQLDuplicateMode string // Default: "ignore"
+
+	// Extra LOAD DATA clauses (e.g. "ESCAPED BY '\\' STARTING BY 'x'"), placed at their grammar position
+	LoadExtraClauses string
+
+	// Dead-letter output for rows skipped by row-level policies
+	// Local file path or s3://bucket/key (empty disables)
+	DeadLetter string
+
+	// Fail the segment on a row with a short hash or invalid UTF-8 in aggr (instead of skipping it)
+	FailOnInvalidRows bool
+
+	// Soft-delete exclusion: comma-separated "column" (exclude when NOT NULL) or "column=value" terms
+	ExcludeWhere string
+
+	// Subset to migrate: "column op value" or "column IS [NOT] NULL" terms joined with AND, on allow-listed columns
+	Where         string
+	AllowRawWhere bool // Accept Where as raw SQL, on any column
+
+	// Top-up sync: migrate only the rows modified since the newest last_modified already in Aurora
+	SinceCheckpoint   bool
+	SinceLastModified string // Set by the run for SinceCheckpoint: that last_modified as Unix seconds, "" migrates every row
+
+	// Replace aggr in the CSV for non-prod copies: "placeholder" or "sha256" (empty exports it unchanged)
+	MaskAggr string
+
+	// Check that aggr is valid JSON: "skip" skips invalid rows like the other row policies, "fail" fails the segment
+	// (empty doesn't check)
+	ValidateJSON string
+
+	// Comma-separated columns left out of the CSV and the LOAD DATA column list: aggr, last_modified or version
+	// (e.g. aggr for a metadata-only migration). The target fills them with their defaults.
+	ExcludeColumns string
+
+	// CSV compression: "none", "gzip" or "zstd" (zstd can't be loaded by Aurora, so not with -execute-sql)
+	Compress      string // Default: none
+	CompressLevel int    // gzip 1-9, zstd 1-22. Default: 0 (the codec's default level)
+
+	// Output file format: "csv" or "parquet" (one Snappy-compressed file per segment, e.g. for Athena; Aurora can't load it)
+	Format string // Default: csv
+
+	// Export all segments sequentially into one tenant-<id>.<table>.csv instead of a file per segment
+	SingleFile bool
+
+	// Flag segments whose max last_modified changed while they were exported
+	DetectSourceChanges bool
+
+	// Fail the preflight (instead of warning) if no index leads with (tenantid, hash)
+	RequireIndex bool
+
+	// Pause/resume control: write "pause" or "resume" to this file while the migration runs
+	ControlFile         string
+	ControlPollInterval int // Default: 5 (seconds)
 
 	// Output Control
-	Quiet bool // Suppress "Next Steps" instructions (useful when run via script)
+	Quiet         bool   // Suppress "Next Steps" instructions (useful when run via script)
+	PrintSQL      bool   // Also write the LOAD DATA statements to stdout; with Quiet nothing else is printed
+	PreviewSQL    bool   // Only print the LOAD DATA statements a run would generate, without exporting, uploading or connecting to a database
+	NotifyWebhook string // POST a JSON summary to this URL when the run finishes or fails (e.g. a Slack incoming webhook)
+
+	// Logging
+	LogLevel  string // Default: "info" (debug, info, warn, error)
+	LogStdout bool   // Log JSON to stdout instead of a file
+	LogDir    string // Default: "/tmp" (log file is <log-dir>/migration.log)
 }
 
-// LoadConfig loads configuration from CLI flags, environment variables, and YAML file.
+// LoadConfig loads configuration from the process command line (os.Args), environment variables, and YAML file.
 // Priority: CLI flags > environment variables > YAML file > defaults
 func LoadConfig() (*Config, error) {
-	cfg := &Config{}
+	return LoadConfigFromArgs(os.Args[1:])
+}
+
+// LoadConfigFromArgs loads configuration like LoadConfig, parsing args instead of os.Args.
+// Flags are parsed with a fresh flag.FlagSet, so it can be called repeatedly (e.g. in tests).
+// Returns flag.ErrHelp if -h or -help was given; any other error is an errs.ErrConfig.
+func LoadConfigFromArgs(args []string) (*Config, error) {
+	cfg, err := loadConfigFromArgs(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	return cfg, err
+}
+
+func loadConfigFromArgs(args []string) (*Config, error) {
+	runStart := time.Now().UTC()
+	cfg := &Config{CheckSchema: true, CSVHeader: true, RunDate: runStart.Format("2006-01-02"), RunTimestamp: runStart.Format("20060102T150405Z")}
 
 	// CLI flags
-	tenantID := flag.Int("tenant-id", 0, "Tenant ID to migrate")
-	tableName := flag.String("table-name", "fis_aggr", "Table name (default: fis_aggr)")
-	mariadbHost := flag.String("mariadb-host", "", "MariaDB host:port")
-	mariadbPort := flag.Int("mariadb-port", 3306, "MariaDB port (default: 3306)")
-	mariadbUser := flag.String("mariadb-user", "", "MariaDB username")
-	mariadbPassword := flag.String("mariadb-password", "", "MariaDB password")
-	mariadbAuth := flag.String("mariadb-auth", "", "MariaDB auth file path (JSON with user and password)")
-	mariadbDatabase := flag.String("mariadb-database", "fis", "MariaDB database name (default: fis)")
-	s3Bucket := flag.String("s3-bucket", "", "S3 bucket name")
-	s3Prefix := flag.String("s3-prefix", "fis-migration", "S3 key prefix (default: fis-migration)")
-	awsRegion := flag.String("aws-region", "", "AWS region")
-	awsAccessKeyID := flag.String("aws-access-key-id", "", "AWS Access Key ID (optional, can use env vars or AWS CLI)")
-	awsSecretAccessKey := flag.String("aws-secret-access-key", "", "AWS Secret Access Key (optional, can use env vars or AWS CLI)")
-	awsSessionToken := flag.String("aws-session-token", "", "AWS Session Token (optional, only needed for temporary credentials like STS, assume-role, SSO)")
-	segments := flag.Int("segments", 16, "Number of hash segments (default: 16)")
-	maxParallelSegs := flag.Int("max-parallel-segments", 8, "Max parallel segments (default: 8)")
-	batchSize := flag.Int("batch-size", 100000, "Batch size for pagination (default: 100000)")
-	configFile := flag.String("config-file", "migration-config.yaml", "Config file path (default: migration-config.yaml)")
+	fs := flag.NewFlagSet("migration", flag.ContinueOnError)
+	tenantID := fs.Int("tenant-id", 0, "Tenant ID to migrate")
+	tenantIDsFile := fs.String("tenant-ids-file", "", "File with newline-separated tenant IDs to migrate one after another")
+	failFast := fs.Bool("fail-fast", false, "Stop at the first failed tenant when using -tenant-ids-file")
+	tableName := fs.String("table-name", "fis_aggr", "Table name (default: fis_aggr)")
+	tables := fs.String("tables", "", "Comma-separated tables sharing the hash segmentation, migrated one after another (overrides -table-name)")
+	tenantColumn := fs.String("tenant-column", "", "Name of the tenant ID column in the source and target tables (default: tenantid)")
+	mariadbHost := fs.String("mariadb-host", "", "MariaDB host:port")
+	mariadbPort := fs.Int("mariadb-port", 3306, "MariaDB port (default: 3306)")
+	mariadbUser := fs.String("mariadb-user", "", "MariaDB username")
+	mariadbPassword := fs.String("mariadb-password", "", "MariaDB password")
+	mariadbSecret := fs.String("mariadb-secret", "", "AWS Secrets Manager secret with the MariaDB password")
+	mariadbSecretRegion := fs.String("mariadb-secret-region", "", "AWS region of the MariaDB secret (default: aws-region)")
+	dbMaxOpenConns := fs.Int("db-max-open-conns", 0, "Max open MariaDB connections for the exporter (default: 0, unlimited)")
+	dbMaxIdleConns := fs.Int("db-max-idle-conns", 0, "Max idle MariaDB connections kept by the exporter (default: 0, driver default of 2)")
+	dbConnMaxLifetime := fs.Int("db-conn-max-lifetime", 0, "Max lifetime of exporter MariaDB connections in seconds (default: 0, unlimited)")
+	dbConnectRetries := fs.Int("db-connect-retries", 0, "Times to ping MariaDB again when the exporter's first ping fails, for a database still starting up (default: 0)")
+	dbConnectBackoff := fs.Duration("db-connect-backoff", time.Second, "Wait between the exporter's MariaDB pings with -db-connect-retries, e.g. 2s (default: 1s)")
+	mariadbAuth := fs.String("mariadb-auth", "", "MariaDB auth file path (JSON with user and password)")
+	mariadbDatabase := fs.String("mariadb-database", "fis", "MariaDB database name (default: fis)")
+	mariadbParams := fs.String("mariadb-params", "", "Extra MariaDB DSN parameters as a query string, e.g. charset=utf8mb4&readTimeout=30s (parseTime=true is always kept)")
+	mariadbReplicaHost := fs.String("mariadb-replica-host", "", "MariaDB read replica host[:port] to export from instead of -mariadb-host, with the same credentials and database")
+	replicaMaxLag := fs.Duration("replica-max-lag", 0, "With -mariadb-replica-host, the most replication lag (SHOW SLAVE STATUS Seconds_Behind_Master) allowed before export, e.g. 30s (default: 0, not checked)")
+	replicaLagAction := fs.String("replica-lag-action", "", "What to do when the replica lags more than -replica-max-lag: warn or abort (default: abort)")
+	s3Bucket := fs.String("s3-bucket", "", "S3 bucket name")
+	outputDir := fs.String("output-dir", "", "Also write CSV files to this local directory (local-only if -s3-bucket is omitted)")
+	report := fs.Bool("report", false, "Print the row count per segment with min/max/avg and a skew warning, without exporting")
+	detectDuplicates := fs.Bool("detect-duplicates", false, "Print the hashes held by more than one of the tenant's rows per segment (the target keeps one of each), without exporting")
+	dumpSchema := fs.Bool("dump-schema", false, "Write the source table's CREATE TABLE to -output-dir and/or <s3-prefix>/schema/<table>.sql, without exporting rows")
+	dumpSchemaAurora := fs.Bool("dump-schema-aurora", false, "With -dump-schema, translate MariaDB-specific syntax (engines, collations, JSON columns) for Aurora MySQL")
+	singleHash := fs.String("single-hash", "", "Debugging: print the tenant's row with this hex hash (or write a one-row CSV to -output-dir), without S3 upload")
+	s3Prefix := fs.String("s3-prefix", "fis-migration", "S3 key prefix (default: fis-migration)")
+	partitionByDate := fs.Bool("partition-by-date", false, "Put CSV objects under <prefix>/dt=YYYY-MM-DD/ (run start date, UTC) for Athena/Glue")
+	timestampKeys := fs.Bool("timestamp-keys", false, "Put CSV objects under <prefix>/run-YYYYMMDDTHHMMSSZ/ (run start time, UTC) to keep earlier runs' objects")
+	s3KeyTemplate := fs.String("s3-key-template", "", "Go text/template for CSV object keys, e.g. {{.Prefix}}/{{.Table}}/tenant={{.TenantID}}/{{.StartHex}}-{{.EndHex}}.csv")
+	awsRegion := fs.String("aws-region", "", "AWS region (default: from AWS_REGION, the shared config or instance metadata)")
+	s3Tags := fs.String("s3-tags", "", "S3 object tags as comma-separated key=value pairs (e.g. team=fis,env=prod)")
+	uploadRateLimitMbps := fs.Int("upload-rate-limit-mbps", 0, "Cap total S3 upload bandwidth in megabits per second (default: 0, unlimited)")
+	maxParallelUploads := fs.Int("max-parallel-uploads", 0, "Cap on concurrent S3 part uploads across all segments, independent of -max-parallel-segments (default: 0, unlimited)")
+	s3CircuitThreshold := fs.Int("s3-circuit-threshold", 0, "Consecutive S3 part upload failures, across all segments, after which part uploads fail fast for a cooldown (default: 0, disabled)")
+	resumeUploads := fs.Bool("resume-uploads", false, "Keep multipart uploads that fail and resume them from the uploaded parts on re-run")
+	s3StorageClass := fs.String("s3-storage-class", "", "S3 storage class for uploaded objects (e.g. STANDARD_IA)")
+	contentType := fs.String("content-type", "", "Content-Type of the exported CSV and Parquet objects (default: text/csv, application/vnd.apache.parquet)")
+	s3Endpoint := fs.String("s3-endpoint", "", "Custom S3 endpoint URL, e.g. http://minio:9000 (default: AWS_ENDPOINT_URL, else AWS)")
+	s3Addressing := fs.String("s3-addressing", "auto", "S3 addressing style: auto (-s3-path-style, path-style for AWS_ENDPOINT_URL), path or virtual (bucket in the hostname), whatever the endpoint")
+	s3PathStyle := fs.Bool("s3-path-style", false, "Use path-style S3 addressing, as MinIO usually needs (always on for AWS_ENDPOINT_URL)")
+	awsAccessKeyID := fs.String("aws-access-key-id", "", "AWS Access Key ID (optional, can use env vars or AWS CLI)")
+	awsSecretAccessKey := fs.String("aws-secret-access-key", "", "AWS Secret Access Key (optional, can use env vars or AWS CLI)")
+	awsSessionToken := fs.String("aws-session-token", "", "AWS Session Token (optional, only needed for temporary credentials like STS, assume-role, SSO)")
+	segments := fs.Int("segments", 16, "Number of hash segments (default: 16)")
+	autoSegments := fs.Bool("auto-segments", false, "Pick -segments per tenant from its row count, about -rows-per-segment rows each (1-256)")
+	rowsPerSegment := fs.Int("rows-per-segment", 500000, "Target rows per segment for -auto-segments (default: 500000)")
+	maxParallelSegs := fs.Int("max-parallel-segments", 8, "Max parallel segments (default: 8)")
+	adaptiveParallelism := fs.Bool("adaptive-parallelism", false, "Start with 1 parallel segment and add one while segments finish within -adaptive-target-latency, halving on a slower one (up to -max-parallel-segments)")
+	adaptiveTargetLatency := fs.Int("adaptive-target-latency", 120, "With -adaptive-parallelism, the segment duration in seconds above which parallelism is halved (default: 120)")
+	batchSize := fs.Int("batch-size", 100000, "Batch size for pagination (default: 100000)")
+	batchBytes := fs.Int("batch-bytes", 0, "Upload a multipart part each time the CSV reaches this many bytes, instead of one part per batch (exclusive with -batch-size)")
+	maxBatchesPerSegment := fs.Int("max-batches-per-segment", 10000, "Max batches per segment before failing it as incomplete (default: 10000)")
+	isolationLevel := fs.String("isolation-level", "", "Export transaction isolation: repeatable-read, read-committed, snapshot (default: repeatable-read)")
+	segmentTimeout := fs.Int("segment-timeout", 0, "Timeout in seconds for a segment's export transaction (default: 600)")
+	queryTimeout := fs.Int("query-timeout", 0, "Timeout in seconds for each batch query of a segment, below -segment-timeout (default: 0, only -segment-timeout)")
+	lockRetries := fs.Int("lock-retries", 3, "Times to export a segment again after its transaction hits a MariaDB deadlock or lock wait timeout (default: 3)")
+	onlySegments := fs.String("only-segments", "", "Migrate only these segment indices or ranges, e.g. 0,2,5-7 (default: all)")
+	skipSegments := fs.String("skip-segments", "", "Leave out these segment indices or ranges, e.g. 3-7")
+	checkSegmentCardinality := fs.Bool("check-segment-cardinality", false, "Sample distinct hash prefixes and warn when -segments is far from them (default: false)")
+	segmentOrder := fs.String("segment-order", "", "Segment dispatch order: natural, largest-first, smallest-first (default: natural)")
+	partitionStrategy := fs.String("partition-strategy", "", "How rows are split into segments: hash (hash prefixes) or range (value ranges of -partition-column) (default: hash)")
+	partitionColumn := fs.String("partition-column", "", "Numeric column split into -segments value ranges with -partition-strategy range, e.g. id")
+	concurrencyBudget := fs.Int("concurrency-budget", 0, "Total concurrent operations shared by exports, uploads and loads (default: 0, disabled)")
+	concurrencyWeights := fs.String("concurrency-weights", "", "Budget weights per phase (default: export=2,upload=1,load=1)")
+	continueOnSegmentError := fs.Bool("continue-on-segment-error", false, "Continue with partial results when segments fail (default: fail the run)")
+	failFastAbort := fs.Bool("fail-fast-abort", false, "At the first failed segment, stop dispatching segments and abort the S3 uploads in flight")
+	maxRuntime := fs.Duration("max-runtime", 0, "Wall-clock budget, e.g. 2h30m: stop dispatching segments before it runs out and checkpoint the completed ones (default: 0, unlimited)")
+	resume := fs.Bool("resume", false, "Skip segments completed by a previous run that failed or hit -max-runtime")
+	checkpointInterval := fs.Int("checkpoint-interval", 0, "Checkpoint each segment's cursor and uploaded parts every N batches, so -resume continues it mid-segment (requires -resume-uploads; default: 0, disabled)")
+	maxEmptyBatches := fs.Int("max-empty-batches", 1, "Consecutive batches with no rows past the cursor before failing a segment (default: 1, the first)")
+	configFile := fs.String("config-file", "migration-config.yaml", "Config file path (default: migration-config.yaml)")
+	profile := fs.String("profile", "", "Named block to load from the config file's profiles map, e.g. prod (required if the file has profiles)")
+	yamlStrictEnv := fs.Bool("yaml-strict-env", false, "Fail if the config file references an unset environment variable as ${VAR} or $VAR (default: it expands to an empty value)")
 
 	// Aurora connection for SQL execution
-	auroraHost := flag.String("aurora-host", "", "Aurora MySQL endpoint (optional)")
-	auroraPort := flag.Int("aurora-port", 3306, "Aurora MySQL port (default: 3306)")
-	auroraUser := flag.String("aurora-user", "", "Aurora MySQL username")
-	auroraSecret := flag.String("aurora-secret", "", "AWS Secrets Manager secret name (e.g., rds!cluster-xxx)")
-	auroraRegion := flag.String("aurora-region", "", "AWS region for Secrets Manager (e.g., us-east-1)")
-	auroraDatabase := flag.String("aurora-database", "fis", "Aurora MySQL database name (default: fis)")
-	executeSQL := flag.Bool("execute-sql", false, "Execute LOAD DATA FROM S3 after generating SQL")
-	sqlExecTimeout := flag.Int("sql-exec-timeout", 300, "SQL execution timeout in seconds (default: 300)")
-	quiet := flag.Bool("quiet", false, "Suppress 'Next Steps' instructions (useful when run via script)")
-
-	flag.Parse()
+	auroraHost := fs.String("aurora-host", "", "Aurora MySQL endpoint (optional)")
+	auroraPort := fs.Int("aurora-port", 3306, "Aurora MySQL port (default: 3306)")
+	auroraUser := fs.String("aurora-user", "", "Aurora MySQL username")
+	auroraSecret := fs.String("aurora-secret", "", "AWS Secrets Manager secret name (e.g., rds!cluster-xxx)")
+	auroraPassword := fs.String("aurora-password", "", "Fallback Aurora password, used (with a warning) only when the -aurora-secret lookup fails")
+	auroraAuthMode := fs.String("aurora-auth-mode", "", "Aurora authentication: secretsmanager (password from -aurora-secret) or iam (RDS IAM auth token over TLS) (default: secretsmanager)")
+	auroraRegion := fs.String("aurora-region", "", "AWS region for Secrets Manager or the IAM auth token (e.g., us-east-1)")
+	secretsMaxAttempts := fs.Int("secrets-max-attempts", 5, "Secrets Manager lookup attempts; throttling and service errors are retried with backoff (default: 5)")
+	auroraDatabase := fs.String("aurora-database", "fis", "Aurora MySQL database name (default: fis)")
+	auroraParams := fs.String("aurora-params", "", "Extra Aurora MySQL DSN parameters as a query string, e.g. maxAllowedPacket=67108864 (parseTime=true is always kept)")
+	executeSQL := fs.Bool("execute-sql", false, "Execute LOAD DATA FROM S3 after generating SQL")
+	skipSQLGen := fs.Bool("skip-sql-gen", false, "Don't generate or upload the load-data SQL file, when another system loads the CSV files")
+	directLoad := fs.Bool("direct-load", false, "INSERT the exported rows in batches straight into Aurora instead of uploading CSV files to S3 and loading them (small tenants)")
+	directLoadWarnRows := fs.Int("direct-load-warn-rows", 1000000, "With -direct-load, warn when the tenant has more rows than this, for which S3 and LOAD DATA are faster (default: 1000000)")
+	verifySample := fs.Int("verify-sample", 0, "After upload, re-download the start of N random CSV objects and check they parse as well-formed CSV (0 = off)")
+	skipPreflight := fs.Bool("skip-preflight", false, "Skip the startup check that MariaDB, S3, Secrets Manager and Aurora are reachable")
+	checkSchema := fs.Bool("check-schema", true, "With -execute-sql, check that the Aurora table has the expected columns before exporting (default: true)")
+	fullVerify := fs.Bool("full-verify", false, "After -execute-sql, compare per-segment content checksums of source and Aurora and report mismatches")
+	verifyDiff := fs.Bool("verify-diff", false, "After -execute-sql, compare source and Aurora row by row and report hashes missing on either side or with different content")
+	manifest := fs.Bool("manifest", false, "Write a manifest with each segment's row count and SHA-256 over its (hash, aggr) pairs to <s3-prefix>/manifest/ and/or <output-dir>/manifest/")
+	listOrphanObjects := fs.Bool("list-orphan-objects", false, "After upload, list objects under <prefix>/tenant-<id>/<table>/ that this run didn't produce (leftovers of earlier runs)")
+	prune := fs.Bool("prune", false, "With -list-orphan-objects, delete the reported objects")
+	validateCoverage := fs.Bool("validate-coverage", false, "After export, check that the segments' row counts add up to a COUNT(*) of the tenant's rows (catches segment boundary gaps and overlaps)")
+	verifyManifest := fs.Bool("verify-manifest", false, "Instead of exporting, recompute each segment's checksum on the Aurora table and compare it with the -manifest of an earlier run")
+	sqlExecTimeout := fs.Int("sql-exec-timeout", 300, "SQL execution timeout in seconds (default: 300)")
+	sqlStatementTimeout := fs.Int("sql-statement-timeout", 0, "Timeout in seconds for each LOAD DATA statement (default: -sql-exec-timeout)")
+	sqlTotalTimeout := fs.Int("sql-total-timeout", 0, "Timeout in seconds for running all LOAD DATA statements (default: 0, unbounded)")
+	sqlReconnectRetries := fs.Int("sql-reconnect-retries", 3, "Times to reconnect to Aurora and retry a statement after a dropped connection (default: 3)")
+	sqlTransactional := fs.Bool("sql-transactional", false, "Run all LOAD DATA statements in one transaction, rolling back if any fails")
+	loadViaLocal := fs.Bool("load-via-local", false, "When LOAD DATA FROM S3 fails for lack of an Aurora IAM role, download the CSV and load it with LOAD DATA LOCAL INFILE")
+	auditLog := fs.String("audit-log", "", "Append a JSON line per SQL statement run on Aurora (S3 source, start/end time, outcome) to this file")
+	auditLogUpload := fs.Bool("audit-log-upload", false, "Upload the -audit-log to <s3-prefix>/audit/ after the statements ran")
+	nullMarker := fs.String("null-marker", "", "CSV value for NULL last_modified/version, loaded back as NULL (default: \\N)")
+	csvDelimiter := fs.String("csv-delimiter", "", "CSV field delimiter, one character; \\t for tab (default: ,)")
+	csvHeader := fs.Bool("csv-header", true, "Write a header line to each CSV file; LOAD DATA skips it with IGNORE 1 LINES (default: true)")
+	sqlDuplicateMode := fs.String("sql-duplicate-mode", "", "Duplicate key handling in LOAD DATA: ignore, replace or error (default: ignore)")
+	loadExtraClauses := fs.String("load-extra-clauses", "", "Extra LOAD DATA clauses: CHARACTER SET, ESCAPED BY, STARTING BY, IGNORE n LINES")
+	logLevel := fs.String("log-level", "", "Log level: debug, info, warn, error (default: info)")
+	logStdout := fs.Bool("log-stdout", false, "Write JSON logs to stdout instead of a log file")
+	notifyWebhook := fs.String("notify-webhook", "", "POST a JSON summary (tenant, rows, status, duration) to this URL when the run finishes or fails")
+	logDir := fs.String("log-dir", "", "Directory for the migration.log file (default: /tmp)")
+	quiet := fs.Bool("quiet", false, "Suppress 'Next Steps' instructions (useful when run via script)")
+	printSQL := fs.Bool("print-sql", false, "Also write the LOAD DATA statements to stdout, e.g. to pipe them into mysql; with -quiet nothing else is printed")
+	previewSQL := fs.Bool("preview-sql", false, "Print the LOAD DATA statements a run would generate, one per segment, and exit without exporting, uploading or connecting to a database")
+	validateJSON := fs.String("validate-json", "", "Check that each row's aggr is valid JSON: skip (skip invalid rows, like dead-letter policies) or fail (fail the segment) (default: not checked)")
+	excludeColumns := fs.String("exclude-columns", "", "Comma-separated columns to leave out of the export: aggr, last_modified or version (e.g. aggr for a metadata-only migration)")
+	maskAggr := fs.String("mask-aggr", "", "Mask aggr in the CSV for non-prod copies: placeholder (fixed value) or sha256 (deterministic hash) (default: unmasked)")
+	compress := fs.String("compress", "", "Compress the CSV files: none, gzip (.csv.gz) or zstd (.csv.zst, not loadable by Aurora) (default: none)")
+	compressLevel := fs.Int("compress-level", 0, "Compression level: 1-9 for gzip, 1-22 for zstd (default: the codec's default)")
+	fs.IntVar(compressLevel, "compression-level", 0, "Alias of -compress-level")
+	format := fs.String("format", "", "Output file format: csv, or parquet for analytics (no SQL is generated, Aurora can't load it) (default: csv)")
+	singleFile := fs.Bool("single-file", false, "Export all segments one after another into a single tenant-<id>.<table>.csv instead of one file per segment")
+	excludeWhere := fs.String("exclude-where", "", "Skip soft-deleted rows: comma-separated column (exclude when set) or column=value terms, e.g. deleted_at")
+	where := fs.String("where", "", "Migrate only the rows matching this filter: column op value or column IS [NOT] NULL terms joined with AND, on hash, last_modified or version, e.g. \"version >= 3\"")
+	allowRawWhere := fs.Bool("allow-raw-where", false, "Use -where as raw SQL, on any column and with any expression (unchecked)")
+	sinceCheckpoint := fs.Bool("since-checkpoint", false, "Migrate only the rows modified at or after the newest last_modified of the tenant in the Aurora table (all rows if it has none)")
+	deadLetter := fs.String("dead-letter", "", "Write skipped rows to this local path or s3://bucket/key (JSONL)")
+	failOnInvalidRows := fs.Bool("fail-on-invalid-rows", false, "Fail the segment on a row with a hash shorter than 2 chars or invalid UTF-8 in aggr (default: skip and log it, or write it to -dead-letter)")
+	controlFile := fs.String("control-file", "", "File polled for pause/resume commands (\"pause\" stops dispatching new segments)")
+	controlPollInterval := fs.Int("control-poll-interval", 5, "Control file poll interval in seconds (default: 5)")
+	requireIndex := fs.Bool("require-index", false, "Fail if the source table has no index on (tenantid, hash) (default: warn)")
+	detectSourceChanges := fs.Bool("detect-source-changes", false, "Flag segments whose source rows changed during export (samples max last_modified before and after)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Flags given on the command line. Flags with a non-zero default only override
+	// environment variables and YAML when set, so their defaults don't clobber them.
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
 
 	// Load from YAML file if it exists
 	if *configFile != "" {
-		if err := loadFromYAML(cfg, *configFile); err != nil && !os.IsNotExist(err) {
+		strictEnv := *yamlStrictEnv
+		if val := os.Getenv("FIS_MIGRATION_YAML_STRICT_ENV"); val != "" && !setFlags["yaml-strict-env"] {
+			strictEnv = (val == "true" || val == "1")
+		}
+		if *profile == "" {
+			*profile = os.Getenv("FIS_MIGRATION_PROFILE")
+		}
+		err := loadFromYAML(cfg, *configFile, *profile, strictEnv)
+		if os.IsNotExist(err) && *profile != "" {
+			return nil, fmt.Errorf("profile %q requested, but config file %s does not exist", *profile, *configFile)
+		}
+		if err != nil && !os.IsNotExist(err) {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 	}
@@ -113,13 +452,29 @@ func LoadConfig() (*Config, error) {
 	if *tenantID > 0 {
 		cfg.TenantID = *tenantID
 	}
-	if *tableName != "" {
+	if *tenantIDsFile != "" {
+		cfg.TenantIDsFile = *tenantIDsFile
+	}
+	if *failFast {
+		cfg.FailFast = true
+	}
+	if setFlags["table-name"] {
 		cfg.TableName = *tableName
 	}
+	if *tenantColumn != "" {
+		cfg.TenantColumn = *tenantColumn
+	}
+	if *tables != "" {
+		parsed, err := ParseTables(*tables)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Tables = parsed
+	}
 	if *mariadbHost != "" {
 		cfg.MariaDBHost = *mariadbHost
 	}
-	if *mariadbPort > 0 {
+	if setFlags["mariadb-port"] {
 		cfg.MariaDBPort = *mariadbPort
 	}
 	if *mariadbUser != "" {
@@ -128,23 +483,113 @@ func LoadConfig() (*Config, error) {
 	if *mariadbPassword != "" {
 		cfg.MariaDBPassword = *mariadbPassword
 	}
+	if *mariadbSecret != "" {
+		cfg.MariaDBSecret = *mariadbSecret
+	}
+	if *mariadbSecretRegion != "" {
+		cfg.MariaDBSecretRegion = *mariadbSecretRegion
+	}
+	if *dbMaxOpenConns > 0 {
+		cfg.DBMaxOpenConns = *dbMaxOpenConns
+	}
+	if *dbMaxIdleConns > 0 {
+		cfg.DBMaxIdleConns = *dbMaxIdleConns
+	}
+	if *dbConnMaxLifetime > 0 {
+		cfg.DBConnMaxLifetime = *dbConnMaxLifetime
+	}
+	if *dbConnectRetries != 0 {
+		cfg.DBConnectRetries = *dbConnectRetries
+	}
+	if setFlags["db-connect-backoff"] {
+		cfg.DBConnectBackoff = *dbConnectBackoff
+	}
 	if *mariadbAuth != "" {
 		if err := cfg.ReadMariaDBAuth(*mariadbAuth); err != nil {
 			return nil, fmt.Errorf("failed to read MariaDB auth file: %w", err)
 		}
 	}
-	if *mariadbDatabase != "" {
+	if setFlags["mariadb-database"] {
 		cfg.MariaDBDatabase = *mariadbDatabase
 	}
+	if *mariadbParams != "" {
+		cfg.MariaDBParams = *mariadbParams
+	}
+	if *mariadbReplicaHost != "" {
+		cfg.MariaDBReplicaHost = *mariadbReplicaHost
+	}
+	if *replicaMaxLag != 0 {
+		cfg.ReplicaMaxLag = *replicaMaxLag
+	}
+	if *replicaLagAction != "" {
+		cfg.ReplicaLagAction = *replicaLagAction
+	}
 	if *s3Bucket != "" {
 		cfg.S3Bucket = *s3Bucket
 	}
-	if *s3Prefix != "" {
+	if *outputDir != "" {
+		cfg.OutputDir = *outputDir
+	}
+	if *singleHash != "" {
+		cfg.SingleHash = *singleHash
+	}
+	if *report {
+		cfg.Report = true
+	}
+	if *detectDuplicates {
+		cfg.DetectDuplicates = true
+	}
+	if *dumpSchema {
+		cfg.DumpSchema = true
+	}
+	if *dumpSchemaAurora {
+		cfg.DumpSchemaAurora = true
+	}
+	if setFlags["s3-prefix"] {
 		cfg.S3Prefix = *s3Prefix
 	}
+	if *s3KeyTemplate != "" {
+		cfg.S3KeyTemplate = *s3KeyTemplate
+	}
+	if *partitionByDate {
+		cfg.PartitionByDate = true
+	}
+	if *timestampKeys {
+		cfg.TimestampKeys = true
+	}
 	if *awsRegion != "" {
 		cfg.AWSRegion = *awsRegion
 	}
+	if *s3Tags != "" {
+		cfg.S3Tags = *s3Tags
+	}
+	if *s3StorageClass != "" {
+		cfg.S3StorageClass = *s3StorageClass
+	}
+	if *contentType != "" {
+		cfg.ContentType = *contentType
+	}
+	if *s3Endpoint != "" {
+		cfg.S3Endpoint = *s3Endpoint
+	}
+	if setFlags["s3-addressing"] {
+		cfg.S3Addressing = *s3Addressing
+	}
+	if *s3PathStyle {
+		cfg.S3PathStyle = true
+	}
+	if *uploadRateLimitMbps > 0 {
+		cfg.UploadRateLimitMbps = *uploadRateLimitMbps
+	}
+	if *maxParallelUploads != 0 {
+		cfg.MaxParallelUploads = *maxParallelUploads
+	}
+	if *s3CircuitThreshold != 0 {
+		cfg.S3CircuitThreshold = *s3CircuitThreshold
+	}
+	if *resumeUploads {
+		cfg.ResumeUploads = true
+	}
 	if *awsAccessKeyID != "" {
 		cfg.AWSAccessKeyID = *awsAccessKeyID
 	}
@@ -154,19 +599,91 @@ func LoadConfig() (*Config, error) {
 	if *awsSessionToken != "" {
 		cfg.AWSSessionToken = *awsSessionToken
 	}
-	if *segments > 0 {
+	if setFlags["segments"] {
 		cfg.Segments = *segments
 	}
-	if *maxParallelSegs > 0 {
+	if *autoSegments {
+		cfg.AutoSegments = true
+	}
+	if setFlags["rows-per-segment"] {
+		cfg.RowsPerSegment = *rowsPerSegment
+	}
+	if *onlySegments != "" {
+		cfg.OnlySegments = *onlySegments
+	}
+	if *skipSegments != "" {
+		cfg.SkipSegments = *skipSegments
+	}
+	if setFlags["max-parallel-segments"] {
 		cfg.MaxParallelSegs = *maxParallelSegs
 	}
-	if *batchSize > 0 {
+	if *adaptiveParallelism {
+		cfg.AdaptiveParallelism = true
+	}
+	if setFlags["adaptive-target-latency"] {
+		cfg.AdaptiveTargetLatency = *adaptiveTargetLatency
+	}
+	if *segmentOrder != "" {
+		cfg.SegmentOrder = *segmentOrder
+	}
+	if *partitionStrategy != "" {
+		cfg.PartitionStrategy = *partitionStrategy
+	}
+	if *partitionColumn != "" {
+		cfg.PartitionColumn = *partitionColumn
+	}
+	if *checkSegmentCardinality {
+		cfg.CheckSegmentCardinality = true
+	}
+	if *isolationLevel != "" {
+		cfg.IsolationLevel = *isolationLevel
+	}
+	if *segmentTimeout != 0 {
+		cfg.SegmentTimeout = *segmentTimeout
+	}
+	if *queryTimeout != 0 {
+		cfg.QueryTimeout = *queryTimeout
+	}
+	if setFlags["lock-retries"] {
+		cfg.LockRetries = *lockRetries
+	}
+	if *continueOnSegmentError {
+		cfg.ContinueOnSegmentError = true
+	}
+	if *failFastAbort {
+		cfg.FailFastAbort = true
+	}
+	if *maxRuntime != 0 {
+		cfg.MaxRuntime = *maxRuntime
+	}
+	if *resume {
+		cfg.Resume = true
+	}
+	if *checkpointInterval != 0 {
+		cfg.CheckpointInterval = *checkpointInterval
+	}
+	if *concurrencyBudget > 0 {
+		cfg.ConcurrencyBudget = *concurrencyBudget
+	}
+	if *concurrencyWeights != "" {
+		cfg.ConcurrencyWeights = *concurrencyWeights
+	}
+	if setFlags["batch-size"] {
 		cfg.BatchSize = *batchSize
 	}
+	if setFlags["batch-bytes"] {
+		cfg.BatchBytes = *batchBytes
+	}
+	if setFlags["max-empty-batches"] {
+		cfg.MaxEmptyBatches = *maxEmptyBatches
+	}
+	if setFlags["max-batches-per-segment"] {
+		cfg.MaxBatchesPerSegment = *maxBatchesPerSegment
+	}
 	if *auroraHost != "" {
 		cfg.AuroraHost = *auroraHost
 	}
-	if *auroraPort > 0 {
+	if setFlags["aurora-port"] {
 		cfg.AuroraPort = *auroraPort
 	}
 	if *auroraUser != "" {
@@ -175,38 +692,250 @@ func LoadConfig() (*Config, error) {
 	if *auroraSecret != "" {
 		cfg.AuroraSecretsManagerSecret = *auroraSecret
 	}
+	if *auroraPassword != "" {
+		cfg.AuroraPassword = *auroraPassword
+	}
+	if *auroraAuthMode != "" {
+		cfg.AuroraAuthMode = *auroraAuthMode
+	}
 	if *auroraRegion != "" {
 		cfg.AuroraRegion = *auroraRegion
 	}
-	if *auroraDatabase != "" {
+	if setFlags["secrets-max-attempts"] {
+		cfg.SecretsMaxAttempts = *secretsMaxAttempts
+	}
+	if setFlags["aurora-database"] {
 		cfg.AuroraDatabase = *auroraDatabase
 	}
+	if *auroraParams != "" {
+		cfg.AuroraParams = *auroraParams
+	}
 	if *executeSQL {
 		cfg.ExecuteSQL = true
 	}
-	if *sqlExecTimeout > 0 {
+	if *skipSQLGen {
+		cfg.SkipSQLGen = true
+	}
+	if *directLoad {
+		cfg.DirectLoad = true
+	}
+	if setFlags["direct-load-warn-rows"] {
+		cfg.DirectLoadWarnRows = *directLoadWarnRows
+	}
+	if *verifySample != 0 {
+		cfg.VerifySample = *verifySample
+	}
+	if setFlags["check-schema"] {
+		cfg.CheckSchema = *checkSchema
+	}
+	if *skipPreflight {
+		cfg.SkipPreflight = true
+	}
+	if *fullVerify {
+		cfg.FullVerify = true
+	}
+	if *verifyDiff {
+		cfg.VerifyDiff = true
+	}
+	if *manifest {
+		cfg.Manifest = true
+	}
+	if *verifyManifest {
+		cfg.VerifyManifest = true
+	}
+	if *listOrphanObjects {
+		cfg.ListOrphanObjects = true
+	}
+	if *prune {
+		cfg.Prune = true
+	}
+	if *validateCoverage {
+		cfg.ValidateCoverage = true
+	}
+	if setFlags["sql-exec-timeout"] {
 		cfg.SQLExecTimeout = *sqlExecTimeout
 	}
+	if *sqlStatementTimeout != 0 {
+		cfg.SQLStatementTimeout = *sqlStatementTimeout
+	}
+	if *sqlTotalTimeout != 0 {
+		cfg.SQLTotalTimeout = *sqlTotalTimeout
+	}
+	if setFlags["sql-reconnect-retries"] {
+		cfg.SQLReconnectRetries = *sqlReconnectRetries
+	}
+	if *sqlTransactional {
+		cfg.SQLTransactional = true
+	}
+	if *loadViaLocal {
+		cfg.LoadViaLocal = true
+	}
+	if *auditLog != "" {
+		cfg.AuditLog = *auditLog
+	}
+	if *auditLogUpload {
+		cfg.AuditLogUpload = true
+	}
+	if *sqlDuplicateMode != "" {
+		cfg.SQLDuplicateMode = *sqlDuplicateMode
+	}
+	if *nullMarker != "" {
+		cfg.NullMarker = *nullMarker
+	}
+	if *csvDelimiter != "" {
+		cfg.CSVDelimiter = *csvDelimiter
+	}
+	if setFlags["csv-header"] {
+		cfg.CSVHeader = *csvHeader
+	}
+	if *loadExtraClauses != "" {
+		cfg.LoadExtraClauses = *loadExtraClauses
+	}
 	if *quiet {
 		cfg.Quiet = true
 	}
+	if *printSQL {
+		cfg.PrintSQL = true
+	}
+	if *previewSQL {
+		cfg.PreviewSQL = true
+	}
+	if *logLevel != "" {
+		cfg.LogLevel = *logLevel
+	}
+	if *logStdout {
+		cfg.LogStdout = true
+	}
+	if *notifyWebhook != "" {
+		cfg.NotifyWebhook = *notifyWebhook
+	}
+	if *logDir != "" {
+		cfg.LogDir = *logDir
+	}
+	if *excludeWhere != "" {
+		cfg.ExcludeWhere = *excludeWhere
+	}
+	if *where != "" {
+		cfg.Where = *where
+	}
+	if *allowRawWhere {
+		cfg.AllowRawWhere = true
+	}
+	if *sinceCheckpoint {
+		cfg.SinceCheckpoint = true
+	}
+	if *validateJSON != "" {
+		cfg.ValidateJSON = *validateJSON
+	}
+	if *excludeColumns != "" {
+		cfg.ExcludeColumns = *excludeColumns
+	}
+	if *maskAggr != "" {
+		cfg.MaskAggr = *maskAggr
+	}
+	if *compress != "" {
+		cfg.Compress = *compress
+	}
+	if *compressLevel != 0 {
+		cfg.CompressLevel = *compressLevel
+	}
+	if *format != "" {
+		cfg.Format = *format
+	}
+	if *singleFile {
+		cfg.SingleFile = true
+	}
+	if *deadLetter != "" {
+		cfg.DeadLetter = *deadLetter
+	}
+	if *failOnInvalidRows {
+		cfg.FailOnInvalidRows = true
+	}
+	if *detectSourceChanges {
+		cfg.DetectSourceChanges = true
+	}
+	if *requireIndex {
+		cfg.RequireIndex = true
+	}
+	if *controlFile != "" {
+		cfg.ControlFile = *controlFile
+	}
+	if setFlags["control-poll-interval"] {
+		cfg.ControlPollInterval = *controlPollInterval
+	}
 
 	// Set defaults
+	if cfg.TableName == "" {
+		cfg.TableName = "fis_aggr"
+	}
+	if cfg.TenantColumn == "" {
+		cfg.TenantColumn = "tenantid"
+	}
+	if cfg.S3Prefix == "" {
+		cfg.S3Prefix = "fis-migration"
+	}
+	segmentsSet := cfg.Segments != 0
 	if cfg.Segments == 0 {
 		cfg.Segments = 16
 	}
+	if cfg.RowsPerSegment == 0 {
+		cfg.RowsPerSegment = 500000
+	}
+	if cfg.ReplicaLagAction == "" {
+		cfg.ReplicaLagAction = "abort"
+	}
 	if cfg.MaxParallelSegs == 0 {
 		cfg.MaxParallelSegs = 8
 	}
+	if cfg.AdaptiveTargetLatency == 0 {
+		cfg.AdaptiveTargetLatency = 120
+	}
+	batchSizeSet := cfg.BatchSize != 0
 	if cfg.BatchSize == 0 {
 		cfg.BatchSize = 100000
 	}
+	if cfg.MaxEmptyBatches == 0 {
+		cfg.MaxEmptyBatches = 1
+	}
+	if cfg.MaxBatchesPerSegment == 0 {
+		cfg.MaxBatchesPerSegment = 10000
+	}
+	if cfg.SegmentOrder == "" {
+		cfg.SegmentOrder = "natural"
+	}
+	if cfg.PartitionStrategy == "" {
+		cfg.PartitionStrategy = "hash"
+	}
+	if cfg.IsolationLevel == "" {
+		cfg.IsolationLevel = "repeatable-read"
+	}
+	if cfg.Compress == "" {
+		cfg.Compress = "none"
+	}
+	if cfg.Format == "" {
+		cfg.Format = "csv"
+	}
+	if cfg.S3Addressing == "" {
+		cfg.S3Addressing = "auto"
+	}
+	if cfg.SegmentTimeout == 0 {
+		cfg.SegmentTimeout = 600
+	}
+	if cfg.ConcurrencyWeights == "" {
+		cfg.ConcurrencyWeights = "export=2,upload=1,load=1"
+	}
 	if cfg.CSVDelimiter == "" {
 		cfg.CSVDelimiter = ","
 	}
+	if cfg.CSVDelimiter == `\t` {
+		cfg.CSVDelimiter = "\t"
+	}
 	if cfg.CSVQuote == "" {
 		cfg.CSVQuote = "\""
 	}
+	if cfg.NullMarker == "" {
+		cfg.NullMarker = `\N`
+	}
 	if cfg.MariaDBDatabase == "" {
 		cfg.MariaDBDatabase = "fis"
 	}
@@ -222,151 +951,1182 @@ func LoadConfig() (*Config, error) {
 	if cfg.SQLExecTimeout == 0 {
 		cfg.SQLExecTimeout = 300
 	}
-
-	// Validate required fields
-	if cfg.TenantID <= 0 {
-		return nil, fmt.Errorf("tenant-id is required")
+	if cfg.SecretsMaxAttempts == 0 {
+		cfg.SecretsMaxAttempts = 5
 	}
-	if cfg.TableName == "" {
-		return nil, fmt.Errorf("table-name is required")
+	if cfg.SQLReconnectRetries == 0 && !cfg.SQLTransactional {
+		cfg.SQLReconnectRetries = 3
 	}
-	if cfg.MariaDBHost == "" {
-		return nil, fmt.Errorf("mariadb-host is required")
+	if cfg.LockRetries == 0 {
+		cfg.LockRetries = 3
+	}
+	if cfg.DBConnectBackoff == 0 {
+		cfg.DBConnectBackoff = time.Second
+	}
+	if cfg.DirectLoadWarnRows == 0 {
+		cfg.DirectLoadWarnRows = 1000000
 	}
-	if cfg.S3Bucket == "" {
-		return nil, fmt.Errorf("s3-bucket is required")
+	if cfg.SQLDuplicateMode == "" {
+		cfg.SQLDuplicateMode = "ignore"
 	}
-	if cfg.AWSRegion == "" {
-		return nil, fmt.Errorf("aws-region is required")
+	if cfg.AuroraAuthMode == "" {
+		cfg.AuroraAuthMode = "secretsmanager"
+	}
+	if cfg.ControlPollInterval == 0 {
+		cfg.ControlPollInterval = 5
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.LogDir == "" {
+		cfg.LogDir = "/tmp"
 	}
 
-	// Validate Aurora connection if execute-sql is set
-	if cfg.ExecuteSQL {
-		if cfg.AuroraHost == "" {
-			return nil, fmt.Errorf("aurora-host is required when -execute-sql is set")
-		}
-		if cfg.AuroraUser == "" {
-			return nil, fmt.Errorf("aurora-user is required when -execute-sql is set")
+	// Load tenant list for batch migrations
+	if cfg.TenantIDsFile != "" {
+		ids, err := ReadTenantIDsFile(cfg.TenantIDsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tenant IDs file: %w", err)
 		}
-		if cfg.AuroraSecretsManagerSecret == "" {
-			return nil, fmt.Errorf("aurora-secret is required when -execute-sql is set")
+		cfg.TenantIDs = ids
+		cfg.TenantID = ids[0]
+	}
+	if len(cfg.Tenants) > 0 {
+		if cfg.TenantIDsFile != "" {
+			return nil, fmt.Errorf("the config file's tenants list can't be combined with tenant-ids-file")
 		}
-		if cfg.AuroraRegion == "" {
-			return nil, fmt.Errorf("aurora-region is required when -execute-sql is set")
+		ids, err := validateTenants(cfg)
+		if err != nil {
+			return nil, err
 		}
+		cfg.TenantIDs = ids
+		cfg.TenantID = ids[0]
 	}
-
-	return cfg, nil
-}
-
-// loadFromYAML loads configuration from a YAML file.
-func loadFromYAML(cfg *Config, filepath string) error {
-	data, err := os.ReadFile(filepath)
-	if err != nil {
-		return err
+	if len(cfg.Tables) > 0 {
+		cfg.TableName = cfg.Tables[0]
 	}
 
-	var yamlCfg struct {
-		TenantID                   int    `yaml:"tenant_id"`
-		TableName                  string `yaml:"table_name"`
-		MariaDBHost                string `yaml:"mariadb_host"`
-		MariaDBPort                int    `yaml:"mariadb_port"`
-		MariaDBUser                string `yaml:"mariadb_user"`
-		MariaDBPassword            string `yaml:"mariadb_password"`
-		MariaDBDatabase            string `yaml:"mariadb_database"`
-		S3Bucket                   string `yaml:"s3_bucket"`
-		S3Prefix                   string `yaml:"s3_prefix"`
-		AWSRegion                  string `yaml:"aws_region"`
-		AWSAccessKeyID             string `yaml:"aws_access_key_id"`
-		AWSSecretAccessKey         string `yaml:"aws_secret_access_key"`
-		AWSSessionToken            string `yaml:"aws_session_token"`
-		AuroraHost                 string `yaml:"aurora_host"`
-		AuroraPort                 int    `yaml:"aurora_port"`
-		AuroraUser                 string `yaml:"aurora_user"`
-		AuroraSecretsManagerSecret string `yaml:"aurora_secret"`
-		AuroraRegion               string `yaml:"aurora_region"`
-		AuroraDatabase             string `yaml:"aurora_database"`
-		ExecuteSQL                 bool   `yaml:"execute_sql"`
-		Segments                   int    `yaml:"segments"`
-		MaxParallelSegs            int    `yaml:"max_parallel_segments"`
-		BatchSize                  int    `yaml:"batch_size"`
-		SQLExecTimeout             int    `yaml:"sql_exec_timeout"`
+	// Validate required fields
+	if cfg.TenantID <= 0 {
+		return nil, fmt.Errorf("tenant-id or tenant-ids-file is required")
 	}
-
-	if err := yaml.Unmarshal(data, &yamlCfg); err != nil {
-		return err
+	if cfg.TableName == "" {
+		return nil, fmt.Errorf("table-name is required")
 	}
-
-	// Apply YAML values to config (only if not already set)
-	if yamlCfg.TenantID > 0 {
-		cfg.TenantID = yamlCfg.TenantID
+	if !columnName.MatchString(cfg.TenantColumn) {
+		return nil, fmt.Errorf("invalid tenant-column %q: must be a plain column name", cfg.TenantColumn)
 	}
-	if yamlCfg.TableName != "" {
-		cfg.TableName = yamlCfg.TableName
+	if cfg.MariaDBHost == "" {
+		return nil, fmt.Errorf("mariadb-host is required")
 	}
-	if yamlCfg.MariaDBHost != "" {
-		cfg.MariaDBHost = yamlCfg.MariaDBHost
+	if _, err := ParseDSNParams(cfg.MariaDBParams); err != nil {
+		return nil, fmt.Errorf("invalid mariadb-params: %w", err)
 	}
-	if yamlCfg.MariaDBPort > 0 {
-		cfg.MariaDBPort = yamlCfg.MariaDBPort
+	if cfg.DBConnectRetries < 0 {
+		return nil, fmt.Errorf("invalid db-connect-retries %d: must not be negative", cfg.DBConnectRetries)
 	}
-	if yamlCfg.MariaDBUser != "" {
-		cfg.MariaDBUser = yamlCfg.MariaDBUser
+	if cfg.DBConnectBackoff < 0 {
+		return nil, fmt.Errorf("invalid db-connect-backoff %s: must not be negative", cfg.DBConnectBackoff)
 	}
-	if yamlCfg.MariaDBPassword != "" {
-		cfg.MariaDBPassword = yamlCfg.MariaDBPassword
+	if cfg.ReplicaMaxLag < 0 {
+		return nil, fmt.Errorf("invalid replica-max-lag %s: must not be negative", cfg.ReplicaMaxLag)
 	}
-	if yamlCfg.MariaDBDatabase != "" {
-		cfg.MariaDBDatabase = yamlCfg.MariaDBDatabase
+	if cfg.ReplicaMaxLag > 0 && cfg.MariaDBReplicaHost == "" {
+		return nil, fmt.Errorf("-replica-max-lag requires -mariadb-replica-host")
 	}
-	if yamlCfg.S3Bucket != "" {
-		cfg.S3Bucket = yamlCfg.S3Bucket
+	if cfg.ReplicaLagAction != "warn" && cfg.ReplicaLagAction != "abort" {
+		return nil, fmt.Errorf("invalid replica-lag-action %q (expected warn or abort)", cfg.ReplicaLagAction)
 	}
-	if yamlCfg.S3Prefix != "" {
-		cfg.S3Prefix = yamlCfg.S3Prefix
+	if _, err := ParseDSNParams(cfg.AuroraParams); err != nil {
+		return nil, fmt.Errorf("invalid aurora-params: %w", err)
 	}
-	if yamlCfg.AWSRegion != "" {
-		cfg.AWSRegion = yamlCfg.AWSRegion
+	if cfg.S3Bucket == "" && cfg.OutputDir == "" && cfg.SingleHash == "" && !cfg.Report && !cfg.DetectDuplicates && !cfg.DirectLoad {
+		return nil, fmt.Errorf("s3-bucket is required (or -output-dir for local-only output)")
+	}
+	if cfg.SingleHash != "" {
+		if !hexString.MatchString(cfg.SingleHash) {
+			return nil, fmt.Errorf("invalid single-hash %q: must be hex", cfg.SingleHash)
+		}
+		if len(cfg.TenantIDs) > 1 || len(cfg.Tables) > 1 {
+			return nil, fmt.Errorf("single-hash reads one tenant's table, it can't be combined with several tenants or tables")
+		}
+		if cfg.Report {
+			return nil, fmt.Errorf("single-hash and report can't be combined")
+		}
+	}
+	if cfg.DumpSchema && (cfg.SingleHash != "" || cfg.Report) {
+		return nil, fmt.Errorf("dump-schema can't be combined with single-hash or report")
+	}
+	if cfg.DumpSchemaAurora && !cfg.DumpSchema {
+		return nil, fmt.Errorf("-dump-schema-aurora requires -dump-schema")
+	}
+	if cfg.VerifyManifest && (cfg.SingleHash != "" || cfg.Report || cfg.DumpSchema || cfg.ExecuteSQL) {
+		return nil, fmt.Errorf("verify-manifest can't be combined with single-hash, report, dump-schema or execute-sql")
+	}
+	if cfg.DetectDuplicates && (cfg.SingleHash != "" || cfg.Report || cfg.DumpSchema || cfg.VerifyManifest || cfg.PreviewSQL) {
+		return nil, fmt.Errorf("detect-duplicates can't be combined with single-hash, report, dump-schema, verify-manifest or preview-sql")
+	}
+	if cfg.MaxRuntime < 0 {
+		return nil, fmt.Errorf("invalid max-runtime %s: must not be negative", cfg.MaxRuntime)
+	}
+	if cfg.AutoSegments {
+		if segmentsSet {
+			return nil, fmt.Errorf("segments and auto-segments are mutually exclusive")
+		}
+		// Segment indices depend on the count, which isn't known until the tenant's rows are counted
+		if cfg.OnlySegments != "" || cfg.SkipSegments != "" {
+			return nil, fmt.Errorf("-auto-segments can't be combined with -only-segments or -skip-segments (set -segments instead)")
+		}
+	}
+	if cfg.RowsPerSegment < 1 {
+		return nil, fmt.Errorf("invalid rows-per-segment %d: must be at least 1", cfg.RowsPerSegment)
+	}
+	if cfg.BatchBytes < 0 {
+		return nil, fmt.Errorf("invalid batch-bytes %d: must not be negative", cfg.BatchBytes)
+	}
+	if cfg.MaxParallelUploads < 0 {
+		return nil, fmt.Errorf("invalid max-parallel-uploads %d: must not be negative", cfg.MaxParallelUploads)
+	}
+	if cfg.S3CircuitThreshold < 0 {
+		return nil, fmt.Errorf("invalid s3-circuit-threshold %d: must not be negative", cfg.S3CircuitThreshold)
+	}
+	if cfg.BatchBytes > 0 {
+		if batchSizeSet {
+			return nil, fmt.Errorf("batch-size and batch-bytes are mutually exclusive")
+		}
+		// S3 rejects multipart parts other than the last below 5 MiB, and any part above 5 GiB
+		if cfg.S3Bucket != "" && (cfg.BatchBytes < minPartBytes || cfg.BatchBytes > maxPartBytes) {
+			return nil, fmt.Errorf("invalid batch-bytes %d: S3 multipart parts must be between %d (5 MiB) and %d (5 GiB) bytes",
+				cfg.BatchBytes, minPartBytes, maxPartBytes)
+		}
+	}
+	if cfg.ResumeUploads && cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("resume-uploads requires s3-bucket")
+	}
+	if cfg.CheckpointInterval < 0 {
+		return nil, fmt.Errorf("invalid checkpoint-interval %d: must not be negative", cfg.CheckpointInterval)
+	}
+	if cfg.CheckpointInterval > 0 {
+		// The checkpointed parts are kept in the resumed upload, one part per batch
+		if !cfg.Resume || !cfg.ResumeUploads {
+			return nil, fmt.Errorf("checkpoint-interval requires -resume and -resume-uploads (a resumed segment continues its multipart upload)")
+		}
+		if cfg.BatchBytes > 0 || cfg.OutputDir != "" || cfg.DirectLoad || cfg.Compress != "none" {
+			return nil, fmt.Errorf("-checkpoint-interval can't be combined with -batch-bytes, -output-dir, -direct-load or -compress (only S3 parts of one batch each can be continued)")
+		}
+	}
+	if cfg.TimestampKeys && (cfg.Resume || cfg.ResumeUploads) {
+		return nil, fmt.Errorf("-timestamp-keys can't be combined with -resume or -resume-uploads (each run writes under its own run-<timestamp> prefix)")
+	}
+	if cfg.S3Endpoint != "" {
+		if u, err := url.Parse(cfg.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid s3-endpoint %q (expected an http:// or https:// URL)", cfg.S3Endpoint)
+		}
+	}
+	switch cfg.S3Addressing {
+	case "auto", "path":
+	case "virtual":
+		if cfg.S3PathStyle {
+			return nil, fmt.Errorf("-s3-path-style can't be combined with -s3-addressing virtual")
+		}
+	default:
+		return nil, fmt.Errorf("invalid s3-addressing %q (expected auto, path or virtual)", cfg.S3Addressing)
+	}
+	if cfg.NotifyWebhook != "" {
+		if u, err := url.Parse(cfg.NotifyWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid notify-webhook (expected an http:// or https:// URL)")
+		}
+	}
+	if cfg.SecretsMaxAttempts < 1 {
+		return nil, fmt.Errorf("secrets-max-attempts must be at least 1")
+	}
+	switch cfg.SegmentOrder {
+	case "natural", "largest-first", "smallest-first":
+	default:
+		return nil, fmt.Errorf("invalid segment-order %q (expected natural, largest-first or smallest-first)", cfg.SegmentOrder)
+	}
+	switch cfg.PartitionStrategy {
+	case "hash":
+		if cfg.PartitionColumn != "" {
+			return nil, fmt.Errorf("-partition-column requires -partition-strategy range")
+		}
+	case "range":
+		if cfg.PartitionColumn == "" {
+			return nil, fmt.Errorf("-partition-strategy range requires -partition-column")
+		}
+		if !columnName.MatchString(cfg.PartitionColumn) {
+			return nil, fmt.Errorf("invalid partition-column %q: must be a plain column name", cfg.PartitionColumn)
+		}
+		// Range segments exist only for this run: their bounds come from the tenant's data,
+		// and checkpoints, manifests and hash checks only know hash segments
+		if cfg.Resume || cfg.Manifest || cfg.VerifyManifest {
+			return nil, fmt.Errorf("-partition-strategy range can't be combined with -resume, -manifest or -verify-manifest (they record hash segments)")
+		}
+		if cfg.VerifySample > 0 || cfg.CheckSegmentCardinality {
+			return nil, fmt.Errorf("-partition-strategy range can't be combined with -verify-sample or -check-segment-cardinality (they check hash prefixes)")
+		}
+		if cfg.SingleFile {
+			return nil, fmt.Errorf("-partition-strategy range can't be combined with -single-file (it writes the segments in hash order)")
+		}
+	default:
+		return nil, fmt.Errorf("invalid partition-strategy %q (expected hash or range)", cfg.PartitionStrategy)
+	}
+	switch cfg.IsolationLevel {
+	case "repeatable-read", "read-committed", "snapshot":
+	default:
+		return nil, fmt.Errorf("invalid isolation-level %q (expected repeatable-read, read-committed or snapshot)", cfg.IsolationLevel)
+	}
+	if cfg.SegmentTimeout < 0 {
+		return nil, fmt.Errorf("invalid segment-timeout %d: must not be negative", cfg.SegmentTimeout)
+	}
+	if cfg.QueryTimeout < 0 {
+		return nil, fmt.Errorf("invalid query-timeout %d: must not be negative", cfg.QueryTimeout)
+	}
+	if cfg.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(cfg.ContentType); err != nil {
+			return nil, fmt.Errorf("invalid content-type %q: %w", cfg.ContentType, err)
+		}
+	}
+	if cfg.AllowRawWhere && cfg.Where == "" {
+		return nil, fmt.Errorf("-allow-raw-where requires -where")
+	}
+	if cfg.SinceCheckpoint {
+		if !cfg.ExecuteSQL && !cfg.DirectLoad {
+			return nil, fmt.Errorf("-since-checkpoint requires -execute-sql or -direct-load (it reads the newest last_modified from Aurora)")
+		}
+		if cfg.Resume || cfg.FullVerify || cfg.VerifyDiff || cfg.Manifest || cfg.VerifyManifest {
+			return nil, fmt.Errorf("-since-checkpoint can't be combined with -resume, -full-verify, -verify-diff, -manifest or -verify-manifest (they cover every row of the tenant)")
+		}
+	}
+	if cfg.AdaptiveTargetLatency < 0 {
+		return nil, fmt.Errorf("invalid adaptive-target-latency %d: must not be negative", cfg.AdaptiveTargetLatency)
+	}
+	if cfg.AdaptiveParallelism && cfg.SingleFile {
+		return nil, fmt.Errorf("-adaptive-parallelism can't be combined with -single-file (segments run one at a time)")
+	}
+	if cfg.LockRetries < 0 {
+		return nil, fmt.Errorf("invalid lock-retries %d: must not be negative", cfg.LockRetries)
+	}
+	if cfg.MaxEmptyBatches < 0 {
+		return nil, fmt.Errorf("invalid max-empty-batches %d: must not be negative", cfg.MaxEmptyBatches)
+	}
+	// The segment's context would expire first, the query timeout could never fire
+	if cfg.QueryTimeout >= cfg.SegmentTimeout && cfg.QueryTimeout > 0 {
+		return nil, fmt.Errorf("query-timeout %d must be below segment-timeout %d", cfg.QueryTimeout, cfg.SegmentTimeout)
+	}
+	if cfg.S3KeyTemplate != "" {
+		if _, err := ParseS3KeyTemplate(cfg.S3KeyTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.SQLDuplicateMode {
+	case "ignore", "replace", "error":
+	default:
+		return nil, fmt.Errorf("invalid sql-duplicate-mode %q (expected ignore, replace or error)", cfg.SQLDuplicateMode)
+	}
+	switch cfg.AuroraAuthMode {
+	case "secretsmanager", "iam":
+	default:
+		return nil, fmt.Errorf("invalid aurora-auth-mode %q (expected secretsmanager or iam)", cfg.AuroraAuthMode)
+	}
+	if cfg.AuroraPassword != "" && cfg.AuroraAuthMode == "iam" {
+		return nil, fmt.Errorf("-aurora-password can't be combined with -aurora-auth-mode iam (it falls back from Secrets Manager)")
+	}
+	// The marker must be written unquoted, or LOAD DATA won't recognise \N as NULL
+	if r, size := utf8.DecodeRuneInString(cfg.CSVDelimiter); size != len(cfg.CSVDelimiter) || r == utf8.RuneError || strings.ContainsRune("\"\\\r\n", r) {
+		return nil, fmt.Errorf("invalid csv-delimiter %q (must be one character other than a quote, backslash or newline)", cfg.CSVDelimiter)
+	}
+	if cfg.CSVQuote != "\"" {
+		return nil, fmt.Errorf("invalid CSV quote %q (the CSV writer only quotes with \")", cfg.CSVQuote)
+	}
+	if strings.ContainsAny(cfg.NullMarker, cfg.CSVDelimiter+",\"\r\n") {
+		return nil, fmt.Errorf("invalid null-marker %q (must not contain commas, the CSV delimiter, quotes or newlines)", cfg.NullMarker)
+	}
+	// A transactional load stops at the first failure, so the continue-on-error recovery paths don't apply
+	if cfg.SQLTransactional && cfg.SQLReconnectRetries > 0 {
+		return nil, fmt.Errorf("-sql-transactional cannot be combined with -sql-reconnect-retries (a reconnect loses the open transaction)")
+	}
+	if cfg.LoadViaLocal {
+		if !cfg.ExecuteSQL {
+			return nil, fmt.Errorf("-load-via-local requires -execute-sql")
+		}
+		if cfg.SQLTransactional {
+			return nil, fmt.Errorf("-load-via-local can't be combined with -sql-transactional (a transactional load rolls back at the first failed statement)")
+		}
+	}
+	// Only statements run by -execute-sql are audited
+	if cfg.AuditLog != "" && !cfg.ExecuteSQL {
+		return nil, fmt.Errorf("-audit-log requires -execute-sql")
+	}
+	if cfg.AuditLogUpload && cfg.AuditLog == "" {
+		return nil, fmt.Errorf("-audit-log-upload requires -audit-log")
+	}
+
+	if _, _, err := cfg.SegmentSelection(); err != nil {
+		return nil, err
+	}
+	// A tenant's segments override changes the indices -only-segments and -skip-segments can name
+	for _, override := range cfg.Tenants {
+		if _, _, err := cfg.ForTenant(override.TenantID).SegmentSelection(); err != nil {
+			return nil, fmt.Errorf("tenant %d: %w", override.TenantID, err)
+		}
+	}
+
+	if cfg.SQLStatementTimeout < 0 || cfg.SQLTotalTimeout < 0 {
+		return nil, fmt.Errorf("sql-statement-timeout and sql-total-timeout must be >= 0")
+	}
+	if cfg.VerifySample < 0 {
+		return nil, fmt.Errorf("verify-sample must be >= 0, got %d", cfg.VerifySample)
+	}
+	if cfg.VerifySample > 0 && cfg.LocalOutputOnly() {
+		return nil, fmt.Errorf("-verify-sample requires -s3-bucket (it re-downloads uploaded CSV objects)")
+	}
+
+	if cfg.ListOrphanObjects && cfg.LocalOutputOnly() {
+		return nil, fmt.Errorf("-list-orphan-objects requires -s3-bucket (it lists the uploaded objects)")
+	}
+	if cfg.ListOrphanObjects && cfg.S3KeyTemplate != "" {
+		return nil, fmt.Errorf("-list-orphan-objects can't be combined with -s3-key-template (keys may not be under <prefix>/tenant-<id>/<table>/)")
+	}
+	if cfg.Prune && !cfg.ListOrphanObjects {
+		return nil, fmt.Errorf("-prune requires -list-orphan-objects")
+	}
+	// Segments this run doesn't export would look orphaned, and pruning would delete their CSV files
+	if cfg.Prune && (cfg.OnlySegments != "" || cfg.SkipSegments != "" || cfg.ContinueOnSegmentError) {
+		return nil, fmt.Errorf("-prune can't be combined with -only-segments, -skip-segments or -continue-on-segment-error")
+	}
+	if cfg.FailFastAbort {
+		if cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("-fail-fast-abort requires -s3-bucket (it aborts S3 uploads)")
+		}
+		if cfg.ContinueOnSegmentError {
+			return nil, fmt.Errorf("-fail-fast-abort can't be combined with -continue-on-segment-error (it aborts the run at the first failed segment)")
+		}
+		if cfg.ResumeUploads {
+			return nil, fmt.Errorf("-fail-fast-abort can't be combined with -resume-uploads (it keeps failed uploads to resume them)")
+		}
+	}
+	// The segments of a targeted re-run don't cover the tenant's rows
+	if cfg.ValidateCoverage && (cfg.OnlySegments != "" || cfg.SkipSegments != "") {
+		return nil, fmt.Errorf("-validate-coverage can't be combined with -only-segments or -skip-segments (only all segments cover the tenant's rows)")
+	}
+
+	if cfg.SkipSQLGen && cfg.ExecuteSQL {
+		return nil, fmt.Errorf("-skip-sql-gen can't be combined with -execute-sql (there would be no SQL to execute)")
+	}
+	if cfg.PrintSQL {
+		if cfg.LocalOutputOnly() {
+			return nil, fmt.Errorf("-print-sql requires -s3-bucket (LOAD DATA FROM S3 can't read local-only output)")
+		}
+		if cfg.SkipSQLGen {
+			return nil, fmt.Errorf("-print-sql can't be combined with -skip-sql-gen (there would be no SQL to print)")
+		}
+		// JSON log lines would be mixed into the statements
+		if cfg.LogStdout {
+			return nil, fmt.Errorf("-print-sql can't be combined with -log-stdout")
+		}
+	}
+	// The statements are rendered from the config alone, with one CSV file per segment
+	if cfg.PreviewSQL {
+		if cfg.LocalOutputOnly() {
+			return nil, fmt.Errorf("-preview-sql requires -s3-bucket (LOAD DATA FROM S3 can't read local-only output)")
+		}
+		if cfg.SingleHash != "" || cfg.Report || cfg.DumpSchema || cfg.VerifyManifest {
+			return nil, fmt.Errorf("-preview-sql can't be combined with single-hash, report, dump-schema or verify-manifest")
+		}
+		if cfg.AutoSegments || cfg.PartitionStrategy == "range" {
+			return nil, fmt.Errorf("-preview-sql can't be combined with -auto-segments or -partition-strategy range (their segments depend on the source rows)")
+		}
+		if cfg.LogStdout {
+			return nil, fmt.Errorf("-preview-sql can't be combined with -log-stdout")
+		}
+	}
+	if cfg.FullVerify && !cfg.ExecuteSQL && !cfg.DirectLoad {
+		return nil, fmt.Errorf("-full-verify requires -execute-sql or -direct-load (it compares the loaded Aurora table with the source)")
+	}
+	switch cfg.MaskAggr {
+	case "", "placeholder", "sha256":
+	default:
+		return nil, fmt.Errorf("invalid mask-aggr %q (expected placeholder or sha256)", cfg.MaskAggr)
+	}
+	if cfg.MaskAggr != "" && cfg.FullVerify {
+		return nil, fmt.Errorf("-mask-aggr can't be combined with -full-verify (masked rows never match the source)")
+	}
+	if cfg.VerifyDiff && !cfg.ExecuteSQL && !cfg.DirectLoad {
+		return nil, fmt.Errorf("-verify-diff requires -execute-sql or -direct-load (it compares the loaded Aurora table with the source)")
+	}
+	if cfg.MaskAggr != "" && cfg.VerifyDiff {
+		return nil, fmt.Errorf("-mask-aggr can't be combined with -verify-diff (masked rows never match the source)")
+	}
+	if excluded := cfg.ExcludedColumns(); len(excluded) > 0 {
+		seen := make(map[string]bool, len(excluded))
+		for _, column := range excluded {
+			switch column {
+			case "aggr", "last_modified", "version":
+			default:
+				return nil, fmt.Errorf("invalid exclude-columns %q: can't exclude %s (expected aggr, last_modified or version)", cfg.ExcludeColumns, column)
+			}
+			if seen[column] {
+				return nil, fmt.Errorf("invalid exclude-columns %q: %s is listed twice", cfg.ExcludeColumns, column)
+			}
+			seen[column] = true
+		}
+		if cfg.FullVerify || cfg.VerifyDiff || cfg.VerifySample > 0 || cfg.VerifyManifest {
+			return nil, fmt.Errorf("-exclude-columns can't be combined with -full-verify, -verify-diff, -verify-sample or -verify-manifest (the loaded rows lack the excluded columns)")
+		}
+		if cfg.DirectLoad || cfg.Format == "parquet" {
+			return nil, fmt.Errorf("-exclude-columns can't be combined with -direct-load or -format parquet (they write every column)")
+		}
+		if seen["aggr"] && cfg.MaskAggr != "" {
+			return nil, fmt.Errorf("-mask-aggr can't be combined with -exclude-columns aggr (there is no aggr to mask)")
+		}
+		if seen["aggr"] && cfg.ValidateJSON != "" {
+			return nil, fmt.Errorf("-validate-json can't be combined with -exclude-columns aggr (aggr isn't read)")
+		}
+	}
+	switch cfg.ValidateJSON {
+	case "", "skip", "fail":
+	default:
+		return nil, fmt.Errorf("invalid validate-json %q (expected skip or fail)", cfg.ValidateJSON)
+	}
+	switch cfg.Compress {
+	case "none":
+		if cfg.CompressLevel != 0 {
+			return nil, fmt.Errorf("-compress-level requires -compress gzip or zstd")
+		}
+	case "gzip":
+		if err := ValidateCompressLevel(cfg.Compress, cfg.CompressLevel); err != nil {
+			return nil, err
+		}
+	case "zstd":
+		if err := ValidateCompressLevel(cfg.Compress, cfg.CompressLevel); err != nil {
+			return nil, err
+		}
+		// Aurora LOAD DATA FROM S3 reads gzip but not zstd
+		if cfg.ExecuteSQL {
+			return nil, fmt.Errorf("-compress zstd can't be combined with -execute-sql (LOAD DATA FROM S3 can't read zstd, use gzip)")
+		}
+	default:
+		return nil, fmt.Errorf("invalid compress %q (expected none, gzip or zstd)", cfg.Compress)
+	}
+	if cfg.Compress != "none" && cfg.VerifySample > 0 {
+		return nil, fmt.Errorf("-verify-sample can't be combined with -compress (it parses the uploaded objects as plain CSV)")
+	}
+	switch cfg.Format {
+	case "csv":
+	case "parquet":
+		// Aurora LOAD DATA FROM S3 can't read Parquet, so no SQL is generated
+		if cfg.ExecuteSQL || cfg.PrintSQL || cfg.PreviewSQL {
+			return nil, fmt.Errorf("-format parquet can't be combined with -execute-sql, -print-sql or -preview-sql (LOAD DATA FROM S3 can't read Parquet)")
+		}
+		if cfg.Compress != "none" {
+			return nil, fmt.Errorf("-format parquet can't be combined with -compress (Parquet files are Snappy-compressed)")
+		}
+		if cfg.VerifySample > 0 {
+			return nil, fmt.Errorf("-verify-sample can't be combined with -format parquet (it parses the uploaded objects as CSV)")
+		}
+		// A segment is uploaded as one Parquet file, not in parts
+		if cfg.BatchBytes > 0 || cfg.ResumeUploads {
+			return nil, fmt.Errorf("-format parquet can't be combined with -batch-bytes or -resume-uploads (each segment is uploaded as one file)")
+		}
+	default:
+		return nil, fmt.Errorf("invalid format %q (expected csv or parquet)", cfg.Format)
+	}
+	// One upload open across all segments: nothing to resume or reorder, and no per-segment files
+	if cfg.SingleFile {
+		if cfg.Format == "parquet" {
+			return nil, fmt.Errorf("-single-file can't be combined with -format parquet (each segment is its own Parquet file)")
+		}
+		if cfg.Resume || cfg.ResumeUploads || cfg.MaxRuntime > 0 {
+			return nil, fmt.Errorf("-single-file can't be combined with -resume, -resume-uploads or -max-runtime (a partial single file can't be continued)")
+		}
+		if cfg.ContinueOnSegmentError {
+			return nil, fmt.Errorf("-single-file can't be combined with -continue-on-segment-error (a failed segment fails the single file)")
+		}
+		if cfg.Manifest {
+			return nil, fmt.Errorf("-single-file can't be combined with -manifest (it records a file per segment)")
+		}
+		if cfg.SegmentOrder != "natural" {
+			return nil, fmt.Errorf("-single-file can't be combined with -segment-order %s (segments are written in hash order)", cfg.SegmentOrder)
+		}
+	}
+
+	// Validate Aurora connection if execute-sql is set
+	if cfg.ExecuteSQL {
+		if cfg.LocalOutputOnly() {
+			return nil, fmt.Errorf("-execute-sql requires -s3-bucket (LOAD DATA FROM S3 cannot read local output)")
+		}
+		if cfg.AuroraHost == "" {
+			return nil, fmt.Errorf("aurora-host is required when -execute-sql is set")
+		}
+		if cfg.AuroraUser == "" {
+			return nil, fmt.Errorf("aurora-user is required when -execute-sql is set")
+		}
+		if cfg.AuroraSecretsManagerSecret == "" && cfg.AuroraAuthMode == "secretsmanager" {
+			return nil, fmt.Errorf("aurora-secret is required when -execute-sql is set")
+		}
+		if cfg.AuroraRegion == "" {
+			return nil, fmt.Errorf("aurora-region is required when -execute-sql is set")
+		}
+	}
+
+	// -direct-load writes to the Aurora table instead of CSV files, so nothing is uploaded, written or loaded from S3
+	if cfg.DirectLoad {
+		if cfg.S3Bucket != "" || cfg.OutputDir != "" {
+			return nil, fmt.Errorf("-direct-load can't be combined with -s3-bucket or -output-dir (rows are inserted into Aurora, not written to CSV files)")
+		}
+		if cfg.SingleHash != "" || cfg.Report || cfg.DumpSchema || cfg.VerifyManifest || cfg.DetectDuplicates {
+			return nil, fmt.Errorf("-direct-load can't be combined with single-hash, report, dump-schema, verify-manifest or detect-duplicates")
+		}
+		if cfg.ExecuteSQL || cfg.PrintSQL || cfg.PreviewSQL {
+			return nil, fmt.Errorf("-direct-load can't be combined with -execute-sql, -print-sql or -preview-sql (no LOAD DATA statements are generated)")
+		}
+		if cfg.Format == "parquet" || cfg.Compress != "none" || cfg.BatchBytes > 0 || cfg.SingleFile || cfg.Manifest {
+			return nil, fmt.Errorf("-direct-load can't be combined with -format parquet, -compress, -batch-bytes, -single-file or -manifest (they shape CSV files)")
+		}
+		if cfg.VerifySample > 0 || cfg.ListOrphanObjects {
+			return nil, fmt.Errorf("-direct-load can't be combined with -verify-sample or -list-orphan-objects (nothing is uploaded to S3)")
+		}
+		if cfg.DirectLoadWarnRows < 0 {
+			return nil, fmt.Errorf("invalid direct-load-warn-rows %d: must not be negative", cfg.DirectLoadWarnRows)
+		}
+		if cfg.AuroraHost == "" || cfg.AuroraUser == "" || cfg.AuroraRegion == "" {
+			return nil, fmt.Errorf("aurora-host, aurora-user and aurora-region are required when -direct-load is set")
+		}
+		if cfg.AuroraSecretsManagerSecret == "" && cfg.AuroraAuthMode == "secretsmanager" {
+			return nil, fmt.Errorf("aurora-secret is required when -direct-load is set")
+		}
+	}
+
+	// -verify-manifest reads the Aurora table without -execute-sql
+	if cfg.VerifyManifest {
+		if cfg.AuroraHost == "" || cfg.AuroraUser == "" || cfg.AuroraRegion == "" {
+			return nil, fmt.Errorf("aurora-host, aurora-user and aurora-region are required when -verify-manifest is set")
+		}
+		if cfg.AuroraSecretsManagerSecret == "" && cfg.AuroraAuthMode == "secretsmanager" {
+			return nil, fmt.Errorf("aurora-secret is required when -verify-manifest is set")
+		}
+	}
+
+	// Resolve the source password last so a missing required field fails before any AWS call
+	if err := cfg.resolveMariaDBSecret(*mariadbPassword != "" || *mariadbAuth != ""); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// S3 multipart part size limits, for -batch-bytes.
+const (
+	minPartBytes = 5 * 1024 * 1024
+	maxPartBytes = 5 * 1024 * 1024 * 1024
+)
+
+// columnName matches a plain (unquoted) SQL column name, so -tenant-column can be used in queries as is.
+var columnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// hexString matches a non-empty hex string (-single-hash).
+var hexString = regexp.MustCompile(`^[0-9A-Fa-f]+$`)
+
+// secretsManagerPassword fetches a password from AWS Secrets Manager (replaced in tests).
+var secretsManagerPassword = util.GetPasswordFromSecretsManager
+
+// resolveMariaDBSecret sets MariaDBPassword from -mariadb-secret, unless the password
+// was given on the command line (-mariadb-password or -mariadb-auth), which takes priority.
+func (c *Config) resolveMariaDBSecret(passwordFromCLI bool) error {
+	if c.MariaDBSecret == "" || passwordFromCLI {
+		return nil
+	}
+
+	region := c.MariaDBSecretRegion
+	if region == "" {
+		region = c.AWSRegion
+	}
+
+	util.LoadAWSCredentials(c.AWSAccessKeyID, c.AWSSecretAccessKey, c.AWSSessionToken)
+	password, err := secretsManagerPassword(c.MariaDBSecret, region, c.SecretsMaxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve mariadb-secret %s: %w", c.MariaDBSecret, err)
+	}
+	c.MariaDBPassword = password
+	return nil
+}
+
+// loadFromYAML loads configuration from a YAML file (the block named profile, if the file has profiles),
+// expanding environment variable references in string values.
+func loadFromYAML(cfg *Config, filepath, profile string, strictEnv bool) error {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	var yamlCfg struct {
+		TenantID                   int    `yaml:"tenant_id"`
+		TenantIDsFile              string `yaml:"tenant_ids_file"`
+		FailFast                   bool   `yaml:"fail_fast"`
+		TableName                  string `yaml:"table_name"`
+		Tables                     string `yaml:"tables"`
+		TenantColumn               string `yaml:"tenant_column"`
+		MariaDBHost                string `yaml:"mariadb_host"`
+		MariaDBPort                int    `yaml:"mariadb_port"`
+		MariaDBUser                string `yaml:"mariadb_user"`
+		MariaDBPassword            string `yaml:"mariadb_password"`
+		MariaDBSecret              string `yaml:"mariadb_secret"`
+		MariaDBSecretRegion        string `yaml:"mariadb_secret_region"`
+		DBMaxOpenConns             int    `yaml:"db_max_open_conns"`
+		DBMaxIdleConns             int    `yaml:"db_max_idle_conns"`
+		DBConnMaxLifetime          int    `yaml:"db_conn_max_lifetime"`
+		DBConnectRetries           int    `yaml:"db_connect_retries"`
+		DBConnectBackoff           string `yaml:"db_connect_backoff"`
+		MariaDBDatabase            string `yaml:"mariadb_database"`
+		MariaDBParams              string `yaml:"mariadb_params"`
+		MariaDBReplicaHost         string `yaml:"mariadb_replica_host"`
+		ReplicaMaxLag              string `yaml:"replica_max_lag"`
+		ReplicaLagAction           string `yaml:"replica_lag_action"`
+		S3Bucket                   string `yaml:"s3_bucket"`
+		OutputDir                  string `yaml:"output_dir"`
+		SingleHash                 string `yaml:"single_hash"`
+		Report                     bool   `yaml:"report"`
+		DetectDuplicates           bool   `yaml:"detect_duplicates"`
+		DumpSchema                 bool   `yaml:"dump_schema"`
+		DumpSchemaAurora           bool   `yaml:"dump_schema_aurora"`
+		S3Prefix                   string `yaml:"s3_prefix"`
+		S3KeyTemplate              string `yaml:"s3_key_template"`
+		PartitionByDate            bool   `yaml:"partition_by_date"`
+		TimestampKeys              bool   `yaml:"timestamp_keys"`
+		AWSRegion                  string `yaml:"aws_region"`
+		S3Tags                     string `yaml:"s3_tags"`
+		S3StorageClass             string `yaml:"s3_storage_class"`
+		ContentType                string `yaml:"content_type"`
+		S3Endpoint                 string `yaml:"s3_endpoint"`
+		S3PathStyle                bool   `yaml:"s3_path_style"`
+		S3Addressing               string `yaml:"s3_addressing"`
+		UploadRateLimitMbps        int    `yaml:"upload_rate_limit_mbps"`
+		MaxParallelUploads         int    `yaml:"max_parallel_uploads"`
+		S3CircuitThreshold         int    `yaml:"s3_circuit_threshold"`
+		ResumeUploads              bool   `yaml:"resume_uploads"`
+		AWSAccessKeyID             string `yaml:"aws_access_key_id"`
+		AWSSecretAccessKey         string `yaml:"aws_secret_access_key"`
+		AWSSessionToken            string `yaml:"aws_session_token"`
+		AuroraHost                 string `yaml:"aurora_host"`
+		AuroraPort                 int    `yaml:"aurora_port"`
+		AuroraUser                 string `yaml:"aurora_user"`
+		AuroraSecretsManagerSecret string `yaml:"aurora_secret"`
+		AuroraPassword             string `yaml:"aurora_password"`
+		AuroraAuthMode             string `yaml:"aurora_auth_mode"`
+		AuroraRegion               string `yaml:"aurora_region"`
+		SecretsMaxAttempts         int    `yaml:"secrets_max_attempts"`
+		AuroraDatabase             string `yaml:"aurora_database"`
+		AuroraParams               string `yaml:"aurora_params"`
+		ExecuteSQL                 bool   `yaml:"execute_sql"`
+		SkipSQLGen                 bool   `yaml:"skip_sql_gen"`
+		DirectLoad                 bool   `yaml:"direct_load"`
+		DirectLoadWarnRows         int    `yaml:"direct_load_warn_rows"`
+		FullVerify                 bool   `yaml:"full_verify"`
+		VerifyDiff                 bool   `yaml:"verify_diff"`
+		Manifest                   bool   `yaml:"manifest"`
+		VerifyManifest             bool   `yaml:"verify_manifest"`
+		ListOrphanObjects          bool   `yaml:"list_orphan_objects"`
+		Prune                      bool   `yaml:"prune"`
+		ValidateCoverage           bool   `yaml:"validate_coverage"`
+		CheckSchema                *bool  `yaml:"check_schema"`
+		VerifySample               int    `yaml:"verify_sample"`
+		SkipPreflight              bool   `yaml:"skip_preflight"`
+		Segments                   int    `yaml:"segments"`
+		AutoSegments               bool   `yaml:"auto_segments"`
+		RowsPerSegment             int    `yaml:"rows_per_segment"`
+		OnlySegments               string `yaml:"only_segments"`
+		SkipSegments               string `yaml:"skip_segments"`
+		MaxParallelSegs            int    `yaml:"max_parallel_segments"`
+		AdaptiveParallelism        bool   `yaml:"adaptive_parallelism"`
+		AdaptiveTargetLatency      int    `yaml:"adaptive_target_latency"`
+		BatchSize                  int    `yaml:"batch_size"`
+		BatchBytes                 int    `yaml:"batch_bytes"`
+		MaxEmptyBatches            int    `yaml:"max_empty_batches"`
+		MaxBatchesPerSegment       int    `yaml:"max_batches_per_segment"`
+		SegmentOrder               string `yaml:"segment_order"`
+		CheckSegmentCardinality    bool   `yaml:"check_segment_cardinality"`
+		PartitionStrategy          string `yaml:"partition_strategy"`
+		PartitionColumn            string `yaml:"partition_column"`
+		IsolationLevel             string `yaml:"isolation_level"`
+		SegmentTimeout             int    `yaml:"segment_timeout"`
+		QueryTimeout               int    `yaml:"query_timeout"`
+		LockRetries                int    `yaml:"lock_retries"`
+		ContinueOnSegmentError     bool   `yaml:"continue_on_segment_error"`
+		FailFastAbort              bool   `yaml:"fail_fast_abort"`
+		MaxRuntime                 string `yaml:"max_runtime"`
+		Resume                     bool   `yaml:"resume"`
+		CheckpointInterval         int    `yaml:"checkpoint_interval"`
+		ConcurrencyBudget          int    `yaml:"concurrency_budget"`
+		ConcurrencyWeights         string `yaml:"concurrency_weights"`
+		SQLExecTimeout             int    `yaml:"sql_exec_timeout"`
+		SQLStatementTimeout        int    `yaml:"sql_statement_timeout"`
+		SQLTotalTimeout            int    `yaml:"sql_total_timeout"`
+		SQLReconnectRetries        int    `yaml:"sql_reconnect_retries"`
+		SQLTransactional           bool   `yaml:"sql_transactional"`
+		LoadViaLocal               bool   `yaml:"load_via_local"`
+		AuditLog                   string `yaml:"audit_log"`
+		AuditLogUpload             bool   `yaml:"audit_log_upload"`
+		SQLDuplicateMode           string `yaml:"sql_duplicate_mode"`
+		NullMarker                 string `yaml:"null_marker"`
+		CSVHeader                  *bool  `yaml:"csv_header"`
+		CSVDelimiter               string `yaml:"csv_delimiter"`
+		DeadLetter                 string `yaml:"dead_letter"`
+		FailOnInvalidRows          bool   `yaml:"fail_on_invalid_rows"`
+		ExcludeWhere               string `yaml:"exclude_where"`
+		Where                      string `yaml:"where"`
+		AllowRawWhere              bool   `yaml:"allow_raw_where"`
+		SinceCheckpoint            bool   `yaml:"since_checkpoint"`
+		MaskAggr                   string `yaml:"mask_aggr"`
+		ExcludeColumns             string `yaml:"exclude_columns"`
+		ValidateJSON               string `yaml:"validate_json"`
+		Compress                   string `yaml:"compress"`
+		CompressLevel              int    `yaml:"compress_level"`
+		Format                     string `yaml:"format"`
+		SingleFile                 bool   `yaml:"single_file"`
+		LoadExtraClauses           string `yaml:"load_extra_clauses"`
+		DetectSourceChanges        bool   `yaml:"detect_source_changes"`
+		RequireIndex               bool   `yaml:"require_index"`
+		ControlFile                string `yaml:"control_file"`
+		ControlPollInterval        int    `yaml:"control_poll_interval"`
+		LogLevel                   string `yaml:"log_level"`
+		LogStdout                  bool   `yaml:"log_stdout"`
+		PrintSQL                   bool   `yaml:"print_sql"`
+		PreviewSQL                 bool   `yaml:"preview_sql"`
+		NotifyWebhook              string `yaml:"notify_webhook"`
+		LogDir                     string `yaml:"log_dir"`
+
+		// A list of entries, each merged over the global settings for its tenant
+		Tenants []TenantOverride `yaml:"tenants"`
+	}
+
+	if err := decodeYAMLProfile(data, profile, &yamlCfg); err != nil {
+		return err
+	}
+	if err := expandYAMLEnv(&yamlCfg, strictEnv); err != nil {
+		return err
+	}
+
+	// Apply YAML values to config (only if not already set)
+	if yamlCfg.TenantID > 0 {
+		cfg.TenantID = yamlCfg.TenantID
+	}
+	if yamlCfg.TenantIDsFile != "" {
+		cfg.TenantIDsFile = yamlCfg.TenantIDsFile
+	}
+	if len(yamlCfg.Tenants) > 0 {
+		cfg.Tenants = yamlCfg.Tenants
+	}
+	if yamlCfg.FailFast {
+		cfg.FailFast = true
+	}
+	if yamlCfg.TableName != "" {
+		cfg.TableName = yamlCfg.TableName
+	}
+	if yamlCfg.TenantColumn != "" {
+		cfg.TenantColumn = yamlCfg.TenantColumn
+	}
+	if yamlCfg.Tables != "" {
+		tables, err := ParseTables(yamlCfg.Tables)
+		if err != nil {
+			return err
+		}
+		cfg.Tables = tables
+	}
+	if yamlCfg.MariaDBHost != "" {
+		cfg.MariaDBHost = yamlCfg.MariaDBHost
+	}
+	if yamlCfg.MariaDBPort > 0 {
+		cfg.MariaDBPort = yamlCfg.MariaDBPort
+	}
+	if yamlCfg.MariaDBUser != "" {
+		cfg.MariaDBUser = yamlCfg.MariaDBUser
+	}
+	if yamlCfg.MariaDBPassword != "" {
+		cfg.MariaDBPassword = yamlCfg.MariaDBPassword
+	}
+	if yamlCfg.MariaDBSecret != "" {
+		cfg.MariaDBSecret = yamlCfg.MariaDBSecret
+	}
+	if yamlCfg.MariaDBSecretRegion != "" {
+		cfg.MariaDBSecretRegion = yamlCfg.MariaDBSecretRegion
+	}
+	if yamlCfg.DBMaxOpenConns > 0 {
+		cfg.DBMaxOpenConns = yamlCfg.DBMaxOpenConns
+	}
+	if yamlCfg.DBMaxIdleConns > 0 {
+		cfg.DBMaxIdleConns = yamlCfg.DBMaxIdleConns
+	}
+	if yamlCfg.DBConnMaxLifetime > 0 {
+		cfg.DBConnMaxLifetime = yamlCfg.DBConnMaxLifetime
+	}
+	if yamlCfg.DBConnectRetries > 0 {
+		cfg.DBConnectRetries = yamlCfg.DBConnectRetries
+	}
+	if yamlCfg.DBConnectBackoff != "" {
+		dbConnectBackoff, err := time.ParseDuration(yamlCfg.DBConnectBackoff)
+		if err != nil {
+			return fmt.Errorf("invalid db_connect_backoff %q: %w", yamlCfg.DBConnectBackoff, err)
+		}
+		cfg.DBConnectBackoff = dbConnectBackoff
+	}
+	if yamlCfg.MariaDBDatabase != "" {
+		cfg.MariaDBDatabase = yamlCfg.MariaDBDatabase
+	}
+	if yamlCfg.MariaDBParams != "" {
+		cfg.MariaDBParams = yamlCfg.MariaDBParams
+	}
+	if yamlCfg.MariaDBReplicaHost != "" {
+		cfg.MariaDBReplicaHost = yamlCfg.MariaDBReplicaHost
+	}
+	if yamlCfg.ReplicaMaxLag != "" {
+		replicaMaxLag, err := time.ParseDuration(yamlCfg.ReplicaMaxLag)
+		if err != nil {
+			return fmt.Errorf("invalid replica_max_lag %q: %w", yamlCfg.ReplicaMaxLag, err)
+		}
+		cfg.ReplicaMaxLag = replicaMaxLag
+	}
+	if yamlCfg.ReplicaLagAction != "" {
+		cfg.ReplicaLagAction = yamlCfg.ReplicaLagAction
+	}
+	if yamlCfg.S3Bucket != "" {
+		cfg.S3Bucket = yamlCfg.S3Bucket
+	}
+	if yamlCfg.OutputDir != "" {
+		cfg.OutputDir = yamlCfg.OutputDir
+	}
+	if yamlCfg.SingleHash != "" {
+		cfg.SingleHash = yamlCfg.SingleHash
+	}
+	if yamlCfg.Report {
+		cfg.Report = true
+	}
+	if yamlCfg.DetectDuplicates {
+		cfg.DetectDuplicates = true
+	}
+	if yamlCfg.DumpSchema {
+		cfg.DumpSchema = true
+	}
+	if yamlCfg.DumpSchemaAurora {
+		cfg.DumpSchemaAurora = true
+	}
+	if yamlCfg.S3Prefix != "" {
+		cfg.S3Prefix = yamlCfg.S3Prefix
+	}
+	if yamlCfg.S3KeyTemplate != "" {
+		cfg.S3KeyTemplate = yamlCfg.S3KeyTemplate
+	}
+	if yamlCfg.PartitionByDate {
+		cfg.PartitionByDate = true
+	}
+	if yamlCfg.TimestampKeys {
+		cfg.TimestampKeys = true
+	}
+	if yamlCfg.AWSRegion != "" {
+		cfg.AWSRegion = yamlCfg.AWSRegion
+	}
+	if yamlCfg.S3Tags != "" {
+		cfg.S3Tags = yamlCfg.S3Tags
+	}
+	if yamlCfg.S3StorageClass != "" {
+		cfg.S3StorageClass = yamlCfg.S3StorageClass
+	}
+	if yamlCfg.ContentType != "" {
+		cfg.ContentType = yamlCfg.ContentType
+	}
+	if yamlCfg.S3Endpoint != "" {
+		cfg.S3Endpoint = yamlCfg.S3Endpoint
+	}
+	if yamlCfg.S3Addressing != "" {
+		cfg.S3Addressing = yamlCfg.S3Addressing
+	}
+	if yamlCfg.S3PathStyle {
+		cfg.S3PathStyle = true
+	}
+	if yamlCfg.UploadRateLimitMbps > 0 {
+		cfg.UploadRateLimitMbps = yamlCfg.UploadRateLimitMbps
+	}
+	if yamlCfg.MaxParallelUploads != 0 {
+		cfg.MaxParallelUploads = yamlCfg.MaxParallelUploads
+	}
+	if yamlCfg.S3CircuitThreshold != 0 {
+		cfg.S3CircuitThreshold = yamlCfg.S3CircuitThreshold
+	}
+	if yamlCfg.ResumeUploads {
+		cfg.ResumeUploads = true
 	}
 	if yamlCfg.AWSAccessKeyID != "" {
 		cfg.AWSAccessKeyID = yamlCfg.AWSAccessKeyID
 	}
-	if yamlCfg.AWSSecretAccessKey != "" {
-		cfg.AWSSecretAccessKey = yamlCfg.AWSSecretAccessKey
+	if yamlCfg.AWSSecretAccessKey != "" {
+		cfg.AWSSecretAccessKey = yamlCfg.AWSSecretAccessKey
+	}
+	if yamlCfg.AWSSessionToken != "" {
+		cfg.AWSSessionToken = yamlCfg.AWSSessionToken
+	}
+	if yamlCfg.AuroraHost != "" {
+		cfg.AuroraHost = yamlCfg.AuroraHost
+	}
+	if yamlCfg.AuroraPort > 0 {
+		cfg.AuroraPort = yamlCfg.AuroraPort
+	}
+	if yamlCfg.AuroraUser != "" {
+		cfg.AuroraUser = yamlCfg.AuroraUser
+	}
+	if yamlCfg.AuroraSecretsManagerSecret != "" {
+		cfg.AuroraSecretsManagerSecret = yamlCfg.AuroraSecretsManagerSecret
+	}
+	if yamlCfg.AuroraPassword != "" {
+		cfg.AuroraPassword = yamlCfg.AuroraPassword
+	}
+	if yamlCfg.AuroraAuthMode != "" {
+		cfg.AuroraAuthMode = yamlCfg.AuroraAuthMode
+	}
+	if yamlCfg.AuroraRegion != "" {
+		cfg.AuroraRegion = yamlCfg.AuroraRegion
+	}
+	if yamlCfg.SecretsMaxAttempts != 0 {
+		cfg.SecretsMaxAttempts = yamlCfg.SecretsMaxAttempts
+	}
+	if yamlCfg.AuroraDatabase != "" {
+		cfg.AuroraDatabase = yamlCfg.AuroraDatabase
+	}
+	if yamlCfg.AuroraParams != "" {
+		cfg.AuroraParams = yamlCfg.AuroraParams
+	}
+	cfg.ExecuteSQL = yamlCfg.ExecuteSQL
+	if yamlCfg.SkipSQLGen {
+		cfg.SkipSQLGen = true
+	}
+	if yamlCfg.DirectLoad {
+		cfg.DirectLoad = true
+	}
+	if yamlCfg.DirectLoadWarnRows > 0 {
+		cfg.DirectLoadWarnRows = yamlCfg.DirectLoadWarnRows
+	}
+	if yamlCfg.VerifySample != 0 {
+		cfg.VerifySample = yamlCfg.VerifySample
+	}
+	if yamlCfg.CheckSchema != nil {
+		cfg.CheckSchema = *yamlCfg.CheckSchema
+	}
+	if yamlCfg.SkipPreflight {
+		cfg.SkipPreflight = true
+	}
+	if yamlCfg.FullVerify {
+		cfg.FullVerify = true
+	}
+	if yamlCfg.VerifyDiff {
+		cfg.VerifyDiff = true
+	}
+	if yamlCfg.Manifest {
+		cfg.Manifest = true
+	}
+	if yamlCfg.VerifyManifest {
+		cfg.VerifyManifest = true
+	}
+	if yamlCfg.ListOrphanObjects {
+		cfg.ListOrphanObjects = true
+	}
+	if yamlCfg.Prune {
+		cfg.Prune = true
+	}
+	if yamlCfg.ValidateCoverage {
+		cfg.ValidateCoverage = true
+	}
+	if yamlCfg.Segments > 0 {
+		cfg.Segments = yamlCfg.Segments
+	}
+	if yamlCfg.AutoSegments {
+		cfg.AutoSegments = true
+	}
+	if yamlCfg.RowsPerSegment != 0 {
+		cfg.RowsPerSegment = yamlCfg.RowsPerSegment
+	}
+	if yamlCfg.OnlySegments != "" {
+		cfg.OnlySegments = yamlCfg.OnlySegments
+	}
+	if yamlCfg.SkipSegments != "" {
+		cfg.SkipSegments = yamlCfg.SkipSegments
+	}
+	if yamlCfg.MaxParallelSegs > 0 {
+		cfg.MaxParallelSegs = yamlCfg.MaxParallelSegs
+	}
+	if yamlCfg.AdaptiveParallelism {
+		cfg.AdaptiveParallelism = true
+	}
+	if yamlCfg.AdaptiveTargetLatency > 0 {
+		cfg.AdaptiveTargetLatency = yamlCfg.AdaptiveTargetLatency
+	}
+	if yamlCfg.BatchSize > 0 {
+		cfg.BatchSize = yamlCfg.BatchSize
+	}
+	if yamlCfg.BatchBytes > 0 {
+		cfg.BatchBytes = yamlCfg.BatchBytes
+	}
+	if yamlCfg.MaxEmptyBatches > 0 {
+		cfg.MaxEmptyBatches = yamlCfg.MaxEmptyBatches
+	}
+	if yamlCfg.MaxBatchesPerSegment > 0 {
+		cfg.MaxBatchesPerSegment = yamlCfg.MaxBatchesPerSegment
+	}
+	if yamlCfg.SegmentOrder != "" {
+		cfg.SegmentOrder = yamlCfg.SegmentOrder
+	}
+	if yamlCfg.PartitionStrategy != "" {
+		cfg.PartitionStrategy = yamlCfg.PartitionStrategy
+	}
+	if yamlCfg.PartitionColumn != "" {
+		cfg.PartitionColumn = yamlCfg.PartitionColumn
+	}
+	if yamlCfg.CheckSegmentCardinality {
+		cfg.CheckSegmentCardinality = true
+	}
+	if yamlCfg.IsolationLevel != "" {
+		cfg.IsolationLevel = yamlCfg.IsolationLevel
+	}
+	if yamlCfg.SegmentTimeout > 0 {
+		cfg.SegmentTimeout = yamlCfg.SegmentTimeout
+	}
+	if yamlCfg.QueryTimeout > 0 {
+		cfg.QueryTimeout = yamlCfg.QueryTimeout
+	}
+	if yamlCfg.LockRetries > 0 {
+		cfg.LockRetries = yamlCfg.LockRetries
+	}
+	if yamlCfg.ContinueOnSegmentError {
+		cfg.ContinueOnSegmentError = true
+	}
+	if yamlCfg.FailFastAbort {
+		cfg.FailFastAbort = true
+	}
+	if yamlCfg.MaxRuntime != "" {
+		maxRuntime, err := time.ParseDuration(yamlCfg.MaxRuntime)
+		if err != nil {
+			return fmt.Errorf("invalid max_runtime %q: %w", yamlCfg.MaxRuntime, err)
+		}
+		cfg.MaxRuntime = maxRuntime
+	}
+	if yamlCfg.Resume {
+		cfg.Resume = true
+	}
+	if yamlCfg.CheckpointInterval > 0 {
+		cfg.CheckpointInterval = yamlCfg.CheckpointInterval
+	}
+	if yamlCfg.ConcurrencyBudget > 0 {
+		cfg.ConcurrencyBudget = yamlCfg.ConcurrencyBudget
+	}
+	if yamlCfg.ConcurrencyWeights != "" {
+		cfg.ConcurrencyWeights = yamlCfg.ConcurrencyWeights
+	}
+	if yamlCfg.SQLExecTimeout > 0 {
+		cfg.SQLExecTimeout = yamlCfg.SQLExecTimeout
+	}
+	if yamlCfg.SQLStatementTimeout > 0 {
+		cfg.SQLStatementTimeout = yamlCfg.SQLStatementTimeout
+	}
+	if yamlCfg.SQLTotalTimeout > 0 {
+		cfg.SQLTotalTimeout = yamlCfg.SQLTotalTimeout
+	}
+	if yamlCfg.SQLReconnectRetries > 0 {
+		cfg.SQLReconnectRetries = yamlCfg.SQLReconnectRetries
+	}
+	if yamlCfg.SQLTransactional {
+		cfg.SQLTransactional = true
+	}
+	if yamlCfg.LoadViaLocal {
+		cfg.LoadViaLocal = true
+	}
+	if yamlCfg.AuditLog != "" {
+		cfg.AuditLog = yamlCfg.AuditLog
+	}
+	if yamlCfg.AuditLogUpload {
+		cfg.AuditLogUpload = true
+	}
+	if yamlCfg.SQLDuplicateMode != "" {
+		cfg.SQLDuplicateMode = yamlCfg.SQLDuplicateMode
+	}
+	if yamlCfg.NullMarker != "" {
+		cfg.NullMarker = yamlCfg.NullMarker
 	}
-	if yamlCfg.AWSSessionToken != "" {
-		cfg.AWSSessionToken = yamlCfg.AWSSessionToken
+	if yamlCfg.CSVDelimiter != "" {
+		cfg.CSVDelimiter = yamlCfg.CSVDelimiter
 	}
-	if yamlCfg.AuroraHost != "" {
-		cfg.AuroraHost = yamlCfg.AuroraHost
+	if yamlCfg.CSVHeader != nil {
+		cfg.CSVHeader = *yamlCfg.CSVHeader
 	}
-	if yamlCfg.AuroraPort > 0 {
-		cfg.AuroraPort = yamlCfg.AuroraPort
+	if yamlCfg.ExcludeWhere != "" {
+		cfg.ExcludeWhere = yamlCfg.ExcludeWhere
 	}
-	if yamlCfg.AuroraUser != "" {
-		cfg.AuroraUser = yamlCfg.AuroraUser
+	if yamlCfg.Where != "" {
+		cfg.Where = yamlCfg.Where
 	}
-	if yamlCfg.AuroraSecretsManagerSecret != "" {
-		cfg.AuroraSecretsManagerSecret = yamlCfg.AuroraSecretsManagerSecret
+	if yamlCfg.AllowRawWhere {
+		cfg.AllowRawWhere = true
 	}
-	if yamlCfg.AuroraRegion != "" {
-		cfg.AuroraRegion = yamlCfg.AuroraRegion
+	if yamlCfg.SinceCheckpoint {
+		cfg.SinceCheckpoint = true
 	}
-	if yamlCfg.AuroraDatabase != "" {
-		cfg.AuroraDatabase = yamlCfg.AuroraDatabase
+	if yamlCfg.ExcludeColumns != "" {
+		cfg.ExcludeColumns = yamlCfg.ExcludeColumns
 	}
-	cfg.ExecuteSQL = yamlCfg.ExecuteSQL
-	if yamlCfg.Segments > 0 {
-		cfg.Segments = yamlCfg.Segments
+	if yamlCfg.ValidateJSON != "" {
+		cfg.ValidateJSON = yamlCfg.ValidateJSON
 	}
-	if yamlCfg.MaxParallelSegs > 0 {
-		cfg.MaxParallelSegs = yamlCfg.MaxParallelSegs
+	if yamlCfg.MaskAggr != "" {
+		cfg.MaskAggr = yamlCfg.MaskAggr
 	}
-	if yamlCfg.BatchSize > 0 {
-		cfg.BatchSize = yamlCfg.BatchSize
+	if yamlCfg.Compress != "" {
+		cfg.Compress = yamlCfg.Compress
 	}
-	if yamlCfg.SQLExecTimeout > 0 {
-		cfg.SQLExecTimeout = yamlCfg.SQLExecTimeout
+	if yamlCfg.CompressLevel != 0 {
+		cfg.CompressLevel = yamlCfg.CompressLevel
+	}
+	if yamlCfg.Format != "" {
+		cfg.Format = yamlCfg.Format
+	}
+	if yamlCfg.SingleFile {
+		cfg.SingleFile = true
+	}
+	if yamlCfg.DeadLetter != "" {
+		cfg.DeadLetter = yamlCfg.DeadLetter
+	}
+	if yamlCfg.FailOnInvalidRows {
+		cfg.FailOnInvalidRows = true
+	}
+	if yamlCfg.LoadExtraClauses != "" {
+		cfg.LoadExtraClauses = yamlCfg.LoadExtraClauses
+	}
+	if yamlCfg.DetectSourceChanges {
+		cfg.DetectSourceChanges = true
+	}
+	if yamlCfg.RequireIndex {
+		cfg.RequireIndex = true
+	}
+	if yamlCfg.ControlFile != "" {
+		cfg.ControlFile = yamlCfg.ControlFile
+	}
+	if yamlCfg.ControlPollInterval > 0 {
+		cfg.ControlPollInterval = yamlCfg.ControlPollInterval
+	}
+	if yamlCfg.LogLevel != "" {
+		cfg.LogLevel = yamlCfg.LogLevel
+	}
+	if yamlCfg.LogStdout {
+		cfg.LogStdout = true
+	}
+	if yamlCfg.PrintSQL {
+		cfg.PrintSQL = true
+	}
+	if yamlCfg.PreviewSQL {
+		cfg.PreviewSQL = true
+	}
+	if yamlCfg.NotifyWebhook != "" {
+		cfg.NotifyWebhook = yamlCfg.NotifyWebhook
+	}
+	if yamlCfg.LogDir != "" {
+		cfg.LogDir = yamlCfg.LogDir
 	}
 
 	return nil
@@ -379,9 +2139,23 @@ func loadFromEnv(cfg *Config) {
 			cfg.TenantID = tid
 		}
 	}
+	if val := os.Getenv("FIS_MIGRATION_TENANT_IDS_FILE"); val != "" {
+		cfg.TenantIDsFile = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_FAIL_FAST"); val != "" {
+		cfg.FailFast = (val == "true" || val == "1")
+	}
 	if val := os.Getenv("FIS_MIGRATION_TABLE_NAME"); val != "" {
 		cfg.TableName = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_TENANT_COLUMN"); val != "" {
+		cfg.TenantColumn = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_TABLES"); val != "" {
+		if tables, err := ParseTables(val); err == nil {
+			cfg.Tables = tables
+		}
+	}
 	if val := os.Getenv("FIS_MIGRATION_MARIADB_HOST"); val != "" {
 		cfg.MariaDBHost = val
 	}
@@ -399,15 +2173,123 @@ func loadFromEnv(cfg *Config) {
 	if val := os.Getenv("FIS_MIGRATION_MARIADB_DATABASE"); val != "" {
 		cfg.MariaDBDatabase = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_MARIADB_PARAMS"); val != "" {
+		cfg.MariaDBParams = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_MARIADB_REPLICA_HOST"); val != "" {
+		cfg.MariaDBReplicaHost = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_REPLICA_MAX_LAG"); val != "" {
+		if replicaMaxLag, err := time.ParseDuration(val); err == nil {
+			cfg.ReplicaMaxLag = replicaMaxLag
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_REPLICA_LAG_ACTION"); val != "" {
+		cfg.ReplicaLagAction = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_MARIADB_SECRET"); val != "" {
+		cfg.MariaDBSecret = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_MARIADB_SECRET_REGION"); val != "" {
+		cfg.MariaDBSecretRegion = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_DB_MAX_OPEN_CONNS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.DBMaxOpenConns = n
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_DB_MAX_IDLE_CONNS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.DBMaxIdleConns = n
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_DB_CONN_MAX_LIFETIME"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.DBConnMaxLifetime = n
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_DB_CONNECT_RETRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.DBConnectRetries = n
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_DB_CONNECT_BACKOFF"); val != "" {
+		if backoff, err := time.ParseDuration(val); err == nil {
+			cfg.DBConnectBackoff = backoff
+		}
+	}
 	if val := os.Getenv("FIS_MIGRATION_S3_BUCKET"); val != "" {
 		cfg.S3Bucket = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_OUTPUT_DIR"); val != "" {
+		cfg.OutputDir = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_SINGLE_HASH"); val != "" {
+		cfg.SingleHash = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_REPORT"); val != "" {
+		cfg.Report = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_DETECT_DUPLICATES"); val != "" {
+		cfg.DetectDuplicates = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_DUMP_SCHEMA"); val != "" {
+		cfg.DumpSchema = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_DUMP_SCHEMA_AURORA"); val != "" {
+		cfg.DumpSchemaAurora = (val == "true" || val == "1")
+	}
 	if val := os.Getenv("FIS_MIGRATION_S3_PREFIX"); val != "" {
 		cfg.S3Prefix = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_S3_KEY_TEMPLATE"); val != "" {
+		cfg.S3KeyTemplate = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_PARTITION_BY_DATE"); val != "" {
+		cfg.PartitionByDate = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_TIMESTAMP_KEYS"); val != "" {
+		cfg.TimestampKeys = (val == "true" || val == "1")
+	}
 	if val := os.Getenv("FIS_MIGRATION_AWS_REGION"); val != "" {
 		cfg.AWSRegion = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_S3_TAGS"); val != "" {
+		cfg.S3Tags = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_S3_STORAGE_CLASS"); val != "" {
+		cfg.S3StorageClass = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONTENT_TYPE"); val != "" {
+		cfg.ContentType = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_S3_ENDPOINT"); val != "" {
+		cfg.S3Endpoint = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_S3_ADDRESSING"); val != "" {
+		cfg.S3Addressing = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_S3_PATH_STYLE"); val != "" {
+		cfg.S3PathStyle = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_UPLOAD_RATE_LIMIT_MBPS"); val != "" {
+		if mbps, err := strconv.Atoi(val); err == nil {
+			cfg.UploadRateLimitMbps = mbps
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_MAX_PARALLEL_UPLOADS"); val != "" {
+		if uploads, err := strconv.Atoi(val); err == nil {
+			cfg.MaxParallelUploads = uploads
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_S3_CIRCUIT_THRESHOLD"); val != "" {
+		if threshold, err := strconv.Atoi(val); err == nil {
+			cfg.S3CircuitThreshold = threshold
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_RESUME_UPLOADS"); val != "" {
+		cfg.ResumeUploads = (val == "true" || val == "1")
+	}
 	if val := os.Getenv("FIS_MIGRATION_AWS_ACCESS_KEY_ID"); val != "" {
 		cfg.AWSAccessKeyID = val
 	}
@@ -428,45 +2310,463 @@ func loadFromEnv(cfg *Config) {
 	if val := os.Getenv("FIS_MIGRATION_AURORA_SECRET"); val != "" {
 		cfg.AuroraSecretsManagerSecret = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_AURORA_PASSWORD"); val != "" {
+		cfg.AuroraPassword = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_AURORA_AUTH_MODE"); val != "" {
+		cfg.AuroraAuthMode = val
+	}
 	if val := os.Getenv("FIS_MIGRATION_AURORA_REGION"); val != "" {
 		cfg.AuroraRegion = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_SECRETS_MAX_ATTEMPTS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.SecretsMaxAttempts = n
+		}
+	}
 	if val := os.Getenv("FIS_MIGRATION_AURORA_DATABASE"); val != "" {
 		cfg.AuroraDatabase = val
 	}
+	if val := os.Getenv("FIS_MIGRATION_AURORA_PARAMS"); val != "" {
+		cfg.AuroraParams = val
+	}
 	if val := os.Getenv("FIS_MIGRATION_EXECUTE_SQL"); val != "" {
 		cfg.ExecuteSQL = (val == "true" || val == "1")
 	}
+	if val := os.Getenv("FIS_MIGRATION_DIRECT_LOAD"); val != "" {
+		cfg.DirectLoad = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_DIRECT_LOAD_WARN_ROWS"); val != "" {
+		if rows, err := strconv.Atoi(val); err == nil {
+			cfg.DirectLoadWarnRows = rows
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_SKIP_SQL_GEN"); val != "" {
+		cfg.SkipSQLGen = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_VERIFY_SAMPLE"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.VerifySample = n
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_CHECK_SCHEMA"); val != "" {
+		cfg.CheckSchema = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_SKIP_PREFLIGHT"); val != "" {
+		cfg.SkipPreflight = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_FULL_VERIFY"); val != "" {
+		cfg.FullVerify = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_VERIFY_DIFF"); val != "" {
+		cfg.VerifyDiff = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_MANIFEST"); val != "" {
+		cfg.Manifest = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_VERIFY_MANIFEST"); val != "" {
+		cfg.VerifyManifest = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_LIST_ORPHAN_OBJECTS"); val != "" {
+		cfg.ListOrphanObjects = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_PRUNE"); val != "" {
+		cfg.Prune = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_VALIDATE_COVERAGE"); val != "" {
+		cfg.ValidateCoverage = (val == "true" || val == "1")
+	}
 	if val := os.Getenv("FIS_MIGRATION_SEGMENTS"); val != "" {
 		if segs, err := strconv.Atoi(val); err == nil {
 			cfg.Segments = segs
 		}
 	}
+	if val := os.Getenv("FIS_MIGRATION_AUTO_SEGMENTS"); val != "" {
+		cfg.AutoSegments = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_ROWS_PER_SEGMENT"); val != "" {
+		if rows, err := strconv.Atoi(val); err == nil {
+			cfg.RowsPerSegment = rows
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_ONLY_SEGMENTS"); val != "" {
+		cfg.OnlySegments = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_SKIP_SEGMENTS"); val != "" {
+		cfg.SkipSegments = val
+	}
 	if val := os.Getenv("FIS_MIGRATION_MAX_PARALLEL_SEGMENTS"); val != "" {
 		if max, err := strconv.Atoi(val); err == nil {
 			cfg.MaxParallelSegs = max
 		}
 	}
+	if val := os.Getenv("FIS_MIGRATION_ADAPTIVE_PARALLELISM"); val != "" {
+		cfg.AdaptiveParallelism = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_ADAPTIVE_TARGET_LATENCY"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.AdaptiveTargetLatency = seconds
+		}
+	}
 	if val := os.Getenv("FIS_MIGRATION_BATCH_SIZE"); val != "" {
 		if batch, err := strconv.Atoi(val); err == nil {
 			cfg.BatchSize = batch
 		}
 	}
+	if val := os.Getenv("FIS_MIGRATION_BATCH_BYTES"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil {
+			cfg.BatchBytes = size
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_MAX_EMPTY_BATCHES"); val != "" {
+		if max, err := strconv.Atoi(val); err == nil {
+			cfg.MaxEmptyBatches = max
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_MAX_BATCHES_PER_SEGMENT"); val != "" {
+		if max, err := strconv.Atoi(val); err == nil {
+			cfg.MaxBatchesPerSegment = max
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_SEGMENT_ORDER"); val != "" {
+		cfg.SegmentOrder = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_PARTITION_STRATEGY"); val != "" {
+		cfg.PartitionStrategy = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_PARTITION_COLUMN"); val != "" {
+		cfg.PartitionColumn = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_CHECK_SEGMENT_CARDINALITY"); val != "" {
+		cfg.CheckSegmentCardinality = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_ISOLATION_LEVEL"); val != "" {
+		cfg.IsolationLevel = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_SEGMENT_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil {
+			cfg.SegmentTimeout = timeout
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_QUERY_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil {
+			cfg.QueryTimeout = timeout
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOCK_RETRIES"); val != "" {
+		if retries, err := strconv.Atoi(val); err == nil {
+			cfg.LockRetries = retries
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONTINUE_ON_SEGMENT_ERROR"); val != "" {
+		cfg.ContinueOnSegmentError = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_FAIL_FAST_ABORT"); val != "" {
+		cfg.FailFastAbort = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_MAX_RUNTIME"); val != "" {
+		if maxRuntime, err := time.ParseDuration(val); err == nil {
+			cfg.MaxRuntime = maxRuntime
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_RESUME"); val != "" {
+		cfg.Resume = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_CHECKPOINT_INTERVAL"); val != "" {
+		if interval, err := strconv.Atoi(val); err == nil {
+			cfg.CheckpointInterval = interval
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONCURRENCY_BUDGET"); val != "" {
+		if budget, err := strconv.Atoi(val); err == nil {
+			cfg.ConcurrencyBudget = budget
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONCURRENCY_WEIGHTS"); val != "" {
+		cfg.ConcurrencyWeights = val
+	}
 	if val := os.Getenv("FIS_MIGRATION_SQL_EXEC_TIMEOUT"); val != "" {
 		if timeout, err := strconv.Atoi(val); err == nil {
 			cfg.SQLExecTimeout = timeout
 		}
 	}
+	if val := os.Getenv("FIS_MIGRATION_SQL_STATEMENT_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil {
+			cfg.SQLStatementTimeout = timeout
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_SQL_TOTAL_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil {
+			cfg.SQLTotalTimeout = timeout
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_SQL_RECONNECT_RETRIES"); val != "" {
+		if retries, err := strconv.Atoi(val); err == nil {
+			cfg.SQLReconnectRetries = retries
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_SQL_TRANSACTIONAL"); val != "" {
+		cfg.SQLTransactional = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOAD_VIA_LOCAL"); val != "" {
+		cfg.LoadViaLocal = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_AUDIT_LOG"); val != "" {
+		cfg.AuditLog = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_AUDIT_LOG_UPLOAD"); val != "" {
+		cfg.AuditLogUpload = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_SQL_DUPLICATE_MODE"); val != "" {
+		cfg.SQLDuplicateMode = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_NULL_MARKER"); val != "" {
+		cfg.NullMarker = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_CSV_DELIMITER"); val != "" {
+		cfg.CSVDelimiter = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_CSV_HEADER"); val != "" {
+		cfg.CSVHeader = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_DEAD_LETTER"); val != "" {
+		cfg.DeadLetter = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_FAIL_ON_INVALID_ROWS"); val != "" {
+		cfg.FailOnInvalidRows = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_EXCLUDE_WHERE"); val != "" {
+		cfg.ExcludeWhere = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_WHERE"); val != "" {
+		cfg.Where = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_ALLOW_RAW_WHERE"); val != "" {
+		cfg.AllowRawWhere = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_SINCE_CHECKPOINT"); val != "" {
+		cfg.SinceCheckpoint = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_EXCLUDE_COLUMNS"); val != "" {
+		cfg.ExcludeColumns = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_VALIDATE_JSON"); val != "" {
+		cfg.ValidateJSON = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_MASK_AGGR"); val != "" {
+		cfg.MaskAggr = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_COMPRESS"); val != "" {
+		cfg.Compress = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_COMPRESS_LEVEL"); val != "" {
+		if level, err := strconv.Atoi(val); err == nil {
+			cfg.CompressLevel = level
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_FORMAT"); val != "" {
+		cfg.Format = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_SINGLE_FILE"); val != "" {
+		cfg.SingleFile = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOAD_EXTRA_CLAUSES"); val != "" {
+		cfg.LoadExtraClauses = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_DETECT_SOURCE_CHANGES"); val != "" {
+		cfg.DetectSourceChanges = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_REQUIRE_INDEX"); val != "" {
+		cfg.RequireIndex = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONTROL_FILE"); val != "" {
+		cfg.ControlFile = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_CONTROL_POLL_INTERVAL"); val != "" {
+		if interval, err := strconv.Atoi(val); err == nil {
+			cfg.ControlPollInterval = interval
+		}
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOG_LEVEL"); val != "" {
+		cfg.LogLevel = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOG_STDOUT"); val != "" {
+		cfg.LogStdout = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_PRINT_SQL"); val != "" {
+		cfg.PrintSQL = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_PREVIEW_SQL"); val != "" {
+		cfg.PreviewSQL = (val == "true" || val == "1")
+	}
+	if val := os.Getenv("FIS_MIGRATION_NOTIFY_WEBHOOK"); val != "" {
+		cfg.NotifyWebhook = val
+	}
+	if val := os.Getenv("FIS_MIGRATION_LOG_DIR"); val != "" {
+		cfg.LogDir = val
+	}
+}
+
+// LocalOutputOnly reports whether CSV files are only written to -output-dir (no S3 bucket).
+func (c *Config) LocalOutputOnly() bool {
+	return c.OutputDir != "" && c.S3Bucket == ""
+}
+
+// ExcludedColumns returns the -exclude-columns list, lowercased, or nil if no column is excluded.
+func (c *Config) ExcludedColumns() []string {
+	var columns []string
+	for _, column := range strings.Split(c.ExcludeColumns, ",") {
+		if column = strings.ToLower(strings.TrimSpace(column)); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// SQLOnlyStdout reports whether stdout carries nothing but the LOAD DATA statements (-print-sql with -quiet),
+// so it can be piped into mysql.
+func (c *Config) SQLOnlyStdout() bool {
+	return c.PrintSQL && c.Quiet
+}
+
+// TenantColumnName returns the tenant ID column name (-tenant-column), defaulting to tenantid
+// for configs not built by LoadConfigFromArgs.
+func (c *Config) TenantColumnName() string {
+	if c.TenantColumn == "" {
+		return "tenantid"
+	}
+	return c.TenantColumn
+}
+
+// CSVComma returns the CSV field delimiter (-csv-delimiter), defaulting to a comma
+// for configs not built by LoadConfigFromArgs.
+func (c *Config) CSVComma() rune {
+	if c.CSVDelimiter == "" {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(c.CSVDelimiter)
+	return r
+}
+
+// CSVEnclosure returns the CSV quote character, defaulting to a double quote
+// for configs not built by LoadConfigFromArgs.
+func (c *Config) CSVEnclosure() string {
+	if c.CSVQuote == "" {
+		return "\""
+	}
+	return c.CSVQuote
+}
+
+// CSVKeyPrefix returns the S3 prefix for CSV objects: -s3-prefix, followed by dt=<RunDate> with -partition-by-date
+// and run-<RunTimestamp> with -timestamp-keys.
+func (c *Config) CSVKeyPrefix() string {
+	prefix := c.S3Prefix
+	if c.PartitionByDate {
+		prefix = fmt.Sprintf("%s/dt=%s", prefix, c.RunDate)
+	}
+	if c.TimestampKeys {
+		prefix = fmt.Sprintf("%s/run-%s", prefix, c.RunTimestamp)
+	}
+	return prefix
+}
+
+// SegmentSelection parses -only-segments and -skip-segments against the segment count.
+// Both are nil when not set.
+func (c *Config) SegmentSelection() (only, skip []int, err error) {
+	if c.OnlySegments != "" {
+		if only, err = segment.ParseIndexList(c.OnlySegments, c.Segments); err != nil {
+			return nil, nil, fmt.Errorf("invalid only-segments: %w", err)
+		}
+	}
+	if c.SkipSegments != "" {
+		if skip, err = segment.ParseIndexList(c.SkipSegments, c.Segments); err != nil {
+			return nil, nil, fmt.Errorf("invalid skip-segments: %w", err)
+		}
+	}
+	return only, skip, nil
+}
+
+// Secrets returns the known secret values (passwords, AWS keys and session tokens) to scrub from logs.
+// The Aurora password fetched from Secrets Manager at execution time is not known here,
+// unless it is set with FIS_AWS_SQL_PASSWORD (or is the -aurora-password fallback).
+func (c *Config) Secrets() []string {
+	// Webhook URLs (e.g. Slack) embed their token
+	secrets := []string{c.MariaDBPassword, c.AuroraPassword, c.AWSAccessKeyID, c.AWSSecretAccessKey, c.AWSSessionToken, c.NotifyWebhook}
+	for _, env := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", util.AWSSQLPasswordEnv} {
+		secrets = append(secrets, os.Getenv(env))
+	}
+
+	var known []string
+	for _, secret := range secrets {
+		if secret != "" {
+			known = append(known, secret)
+		}
+	}
+	return known
+}
+
+// compressLevels are the -compress-level ranges of the codecs.
+var compressLevels = map[string][2]int{
+	"gzip": {1, 9},
+	"zstd": {1, 22},
+}
+
+// ValidateCompressLevel checks a -compress-level for codec: 1-9 for gzip, 1-22 for zstd, or 0 for the codec's default.
+func ValidateCompressLevel(codec string, level int) error {
+	levels, ok := compressLevels[codec]
+	if level == 0 || !ok {
+		return nil
+	}
+	if level < levels[0] || level > levels[1] {
+		return fmt.Errorf("invalid compress-level %d for %s (expected %d-%d)", level, codec, levels[0], levels[1])
+	}
+	return nil
+}
+
+// ParseDSNParams parses extra DSN parameters (-mariadb-params, -aurora-params) given as a query string.
+// parseTime is dropped: rows are scanned into time.Time, so DSNs always keep parseTime=true.
+// Returns an error if params isn't a valid query string or sets parseTime to anything else.
+func ParseDSNParams(params string) (url.Values, error) {
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a query string: %w", params, err)
+	}
+	for key, vals := range values {
+		if key == "" {
+			return nil, fmt.Errorf("%q has a parameter without a name", params)
+		}
+		if key == "parseTime" {
+			for _, val := range vals {
+				if val != "true" {
+					return nil, fmt.Errorf("parseTime must stay true, got %q", val)
+				}
+			}
+		}
+	}
+	values.Del("parseTime")
+	return values, nil
 }
 
-// GetMariaDBDSN returns the MariaDB connection string.
+// GetMariaDBDSN returns the MariaDB connection string, with -mariadb-params after parseTime=true.
 func (c *Config) GetMariaDBDSN() string {
-	host := c.MariaDBHost
-	if c.MariaDBPort > 0 && c.MariaDBPort != 3306 {
-		host = fmt.Sprintf("%s:%d", c.MariaDBHost, c.MariaDBPort)
+	return c.mariaDBDSN(c.MariaDBHost)
+}
+
+// GetMariaDBExportDSN returns the connection string the exporter reads from: the read replica
+// (-mariadb-replica-host) if set, otherwise the primary.
+func (c *Config) GetMariaDBExportDSN() string {
+	if c.MariaDBReplicaHost != "" {
+		return c.mariaDBDSN(c.MariaDBReplicaHost)
 	}
+	return c.GetMariaDBDSN()
+}
 
-	dsn := fmt.Sprintf("tcp(%s)/%s?parseTime=true", host, c.MariaDBDatabase)
+// mariaDBDSN returns the MariaDB connection string for host (see mariaDBAddress).
+func (c *Config) mariaDBDSN(host string) string {
+	dsn := fmt.Sprintf("tcp(%s)/%s?parseTime=true", mariaDBAddress(host, c.MariaDBPort), c.MariaDBDatabase)
+	if params, err := ParseDSNParams(c.MariaDBParams); err == nil && len(params) > 0 {
+		dsn += "&" + params.Encode()
+	}
 	if c.MariaDBUser != "" {
 		if c.MariaDBPassword != "" {
 			dsn = fmt.Sprintf("%s:%s@%s", c.MariaDBUser, c.MariaDBPassword, dsn)
@@ -477,6 +2777,19 @@ func (c *Config) GetMariaDBDSN() string {
 	return dsn
 }
 
+// mariaDBAddress returns the address of host: as given if it has a port (host:3306, [::1]:3307), which
+// takes precedence over -mariadb-port, otherwise with port added unless it's the default 3306.
+// A bare IPv6 address given a port is bracketed.
+func mariaDBAddress(host string, port int) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if port > 0 && port != 3306 {
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return host
+}
+
 // ReadMariaDBAuth reads MariaDB credentials from an auth file (JSON format).
 func (c *Config) ReadMariaDBAuth(authFile string) error {
 	if authFile == "" {
@@ -501,3 +2814,53 @@ func (c *Config) ReadMariaDBAuth(authFile string) error {
 	c.MariaDBPassword = auth.Password
 	return nil
 }
+
+// ParseTables parses a comma-separated -tables list, rejecting empty and duplicate names.
+func ParseTables(list string) ([]string, error) {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, table := range strings.Split(list, ",") {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			return nil, fmt.Errorf("invalid tables %q: empty table name", list)
+		}
+		if seen[table] {
+			return nil, fmt.Errorf("invalid tables %q: duplicate table %s", list, table)
+		}
+		seen[table] = true
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// ReadTenantIDsFile reads newline-separated tenant IDs from a file.
+// Blank lines and lines starting with '#' are ignored.
+func ReadTenantIDsFile(path string) ([]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant IDs file: %w", err)
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := strconv.Atoi(line)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid tenant ID %q on line %d", line, i+1)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate tenant ID %d on line %d", id, i+1)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no tenant IDs found in %s", path)
+	}
+	return ids, nil
+}
@@ -3,8 +3,15 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 )
 
 func TestLoadConfig_EnvironmentVariables(t *testing.T) {
@@ -14,17 +21,67 @@ func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 	os.Setenv("FIS_MIGRATION_MARIADB_HOST", "localhost:3306")
 	os.Setenv("FIS_MIGRATION_S3_BUCKET", "test-bucket")
 	os.Setenv("FIS_MIGRATION_AWS_REGION", "us-east-1")
+	os.Setenv("FIS_MIGRATION_SEGMENTS", "64")
 	defer func() {
 		os.Unsetenv("FIS_MIGRATION_TENANT_ID")
 		os.Unsetenv("FIS_MIGRATION_TABLE_NAME")
 		os.Unsetenv("FIS_MIGRATION_MARIADB_HOST")
 		os.Unsetenv("FIS_MIGRATION_S3_BUCKET")
 		os.Unsetenv("FIS_MIGRATION_AWS_REGION")
+		os.Unsetenv("FIS_MIGRATION_SEGMENTS")
 	}()
 
-	// Note: LoadConfig() uses flag.Parse() which requires actual CLI args
-	// For full testing, we'd need to reset flags or use a different approach
-	// This is a placeholder for the test structure
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.TenantID != 1234 || cfg.TableName != "test_table" || cfg.MariaDBHost != "localhost:3306" ||
+		cfg.S3Bucket != "test-bucket" || cfg.AWSRegion != "us-east-1" {
+		t.Errorf("environment variables not applied: %+v", cfg)
+	}
+	// Flag defaults (-table-name fis_aggr, -segments 16) must not override the environment
+	if cfg.Segments != 64 {
+		t.Errorf("Segments = %d, want 64 from FIS_MIGRATION_SEGMENTS", cfg.Segments)
+	}
+
+	// Explicit flags still override the environment
+	cfg, err = LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-segments", "32"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.Segments != 32 {
+		t.Errorf("Segments = %d, want 32 from -segments", cfg.Segments)
+	}
+}
+
+func TestLoadConfigFromArgs_Repeated(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-mariadb-host", "localhost", "-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	first, err := LoadConfigFromArgs(append([]string{"-tenant-id", "1001", "-table-name", "fis_aggr", "-segments", "32", "-require-index"}, base...))
+	if err != nil {
+		t.Fatalf("first LoadConfigFromArgs() error = %v", err)
+	}
+	// Flags set in the first call must not leak into the second
+	second, err := LoadConfigFromArgs(append([]string{"-tenant-id", "1002", "-table-name", "other_table"}, base...))
+	if err != nil {
+		t.Fatalf("second LoadConfigFromArgs() error = %v", err)
+	}
+
+	if first.TenantID != 1001 || first.TableName != "fis_aggr" || first.Segments != 32 || !first.RequireIndex {
+		t.Errorf("first config = tenant %d table %s segments %d require-index %v",
+			first.TenantID, first.TableName, first.Segments, first.RequireIndex)
+	}
+	if second.TenantID != 1002 || second.TableName != "other_table" || second.Segments != 16 || second.RequireIndex {
+		t.Errorf("second config = tenant %d table %s segments %d require-index %v, want defaults for unset flags",
+			second.TenantID, second.TableName, second.Segments, second.RequireIndex)
+	}
+
+	if _, err := LoadConfigFromArgs(append([]string{"-tenant-id", "1003", "-no-such-flag"}, base...)); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("LoadConfigFromArgs() with an unknown flag error = %v, want ErrConfig", err)
+	}
+	if _, err := LoadConfigFromArgs([]string{"-h"}); !errors.Is(err, flag.ErrHelp) || errors.Is(err, errs.ErrConfig) {
+		t.Errorf("LoadConfigFromArgs(-h) error = %v, want flag.ErrHelp", err)
+	}
 }
 
 func TestConfig_GetMariaDBDSN(t *testing.T) {
@@ -66,6 +123,46 @@ func TestConfig_GetMariaDBDSN(t *testing.T) {
 			},
 			contains: []string{"testuser", "testdb"},
 		},
+		{
+			name: "with extra params",
+			config: &Config{
+				MariaDBHost:     "localhost",
+				MariaDBPort:     3306,
+				MariaDBDatabase: "testdb",
+				MariaDBParams:   "charset=utf8mb4&readTimeout=30s&parseTime=true",
+			},
+			contains: []string{"?parseTime=true&", "charset=utf8mb4", "readTimeout=30s"},
+		},
+		{
+			name:     "host with default port",
+			config:   &Config{MariaDBHost: "db.example.com", MariaDBPort: 3306, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp(db.example.com)/testdb?"},
+		},
+		{
+			name:     "host:3306 in the host",
+			config:   &Config{MariaDBHost: "db.example.com:3306", MariaDBPort: 3306, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp(db.example.com:3306)/testdb?"},
+		},
+		{
+			name:     "host with custom port",
+			config:   &Config{MariaDBHost: "db.example.com", MariaDBPort: 3307, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp(db.example.com:3307)/testdb?"},
+		},
+		{
+			name:     "port in the host takes precedence",
+			config:   &Config{MariaDBHost: "db.example.com:3308", MariaDBPort: 3307, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp(db.example.com:3308)/testdb?"},
+		},
+		{
+			name:     "ipv6 with custom port",
+			config:   &Config{MariaDBHost: "::1", MariaDBPort: 3307, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp([::1]:3307)/testdb?"},
+		},
+		{
+			name:     "bracketed ipv6 with port",
+			config:   &Config{MariaDBHost: "[::1]:3306", MariaDBPort: 3307, MariaDBDatabase: "testdb"},
+			contains: []string{"tcp([::1]:3306)/testdb?"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,3 +220,1824 @@ func containsMiddle(s, substr string) bool {
 	return false
 }
 
+
+func TestReadTenantIDsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"valid list", "1001\n1002\n1003\n", 3, false},
+		{"comments and blanks", "# tenants\n1001\n\n  1002  \n", 2, false},
+		{"invalid id", "1001\nabc\n", 0, true},
+		{"duplicate id", "1001\n1001\n", 0, true},
+		{"empty file", "\n# none\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile, err := os.CreateTemp("", "tenants-*.txt")
+			if err != nil {
+				t.Fatalf("failed to create temp file: %v", err)
+			}
+			defer os.Remove(tmpFile.Name())
+			tmpFile.WriteString(tt.content)
+			tmpFile.Close()
+
+			ids, err := ReadTenantIDsFile(tmpFile.Name())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadTenantIDsFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(ids) != tt.want {
+				t.Errorf("expected %d tenant IDs, got %d", tt.want, len(ids))
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveMariaDBSecret(t *testing.T) {
+	// Mock Secrets Manager holding one known secret
+	var lookups []string
+	orig := secretsManagerPassword
+	secretsManagerPassword = func(secretName, region string, attempts int) (string, error) {
+		lookups = append(lookups, secretName+"@"+region)
+		if secretName != "fis/mariadb" {
+			return "", fmt.Errorf("secret %s not found", secretName)
+		}
+		return "secret-pass", nil
+	}
+	defer func() { secretsManagerPassword = orig }()
+
+	tests := []struct {
+		name            string
+		config          *Config
+		passwordFromCLI bool
+		wantPassword    string
+		wantLookup      string // empty if Secrets Manager should not be called
+		wantErr         bool
+	}{
+		{
+			name:         "secret resolves password",
+			config:       &Config{MariaDBSecret: "fis/mariadb", MariaDBSecretRegion: "us-west-2", AWSRegion: "us-east-1"},
+			wantPassword: "secret-pass",
+			wantLookup:   "fis/mariadb@us-west-2",
+		},
+		{
+			name:         "secret region defaults to aws-region",
+			config:       &Config{MariaDBSecret: "fis/mariadb", AWSRegion: "us-east-1"},
+			wantPassword: "secret-pass",
+			wantLookup:   "fis/mariadb@us-east-1",
+		},
+		{
+			name:         "secret overrides yaml/env password",
+			config:       &Config{MariaDBSecret: "fis/mariadb", AWSRegion: "us-east-1", MariaDBPassword: "yaml-pass"},
+			wantPassword: "secret-pass",
+			wantLookup:   "fis/mariadb@us-east-1",
+		},
+		{
+			name:            "cli password overrides secret",
+			config:          &Config{MariaDBSecret: "fis/mariadb", AWSRegion: "us-east-1", MariaDBPassword: "cli-pass"},
+			passwordFromCLI: true,
+			wantPassword:    "cli-pass",
+		},
+		{
+			name:         "no secret keeps password",
+			config:       &Config{MariaDBPassword: "plain-pass"},
+			wantPassword: "plain-pass",
+		},
+		{
+			name:       "missing secret fails",
+			config:     &Config{MariaDBSecret: "fis/other", AWSRegion: "us-east-1"},
+			wantLookup: "fis/other@us-east-1",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups = nil
+			err := tt.config.resolveMariaDBSecret(tt.passwordFromCLI)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveMariaDBSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.config.MariaDBPassword != tt.wantPassword {
+				t.Errorf("MariaDBPassword = %q, want %q", tt.config.MariaDBPassword, tt.wantPassword)
+			}
+			if tt.wantLookup == "" && len(lookups) > 0 {
+				t.Errorf("Secrets Manager should not be called, got %v", lookups)
+			}
+			if tt.wantLookup != "" && (len(lookups) != 1 || lookups[0] != tt.wantLookup) {
+				t.Errorf("Secrets Manager lookups = %v, want [%s]", lookups, tt.wantLookup)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_FullVerifyRequiresExecuteSQL(t *testing.T) {
+	args := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-full-verify"}
+	if _, err := LoadConfigFromArgs(args); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -full-verify without -execute-sql")
+	}
+
+	args = append(args, "-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1")
+	cfg, err := LoadConfigFromArgs(args)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.FullVerify {
+		t.Error("FullVerify = false, want true")
+	}
+}
+
+func TestLoadConfigFromArgs_VerifyDiffRequiresExecuteSQL(t *testing.T) {
+	args := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-verify-diff"}
+	if _, err := LoadConfigFromArgs(args); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -verify-diff without -execute-sql")
+	}
+
+	args = append(args, "-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1")
+	cfg, err := LoadConfigFromArgs(args)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.VerifyDiff {
+		t.Error("VerifyDiff = false, want true")
+	}
+}
+
+func TestLoadConfigFromArgs_SQLTransactional(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-sql-transactional"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SQLTransactional || cfg.SQLReconnectRetries != 0 {
+		t.Errorf("SQLTransactional = %v, SQLReconnectRetries = %d, want true and 0", cfg.SQLTransactional, cfg.SQLReconnectRetries)
+	}
+	if cfg.SQLDuplicateMode != "ignore" {
+		t.Errorf("SQLDuplicateMode = %q, want ignore", cfg.SQLDuplicateMode)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-sql-transactional", "-sql-reconnect-retries", "2")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -sql-transactional with -sql-reconnect-retries")
+	}
+	if _, err := LoadConfigFromArgs(append(base, "-sql-duplicate-mode", "skip")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject an unknown sql-duplicate-mode")
+	}
+}
+
+func TestLoadConfigFromArgs_NullMarker(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.NullMarker != `\N` {
+		t.Errorf("NullMarker = %q, want \\N", cfg.NullMarker)
+	}
+
+	for _, marker := range []string{"a,b", `"null"`, "x\ny"} {
+		if _, err := LoadConfigFromArgs(append(base, "-null-marker", marker)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject null-marker %q", marker)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_Tables(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-tables", "fis_aggr, fis_aggr_v2"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if fmt.Sprint(cfg.Tables) != "[fis_aggr fis_aggr_v2]" {
+		t.Errorf("Tables = %v, want [fis_aggr fis_aggr_v2]", cfg.Tables)
+	}
+	if cfg.TableName != "fis_aggr" {
+		t.Errorf("TableName = %q, want the first table", cfg.TableName)
+	}
+
+	for _, tables := range []string{"fis_aggr,", "fis_aggr,fis_aggr"} {
+		if _, err := LoadConfigFromArgs(append(base, "-tables", tables)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject tables %q", tables)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_TenantColumn(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.TenantColumn != "tenantid" {
+		t.Errorf("TenantColumn = %q, want the tenantid default", cfg.TenantColumn)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-tenant-column", "tenant_id"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.TenantColumn != "tenant_id" {
+		t.Errorf("TenantColumn = %q, want tenant_id", cfg.TenantColumn)
+	}
+
+	for _, column := range []string{"tenant id", "tenantid; DROP TABLE fis_aggr", "`tenantid`", "1tenant"} {
+		if _, err := LoadConfigFromArgs(append(base, "-tenant-column", column)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject tenant-column %q", column)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_SingleHash(t *testing.T) {
+	// No S3 bucket needed: the row is printed
+	args := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-single-hash", "00ABC123def456"}
+
+	cfg, err := LoadConfigFromArgs(args)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SingleHash != "00ABC123def456" {
+		t.Errorf("SingleHash = %q, want 00ABC123def456", cfg.SingleHash)
+	}
+
+	if _, err := LoadConfigFromArgs(append(args, "-single-hash", "00abc' OR 1=1")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a non-hex single-hash")
+	}
+	if _, err := LoadConfigFromArgs(append(args, "-tables", "fis_aggr,fis_aggr_v2")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject single-hash with several tables")
+	}
+}
+
+func TestLoadConfigFromArgs_SecretsMaxAttempts(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SecretsMaxAttempts != 5 {
+		t.Errorf("SecretsMaxAttempts = %d, want the default 5", cfg.SecretsMaxAttempts)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-secrets-max-attempts", "1"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SecretsMaxAttempts != 1 {
+		t.Errorf("SecretsMaxAttempts = %d, want 1", cfg.SecretsMaxAttempts)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-secrets-max-attempts", "-2")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a negative secrets-max-attempts")
+	}
+}
+
+func TestLoadConfigFromArgs_S3Endpoint(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-s3-endpoint", "http://minio:9000", "-s3-path-style"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.S3Endpoint != "http://minio:9000" || !cfg.S3PathStyle {
+		t.Errorf("S3Endpoint = %q, S3PathStyle = %v, want http://minio:9000 and true", cfg.S3Endpoint, cfg.S3PathStyle)
+	}
+
+	for _, endpoint := range []string{"minio:9000", "ftp://minio", "http://"} {
+		if _, err := LoadConfigFromArgs(append(base, "-s3-endpoint", endpoint)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject s3-endpoint %q", endpoint)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_CSVHeader(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.CSVHeader {
+		t.Error("CSVHeader should default to true")
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-csv-header=false"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.CSVHeader {
+		t.Error("CSVHeader should be false with -csv-header=false")
+	}
+}
+
+func TestLoadConfigFromArgs_NotifyWebhook(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	webhook := "https://hooks.slack.com/services/T000/B000/secret-token"
+	cfg, err := LoadConfigFromArgs(append(base, "-notify-webhook", webhook))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.NotifyWebhook != webhook {
+		t.Errorf("NotifyWebhook = %q, want %q", cfg.NotifyWebhook, webhook)
+	}
+	if secrets := strings.Join(cfg.Secrets(), " "); !strings.Contains(secrets, webhook) {
+		t.Error("Secrets() should include the webhook URL so its token is scrubbed from logs")
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-notify-webhook", "hooks.slack.com/services/x")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a webhook without a scheme")
+	}
+}
+
+func TestLoadConfigFromArgs_PartitionByDate(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-s3-prefix", "lake"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if got := cfg.CSVKeyPrefix(); got != "lake" {
+		t.Errorf("CSVKeyPrefix() = %q, want lake without -partition-by-date", got)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-partition-by-date"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if _, err := time.Parse("2006-01-02", cfg.RunDate); err != nil {
+		t.Fatalf("RunDate = %q, want a YYYY-MM-DD date", cfg.RunDate)
+	}
+	if got, want := cfg.CSVKeyPrefix(), "lake/dt="+cfg.RunDate; got != want {
+		t.Errorf("CSVKeyPrefix() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfigFromArgs_TimestampKeys(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-s3-prefix", "lake", "-timestamp-keys"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if _, err := time.Parse("20060102T150405Z", cfg.RunTimestamp); err != nil {
+		t.Fatalf("RunTimestamp = %q, want a YYYYMMDDTHHMMSSZ time", cfg.RunTimestamp)
+	}
+	if cfg.RunDate != cfg.RunTimestamp[:4]+"-"+cfg.RunTimestamp[4:6]+"-"+cfg.RunTimestamp[6:8] {
+		t.Errorf("RunDate = %q and RunTimestamp = %q, want the same run start", cfg.RunDate, cfg.RunTimestamp)
+	}
+	if got, want := cfg.CSVKeyPrefix(), "lake/run-"+cfg.RunTimestamp; got != want {
+		t.Errorf("CSVKeyPrefix() = %q, want %q", got, want)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-partition-by-date"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if got, want := cfg.CSVKeyPrefix(), "lake/dt="+cfg.RunDate+"/run-"+cfg.RunTimestamp; got != want {
+		t.Errorf("CSVKeyPrefix() with -partition-by-date = %q, want %q", got, want)
+	}
+
+	for _, flag := range []string{"-resume", "-resume-uploads"} {
+		if _, err := LoadConfigFromArgs(append(append([]string{}, base...), flag)); err == nil || !strings.Contains(err.Error(), "-timestamp-keys can't be combined") {
+			t.Errorf("LoadConfigFromArgs() with %s error = %v, want it rejected", flag, err)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_DirectLoad(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-region", "us-east-1", "-aurora-auth-mode", "iam", "-direct-load"}
+
+	// No S3 bucket needed
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.DirectLoad || cfg.DirectLoadWarnRows != 1000000 {
+		t.Errorf("DirectLoad = %v, DirectLoadWarnRows = %d, want true and the 1000000 default", cfg.DirectLoad, cfg.DirectLoadWarnRows)
+	}
+	cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-direct-load-warn-rows", "5000", "-full-verify"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.DirectLoadWarnRows != 5000 || !cfg.FullVerify {
+		t.Errorf("DirectLoadWarnRows = %d, FullVerify = %v, want 5000 and -full-verify allowed", cfg.DirectLoadWarnRows, cfg.FullVerify)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"s3 bucket", []string{"-s3-bucket", "bucket", "-aws-region", "us-east-1"}, "-direct-load can't be combined with -s3-bucket"},
+		{"output dir", []string{"-output-dir", "/tmp/out"}, "-direct-load can't be combined with -s3-bucket or -output-dir"},
+		{"execute sql", []string{"-execute-sql"}, "-direct-load can't be combined with -execute-sql"},
+		{"compress", []string{"-compress", "gzip"}, "-direct-load can't be combined with -format parquet"},
+		{"report", []string{"-report"}, "-direct-load can't be combined with single-hash"},
+		{"negative warn rows", []string{"-direct-load-warn-rows", "-1"}, "invalid direct-load-warn-rows -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-direct-load"}); err == nil || !strings.Contains(err.Error(), "aurora-host, aurora-user and aurora-region are required") {
+		t.Errorf("LoadConfigFromArgs() without Aurora settings error = %v, want it rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_DBConnectRetries(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.DBConnectRetries != 0 || cfg.DBConnectBackoff != time.Second {
+		t.Errorf("DBConnectRetries = %d, DBConnectBackoff = %s, want 0 and 1s by default", cfg.DBConnectRetries, cfg.DBConnectBackoff)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-db-connect-retries", "5", "-db-connect-backoff", "3s"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.DBConnectRetries != 5 || cfg.DBConnectBackoff != 3*time.Second {
+		t.Errorf("DBConnectRetries = %d, DBConnectBackoff = %s, want 5 and 3s", cfg.DBConnectRetries, cfg.DBConnectBackoff)
+	}
+
+	for _, args := range [][]string{{"-db-connect-retries", "-1"}, {"-db-connect-backoff", "-1s"}} {
+		if _, err := LoadConfigFromArgs(append(append([]string{}, base...), args...)); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+			t.Errorf("LoadConfigFromArgs() with %v error = %v, want it rejected", args, err)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_AdaptiveParallelism(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-adaptive-parallelism"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.AdaptiveParallelism || cfg.AdaptiveTargetLatency != 120 {
+		t.Errorf("AdaptiveParallelism = %v, AdaptiveTargetLatency = %d, want true and the 120s default", cfg.AdaptiveParallelism, cfg.AdaptiveTargetLatency)
+	}
+	cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-adaptive-target-latency", "30"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.AdaptiveTargetLatency != 30 {
+		t.Errorf("AdaptiveTargetLatency = %d, want 30", cfg.AdaptiveTargetLatency)
+	}
+
+	for _, args := range [][]string{{"-adaptive-target-latency", "-1"}, {"-single-file"}} {
+		if _, err := LoadConfigFromArgs(append(append([]string{}, base...), args...)); err == nil {
+			t.Errorf("LoadConfigFromArgs() with %v should be rejected", args)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_Where(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-where", "version >= 3", "-allow-raw-where"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.Where != "version >= 3" || !cfg.AllowRawWhere {
+		t.Errorf("Where = %q, AllowRawWhere = %v, want the -where filter as raw SQL", cfg.Where, cfg.AllowRawWhere)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-allow-raw-where")); err == nil || !strings.Contains(err.Error(), "-allow-raw-where requires -where") {
+		t.Errorf("LoadConfigFromArgs() with -allow-raw-where alone error = %v, want it rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_ExcludeColumns(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-exclude-columns", " Aggr, version"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if got := strings.Join(cfg.ExcludedColumns(), ","); got != "aggr,version" {
+		t.Errorf("ExcludedColumns() = %v, want [aggr version]", got)
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-exclude-columns", "hash"}, "can't exclude hash"},
+		{[]string{"-exclude-columns", "aggr,aggr"}, "aggr is listed twice"},
+		{[]string{"-exclude-columns", "aggr", "-verify-sample", "1"}, "-exclude-columns can't be combined with"},
+		{[]string{"-exclude-columns", "aggr", "-format", "parquet"}, "-exclude-columns can't be combined with"},
+		{[]string{"-exclude-columns", "aggr", "-mask-aggr", "sha256"}, "there is no aggr to mask"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_ValidateJSON(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-validate-json", "fail"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.ValidateJSON != "fail" {
+		t.Errorf("ValidateJSON = %q, want fail", cfg.ValidateJSON)
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-validate-json", "strict"}, "invalid validate-json"},
+		{[]string{"-validate-json", "skip", "-exclude-columns", "aggr"}, "-validate-json can't be combined with -exclude-columns aggr"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_S3Addressing(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.S3Addressing != "auto" {
+		t.Errorf("S3Addressing = %q, want the auto default", cfg.S3Addressing)
+	}
+	cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-s3-addressing", "virtual"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.S3Addressing != "virtual" {
+		t.Errorf("S3Addressing = %q, want virtual", cfg.S3Addressing)
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-s3-addressing", "dns"}, "invalid s3-addressing"},
+		{[]string{"-s3-addressing", "virtual", "-s3-path-style"}, "-s3-path-style can't be combined with -s3-addressing virtual"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_CheckpointInterval(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-checkpoint-interval", "10", "-resume", "-resume-uploads"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.CheckpointInterval != 10 {
+		t.Errorf("CheckpointInterval = %d, want 10", cfg.CheckpointInterval)
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-checkpoint-interval", "-1"}, "invalid checkpoint-interval"},
+		{[]string{"-checkpoint-interval", "10", "-resume"}, "checkpoint-interval requires -resume and -resume-uploads"},
+		{[]string{"-checkpoint-interval", "10", "-resume-uploads"}, "checkpoint-interval requires -resume and -resume-uploads"},
+		{[]string{"-checkpoint-interval", "10", "-resume", "-resume-uploads", "-output-dir", "out"}, "-checkpoint-interval can't be combined"},
+		{[]string{"-checkpoint-interval", "10", "-resume", "-resume-uploads", "-compress", "gzip"}, "-checkpoint-interval can't be combined"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_LoadViaLocal(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	executeSQL := []string{"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append(append([]string{}, base...), executeSQL...), "-load-via-local"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.LoadViaLocal {
+		t.Error("LoadViaLocal should be set with -load-via-local")
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-load-via-local"}, "-load-via-local requires -execute-sql"},
+		{append(append([]string{}, executeSQL...), "-load-via-local", "-sql-transactional"), "-load-via-local can't be combined with -sql-transactional"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_SinceCheckpoint(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	executeSQL := []string{"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append(append([]string{}, base...), executeSQL...), "-since-checkpoint"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SinceCheckpoint || cfg.SinceLastModified != "" {
+		t.Errorf("SinceCheckpoint = %v, SinceLastModified = %q, want set and left to the run", cfg.SinceCheckpoint, cfg.SinceLastModified)
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-since-checkpoint"}, "-since-checkpoint requires -execute-sql or -direct-load"},
+		{append(append([]string{}, executeSQL...), "-since-checkpoint", "-resume"), "-since-checkpoint can't be combined with"},
+		{append(append([]string{}, executeSQL...), "-since-checkpoint", "-full-verify"), "-since-checkpoint can't be combined with"},
+		{append(append([]string{}, executeSQL...), "-since-checkpoint", "-manifest"), "-since-checkpoint can't be combined with"},
+	} {
+		_, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_ContentType(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-content-type", "text/csv; charset=utf-8"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.ContentType != "text/csv; charset=utf-8" {
+		t.Errorf("ContentType = %q, want text/csv; charset=utf-8", cfg.ContentType)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-content-type", "text/csv; charset")); err == nil || !strings.Contains(err.Error(), "invalid content-type") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want an invalid content-type", err)
+	}
+}
+
+func TestLoadConfigFromArgs_FailFastAbort(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-fail-fast-abort"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.FailFastAbort {
+		t.Error("FailFastAbort should be set with -fail-fast-abort")
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-output-dir", t.TempDir(), "-fail-fast-abort"},
+			"-fail-fast-abort requires -s3-bucket"},
+		{append(append([]string{}, base...), "-fail-fast-abort", "-continue-on-segment-error"), "-fail-fast-abort can't be combined with -continue-on-segment-error"},
+		{append(append([]string{}, base...), "-fail-fast-abort", "-resume-uploads"), "-fail-fast-abort can't be combined with -resume-uploads"},
+	} {
+		_, err := LoadConfigFromArgs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfigFromArgs(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_FailOnInvalidRows(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil || cfg.FailOnInvalidRows {
+		t.Fatalf("LoadConfigFromArgs() = %v, %v, want invalid rows skipped by default", cfg, err)
+	}
+	if cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-fail-on-invalid-rows")); err != nil || !cfg.FailOnInvalidRows {
+		t.Errorf("LoadConfigFromArgs() = %v, %v, want FailOnInvalidRows with -fail-on-invalid-rows", cfg, err)
+	}
+
+	t.Setenv("FIS_MIGRATION_FAIL_ON_INVALID_ROWS", "true")
+	if cfg, err = LoadConfigFromArgs(append([]string{}, base...)); err != nil || !cfg.FailOnInvalidRows {
+		t.Errorf("LoadConfigFromArgs() with env = %v, %v, want FailOnInvalidRows", cfg, err)
+	}
+}
+
+func TestLoadConfigFromArgs_Report(t *testing.T) {
+	// -report only counts rows, so S3 isn't required
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-report"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.Report {
+		t.Error("Report should be set with -report")
+	}
+
+	if _, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-report", "-single-hash", "abc"}); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -report with -single-hash")
+	}
+}
+
+func TestLoadConfigFromArgs_CSVDelimiter(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	tests := []struct {
+		delimiter string
+		want      rune
+	}{
+		{"|", '|'},
+		{`\t`, '\t'},
+		{";", ';'},
+	}
+	for _, tt := range tests {
+		cfg, err := LoadConfigFromArgs(append(base, "-csv-delimiter", tt.delimiter))
+		if err != nil {
+			t.Fatalf("LoadConfigFromArgs(-csv-delimiter %q) error = %v", tt.delimiter, err)
+		}
+		if cfg.CSVComma() != tt.want {
+			t.Errorf("CSVComma() = %q, want %q", cfg.CSVComma(), tt.want)
+		}
+	}
+
+	for _, delimiter := range []string{"||", `"`, `\`, "\n"} {
+		if _, err := LoadConfigFromArgs(append(base, "-csv-delimiter", delimiter)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject csv-delimiter %q", delimiter)
+		}
+	}
+	if _, err := LoadConfigFromArgs(append(base, "-csv-delimiter", "|", "-null-marker", "a|b")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a null-marker containing the delimiter")
+	}
+}
+
+func TestLoadConfigFromArgs_ResumeUploads(t *testing.T) {
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-resume-uploads"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.ResumeUploads {
+		t.Error("ResumeUploads should be set with -resume-uploads")
+	}
+
+	if _, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-resume-uploads"}); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -resume-uploads without -s3-bucket")
+	}
+}
+
+func TestLoadConfigFromArgs_MaxRuntime(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-max-runtime", "2h30m", "-resume"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MaxRuntime != 150*time.Minute || !cfg.Resume {
+		t.Errorf("MaxRuntime = %s, Resume = %v, want 2h30m and true", cfg.MaxRuntime, cfg.Resume)
+	}
+
+	t.Setenv("FIS_MIGRATION_MAX_RUNTIME", "45m")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MaxRuntime != 45*time.Minute {
+		t.Errorf("MaxRuntime = %s, want 45m from the environment", cfg.MaxRuntime)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-max-runtime", "-1m")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a negative -max-runtime")
+	}
+}
+
+func TestLoadConfigFromArgs_MaskAggr(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-mask-aggr", "sha256"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MaskAggr != "sha256" {
+		t.Errorf("MaskAggr = %q, want sha256", cfg.MaskAggr)
+	}
+
+	t.Setenv("FIS_MIGRATION_MASK_AGGR", "placeholder")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MaskAggr != "placeholder" {
+		t.Errorf("MaskAggr = %q, want placeholder from the environment", cfg.MaskAggr)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-mask-aggr", "md5")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject an unknown -mask-aggr mode")
+	}
+	aurora := []string{"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+	for _, verify := range []string{"-full-verify", "-verify-diff"} {
+		if _, err := LoadConfigFromArgs(append(append(base, aurora...), "-mask-aggr", "sha256", verify)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject -mask-aggr with %s", verify)
+		}
+	}
+}
+
+func TestLoadConfigFromArgs_BatchBytes(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-batch-bytes", "67108864"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.BatchBytes != 64<<20 || cfg.BatchSize != 100000 {
+		t.Errorf("BatchBytes = %d, BatchSize = %d, want 64 MiB and the default batch size", cfg.BatchBytes, cfg.BatchSize)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"with batch-size", []string{"-batch-bytes", "67108864", "-batch-size", "5000"}},
+		{"negative", []string{"-batch-bytes", "-1"}},
+		{"below the S3 part minimum", []string{"-batch-bytes", "1048576"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(base, tt.args...)); err == nil {
+				t.Errorf("LoadConfigFromArgs(%v) should fail", tt.args)
+			}
+		})
+	}
+
+	// Local-only output has no part minimum
+	cfg, err = LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-batch-bytes", "4096"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() with -output-dir error = %v", err)
+	}
+	if cfg.BatchBytes != 4096 {
+		t.Errorf("BatchBytes = %d, want 4096", cfg.BatchBytes)
+	}
+}
+
+func TestLoadConfigFromArgs_SegmentTimeout(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SegmentTimeout != 600 {
+		t.Errorf("SegmentTimeout = %d, want the 600 second default", cfg.SegmentTimeout)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-segment-timeout", "3600"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SegmentTimeout != 3600 {
+		t.Errorf("SegmentTimeout = %d, want 3600", cfg.SegmentTimeout)
+	}
+
+	t.Setenv("FIS_MIGRATION_SEGMENT_TIMEOUT", "1200")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.SegmentTimeout != 1200 {
+		t.Errorf("SegmentTimeout = %d, want 1200 from the environment", cfg.SegmentTimeout)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-segment-timeout", "-5")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a negative -segment-timeout")
+	}
+}
+
+func TestLoadConfigFromArgs_Compress(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	executeSQL := append([]string{"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin",
+		"-aurora-secret", "secret", "-aurora-region", "us-east-1"}, base...)
+
+	tests := []struct {
+		name      string
+		args      []string
+		want      string
+		wantLevel int
+		wantErr   bool
+	}{
+		{name: "default", args: base, want: "none"},
+		{name: "gzip", args: append([]string{"-compress", "gzip", "-compress-level", "9"}, base...), want: "gzip", wantLevel: 9},
+		{name: "zstd", args: append([]string{"-compress", "zstd", "-compress-level", "19"}, base...), want: "zstd", wantLevel: 19},
+		{name: "gzip fastest", args: append([]string{"-compress", "gzip", "-compress-level", "1"}, base...), want: "gzip", wantLevel: 1},
+		{name: "zstd fastest", args: append([]string{"-compress", "zstd", "-compress-level", "1"}, base...), want: "zstd", wantLevel: 1},
+		{name: "zstd best", args: append([]string{"-compress", "zstd", "-compress-level", "22"}, base...), want: "zstd", wantLevel: 22},
+		{name: "compression-level alias", args: append([]string{"-compress", "gzip", "-compression-level", "3"}, base...), want: "gzip", wantLevel: 3},
+		{name: "gzip with execute-sql", args: append([]string{"-compress", "gzip"}, executeSQL...), want: "gzip"},
+		{name: "zstd with execute-sql", args: append([]string{"-compress", "zstd"}, executeSQL...), wantErr: true},
+		{name: "unknown codec", args: append([]string{"-compress", "lz4"}, base...), wantErr: true},
+		{name: "gzip level out of range", args: append([]string{"-compress", "gzip", "-compress-level", "10"}, base...), wantErr: true},
+		{name: "zstd level out of range", args: append([]string{"-compress", "zstd", "-compress-level", "23"}, base...), wantErr: true},
+		{name: "negative level", args: append([]string{"-compress", "gzip", "-compress-level", "-1"}, base...), wantErr: true},
+		{name: "alias level out of range", args: append([]string{"-compress", "zstd", "-compression-level", "23"}, base...), wantErr: true},
+		{name: "level without codec", args: append([]string{"-compress-level", "5"}, base...), wantErr: true},
+		{name: "with verify-sample", args: append([]string{"-compress", "gzip", "-verify-sample", "1"}, base...), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfigFromArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfigFromArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Compress != tt.want || cfg.CompressLevel != tt.wantLevel {
+				t.Errorf("Compress = %q level %d, want %q level %d", cfg.Compress, cfg.CompressLevel, tt.want, tt.wantLevel)
+			}
+		})
+	}
+
+	// zstd is fine for local output, where nothing is loaded by Aurora
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-compress", "zstd"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() with local output error = %v", err)
+	}
+	if cfg.Compress != "zstd" {
+		t.Errorf("Compress = %q, want zstd", cfg.Compress)
+	}
+
+	t.Setenv("FIS_MIGRATION_COMPRESS", "gzip")
+	t.Setenv("FIS_MIGRATION_COMPRESS_LEVEL", "1")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.Compress != "gzip" || cfg.CompressLevel != 1 {
+		t.Errorf("Compress = %q level %d, want gzip level 1 from the environment", cfg.Compress, cfg.CompressLevel)
+	}
+}
+
+func TestLoadConfigFromArgs_DumpSchema(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-dump-schema", "-dump-schema-aurora"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.DumpSchema || !cfg.DumpSchemaAurora {
+		t.Errorf("DumpSchema = %v, DumpSchemaAurora = %v, want both set", cfg.DumpSchema, cfg.DumpSchemaAurora)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-dump-schema-aurora")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -dump-schema-aurora without -dump-schema")
+	}
+	if _, err := LoadConfigFromArgs(append(base, "-dump-schema", "-report")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject -dump-schema with -report")
+	}
+
+	t.Setenv("FIS_MIGRATION_DUMP_SCHEMA", "true")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.DumpSchema {
+		t.Error("DumpSchema should be set from the environment")
+	}
+}
+
+func TestLoadConfigFromArgs_DSNParams(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-mariadb-params", "charset=utf8mb4&maxAllowedPacket=67108864",
+		"-aurora-params", "readTimeout=10m"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBParams != "charset=utf8mb4&maxAllowedPacket=67108864" || cfg.AuroraParams != "readTimeout=10m" {
+		t.Errorf("MariaDBParams = %q, AuroraParams = %q", cfg.MariaDBParams, cfg.AuroraParams)
+	}
+	dsn := cfg.GetMariaDBDSN()
+	if !strings.Contains(dsn, "parseTime=true") || !strings.Contains(dsn, "charset=utf8mb4") {
+		t.Errorf("GetMariaDBDSN() = %q, want parseTime=true and charset=utf8mb4", dsn)
+	}
+
+	for _, params := range []string{"parseTime=false", "charset=%zz", "=utf8mb4"} {
+		if _, err := LoadConfigFromArgs(append(base, "-mariadb-params", params)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject -mariadb-params %q", params)
+		}
+		if _, err := LoadConfigFromArgs(append(base, "-aurora-params", params)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject -aurora-params %q", params)
+		}
+	}
+
+	t.Setenv("FIS_MIGRATION_MARIADB_PARAMS", "collation=utf8mb4_bin")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBParams != "collation=utf8mb4_bin" {
+		t.Errorf("MariaDBParams = %q, want collation=utf8mb4_bin from the environment", cfg.MariaDBParams)
+	}
+}
+
+func TestLoadConfigFromArgs_AutoSegments(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-auto-segments"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.AutoSegments || cfg.RowsPerSegment != 500000 {
+		t.Errorf("AutoSegments = %v, RowsPerSegment = %d, want true and the 500000 default", cfg.AutoSegments, cfg.RowsPerSegment)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-auto-segments", "-rows-per-segment", "100000"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.RowsPerSegment != 100000 {
+		t.Errorf("RowsPerSegment = %d, want 100000", cfg.RowsPerSegment)
+	}
+
+	for _, args := range [][]string{
+		{"-auto-segments", "-segments", "32"},
+		{"-auto-segments", "-only-segments", "0-3"},
+		{"-auto-segments", "-rows-per-segment", "-1"},
+	} {
+		if _, err := LoadConfigFromArgs(append(base, args...)); err == nil {
+			t.Errorf("LoadConfigFromArgs(%v) should fail", args)
+		}
+	}
+
+	t.Setenv("FIS_MIGRATION_AUTO_SEGMENTS", "1")
+	t.Setenv("FIS_MIGRATION_ROWS_PER_SEGMENT", "250000")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.AutoSegments || cfg.RowsPerSegment != 250000 {
+		t.Errorf("AutoSegments = %v, RowsPerSegment = %d, want them from the environment", cfg.AutoSegments, cfg.RowsPerSegment)
+	}
+}
+
+func TestLoadConfigFromArgs_SkipSQLGen(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-skip-sql-gen"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SkipSQLGen {
+		t.Error("SkipSQLGen should be set")
+	}
+
+	_, err = LoadConfigFromArgs(append(base, "-skip-sql-gen", "-execute-sql", "-aurora-host", "aurora",
+		"-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"))
+	if err == nil || !strings.Contains(err.Error(), "skip-sql-gen") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want -skip-sql-gen rejected with -execute-sql", err)
+	}
+
+	t.Setenv("FIS_MIGRATION_SKIP_SQL_GEN", "true")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SkipSQLGen {
+		t.Error("SkipSQLGen should be set from the environment")
+	}
+}
+
+func TestLoadConfigFromArgs_Manifest(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	aurora := []string{"-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-manifest"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.Manifest || cfg.VerifyManifest {
+		t.Errorf("Manifest = %v, VerifyManifest = %v, want only Manifest", cfg.Manifest, cfg.VerifyManifest)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(append(base, "-verify-manifest"), aurora...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.VerifyManifest {
+		t.Error("VerifyManifest should be set")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"without aurora", []string{"-verify-manifest"}},
+		{"with execute-sql", append([]string{"-verify-manifest", "-execute-sql"}, aurora...)},
+		{"with report", append([]string{"-verify-manifest", "-report"}, aurora...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), "verify-manifest") {
+				t.Errorf("LoadConfigFromArgs() error = %v, want -verify-manifest rejected", err)
+			}
+		})
+	}
+
+	t.Setenv("FIS_MIGRATION_MANIFEST", "1")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.Manifest {
+		t.Error("Manifest should be set from the environment")
+	}
+}
+
+func TestConfig_GetMariaDBExportDSN(t *testing.T) {
+	cfg := &Config{MariaDBHost: "primary", MariaDBPort: 3307, MariaDBUser: "reader", MariaDBPassword: "pw", MariaDBDatabase: "fis"}
+	if dsn := cfg.GetMariaDBExportDSN(); dsn != cfg.GetMariaDBDSN() || !strings.Contains(dsn, "tcp(primary:3307)/fis") {
+		t.Errorf("GetMariaDBExportDSN() without replica = %q, want the primary DSN", dsn)
+	}
+
+	cfg.MariaDBReplicaHost = "replica"
+	if dsn := cfg.GetMariaDBExportDSN(); dsn != "reader:pw@tcp(replica:3307)/fis?parseTime=true" {
+		t.Errorf("GetMariaDBExportDSN() = %q, want the replica with the primary's port and credentials", dsn)
+	}
+	if dsn := cfg.GetMariaDBDSN(); !strings.Contains(dsn, "tcp(primary:3307)") {
+		t.Errorf("GetMariaDBDSN() = %q, want the primary", dsn)
+	}
+
+	cfg.MariaDBReplicaHost = "replica:3310"
+	if dsn := cfg.GetMariaDBExportDSN(); !strings.Contains(dsn, "tcp(replica:3310)/") {
+		t.Errorf("GetMariaDBExportDSN() = %q, want the replica's own port", dsn)
+	}
+}
+
+func TestLoadConfigFromArgs_Replica(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBReplicaHost != "" || cfg.ReplicaMaxLag != 0 || cfg.ReplicaLagAction != "abort" {
+		t.Errorf("replica defaults = %q, %s, %q, want no replica and abort", cfg.MariaDBReplicaHost, cfg.ReplicaMaxLag, cfg.ReplicaLagAction)
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-mariadb-replica-host", "replica", "-replica-max-lag", "30s", "-replica-lag-action", "warn"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBReplicaHost != "replica" || cfg.ReplicaMaxLag != 30*time.Second || cfg.ReplicaLagAction != "warn" {
+		t.Errorf("replica settings = %q, %s, %q, want replica, 30s, warn", cfg.MariaDBReplicaHost, cfg.ReplicaMaxLag, cfg.ReplicaLagAction)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"max lag without replica", []string{"-replica-max-lag", "30s"}},
+		{"negative max lag", []string{"-mariadb-replica-host", "replica", "-replica-max-lag", "-1s"}},
+		{"invalid action", []string{"-mariadb-replica-host", "replica", "-replica-lag-action", "ignore"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), "replica") {
+				t.Errorf("LoadConfigFromArgs() error = %v, want the replica settings rejected", err)
+			}
+		})
+	}
+
+	t.Setenv("FIS_MIGRATION_MARIADB_REPLICA_HOST", "env-replica")
+	t.Setenv("FIS_MIGRATION_REPLICA_MAX_LAG", "1m")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBReplicaHost != "env-replica" || cfg.ReplicaMaxLag != time.Minute {
+		t.Errorf("replica settings from the environment = %q, %s", cfg.MariaDBReplicaHost, cfg.ReplicaMaxLag)
+	}
+}
+
+func TestLoadConfigFromArgs_ListOrphanObjects(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-list-orphan-objects", "-prune"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.ListOrphanObjects || !cfg.Prune {
+		t.Errorf("ListOrphanObjects = %v, Prune = %v, want both set", cfg.ListOrphanObjects, cfg.Prune)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"prune alone", []string{"-prune"}, "-prune requires"},
+		{"with key template", []string{"-list-orphan-objects", "-s3-key-template", "{{.Prefix}}/{{.Filename}}"}, "can't be combined with -s3-key-template"},
+		{"prune with only-segments", []string{"-list-orphan-objects", "-prune", "-only-segments", "0"}, "only-segments"},
+		{"prune with continue-on-segment-error", []string{"-list-orphan-objects", "-prune", "-continue-on-segment-error"}, "continue-on-segment-error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	local := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-list-orphan-objects"}
+	if _, err := LoadConfigFromArgs(local); err == nil || !strings.Contains(err.Error(), "s3-bucket") {
+		t.Errorf("LoadConfigFromArgs() with local-only output error = %v, want -s3-bucket required", err)
+	}
+}
+
+func TestLoadConfigFromArgs_PrintSQL(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-print-sql"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.PrintSQL || cfg.SQLOnlyStdout() {
+		t.Errorf("PrintSQL = %v, SQLOnlyStdout() = %v, want only PrintSQL without -quiet", cfg.PrintSQL, cfg.SQLOnlyStdout())
+	}
+	cfg, err = LoadConfigFromArgs(append(base, "-print-sql", "-quiet"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SQLOnlyStdout() {
+		t.Error("SQLOnlyStdout() = false with -print-sql -quiet, want true")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"with skip-sql-gen", []string{"-print-sql", "-skip-sql-gen"}, "-skip-sql-gen"},
+		{"with log-stdout", []string{"-print-sql", "-log-stdout"}, "-log-stdout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	local := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-print-sql"}
+	if _, err := LoadConfigFromArgs(local); err == nil || !strings.Contains(err.Error(), "s3-bucket") {
+		t.Errorf("LoadConfigFromArgs() with local-only output error = %v, want -s3-bucket required", err)
+	}
+}
+
+func TestLoadConfigFromArgs_Format(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.Format != "csv" {
+		t.Errorf("Format = %q, want csv by default", cfg.Format)
+	}
+	cfg, err = LoadConfigFromArgs(append(base, "-format", "parquet"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.Format != "parquet" {
+		t.Errorf("Format = %q, want parquet", cfg.Format)
+	}
+
+	aurora := []string{"-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown format", []string{"-format", "avro"}, "invalid format"},
+		{"with execute-sql", append([]string{"-format", "parquet", "-execute-sql"}, aurora...), "-execute-sql"},
+		{"with print-sql", []string{"-format", "parquet", "-print-sql"}, "-print-sql"},
+		{"with compress", []string{"-format", "parquet", "-compress", "gzip"}, "-compress"},
+		{"with verify-sample", []string{"-format", "parquet", "-verify-sample", "2"}, "-verify-sample"},
+		{"with batch-bytes", []string{"-format", "parquet", "-batch-bytes", "8388608"}, "-batch-bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_QueryTimeout(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-query-timeout", "30"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.QueryTimeout != 30 {
+		t.Errorf("QueryTimeout = %d, want 30", cfg.QueryTimeout)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"negative", []string{"-query-timeout", "-1"}, "must not be negative"},
+		{"above default segment timeout", []string{"-query-timeout", "900"}, "must be below segment-timeout 600"},
+		{"equal to segment timeout", []string{"-query-timeout", "60", "-segment-timeout", "60"}, "must be below segment-timeout 60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_AuditLog(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	aurora := []string{"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append(append([]string{}, base...), aurora...), "-audit-log", "/var/log/fis-audit.jsonl", "-audit-log-upload"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.AuditLog != "/var/log/fis-audit.jsonl" || !cfg.AuditLogUpload {
+		t.Errorf("AuditLog = %q, AuditLogUpload = %v, want both set", cfg.AuditLog, cfg.AuditLogUpload)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"without execute-sql", []string{"-audit-log", "audit.jsonl"}, "-audit-log requires -execute-sql"},
+		{"upload without audit log", append([]string{"-audit-log-upload"}, aurora...), "-audit-log-upload requires -audit-log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_ValidateCoverage(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-validate-coverage", "-continue-on-segment-error"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.ValidateCoverage {
+		t.Error("ValidateCoverage = false, want true")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"only segments", []string{"-validate-coverage", "-only-segments", "0-3"}, "can't be combined with -only-segments"},
+		{"skip segments", []string{"-validate-coverage", "-skip-segments", "5"}, "can't be combined with -only-segments or -skip-segments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_SingleFile(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-single-file", "-compress", "gzip"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.SingleFile {
+		t.Error("SingleFile = false, want true")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"parquet", []string{"-single-file", "-format", "parquet"}, "can't be combined with -format parquet"},
+		{"resume", []string{"-single-file", "-resume"}, "can't be combined with -resume"},
+		{"max runtime", []string{"-single-file", "-max-runtime", "1h"}, "a partial single file can't be continued"},
+		{"continue on segment error", []string{"-single-file", "-continue-on-segment-error"}, "can't be combined with -continue-on-segment-error"},
+		{"manifest", []string{"-single-file", "-manifest"}, "can't be combined with -manifest"},
+		{"segment order", []string{"-single-file", "-segment-order", "largest-first"}, "can't be combined with -segment-order largest-first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_AuroraPassword(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1",
+		"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "admin", "-aurora-secret", "secret", "-aurora-region", "us-east-1"}
+
+	t.Setenv("FIS_MIGRATION_AURORA_PASSWORD", "env-pwd")
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	// The secret stays the preferred source, the password is only the fallback
+	if cfg.AuroraPassword != "env-pwd" || cfg.AuroraSecretsManagerSecret != "secret" {
+		t.Errorf("AuroraPassword = %q, AuroraSecretsManagerSecret = %q, want both set", cfg.AuroraPassword, cfg.AuroraSecretsManagerSecret)
+	}
+	found := false
+	for _, secret := range cfg.Secrets() {
+		found = found || secret == "env-pwd"
+	}
+	if !found {
+		t.Error("Secrets() should include the fallback Aurora password")
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-aurora-auth-mode", "iam")); err == nil || !strings.Contains(err.Error(), "can't be combined with -aurora-auth-mode iam") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want the iam conflict", err)
+	}
+}
+
+func TestLoadConfigFromArgs_MaxParallelUploads(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), "-max-parallel-uploads", "4", "-max-parallel-segments", "16"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MaxParallelUploads != 4 || cfg.MaxParallelSegs != 16 {
+		t.Errorf("MaxParallelUploads = %d, MaxParallelSegs = %d, want 4 and 16", cfg.MaxParallelUploads, cfg.MaxParallelSegs)
+	}
+
+	t.Setenv("FIS_MIGRATION_MAX_PARALLEL_UPLOADS", "6")
+	if cfg, err = LoadConfigFromArgs(append([]string{}, base...)); err != nil || cfg.MaxParallelUploads != 6 {
+		t.Errorf("LoadConfigFromArgs() with env = %v, %v, want MaxParallelUploads 6", cfg, err)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-max-parallel-uploads", "-1")); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want the negative cap rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_PartitionStrategy(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    string
+		wantColumn string
+	}{
+		{"default hash", nil, "", ""},
+		{"range", []string{"-partition-strategy", "range", "-partition-column", "id"}, "", "id"},
+		{"range without column", []string{"-partition-strategy", "range"}, "requires -partition-column", ""},
+		{"column without range", []string{"-partition-column", "id"}, "requires -partition-strategy range", ""},
+		{"invalid column", []string{"-partition-strategy", "range", "-partition-column", "id; DROP TABLE x"}, "invalid partition-column", ""},
+		{"invalid strategy", []string{"-partition-strategy", "list"}, "invalid partition-strategy", ""},
+		{"range with resume", []string{"-partition-strategy", "range", "-partition-column", "id", "-resume"}, "can't be combined with -resume", ""},
+		{"range with single file", []string{"-partition-strategy", "range", "-partition-column", "id", "-single-file"}, "can't be combined with -single-file", ""},
+		{"range with cardinality check", []string{"-partition-strategy", "range", "-partition-column", "id", "-check-segment-cardinality"}, "can't be combined with -verify-sample", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfigFromArgs() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfigFromArgs() error = %v", err)
+			}
+			wantStrategy := "hash"
+			if tt.wantColumn != "" {
+				wantStrategy = "range"
+			}
+			if cfg.PartitionStrategy != wantStrategy || cfg.PartitionColumn != tt.wantColumn {
+				t.Errorf("partition = %s on %q, want %s on %q", cfg.PartitionStrategy, cfg.PartitionColumn, wantStrategy, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_S3CircuitThreshold(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil || cfg.S3CircuitThreshold != 0 {
+		t.Fatalf("LoadConfigFromArgs() = %v, %v, want the circuit breaker disabled by default", cfg, err)
+	}
+	if cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-s3-circuit-threshold", "20")); err != nil || cfg.S3CircuitThreshold != 20 {
+		t.Errorf("LoadConfigFromArgs() = %v, %v, want S3CircuitThreshold 20", cfg, err)
+	}
+
+	t.Setenv("FIS_MIGRATION_S3_CIRCUIT_THRESHOLD", "8")
+	if cfg, err = LoadConfigFromArgs(append([]string{}, base...)); err != nil || cfg.S3CircuitThreshold != 8 {
+		t.Errorf("LoadConfigFromArgs() with env = %v, %v, want S3CircuitThreshold 8", cfg, err)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-s3-circuit-threshold", "-1")); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want the negative threshold rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_PreviewSQL(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-aws-region", "us-east-1", "-preview-sql"}
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"with bucket", []string{"-s3-bucket", "bucket"}, ""},
+		{"local only", []string{"-output-dir", "/tmp/out"}, "requires -s3-bucket"},
+		{"with report", []string{"-s3-bucket", "bucket", "-report"}, "can't be combined with single-hash, report"},
+		{"with auto segments", []string{"-s3-bucket", "bucket", "-auto-segments"}, "can't be combined with -auto-segments"},
+		{"with parquet", []string{"-s3-bucket", "bucket", "-format", "parquet"}, "-preview-sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+			if tt.wantErr == "" {
+				if err != nil || !cfg.PreviewSQL {
+					t.Fatalf("LoadConfigFromArgs() = %v, %v, want PreviewSQL set", cfg, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_LockRetries(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil || cfg.LockRetries != 3 {
+		t.Fatalf("LoadConfigFromArgs() = %v, %v, want LockRetries 3 by default", cfg, err)
+	}
+	if cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-lock-retries", "5")); err != nil || cfg.LockRetries != 5 {
+		t.Errorf("LoadConfigFromArgs() = %v, %v, want LockRetries 5", cfg, err)
+	}
+
+	t.Setenv("FIS_MIGRATION_LOCK_RETRIES", "7")
+	if cfg, err = LoadConfigFromArgs(append([]string{}, base...)); err != nil || cfg.LockRetries != 7 {
+		t.Errorf("LoadConfigFromArgs() with env = %v, %v, want LockRetries 7", cfg, err)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-lock-retries", "-1")); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want the negative retries rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_MaxEmptyBatches(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append([]string{}, base...))
+	if err != nil || cfg.MaxEmptyBatches != 1 {
+		t.Fatalf("LoadConfigFromArgs() = %v, %v, want MaxEmptyBatches 1 by default", cfg, err)
+	}
+	if cfg, err = LoadConfigFromArgs(append(append([]string{}, base...), "-max-empty-batches", "3")); err != nil || cfg.MaxEmptyBatches != 3 {
+		t.Errorf("LoadConfigFromArgs() = %v, %v, want MaxEmptyBatches 3", cfg, err)
+	}
+
+	t.Setenv("FIS_MIGRATION_MAX_EMPTY_BATCHES", "2")
+	if cfg, err = LoadConfigFromArgs(append([]string{}, base...)); err != nil || cfg.MaxEmptyBatches != 2 {
+		t.Errorf("LoadConfigFromArgs() with env = %v, %v, want MaxEmptyBatches 2", cfg, err)
+	}
+
+	if _, err := LoadConfigFromArgs(append(append([]string{}, base...), "-max-empty-batches", "-1")); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("LoadConfigFromArgs() error = %v, want the negative value rejected", err)
+	}
+}
+
+func TestLoadConfigFromArgs_DetectDuplicates(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-detect-duplicates"}
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"without S3", nil, ""},
+		{"with report", []string{"-report"}, "detect-duplicates can't be combined"},
+		{"with single hash", []string{"-single-hash", "00ab"}, "can't be combined"},
+		{"with dump schema", []string{"-dump-schema", "-output-dir", "/tmp/out"}, "can't be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfigFromArgs(append(append([]string{}, base...), tt.args...))
+			if tt.wantErr == "" {
+				if err != nil || !cfg.DetectDuplicates {
+					t.Fatalf("LoadConfigFromArgs() = %v, %v, want DetectDuplicates set", cfg, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_VerifySample(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-verify-sample", "3"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.VerifySample != 3 {
+		t.Errorf("VerifySample = %d, want 3", cfg.VerifySample)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-verify-sample", "-1")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a negative verify-sample")
+	}
+	localOnly := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-output-dir", t.TempDir(), "-verify-sample", "1"}
+	if _, err := LoadConfigFromArgs(localOnly); err == nil {
+		t.Error("LoadConfigFromArgs() should reject verify-sample without an S3 bucket")
+	}
+}
+
+func TestLoadConfigFromArgs_AuroraAuthMode(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1",
+		"-execute-sql", "-aurora-host", "aurora", "-aurora-user", "loader", "-aurora-region", "us-east-1"}
+
+	if _, err := LoadConfigFromArgs(base); err == nil {
+		t.Error("LoadConfigFromArgs() should require aurora-secret in secretsmanager mode")
+	}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-aurora-auth-mode", "iam"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.AuroraAuthMode != "iam" {
+		t.Errorf("AuroraAuthMode = %q, want iam", cfg.AuroraAuthMode)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-aurora-auth-mode", "kerberos")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject an unknown aurora-auth-mode")
+	}
+}
+
+func TestLoadConfigFromArgs_CheckSchema(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.CheckSchema {
+		t.Error("CheckSchema should default to true")
+	}
+
+	os.Setenv("FIS_MIGRATION_CHECK_SCHEMA", "false")
+	defer os.Unsetenv("FIS_MIGRATION_CHECK_SCHEMA")
+	cfg, err = LoadConfigFromArgs(base)
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.CheckSchema {
+		t.Error("FIS_MIGRATION_CHECK_SCHEMA=false should disable the schema check")
+	}
+
+	cfg, err = LoadConfigFromArgs(append(base, "-check-schema=true"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if !cfg.CheckSchema {
+		t.Error("-check-schema should override the environment")
+	}
+}
+
+func TestLoadConfigFromArgs_SegmentSelection(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost",
+		"-s3-bucket", "bucket", "-aws-region", "us-east-1", "-segments", "8"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-only-segments", "0,2-3", "-skip-segments", "3"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	only, skip, err := cfg.SegmentSelection()
+	if err != nil {
+		t.Fatalf("SegmentSelection() error = %v", err)
+	}
+	if fmt.Sprint(only) != "[0 2 3]" || fmt.Sprint(skip) != "[3]" {
+		t.Errorf("SegmentSelection() = %v, %v, want [0 2 3], [3]", only, skip)
+	}
+
+	for _, args := range [][]string{
+		{"-only-segments", "8"},
+		{"-skip-segments", "5-9"},
+		{"-only-segments", "x"},
+	} {
+		if _, err := LoadConfigFromArgs(append(base, args...)); err == nil {
+			t.Errorf("LoadConfigFromArgs() should reject %v with 8 segments", args)
+		}
+	}
+}
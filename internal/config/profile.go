@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey is the top-level YAML key holding the named profiles (-profile).
+const profilesKey = "profiles"
+
+// decodeYAMLProfile decodes a config file into yamlCfg. A file with a top-level profiles map holds one block
+// per environment: the top-level keys are shared, and the block named profile overrides them.
+// A file without profiles is decoded as is (profile must then be empty).
+func decodeYAMLProfile(data []byte, profile string, yamlCfg interface{}) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		if profile != "" {
+			return fmt.Errorf("profile %q requested, but the config file is empty", profile)
+		}
+		return nil
+	}
+	if err := doc.Decode(yamlCfg); err != nil {
+		return err
+	}
+
+	profiles := mappingValue(doc.Content[0], profilesKey)
+	if profiles == nil {
+		if profile != "" {
+			return fmt.Errorf("profile %q requested, but the config file has no %s", profile, profilesKey)
+		}
+		return nil
+	}
+	if profiles.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s must map profile names to config blocks", profilesKey)
+	}
+
+	var names []string
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		names = append(names, profiles.Content[i].Value)
+	}
+	sort.Strings(names)
+	if profile == "" {
+		return fmt.Errorf("config file has profiles (%s), select one with -profile", strings.Join(names, ", "))
+	}
+	block := mappingValue(profiles, profile)
+	if block == nil {
+		return fmt.Errorf("profile %q not found in config file (available: %s)", profile, strings.Join(names, ", "))
+	}
+	if err := block.Decode(yamlCfg); err != nil {
+		return fmt.Errorf("invalid profile %q: %w", profile, err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node, or nil if the node is no mapping or lacks key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
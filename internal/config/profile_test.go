@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const twoProfileYAML = `tenant_id: 1
+aws_region: us-east-1
+batch_size: 5000
+profiles:
+  dev:
+    mariadb_host: dev-db:3306
+    s3_bucket: dev-bucket
+  prod:
+    mariadb_host: prod-db:3306
+    s3_bucket: prod-bucket
+    batch_size: 20000
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "migration-config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return file
+}
+
+func TestLoadConfigFromArgs_Profile(t *testing.T) {
+	file := writeConfigFile(t, twoProfileYAML)
+
+	tests := []struct {
+		profile    string
+		wantHost   string
+		wantBucket string
+		wantBatch  int
+	}{
+		{profile: "dev", wantHost: "dev-db:3306", wantBucket: "dev-bucket", wantBatch: 5000},
+		{profile: "prod", wantHost: "prod-db:3306", wantBucket: "prod-bucket", wantBatch: 20000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			cfg, err := LoadConfigFromArgs([]string{"-config-file", file, "-profile", tt.profile})
+			if err != nil {
+				t.Fatalf("LoadConfigFromArgs() error = %v", err)
+			}
+			if cfg.MariaDBHost != tt.wantHost || cfg.S3Bucket != tt.wantBucket {
+				t.Errorf("MariaDBHost = %q, S3Bucket = %q, want the %s block's %q and %q",
+					cfg.MariaDBHost, cfg.S3Bucket, tt.profile, tt.wantHost, tt.wantBucket)
+			}
+			// Top-level keys are shared, the profile overrides them
+			if cfg.TenantID != 1 || cfg.AWSRegion != "us-east-1" || cfg.BatchSize != tt.wantBatch {
+				t.Errorf("TenantID = %d, AWSRegion = %q, BatchSize = %d, want 1, us-east-1, %d",
+					cfg.TenantID, cfg.AWSRegion, cfg.BatchSize, tt.wantBatch)
+			}
+		})
+	}
+
+	t.Setenv("FIS_MIGRATION_PROFILE", "prod")
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", file})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.S3Bucket != "prod-bucket" {
+		t.Errorf("S3Bucket = %q, want the profile from FIS_MIGRATION_PROFILE", cfg.S3Bucket)
+	}
+}
+
+func TestLoadConfigFromArgs_ProfileErrors(t *testing.T) {
+	profiles := writeConfigFile(t, twoProfileYAML)
+	flat := writeConfigFile(t, "tenant_id: 1\nmariadb_host: localhost\ns3_bucket: bucket\naws_region: us-east-1\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown profile", []string{"-config-file", profiles, "-profile", "stage"}, `profile "stage" not found in config file (available: dev, prod)`},
+		{"no profile selected", []string{"-config-file", profiles}, "select one with -profile"},
+		{"profile of a flat file", []string{"-config-file", flat, "-profile", "dev"}, "has no profiles"},
+		{"profile without a file", []string{"-config-file", "does-not-exist.yaml", "-profile", "dev"}, "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfigFromArgs(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFromArgs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// A flat file without -profile loads as before
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", flat})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBHost != "localhost" || cfg.S3Bucket != "bucket" {
+		t.Errorf("flat file MariaDBHost = %q, S3Bucket = %q", cfg.MariaDBHost, cfg.S3Bucket)
+	}
+}
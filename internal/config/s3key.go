@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// S3KeyFields are the variables available to -s3-key-template.
+type S3KeyFields struct {
+	Prefix   string // -s3-prefix
+	TenantID int
+	Table    string
+	StartHex string // Segment start (inclusive)
+	EndHex   string // Segment end (exclusive)
+	Filename string // Default CSV filename, tenant-<id>.<table>.hash-<start>-<end>.csv
+}
+
+// ParseS3KeyTemplate parses an -s3-key-template and renders it once with sample values,
+// so unknown variables and malformed templates fail at config load instead of mid-export.
+func ParseS3KeyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("s3-key-template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3-key-template: %w", err)
+	}
+
+	sample := S3KeyFields{Prefix: "fis-migration", TenantID: 1, Table: "fis_aggr", StartHex: "00", EndHex: "10",
+		Filename: "tenant-1.fis_aggr.hash-00-10.csv"}
+	key, err := RenderS3Key(tmpl, sample)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3-key-template: %w", err)
+	}
+	// Keys must differ per segment, otherwise segments overwrite each other
+	other := sample
+	other.StartHex, other.EndHex, other.Filename = "10", "20", "tenant-1.fis_aggr.hash-10-20.csv"
+	otherKey, err := RenderS3Key(tmpl, other)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3-key-template: %w", err)
+	}
+	if key == otherKey {
+		return nil, fmt.Errorf("invalid s3-key-template: key %q does not vary by segment (use {{.StartHex}} and {{.EndHex}} or {{.Filename}})", key)
+	}
+	return tmpl, nil
+}
+
+// RenderS3Key renders the S3 object key for fields.
+func RenderS3Key(tmpl *template.Template, fields S3KeyFields) (string, error) {
+	var key strings.Builder
+	if err := tmpl.Execute(&key, fields); err != nil {
+		return "", err
+	}
+	if key.Len() == 0 {
+		return "", fmt.Errorf("rendered an empty key")
+	}
+	if strings.HasPrefix(key.String(), "/") {
+		return "", fmt.Errorf("rendered key %q starts with /", key.String())
+	}
+	return key.String(), nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import "testing"
+
+func TestParseS3KeyTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"default layout", "{{.Prefix}}/tenant-{{.TenantID}}/{{.Table}}/{{.Filename}}", false},
+		{"hive style", "{{.Prefix}}/table={{.Table}}/tenant={{.TenantID}}/{{.StartHex}}-{{.EndHex}}.csv", false},
+		{"start only", "{{.TenantID}}/{{.StartHex}}.csv", false},
+		{"malformed", "{{.Prefix}/{{.Filename}}", true},
+		{"unknown variable", "{{.Prefix}}/{{.Bucket}}/{{.Filename}}", true},
+		{"same key for every segment", "{{.Prefix}}/tenant-{{.TenantID}}.csv", true},
+		{"leading slash", "/{{.Prefix}}/{{.Filename}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseS3KeyTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseS3KeyTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromArgs_S3KeyTemplate(t *testing.T) {
+	base := []string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1", "-mariadb-host", "localhost", "-s3-bucket", "bucket", "-aws-region", "us-east-1"}
+
+	cfg, err := LoadConfigFromArgs(append(base, "-s3-key-template", "{{.Prefix}}/{{.StartHex}}-{{.EndHex}}.csv"))
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.S3KeyTemplate != "{{.Prefix}}/{{.StartHex}}-{{.EndHex}}.csv" {
+		t.Errorf("S3KeyTemplate = %q", cfg.S3KeyTemplate)
+	}
+
+	if _, err := LoadConfigFromArgs(append(base, "-s3-key-template", "{{.Prefix}/{{.StartHex}}")); err == nil {
+		t.Error("LoadConfigFromArgs() should reject a malformed s3-key-template")
+	}
+}
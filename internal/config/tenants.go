@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+)
+
+// TenantOverride is one entry of the config file's tenants list: a tenant to migrate,
+// with optional settings that replace the global ones for that tenant only. Zero values keep the global setting.
+type TenantOverride struct {
+	TenantID  int    `yaml:"tenant_id"`
+	Segments  int    `yaml:"segments"`
+	BatchSize int    `yaml:"batch_size"`
+	TableName string `yaml:"table_name"`
+}
+
+// ForTenant returns a copy of c for migrating tenantID, with its tenants list overrides applied.
+// A table_name override replaces -tables as well, so the tenant migrates only that table.
+func (c *Config) ForTenant(tenantID int) *Config {
+	tenantCfg := *c
+	tenantCfg.TenantID = tenantID
+	for _, override := range c.Tenants {
+		if override.TenantID != tenantID {
+			continue
+		}
+		if override.Segments > 0 {
+			tenantCfg.Segments = override.Segments
+		}
+		if override.BatchSize > 0 {
+			tenantCfg.BatchSize = override.BatchSize
+		}
+		if override.TableName != "" {
+			tenantCfg.TableName = override.TableName
+			tenantCfg.Tables = nil
+		}
+	}
+	return &tenantCfg
+}
+
+// validateTenants checks the tenants list and returns its tenant IDs in order.
+func validateTenants(cfg *Config) ([]int, error) {
+	seen := make(map[int]bool)
+	var ids []int
+	for i, override := range cfg.Tenants {
+		if override.TenantID <= 0 {
+			return nil, fmt.Errorf("invalid tenants entry %d: tenant_id is required", i+1)
+		}
+		if seen[override.TenantID] {
+			return nil, fmt.Errorf("invalid tenants entry %d: tenant %d is listed twice", i+1, override.TenantID)
+		}
+		seen[override.TenantID] = true
+		if override.Segments < 0 || override.BatchSize < 0 {
+			return nil, fmt.Errorf("invalid tenants entry for tenant %d: segments and batch_size must not be negative", override.TenantID)
+		}
+		if override.Segments > 0 && cfg.AutoSegments {
+			return nil, fmt.Errorf("tenant %d overrides segments, which can't be combined with auto-segments", override.TenantID)
+		}
+		if override.BatchSize > 0 && cfg.BatchBytes > 0 {
+			return nil, fmt.Errorf("tenant %d overrides batch_size, which can't be combined with batch-bytes", override.TenantID)
+		}
+		ids = append(ids, override.TenantID)
+	}
+	return ids, nil
+}
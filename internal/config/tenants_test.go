@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const twoTenantYAML = `mariadb_host: localhost
+s3_bucket: bucket
+aws_region: us-east-1
+segments: 16
+batch_size: 5000
+tenants:
+  - tenant_id: 1001
+  - tenant_id: 1002
+    segments: 64
+    table_name: fis_aggr_v2
+`
+
+func TestLoadConfigFromArgs_Tenants(t *testing.T) {
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", writeConfigFile(t, twoTenantYAML), "-tables", "fis_aggr,fis_aggr_v1"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if fmt.Sprint(cfg.TenantIDs) != "[1001 1002]" || cfg.TenantID != 1001 {
+		t.Fatalf("TenantIDs = %v, TenantID = %d, want the tenants list in order", cfg.TenantIDs, cfg.TenantID)
+	}
+
+	tests := []struct {
+		tenantID     int
+		wantSegments int
+		wantBatch    int
+		wantTable    string
+		wantTables   []string
+	}{
+		{1001, 16, 5000, "fis_aggr", []string{"fis_aggr", "fis_aggr_v1"}},
+		{1002, 64, 5000, "fis_aggr_v2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.tenantID), func(t *testing.T) {
+			tenantCfg := cfg.ForTenant(tt.tenantID)
+			if tenantCfg.TenantID != tt.tenantID || tenantCfg.Segments != tt.wantSegments || tenantCfg.BatchSize != tt.wantBatch {
+				t.Errorf("ForTenant() = tenant %d, %d segments, batch size %d, want tenant %d, %d segments, batch size %d",
+					tenantCfg.TenantID, tenantCfg.Segments, tenantCfg.BatchSize, tt.tenantID, tt.wantSegments, tt.wantBatch)
+			}
+			if tenantCfg.TableName != tt.wantTable || fmt.Sprint(tenantCfg.Tables) != fmt.Sprint(tt.wantTables) {
+				t.Errorf("ForTenant() table %s, tables %v, want %s, %v", tenantCfg.TableName, tenantCfg.Tables, tt.wantTable, tt.wantTables)
+			}
+		})
+	}
+	// The overrides don't leak into the shared config
+	if cfg.Segments != 16 || cfg.TenantID != 1001 || len(cfg.Tables) != 2 {
+		t.Errorf("ForTenant() modified the shared config: %d segments, tenant %d, tables %v", cfg.Segments, cfg.TenantID, cfg.Tables)
+	}
+}
+
+func TestLoadConfigFromArgs_TenantsErrors(t *testing.T) {
+	base := "mariadb_host: localhost\ns3_bucket: bucket\naws_region: us-east-1\n"
+	tenantFile := filepath.Join(t.TempDir(), "tenants.txt")
+	if err := os.WriteFile(tenantFile, []byte("1001\n"), 0644); err != nil {
+		t.Fatalf("failed to write tenant file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		args []string
+	}{
+		{"missing tenant_id", base + "tenants:\n  - segments: 8\n", nil},
+		{"duplicate tenant", base + "tenants:\n  - tenant_id: 1001\n  - tenant_id: 1001\n", nil},
+		{"negative segments", base + "tenants:\n  - tenant_id: 1001\n    segments: -1\n", nil},
+		{"segments with auto-segments", base + "tenants:\n  - tenant_id: 1001\n    segments: 8\n", []string{"-auto-segments"}},
+		{"batch_size with batch-bytes", base + "tenants:\n  - tenant_id: 1001\n    batch_size: 10\n",
+			[]string{"-output-dir", t.TempDir(), "-batch-bytes", "4096"}},
+		{"only-segments beyond a tenant's segments", base + "tenants:\n  - tenant_id: 1001\n    segments: 4\n",
+			[]string{"-only-segments", "10"}},
+		{"with tenant-ids-file", base + "tenants:\n  - tenant_id: 1001\n", []string{"-tenant-ids-file", tenantFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-config-file", writeConfigFile(t, tt.yaml)}, tt.args...)
+			if _, err := LoadConfigFromArgs(args); err == nil {
+				t.Errorf("LoadConfigFromArgs() should reject %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// envVarName matches an environment variable name. os.Expand also passes special shell
+// parameters such as $1 or $!, which are kept as written.
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expandYAMLEnv expands ${VAR} and $VAR references in the string fields of yamlCfg (a pointer to
+// the YAML struct) from the environment, so secrets can stay out of the config file. $$ is a literal $.
+// Unset variables expand to "", or fail the load with strict (-yaml-strict-env).
+func expandYAMLEnv(yamlCfg interface{}, strict bool) error {
+	v := reflect.ValueOf(yamlCfg).Elem()
+	var missing []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.String || !strings.Contains(field.String(), "$") {
+			continue
+		}
+		key := v.Type().Field(i).Tag.Get("yaml")
+		field.SetString(os.Expand(field.String(), func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			if !envVarName.MatchString(name) {
+				return "$" + name
+			}
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, fmt.Sprintf("%s (in %s)", name, key))
+			}
+			return value
+		}))
+	}
+	if strict && len(missing) > 0 {
+		return fmt.Errorf("config file references unset environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
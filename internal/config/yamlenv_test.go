@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFromArgs_YAMLEnvExpansion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "migration-config.yaml")
+	yamlConfig := `tenant_id: 1
+mariadb_host: ${TEST_DB_HOST}:3306
+mariadb_user: $TEST_DB_USER
+mariadb_password: ${TEST_PW}
+mariadb_database: fis_$$literal
+s3_bucket: bucket
+aws_region: us-east-1
+`
+	if err := os.WriteFile(file, []byte(yamlConfig), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("TEST_DB_HOST", "db.internal")
+	t.Setenv("TEST_DB_USER", "migrator")
+	t.Setenv("TEST_PW", "s3cr$t")
+
+	cfg, err := LoadConfigFromArgs([]string{"-config-file", file})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBPassword != "s3cr$t" {
+		t.Errorf("MariaDBPassword = %q, want it resolved from TEST_PW", cfg.MariaDBPassword)
+	}
+	if cfg.MariaDBHost != "db.internal:3306" || cfg.MariaDBUser != "migrator" {
+		t.Errorf("MariaDBHost = %q, MariaDBUser = %q, want db.internal:3306 and migrator", cfg.MariaDBHost, cfg.MariaDBUser)
+	}
+	if cfg.MariaDBDatabase != "fis_$literal" {
+		t.Errorf("MariaDBDatabase = %q, want $$ kept as a literal $", cfg.MariaDBDatabase)
+	}
+
+	// An unset variable expands to empty, unless -yaml-strict-env
+	os.Unsetenv("TEST_PW")
+	cfg, err = LoadConfigFromArgs([]string{"-config-file", file})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	if cfg.MariaDBPassword != "" {
+		t.Errorf("MariaDBPassword = %q, want empty for an unset variable", cfg.MariaDBPassword)
+	}
+	_, err = LoadConfigFromArgs([]string{"-config-file", file, "-yaml-strict-env"})
+	if err == nil || !strings.Contains(err.Error(), "TEST_PW (in mariadb_password)") {
+		t.Errorf("LoadConfigFromArgs() with -yaml-strict-env error = %v, want TEST_PW reported", err)
+	}
+	t.Setenv("FIS_MIGRATION_YAML_STRICT_ENV", "true")
+	if _, err := LoadConfigFromArgs([]string{"-config-file", file}); err == nil {
+		t.Error("LoadConfigFromArgs() with FIS_MIGRATION_YAML_STRICT_ENV should fail for an unset variable")
+	}
+}
+
+func TestExpandYAMLEnv(t *testing.T) {
+	t.Setenv("TEST_VALUE", "v")
+	var yamlCfg struct {
+		Plain   string `yaml:"plain"`
+		Braced  string `yaml:"braced"`
+		Special string `yaml:"special"`
+		Count   int    `yaml:"count"`
+	}
+	yamlCfg.Plain = "no references"
+	yamlCfg.Braced = "a-${TEST_VALUE}-$TEST_VALUE"
+	yamlCfg.Special = "p@ss$1$!"
+	if err := expandYAMLEnv(&yamlCfg, true); err != nil {
+		t.Fatalf("expandYAMLEnv() error = %v", err)
+	}
+	if yamlCfg.Plain != "no references" || yamlCfg.Braced != "a-v-v" || yamlCfg.Special != "p@ss$1$!" {
+		t.Errorf("expandYAMLEnv() = %+v, want references expanded and special parameters kept", yamlCfg)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+// Package errs defines the kinds of failure a migration run can hit, so callers can tell
+// retryable failures from fatal ones with errors.Is instead of matching error messages.
+package errs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Error kinds. Errors are wrapped with one at the package boundary where they happen
+// (exporter, s3, sqlgen, config); errors.Is(err, errs.ErrUploadFailed) matches through any later wrapping.
+var (
+	ErrConfig        = errors.New("configuration error")               // Invalid flags or settings, fatal
+	ErrSourceConnect = errors.New("source database connection failed") // MariaDB unreachable, retryable
+	ErrSourceQuery   = errors.New("source database query failed")      // Export query failed, retryable
+	ErrUploadFailed  = errors.New("S3 upload failed")                  // S3 request failed, retryable
+	ErrTargetConnect = errors.New("Aurora connection failed")          // Aurora or its credentials unreachable, retryable
+	ErrSQLExec       = errors.New("SQL execution failed")              // LOAD DATA statements failed, fatal
+)
+
+// retryableKinds are the kinds worth retrying: the failure is likely transient.
+var retryableKinds = []error{ErrSourceConnect, ErrSourceQuery, ErrUploadFailed, ErrTargetConnect}
+
+// allKinds lists every kind, in the order KindOf reports them.
+var allKinds = []error{ErrConfig, ErrSourceConnect, ErrSourceQuery, ErrUploadFailed, ErrTargetConnect, ErrSQLExec}
+
+// Error is an error tagged with its kind. The message is that of the wrapped error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns both the kind and the wrapped error, so errors.Is and errors.As match either.
+func (e *Error) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+// Wrap tags err with kind. Returns nil for a nil err, and err unchanged if it already is of kind.
+func Wrap(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of err, or nil if it has none. If err wraps several kinds
+// (e.g. a failed upload of a file that failed to generate), the first in allKinds is returned.
+func KindOf(err error) error {
+	for _, kind := range allKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// MySQL error numbers of a server rejecting the connection's user, password or database.
+const (
+	errNumDBAccessDenied = 1044 // ER_DBACCESS_DENIED_ERROR
+	errNumAccessDenied   = 1045 // ER_ACCESS_DENIED_ERROR
+	errNumBadDB          = 1049 // ER_BAD_DB_ERROR
+)
+
+// WrapConnect tags err, a failed connection or ping, with kind (ErrSourceConnect or ErrTargetConnect),
+// and with ErrConfig if the server rejected the user, password or database: connecting again won't help.
+func WrapConnect(kind, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case errNumDBAccessDenied, errNumAccessDenied, errNumBadDB:
+			err = Wrap(ErrConfig, err)
+		}
+	}
+	return Wrap(kind, err)
+}
+
+// IsRetryable reports whether err is of a kind worth retrying. Configuration errors and
+// cancellation are never retryable, even when wrapped in a retryable kind.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrConfig) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	for _, kind := range retryableKinds {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestWrap(t *testing.T) {
+	if Wrap(ErrUploadFailed, nil) != nil {
+		t.Error("Wrap() of nil should be nil")
+	}
+
+	cause := errors.New("connection reset")
+	err := fmt.Errorf("segment 3: %w", Wrap(ErrUploadFailed, fmt.Errorf("failed to upload part 2: %w", cause)))
+	if !errors.Is(err, ErrUploadFailed) || !errors.Is(err, cause) {
+		t.Errorf("wrapped error %v should match both its kind and its cause", err)
+	}
+	if err.Error() != "segment 3: failed to upload part 2: connection reset" {
+		t.Errorf("Error() = %q, want the message unchanged by the kind", err.Error())
+	}
+
+	var kindErr *Error
+	if !errors.As(err, &kindErr) || kindErr.Kind != ErrUploadFailed {
+		t.Errorf("errors.As() = %v, want an *Error of ErrUploadFailed", kindErr)
+	}
+	if again := Wrap(ErrUploadFailed, err); again != err {
+		t.Error("Wrap() should return an error already of the kind unchanged")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"none", errors.New("boom"), nil},
+		{"nil", nil, nil},
+		{"direct", Wrap(ErrSQLExec, errors.New("statement 1/2 failed")), ErrSQLExec},
+		{"wrapped", fmt.Errorf("tenant 1: %w", Wrap(ErrSourceConnect, errors.New("refused"))), ErrSourceConnect},
+		{"config wins", Wrap(ErrSQLExec, Wrap(ErrConfig, errors.New("invalid clause"))), ErrConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapConnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+	}{
+		{"refused", errors.New("dial tcp 127.0.0.1:3306: connect: connection refused"), ErrSourceConnect},
+		{"server gone", &mysql.MySQLError{Number: 2006, Message: "MySQL server has gone away"}, ErrSourceConnect},
+		{"access denied", fmt.Errorf("ping: %w", &mysql.MySQLError{Number: 1045, Message: "Access denied for user 'fis'"}), ErrConfig},
+		{"database access denied", &mysql.MySQLError{Number: 1044, Message: "Access denied for user 'fis' to database 'fis'"}, ErrConfig},
+		{"unknown database", &mysql.MySQLError{Number: 1049, Message: "Unknown database 'fis'"}, ErrConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapConnect(ErrSourceConnect, tt.err)
+			if !errors.Is(err, ErrSourceConnect) || !errors.Is(err, tt.err) {
+				t.Errorf("WrapConnect() = %v, want ErrSourceConnect wrapping the error", err)
+			}
+			if got := KindOf(err); got != tt.wantKind {
+				t.Errorf("KindOf(WrapConnect()) = %v, want %v", got, tt.wantKind)
+			}
+			if got, want := IsRetryable(err), tt.wantKind != ErrConfig; got != want {
+				t.Errorf("IsRetryable(WrapConnect()) = %v, want %v", got, want)
+			}
+		})
+	}
+	if WrapConnect(ErrTargetConnect, nil) != nil {
+		t.Error("WrapConnect() of nil should be nil")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unclassified", errors.New("boom"), false},
+		{"source connect", Wrap(ErrSourceConnect, errors.New("refused")), true},
+		{"source query", Wrap(ErrSourceQuery, errors.New("lock wait timeout")), true},
+		{"upload", Wrap(ErrUploadFailed, errors.New("503 slow down")), true},
+		{"target connect", Wrap(ErrTargetConnect, errors.New("refused")), true},
+		{"sql exec", Wrap(ErrSQLExec, errors.New("syntax error")), false},
+		{"config", Wrap(ErrConfig, errors.New("invalid flag")), false},
+		{"config inside retryable", Wrap(ErrUploadFailed, Wrap(ErrConfig, errors.New("bad key template"))), false},
+		{"canceled", Wrap(ErrSourceQuery, context.Canceled), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
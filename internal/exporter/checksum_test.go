@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeChecksummer returns fixed checksums per segment index
+type fakeChecksummer struct {
+	sums map[int]SegmentChecksum
+	err  error
+}
+
+func (f *fakeChecksummer) SegmentChecksum(seg segment.Segment) (SegmentChecksum, error) {
+	if f.err != nil {
+		return SegmentChecksum{}, f.err
+	}
+	return f.sums[seg.Index], nil
+}
+
+func TestCompareSegmentChecksums(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	source := &fakeChecksummer{sums: map[int]SegmentChecksum{
+		0: {Rows: 3, Checksum: 0xaa},
+		1: {Rows: 2, Checksum: 0xbb},
+		2: {Rows: 2, Checksum: 0xcc},
+		3: {Rows: 2, Checksum: 0xdd},
+	}}
+	target := &fakeChecksummer{sums: map[int]SegmentChecksum{
+		0: {Rows: 3, Checksum: 0xaa},
+		1: {Rows: 1, Checksum: 0xb0}, // Row missing
+		2: {Rows: 2, Checksum: 0xcc},
+		3: {Rows: 2, Checksum: 0xd0}, // Same count, different content
+	}}
+
+	report, err := CompareSegmentChecksums(segments, source, target)
+	if err != nil {
+		t.Fatalf("CompareSegmentChecksums() error = %v", err)
+	}
+	if len(report.Segments) != 4 {
+		t.Errorf("expected 4 compared segments, got %d", len(report.Segments))
+	}
+	if len(report.Mismatched) != 2 || report.Mismatched[0].Segment.Index != 1 || report.Mismatched[1].Segment.Index != 3 {
+		t.Errorf("Mismatched = %+v, want segments 1 and 3", report.Mismatched)
+	}
+
+	if _, err := CompareSegmentChecksums(segments, source, &fakeChecksummer{err: errors.New("connection lost")}); err == nil {
+		t.Error("CompareSegmentChecksums() should fail when the target checksum fails")
+	}
+}
+
+func TestCompareSegmentChecksums_CorruptedSegment(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 424242
+	setupTestTable(t, db, tenantID)
+	if _, err := db.Exec(`UPDATE fis_aggr SET last_modified = '2024-01-02 03:04:05', version = 7
+		WHERE tenantid = ? AND hash < '80'`, tenantID); err != nil {
+		t.Fatalf("Failed to set last_modified and version: %v", err)
+	}
+
+	// Emulate the load: copy the tenant's rows, storing NULL versions as the 0 LOAD DATA writes for empty fields
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS fis_aggr_loaded",
+		"CREATE TABLE fis_aggr_loaded LIKE fis_aggr",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create target table: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO fis_aggr_loaded (tenantid, hash, aggr, last_modified, version)
+		SELECT tenantid, hash, aggr, last_modified, IFNULL(version, 0) FROM fis_aggr WHERE tenantid = ?`, tenantID); err != nil {
+		t.Fatalf("Failed to load target table: %v", err)
+	}
+
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis"}
+	source := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	target := NewTableChecksummer(db, "fis_aggr_loaded", "tenantid", tenantID)
+
+	report, err := CompareSegmentChecksums(segments, source, target)
+	if err != nil {
+		t.Fatalf("CompareSegmentChecksums() error = %v", err)
+	}
+	if len(report.Mismatched) != 0 {
+		t.Fatalf("expected a faithful load to match, got mismatches %+v", report.Mismatched)
+	}
+
+	// Corrupt one row of segment 2 (80-bf) without changing the row count
+	if _, err := db.Exec(`UPDATE fis_aggr_loaded SET aggr = '{"test": "corrupted"}' WHERE tenantid = ? AND hash = 'bfabc123def456'`, tenantID); err != nil {
+		t.Fatalf("Failed to corrupt target row: %v", err)
+	}
+
+	report, err = CompareSegmentChecksums(segments, source, target)
+	if err != nil {
+		t.Fatalf("CompareSegmentChecksums() error = %v", err)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0].Segment.Index != 2 {
+		t.Fatalf("Mismatched = %+v, want exactly segment 2", report.Mismatched)
+	}
+	if got := report.Mismatched[0]; got.Source.Rows != got.Target.Rows || got.Source.Rows != 2 {
+		t.Errorf("expected 2 rows on both sides of segment 2, got source %d target %d", got.Source.Rows, got.Target.Rows)
+	}
+}
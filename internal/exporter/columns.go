@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+// ExportColumns returns the columns of the exported CSV files, naming the tenant column tenantColumn:
+// CSVHeader without the -exclude-columns columns.
+func ExportColumns(tenantColumn string, excluded []string) []string {
+	omitted := columnSet(excluded)
+	var columns []string
+	for _, column := range CSVHeader(tenantColumn) {
+		if !omitted[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// columnSet returns the set of the excluded columns (nil if none).
+func columnSet(excluded []string) map[string]bool {
+	if len(excluded) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(excluded))
+	for _, column := range excluded {
+		set[column] = true
+	}
+	return set
+}
+
+// selectColumns returns the select list of the segment queries, the columns scanRow reads.
+// Excluded columns are selected as constants, so their values are neither read nor sent:
+// an empty aggr, a NULL last_modified or version.
+func (e *Exporter) selectColumns() string {
+	aggr, lastModified, version := "aggr", "last_modified", "version"
+	if e.omitted["aggr"] {
+		aggr = "'' AS aggr"
+	}
+	if e.omitted["last_modified"] {
+		lastModified = "NULL AS last_modified"
+	}
+	if e.omitted["version"] {
+		version = "NULL AS version"
+	}
+	return e.config.TenantColumnName() + ", hash, " + aggr + ", " + lastModified + ", " + version
+}
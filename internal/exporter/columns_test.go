@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExportColumns(t *testing.T) {
+	tests := []struct {
+		excluded []string
+		want     []string
+	}{
+		{nil, []string{"tenant_id", "hash", "aggr", "last_modified", "version"}},
+		{[]string{"aggr"}, []string{"tenant_id", "hash", "last_modified", "version"}},
+		{[]string{"version", "last_modified"}, []string{"tenant_id", "hash", "aggr"}},
+	}
+	for _, tt := range tests {
+		if got := ExportColumns("tenant_id", tt.excluded); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExportColumns(%v) = %v, want %v", tt.excluded, got, tt.want)
+		}
+	}
+}
+
+func TestExportSegment_ExcludeColumns(t *testing.T) {
+	hashes := []string{"00abc123", "01abc123", "02abc123"}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+
+	db := sql.OpenDB(&faultConnector{hashes: hashes})
+	defer db.Close()
+	cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 2, CSVHeader: true, NullMarker: "\\N", ExcludeColumns: "aggr"}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t), omitted: columnSet(cfg.ExcludedColumns())}
+
+	// aggr isn't read from the source
+	if got, want := exp.selectColumns(), "tenantid, hash, '' AS aggr, last_modified, version"; got != want {
+		t.Errorf("selectColumns() = %q, want %q", got, want)
+	}
+
+	uploader := newMockS3Uploader()
+	csvFile, err := exp.ExportSegment(seg, uploader)
+	if err != nil {
+		t.Fatalf("ExportSegment() error = %v", err)
+	}
+	var data strings.Builder
+	for _, part := range uploader.streams[csvFile.S3Key].parts {
+		data.Write(part)
+	}
+	records, err := csv.NewReader(strings.NewReader(data.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != len(hashes)+1 {
+		t.Fatalf("CSV has %d lines, want a header and %d rows", len(records), len(hashes))
+	}
+	if want := []string{"tenantid", "hash", "last_modified", "version"}; !reflect.DeepEqual(records[0], want) {
+		t.Errorf("header = %v, want %v", records[0], want)
+	}
+	for i, record := range records[1:] {
+		if len(record) != 4 || record[0] != "1234" || record[1] != hashes[i] {
+			t.Errorf("row %d = %v, want 4 columns: tenantid, hash %s, last_modified, version", i, record, hashes[i])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+)
+
+// -compress codecs.
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// Codec compresses the CSV parts of a segment (-compress).
+// Other codecs can be plugged in with SetCodec.
+type Codec interface {
+	Extension() string                             // Appended to CSV filenames, e.g. ".gz"
+	NewWriter(w io.Writer) (io.WriteCloser, error) // Compresses everything written to w until Close
+}
+
+// ParseCodec returns the codec for a -compress name at -compress-level, or nil for "" and none (CSV uploaded uncompressed).
+// A level of 0 uses the codec's default, others are checked with config.ValidateCompressLevel.
+//   - gzip: levels 1 (fastest) to 9 (best), readable by Aurora LOAD DATA FROM S3.
+//   - zstd: levels 1 to 22, a better ratio and speed on JSON-heavy aggr, but Aurora can't load it.
+func ParseCodec(name string, level int) (Codec, error) {
+	if err := config.ValidateCompressLevel(name, level); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "", CompressNone:
+		return nil, nil
+	case CompressGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzipCodec{level: level}, nil
+	case CompressZstd:
+		encoderLevel := zstd.SpeedDefault
+		if level != 0 {
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		return zstdCodec{level: encoderLevel}, nil
+	default:
+		return nil, fmt.Errorf("invalid compress %q (expected %s, %s or %s)", name, CompressNone, CompressGzip, CompressZstd)
+	}
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (gzipCodec) Extension() string { return ".gz" }
+
+func (c gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.level)
+}
+
+type zstdCodec struct {
+	level zstd.EncoderLevel
+}
+
+func (zstdCodec) Extension() string { return ".zst" }
+
+func (c zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	// Parts are compressed one at a time per segment, so one encoder goroutine is enough
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(c.level), zstd.WithEncoderConcurrency(1))
+}
+
+// compressedStream compresses each part before uploading it to stream.
+// Every part is a complete gzip member or zstd frame, and concatenated members or frames
+// decompress as one file, so the completed multipart object is a valid compressed CSV.
+// A compressed part can be far below S3's minimum part size, so stream coalesces them (see compressStream).
+type compressedStream struct {
+	stream MultipartUploadStreamer
+	codec  Codec
+}
+
+func (c *compressedStream) UploadPart(data []byte) error {
+	var buf bytes.Buffer
+	w, err := c.codec.NewWriter(&buf)
+	if err != nil {
+		return fmt.Errorf("failed to start compressing part: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to compress part: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to compress part: %w", err)
+	}
+	return c.stream.UploadPart(buf.Bytes())
+}
+
+func (c *compressedStream) Complete() error {
+	return c.stream.Complete()
+}
+
+func (c *compressedStream) Abort() {
+	c.stream.Abort()
+}
+
+func (c *compressedStream) KeepParts(n int32) error {
+	return KeepParts(c.stream, n)
+}
+
+// compressStream returns stream with its parts compressed by e.codec. The compressed parts are coalesced
+// until they reach S3's minimum part size: a compressed batch can be far below it, failing the upload on Complete.
+func (e *Exporter) compressStream(stream MultipartUploadStreamer) MultipartUploadStreamer {
+	return &compressedStream{stream: &coalescingStream{stream: stream}, codec: e.codec}
+}
+
+// SetCodec replaces the codec configured by -compress (nil uploads the CSV uncompressed).
+func (e *Exporter) SetCodec(codec Codec) {
+	e.codec = codec
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestParseCodec(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   int
+		wantExt string
+		wantErr bool
+	}{
+		{name: ""},
+		{name: CompressNone},
+		{name: CompressGzip, wantExt: ".gz"},
+		{name: CompressGzip, level: 1, wantExt: ".gz"},
+		{name: CompressGzip, level: 9, wantExt: ".gz"},
+		{name: CompressGzip, level: 10, wantErr: true},
+		{name: CompressGzip, level: -1, wantErr: true},
+		{name: CompressZstd, wantExt: ".zst"},
+		{name: CompressZstd, level: 1, wantExt: ".zst"},
+		{name: CompressZstd, level: 22, wantExt: ".zst"},
+		{name: CompressZstd, level: 23, wantErr: true},
+		{name: CompressZstd, level: -1, wantErr: true},
+		{name: "lz4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%d", tt.name, tt.level), func(t *testing.T) {
+			codec, err := ParseCodec(tt.name, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCodec(%q, %d) error = %v, wantErr %v", tt.name, tt.level, err, tt.wantErr)
+			}
+			ext := ""
+			if codec != nil {
+				ext = codec.Extension()
+			}
+			if ext != tt.wantExt {
+				t.Errorf("ParseCodec(%q, %d) extension = %q, want %q", tt.name, tt.level, ext, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestParseCodec_Level(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		want  Codec
+	}{
+		{CompressGzip, 0, gzipCodec{level: gzip.DefaultCompression}},
+		{CompressGzip, 1, gzipCodec{level: gzip.BestSpeed}},
+		{CompressGzip, 9, gzipCodec{level: gzip.BestCompression}},
+		{CompressZstd, 0, zstdCodec{level: zstd.SpeedDefault}},
+		{CompressZstd, 1, zstdCodec{level: zstd.SpeedFastest}},
+		{CompressZstd, 22, zstdCodec{level: zstd.SpeedBestCompression}},
+	}
+	for _, tt := range tests {
+		codec, err := ParseCodec(tt.name, tt.level)
+		if err != nil {
+			t.Fatalf("ParseCodec(%q, %d) error = %v", tt.name, tt.level, err)
+		}
+		if codec != tt.want {
+			t.Errorf("ParseCodec(%q, %d) = %+v, want %+v", tt.name, tt.level, codec, tt.want)
+		}
+	}
+}
+
+func TestStreamSegment_CompressRoundTrip(t *testing.T) {
+	var rows []Row
+	for i := 0; i < 250; i++ {
+		aggr := fmt.Sprintf(`{"app":"box","user":"user%d@example.com","v":"%s"}`, i, strings.Repeat("x", i%50))
+		rows = append(rows, Row{TenantID: 1, Hash: fmt.Sprintf("00%030x", i), Aggr: aggr})
+	}
+
+	decompress := map[string]func(io.Reader) (io.Reader, error){
+		CompressGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		CompressZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	// The default and both ends of each codec's level range
+	levels := map[string][]int{CompressGzip: {0, 1, 9}, CompressZstd: {0, 1, 22}}
+	for _, name := range []string{CompressGzip, CompressZstd} {
+		for _, level := range levels[name] {
+			t.Run(fmt.Sprintf("%s-%d", name, level), func(t *testing.T) {
+				codec, err := ParseCodec(name, level)
+				if err != nil {
+					t.Fatalf("ParseCodec() error = %v", err)
+				}
+				cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 100, CSVHeader: true, NullMarker: `\N`}
+				exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t), codec: codec}
+
+				query, _ := newFakeQuerier(rows, cfg.BatchSize)
+				mock := &mockMultipartUploadStream{}
+				stream := &compressedStream{stream: mock, codec: codec}
+				seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+				if _, _, err := exp.streamSegment(seg, "test-key", stream, query); err != nil {
+					t.Fatalf("streamSegment() error = %v", err)
+				}
+				if len(mock.parts) != 3 {
+					t.Fatalf("got %d parts, want one per batch", len(mock.parts))
+				}
+
+				// The parts are compressed separately, put together they decompress to the whole CSV
+				reader, err := decompress[name](bytes.NewReader(bytes.Join(mock.parts, nil)))
+				if err != nil {
+					t.Fatalf("failed to open decompressor: %v", err)
+				}
+				got, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("failed to decompress: %v", err)
+				}
+				want, err := exp.rowsToCSVBytes(rows, true)
+				if err != nil {
+					t.Fatalf("rowsToCSVBytes() error = %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("decompressed CSV differs (%d bytes, want %d)", len(got), len(want))
+				}
+				if compressed := len(bytes.Join(mock.parts, nil)); compressed >= len(want) {
+					t.Errorf("compressed size %d is not below the CSV size %d", compressed, len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestCompressStream_MinPartSize(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name      string
+		aggr      func(i int) string
+		rows      int
+		wantParts int
+	}{
+		// Each batch compresses to a few KiB, alone every part but the last would be below S3's minimum
+		{"compressible", func(i int) string {
+			return fmt.Sprintf(`{"app":"box","n":%d,"v":"%s"}`, i, strings.Repeat("x", 16*1024))
+		}, 2000, 1},
+		// About 10 MiB compressed
+		{"incompressible", func(int) string {
+			b := make([]byte, 16*1024)
+			random.Read(b)
+			return hex.EncodeToString(b)
+		}, 600, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []Row
+			for i := 0; i < tt.rows; i++ {
+				rows = append(rows, Row{TenantID: 1, Hash: fmt.Sprintf("00%030x", i), Aggr: tt.aggr(i)})
+			}
+			codec, err := ParseCodec(CompressGzip, 1)
+			if err != nil {
+				t.Fatalf("ParseCodec() error = %v", err)
+			}
+			cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 100, CSVHeader: true, NullMarker: `\N`}
+			exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t), codec: codec}
+
+			query, _ := newFakeQuerier(rows, cfg.BatchSize)
+			mock := &mockMultipartUploadStream{}
+			stream := exp.compressStream(mock)
+			seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+			if _, _, err := exp.streamSegment(seg, "test-key", stream, query); err != nil {
+				t.Fatalf("streamSegment() error = %v", err)
+			}
+			if err := stream.Complete(); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+
+			if len(mock.parts) != tt.wantParts {
+				t.Errorf("uploaded %d parts, want %d", len(mock.parts), tt.wantParts)
+			}
+			for i, part := range mock.parts[:len(mock.parts)-1] {
+				if len(part) < minPartSize {
+					t.Errorf("part %d is %d bytes, below S3's minimum of %d", i+1, len(part), minPartSize)
+				}
+			}
+
+			reader, err := gzip.NewReader(bytes.NewReader(bytes.Join(mock.parts, nil)))
+			if err != nil {
+				t.Fatalf("failed to open decompressor: %v", err)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to decompress: %v", err)
+			}
+			want, err := exp.rowsToCSVBytes(rows, true)
+			if err != nil {
+				t.Fatalf("rowsToCSVBytes() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decompressed CSV differs (%d bytes, want %d)", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import "sync/atomic"
+
+// countingStream counts the bytes of the parts passed to the underlying stream,
+// the size of the object (or local file) once it completes. Wrapped around the opened stream,
+// below compression, it counts what is uploaded rather than the CSV before compression.
+type countingStream struct {
+	stream MultipartUploadStreamer
+	bytes  atomic.Int64
+}
+
+func (c *countingStream) UploadPart(data []byte) error {
+	if err := c.stream.UploadPart(data); err != nil {
+		return err
+	}
+	c.bytes.Add(int64(len(data)))
+	return nil
+}
+
+func (c *countingStream) Complete() error {
+	return c.stream.Complete()
+}
+
+func (c *countingStream) Abort() {
+	c.stream.Abort()
+}
+
+// KeepParts keeps the parts of a previous run, which this run's Bytes don't count.
+func (c *countingStream) KeepParts(n int32) error {
+	return KeepParts(c.stream, n)
+}
+
+// Bytes returns the bytes of the parts uploaded so far.
+func (c *countingStream) Bytes() int64 {
+	return c.bytes.Load()
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExportSegment_ByteSize(t *testing.T) {
+	hashes := []string{"00abc123", "01abc123", "02abc123", "03abc123", "04abc123"}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+
+	tests := []struct {
+		codec     string
+		wantParts int
+	}{
+		{"none", 3},
+		// The compressed batches are coalesced into one part, being below S3's minimum
+		{"gzip", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			codec, err := ParseCodec(tt.codec, 0)
+			if err != nil {
+				t.Fatalf("ParseCodec() error = %v", err)
+			}
+			db := sql.OpenDB(&faultConnector{hashes: hashes})
+			defer db.Close()
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 2, CSVHeader: true}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t), codec: codec}
+
+			uploader := newMockS3Uploader()
+			csvFile, err := exp.ExportSegment(seg, uploader)
+			if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			}
+
+			// The bytes of the parts sent to S3, compressed if the codec is set
+			stream := uploader.streams[csvFile.S3Key]
+			var want int64
+			for _, part := range stream.parts {
+				want += int64(len(part))
+			}
+			if len(stream.parts) != tt.wantParts || want == 0 {
+				t.Fatalf("uploaded %d parts of %d bytes, want %d", len(stream.parts), want, tt.wantParts)
+			}
+			if csvFile.ByteSize != want {
+				t.Errorf("ByteSize = %d, want %d (the sum of the uploaded parts)", csvFile.ByteSize, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// FileUploader uploads a local file to S3.
+// This allows mocking in tests.
+type FileUploader interface {
+	UploadFileWithRetry(filepath, s3Key string) error
+}
+
+// DeadLetterRecord is a single skipped row written to the dead-letter output (one JSON object per line).
+// Aggr holds the raw bytes, base64-encoded: a JSON string would replace invalid UTF-8 with U+FFFD.
+type DeadLetterRecord struct {
+	TenantID int    `json:"tenantid"`
+	Hash     string `json:"hash"`
+	Reason   string `json:"reason"`
+	Segment  int    `json:"segment"`
+	Aggr     []byte `json:"aggr_base64"`
+}
+
+// DeadLetterSink collects rows skipped by row-level policies so nothing is silently lost.
+// Records are written to a local JSONL file. For an s3:// target the file is staged
+// locally and uploaded to S3 on Close.
+// Safe for concurrent use by multiple segment workers.
+type DeadLetterSink struct {
+	mu       sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	path     string
+	s3Key    string // Empty for local targets
+	uploader FileUploader
+	count    int
+	logger   *zap.Logger
+}
+
+// ParseDeadLetterTarget splits a dead-letter target into bucket and key for s3://bucket/key values.
+// Returns ok=false for local paths.
+func ParseDeadLetterTarget(target string) (bucket, key string, ok bool, err error) {
+	if !strings.HasPrefix(target, "s3://") {
+		return "", "", false, nil
+	}
+	rest := strings.TrimPrefix(target, "s3://")
+	idx := strings.Index(rest, "/")
+	if idx <= 0 || idx == len(rest)-1 {
+		return "", "", true, fmt.Errorf("invalid dead-letter S3 target %q (expected s3://bucket/key)", target)
+	}
+	return rest[:idx], rest[idx+1:], true, nil
+}
+
+// NewDeadLetterSink creates a dead-letter sink for the given target.
+// target is a local file path or s3://bucket/key. uploader is only used for S3 targets.
+func NewDeadLetterSink(target string, uploader FileUploader, logger *zap.Logger) (*DeadLetterSink, error) {
+	_, s3Key, isS3, err := ParseDeadLetterTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var file *os.File
+	if isS3 {
+		if uploader == nil {
+			return nil, fmt.Errorf("S3 uploader is required for dead-letter target %s", target)
+		}
+		file, err = os.CreateTemp("", "dead-letter-*.jsonl")
+	} else {
+		file, err = os.Create(target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter file: %w", err)
+	}
+
+	return &DeadLetterSink{
+		file:     file,
+		writer:   bufio.NewWriter(file),
+		path:     file.Name(),
+		s3Key:    s3Key,
+		uploader: uploader,
+		logger:   logger,
+	}, nil
+}
+
+// Write records a skipped row with its skip reason.
+func (d *DeadLetterSink) Write(row Row, segmentIndex int, reason string) error {
+	data, err := json.Marshal(DeadLetterRecord{
+		TenantID: row.TenantID,
+		Hash:     row.Hash,
+		Reason:   reason,
+		Segment:  segmentIndex,
+		Aggr:     []byte(row.Aggr),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode dead-letter record: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead-letter record: %w", err)
+	}
+	d.count++
+	return nil
+}
+
+// Count returns the number of rows written to the dead-letter output.
+func (d *DeadLetterSink) Count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+// Close flushes the dead-letter output and uploads it to S3 for s3:// targets.
+// Nothing is uploaded if no rows were skipped.
+func (d *DeadLetterSink) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.writer.Flush(); err != nil {
+		d.file.Close()
+		return fmt.Errorf("failed to flush dead-letter file: %w", err)
+	}
+	if err := d.file.Close(); err != nil {
+		return fmt.Errorf("failed to close dead-letter file: %w", err)
+	}
+
+	if d.s3Key == "" {
+		if d.count > 0 {
+			d.logger.Warn("Skipped rows written to dead-letter file",
+				zap.String("path", d.path),
+				zap.Int("rows", d.count))
+		}
+		return nil
+	}
+
+	defer os.Remove(d.path)
+	if d.count == 0 {
+		return nil
+	}
+	if err := d.uploader.UploadFileWithRetry(d.path, d.s3Key); err != nil {
+		return fmt.Errorf("failed to upload dead-letter file to S3: %w", err)
+	}
+
+	d.logger.Warn("Skipped rows written to dead-letter object",
+		zap.String("s3_key", d.s3Key),
+		zap.Int("rows", d.count))
+	return nil
+}
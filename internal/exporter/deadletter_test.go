@@ -0,0 +1,183 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// mockFileUploader records uploaded files for testing
+type mockFileUploader struct {
+	uploads map[string]string // s3Key -> file content
+}
+
+func (m *mockFileUploader) UploadFileWithRetry(filepath, s3Key string) error {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+	m.uploads[s3Key] = string(data)
+	return nil
+}
+
+func readDeadLetterRecords(t *testing.T, content string) []DeadLetterRecord {
+	var records []DeadLetterRecord
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		var rec DeadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid dead-letter line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestExportSegment_DeadLetter(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	cfg := &config.Config{
+		TenantID:  1234,
+		TableName: "fis_aggr",
+		BatchSize: 10,
+	}
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	sink, err := NewDeadLetterSink(deadLetterPath, nil, logger)
+	if err != nil {
+		t.Fatalf("NewDeadLetterSink() error = %v", err)
+	}
+
+	exp := &Exporter{config: cfg, logger: logger}
+	exp.SetDeadLetterSink(sink)
+
+	rows := []Row{
+		{TenantID: 1234, Hash: "00abc", Aggr: `{"ok": 1}`},
+		{TenantID: 1234, Hash: "0", Aggr: `{"ok": 2}`},
+		{TenantID: 1234, Hash: "01abc", Aggr: "bad \xff\xfe utf8"},
+		{TenantID: 1234, Hash: "02abc", Aggr: `{"ok": 3}`},
+	}
+	query := func(lastHash string) ([]Row, error) {
+		if lastHash != "" {
+			return nil, nil
+		}
+		return rows, nil
+	}
+
+	stream := &mockMultipartUploadStream{}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	exported, _, err := exp.streamSegment(seg, "test-key", stream, query)
+	if err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Valid rows export normally
+	if exported != 2 {
+		t.Errorf("expected 2 exported rows, got %d", exported)
+	}
+	if len(stream.parts) != 1 {
+		t.Fatalf("expected 1 uploaded part, got %d", len(stream.parts))
+	}
+	csvContent := string(stream.parts[0])
+	for _, hash := range []string{"00abc", "02abc"} {
+		if !strings.Contains(csvContent, hash) {
+			t.Errorf("CSV should contain valid row %s", hash)
+		}
+	}
+	if strings.Contains(csvContent, "01abc") {
+		t.Errorf("CSV should not contain skipped row 01abc")
+	}
+
+	// Skipped rows land in the dead-letter output with reasons
+	content, err := os.ReadFile(deadLetterPath)
+	if err != nil {
+		t.Fatalf("failed to read dead-letter file: %v", err)
+	}
+	records := readDeadLetterRecords(t, string(content))
+	if len(records) != 2 {
+		t.Fatalf("expected 2 dead-letter records, got %d", len(records))
+	}
+	want := map[string]string{"0": SkipReasonShortHash, "01abc": SkipReasonInvalidUTF8}
+	for _, rec := range records {
+		if want[rec.Hash] != rec.Reason {
+			t.Errorf("record %s: expected reason %q, got %q", rec.Hash, want[rec.Hash], rec.Reason)
+		}
+		if rec.TenantID != 1234 {
+			t.Errorf("record %s: expected tenant 1234, got %d", rec.Hash, rec.TenantID)
+		}
+		// The raw aggr, invalid UTF-8 included
+		if wantAggr := map[string]string{"0": `{"ok": 2}`, "01abc": "bad \xff\xfe utf8"}[rec.Hash]; string(rec.Aggr) != wantAggr {
+			t.Errorf("record %s: aggr = %q, want %q", rec.Hash, rec.Aggr, wantAggr)
+		}
+	}
+	if sink.Count() != 2 {
+		t.Errorf("expected Count() 2, got %d", sink.Count())
+	}
+}
+
+func TestDeadLetterSink_S3Target(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	uploader := &mockFileUploader{uploads: make(map[string]string)}
+
+	sink, err := NewDeadLetterSink("s3://test-bucket/dead-letter/tenant-1234.jsonl", uploader, logger)
+	if err != nil {
+		t.Fatalf("NewDeadLetterSink() error = %v", err)
+	}
+	if err := sink.Write(Row{TenantID: 1234, Hash: "0"}, 3, SkipReasonShortHash); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	content, ok := uploader.uploads["dead-letter/tenant-1234.jsonl"]
+	if !ok {
+		t.Fatal("dead-letter file was not uploaded to S3")
+	}
+	records := readDeadLetterRecords(t, content)
+	if len(records) != 1 || records[0].Reason != SkipReasonShortHash || records[0].Segment != 3 {
+		t.Errorf("unexpected dead-letter records: %+v", records)
+	}
+}
+
+func TestParseDeadLetterTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantBucket string
+		wantKey    string
+		wantS3     bool
+		wantErr    bool
+	}{
+		{"local path", "/tmp/dead-letter.jsonl", "", "", false, false},
+		{"s3 target", "s3://bucket/path/dl.jsonl", "bucket", "path/dl.jsonl", true, false},
+		{"s3 missing key", "s3://bucket/", "", "", true, true},
+		{"s3 missing bucket", "s3:///key", "", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, key, isS3, err := ParseDeadLetterTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDeadLetterTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if isS3 != tt.wantS3 {
+				t.Errorf("ParseDeadLetterTarget() isS3 = %v, want %v", isS3, tt.wantS3)
+			}
+			if !tt.wantErr && (bucket != tt.wantBucket || key != tt.wantKey) {
+				t.Errorf("ParseDeadLetterTarget() = %s, %s, want %s, %s", bucket, key, tt.wantBucket, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// Kinds of row difference found by DiffSegments.
+const (
+	DiffMissingInTarget = "missing-in-target" // In the source only
+	DiffExtraInTarget   = "extra-in-target"   // In the target only
+	DiffContent         = "content"           // In both, with different content checksums
+)
+
+// maxReportedDiffs caps the differences kept in a DiffReport, so a badly broken load doesn't
+// hold every row in memory. Every difference is still counted.
+const maxReportedDiffs = 1000
+
+// RowDiff is one hash that differs between source and target.
+type RowDiff struct {
+	Segment segment.Segment
+	Hash    string
+	Kind    string // DiffMissingInTarget, DiffExtraInTarget or DiffContent
+}
+
+// DiffReport lists the rows that differ between source and target (-verify-diff).
+type DiffReport struct {
+	Segments    int
+	SourceRows  int64
+	TargetRows  int64
+	Counts      map[string]int64 // Differences per kind
+	Diffs       []RowDiff        // The first maxReportedDiffs differences
+	DiffsCapped bool             // More differences were found than are listed in Diffs
+}
+
+// Total returns the number of differences found.
+func (r *DiffReport) Total() int64 {
+	var total int64
+	for _, n := range r.Counts {
+		total += n
+	}
+	return total
+}
+
+func (r *DiffReport) add(seg segment.Segment, hash, kind string) {
+	r.Counts[kind]++
+	if len(r.Diffs) < maxReportedDiffs {
+		r.Diffs = append(r.Diffs, RowDiff{Segment: seg, Hash: hash, Kind: kind})
+	} else {
+		r.DiffsCapped = true
+	}
+}
+
+// RowChecksumCursor iterates a segment's rows in hash order, with each row's content checksum.
+type RowChecksumCursor interface {
+	Next() bool
+	Row() (hash string, checksum uint64)
+	Err() error
+	Close() error
+}
+
+// SegmentRowLister opens a cursor over the row checksums of a segment.
+// This allows mocking in tests.
+type SegmentRowLister interface {
+	SegmentRows(ctx context.Context, seg segment.Segment) (RowChecksumCursor, error)
+}
+
+// SegmentRows lists the rows the exporter writes for seg (honouring -exclude-where and -where) with their checksums.
+func (e *Exporter) SegmentRows(ctx context.Context, seg segment.Segment) (RowChecksumCursor, error) {
+	condition, args := e.withExclusion(segmentBoundsCondition(seg))
+	return querySegmentRows(ctx, e.db, tableRef(e.config), e.config.TenantColumnName(), e.config.TenantID, seg, condition, args)
+}
+
+// SegmentRows lists the tenant's rows in seg with their checksums.
+func (c *TableChecksummer) SegmentRows(ctx context.Context, seg segment.Segment) (RowChecksumCursor, error) {
+	condition, args := segmentBoundsCondition(seg)
+	return querySegmentRows(ctx, c.db, c.table, c.tenantColumn, c.tenantID, seg, condition, args)
+}
+
+// querySegmentRows queries the hash and row checksum (the same as the segment checksum XORs) of the tenant's rows matching condition.
+func querySegmentRows(ctx context.Context, db *sql.DB, table, tenantColumn string, tenantID int, seg segment.Segment, condition string, args []interface{}) (RowChecksumCursor, error) {
+	query := `
+		SELECT hash, ` + rowChecksumExpr + `
+		FROM ` + table + `
+		WHERE ` + tenantColumn + ` = ?
+		  AND ` + condition + `
+		ORDER BY hash`
+
+	rows, err := db.QueryContext(ctx, query, append([]interface{}{tenantID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rows of segment %d of %s: %w", seg.Index, table, err)
+	}
+	return &sqlRowChecksumCursor{rows: rows}, nil
+}
+
+// sqlRowChecksumCursor reads (hash, checksum) rows from a query.
+type sqlRowChecksumCursor struct {
+	rows     *sql.Rows
+	hash     string
+	checksum uint64
+	err      error
+}
+
+func (c *sqlRowChecksumCursor) Next() bool {
+	if c.err != nil || !c.rows.Next() {
+		return false
+	}
+	if c.err = c.rows.Scan(&c.hash, &c.checksum); c.err != nil {
+		return false
+	}
+	return true
+}
+
+func (c *sqlRowChecksumCursor) Row() (string, uint64) { return c.hash, c.checksum }
+
+func (c *sqlRowChecksumCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
+
+func (c *sqlRowChecksumCursor) Close() error { return c.rows.Close() }
+
+// DiffSegments compares source and target row by row: each segment is read from both in hash order
+// and merged, so only the current row of each side is held in memory.
+func DiffSegments(segments []segment.Segment, source, target SegmentRowLister) (*DiffReport, error) {
+	report := &DiffReport{Counts: map[string]int64{}}
+	for _, seg := range segments {
+		if err := diffSegment(seg, source, target, report); err != nil {
+			return nil, err
+		}
+		report.Segments++
+	}
+	return report, nil
+}
+
+// diffSegment merges the source and target rows of seg into report.
+func diffSegment(seg segment.Segment, source, target SegmentRowLister, report *DiffReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	src, err := source.SegmentRows(ctx, seg)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer src.Close()
+	dst, err := target.SegmentRows(ctx, seg)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	defer dst.Close()
+
+	srcOK, dstOK := src.Next(), dst.Next()
+	for srcOK || dstOK {
+		srcHash, srcSum := src.Row()
+		dstHash, dstSum := dst.Row()
+		switch {
+		case !dstOK || (srcOK && srcHash < dstHash):
+			report.add(seg, srcHash, DiffMissingInTarget)
+			report.SourceRows++
+			srcOK = src.Next()
+		case !srcOK || dstHash < srcHash:
+			report.add(seg, dstHash, DiffExtraInTarget)
+			report.TargetRows++
+			dstOK = dst.Next()
+		default:
+			if srcSum != dstSum {
+				report.add(seg, srcHash, DiffContent)
+			}
+			report.SourceRows++
+			report.TargetRows++
+			srcOK, dstOK = src.Next(), dst.Next()
+		}
+	}
+	if err := src.Err(); err != nil {
+		return fmt.Errorf("source: failed to read rows of segment %d: %w", seg.Index, err)
+	}
+	if err := dst.Err(); err != nil {
+		return fmt.Errorf("target: failed to read rows of segment %d: %w", seg.Index, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeRowLister lists fixed (hash, checksum) rows per segment index
+type fakeRowLister struct {
+	rows map[int][]fakeRow
+	err  error
+}
+
+type fakeRow struct {
+	hash     string
+	checksum uint64
+}
+
+type fakeRowCursor struct {
+	rows []fakeRow
+	pos  int
+}
+
+func (c *fakeRowCursor) Next() bool {
+	c.pos++
+	return c.pos <= len(c.rows)
+}
+
+func (c *fakeRowCursor) Row() (string, uint64) {
+	if c.pos < 1 || c.pos > len(c.rows) {
+		return "", 0
+	}
+	return c.rows[c.pos-1].hash, c.rows[c.pos-1].checksum
+}
+
+func (c *fakeRowCursor) Err() error   { return nil }
+func (c *fakeRowCursor) Close() error { return nil }
+
+func (f *fakeRowLister) SegmentRows(ctx context.Context, seg segment.Segment) (RowChecksumCursor, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &fakeRowCursor{rows: f.rows[seg.Index]}, nil
+}
+
+func TestDiffSegments(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(2)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	source := &fakeRowLister{rows: map[int][]fakeRow{
+		0: {{"01", 1}, {"02", 2}, {"03", 3}, {"05", 5}},
+		1: {{"80", 8}, {"90", 9}},
+	}}
+	target := &fakeRowLister{rows: map[int][]fakeRow{
+		0: {{"01", 1}, {"03", 30}, {"04", 4}, {"05", 5}}, // 02 missing, 03 changed, 04 extra
+		1: {{"80", 8}},                                   // 90 missing at the end
+	}}
+
+	report, err := DiffSegments(segments, source, target)
+	if err != nil {
+		t.Fatalf("DiffSegments() error = %v", err)
+	}
+	want := []RowDiff{
+		{Segment: segments[0], Hash: "02", Kind: DiffMissingInTarget},
+		{Segment: segments[0], Hash: "03", Kind: DiffContent},
+		{Segment: segments[0], Hash: "04", Kind: DiffExtraInTarget},
+		{Segment: segments[1], Hash: "90", Kind: DiffMissingInTarget},
+	}
+	if fmt.Sprint(report.Diffs) != fmt.Sprint(want) {
+		t.Errorf("Diffs = %v, want %v", report.Diffs, want)
+	}
+	if report.Total() != 4 || report.Counts[DiffMissingInTarget] != 2 {
+		t.Errorf("Counts = %v, want 4 differences, 2 of them missing in target", report.Counts)
+	}
+	if report.Segments != 2 || report.SourceRows != 6 || report.TargetRows != 5 {
+		t.Errorf("report covers %d segments, %d source and %d target rows, want 2, 6 and 5",
+			report.Segments, report.SourceRows, report.TargetRows)
+	}
+
+	if _, err := DiffSegments(segments, source, &fakeRowLister{err: errors.New("connection lost")}); err == nil {
+		t.Error("DiffSegments() should fail when the target can't be read")
+	}
+}
+
+func TestDiffSegments_CappedDiffs(t *testing.T) {
+	var rows []fakeRow
+	for i := 0; i < maxReportedDiffs+10; i++ {
+		rows = append(rows, fakeRow{fmt.Sprintf("%06x", i), 1})
+	}
+	seg := []segment.Segment{{Index: 0, StartHex: "00", EndHex: "ff"}}
+	report, err := DiffSegments(seg, &fakeRowLister{rows: map[int][]fakeRow{0: rows}}, &fakeRowLister{})
+	if err != nil {
+		t.Fatalf("DiffSegments() error = %v", err)
+	}
+	if len(report.Diffs) != maxReportedDiffs || !report.DiffsCapped || report.Total() != int64(len(rows)) {
+		t.Errorf("listed %d of %d differences (capped %v), want %d listed and all counted",
+			len(report.Diffs), report.Total(), report.DiffsCapped, maxReportedDiffs)
+	}
+}
+
+func TestDiffSegments_SourceAndTargetDBs(t *testing.T) {
+	sourceDB, cleanupSource, _ := setupTestDB(t)
+	defer cleanupSource()
+	targetDB, cleanupTarget, _ := setupTestDB(t)
+	defer cleanupTarget()
+
+	tenantID := 515151
+	setupTestTable(t, sourceDB, tenantID)
+	setupTestTable(t, targetDB, tenantID)
+
+	// Drop one row of segment 1 (40-7f) and change one of segment 3 (c0-ff) in the target
+	if _, err := targetDB.Exec(`DELETE FROM fis_aggr WHERE tenantid = ? AND hash = '7fabc123def456'`, tenantID); err != nil {
+		t.Fatalf("Failed to delete target row: %v", err)
+	}
+	if _, err := targetDB.Exec(`UPDATE fis_aggr SET aggr = '{"test": "changed"}' WHERE tenantid = ? AND hash = 'c0abc123def456'`, tenantID); err != nil {
+		t.Fatalf("Failed to change target row: %v", err)
+	}
+
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis"}
+	source := &Exporter{db: sourceDB, config: cfg, logger: zaptest.NewLogger(t)}
+	target := NewTableChecksummer(targetDB, "fis_aggr", "tenantid", tenantID)
+
+	report, err := DiffSegments(segments, source, target)
+	if err != nil {
+		t.Fatalf("DiffSegments() error = %v", err)
+	}
+	want := []RowDiff{
+		{Segment: segments[1], Hash: "7fabc123def456", Kind: DiffMissingInTarget},
+		{Segment: segments[3], Hash: "c0abc123def456", Kind: DiffContent},
+	}
+	if fmt.Sprint(report.Diffs) != fmt.Sprint(want) {
+		t.Errorf("Diffs = %v, want %v", report.Diffs, want)
+	}
+	if report.SourceRows != 9 || report.TargetRows != 8 {
+		t.Errorf("compared %d source and %d target rows, want 9 and 8", report.SourceRows, report.TargetRows)
+	}
+}
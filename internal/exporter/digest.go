@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// RowDigest is the SHA-256 over a segment's ordered (hash, aggr) pairs, recorded in the manifest (-manifest).
+// It covers the values, not the CSV bytes, so it doesn't change with -csv-delimiter, -csv-header or -compress.
+// Each value is length-prefixed, so moving bytes between hash and aggr changes the digest.
+type RowDigest struct {
+	sha  hash.Hash
+	rows int64
+}
+
+// NewRowDigest creates an empty digest.
+func NewRowDigest() *RowDigest {
+	return &RowDigest{sha: sha256.New()}
+}
+
+// Add adds the next row. Rows must be added in hash order.
+func (d *RowDigest) Add(rowHash, aggr string) {
+	d.addValue(rowHash)
+	d.addValue(aggr)
+	d.rows++
+}
+
+func (d *RowDigest) addValue(value string) {
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(len(value)))
+	d.sha.Write(size[:])
+	d.sha.Write([]byte(value))
+}
+
+// Rows returns the number of rows added.
+func (d *RowDigest) Rows() int64 {
+	return d.rows
+}
+
+// Sum returns the hex-encoded SHA-256 of the rows added so far.
+func (d *RowDigest) Sum() string {
+	return hex.EncodeToString(d.sha.Sum(nil))
+}
+
+// MarshalBinary returns the state of the digest, to continue it in a later run (-checkpoint-interval).
+func (d *RowDigest) MarshalBinary() ([]byte, error) {
+	state, err := d.sha.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(state, uint64(d.rows)), nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (d *RowDigest) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("invalid row digest state of %d bytes", len(data))
+	}
+	sha := sha256.New()
+	if err := sha.(encoding.BinaryUnmarshaler).UnmarshalBinary(data[:len(data)-8]); err != nil {
+		return fmt.Errorf("invalid row digest state: %w", err)
+	}
+	d.sha = sha
+	d.rows = int64(binary.BigEndian.Uint64(data[len(data)-8:]))
+	return nil
+}
+
+// SegmentDigester computes the row digest of a segment.
+// This allows mocking in tests.
+type SegmentDigester interface {
+	SegmentDigest(seg segment.Segment) (*RowDigest, error)
+}
+
+// SegmentDigest computes the row digest of the tenant's rows in seg (-verify-manifest on the Aurora target).
+func (c *TableChecksummer) SegmentDigest(seg segment.Segment) (*RowDigest, error) {
+	condition, args := segmentBoundsCondition(seg)
+	query := `
+		SELECT hash, aggr
+		FROM ` + c.table + `
+		WHERE ` + c.tenantColumn + ` = ?
+		  AND ` + condition + `
+		ORDER BY hash`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, query, append([]interface{}{c.tenantID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment %d of %s: %w", seg.Index, c.table, err)
+	}
+	defer rows.Close()
+
+	digest := NewRowDigest()
+	for rows.Next() {
+		var rowHash, aggr string
+		if err := rows.Scan(&rowHash, &aggr); err != nil {
+			return nil, fmt.Errorf("failed to read segment %d of %s: %w", seg.Index, c.table, err)
+		}
+		digest.Add(rowHash, aggr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read segment %d of %s: %w", seg.Index, c.table, err)
+	}
+	return digest, nil
+}
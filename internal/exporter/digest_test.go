@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRowDigest(t *testing.T) {
+	empty := NewRowDigest()
+	if got, want := empty.Sum(), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"; got != want {
+		t.Errorf("empty digest = %s, want the SHA-256 of nothing %s", got, want)
+	}
+
+	digest := func(pairs ...string) string {
+		d := NewRowDigest()
+		for i := 0; i < len(pairs); i += 2 {
+			d.Add(pairs[i], pairs[i+1])
+		}
+		return d.Sum()
+	}
+	if digest("aa", "x", "bb", "y") != digest("aa", "x", "bb", "y") {
+		t.Error("digest of the same rows differs")
+	}
+	if digest("aa", "x", "bb", "y") == digest("bb", "y", "aa", "x") {
+		t.Error("digest should depend on the row order")
+	}
+	if digest("ab", "c") == digest("a", "bc") {
+		t.Error("digest should tell apart bytes moved between hash and aggr")
+	}
+	if digest("aa", "x") == digest("aa", "y") {
+		t.Error("digest should depend on aggr")
+	}
+
+	// A digest continued from its state sums as if it never stopped
+	first := NewRowDigest()
+	first.Add("aa", "x")
+	state, err := first.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	continued := NewRowDigest()
+	if err := continued.UnmarshalBinary(state); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	continued.Add("bb", "y")
+	if continued.Sum() != digest("aa", "x", "bb", "y") || continued.Rows() != 2 {
+		t.Errorf("continued digest = %s over %d rows, want %s over 2", continued.Sum(), continued.Rows(), digest("aa", "x", "bb", "y"))
+	}
+	if err := continued.UnmarshalBinary([]byte("short")); err == nil {
+		t.Error("UnmarshalBinary() should reject a truncated state")
+	}
+}
+
+func TestStreamSegment_ChecksumStableAcrossCSVFormat(t *testing.T) {
+	rows := []Row{
+		{TenantID: 1, Hash: "00aa", Aggr: `{"app":"box","users":["a","b"]}`},
+		{TenantID: 1, Hash: "00bb", Aggr: "tab\tand,comma"},
+		{TenantID: 1, Hash: "00cc", Aggr: `quote "inside"`},
+	}
+	want := NewRowDigest()
+	for _, row := range rows {
+		want.Add(row.Hash, row.Aggr)
+	}
+
+	var csvs [][]byte
+	for _, delimiter := range []string{",", "\t", "|"} {
+		cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 2, CSVHeader: true, NullMarker: `\N`, CSVDelimiter: delimiter}
+		exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+
+		query, _ := newFakeQuerier(rows, cfg.BatchSize)
+		mock := &mockMultipartUploadStream{}
+		exported, checksum, err := exp.streamSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, "test-key", mock, query)
+		if err != nil {
+			t.Fatalf("streamSegment() with delimiter %q error = %v", delimiter, err)
+		}
+		if exported != len(rows) {
+			t.Errorf("exported %d rows with delimiter %q, want %d", exported, delimiter, len(rows))
+		}
+		if checksum != want.Sum() {
+			t.Errorf("checksum with delimiter %q = %s, want %s", delimiter, checksum, want.Sum())
+		}
+		csvs = append(csvs, bytes.Join(mock.parts, nil))
+	}
+	if bytes.Equal(csvs[0], csvs[1]) || bytes.Equal(csvs[1], csvs[2]) {
+		t.Error("CSV output should differ between delimiters")
+	}
+}
+
+func TestStreamSegment_ChecksumOfMaskedAggr(t *testing.T) {
+	rows := []Row{{TenantID: 1, Hash: "00aa", Aggr: `{"user":"a@example.com"}`}}
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 10, NullMarker: `\N`}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t), maskAggr: func(string) string { return "{}" }}
+
+	query, _ := newFakeQuerier(rows, cfg.BatchSize)
+	_, checksum, err := exp.streamSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, "test-key", &mockMultipartUploadStream{}, query)
+	if err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+
+	// The target holds the masked aggr, so that is what the checksum covers
+	want := NewRowDigest()
+	want.Add("00aa", "{}")
+	if checksum != want.Sum() {
+		t.Errorf("checksum = %s, want the digest of the masked aggr %s", checksum, want.Sum())
+	}
+}
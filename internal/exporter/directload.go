@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// RowLoader inserts exported rows into the target table (-direct-load).
+// This allows mocking in tests.
+type RowLoader interface {
+	LoadRows(rows []Row) error
+}
+
+// SetRowLoader makes ExportSegment insert each batch's rows with loader instead of uploading them as CSV parts.
+func (e *Exporter) SetRowLoader(loader RowLoader) {
+	e.rowLoader = loader
+}
+
+// loadSegmentOnce exports a segment in one transaction into the row loader (see ExportSegment).
+// The returned CSVFile has no S3 key or file path: it records the segment's row count and digest.
+func (e *Exporter) loadSegmentOnce(seg segment.Segment) (*CSVFile, error) {
+	var timing SegmentTiming
+	totalRows, checksum, sourceChanged, err := e.exportSegmentRows(seg, "", nil, false, &timing)
+	if err != nil {
+		return nil, err
+	}
+	if totalRows == 0 {
+		return nil, nil
+	}
+	return &CSVFile{
+		Segment:       seg,
+		RowCount:      totalRows,
+		SourceChanged: sourceChanged,
+		Checksum:      checksum,
+		Timing:        timing,
+	}, nil
+}
+
+// loadRows inserts a batch with the row loader, with aggr as it would be written to the CSV (-mask-aggr).
+func (e *Exporter) loadRows(rows []Row) error {
+	if e.maskAggr != nil {
+		masked := make([]Row, len(rows))
+		for i, row := range rows {
+			masked[i] = row
+			masked[i].Aggr = e.exportedAggr(row)
+		}
+		rows = masked
+	}
+	if err := e.rowLoader.LoadRows(rows); err != nil {
+		return fmt.Errorf("failed to load batch into the target: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// recordingLoader keeps every batch it is given, failing the batches for which fail returns an error
+type recordingLoader struct {
+	batches [][]Row
+	fail    func(batch int) error // Optional - called with the 1-based batch number
+}
+
+func (l *recordingLoader) LoadRows(rows []Row) error {
+	l.batches = append(l.batches, rows)
+	if l.fail != nil {
+		return l.fail(len(l.batches))
+	}
+	return nil
+}
+
+func TestExportSegment_RowLoader(t *testing.T) {
+	hashes := []string{"00abc123", "01abc123", "01abc123", "02abc123", "03abc123"}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	loadErr := errors.New("target unreachable")
+
+	tests := []struct {
+		name        string
+		mask        AggrMasker
+		fail        func(batch int) error
+		wantBatches int
+		wantAggr    string
+		wantErr     bool
+	}{
+		{"batches loaded in order", nil, nil, 2, `{"test": "data"}`, false},
+		{"masked aggr", maskPlaceholder, nil, 2, AggrMaskPlaceholder, false},
+		{"load failure fails the segment", nil, func(batch int) error {
+			if batch == 1 {
+				return loadErr
+			}
+			return nil
+		}, 1, `{"test": "data"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(&faultConnector{hashes: hashes})
+			defer db.Close()
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 2}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t), maskAggr: tt.mask}
+			loader := &recordingLoader{fail: tt.fail}
+			exp.SetRowLoader(loader)
+
+			// No uploader: the rows only go to the loader
+			csvFile, err := exp.ExportSegment(seg, nil)
+			if tt.wantErr {
+				if !errors.Is(err, loadErr) {
+					t.Errorf("ExportSegment() error = %v, want the load failure", err)
+				}
+			} else if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			} else if csvFile.RowCount != len(hashes) || csvFile.S3Key != "" || csvFile.FilePath != "" || csvFile.Checksum == "" {
+				t.Errorf("ExportSegment() = %+v, want %d rows with a digest and no file", csvFile, len(hashes))
+			}
+
+			var loaded []string
+			for _, batch := range loader.batches {
+				for _, row := range batch {
+					loaded = append(loaded, row.Hash)
+					if row.Aggr != tt.wantAggr {
+						t.Errorf("row %s loaded with aggr %q, want %q", row.Hash, row.Aggr, tt.wantAggr)
+					}
+				}
+			}
+			if len(loader.batches) != tt.wantBatches {
+				t.Errorf("loader got %d batches, want %d", len(loader.batches), tt.wantBatches)
+			}
+			if !tt.wantErr && fmt.Sprint(loaded) != fmt.Sprint(hashes) {
+				t.Errorf("loaded %v, want %v", loaded, hashes)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// MaxDuplicateHashes is how many duplicate hashes SegmentDuplicates returns per segment.
+const MaxDuplicateHashes = 100
+
+// DuplicateHash is a hash held by more than one of the tenant's rows.
+type DuplicateHash struct {
+	Hash string
+	Rows int64
+}
+
+// SegmentDuplicates returns the hashes of the segment held by more than one of the tenant's rows, in hash order,
+// excluding -exclude-where rows. All of them are exported, but the target's unique (tenantid, hash) key keeps one per hash.
+// At most MaxDuplicateHashes are returned; truncated reports whether the segment holds more.
+// The unique (tenantid, hash) index makes this a no-op; without it the query scans the segment.
+func (e *Exporter) SegmentDuplicates(seg segment.Segment) (duplicates []DuplicateHash, truncated bool, err error) {
+	hashCondition, boundArgs := e.withExclusion(segmentBoundsCondition(seg))
+	args := append([]interface{}{e.config.TenantID}, boundArgs...)
+	args = append(args, MaxDuplicateHashes+1)
+
+	query := fmt.Sprintf(`
+		SELECT hash, COUNT(*)
+		FROM %s
+		WHERE %s = ?
+		  AND %s
+		GROUP BY hash
+		HAVING COUNT(*) > 1
+		ORDER BY hash
+		LIMIT ?`,
+		tableRef(e.config), e.config.TenantColumnName(), hashCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.segmentTimeout())
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to find duplicate hashes of segment %d: %w", seg.Index, err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var duplicate DuplicateHash
+		if err := rows.Scan(&duplicate.Hash, &duplicate.Rows); err != nil {
+			return nil, false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to scan duplicate hash of segment %d: %w", seg.Index, err))
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to find duplicate hashes of segment %d: %w", seg.Index, err))
+	}
+	if len(duplicates) > MaxDuplicateHashes {
+		return duplicates[:MaxDuplicateHashes], true, nil
+	}
+	return duplicates, false, nil
+}
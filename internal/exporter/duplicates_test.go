@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSegmentDuplicates(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	// The same table without its unique (tenantid, hash) constraint
+	tenantID := 599599
+	if _, err := db.Exec(`CREATE TABLE fis_aggr_nounique (
+		tenantid INT NOT NULL,
+		hash VARCHAR(255) NOT NULL,
+		aggr LONGTEXT NOT NULL,
+		last_modified TIMESTAMP NULL,
+		version INT NULL
+	)`); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	for _, row := range []struct {
+		tenantID int
+		hash     string
+		copies   int
+	}{
+		{tenantID, "00abc123", 1},
+		{tenantID, "01abc123", 3}, // Duplicate
+		{tenantID, "02abc123", 1},
+		{tenantID, "3fabc123", 2}, // Duplicate
+		{tenantID, "40abc123", 2}, // Duplicate in the next segment
+		{tenantID + 1, "02abc123", 2},
+	} {
+		for i := 0; i < row.copies; i++ {
+			if _, err := db.Exec(`INSERT INTO fis_aggr_nounique (tenantid, hash, aggr) VALUES (?, ?, ?)`,
+				row.tenantID, row.hash, fmt.Sprintf(`{"copy": %d}`, i)); err != nil {
+				t.Fatalf("Failed to insert test data: %v", err)
+			}
+		}
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr_nounique", MariaDBDatabase: "fis", BatchSize: 1000}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+
+	tests := []struct {
+		name string
+		seg  segment.Segment
+		want []DuplicateHash
+	}{
+		{"first segment", segment.Segment{Index: 0, StartHex: "00", EndHex: "40"},
+			[]DuplicateHash{{"01abc123", 3}, {"3fabc123", 2}}},
+		{"last segment", segment.Segment{Index: 1, StartHex: "40", EndHex: "100"},
+			[]DuplicateHash{{"40abc123", 2}}},
+		{"no duplicates", segment.Segment{Index: 2, StartHex: "00", EndHex: "01"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := exp.SegmentDuplicates(tt.seg)
+			if err != nil {
+				t.Fatalf("SegmentDuplicates() error = %v", err)
+			}
+			if truncated || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("SegmentDuplicates() = %v, truncated %v, want %v", got, truncated, tt.want)
+			}
+		})
+	}
+
+	// The export keeps every row of a duplicate hash, for the target's unique key to settle
+	uploader := newMockS3Uploader()
+	csvFile, err := exp.ExportSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}, uploader)
+	if err != nil {
+		t.Fatalf("ExportSegment() error = %v", err)
+	}
+	if csvFile.RowCount != 7 {
+		t.Errorf("ExportSegment() exported %d of the segment's 7 rows", csvFile.RowCount)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sqlIdentifier matches a plain, unquoted column name.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExcludeFilter is the row exclusion parsed from -exclude-where, used to skip soft-deleted rows.
+// Condition selects the rows to keep and is appended to the segment query with AND.
+type ExcludeFilter struct {
+	Condition string // e.g. "`deleted_at` IS NULL"; empty when nothing is excluded
+	Args      []interface{}
+}
+
+// ParseExcludeWhere parses -exclude-where into a parameterized predicate.
+// The value is a comma-separated list of terms, and a row matching any term is excluded:
+//   - column: exclude rows where column IS NOT NULL (e.g. deleted_at)
+//   - column=value: exclude rows where column equals value (e.g. is_deleted=1)
+//
+// Column names must be plain identifiers and values are always bound as query args,
+// so the value cannot inject SQL into the export query.
+func ParseExcludeWhere(spec string) (ExcludeFilter, error) {
+	var filter ExcludeFilter
+	if strings.TrimSpace(spec) == "" {
+		return filter, nil
+	}
+
+	var conditions []string
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		column, value, hasValue := strings.Cut(term, "=")
+		column = strings.TrimSpace(column)
+		if !sqlIdentifier.MatchString(column) {
+			return ExcludeFilter{}, fmt.Errorf("invalid exclude-where column %q in %q (expected column or column=value)", column, spec)
+		}
+
+		if !hasValue {
+			conditions = append(conditions, fmt.Sprintf("`%s` IS NULL", column))
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return ExcludeFilter{}, fmt.Errorf("missing exclude-where value for column %s in %q", column, spec)
+		}
+		// Null-safe comparison so rows with a NULL marker are kept
+		conditions = append(conditions, fmt.Sprintf("NOT (`%s` <=> ?)", column))
+		filter.Args = append(filter.Args, value)
+	}
+
+	filter.Condition = strings.Join(conditions, " AND ")
+	return filter, nil
+}
+
+// withExclusion appends the -exclude-where, -where and -since-checkpoint predicates (if any) to a WHERE condition and its args.
+func (e *Exporter) withExclusion(condition string, args []interface{}) (string, []interface{}) {
+	for _, filter := range []ExcludeFilter{e.exclude, e.where, e.since} {
+		if filter.Condition != "" {
+			condition += " AND " + filter.Condition
+			args = append(args, filter.Args...)
+		}
+	}
+	return condition, args
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestParseExcludeWhere(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		wantCondition string
+		wantArgs      []interface{}
+		wantErr       bool
+	}{
+		{"empty", "", "", nil, false},
+		{"nullable marker", "deleted_at", "`deleted_at` IS NULL", nil, false},
+		{"flag value", "is_deleted=1", "NOT (`is_deleted` <=> ?)", []interface{}{"1"}, false},
+		{"several terms", " deleted_at , status = purged ", "`deleted_at` IS NULL AND NOT (`status` <=> ?)", []interface{}{"purged"}, false},
+		{"injection in value is bound", "status=x' OR '1'='1", "NOT (`status` <=> ?)", []interface{}{"x' OR '1'='1"}, false},
+		{"expression as column", "deleted_at IS NOT NULL", "", nil, true},
+		{"injection in column", "deleted_at`; DROP TABLE fis_aggr; --", "", nil, true},
+		{"missing value", "is_deleted=", "", nil, true},
+		{"empty term", "deleted_at,", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseExcludeWhere(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExcludeWhere(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if filter.Condition != tt.wantCondition {
+				t.Errorf("Condition = %q, want %q", filter.Condition, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(filter.Args, tt.wantArgs) {
+				t.Errorf("Args = %v, want %v", filter.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestExportSegment_ExcludeWhere(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.Exec(`
+		CREATE TABLE fis_aggr_softdelete (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			deleted_at TIMESTAMP NULL,
+			is_deleted TINYINT NULL,
+			UNIQUE KEY uk_tenant_hash (tenantid, hash)
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create soft-delete table: %v", err)
+	}
+
+	// Live rows, rows with a deleted_at tombstone and rows flagged is_deleted=1
+	const tenantID = 777777
+	seed := []struct {
+		hash      string
+		deletedAt bool
+		isDeleted interface{}
+	}{
+		{"00aa", false, nil},
+		{"00bb", true, nil},
+		{"00cc", false, 0},
+		{"00dd", false, 1},
+		{"00ee", true, 1},
+		{"00ff", false, nil},
+	}
+	for _, row := range seed {
+		deletedAt := "NULL"
+		if row.deletedAt {
+			deletedAt = "NOW()"
+		}
+		_, err := db.Exec(fmt.Sprintf(`INSERT INTO fis_aggr_softdelete (tenantid, hash, aggr, deleted_at, is_deleted)
+			VALUES (?, ?, '{}', %s, ?)`, deletedAt), tenantID, row.hash, row.isDeleted)
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		exclude    string
+		wantHashes []string
+	}{
+		{"no exclusion", "", []string{"00aa", "00bb", "00cc", "00dd", "00ee", "00ff"}},
+		{"deleted_at tombstones", "deleted_at", []string{"00aa", "00cc", "00dd", "00ff"}},
+		{"is_deleted flag", "is_deleted=1", []string{"00aa", "00bb", "00cc", "00ff"}},
+		{"both markers", "deleted_at,is_deleted=1", []string{"00aa", "00cc", "00ff"}},
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				TenantID:        tenantID,
+				TableName:       "fis_aggr_softdelete",
+				MariaDBDatabase: "fis",
+				BatchSize:       2,
+				S3Prefix:        "test-prefix",
+				ExcludeWhere:    tt.exclude,
+				CSVHeader:       true,
+			}
+			exclude, err := ParseExcludeWhere(cfg.ExcludeWhere)
+			if err != nil {
+				t.Fatalf("ParseExcludeWhere() error = %v", err)
+			}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t), exclude: exclude}
+
+			count, err := exp.CountSegmentRows(seg)
+			if err != nil {
+				t.Fatalf("CountSegmentRows() error = %v", err)
+			}
+			if count != int64(len(tt.wantHashes)) {
+				t.Errorf("CountSegmentRows() = %d, want %d", count, len(tt.wantHashes))
+			}
+
+			uploader := newMockS3Uploader()
+			csvFile, err := exp.ExportSegment(seg, uploader)
+			if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			}
+			if csvFile.RowCount != len(tt.wantHashes) {
+				t.Errorf("expected %d rows, got %d", len(tt.wantHashes), csvFile.RowCount)
+			}
+
+			var data strings.Builder
+			for _, part := range uploader.streams[csvFile.S3Key].parts {
+				data.Write(part)
+			}
+			records, err := csv.NewReader(strings.NewReader(data.String())).ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse CSV: %v", err)
+			}
+			var hashes []string
+			for _, record := range records[1:] {
+				hashes = append(hashes, record[1])
+			}
+			if !reflect.DeepEqual(hashes, tt.wantHashes) {
+				t.Errorf("exported hashes = %v, want %v", hashes, tt.wantHashes)
+			}
+		})
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"text/template"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"github.com/netSkope/fis-migration-tool/internal/s3"
 	"github.com/netSkope/fis-migration-tool/internal/segment"
 	"go.uber.org/zap"
@@ -42,6 +45,10 @@ func (a *s3StreamAdapter) Abort() {
 	a.stream.Abort()
 }
 
+func (a *s3StreamAdapter) KeepParts(n int32) error {
+	return a.stream.KeepParts(n)
+}
+
 // MultipartUploadStreamCreator creates a new multipart upload stream.
 type MultipartUploadStreamCreator interface {
 	NewMultipartUploadStream(s3Key string) (MultipartUploadStreamer, error)
@@ -65,35 +72,188 @@ func NewS3UploaderAdapter(uploader *s3.Uploader) MultipartUploadStreamCreator {
 	return &s3UploaderAdapter{uploader: uploader}
 }
 
-// Exporter handles CSV export from MariaDB.
+// Exporter handles CSV export from MariaDB (or MySQL).
 type Exporter struct {
-	db     *sql.DB
-	config *config.Config
-	logger *zap.Logger
+	db            *sql.DB
+	config        *config.Config
+	logger        *zap.Logger
+	sourceVersion SourceVersion      // Source flavor and version, detected at startup
+	deadLetter    *DeadLetterSink    // Optional - receives rows skipped by row policies
+	changeProbe   SourceChangeProbe  // Optional - detects source changes during export
+	txBeginner    TxBeginner         // Optional - starts export transactions (default: db)
+	exclude       ExcludeFilter      // Rows skipped with -exclude-where (e.g. soft-deleted)
+	where         ExcludeFilter      // Rows selected with -where (the subset to migrate)
+	since         ExcludeFilter      // Rows modified since the target's newest (-since-checkpoint)
+	maskAggr      AggrMasker         // Optional - replaces aggr in the CSV (-mask-aggr)
+	omitted       map[string]bool    // Columns left out of the CSV (-exclude-columns)
+	codec         Codec              // Optional - compresses the CSV parts (-compress)
+	keyTemplate   *template.Template // Optional - renders S3 keys from -s3-key-template
+	rowLoader     RowLoader          // Optional - inserts the rows into the target instead of CSV parts (-direct-load)
+	progress      ProgressRecorder   // Optional - records segment progress every -checkpoint-interval batches
+}
+
+// openSourceDB opens the source connection pool (replaced in tests).
+var openSourceDB = func(dsn string) (*sql.DB, error) {
+	return sql.Open("mysql", dsn)
 }
 
 // NewExporter creates a new CSV exporter.
+// It detects whether the source is MariaDB or MySQL and rejects settings the server can't honor.
 func NewExporter(cfg *config.Config, logger *zap.Logger) (*Exporter, error) {
-	dsn := cfg.GetMariaDBDSN()
+	exclude, err := ParseExcludeWhere(cfg.ExcludeWhere)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	where, err := ParseWhere(cfg.Where, cfg.AllowRawWhere)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	maskAggr, err := ParseAggrMasker(cfg.MaskAggr)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	codec, err := ParseCodec(cfg.Compress, cfg.CompressLevel)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	var keyTemplate *template.Template
+	if cfg.S3KeyTemplate != "" {
+		if keyTemplate, err = config.ParseS3KeyTemplate(cfg.S3KeyTemplate); err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, err)
+		}
+	}
 
-	db, err := sql.Open("mysql", dsn)
+	// All SELECTs go to the read replica if one is set
+	dsn := cfg.GetMariaDBExportDSN()
+
+	db, err := openSourceDB(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, errs.Wrap(errs.ErrSourceConnect, fmt.Errorf("failed to open database: %w", err))
+	}
+	configurePool(db, cfg, logger)
+
+	// Test connection, waiting for a database that is still starting up (-db-connect-retries)
+	if err := pingSource(db, cfg, logger); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
+	version, err := detectSourceVersion(ctx, db)
+	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, errs.Wrap(errs.ErrSourceConnect, err)
+	}
+	if err := checkSourceSupport(version, cfg.IsolationLevel); err != nil {
+		db.Close()
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	logger.Info("Detected source database",
+		zap.String("flavor", version.Flavor),
+		zap.String("version", version.Version))
+	if cfg.MariaDBReplicaHost != "" {
+		logger.Info("Exporting from read replica",
+			zap.String("replica_host", cfg.MariaDBReplicaHost),
+			zap.String("primary_host", cfg.MariaDBHost))
 	}
 
-	return &Exporter{
-		db:     db,
-		config: cfg,
-		logger: logger,
-	}, nil
+	exp := &Exporter{
+		db:            db,
+		config:        cfg,
+		logger:        logger,
+		sourceVersion: version,
+		exclude:       exclude,
+		where:         where,
+		since:         sinceFilter(cfg.SinceLastModified),
+		maskAggr:      maskAggr,
+		omitted:       columnSet(cfg.ExcludedColumns()),
+		codec:         codec,
+		keyTemplate:   keyTemplate,
+	}
+	if cfg.DetectSourceChanges {
+		exp.changeProbe = &dbSourceChangeProbe{db: db, config: cfg}
+	}
+	if cfg.MariaDBReplicaHost != "" && cfg.ReplicaMaxLag > 0 {
+		if err := exp.checkReplica(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return exp, nil
+}
+
+// segmentS3Key returns the S3 key for a segment's CSV file, rendered from -s3-key-template if set.
+// With -partition-by-date the prefix (also {{.Prefix}} in templates) ends in dt=<run date>.
+func (e *Exporter) segmentS3Key(seg segment.Segment, filename string) (string, error) {
+	if e.keyTemplate == nil {
+		return fmt.Sprintf("%s/tenant-%d/%s/%s",
+			e.config.CSVKeyPrefix(), e.config.TenantID, e.config.TableName, filename), nil
+	}
+	start, end := seg.Bounds()
+	key, err := config.RenderS3Key(e.keyTemplate, config.S3KeyFields{
+		Prefix:   e.config.CSVKeyPrefix(),
+		TenantID: e.config.TenantID,
+		Table:    e.config.TableName,
+		StartHex: start,
+		EndHex:   end,
+		Filename: filename,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render S3 key for segment %d: %w", seg.Index, err)
+	}
+	return key, nil
+}
+
+// configurePool applies the -db-max-open-conns, -db-max-idle-conns and -db-conn-max-lifetime
+// limits to the exporter's connection pool, so parallel segments can't exhaust MariaDB's connection limit.
+// Each segment holds one connection for its export transaction.
+func configurePool(db *sql.DB, cfg *config.Config, logger *zap.Logger) {
+	if cfg.DBMaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+		if cfg.DBMaxOpenConns < cfg.MaxParallelSegs {
+			logger.Warn("db-max-open-conns is below max-parallel-segments, segments will wait for connections",
+				zap.Int("db_max_open_conns", cfg.DBMaxOpenConns),
+				zap.Int("max_parallel_segments", cfg.MaxParallelSegs))
+		}
+	}
+	if cfg.DBMaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	}
+	if cfg.DBConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetime) * time.Second)
+	}
+}
+
+// pingSource pings the source database, pinging again up to -db-connect-retries times, -db-connect-backoff apart.
+// Each ping has a 5-second timeout. A failure is an errs.ErrSourceConnect, and isn't retried if it isn't
+// retryable (errs.IsRetryable), e.g. the server rejected the user or password.
+func pingSource(db *sql.DB, cfg *config.Config, logger *zap.Logger) error {
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := errs.WrapConnect(errs.ErrSourceConnect, db.PingContext(ctx))
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt > cfg.DBConnectRetries || !errs.IsRetryable(err) {
+			if attempt > 1 {
+				return fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+			}
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+		logger.Warn("Source database ping failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Int("db_connect_retries", cfg.DBConnectRetries),
+			zap.Duration("backoff", cfg.DBConnectBackoff),
+			zap.Error(err))
+		time.Sleep(cfg.DBConnectBackoff)
+	}
+}
+
+// SetDeadLetterSink sets the sink that receives rows skipped by row-level policies.
+func (e *Exporter) SetDeadLetterSink(sink *DeadLetterSink) {
+	e.deadLetter = sink
 }
 
 // Close closes the database connection.
@@ -106,20 +266,46 @@ func (e *Exporter) Close() error {
 
 // ExportSegment exports data for a single segment using streaming multipart upload to S3.
 // Returns a single CSVFile for the hash range.
-// Uses a transaction with REPEATABLE READ isolation to get a consistent snapshot,
+// With -output-dir the CSV is also written to <dir>/<filename>; a nil uploader writes it locally only.
+// Uses a transaction at -isolation-level (default REPEATABLE READ) to get a consistent snapshot,
 // preventing new inserts from fis-updater from causing infinite pagination loops.
 // Each 100k-row batch is converted to CSV bytes and uploaded as a separate multipart part.
+// With -compress each part is compressed and the filename gets the codec's extension (e.g. .csv.gz).
+// With -format parquet the segment is buffered and uploaded as one .parquet file instead.
+// On failure the upload is aborted, or kept for the next run to resume with -resume-uploads.
+// A deadlock or lock wait timeout exports the segment again, up to -lock-retries times (see exportSegmentWithLockRetries).
+// With a row loader (-direct-load, see SetRowLoader) the batches are inserted into the target instead and uploader is unused.
 func (e *Exporter) ExportSegment(seg segment.Segment, uploader MultipartUploadStreamCreator) (*CSVFile, error) {
-	// Generate S3 key (one file per hash range)
-	filename := fmt.Sprintf("tenant-%d.%s.hash-%s-%s.csv",
-		e.config.TenantID, e.config.TableName, seg.StartHex, seg.EndHex)
-	s3Key := fmt.Sprintf("%s/tenant-%d/%s/%s",
-		e.config.S3Prefix, e.config.TenantID, e.config.TableName, filename)
-
-	// Initiate multipart upload stream
-	stream, err := uploader.NewMultipartUploadStream(s3Key)
+	return e.exportSegmentWithLockRetries(seg, func() (*CSVFile, error) {
+		if e.rowLoader != nil {
+			return e.loadSegmentOnce(seg)
+		}
+		return e.exportSegmentOnce(seg, uploader)
+	})
+}
+
+// exportSegmentOnce exports a segment in one transaction into one upload (see ExportSegment).
+func (e *Exporter) exportSegmentOnce(seg segment.Segment, uploader MultipartUploadStreamCreator) (*CSVFile, error) {
+	// Generate S3 key (one file per hash range, or per column range with -partition-strategy range)
+	filename := e.segmentFilename(seg)
+	s3Key, err := e.segmentS3Key(seg, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initiate multipart upload stream (and/or local file)
+	stream, localPath, err := e.openSegmentStream(s3Key, filename, uploader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initiate multipart upload: %w", err)
+		return nil, err
+	}
+	if uploader == nil {
+		s3Key = "" // Local-only output
+	}
+	var timing SegmentTiming
+	counted := &countingStream{stream: &timedStream{stream: stream, timing: &timing}}
+	stream = counted
+	if e.codec != nil {
+		stream = e.compressStream(stream)
 	}
 	defer func() {
 		if err != nil {
@@ -127,132 +313,430 @@ func (e *Exporter) ExportSegment(seg segment.Segment, uploader MultipartUploadSt
 		}
 	}()
 
-	// Start a transaction with REPEATABLE READ isolation to get a consistent snapshot
-	// This prevents new inserts from fis-updater from appearing during pagination
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	totalRows, checksum, sourceChanged, err := e.exportSegmentRows(seg, s3Key, stream, e.config.CSVHeader, &timing)
+	if err != nil {
+		return nil, err
+	}
+
+	if totalRows == 0 {
+		// No data exported, abort multipart upload
+		stream.Abort()
+		return nil, nil
+	}
+
+	// Complete multipart upload
+	if err := stream.Complete(); err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return &CSVFile{
+		FilePath:      localPath, // Empty unless -output-dir is set
+		S3Key:         s3Key,
+		Segment:       seg,
+		RowCount:      totalRows,
+		SourceChanged: sourceChanged,
+		Checksum:      checksum,
+		ByteSize:      counted.Bytes(),
+		Timing:        timing,
+	}, nil
+}
+
+// segmentFilename returns the name of a segment's file: tenant-<id>.<table>.hash-<start>-<end>.csv,
+// or tenant-<id>.<table>.<column>-<start>-<end>.csv for a range segment.
+func (e *Exporter) segmentFilename(seg segment.Segment) string {
+	if seg.Range != nil {
+		return fmt.Sprintf("tenant-%d.%s.%s-%d-%d%s",
+			e.config.TenantID, e.config.TableName, seg.Range.Column, seg.Range.Start, seg.Range.End, e.fileExtension())
+	}
+	return fmt.Sprintf("tenant-%d.%s.hash-%s-%s%s",
+		e.config.TenantID, e.config.TableName, seg.StartHex, seg.EndHex, e.fileExtension())
+}
+
+// fileExtension returns the extension of the exported files: .parquet with -format parquet,
+// otherwise .csv with the -compress codec's extension (e.g. .csv.gz).
+func (e *Exporter) fileExtension() string {
+	if e.config.Format == FormatParquet {
+		return ".parquet"
+	}
+	if e.codec != nil {
+		return ".csv" + e.codec.Extension()
+	}
+	return ".csv"
+}
+
+// exportSegmentRows streams the rows of seg to stream in its own export transaction, writing the CSV header
+// with the first batch if header is set, and adds the time spent to timing (see streamSegmentRows).
+// Returns the rows exported, their row digest and whether -detect-source-changes saw the segment change.
+// The stream is neither completed nor aborted.
+func (e *Exporter) exportSegmentRows(seg segment.Segment, s3Key string, stream MultipartUploadStreamer, header bool, timing *SegmentTiming) (int, string, bool, error) {
+	// Sample the segment before the snapshot so writes during export can be detected
+	var modifiedBefore *time.Time
+	var err error
+	if e.changeProbe != nil {
+		if modifiedBefore, err = e.changeProbe.MaxLastModified(seg); err != nil {
+			return 0, "", false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to sample segment for change detection: %w", err))
+		}
+	}
+
+	// Start a transaction at the configured isolation level (REPEATABLE READ by default)
+	// so new inserts from fis-updater don't appear during pagination
+	timeout := e.segmentTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	warnTimer := e.warnNearSegmentTimeout(seg, timeout)
+	defer warnTimer.Stop()
 
-	tx, err := e.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelRepeatableRead,
-	})
+	tx, err := e.beginExportTx(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
+		return 0, "", false, errs.Wrap(errs.ErrSourceQuery, err)
 	}
 	defer tx.Rollback() // Safe to call even if committed
 
+	totalRows, checksum, err := e.streamSegmentRows(seg, s3Key, stream, func(lastHash string) ([]Row, error) {
+		rows, err := e.querySegmentInTx(tx, seg, lastHash, ctx)
+		return rows, errs.Wrap(errs.ErrSourceQuery, err)
+	}, header, timing)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("segment %d exceeded -segment-timeout of %s: %w", seg.Index, timeout, err)
+		}
+		return 0, "", false, err
+	}
+
+	// Commit transaction (read-only, but needed to release locks)
+	if err = tx.Commit(); err != nil {
+		return 0, "", false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	sourceChanged := false
+	if e.changeProbe != nil {
+		if sourceChanged, err = e.sourceChangedSince(seg, modifiedBefore); err != nil {
+			return 0, "", false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to sample segment for change detection: %w", err))
+		}
+	}
+	return totalRows, checksum, sourceChanged, nil
+}
+
+// segmentTimeout returns the timeout of a segment's export transaction (-segment-timeout, 10 minutes if unset).
+func (e *Exporter) segmentTimeout() time.Duration {
+	if e.config.SegmentTimeout <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(e.config.SegmentTimeout) * time.Second
+}
+
+// warnNearSegmentTimeout logs a warning once a segment's export has used 80% of its timeout,
+// so a segment about to fail can be told apart from a hung one. Stop the returned timer when the export ends.
+func (e *Exporter) warnNearSegmentTimeout(seg segment.Segment, timeout time.Duration) *time.Timer {
+	start := time.Now()
+	return time.AfterFunc(timeout*8/10, func() {
+		e.logger.Warn("Segment export is close to -segment-timeout, raise it if the segment keeps failing",
+			zap.Int("segment", seg.Index),
+			zap.String("start_hex", seg.StartHex),
+			zap.String("end_hex", seg.EndHex),
+			zap.Duration("elapsed", time.Since(start)),
+			zap.Duration("segment_timeout", timeout))
+	})
+}
+
+// batchQueryFunc returns the next batch of rows for a segment after lastHash
+// (from the segment start when lastHash is empty).
+type batchQueryFunc func(lastHash string) ([]Row, error)
+
+// streamSegment streams a segment into a file of its own, starting with the CSV header if -csv-header is set
+// (see streamSegmentRows).
+func (e *Exporter) streamSegment(seg segment.Segment, s3Key string, stream MultipartUploadStreamer, query batchQueryFunc) (int, string, error) {
+	return e.streamSegmentRows(seg, s3Key, stream, query, e.config.CSVHeader, &SegmentTiming{})
+}
+
+// streamSegmentRows paginates through a segment with query and uploads each batch as a multipart part,
+// writing the CSV header with the first batch if header is set.
+// Rows rejected by row-level policies are sent to the dead-letter sink (if configured) instead of the CSV.
+// The time spent querying and serializing is added to timing; the upload time is added by the timedStream
+// below stream, or here for -direct-load inserts.
+// Returns the number of rows exported and their row digest (see RowDigest).
+func (e *Exporter) streamSegmentRows(seg segment.Segment, s3Key string, stream MultipartUploadStreamer, query batchQueryFunc, header bool, timing *SegmentTiming) (int, string, error) {
 	lastHash := "" // Track last hash for pagination
 	batchNum := 0
 	totalRows := 0
-	maxBatches := 10000 // Safety limit to prevent infinite loops
+	skippedRows := 0
+	invalidJSONRows := 0
+	maxBatches := e.config.MaxBatchesPerSegment // Safety limit to prevent infinite loops
+	if maxBatches <= 0 {
+		maxBatches = 10000
+	}
 	headerWritten := false
+	emptyBatches := 0 // Consecutive batches that returned no rows past the cursor
 
-	for batchNum < maxBatches {
-		var rows []Row
-		var queryErr error
+	// With -batch-bytes, parts are cut by CSV size instead of one per batch.
+	// With -format parquet the whole segment is one part.
+	var parts *csvPartWriter
+	var parquetFile *parquetSegmentWriter
+	if e.config.Format == FormatParquet {
+		parquetFile = e.newParquetSegmentWriter(stream)
+	} else if e.config.BatchBytes > 0 {
+		parts = e.newCSVPartWriter(stream, e.config.BatchBytes)
+	}
 
-		// Query segment (with cursor if not first batch)
-		if batchNum == 0 {
-			// First batch: query from segment start (no cursor)
-			rows, queryErr = e.querySegmentInTx(tx, seg, "", ctx)
-		} else {
-			// Subsequent batches: query from last hash (cursor-based pagination)
-			rows, queryErr = e.querySegmentInTx(tx, seg, lastHash, ctx)
-		}
+	maxEmptyBatches := e.config.MaxEmptyBatches
+	if maxEmptyBatches <= 0 {
+		maxEmptyBatches = 1
+	}
+
+	digest := NewRowDigest() // Over the rows as written, for the manifest
 
-		if queryErr != nil {
-			return nil, fmt.Errorf("failed to query segment: %w", queryErr)
+	// -checkpoint-interval: continue after the last batch a previous run recorded, and record progress
+	// every interval batches (one part each, uploaded before it's recorded)
+	var uploadedParts int32
+	if resumed, resumedDigest := e.resumeSegment(seg, stream); resumed != nil {
+		lastHash, totalRows, uploadedParts, digest = resumed.LastHash, resumed.Rows, resumed.Parts, resumedDigest
+		headerWritten = true
+	}
+	sinceProgress := 0
+
+	for batchNum < maxBatches {
+		// Query segment (first batch from segment start, then cursor-based from last hash)
+		queryStart := time.Now()
+		rows, err := query(lastHash)
+		timing.Query += time.Since(queryStart)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to query segment: %w", err)
 		}
 
 		if len(rows) == 0 {
 			break // No more data
 		}
+		fetched := len(rows)
 
-		// Update last hash for next iteration
-		if len(rows) > 0 {
-			lastHash = rows[len(rows)-1].Hash
+		// A batch whose rows are all at or before the cursor makes no progress.
+		// This should never happen mid-segment and indicates a cursor bug, so stop after
+		// -max-empty-batches in a row instead of looping up to maxBatches. In one snapshot the
+		// same cursor returns the same rows again, hence the default of failing at the first.
+		rows = rowsAfterCursor(rows, lastHash)
+		if len(rows) == 0 {
+			emptyBatches++
+			e.logger.Warn("Segment batch returned no rows past cursor",
+				zap.Int("segment", seg.Index),
+				zap.Int("batch", batchNum+1),
+				zap.Int("fetched_rows", fetched),
+				zap.String("last_hash", lastHash),
+				zap.Int("empty_batches", emptyBatches),
+				zap.Int("max_empty_batches", maxEmptyBatches))
+			if emptyBatches >= maxEmptyBatches {
+				return 0, "", fmt.Errorf("segment %d: %d consecutive batch(es) returned no rows past cursor %q (possible cursor bug, -max-empty-batches %d)",
+					seg.Index, emptyBatches, lastHash, maxEmptyBatches)
+			}
+			batchNum++
+			continue
 		}
+		emptyBatches = 0
+
+		// Update last hash for next iteration
+		lastHash = rows[len(rows)-1].Hash
 
-		// Convert rows to CSV bytes and upload as multipart part
-		csvBytes, err := e.rowsToCSVBytes(rows, !headerWritten)
+		serialized := timing.serializing()
+		rows, skipped, err := e.applyRowPolicies(rows, seg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert rows to CSV: %w", err)
+			return 0, "", err
 		}
-		headerWritten = true
+		batchSkipped := 0
+		for reason, count := range skipped {
+			batchSkipped += count
+			if reason == SkipReasonInvalidJSON {
+				invalidJSONRows += count
+			}
+		}
+		skippedRows += batchSkipped
+		for _, row := range rows {
+			digest.Add(row.Hash, e.exportedAggr(row))
+		}
+
+		if len(rows) > 0 && e.rowLoader != nil {
+			loadStart := time.Now()
+			err := e.loadRows(rows)
+			timing.Upload += time.Since(loadStart)
+			if err != nil {
+				return 0, "", err
+			}
+			totalRows += len(rows)
+		} else if len(rows) > 0 && parquetFile != nil {
+			if err := parquetFile.writeRows(rows); err != nil {
+				return 0, "", err
+			}
+			totalRows += len(rows)
+		} else if len(rows) > 0 && parts != nil {
+			// Buffer the CSV, parts are uploaded as they reach -batch-bytes
+			if err := parts.writeRows(rows, header && !headerWritten); err != nil {
+				return 0, "", err
+			}
+			headerWritten = true
+			totalRows += len(rows)
+		} else if len(rows) > 0 {
+			// Convert rows to CSV bytes and upload as multipart part
+			csvBytes, err := e.rowsToCSVBytes(rows, header && !headerWritten)
+			if err != nil {
+				return 0, "", fmt.Errorf("failed to convert rows to CSV: %w", err)
+			}
+			headerWritten = true
 
-		// Upload batch as multipart part
-		if err := stream.UploadPart(csvBytes); err != nil {
-			return nil, fmt.Errorf("failed to upload batch as multipart part: %w", err)
+			// Upload batch as multipart part
+			if err := stream.UploadPart(csvBytes); err != nil {
+				return 0, "", fmt.Errorf("failed to upload batch as multipart part: %w", err)
+			}
+			uploadedParts++
+
+			totalRows += len(rows)
 		}
+		serialized()
 
-		totalRows += len(rows)
+		if e.progress != nil && e.config.CheckpointInterval > 0 {
+			if sinceProgress++; sinceProgress >= e.config.CheckpointInterval {
+				e.recordProgress(seg, lastHash, uploadedParts, totalRows, digest)
+				sinceProgress = 0
+			}
+		}
 
 		e.logger.Info("Exported and uploaded segment batch",
 			zap.Int("segment", seg.Index),
 			zap.Int("batch", batchNum+1),
 			zap.Int("rows", len(rows)),
+			zap.Int("skipped_rows", batchSkipped),
 			zap.Int("total_rows", totalRows),
 			zap.String("s3_key", s3Key))
 
 		// If we got fewer rows than batch size, we're done
-		if len(rows) < e.config.BatchSize {
+		if fetched < e.config.BatchSize {
 			break
 		}
 
 		batchNum++
 	}
 
-	// Commit transaction (read-only, but needed to release locks)
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Rows past the limit would be silently missing from the CSV, so fail the segment if there are any.
+	// A segment of exactly maxBatches full batches also ends here: one more query tells them apart.
+	if batchNum >= maxBatches {
+		queryStart := time.Now()
+		rows, err := query(lastHash)
+		timing.Query += time.Since(queryStart)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to query segment: %w", err)
+		}
+		if len(rowsAfterCursor(rows, lastHash)) > 0 {
+			e.logger.Error("Segment export hit maximum batch limit",
+				zap.Int("segment", seg.Index),
+				zap.Int("max_batches", maxBatches),
+				zap.Int("total_batches", batchNum),
+				zap.Int("total_rows", totalRows),
+				zap.String("last_hash", lastHash))
+			return 0, "", fmt.Errorf("segment %d hit max batches limit (%d) after %d rows, export is incomplete (increase -max-batches-per-segment or -batch-size)",
+				seg.Index, maxBatches, totalRows)
+		}
 	}
 
-	if batchNum >= maxBatches {
-		e.logger.Warn("Segment export hit maximum batch limit",
-			zap.Int("segment", seg.Index),
-			zap.Int("max_batches", maxBatches),
-			zap.Int("total_batches", batchNum))
+	// Upload the rows below the -batch-bytes threshold as the last part
+	serialized := timing.serializing()
+	if parts != nil {
+		if err := parts.flush(); err != nil {
+			return 0, "", err
+		}
 	}
+	if parquetFile != nil {
+		if err := parquetFile.flush(); err != nil {
+			return 0, "", err
+		}
+	}
+	serialized()
 
-	if totalRows == 0 {
-		// No data exported, abort multipart upload
-		stream.Abort()
-		return nil, nil
+	if skippedRows > 0 {
+		e.logger.Warn("Segment rows skipped by row policies",
+			zap.Int("segment", seg.Index),
+			zap.Int("skipped_rows", skippedRows),
+			zap.Int("invalid_json_rows", invalidJSONRows),
+			zap.Bool("dead_letter", e.deadLetter != nil))
+	}
+	if e.config.ValidateJSON != "" {
+		e.logger.Info("Segment aggr JSON validated",
+			zap.Int("segment", seg.Index),
+			zap.Int("rows", totalRows),
+			zap.Int("invalid_json_rows", invalidJSONRows))
 	}
 
-	// Complete multipart upload
-	if err := stream.Complete(); err != nil {
-		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	return totalRows, digest.Sum(), nil
+}
+
+// rowsAfterCursor returns the rows with a hash strictly greater than lastHash.
+// Rows must be ordered by hash (as returned by querySegmentInTx). All rows are returned when lastHash is empty (first batch).
+func rowsAfterCursor(rows []Row, lastHash string) []Row {
+	if lastHash == "" {
+		return rows
+	}
+	for i, row := range rows {
+		if row.Hash > lastHash {
+			return rows[i:]
+		}
 	}
+	return nil
+}
 
-	return &CSVFile{
-		FilePath: "", // Empty for streaming uploads
-		S3Key:    s3Key,
-		Segment:  seg,
-		RowCount: totalRows,
-	}, nil
+// applyRowPolicies filters out rows rejected by checkRow and, with -validate-json, checkRowJSON,
+// recording them in the dead-letter sink.
+// With -fail-on-invalid-rows a row rejected by checkRow fails the segment instead of being skipped.
+// Returns the exportable rows and the number of skipped rows by skip reason.
+func (e *Exporter) applyRowPolicies(rows []Row, seg segment.Segment) ([]Row, map[string]int, error) {
+	valid := rows[:0]
+	var skipped map[string]int
+	for _, row := range rows {
+		reason := checkRow(row)
+		if reason != "" && e.config.FailOnInvalidRows {
+			return nil, nil, fmt.Errorf("segment %d: row %q can't be exported (%s, -fail-on-invalid-rows)", seg.Index, row.Hash, reason)
+		}
+		if reason == "" {
+			var err error
+			if reason, err = checkRowJSON(row, seg, e.config.ValidateJSON); err != nil {
+				return nil, nil, err
+			}
+		}
+		if reason == "" {
+			valid = append(valid, row)
+			continue
+		}
+
+		if skipped == nil {
+			skipped = make(map[string]int)
+		}
+		skipped[reason]++
+		if e.deadLetter != nil {
+			if e.maskAggr != nil {
+				row.Aggr = e.maskAggr(row.Aggr) // Dead-letter file must not carry the original either
+			}
+			if err := e.deadLetter.Write(row, seg.Index, reason); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			e.logger.Warn("Skipping row",
+				zap.Int("segment", seg.Index),
+				zap.String("hash", row.Hash),
+				zap.String("reason", reason))
+		}
+	}
+	return valid, skipped, nil
 }
 
 // rowsToCSVBytes converts rows to CSV bytes in memory.
 func (e *Exporter) rowsToCSVBytes(rows []Row, includeHeader bool) ([]byte, error) {
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
+	writer.Comma = e.config.CSVComma()
 
 	if includeHeader {
-		header := []string{"tenantid", "hash", "aggr", "last_modified", "version"}
+		header := ExportColumns(e.config.TenantColumnName(), e.config.ExcludedColumns())
 		if err := writer.Write(header); err != nil {
 			return nil, fmt.Errorf("failed to write CSV header: %w", err)
 		}
 	}
 
 	for _, row := range rows {
-		record := []string{
-			fmt.Sprintf("%d", row.TenantID),
-			row.Hash,
-			row.Aggr,
-			formatTimestamp(row.LastModified),
-			formatInt(row.Version),
-		}
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(e.csvRecord(row)); err != nil {
 			return nil, fmt.Errorf("failed to write CSV row: %w", err)
 		}
 	}
@@ -265,146 +749,339 @@ func (e *Exporter) rowsToCSVBytes(rows []Row, includeHeader bool) ([]byte, error
 	return buf.Bytes(), nil
 }
 
-// querySegmentInTx queries a segment within a transaction.
-// If lastHash is provided (non-empty), it implements cursor-based pagination starting from that hash.
-// If lastHash is empty, it queries from the segment start.
-func (e *Exporter) querySegmentInTx(tx *sql.Tx, seg segment.Segment, lastHash string, ctx context.Context) ([]Row, error) {
-	// Handle the special case where EndHex is "100" (means >= 256, should include "ff")
-	// For the last segment, we use <= "ff" instead of < "100"
-	endHex := seg.EndHex
-	useLessThan := true
-	if endHex == "100" {
-		endHex = "ff"       // Compare against "ff" for the last segment
-		useLessThan = false // Use <= for the last segment to include "ff"
+// csvRecord returns the CSV fields of a row, with aggr masked if -mask-aggr is set
+// and without the -exclude-columns columns.
+func (e *Exporter) csvRecord(row Row) []string {
+	record := make([]string, 0, 5)
+	record = append(record, fmt.Sprintf("%d", row.TenantID), row.Hash)
+	if !e.omitted["aggr"] {
+		record = append(record, e.exportedAggr(row))
+	}
+	if !e.omitted["last_modified"] {
+		record = append(record, formatTimestamp(row.LastModified, e.config.NullMarker))
+	}
+	if !e.omitted["version"] {
+		record = append(record, formatInt(row.Version, e.config.NullMarker))
 	}
+	return record
+}
 
-	// Use database.table format if database is specified
-	tableRef := e.config.TableName
-	if e.config.MariaDBDatabase != "" {
-		tableRef = fmt.Sprintf("%s.%s", e.config.MariaDBDatabase, e.config.TableName)
+// exportedAggr returns the aggr written for a row, masked if -mask-aggr is set.
+func (e *Exporter) exportedAggr(row Row) string {
+	if e.maskAggr != nil {
+		return e.maskAggr(row.Aggr)
 	}
+	return row.Aggr
+}
 
+// querySegmentInTx queries a segment within a transaction.
+// If lastHash is provided (non-empty), it implements cursor-based pagination starting from that hash.
+// If lastHash is empty, it queries from the segment start.
+// A full batch always ends with every row of its last hash, even past -batch-size: the next batch
+// starts after that hash, so rows sharing it (a source without the unique (tenantid, hash) constraint)
+// would otherwise be lost at the batch boundary.
+// With -query-timeout the batch gets its own deadline within the segment's ctx, so a hung query fails
+// the segment right away instead of using up the rest of -segment-timeout.
+func (e *Exporter) querySegmentInTx(tx *sql.Tx, seg segment.Segment, lastHash string, ctx context.Context) ([]Row, error) {
 	// Build hash condition based on whether we have a cursor (lastHash) and segment type
-	var hashCondition string
+	// Uses lexicographic string comparison: comparing '00' (2 chars) against full hash strings
+	// like '00abc123...' (32 chars) works because shorter prefix strings compare less than
+	// longer strings that start with that prefix. This allows prefix matching via direct
+	// string comparison, for any prefix width (e.g. '000' for 3-char prefixes).
+	hashCondition, boundArgs := e.withExclusion(segmentBoundsCondition(seg))
 	hasCursor := lastHash != ""
 
 	if hasCursor {
-		// Cursor-based pagination: hash > lastHash AND hash >= startHex AND hash < endHex
+		// Cursor-based pagination: hash > lastHash AND <segment bounds>
 		// This ensures we:
 		// 1. Continue from where we left off (hash > lastHash)
 		// 2. Stay within the segment boundaries (hash >= startHex AND hash < endHex)
-		if !useLessThan {
-			// Last segment: hash > lastHash AND hash >= startHex AND hash <= 'ff'
-			hashCondition = "hash > ? AND hash >= ? AND hash <= 'ff'"
-		} else {
-			// Regular segment: hash > lastHash AND hash >= startHex AND hash < endHex
-			hashCondition = "hash > ? AND hash >= ? AND hash < ?"
-		}
-	} else {
-		// No cursor: query from segment start
-		// Uses lexicographic string comparison: comparing '00' (2 chars) against full hash strings
-		// like '00abc123...' (32 chars) works because shorter prefix strings compare less than
-		// longer strings that start with that prefix. This allows prefix matching via direct
-		// string comparison.
-		if !useLessThan {
-			// Last segment: hash >= startHex AND hash <= 'ff' (inclusive of 'ff' prefix)
-			// Note: 'ff' as string boundary includes all hashes starting with 'ff'
-			hashCondition = "hash >= ? AND hash <= 'ff'"
-		} else {
-			// Regular segment: hash >= startHex AND hash < endHex (exclusive)
-			// This matches all hashes where the first 2 hex chars are in [startHex, endHex)
-			hashCondition = "hash >= ? AND hash < ?"
-		}
+		hashCondition = "hash > ? AND " + hashCondition
 	}
 
 	query := fmt.Sprintf(`
-		SELECT tenantid, hash, aggr, last_modified, version
-		FROM %s
-		WHERE tenantid = ?
-		  AND %s
+		SELECT %[4]s
+		FROM %[2]s
+		WHERE %[1]s = ?
+		  AND %[3]s
 		ORDER BY hash
 		LIMIT ?`,
-		tableRef, hashCondition)
+		e.config.TenantColumnName(), tableRef(e.config), hashCondition, e.selectColumns())
 
-	// Build args based on cursor presence and segment type
+	// Build args based on cursor presence: lastHash first, then segment bounds and exclusions
 	var args []interface{}
 	args = append(args, e.config.TenantID)
-
 	if hasCursor {
-		// Cursor-based: add lastHash first, then segment bounds
 		args = append(args, lastHash)
-		args = append(args, seg.StartHex)
-		if useLessThan {
-			args = append(args, endHex)
-		}
-	} else {
-		// No cursor: just segment bounds
-		args = append(args, seg.StartHex)
-		if useLessThan {
-			args = append(args, endHex)
-		}
 	}
-
+	args = append(args, boundArgs...)
 	args = append(args, e.config.BatchSize)
 
 	e.logger.Debug("Querying segment",
 		zap.Int("segment", seg.Index),
 		zap.String("start_hex", seg.StartHex),
 		zap.String("end_hex", seg.EndHex),
-		zap.String("end_hex_adjusted", endHex),
+		zap.Bool("last_segment", seg.IsLast()),
 		zap.String("last_hash", lastHash),
 		zap.Bool("has_cursor", hasCursor),
 		zap.String("query", query),
 		zap.Int("tenant_id", e.config.TenantID))
 
+	queryCtx := ctx
+	if e.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, time.Duration(e.config.QueryTimeout)*time.Second)
+		defer cancel()
+	}
+
 	// Use transaction to ensure REPEATABLE READ isolation
+	result, err := queryRowsInTx(tx, queryCtx, query, args)
+	if err != nil {
+		return nil, e.queryTimeoutError(queryCtx, ctx, seg, lastHash, err)
+	}
+	if len(result) < e.config.BatchSize {
+		return result, nil
+	}
+
+	// Full batch: replace its rows of the last hash with all of them, read in the same snapshot
+	boundaryHash := result[len(result)-1].Hash
+	groupCondition, groupBoundArgs := e.withExclusion(segmentBoundsCondition(seg))
+	groupQuery := fmt.Sprintf(`
+		SELECT %[4]s
+		FROM %[2]s
+		WHERE %[1]s = ?
+		  AND hash = ?
+		  AND %[3]s`,
+		e.config.TenantColumnName(), tableRef(e.config), groupCondition, e.selectColumns())
+	groupArgs := append([]interface{}{e.config.TenantID, boundaryHash}, groupBoundArgs...)
+	group, err := queryRowsInTx(tx, queryCtx, groupQuery, groupArgs)
+	if err != nil {
+		return nil, e.queryTimeoutError(queryCtx, ctx, seg, lastHash, err)
+	}
+	for len(result) > 0 && result[len(result)-1].Hash == boundaryHash {
+		result = result[:len(result)-1]
+	}
+	if len(result)+len(group) > e.config.BatchSize {
+		e.logger.Debug("Batch extended past batch size to the last hash's duplicate rows",
+			zap.Int("segment", seg.Index),
+			zap.String("hash", boundaryHash),
+			zap.Int("duplicate_rows", len(group)))
+	}
+	return append(result, group...), nil
+}
+
+// queryRowsInTx runs a segment query within the export transaction and scans its rows.
+func queryRowsInTx(tx *sql.Tx, ctx context.Context, query string, args []interface{}) ([]Row, error) {
 	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, lockConflict(fmt.Errorf("query failed: %w", err))
 	}
 	defer rows.Close()
 
 	var result []Row
 	for rows.Next() {
-		var r Row
-		var lastModified sql.NullTime
-		var version sql.NullInt64
-
-		if err := rows.Scan(&r.TenantID, &r.Hash, &r.Aggr, &lastModified, &version); err != nil {
+		r, err := scanRow(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-
-		if lastModified.Valid {
-			r.LastModified = &lastModified.Time
-		}
-		if version.Valid {
-			v := int(version.Int64)
-			r.Version = &v
-		}
-
 		result = append(result, r)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+		return nil, lockConflict(fmt.Errorf("row iteration error: %w", err))
 	}
 
 	return result, nil
 }
 
-// formatTimestamp formats a timestamp for CSV.
-func formatTimestamp(t *time.Time) string {
+// queryTimeoutError names -query-timeout in err if the batch query's own deadline expired (queryCtx),
+// as opposed to the segment's (ctx). Other errors are returned unchanged.
+func (e *Exporter) queryTimeoutError(queryCtx, ctx context.Context, seg segment.Segment, lastHash string, err error) error {
+	if ctx.Err() != nil || !errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("segment %d batch query after hash %q exceeded -query-timeout of %ds: %w",
+		seg.Index, lastHash, e.config.QueryTimeout, err)
+}
+
+// rowScanner is implemented by *sql.Rows and *sql.Row.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRow scans the tenant column, hash, aggr, last_modified and version into a Row.
+func scanRow(scanner rowScanner) (Row, error) {
+	var r Row
+	var lastModified sql.NullTime
+	var version sql.NullInt64
+
+	if err := scanner.Scan(&r.TenantID, &r.Hash, &r.Aggr, &lastModified, &version); err != nil {
+		return Row{}, err
+	}
+
+	if lastModified.Valid {
+		r.LastModified = &lastModified.Time
+	}
+	if version.Valid {
+		v := int(version.Int64)
+		r.Version = &v
+	}
+	return r, nil
+}
+
+// CountSegmentRows returns the number of rows in a segment.
+// Used as a cheap pre-count (index range scan on tenantid, hash) for ordering segment dispatch.
+func (e *Exporter) CountSegmentRows(seg segment.Segment) (int64, error) {
+	hashCondition, boundArgs := e.withExclusion(segmentBoundsCondition(seg))
+	args := append([]interface{}{e.config.TenantID}, boundArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE %s = ?
+		  AND %s`,
+		tableRef(e.config), e.config.TenantColumnName(), hashCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var count int64
+	if err := e.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to count segment %d: %w", seg.Index, err))
+	}
+	return count, nil
+}
+
+// CountTenantRows counts the tenant's rows in the source table, excluding -exclude-where rows and those not matching -where.
+// The count is served by the (tenantid, hash) index.
+func (e *Exporter) CountTenantRows() (int64, error) {
+	condition, args := e.withExclusion(e.config.TenantColumnName()+" = ?", []interface{}{e.config.TenantID})
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE %s`,
+		tableRef(e.config), condition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var count int64
+	if err := e.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to count rows of tenant %d: %w", e.config.TenantID, err))
+	}
+	return count, nil
+}
+
+// ColumnRange returns the minimum and maximum of a numeric column over the tenant's rows,
+// excluding -exclude-where rows, for -partition-strategy range. Returns ok false if the tenant has no rows.
+// Rows with a NULL in the column would be in no range segment, so they fail the call.
+func (e *Exporter) ColumnRange(column string) (min, max int64, ok bool, err error) {
+	condition, args := e.withExclusion(e.config.TenantColumnName()+" = ?", []interface{}{e.config.TenantID})
+	query := fmt.Sprintf(`
+		SELECT MIN(%[1]s), MAX(%[1]s), COUNT(*) - COUNT(%[1]s)
+		FROM %[2]s
+		WHERE %[3]s`,
+		column, tableRef(e.config), condition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var minVal, maxVal sql.NullInt64
+	var nulls int64
+	if err := e.db.QueryRowContext(ctx, query, args...).Scan(&minVal, &maxVal, &nulls); err != nil {
+		return 0, 0, false, errs.Wrap(errs.ErrSourceQuery, fmt.Errorf("failed to read the range of %s for tenant %d: %w", column, e.config.TenantID, err))
+	}
+	if nulls > 0 {
+		return 0, 0, false, errs.Wrap(errs.ErrConfig, fmt.Errorf("partition column %s is NULL in %d rows of tenant %d, which no range segment would export",
+			column, nulls, e.config.TenantID))
+	}
+	if !minVal.Valid || !maxVal.Valid {
+		return 0, 0, false, nil
+	}
+	return minVal.Int64, maxVal.Int64, true, nil
+}
+
+// DistinctHashPrefixes returns the distinct hash prefixes of prefixLen hex characters present for the tenant,
+// in ascending order. Used to compare the requested segment count with the actual prefix cardinality.
+func (e *Exporter) DistinctHashPrefixes(prefixLen int) ([]string, error) {
+	condition, args := e.withExclusion("CHAR_LENGTH(hash) >= ?", []interface{}{prefixLen})
+	query := fmt.Sprintf(`
+		SELECT DISTINCT LOWER(LEFT(hash, ?)) AS prefix
+		FROM %s
+		WHERE %s = ?
+		  AND %s
+		ORDER BY prefix`,
+		tableRef(e.config), e.config.TenantColumnName(), condition)
+	args = append([]interface{}{prefixLen, e.config.TenantID}, args...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample hash prefixes: %w", err)
+	}
+	defer rows.Close()
+
+	var prefixes []string
+	for rows.Next() {
+		var prefix string
+		if err := rows.Scan(&prefix); err != nil {
+			return nil, fmt.Errorf("failed to scan hash prefix: %w", err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("hash prefix iteration error: %w", err)
+	}
+	return prefixes, nil
+}
+
+// segmentBoundsCondition returns the hash condition and args selecting all rows of a segment.
+// A range segment selects on its partition column instead.
+func segmentBoundsCondition(seg segment.Segment) (string, []interface{}) {
+	if r := seg.Range; r != nil {
+		if r.Last {
+			// Last segment: no upper bound, so rows above the max found at the start are kept
+			return r.Column + " >= ?", []interface{}{r.Start}
+		}
+		return r.Column + " >= ? AND " + r.Column + " < ?", []interface{}{r.Start, r.End}
+	}
+	if seg.IsLast() {
+		// Last segment: hash >= startHex with no upper bound. Its EndHex ("100", "1000", ...)
+		// sorts before "ff" as a string, and a 'ff' bound would exclude hashes like 'ffabc...'
+		return "hash >= ?", []interface{}{seg.StartHex}
+	}
+	// Regular segment: hash >= startHex AND hash < endHex (exclusive)
+	return "hash >= ? AND hash < ?", []interface{}{seg.StartHex, seg.EndHex}
+}
+
+// tableRef returns the source table reference, using database.table format if database is specified.
+func tableRef(cfg *config.Config) string {
+	if cfg.MariaDBDatabase != "" {
+		return fmt.Sprintf("%s.%s", cfg.MariaDBDatabase, cfg.TableName)
+	}
+	return cfg.TableName
+}
+
+// CSVHeader returns the header of the exported CSV files, naming the tenant column tenantColumn.
+func CSVHeader(tenantColumn string) []string {
+	return []string{tenantColumn, "hash", "aggr", "last_modified", "version"}
+}
+
+// formatTimestamp formats a timestamp for CSV, writing null (-null-marker) for NULL.
+func formatTimestamp(t *time.Time, null string) string {
 	if t == nil {
-		return ""
+		return null
 	}
 	// MySQL timestamp format: YYYY-MM-DD HH:MM:SS
 	return t.Format("2006-01-02 15:04:05")
 }
 
-// formatInt formats an integer pointer for CSV.
-func formatInt(i *int) string {
+// formatInt formats an integer pointer for CSV, writing null (-null-marker) for NULL.
+func formatInt(i *int, null string) string {
 	if i == nil {
-		return ""
+		return null
 	}
 	return fmt.Sprintf("%d", *i)
 }
@@ -3,21 +3,30 @@
 package exporter
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"github.com/netSkope/fis-migration-tool/internal/segment"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/mariadb"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // detectReaperIssue checks if we need to disable the testcontainers reaper
@@ -380,12 +389,10 @@ func TestQuerySegmentInTx_FromStart(t *testing.T) {
 		t.Fatalf("QuerySegment failed: %v", err)
 	}
 
-	// Should find at least 1 row (c0), ff might not match due to string comparison
-	// The query uses hash <= 'ff' which means full hash string comparison
-	// "ffabc123def456" > "ff" as a string, so it won't match
-	// This is expected behavior - the query correctly handles the last segment
-	if len(rows) < 1 {
-		t.Errorf("Expected at least 1 row in segment 3, got %d", len(rows))
+	// Should find 2 rows (c0, ff) - the last segment has no upper bound,
+	// so "ffabc123def456" is included even though it sorts after "ff"
+	if len(rows) != 2 {
+		t.Errorf("Expected 2 rows in segment 3, got %d", len(rows))
 	}
 
 	// Verify the row we got is in the correct segment
@@ -478,6 +485,365 @@ func TestExportSegment(t *testing.T) {
 	}
 }
 
+func TestExportSegment_MultipleTables(t *testing.T) {
+	db, cleanup, connStr := setupTestDB(t)
+	defer cleanup()
+
+	logger := zaptest.NewLogger(t)
+
+	parts := strings.Split(connStr, "@tcp(")
+	if len(parts) < 2 {
+		t.Fatalf("Invalid connection string format: %s", connStr)
+	}
+	hostPortPart := strings.Split(parts[1], ")/")[0]
+
+	tenantID := 777777
+	setupTestTable(t, db, tenantID)
+	// Second table with the same rows, as -tables migrates tables sharing the hash segmentation
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS fis_aggr_v2",
+		"CREATE TABLE fis_aggr_v2 LIKE fis_aggr",
+		fmt.Sprintf("INSERT INTO fis_aggr_v2 SELECT * FROM fis_aggr WHERE tenantid = %d", tenantID),
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up second table: %v", err)
+		}
+	}
+
+	mockUploader := newMockS3Uploader()
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}
+	keysByTable := make(map[string]string)
+	for _, table := range []string{"fis_aggr", "fis_aggr_v2"} {
+		cfg := &config.Config{
+			TenantID:        tenantID,
+			TableName:       table,
+			Tables:          []string{"fis_aggr", "fis_aggr_v2"},
+			MariaDBDatabase: "fis",
+			BatchSize:       1000,
+			S3Prefix:        "test-prefix",
+			MariaDBHost:     hostPortPart,
+			MariaDBUser:     "root",
+			MariaDBPassword: "testpassword",
+		}
+		exporter, err := NewExporter(cfg, logger)
+		if err != nil {
+			t.Fatalf("Failed to create exporter: %v", err)
+		}
+		exporter.db = db
+
+		csvFile, err := exporter.ExportSegment(seg, mockUploader)
+		if err != nil {
+			t.Fatalf("ExportSegment(%s) failed: %v", table, err)
+		}
+		if csvFile.RowCount != 3 {
+			t.Errorf("expected 3 rows from %s, got %d", table, csvFile.RowCount)
+		}
+		wantDir := fmt.Sprintf("test-prefix/tenant-%d/%s/", tenantID, table)
+		if !strings.HasPrefix(csvFile.S3Key, wantDir) {
+			t.Errorf("expected %s key under %s, got %s", table, wantDir, csvFile.S3Key)
+		}
+		keysByTable[table] = csvFile.S3Key
+	}
+
+	if keysByTable["fis_aggr"] == keysByTable["fis_aggr_v2"] {
+		t.Errorf("tables share S3 key %s", keysByTable["fis_aggr"])
+	}
+	if len(mockUploader.streams) != 2 {
+		t.Errorf("expected 2 uploaded objects, got %d", len(mockUploader.streams))
+	}
+}
+
+func TestExportSegment_TenantColumn(t *testing.T) {
+	db, cleanup, connStr := setupTestDB(t)
+	defer cleanup()
+
+	logger := zaptest.NewLogger(t)
+
+	parts := strings.Split(connStr, "@tcp(")
+	if len(parts) < 2 {
+		t.Fatalf("Invalid connection string format: %s", connStr)
+	}
+	hostPortPart := strings.Split(parts[1], ")/")[0]
+
+	tenantID := 888888
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS fis_aggr_tenant_id",
+		`CREATE TABLE fis_aggr_tenant_id (
+			tenant_id INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE(tenant_id, hash)
+		)`,
+		fmt.Sprintf(`INSERT INTO fis_aggr_tenant_id (tenant_id, hash, aggr) VALUES
+			(%[1]d, '00abc123def456', '{}'), (%[1]d, '3fabc123def456', '{}'),
+			(%[1]d, '40abc123def456', '{}'), (%[1]d + 1, '01abc123def456', '{}')`, tenantID),
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up table: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		TenantID:        tenantID,
+		TableName:       "fis_aggr_tenant_id",
+		TenantColumn:    "tenant_id",
+		MariaDBDatabase: "fis",
+		BatchSize:       1,
+		S3Prefix:        "test-prefix",
+		RequireIndex:    true,
+		CSVHeader:       true,
+		MariaDBHost:     hostPortPart,
+		MariaDBUser:     "root",
+		MariaDBPassword: "testpassword",
+	}
+	exporter, err := NewExporter(cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.db = db
+
+	if err := exporter.VerifySegmentIndex(); err != nil {
+		t.Errorf("VerifySegmentIndex() error = %v", err)
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}
+	count, err := exporter.CountSegmentRows(seg)
+	if err != nil {
+		t.Fatalf("CountSegmentRows() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows counted, got %d", count)
+	}
+
+	mockUploader := newMockS3Uploader()
+	csvFile, err := exporter.ExportSegment(seg, mockUploader)
+	if err != nil {
+		t.Fatalf("ExportSegment() failed: %v", err)
+	}
+	if csvFile.RowCount != 2 {
+		t.Errorf("expected 2 rows, got %d", csvFile.RowCount)
+	}
+
+	var content bytes.Buffer
+	for _, part := range mockUploader.streams[csvFile.S3Key].parts {
+		content.Write(part)
+	}
+	records, err := csv.NewReader(&content).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse exported CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %v", records)
+	}
+	if got := strings.Join(records[0], ","); got != "tenant_id,hash,aggr,last_modified,version" {
+		t.Errorf("unexpected header %q", got)
+	}
+	for _, record := range records[1:] {
+		if record[0] != fmt.Sprintf("%d", tenantID) {
+			t.Errorf("expected tenant %d, got row %v", tenantID, record)
+		}
+	}
+}
+
+func TestExportSingleHash(t *testing.T) {
+	db, cleanup, connStr := setupTestDB(t)
+	defer cleanup()
+
+	logger := zaptest.NewLogger(t)
+
+	parts := strings.Split(connStr, "@tcp(")
+	if len(parts) < 2 {
+		t.Fatalf("Invalid connection string format: %s", connStr)
+	}
+	hostPortPart := strings.Split(parts[1], ")/")[0]
+
+	tenantID := 555555
+	setupTestTable(t, db, tenantID)
+	if _, err := db.Exec(`
+		INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version)
+		VALUES (?, '5eabc123def456', '{"known": "row"}', '2024-03-01 12:00:00', 7)`, tenantID); err != nil {
+		t.Fatalf("Failed to insert known row: %v", err)
+	}
+
+	cfg := &config.Config{
+		TenantID:        tenantID,
+		TableName:       "fis_aggr",
+		MariaDBDatabase: "fis",
+		NullMarker:      "\\N",
+		CSVHeader:       true,
+		MariaDBHost:     hostPortPart,
+		MariaDBUser:     "root",
+		MariaDBPassword: "testpassword",
+	}
+	exporter, err := NewExporter(cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.db = db
+
+	row, err := exporter.ExportSingleHash("5eabc123def456")
+	if err != nil {
+		t.Fatalf("ExportSingleHash() error = %v", err)
+	}
+	if row == nil {
+		t.Fatal("ExportSingleHash() returned no row")
+	}
+	if row.TenantID != tenantID || row.Hash != "5eabc123def456" || row.Aggr != `{"known": "row"}` {
+		t.Errorf("unexpected row %+v", row)
+	}
+	if row.Version == nil || *row.Version != 7 || row.LastModified == nil {
+		t.Errorf("expected version 7 and last_modified, got %+v", row)
+	}
+
+	data, err := exporter.FormatCSV([]Row{*row})
+	if err != nil {
+		t.Fatalf("FormatCSV() error = %v", err)
+	}
+	want := "tenantid,hash,aggr,last_modified,version\n" +
+		fmt.Sprintf("%d,5eabc123def456,\"{\"\"known\"\": \"\"row\"\"}\",2024-03-01 12:00:00,7\n", tenantID)
+	if string(data) != want {
+		t.Errorf("FormatCSV() = %q, want %q", data, want)
+	}
+
+	// Another tenant's hash or an unknown hash is not found
+	row, err = exporter.ExportSingleHash("ffffffffffffff")
+	if err != nil || row != nil {
+		t.Errorf("ExportSingleHash() for an unknown hash = %+v, %v, want nil, nil", row, err)
+	}
+}
+
+func TestNewExporter_SourceVersion(t *testing.T) {
+	_, cleanup, connStr := setupTestDB(t)
+	defer cleanup()
+
+	parts := strings.Split(connStr, "@tcp(")
+	if len(parts) < 2 {
+		t.Fatalf("Invalid connection string format: %s", connStr)
+	}
+	hostPortPart := strings.Split(parts[1], ")/")[0]
+
+	cfg := &config.Config{
+		TenantID:        1,
+		TableName:       "fis_aggr",
+		MariaDBDatabase: "fis",
+		MariaDBHost:     hostPortPart,
+		MariaDBUser:     "root",
+		MariaDBPassword: "testpassword",
+		IsolationLevel:  IsolationSnapshot,
+	}
+	exporter, err := NewExporter(cfg, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	defer exporter.Close()
+
+	v := exporter.SourceVersion()
+	if v.Flavor != FlavorMariaDB || !strings.HasPrefix(v.Version, "10.11.") || v.Major != 10 || v.Minor != 11 {
+		t.Errorf("SourceVersion() = %+v, want MariaDB 10.11 from the container", v)
+	}
+}
+
+func TestNewExporter_ErrorKinds(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	_, err := NewExporter(&config.Config{ExcludeWhere: "deleted_at;"}, logger)
+	if !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("NewExporter() with invalid -exclude-where error = %v, want ErrConfig", err)
+	}
+
+	// Nothing listens on port 1
+	_, err = NewExporter(&config.Config{MariaDBHost: "127.0.0.1", MariaDBPort: 1, MariaDBUser: "root"}, logger)
+	if !errors.Is(err, errs.ErrSourceConnect) || !errs.IsRetryable(err) {
+		t.Errorf("NewExporter() with unreachable MariaDB error = %v, want a retryable ErrSourceConnect", err)
+	}
+}
+
+// coldConnector refuses the first failures connections, like a database still starting up, then serves SELECT VERSION()
+type coldConnector struct {
+	failures int
+	err      error // Of the failed attempts; nil for connection refused
+	attempts int
+}
+
+func (c *coldConnector) Connect(context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+	}
+	return coldConn{}, nil
+}
+func (c *coldConnector) Driver() driver.Driver { return nil }
+
+type coldConn struct{}
+
+func (coldConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (coldConn) Close() error                              { return nil }
+func (coldConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+func (coldConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &versionRows{}, nil
+}
+
+type versionRows struct{ done bool }
+
+func (*versionRows) Columns() []string { return []string{"VERSION()"} }
+func (*versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = "10.11.6-MariaDB", true
+	return nil
+}
+
+func TestNewExporter_ConnectRetries(t *testing.T) {
+	origOpen := openSourceDB
+	defer func() { openSourceDB = origOpen }()
+
+	tests := []struct {
+		name         string
+		failures     int
+		retries      int
+		err          error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"first ping fails, then succeeds", 1, 3, nil, false, 2},
+		{"no retries", 1, 0, nil, true, 1},
+		{"retries exhausted", 5, 2, nil, true, 3},
+		{"up at once", 0, 3, nil, false, 1},
+		{"access denied is not retried", 1, 3, &mysql.MySQLError{Number: 1045, Message: "Access denied for user 'fis'"}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &coldConnector{failures: tt.failures, err: tt.err}
+			openSourceDB = func(dsn string) (*sql.DB, error) { return sql.OpenDB(connector), nil }
+
+			cfg := &config.Config{DBConnectRetries: tt.retries, DBConnectBackoff: time.Millisecond}
+			exp, err := NewExporter(cfg, zaptest.NewLogger(t))
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrSourceConnect) {
+					t.Errorf("NewExporter() error = %v, want ErrSourceConnect", err)
+				}
+			} else if err != nil {
+				t.Fatalf("NewExporter() error = %v", err)
+			} else {
+				if v := exp.SourceVersion(); v.Flavor != FlavorMariaDB {
+					t.Errorf("SourceVersion() = %+v, want MariaDB", v)
+				}
+				exp.Close()
+			}
+			if connector.attempts != tt.wantAttempts {
+				t.Errorf("connection attempts = %d, want %d", connector.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
 func TestExportSegment_Pagination(t *testing.T) {
 	// Test that ExportSegment correctly paginates through all data
 	// even when total rows exceed BatchSize
@@ -688,3 +1054,598 @@ func TestQuerySegmentInTx_WithCursor(t *testing.T) {
 		}
 	}
 }
+
+// newFakeQuerier returns a batchQueryFunc serving rows (ordered by hash) with
+// cursor-based pagination, for testing the export loop without a database
+func newFakeQuerier(rows []Row, batchSize int) (batchQueryFunc, *int) {
+	calls := 0
+	return func(lastHash string) ([]Row, error) {
+		calls++
+		var batch []Row
+		for _, row := range rows {
+			if row.Hash > lastHash {
+				batch = append(batch, row)
+				if len(batch) == batchSize {
+					break
+				}
+			}
+		}
+		return batch, nil
+	}, &calls
+}
+
+func TestExportSegment_MaxBatchesLimit(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	cfg := &config.Config{
+		TenantID:             999999,
+		TableName:            "fis_aggr",
+		BatchSize:            2,
+		MaxBatchesPerSegment: 3,
+	}
+	exporter := &Exporter{config: cfg, logger: logger}
+
+	// 10 rows with batch size 2 need 5 batches, exceeding the limit of 3
+	var rows []Row
+	for i := 0; i < 10; i++ {
+		rows = append(rows, Row{TenantID: 999999, Hash: fmt.Sprintf("00%030x", i), Aggr: "{}"})
+	}
+	query, calls := newFakeQuerier(rows, cfg.BatchSize)
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	_, _, err := exporter.streamSegment(seg, "test-key", &mockMultipartUploadStream{}, query)
+	if err == nil {
+		t.Fatal("expected error when max batches limit is exceeded")
+	}
+	if !strings.Contains(err.Error(), "max batches limit") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The limit's batches, then one query finding the rows past them
+	if *calls != cfg.MaxBatchesPerSegment+1 {
+		t.Errorf("expected %d queries, got %d", cfg.MaxBatchesPerSegment+1, *calls)
+	}
+
+	// Exactly the limit's full batches (6 rows in 3 batches of 2) is a complete export
+	query, calls = newFakeQuerier(rows[:6], cfg.BatchSize)
+	exported, _, err := exporter.streamSegment(seg, "test-key", &mockMultipartUploadStream{}, query)
+	if err != nil {
+		t.Fatalf("streamSegment() of exactly %d full batches error = %v", cfg.MaxBatchesPerSegment, err)
+	}
+	if exported != 6 || *calls != cfg.MaxBatchesPerSegment+1 {
+		t.Errorf("expected 6 rows in %d queries, got %d rows in %d", cfg.MaxBatchesPerSegment+1, exported, *calls)
+	}
+
+	// Raising the limit exports everything
+	cfg.MaxBatchesPerSegment = 10
+	query, _ = newFakeQuerier(rows, cfg.BatchSize)
+	exported, _, err = exporter.streamSegment(seg, "test-key", &mockMultipartUploadStream{}, query)
+	if err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+	if exported != len(rows) {
+		t.Errorf("expected %d rows, got %d", len(rows), exported)
+	}
+}
+
+func TestExportSegment_SegmentTimeout(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 616161
+	setupTestTable(t, db, tenantID)
+	// Every row read through the view takes a second, so the 9 rows outlast a 1 second timeout
+	if _, err := db.Exec(`CREATE OR REPLACE VIEW fis_aggr_slow AS
+		SELECT tenantid, hash, aggr, last_modified, version FROM fis_aggr WHERE SLEEP(1) = 0`); err != nil {
+		t.Fatalf("Failed to create slow view: %v", err)
+	}
+
+	core, logs := observer.New(zap.WarnLevel)
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr_slow", MariaDBDatabase: "fis", BatchSize: 1000, SegmentTimeout: 1}
+	exp := &Exporter{db: db, config: cfg, logger: zap.New(core)}
+	uploader := newMockS3Uploader()
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "ff"}
+	start := time.Now()
+	_, err := exp.ExportSegment(seg, uploader)
+	if err == nil || !strings.Contains(err.Error(), "segment-timeout") {
+		t.Fatalf("ExportSegment() error = %v, want the -segment-timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ExportSegment() took %s, want it cancelled near the 1s timeout", elapsed)
+	}
+	for key, stream := range uploader.streams {
+		if !stream.aborted || stream.completed {
+			t.Errorf("stream %s: aborted = %v, completed = %v, want the upload aborted", key, stream.aborted, stream.completed)
+		}
+	}
+	if logs.FilterMessageSnippet("close to -segment-timeout").Len() != 1 {
+		t.Errorf("expected one warning at 80%% of the timeout, got %v", logs.All())
+	}
+}
+
+func TestExportSegment_QueryTimeout(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 626262
+	setupTestTable(t, db, tenantID)
+	// SLEEP(5) stalls the batch query long before the 60 second segment timeout
+	if _, err := db.Exec(`CREATE OR REPLACE VIEW fis_aggr_stalled AS
+		SELECT tenantid, hash, aggr, last_modified, version FROM fis_aggr WHERE SLEEP(5) = 0`); err != nil {
+		t.Fatalf("Failed to create stalled view: %v", err)
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr_stalled", MariaDBDatabase: "fis", BatchSize: 1000,
+		SegmentTimeout: 60, QueryTimeout: 1}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	uploader := newMockS3Uploader()
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "ff"}
+	start := time.Now()
+	_, err := exp.ExportSegment(seg, uploader)
+	if err == nil || !strings.Contains(err.Error(), "exceeded -query-timeout of 1s") {
+		t.Fatalf("ExportSegment() error = %v, want the per-batch -query-timeout error", err)
+	}
+	if strings.Contains(err.Error(), "segment-timeout") {
+		t.Errorf("ExportSegment() error = %v, the segment timeout didn't expire", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ExportSegment() took %s, want it cancelled near the 1s query timeout", elapsed)
+	}
+	for key, stream := range uploader.streams {
+		if !stream.aborted || stream.completed {
+			t.Errorf("stream %s: aborted = %v, completed = %v, want the upload aborted", key, stream.aborted, stream.completed)
+		}
+	}
+}
+
+func TestQueryTimeoutError(t *testing.T) {
+	exp := &Exporter{config: &config.Config{QueryTimeout: 2}}
+	seg := segment.Segment{Index: 4}
+	queryErr := errors.New("query failed: context deadline exceeded")
+
+	expired := func(parent context.Context) context.Context {
+		ctx, cancel := context.WithDeadline(parent, time.Now().Add(-time.Second))
+		t.Cleanup(cancel)
+		return ctx
+	}
+	live, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := exp.queryTimeoutError(expired(live), live, seg, "00ab", queryErr); !errors.Is(err, queryErr) ||
+		!strings.Contains(err.Error(), `segment 4 batch query after hash "00ab" exceeded -query-timeout of 2s`) {
+		t.Errorf("queryTimeoutError() with the query deadline expired = %v, want the -query-timeout error", err)
+	}
+	// The segment's own timeout is reported by ExportSegment, not as a query timeout
+	segmentCtx := expired(context.Background())
+	if err := exp.queryTimeoutError(segmentCtx, segmentCtx, seg, "", queryErr); err != queryErr {
+		t.Errorf("queryTimeoutError() with the segment deadline expired = %v, want the error unchanged", err)
+	}
+	if err := exp.queryTimeoutError(live, live, seg, "", queryErr); err != queryErr {
+		t.Errorf("queryTimeoutError() without an expired deadline = %v, want the error unchanged", err)
+	}
+}
+
+func TestWarnNearSegmentTimeout(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	exp := &Exporter{config: &config.Config{}, logger: zap.New(core)}
+	seg := segment.Segment{Index: 3, StartHex: "30", EndHex: "3f"}
+
+	// Stopped before 80% of the timeout: no warning
+	exp.warnNearSegmentTimeout(seg, time.Hour).Stop()
+	timer := exp.warnNearSegmentTimeout(seg, 20*time.Millisecond)
+	defer timer.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	warnings := logs.FilterMessageSnippet("close to -segment-timeout").All()
+	if len(warnings) != 1 || warnings[0].ContextMap()["segment"] != int64(3) {
+		t.Errorf("warnings = %v, want one for segment 3", warnings)
+	}
+	if got := exp.segmentTimeout(); got != 10*time.Minute {
+		t.Errorf("segmentTimeout() = %s, want the 10 minute default", got)
+	}
+}
+
+func TestExportSegment_EmptyBatchAnomaly(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	cfg := &config.Config{
+		TenantID:        999999,
+		TableName:       "fis_aggr",
+		BatchSize:       2,
+		MaxEmptyBatches: 3,
+	}
+	exporter := &Exporter{config: cfg, logger: logger}
+
+	// Fake querier with a broken cursor: after the first page it keeps returning
+	// the same page, so no batch yields rows past the cursor
+	page := []Row{
+		{TenantID: 999999, Hash: "00abc", Aggr: "{}"},
+		{TenantID: 999999, Hash: "01abc", Aggr: "{}"},
+	}
+	calls := 0
+	query := func(lastHash string) ([]Row, error) {
+		calls++
+		if calls > 100 {
+			t.Fatal("querier called too many times, anomaly not detected")
+		}
+		return page, nil
+	}
+
+	stream := &mockMultipartUploadStream{}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	_, _, err := exporter.streamSegment(seg, "test-key", stream, query)
+	if err == nil {
+		t.Fatal("expected error for repeated empty batches")
+	}
+	if !strings.Contains(err.Error(), "possible cursor bug") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// First page + MaxEmptyBatches empty batches
+	if calls != 1+cfg.MaxEmptyBatches {
+		t.Errorf("expected %d queries, got %d", 1+cfg.MaxEmptyBatches, calls)
+	}
+	if len(stream.parts) != 1 {
+		t.Errorf("expected only the first page to be uploaded, got %d parts", len(stream.parts))
+	}
+
+	// By default the first batch without progress fails the segment
+	cfg.MaxEmptyBatches = 0
+	calls = 0
+	if _, _, err := exporter.streamSegment(seg, "test-key", &mockMultipartUploadStream{}, query); err == nil {
+		t.Fatal("expected error for a batch making no progress")
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 queries by default, got %d", calls)
+	}
+}
+
+func TestSegmentBoundsCondition(t *testing.T) {
+	tests := []struct {
+		name          string
+		seg           segment.Segment
+		wantCondition string
+		wantArgs      []interface{}
+	}{
+		{"2-char segment", segment.Segment{StartHex: "00", EndHex: "10"}, "hash >= ? AND hash < ?", []interface{}{"00", "10"}},
+		{"2-char last segment", segment.Segment{StartHex: "f0", EndHex: "100"}, "hash >= ?", []interface{}{"f0"}},
+		{"3-char segment", segment.Segment{StartHex: "a04", EndHex: "a08"}, "hash >= ? AND hash < ?", []interface{}{"a04", "a08"}},
+		{"3-char last segment", segment.Segment{StartHex: "ffc", EndHex: "1000"}, "hash >= ?", []interface{}{"ffc"}},
+		{"3-char segment ending at 100", segment.Segment{StartHex: "0fc", EndHex: "100"}, "hash >= ? AND hash < ?", []interface{}{"0fc", "100"}},
+		{"range segment", segment.Segment{Range: &segment.Range{Column: "id", Start: 100, End: 200}}, "id >= ? AND id < ?", []interface{}{100, 200}},
+		{"last range segment", segment.Segment{Range: &segment.Range{Column: "id", Start: 200, End: 301, Last: true}}, "id >= ?", []interface{}{200}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := segmentBoundsCondition(tt.seg)
+			if condition != tt.wantCondition {
+				t.Errorf("segmentBoundsCondition() condition = %q, want %q", condition, tt.wantCondition)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.wantArgs) {
+				t.Errorf("segmentBoundsCondition() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestDistinctHashPrefixes(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 555555
+	setupTestTable(t, db, tenantID)
+	// A one-character hash cannot fill a two-character prefix and is not sampled
+	for _, hash := range []string{"00ABC999", "c0def999", "f"} {
+		if _, err := db.Exec(`INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES (?, ?, '{}')`, tenantID, hash); err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis"}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+
+	prefixes, err := exp.DistinctHashPrefixes(2)
+	if err != nil {
+		t.Fatalf("DistinctHashPrefixes() error = %v", err)
+	}
+	want := []string{"00", "1a", "3f", "40", "7f", "80", "bf", "c0", "ff"}
+	if !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("DistinctHashPrefixes(2) = %v, want %v", prefixes, want)
+	}
+}
+
+func TestCountTenantRows(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 444444
+	setupTestTable(t, db, tenantID)
+	setupTestTable(t, db, tenantID+1) // Another tenant's rows are not counted
+	if _, err := db.Exec(`INSERT INTO fis_aggr (tenantid, hash, aggr, version) VALUES (?, 'aa00', '{}', 1)`, tenantID); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis"}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	count, err := exp.CountTenantRows()
+	if err != nil {
+		t.Fatalf("CountTenantRows() error = %v", err)
+	}
+	if count != 10 {
+		t.Errorf("CountTenantRows() = %d, want the 10 seeded rows", count)
+	}
+
+	// -exclude-where rows are left out, as in the export
+	if exp.exclude, err = ParseExcludeWhere("version=1"); err != nil {
+		t.Fatalf("ParseExcludeWhere() error = %v", err)
+	}
+	if count, err = exp.CountTenantRows(); err != nil || count != 9 {
+		t.Errorf("CountTenantRows() with -exclude-where = %d, %v, want 9", count, err)
+	}
+}
+
+// TestExportSegments_Coverage checks the invariant -validate-coverage relies on: whatever the segment count,
+// the segments' row counts add up to the tenant's row count, with no row missed or exported twice at a boundary
+func TestExportSegments_Coverage(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 454545
+	setupTestTable(t, db, tenantID)
+	if _, err := db.Exec("DELETE FROM fis_aggr WHERE tenantid = ?", tenantID); err != nil {
+		t.Fatalf("Failed to clear test data: %v", err)
+	}
+
+	// Every two-character prefix, at its lowest and highest hash and as a bare prefix (e.g. "ff" itself)
+	var values []string
+	var args []interface{}
+	for prefix := 0; prefix < 256; prefix++ {
+		for _, hash := range []string{
+			fmt.Sprintf("%02x", prefix),
+			fmt.Sprintf("%02x%s", prefix, strings.Repeat("0", 30)),
+			fmt.Sprintf("%02x%s", prefix, strings.Repeat("f", 30)),
+		} {
+			values = append(values, `(?, ?, '{"test": "data"}')`)
+			args = append(args, tenantID, hash)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES "+strings.Join(values, ", "), args...); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis", BatchSize: 100, S3Prefix: "test-prefix"}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	total, err := exp.CountTenantRows()
+	if err != nil {
+		t.Fatalf("CountTenantRows() error = %v", err)
+	}
+	if total != int64(len(values)) {
+		t.Fatalf("CountTenantRows() = %d, want the %d seeded rows", total, len(values))
+	}
+
+	for _, count := range []int{1, 3, 16, 100, 256} {
+		t.Run(fmt.Sprintf("%d segments", count), func(t *testing.T) {
+			segments, err := segment.SegmentHashSpaceN(count, segment.PrefixLenForSegments(count))
+			if err != nil {
+				t.Fatalf("SegmentHashSpaceN(%d) error = %v", count, err)
+			}
+			var exported int64
+			for _, seg := range segments {
+				csvFile, err := exp.ExportSegment(seg, newMockS3Uploader())
+				if err != nil {
+					t.Fatalf("ExportSegment(%d) error = %v", seg.Index, err)
+				}
+				exported += int64(csvFile.RowCount)
+			}
+			if exported != total {
+				t.Errorf("segments exported %d rows in total, want the tenant's %d (delta %d)", exported, total, exported-total)
+			}
+		})
+	}
+}
+
+// TestExportSegments_RangeCoverage range-partitions an integer column and checks every row
+// is exported by exactly one segment, including rows on the boundaries and at the max
+func TestExportSegments_RangeCoverage(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 593593
+	setupTestTable(t, db, tenantID)
+	if _, err := db.Exec("DELETE FROM fis_aggr WHERE tenantid = ?", tenantID); err != nil {
+		t.Fatalf("Failed to clear test data: %v", err)
+	}
+
+	// version 1..100, one row each
+	var values []string
+	var args []interface{}
+	for v := 1; v <= 100; v++ {
+		values = append(values, `(?, ?, '{"test": "data"}', ?)`)
+		args = append(args, tenantID, fmt.Sprintf("%02xrange%03d", v, v), v)
+	}
+	if _, err := db.Exec("INSERT INTO fis_aggr (tenantid, hash, aggr, version) VALUES "+strings.Join(values, ", "), args...); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis", BatchSize: 7, S3Prefix: "test-prefix"}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	min, max, ok, err := exp.ColumnRange("version")
+	if err != nil || !ok || min != 1 || max != 100 {
+		t.Fatalf("ColumnRange() = %d, %d, %v, %v, want 1, 100", min, max, ok, err)
+	}
+
+	for _, count := range []int{1, 3, 16, 100} {
+		t.Run(fmt.Sprintf("%d segments", count), func(t *testing.T) {
+			segments, err := segment.SegmentRange("version", min, max, count)
+			if err != nil {
+				t.Fatalf("SegmentRange(%d) error = %v", count, err)
+			}
+			uploader := newMockS3Uploader()
+			seen := make(map[string]int)
+			for _, seg := range segments {
+				csvFile, err := exp.ExportSegment(seg, uploader)
+				if err != nil {
+					t.Fatalf("ExportSegment(%d) error = %v", seg.Index, err)
+				}
+				if !strings.Contains(csvFile.S3Key, fmt.Sprintf(".version-%d-%d.csv", seg.Range.Start, seg.Range.End)) {
+					t.Errorf("segment %d key %s, want it named by its version range", seg.Index, csvFile.S3Key)
+				}
+				records, err := csv.NewReader(bytes.NewReader(bytes.Join(uploader.streams[csvFile.S3Key].parts, nil))).ReadAll()
+				if err != nil {
+					t.Fatalf("segment %d CSV error = %v", seg.Index, err)
+				}
+				for _, record := range records {
+					if seen[record[1]] != 0 {
+						t.Errorf("row %s exported by segments %d and %d", record[1], seen[record[1]]-1, seg.Index)
+					}
+					seen[record[1]] = seg.Index + 1
+				}
+			}
+			if len(seen) != len(values) {
+				t.Errorf("segments exported %d distinct rows, want all %d", len(seen), len(values))
+			}
+		})
+	}
+
+	// A NULL in the column would be in no segment
+	if _, err := db.Exec(`INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES (?, 'ffnull', '{}')`, tenantID); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+	if _, _, _, err := exp.ColumnRange("version"); !errors.Is(err, errs.ErrConfig) {
+		t.Errorf("ColumnRange() with a NULL version error = %v, want a config error", err)
+	}
+}
+
+func TestSegmentS3Key(t *testing.T) {
+	cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", S3Prefix: "lake/raw"}
+	seg := segment.Segment{Index: 3, StartHex: "30", EndHex: "40"}
+	filename := "tenant-1234.fis_aggr.hash-30-40.csv"
+
+	exp := &Exporter{config: cfg}
+	key, err := exp.segmentS3Key(seg, filename)
+	if err != nil {
+		t.Fatalf("segmentS3Key() error = %v", err)
+	}
+	if want := "lake/raw/tenant-1234/fis_aggr/tenant-1234.fis_aggr.hash-30-40.csv"; key != want {
+		t.Errorf("default key = %q, want %q", key, want)
+	}
+
+	tmpl, err := config.ParseS3KeyTemplate("{{.Prefix}}/table={{.Table}}/tenant={{.TenantID}}/{{.StartHex}}-{{.EndHex}}.csv")
+	if err != nil {
+		t.Fatalf("ParseS3KeyTemplate() error = %v", err)
+	}
+	exp.keyTemplate = tmpl
+	key, err = exp.segmentS3Key(seg, filename)
+	if err != nil {
+		t.Fatalf("segmentS3Key() error = %v", err)
+	}
+	if want := "lake/raw/table=fis_aggr/tenant=1234/30-40.csv"; key != want {
+		t.Errorf("templated key = %q, want %q", key, want)
+	}
+
+	// -partition-by-date puts every segment of the run under the same dt= partition
+	cfg.PartitionByDate = true
+	cfg.RunDate = "2024-06-01"
+	key, err = exp.segmentS3Key(seg, filename)
+	if err != nil {
+		t.Fatalf("segmentS3Key() error = %v", err)
+	}
+	if want := "lake/raw/dt=2024-06-01/table=fis_aggr/tenant=1234/30-40.csv"; key != want {
+		t.Errorf("partitioned templated key = %q, want %q", key, want)
+	}
+	exp.keyTemplate = nil
+	key, err = exp.segmentS3Key(seg, filename)
+	if err != nil {
+		t.Fatalf("segmentS3Key() error = %v", err)
+	}
+	if want := "lake/raw/dt=2024-06-01/tenant-1234/fis_aggr/tenant-1234.fis_aggr.hash-30-40.csv"; key != want {
+		t.Errorf("partitioned default key = %q, want %q", key, want)
+	}
+}
+
+func TestRowsToCSVBytes_NullMarker(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	version := 0
+	rows := []Row{
+		{TenantID: 1, Hash: "00aa", Aggr: "{}"},
+		{TenantID: 1, Hash: "00bb", Aggr: "{}", LastModified: &modified, Version: &version},
+	}
+
+	tests := []struct {
+		marker string
+		want   string
+	}{
+		{`\N`, "1,00aa,{},\\N,\\N\n1,00bb,{},2024-01-02 03:04:05,0\n"},
+		{"NULL", "1,00aa,{},NULL,NULL\n1,00bb,{},2024-01-02 03:04:05,0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.marker, func(t *testing.T) {
+			exp := &Exporter{config: &config.Config{NullMarker: tt.marker}}
+			data, err := exp.rowsToCSVBytes(rows, false)
+			if err != nil {
+				t.Fatalf("rowsToCSVBytes() error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("rowsToCSVBytes() = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowsToCSVBytes_Delimiter(t *testing.T) {
+	rows := []Row{
+		{TenantID: 1, Hash: "00aa", Aggr: `{"a": 1, "b": 2}`},
+		{TenantID: 1, Hash: "00bb", Aggr: "x|y"},
+	}
+	exp := &Exporter{config: &config.Config{NullMarker: `\N`, CSVDelimiter: "|"}}
+
+	data, err := exp.rowsToCSVBytes(rows, true)
+	if err != nil {
+		t.Fatalf("rowsToCSVBytes() error = %v", err)
+	}
+	want := "tenantid|hash|aggr|last_modified|version\n" +
+		"1|00aa|\"{\"\"a\"\": 1, \"\"b\"\": 2}\"|\\N|\\N\n" +
+		"1|00bb|\"x|y\"|\\N|\\N\n"
+	if string(data) != want {
+		t.Errorf("rowsToCSVBytes() = %q, want %q", data, want)
+	}
+}
+
+func TestExportSegment_DuplicateHashesAcrossBatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []string // In hash order
+	}{
+		{"three rows of one hash straddle a batch boundary", []string{"00abc123", "01dup", "01dup", "01dup", "02abc123"}},
+		{"one hash fills several batches", []string{"05dup", "05dup", "05dup", "05dup", "05dup"}},
+		{"duplicates end the segment", []string{"00abc123", "0fdup", "0fdup"}},
+		{"unique hashes", []string{"00abc123", "01abc123", "02abc123", "03abc123", "04abc123"}},
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(&faultConnector{hashes: tt.hashes})
+			defer db.Close()
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 2}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+			uploader := newMockS3Uploader()
+
+			csvFile, err := exp.ExportSegment(seg, uploader)
+			if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			}
+
+			// Every row exactly once: none lost at a batch boundary, none exported twice
+			var exported []string
+			for _, part := range uploader.streams[csvFile.S3Key].parts {
+				for _, line := range strings.Split(strings.TrimSpace(string(part)), "\n") {
+					exported = append(exported, strings.Split(line, ",")[1])
+				}
+			}
+			if fmt.Sprint(exported) != fmt.Sprint(tt.hashes) || csvFile.RowCount != len(tt.hashes) {
+				t.Errorf("exported %d rows %v, want %v", csvFile.RowCount, exported, tt.hashes)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Source database flavors, detected from SELECT VERSION().
+const (
+	FlavorMariaDB = "mariadb"
+	FlavorMySQL   = "mysql"
+)
+
+// SourceVersion is the flavor and version of the source database server.
+type SourceVersion struct {
+	Flavor  string // FlavorMariaDB or FlavorMySQL
+	Version string // As returned by VERSION(), e.g. "10.11.6-MariaDB-1:10.11.6+maria~ubu2204"
+	Major   int
+	Minor   int
+	Patch   int
+}
+
+// String returns e.g. "MariaDB 10.11.6".
+func (v SourceVersion) String() string {
+	name := "MySQL"
+	if v.Flavor == FlavorMariaDB {
+		name = "MariaDB"
+	}
+	return fmt.Sprintf("%s %d.%d.%d", name, v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether the server version is major.minor.patch or later.
+func (v SourceVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// parseSourceVersion parses a VERSION() string. MariaDB includes "MariaDB" in it, anything else is MySQL.
+// The "5.5.5-" prefix MariaDB reports to old replication clients is skipped.
+func parseSourceVersion(version string) SourceVersion {
+	v := SourceVersion{Flavor: FlavorMySQL, Version: version}
+	number := version
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		v.Flavor = FlavorMariaDB
+		number = strings.TrimPrefix(number, "5.5.5-")
+	}
+	if i := strings.IndexAny(number, "-+~ "); i >= 0 {
+		number = number[:i]
+	}
+	fields := strings.SplitN(number, ".", 3)
+	parts := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, field := range fields {
+		*parts[i], _ = strconv.Atoi(field)
+	}
+	return v
+}
+
+// detectSourceVersion queries the source server's flavor and version.
+func detectSourceVersion(ctx context.Context, db *sql.DB) (SourceVersion, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return SourceVersion{}, fmt.Errorf("failed to detect source database version: %w", err)
+	}
+	return parseSourceVersion(version), nil
+}
+
+// checkSourceSupport rejects settings the source server can't honor, so they fail at startup
+// rather than with a syntax error in the first segment.
+//   - snapshot isolation starts READ ONLY transactions, which need MySQL 5.6.5 or MariaDB 10.0.
+func checkSourceSupport(v SourceVersion, isolationLevel string) error {
+	if isolationLevel == IsolationSnapshot {
+		if (v.Flavor == FlavorMySQL && !v.AtLeast(5, 6, 5)) || (v.Flavor == FlavorMariaDB && !v.AtLeast(10, 0, 0)) {
+			return fmt.Errorf("isolation-level %s needs read-only transactions, which %s does not support (MySQL 5.6.5 or MariaDB 10.0 or later)",
+				IsolationSnapshot, v)
+		}
+	}
+	return nil
+}
+
+// SourceVersion returns the source server flavor and version detected by NewExporter.
+func (e *Exporter) SourceVersion() SourceVersion {
+	return e.sourceVersion
+}
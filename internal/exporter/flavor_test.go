@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import "testing"
+
+func TestParseSourceVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		wantFlavor string
+		wantString string
+	}{
+		{"10.11.6-MariaDB-1:10.11.6+maria~ubu2204", FlavorMariaDB, "MariaDB 10.11.6"},
+		{"5.5.5-10.6.16-MariaDB-log", FlavorMariaDB, "MariaDB 10.6.16"},
+		{"8.0.36", FlavorMySQL, "MySQL 8.0.36"},
+		{"5.7.44-log", FlavorMySQL, "MySQL 5.7.44"},
+		{"8.0.mysql_aurora.3.05.2", FlavorMySQL, "MySQL 8.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v := parseSourceVersion(tt.version)
+			if v.Flavor != tt.wantFlavor || v.String() != tt.wantString || v.Version != tt.version {
+				t.Errorf("parseSourceVersion() = %+v (%s), want %s %s", v, v, tt.wantFlavor, tt.wantString)
+			}
+		})
+	}
+}
+
+func TestCheckSourceSupport(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		level   string
+		wantErr bool
+	}{
+		{"mariadb snapshot", "10.11.6-MariaDB", IsolationSnapshot, false},
+		{"mysql 8 snapshot", "8.0.36", IsolationSnapshot, false},
+		{"mysql 5.6.5 snapshot", "5.6.5", IsolationSnapshot, false},
+		{"mysql 5.5 snapshot", "5.5.62", IsolationSnapshot, true},
+		{"old mariadb snapshot", "5.5.5-5.5.68-MariaDB", IsolationSnapshot, true},
+		{"mysql 5.5 repeatable-read", "5.5.62", IsolationRepeatableRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSourceSupport(parseSourceVersion(tt.version), tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSourceSupport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
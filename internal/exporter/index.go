@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// segmentIndexColumns returns the leading columns the segment query filters and orders on
+// (WHERE <tenant column> = ? AND hash ... ORDER BY hash).
+func segmentIndexColumns(tenantColumn string) []string {
+	return []string{tenantColumn, "hash"}
+}
+
+// VerifySegmentIndex checks that the source table has an index leading with (<tenant column>, hash),
+// or (<tenant column>, <partition column>) with -partition-strategy range.
+// Without it every batch query is a full table scan. Logs a warning if no such index exists,
+// or returns an error if -require-index is set. A failed index lookup (e.g. no grant on
+// information_schema) is only an error with -require-index as well.
+func (e *Exporter) VerifySegmentIndex() error {
+	indexes, err := e.tableIndexes()
+	if err != nil {
+		if e.config.RequireIndex {
+			return err
+		}
+		e.logger.Warn("Can't check for an index supporting the segment query, continuing without",
+			zap.String("table", tableRef(e.config)),
+			zap.Error(err))
+		return nil
+	}
+
+	want := segmentIndexColumns(e.config.TenantColumnName())
+	if e.config.PartitionStrategy == "range" {
+		// Range segments filter on the partition column instead of hash
+		want = []string{e.config.TenantColumnName(), e.config.PartitionColumn}
+	}
+	indexName := findSegmentIndex(indexes, want)
+	if indexName == "" {
+		if e.config.RequireIndex {
+			return fmt.Errorf("table %s has no index leading with (%s); segment queries would scan the full table",
+				tableRef(e.config), strings.Join(want, ", "))
+		}
+		e.logger.Warn("No index supports the segment query, every batch will do a full table scan and the migration will be very slow",
+			zap.String("table", tableRef(e.config)),
+			zap.Strings("expected_leading_columns", want),
+			zap.Int("indexes_found", len(indexes)),
+			zap.String("fix", fmt.Sprintf("add an index on (%s), or use -require-index to fail instead", strings.Join(want, ", "))))
+		return nil
+	}
+
+	e.logger.Info("Found index supporting the segment query",
+		zap.String("table", tableRef(e.config)),
+		zap.String("index", indexName))
+	return nil
+}
+
+// tableIndexes returns the indexes of the source table, by name, with their columns in index order.
+func (e *Exporter) tableIndexes() (map[string][]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT INDEX_NAME, COLUMN_NAME
+		FROM information_schema.statistics
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())
+		  AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`,
+		e.config.MariaDBDatabase, e.config.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", tableRef(e.config), err)
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var indexName, columnName string
+		if err := rows.Scan(&indexName, &columnName); err != nil {
+			return nil, fmt.Errorf("failed to scan index column: %w", err)
+		}
+		indexes[indexName] = append(indexes[indexName], columnName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("index iteration error: %w", err)
+	}
+	return indexes, nil
+}
+
+// findSegmentIndex returns the name of an index whose leading columns are leading,
+// or an empty string if there is none. indexes maps index name to its columns in index order.
+func findSegmentIndex(indexes map[string][]string, leading []string) string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Deterministic choice when several indexes qualify
+
+	for _, name := range names {
+		columns := indexes[name]
+		if len(columns) < len(leading) {
+			continue
+		}
+		match := true
+		for i, want := range leading {
+			if !strings.EqualFold(columns[i], want) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return name
+		}
+	}
+	return ""
+}
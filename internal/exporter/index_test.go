@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestVerifySegmentIndex(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	// Same schema as fis_aggr, with and without the (tenantid, hash) index
+	_, err := db.Exec(`
+		CREATE TABLE fis_aggr_noindex (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			INDEX idx_hash (hash)
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table without index: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE fis_aggr_indexed (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE KEY uk_tenant_hash (tenantid, hash)
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create indexed table: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		table        string
+		requireIndex bool
+		wantErr      bool
+		wantWarning  bool
+	}{
+		{"missing index warns", "fis_aggr_noindex", false, false, true},
+		{"missing index fails with -require-index", "fis_aggr_noindex", true, true, false},
+		{"index present passes", "fis_aggr_indexed", true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			cfg := &config.Config{
+				TableName:       tt.table,
+				MariaDBDatabase: "fis",
+				RequireIndex:    tt.requireIndex,
+			}
+			exp := &Exporter{db: db, config: cfg, logger: zap.New(core)}
+
+			err := exp.VerifySegmentIndex()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySegmentIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			warnings := logs.FilterLevelExact(zap.WarnLevel).Len()
+			if tt.wantWarning && warnings == 0 {
+				t.Error("expected a warning about the missing index")
+			}
+			if !tt.wantWarning && warnings > 0 {
+				t.Errorf("expected no warnings, got %d", warnings)
+			}
+		})
+	}
+}
+
+func TestVerifySegmentIndex_LookupFails(t *testing.T) {
+	db := sql.OpenDB(&faultConnector{fault: func(int) error { return errors.New("SELECT command denied") }})
+	defer db.Close()
+
+	for _, requireIndex := range []bool{false, true} {
+		core, logs := observer.New(zap.InfoLevel)
+		cfg := &config.Config{TableName: "fis_aggr", MariaDBDatabase: "fis", RequireIndex: requireIndex}
+		exp := &Exporter{db: db, config: cfg, logger: zap.New(core)}
+
+		// Only -require-index makes a failed lookup fatal, otherwise it's a warning
+		err := exp.VerifySegmentIndex()
+		if (err != nil) != requireIndex {
+			t.Errorf("VerifySegmentIndex() with require-index %v error = %v", requireIndex, err)
+		}
+		if warned := logs.FilterLevelExact(zap.WarnLevel).Len() > 0; warned == requireIndex {
+			t.Errorf("VerifySegmentIndex() with require-index %v logged a warning = %v", requireIndex, warned)
+		}
+	}
+}
+
+func TestFindSegmentIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes map[string][]string
+		want    string
+	}{
+		{"no indexes", map[string][]string{}, ""},
+		{"exact index", map[string][]string{"uk": {"tenantid", "hash"}}, "uk"},
+		{"wider index", map[string][]string{"idx": {"tenantid", "hash", "version"}}, "idx"},
+		{"upper-case columns", map[string][]string{"idx": {"TENANTID", "HASH"}}, "idx"},
+		{"wrong order", map[string][]string{"idx": {"hash", "tenantid"}}, ""},
+		{"tenant only", map[string][]string{"idx": {"tenantid"}}, ""},
+		{"hash only", map[string][]string{"idx": {"hash"}, "PRIMARY": {"id"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findSegmentIndex(tt.indexes, segmentIndexColumns("tenantid")); got != tt.want {
+				t.Errorf("findSegmentIndex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Export transaction isolation levels accepted by -isolation-level.
+const (
+	IsolationRepeatableRead = "repeatable-read"
+	IsolationReadCommitted  = "read-committed"
+	IsolationSnapshot       = "snapshot"
+)
+
+// TxBeginner is an interface for starting the export transaction.
+// This allows mocking in tests.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// exportTxOptions maps an -isolation-level value to transaction options.
+//   - repeatable-read (default): one snapshot for the whole segment, taken at the first read.
+//     Pagination never sees concurrent inserts, but a long export holds the snapshot and
+//     can bloat the undo log on MariaDB.
+//   - read-committed: every batch sees the latest committed data, which reduces undo pressure
+//     but may include rows inserted during the export (the hash cursor keeps pagination moving).
+//   - snapshot: read-only REPEATABLE READ with the snapshot taken when the transaction starts,
+//     like START TRANSACTION WITH CONSISTENT SNAPSHOT.
+func exportTxOptions(level string) (*sql.TxOptions, error) {
+	switch level {
+	case "", IsolationRepeatableRead:
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead}, nil
+	case IsolationReadCommitted:
+		return &sql.TxOptions{Isolation: sql.LevelReadCommitted}, nil
+	case IsolationSnapshot:
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, nil
+	default:
+		return nil, fmt.Errorf("invalid isolation level %q (expected %s, %s or %s)",
+			level, IsolationRepeatableRead, IsolationReadCommitted, IsolationSnapshot)
+	}
+}
+
+// SetTxBeginner replaces the database used to start export transactions.
+func (e *Exporter) SetTxBeginner(beginner TxBeginner) {
+	e.txBeginner = beginner
+}
+
+// beginExportTx starts the segment export transaction at the configured isolation level.
+func (e *Exporter) beginExportTx(ctx context.Context) (*sql.Tx, error) {
+	opts, err := exportTxOptions(e.config.IsolationLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var beginner TxBeginner = e.db
+	if e.txBeginner != nil {
+		beginner = e.txBeginner
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if e.config.IsolationLevel == IsolationSnapshot {
+		// InnoDB creates the read view at the first consistent read, so read now
+		// to pin the snapshot to the start of the transaction
+		var one int
+		query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s = ? LIMIT 1", tableRef(e.config), e.config.TenantColumnName())
+		err := tx.QueryRowContext(ctx, query, e.config.TenantID).Scan(&one)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to establish consistent snapshot: %w", err)
+		}
+	}
+	return tx, nil
+}
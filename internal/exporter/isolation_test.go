@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// spyTxBeginner records the options passed to BeginTx and fails so no database is needed
+type spyTxBeginner struct {
+	opts  *sql.TxOptions
+	calls int
+}
+
+func (s *spyTxBeginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	s.opts = opts
+	s.calls++
+	return nil, fmt.Errorf("spy: no database")
+}
+
+func TestExportSegment_IsolationLevel(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        string
+		wantLevel    sql.IsolationLevel
+		wantReadOnly bool
+	}{
+		{"default", "", sql.LevelRepeatableRead, false},
+		{"repeatable-read", IsolationRepeatableRead, sql.LevelRepeatableRead, false},
+		{"read-committed", IsolationReadCommitted, sql.LevelReadCommitted, false},
+		{"snapshot", IsolationSnapshot, sql.LevelRepeatableRead, true},
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 100, IsolationLevel: tt.level}
+			exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+			spy := &spyTxBeginner{}
+			exp.SetTxBeginner(spy)
+
+			uploader := newMockS3Uploader()
+			if _, err := exp.ExportSegment(seg, uploader); err == nil {
+				t.Fatal("ExportSegment() should fail when the transaction cannot start")
+			}
+
+			if spy.calls != 1 {
+				t.Fatalf("BeginTx called %d times, want 1", spy.calls)
+			}
+			if spy.opts.Isolation != tt.wantLevel {
+				t.Errorf("BeginTx isolation = %v, want %v", spy.opts.Isolation, tt.wantLevel)
+			}
+			if spy.opts.ReadOnly != tt.wantReadOnly {
+				t.Errorf("BeginTx read-only = %v, want %v", spy.opts.ReadOnly, tt.wantReadOnly)
+			}
+			for key, stream := range uploader.streams {
+				if !stream.aborted {
+					t.Errorf("upload %s should be aborted when the transaction fails", key)
+				}
+			}
+		})
+	}
+}
+
+func TestExportTxOptions_Invalid(t *testing.T) {
+	for _, level := range []string{"serializable", "READ-COMMITTED", "read committed"} {
+		if _, err := exportTxOptions(level); err == nil {
+			t.Errorf("exportTxOptions(%q) should fail", level)
+		}
+	}
+}
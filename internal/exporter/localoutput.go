@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// localFileStream writes a segment's CSV parts to a local file (-output-dir).
+// It implements MultipartUploadStreamer so it can replace or accompany the S3 stream.
+// Parts are written to a .partial file that is renamed on Complete, so an aborted
+// segment never leaves a truncated CSV behind.
+type localFileStream struct {
+	path    string
+	tmpPath string
+	file    *os.File
+}
+
+func newLocalFileStream(path string) (*localFileStream, error) {
+	tmpPath := path + ".partial"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local CSV file: %w", err)
+	}
+	return &localFileStream{path: path, tmpPath: tmpPath, file: file}, nil
+}
+
+func (l *localFileStream) UploadPart(data []byte) error {
+	if _, err := l.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write local CSV file %s: %w", l.tmpPath, err)
+	}
+	return nil
+}
+
+func (l *localFileStream) Complete() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close local CSV file %s: %w", l.tmpPath, err)
+	}
+	if err := os.Rename(l.tmpPath, l.path); err != nil {
+		return fmt.Errorf("failed to finalize local CSV file %s: %w", l.path, err)
+	}
+	return nil
+}
+
+func (l *localFileStream) Abort() {
+	l.file.Close()
+	os.Remove(l.tmpPath)
+}
+
+// teeStream sends every part to several streams (S3 and a local file).
+type teeStream struct {
+	streams []MultipartUploadStreamer
+}
+
+func (t *teeStream) UploadPart(data []byte) error {
+	for _, stream := range t.streams {
+		if err := stream.UploadPart(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *teeStream) Complete() error {
+	for _, stream := range t.streams {
+		if err := stream.Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *teeStream) Abort() {
+	for _, stream := range t.streams {
+		stream.Abort()
+	}
+}
+
+// openSegmentStream opens the output stream for a segment: the S3 multipart upload, the local
+// file under -output-dir, or both. uploader is nil in local-only mode.
+// Returns the stream and the local file path (empty when not writing locally).
+func (e *Exporter) openSegmentStream(s3Key, filename string, uploader MultipartUploadStreamCreator) (MultipartUploadStreamer, string, error) {
+	var s3Stream MultipartUploadStreamer
+	if uploader != nil {
+		stream, err := uploader.NewMultipartUploadStream(s3Key)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to initiate multipart upload: %w", err)
+		}
+		s3Stream = stream
+	}
+
+	if e.config.OutputDir == "" {
+		if s3Stream == nil {
+			return nil, "", fmt.Errorf("no S3 uploader or output directory for segment output")
+		}
+		return s3Stream, "", nil
+	}
+
+	localPath := filepath.Join(e.config.OutputDir, filename)
+	local, err := newLocalFileStream(localPath)
+	if err != nil {
+		if s3Stream != nil {
+			s3Stream.Abort()
+		}
+		return nil, "", err
+	}
+	if s3Stream == nil {
+		return local, localPath, nil
+	}
+	return &teeStream{streams: []MultipartUploadStreamer{s3Stream, local}}, localPath, nil
+}
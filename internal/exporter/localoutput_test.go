@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// readCSVFile reads a local CSV file and returns its records (including the header)
+func readCSVFile(t *testing.T, path string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV file: %v", err)
+	}
+	return records
+}
+
+func TestStreamSegment_OutputDir(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+
+	var rows []Row
+	for i := 0; i < 7; i++ {
+		rows = append(rows, Row{TenantID: 1234, Hash: fmt.Sprintf("0%d%030x", i, i), Aggr: fmt.Sprintf(`{"n": %d}`, i)})
+	}
+
+	tests := []struct {
+		name     string
+		uploader *mockS3Uploader // nil for local-only output
+	}{
+		{"local only", nil},
+		{"local and S3", newMockS3Uploader()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 3, OutputDir: dir, CSVHeader: true}
+			exp := &Exporter{config: cfg, logger: logger}
+
+			var uploader MultipartUploadStreamCreator
+			if tt.uploader != nil {
+				uploader = tt.uploader
+			}
+			stream, localPath, err := exp.openSegmentStream("test-key", "segment.csv", uploader)
+			if err != nil {
+				t.Fatalf("openSegmentStream() error = %v", err)
+			}
+			if localPath != filepath.Join(dir, "segment.csv") {
+				t.Errorf("local path = %s, want it under %s", localPath, dir)
+			}
+
+			query, _ := newFakeQuerier(rows, cfg.BatchSize)
+			exported, _, err := exp.streamSegment(seg, "test-key", stream, query)
+			if err != nil {
+				t.Fatalf("streamSegment() error = %v", err)
+			}
+			if err := stream.Complete(); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if exported != len(rows) {
+				t.Errorf("expected %d exported rows, got %d", len(rows), exported)
+			}
+
+			records := readCSVFile(t, localPath)
+			if len(records) != len(rows)+1 {
+				t.Fatalf("expected header and %d rows in local CSV, got %d records", len(rows), len(records))
+			}
+			if strings.Join(records[0], ",") != "tenantid,hash,aggr,last_modified,version" {
+				t.Errorf("unexpected header: %v", records[0])
+			}
+			for i, row := range rows {
+				if records[i+1][1] != row.Hash || records[i+1][2] != row.Aggr {
+					t.Errorf("record %d = %v, want hash %s aggr %s", i+1, records[i+1], row.Hash, row.Aggr)
+				}
+			}
+			if _, err := os.Stat(localPath + ".partial"); !os.IsNotExist(err) {
+				t.Error("partial file should be renamed on Complete")
+			}
+
+			if tt.uploader != nil {
+				s3Stream := tt.uploader.streams["test-key"]
+				if !s3Stream.completed {
+					t.Error("S3 upload was not completed")
+				}
+				local, _ := os.ReadFile(localPath)
+				if !bytes.Equal(bytes.Join(s3Stream.parts, nil), local) {
+					t.Error("S3 and local output differ")
+				}
+			}
+		})
+	}
+}
+
+func TestLocalFileStream_Abort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "segment.csv")
+	stream, err := newLocalFileStream(path)
+	if err != nil {
+		t.Fatalf("newLocalFileStream() error = %v", err)
+	}
+	if err := stream.UploadPart([]byte("tenantid,hash\n")); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	stream.Abort()
+
+	for _, p := range []string{path, path + ".partial"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist after Abort", p)
+		}
+	}
+}
+
+func TestExportSegment_OutputDirLocalOnly(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	logger := zaptest.NewLogger(t)
+	cfg := &config.Config{
+		TenantID:        888888,
+		TableName:       "fis_aggr",
+		MariaDBDatabase: "fis",
+		BatchSize:       100,
+		S3Prefix:        "test-prefix",
+		OutputDir:       t.TempDir(),
+		CSVHeader:       true,
+	}
+	exp := &Exporter{db: db, config: cfg, logger: logger}
+	setupTestTable(t, db, cfg.TenantID)
+
+	totalRows := 250
+	for i := 0; i < totalRows; i++ {
+		_, err := db.Exec(`INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES (?, ?, '{"test": "data"}')`,
+			cfg.TenantID, fmt.Sprintf("00%030x", i))
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	csvFile, err := exp.ExportSegment(seg, nil)
+	if err != nil {
+		t.Fatalf("ExportSegment() error = %v", err)
+	}
+	if csvFile == nil {
+		t.Fatal("ExportSegment returned nil CSVFile")
+	}
+	if csvFile.S3Key != "" {
+		t.Errorf("expected no S3 key in local-only mode, got %s", csvFile.S3Key)
+	}
+	if filepath.Dir(csvFile.FilePath) != cfg.OutputDir {
+		t.Errorf("FilePath = %s, want a file in %s", csvFile.FilePath, cfg.OutputDir)
+	}
+	if csvFile.RowCount != totalRows {
+		t.Errorf("expected %d rows, got %d", totalRows, csvFile.RowCount)
+	}
+
+	records := readCSVFile(t, csvFile.FilePath)
+	if len(records)-1 != totalRows {
+		t.Errorf("expected %d rows in local CSV, got %d", totalRows, len(records)-1)
+	}
+}
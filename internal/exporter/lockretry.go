@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// MariaDB error numbers of lock conflicts that fail a segment query under concurrent writes.
+const (
+	errNumLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	errNumDeadlock        = 1213 // ER_LOCK_DEADLOCK
+)
+
+// lockRetryBackoff is the wait before exporting a segment again after a lock conflict;
+// it grows linearly per retry (replaced in tests).
+var lockRetryBackoff = 2 * time.Second
+
+// errLockConflict marks a segment query that failed on a deadlock or lock wait timeout.
+// The conflict is transient, so the segment can be exported again from a new transaction.
+var errLockConflict = errors.New("lock conflict")
+
+// lockConflict returns err marked with errLockConflict if it is a deadlock or lock wait timeout,
+// otherwise err unchanged.
+func lockConflict(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == errNumDeadlock || mysqlErr.Number == errNumLockWaitTimeout) {
+		return fmt.Errorf("%w: %w", errLockConflict, err)
+	}
+	return err
+}
+
+// exportSegmentWithLockRetries runs export, and runs it again while it fails on a lock conflict,
+// up to -lock-retries times with a growing backoff. Each run has its own transaction and upload:
+// the failed run's parts are aborted (or kept to be skipped with -resume-uploads), so no rows are exported twice.
+// Other errors, and lock conflicts that aren't retryable (errs.IsRetryable, e.g. cancelled), are returned right away.
+func (e *Exporter) exportSegmentWithLockRetries(seg segment.Segment, export func() (*CSVFile, error)) (*CSVFile, error) {
+	for retry := 0; ; retry++ {
+		csvFile, err := export()
+		if err == nil || !errors.Is(err, errLockConflict) || !errs.IsRetryable(err) {
+			return csvFile, err
+		}
+		if retry >= e.config.LockRetries {
+			return nil, fmt.Errorf("segment %d failed on a lock conflict after %d retries (-lock-retries): %w", seg.Index, retry, err)
+		}
+		backoff := lockRetryBackoff * time.Duration(retry+1)
+		e.logger.Warn("Segment export hit a lock conflict, exporting it again",
+			zap.Int("segment", seg.Index),
+			zap.Int("retry", retry+1),
+			zap.Int("lock_retries", e.config.LockRetries),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		time.Sleep(backoff)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// faultConnector opens connections to an in-memory fis_aggr holding hashes (in hash order, duplicates allowed),
+// failing the segment queries for which fault returns an error (fault injection wrapper)
+type faultConnector struct {
+	hashes []string
+	fault  func(query int) error // Optional - called with the 1-based number of each segment query
+
+	mu      sync.Mutex
+	queries int
+}
+
+func (c *faultConnector) Connect(context.Context) (driver.Conn, error) { return &faultConn{c: c}, nil }
+func (c *faultConnector) Driver() driver.Driver                        { return nil }
+
+type faultConn struct{ c *faultConnector }
+
+func (*faultConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (*faultConn) Close() error                              { return nil }
+func (*faultConn) Begin() (driver.Tx, error)                 { return faultTx{}, nil }
+func (*faultConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return faultTx{}, nil
+}
+
+// QueryContext serves the segment queries: the rows of one hash, or the rows after the cursor hash
+// up to the batch size (the last argument)
+func (fc *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	fc.c.mu.Lock()
+	fc.c.queries++
+	n := fc.c.queries
+	fc.c.mu.Unlock()
+	if fc.c.fault != nil {
+		if err := fc.c.fault(n); err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.Contains(query, "hash = ?") {
+		rows := &faultRows{}
+		for _, hash := range fc.c.hashes {
+			if hash == args[1].Value.(string) {
+				rows.hashes = append(rows.hashes, hash)
+			}
+		}
+		return rows, nil
+	}
+	lastHash := ""
+	if strings.Contains(query, "hash > ?") {
+		lastHash = args[1].Value.(string)
+	}
+	limit := int(args[len(args)-1].Value.(int64))
+	rows := &faultRows{}
+	for _, hash := range fc.c.hashes {
+		if hash > lastHash && len(rows.hashes) < limit {
+			rows.hashes = append(rows.hashes, hash)
+		}
+	}
+	return rows, nil
+}
+
+type faultTx struct{}
+
+func (faultTx) Commit() error   { return nil }
+func (faultTx) Rollback() error { return nil }
+
+type faultRows struct {
+	hashes []string
+	next   int
+}
+
+func (*faultRows) Columns() []string {
+	return []string{"tenantid", "hash", "aggr", "last_modified", "version"}
+}
+func (*faultRows) Close() error { return nil }
+func (r *faultRows) Next(dest []driver.Value) error {
+	if r.next == len(r.hashes) {
+		return io.EOF
+	}
+	dest[0], dest[1], dest[2], dest[3], dest[4] = int64(1234), r.hashes[r.next], `{"test": "data"}`, nil, nil
+	r.next++
+	return nil
+}
+
+// recordingUploader keeps every stream it opens, including those of failed attempts
+type recordingUploader struct {
+	streams []*mockMultipartUploadStream
+}
+
+func (u *recordingUploader) NewMultipartUploadStream(s3Key string) (MultipartUploadStreamer, error) {
+	stream := &mockMultipartUploadStream{partNumber: 1}
+	u.streams = append(u.streams, stream)
+	return stream, nil
+}
+
+func TestExportSegment_LockRetries(t *testing.T) {
+	origBackoff := lockRetryBackoff
+	lockRetryBackoff = 0
+	defer func() { lockRetryBackoff = origBackoff }()
+
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+	lockWait := &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"}
+	syntax := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	failQueries := func(err error, queries ...int) func(int) error {
+		return func(n int) error {
+			for _, q := range queries {
+				if n == q {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name        string
+		lockRetries int
+		fault       func(int) error
+		wantErr     error // nil: the segment is exported
+		wantStreams int
+	}{
+		{"deadlock on the first attempt", 3, failQueries(deadlock, 1), nil, 2},
+		{"deadlock on a later batch", 3, failQueries(deadlock, 3), nil, 2},
+		{"deadlock completing a batch's last hash", 3, failQueries(deadlock, 2), nil, 2},
+		{"lock wait timeout twice", 3, failQueries(lockWait, 1, 3), nil, 3},
+		{"retries exhausted", 2, func(int) error { return deadlock }, errLockConflict, 3},
+		{"retries disabled", 0, failQueries(deadlock, 1), errLockConflict, 1},
+		{"other errors are not retried", 3, failQueries(syntax, 1), syntax, 1},
+		{"cancelled lock conflicts are not retried", 3, failQueries(fmt.Errorf("%w: %w", context.Canceled, deadlock), 1), context.Canceled, 1},
+	}
+
+	hashes := []string{"00abc123", "01abc123", "02abc123", "03abc123", "0fabc123"}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(&faultConnector{hashes: hashes, fault: tt.fault})
+			defer db.Close()
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 2, LockRetries: tt.lockRetries}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+			uploader := &recordingUploader{}
+
+			csvFile, err := exp.ExportSegment(seg, uploader)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ExportSegment() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			} else if csvFile.RowCount != len(hashes) {
+				t.Errorf("ExportSegment() exported %d rows, want %d", csvFile.RowCount, len(hashes))
+			}
+
+			// Every failed attempt's upload is aborted; a successful retry uploads every row exactly once
+			if len(uploader.streams) != tt.wantStreams {
+				t.Fatalf("ExportSegment() opened %d uploads, want %d", len(uploader.streams), tt.wantStreams)
+			}
+			for i, stream := range uploader.streams {
+				last := i == len(uploader.streams)-1
+				if wantAborted := !last || tt.wantErr != nil; stream.aborted != wantAborted {
+					t.Errorf("upload %d aborted = %v, want %v", i+1, stream.aborted, wantAborted)
+				}
+				if last && tt.wantErr == nil {
+					var exported []string
+					for _, part := range stream.parts {
+						for _, line := range strings.Split(strings.TrimSpace(string(part)), "\n") {
+							exported = append(exported, strings.Split(line, ",")[1])
+						}
+					}
+					sort.Strings(exported)
+					if fmt.Sprint(exported) != fmt.Sprint(hashes) {
+						t.Errorf("exported hashes = %v, want %v", exported, hashes)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLockConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, true},
+		{"wrapped deadlock", fmt.Errorf("query failed: %w", &mysql.MySQLError{Number: 1213}), true},
+		{"syntax error", &mysql.MySQLError{Number: 1064}, false},
+		{"not a server error", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lockConflict(tt.err)
+			if got := errors.Is(err, errLockConflict); got != tt.want {
+				t.Errorf("lockConflict(%v) marked = %v, want %v", tt.err, got, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("lockConflict(%v) = %v, want the original error wrapped", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// -mask-aggr modes.
+const (
+	MaskPlaceholder = "placeholder"
+	MaskSHA256      = "sha256"
+)
+
+// AggrMaskPlaceholder is the aggr value written by -mask-aggr placeholder.
+// It is valid JSON so the masked column still parses like the real aggr.
+const AggrMaskPlaceholder = `{"masked":true}`
+
+// AggrMasker replaces a row's aggr value in the exported CSV (-mask-aggr), so copies for
+// non-prod environments don't carry PII. Tenant, hash and metadata columns are not masked.
+// Other masking functions can be plugged in with SetAggrMasker.
+type AggrMasker func(aggr string) string
+
+// ParseAggrMasker returns the masker for a -mask-aggr mode, or nil for "" (aggr exported unchanged).
+//   - placeholder: every aggr becomes AggrMaskPlaceholder.
+//   - sha256: aggr becomes {"sha256":"<hex>"}, so equal values stay equal across rows and runs.
+func ParseAggrMasker(mode string) (AggrMasker, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case MaskPlaceholder:
+		return maskPlaceholder, nil
+	case MaskSHA256:
+		return maskSHA256, nil
+	default:
+		return nil, fmt.Errorf("invalid mask-aggr %q (expected %s or %s)", mode, MaskPlaceholder, MaskSHA256)
+	}
+}
+
+func maskPlaceholder(string) string {
+	return AggrMaskPlaceholder
+}
+
+func maskSHA256(aggr string) string {
+	sum := sha256.Sum256([]byte(aggr))
+	return `{"sha256":"` + hex.EncodeToString(sum[:]) + `"}`
+}
+
+// SetAggrMasker replaces the aggr masker configured by -mask-aggr (nil exports aggr unchanged).
+func (e *Exporter) SetAggrMasker(masker AggrMasker) {
+	e.maskAggr = masker
+}
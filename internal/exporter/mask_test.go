@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+)
+
+func TestParseAggrMasker(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantNil bool
+		wantErr bool
+	}{
+		{mode: "", wantNil: true},
+		{mode: MaskPlaceholder},
+		{mode: MaskSHA256},
+		{mode: "md5", wantNil: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			masker, err := ParseAggrMasker(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAggrMasker(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if (masker == nil) != tt.wantNil {
+				t.Errorf("ParseAggrMasker(%q) nil = %v, want %v", tt.mode, masker == nil, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRowsToCSVBytes_MaskAggr(t *testing.T) {
+	secret := `{"email": "alice@example.com", "path": "/finance/q3.xlsx"}`
+	rows := []Row{
+		{TenantID: 1, Hash: "00aa", Aggr: secret},
+		{TenantID: 1, Hash: "00bb", Aggr: secret},
+		{TenantID: 1, Hash: "00cc", Aggr: `{"other": true}`},
+	}
+
+	for _, mode := range []string{MaskPlaceholder, MaskSHA256} {
+		t.Run(mode, func(t *testing.T) {
+			masker, err := ParseAggrMasker(mode)
+			if err != nil {
+				t.Fatalf("ParseAggrMasker() error = %v", err)
+			}
+			exp := &Exporter{config: &config.Config{NullMarker: `\N`}, maskAggr: masker}
+			data, err := exp.rowsToCSVBytes(rows, true)
+			if err != nil {
+				t.Fatalf("rowsToCSVBytes() error = %v", err)
+			}
+			if bytes.Contains(data, []byte("alice@example.com")) || bytes.Contains(data, []byte("finance")) {
+				t.Fatalf("masked CSV contains the original aggr: %q", data)
+			}
+
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			if err != nil {
+				t.Fatalf("masked CSV does not parse: %v", err)
+			}
+			if len(records) != len(rows)+1 {
+				t.Fatalf("got %d records, want header and %d rows", len(records), len(rows))
+			}
+			for i, record := range records[1:] {
+				if len(record) != 5 {
+					t.Fatalf("row %d has %d columns, want 5", i, len(record))
+				}
+				if record[0] != "1" || record[1] != rows[i].Hash {
+					t.Errorf("row %d tenantid/hash = %s/%s, want 1/%s", i, record[0], record[1], rows[i].Hash)
+				}
+				if !json.Valid([]byte(record[2])) {
+					t.Errorf("row %d masked aggr %q is not JSON", i, record[2])
+				}
+			}
+
+			// sha256 keeps equal values equal; placeholder makes every value equal
+			sameAsDuplicate := records[1][2] == records[2][2]
+			sameAsOther := records[1][2] == records[3][2]
+			if !sameAsDuplicate || sameAsOther != (mode == MaskPlaceholder) {
+				t.Errorf("masked aggr values = %v", []string{records[1][2], records[2][2], records[3][2]})
+			}
+		})
+	}
+}
+
+func TestRowsToCSVBytes_CustomMasker(t *testing.T) {
+	exp := &Exporter{config: &config.Config{NullMarker: `\N`}}
+	exp.SetAggrMasker(func(aggr string) string { return strings.ToUpper(aggr) })
+
+	data, err := exp.rowsToCSVBytes([]Row{{TenantID: 1, Hash: "00aa", Aggr: "abc"}}, false)
+	if err != nil {
+		t.Fatalf("rowsToCSVBytes() error = %v", err)
+	}
+	if want := "1,00aa,ABC,\\N,\\N\n"; string(data) != want {
+		t.Errorf("rowsToCSVBytes() = %q, want %q", data, want)
+	}
+}
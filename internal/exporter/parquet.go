@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// -format values.
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// ParquetSchema returns the schema of the exported Parquet files of table: the five columns of the CSV,
+// with last_modified (a UTC timestamp in milliseconds) and version nullable. Columns are stored in name order.
+func ParquetSchema(table, tenantColumn string) *parquet.Schema {
+	return parquet.NewSchema(table, parquet.Group{
+		tenantColumn:    parquet.Int(64),
+		"hash":          parquet.String(),
+		"aggr":          parquet.String(),
+		"last_modified": parquet.Optional(parquet.Timestamp(parquet.Millisecond)),
+		"version":       parquet.Optional(parquet.Int(32)),
+	})
+}
+
+// parquetSegmentWriter buffers the rows of a segment as one Snappy-compressed Parquet file (-format parquet)
+// and uploads it as a single part on flush. Unlike CSV, Parquet can't be cut into parts as it goes:
+// the footer with the schema and row group offsets is only known once every row is written.
+type parquetSegmentWriter struct {
+	exporter *Exporter
+	stream   MultipartUploadStreamer
+	buf      bytes.Buffer
+	writer   *parquet.Writer
+	rows     int
+}
+
+// newParquetSegmentWriter returns a segment writer uploading the Parquet file to stream.
+func (e *Exporter) newParquetSegmentWriter(stream MultipartUploadStreamer) *parquetSegmentWriter {
+	p := &parquetSegmentWriter{exporter: e, stream: stream}
+	p.writer = parquet.NewWriter(&p.buf, ParquetSchema(e.config.TableName, e.config.TenantColumnName()), parquet.Compression(&parquet.Snappy))
+	return p
+}
+
+// writeRows appends rows to the buffered Parquet file.
+func (p *parquetSegmentWriter) writeRows(rows []Row) error {
+	if err := p.exporter.rowsToParquet(p.writer, rows); err != nil {
+		return err
+	}
+	p.rows += len(rows)
+	return nil
+}
+
+// flush writes the Parquet footer and uploads the file, if any rows were written.
+func (p *parquetSegmentWriter) flush() error {
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %w", err)
+	}
+	if p.rows == 0 {
+		return nil
+	}
+	if err := p.stream.UploadPart(p.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to upload Parquet file: %w", err)
+	}
+	return nil
+}
+
+// rowsToParquet writes rows to a Parquet writer, with aggr masked if -mask-aggr is set.
+func (e *Exporter) rowsToParquet(writer *parquet.Writer, rows []Row) error {
+	tenantColumn := e.config.TenantColumnName()
+	for _, row := range rows {
+		record := map[string]interface{}{
+			tenantColumn:    int64(row.TenantID),
+			"hash":          row.Hash,
+			"aggr":          e.exportedAggr(row),
+			"last_modified": nil,
+			"version":       nil,
+		}
+		if row.LastModified != nil {
+			record["last_modified"] = row.LastModified.UnixMilli()
+		}
+		if row.Version != nil {
+			record["version"] = int32(*row.Version)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write Parquet row: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/parquet-go/parquet-go"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStreamSegment_ParquetRoundTrip(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	version := 7
+	var rows []Row
+	for i := 0; i < 250; i++ {
+		row := Row{TenantID: 1, Hash: fmt.Sprintf("00%030x", i), Aggr: fmt.Sprintf(`{"app":"box","n":%d}`, i)}
+		if i%2 == 0 {
+			row.LastModified = &modified
+			row.Version = &version
+		}
+		rows = append(rows, row)
+	}
+
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 100, Format: FormatParquet, TenantColumn: "tenant_id"}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	query, _ := newFakeQuerier(rows, cfg.BatchSize)
+	stream := &mockMultipartUploadStream{}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	exported, _, err := exp.streamSegment(seg, "test-key", stream, query)
+	if err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+	if exported != len(rows) {
+		t.Errorf("streamSegment() exported %d rows, want %d", exported, len(rows))
+	}
+	// The segment is buffered and uploaded as one file, not one part per batch
+	if len(stream.parts) != 1 {
+		t.Fatalf("got %d parts, want the whole Parquet file as one part", len(stream.parts))
+	}
+
+	data := stream.parts[0]
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("uploaded part is not a Parquet file: %v", err)
+	}
+	if file.NumRows() != int64(len(rows)) {
+		t.Errorf("Parquet file has %d rows, want %d", file.NumRows(), len(rows))
+	}
+	columns := map[string]string{}
+	for _, field := range file.Schema().Fields() {
+		columns[field.Name()] = fmt.Sprintf("%s optional=%v", field.Type(), field.Optional())
+	}
+	wantColumns := map[string]string{
+		"tenant_id":     "INT(64,true) optional=false",
+		"hash":          "STRING optional=false",
+		"aggr":          "STRING optional=false",
+		"last_modified": "TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS) optional=true",
+		"version":       "INT(32,true) optional=true",
+	}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("Parquet schema = %v, want %v", columns, wantColumns)
+	}
+
+	reader := parquet.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	for i, want := range rows {
+		record := map[string]interface{}{}
+		if err := reader.Read(&record); err != nil {
+			t.Fatalf("failed to read row %d: %v", i, err)
+		}
+		wantRecord := map[string]interface{}{
+			"tenant_id":     int64(1),
+			"hash":          want.Hash,
+			"aggr":          want.Aggr,
+			"last_modified": nil,
+			"version":       nil,
+		}
+		if want.LastModified != nil {
+			wantRecord["last_modified"] = modified.UnixMilli()
+			wantRecord["version"] = int32(version)
+		}
+		if !reflect.DeepEqual(record, wantRecord) {
+			t.Fatalf("row %d = %v, want %v", i, record, wantRecord)
+		}
+	}
+	if err := reader.Read(&map[string]interface{}{}); err != io.EOF {
+		t.Errorf("reading past the last row error = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamSegment_ParquetMasked(t *testing.T) {
+	masker, err := ParseAggrMasker("placeholder")
+	if err != nil {
+		t.Fatalf("ParseAggrMasker() error = %v", err)
+	}
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 100, Format: FormatParquet}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t), maskAggr: masker}
+	query, _ := newFakeQuerier([]Row{{TenantID: 1, Hash: "0001", Aggr: `{"user":"alice@example.com"}`}}, cfg.BatchSize)
+	stream := &mockMultipartUploadStream{}
+	if _, _, err := exp.streamSegment(segment.Segment{StartHex: "00", EndHex: "01"}, "test-key", stream, query); err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+
+	type record struct {
+		TenantID int64  `parquet:"tenantid"`
+		Aggr     string `parquet:"aggr"`
+	}
+	got, err := parquet.Read[record](bytes.NewReader(stream.parts[0]), int64(len(stream.parts[0])))
+	if err != nil {
+		t.Fatalf("failed to read Parquet file: %v", err)
+	}
+	if len(got) != 1 || got[0].TenantID != 1 || got[0].Aggr != masker(`{"user":"alice@example.com"}`) {
+		t.Errorf("Parquet rows = %+v, want one row with the masked aggr under the default tenant column", got)
+	}
+}
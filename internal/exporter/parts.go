@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+)
+
+// csvPartWriter serializes rows to CSV and uploads a multipart part each time about
+// -batch-bytes have accumulated, so part sizes don't depend on how large aggr values are.
+// Parts end on a row boundary: a part is at most one row larger than the target.
+type csvPartWriter struct {
+	exporter *Exporter
+	stream   MultipartUploadStreamer
+	target   int
+	buf      bytes.Buffer
+	writer   *csv.Writer
+}
+
+// newCSVPartWriter returns a part writer uploading parts of about target bytes to stream.
+func (e *Exporter) newCSVPartWriter(stream MultipartUploadStreamer, target int) *csvPartWriter {
+	p := &csvPartWriter{exporter: e, stream: stream, target: target}
+	p.writer = csv.NewWriter(&p.buf)
+	p.writer.Comma = e.config.CSVComma()
+	return p
+}
+
+// writeRows appends rows (after the header, if includeHeader), uploading a part whenever the target is reached.
+func (p *csvPartWriter) writeRows(rows []Row, includeHeader bool) error {
+	if includeHeader {
+		if err := p.writer.Write(ExportColumns(p.exporter.config.TenantColumnName(), p.exporter.config.ExcludedColumns())); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	for _, row := range rows {
+		if err := p.writer.Write(p.exporter.csvRecord(row)); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+		p.writer.Flush()
+		if err := p.writer.Error(); err != nil {
+			return fmt.Errorf("failed to flush CSV: %w", err)
+		}
+		if p.buf.Len() >= p.target {
+			if err := p.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush uploads the buffered rows as a part, if there are any.
+func (p *csvPartWriter) flush() error {
+	p.writer.Flush()
+	if err := p.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	part := p.buf.Bytes()
+	p.buf = bytes.Buffer{} // The stream may keep the uploaded part, start a new buffer
+	if err := p.stream.UploadPart(part); err != nil {
+		return fmt.Errorf("failed to upload batch as multipart part: %w", err)
+	}
+	return nil
+}
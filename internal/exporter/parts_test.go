@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStreamSegment_BatchBytes(t *testing.T) {
+	const target = 4096
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", BatchSize: 50, BatchBytes: target, CSVHeader: true, NullMarker: `\N`}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+
+	// aggr sizes vary from a few bytes to 2KB, as they do in fis_aggr
+	var rows []Row
+	maxRow := 0
+	for i := 0; i < 500; i++ {
+		aggr := fmt.Sprintf(`{"v":"%s"}`, strings.Repeat("x", (i*7919)%2000))
+		rows = append(rows, Row{TenantID: 1, Hash: fmt.Sprintf("00%030x", i), Aggr: aggr})
+		if len(aggr) > maxRow {
+			maxRow = len(aggr)
+		}
+	}
+	query, _ := newFakeQuerier(rows, cfg.BatchSize)
+	stream := &mockMultipartUploadStream{}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	exported, _, err := exp.streamSegment(seg, "test-key", stream, query)
+	if err != nil {
+		t.Fatalf("streamSegment() error = %v", err)
+	}
+	if exported != len(rows) {
+		t.Errorf("exported %d rows, want %d", exported, len(rows))
+	}
+
+	// Every part but the last is at the target plus at most one row (CSV adds a few bytes per row)
+	if len(stream.parts) < 2 {
+		t.Fatalf("got %d parts, want the CSV split by size", len(stream.parts))
+	}
+	for i, part := range stream.parts[:len(stream.parts)-1] {
+		if len(part) < target || len(part) > target+maxRow+64 {
+			t.Errorf("part %d is %d bytes, want between %d and %d", i+1, len(part), target, target+maxRow+64)
+		}
+		if !bytes.HasSuffix(part, []byte("\n")) {
+			t.Errorf("part %d does not end on a row boundary", i+1)
+		}
+	}
+	if last := stream.parts[len(stream.parts)-1]; len(last) == 0 || len(last) > target+maxRow+64 {
+		t.Errorf("last part is %d bytes", len(last))
+	}
+
+	// The parts put together are the same CSV as without -batch-bytes
+	want, err := exp.rowsToCSVBytes(rows, true)
+	if err != nil {
+		t.Fatalf("rowsToCSVBytes() error = %v", err)
+	}
+	if got := bytes.Join(stream.parts, nil); !bytes.Equal(got, want) {
+		t.Errorf("joined parts differ from the CSV (%d bytes, want %d)", len(got), len(want))
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"text/template"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// PlannedCSVFiles returns the CSV files an export of segments would write, with their S3 keys and no rows,
+// without connecting to the source (-preview-sql). With -single-file that is one file spanning all segments.
+func PlannedCSVFiles(cfg *config.Config, segments []segment.Segment) ([]CSVFile, error) {
+	codec, err := ParseCodec(cfg.Compress, cfg.CompressLevel)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, err)
+	}
+	var keyTemplate *template.Template
+	if cfg.S3KeyTemplate != "" {
+		if keyTemplate, err = config.ParseS3KeyTemplate(cfg.S3KeyTemplate); err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, err)
+		}
+	}
+	e := &Exporter{config: cfg, codec: codec, keyTemplate: keyTemplate}
+
+	if cfg.SingleFile {
+		if len(segments) == 0 {
+			return nil, nil
+		}
+		_, span := singleFileSpan(segments)
+		s3Key, err := e.segmentS3Key(span, e.singleFilename())
+		if err != nil {
+			return nil, err
+		}
+		return []CSVFile{{S3Key: s3Key, Segment: span}}, nil
+	}
+
+	csvFiles := make([]CSVFile, 0, len(segments))
+	for _, seg := range segments {
+		s3Key, err := e.segmentS3Key(seg, e.segmentFilename(seg))
+		if err != nil {
+			return nil, err
+		}
+		csvFiles = append(csvFiles, CSVFile{S3Key: s3Key, Segment: seg})
+	}
+	return csvFiles, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakePoolDriver hands out connections that support nothing but being pooled
+type fakePoolDriver struct{}
+
+func (fakePoolDriver) Open(name string) (driver.Conn, error) { return fakePoolConn{}, nil }
+
+type fakePoolConn struct{}
+
+func (fakePoolConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (fakePoolConn) Close() error              { return nil }
+func (fakePoolConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("exporter-fakepool", fakePoolDriver{})
+}
+
+func TestConfigurePool(t *testing.T) {
+	db, err := sql.Open("exporter-fakepool", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := &config.Config{DBMaxOpenConns: 4, DBMaxIdleConns: 2, DBConnMaxLifetime: 1, MaxParallelSegs: 8}
+	configurePool(db, cfg, zap.New(core))
+
+	if got := db.Stats().MaxOpenConnections; got != 4 {
+		t.Errorf("MaxOpenConnections = %d, want 4", got)
+	}
+	if logs.FilterLevelExact(zap.WarnLevel).Len() != 1 {
+		t.Error("expected a warning that db-max-open-conns is below max-parallel-segments")
+	}
+
+	// Hold every allowed connection, one more must wait
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 4; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("db.Conn() error = %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.Conn(waitCtx); err == nil {
+		t.Fatal("db.Conn() should block beyond db-max-open-conns")
+	}
+	if stats := db.Stats(); stats.OpenConnections != 4 || stats.WaitCount == 0 {
+		t.Errorf("stats = %+v, want 4 open connections and a wait", stats)
+	}
+
+	// Released connections beyond db-max-idle-conns are closed
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if stats := db.Stats(); stats.Idle != 2 || stats.MaxIdleClosed != 2 {
+		t.Errorf("stats = %+v, want 2 idle and 2 closed by the idle limit", stats)
+	}
+
+	// Idle connections older than db-conn-max-lifetime are not reused
+	time.Sleep(1100 * time.Millisecond)
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn() error = %v", err)
+	}
+	conn.Close()
+	if stats := db.Stats(); stats.MaxLifetimeClosed == 0 {
+		t.Errorf("stats = %+v, want connections closed by the lifetime limit", stats)
+	}
+}
+
+func TestConfigurePool_Defaults(t *testing.T) {
+	db, err := sql.Open("exporter-fakepool", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	configurePool(db, &config.Config{MaxParallelSegs: 8}, zap.New(core))
+
+	if got := db.Stats().MaxOpenConnections; got != 0 {
+		t.Errorf("MaxOpenConnections = %d, want 0 (unlimited)", got)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no warnings with default pool settings, got %d", logs.Len())
+	}
+}
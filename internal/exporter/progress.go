@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// SegmentProgress is how far the export of a segment got, recorded every -checkpoint-interval batches
+// so a resumed run continues the segment after the last recorded batch instead of from its start.
+type SegmentProgress struct {
+	LastHash string `json:"last_hash"` // Cursor: the last hash read from the segment
+	Parts    int32  `json:"parts"`     // Parts uploaded, holding every row up to LastHash
+	Rows     int    `json:"rows"`      // Rows in those parts
+	Digest   []byte `json:"digest"`    // RowDigest state over those rows
+}
+
+// ProgressRecorder records the progress of segment exports and returns the progress a previous run recorded.
+// This allows mocking in tests.
+type ProgressRecorder interface {
+	SegmentProgress(seg segment.Segment) *SegmentProgress // nil if none
+	RecordProgress(seg segment.Segment, progress SegmentProgress) error
+}
+
+// SetProgressRecorder sets where segment progress is recorded every -checkpoint-interval batches
+// and read back from when a segment starts (nil exports every segment from its start).
+func (e *Exporter) SetProgressRecorder(recorder ProgressRecorder) {
+	e.progress = recorder
+}
+
+// PartKeeper is implemented by streams that continue a resumed multipart upload after its first n parts,
+// as a previous run uploaded them (see s3.MultipartUploadStream.KeepParts).
+type PartKeeper interface {
+	KeepParts(n int32) error
+}
+
+// KeepParts keeps the first n parts of stream, which must be a PartKeeper. Stream wrappers pass it on with KeepParts.
+func KeepParts(stream MultipartUploadStreamer, n int32) error {
+	keeper, ok := stream.(PartKeeper)
+	if !ok {
+		return fmt.Errorf("%T can't continue a previous upload", stream)
+	}
+	return keeper.KeepParts(n)
+}
+
+// resumeSegment returns the progress a previous run recorded for seg and its digest so far,
+// keeping the parts it covers in stream, or nil to export the segment from its start.
+// Progress that can't be continued (e.g. its upload expired) is logged and ignored.
+func (e *Exporter) resumeSegment(seg segment.Segment, stream MultipartUploadStreamer) (*SegmentProgress, *RowDigest) {
+	if e.progress == nil {
+		return nil, nil
+	}
+	progress := e.progress.SegmentProgress(seg)
+	if progress == nil {
+		return nil, nil
+	}
+	digest := NewRowDigest()
+	err := digest.UnmarshalBinary(progress.Digest)
+	if err == nil {
+		err = KeepParts(stream, progress.Parts)
+	}
+	if err != nil {
+		e.logger.Warn("Can't continue segment from its checkpoint, exporting it from the start",
+			zap.Int("segment", seg.Index),
+			zap.String("last_hash", progress.LastHash),
+			zap.Error(err))
+		return nil, nil
+	}
+	e.logger.Info("Continuing segment from checkpoint",
+		zap.Int("segment", seg.Index),
+		zap.String("last_hash", progress.LastHash),
+		zap.Int32("parts", progress.Parts),
+		zap.Int("rows", progress.Rows))
+	return progress, digest
+}
+
+// recordProgress records the progress of seg. A failed write only costs exporting the segment
+// from an earlier batch on resume, so it is logged, not returned.
+func (e *Exporter) recordProgress(seg segment.Segment, lastHash string, parts int32, rows int, digest *RowDigest) {
+	state, err := digest.MarshalBinary()
+	if err == nil {
+		err = e.progress.RecordProgress(seg, SegmentProgress{LastHash: lastHash, Parts: parts, Rows: rows, Digest: state})
+	}
+	if err != nil {
+		e.logger.Warn("Failed to record segment progress", zap.Int("segment", seg.Index), zap.Error(err))
+	}
+}
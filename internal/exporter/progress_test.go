@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// memoryProgress keeps the recorded progress of each segment
+type memoryProgress struct {
+	progress map[int]SegmentProgress
+	records  int
+}
+
+func (m *memoryProgress) SegmentProgress(seg segment.Segment) *SegmentProgress {
+	progress, ok := m.progress[seg.Index]
+	if !ok {
+		return nil
+	}
+	return &progress
+}
+
+func (m *memoryProgress) RecordProgress(seg segment.Segment, progress SegmentProgress) error {
+	m.progress[seg.Index] = progress
+	m.records++
+	return nil
+}
+
+// keepingStream is a mock stream continuing a previous upload of kept parts
+type keepingStream struct {
+	mockMultipartUploadStream
+	kept int32
+}
+
+func (k *keepingStream) KeepParts(n int32) error {
+	k.kept = n
+	return nil
+}
+
+func TestStreamSegment_ResumeFromCheckpoint(t *testing.T) {
+	var rows []Row
+	for i := 0; i < 10; i++ {
+		rows = append(rows, Row{TenantID: 1, Hash: fmt.Sprintf("00%02x", i), Aggr: fmt.Sprintf(`{"n":%d}`, i)})
+	}
+	seg := segment.Segment{Index: 3, StartHex: "00", EndHex: "01"}
+	queryErr := errors.New("connection lost")
+
+	// query pages through rows 2 at a time, recording the cursors, and fails once failAt rows were returned
+	var cursors []string
+	query := func(failAt int) batchQueryFunc {
+		return func(lastHash string) ([]Row, error) {
+			cursors = append(cursors, lastHash)
+			start := 0
+			for start < len(rows) && rows[start].Hash <= lastHash {
+				start++
+			}
+			if start >= failAt {
+				return nil, queryErr
+			}
+			return rows[start:min(start+2, len(rows))], nil
+		}
+	}
+	cfg := &config.Config{BatchSize: 2, CSVHeader: true, CheckpointInterval: 2}
+	recorder := &memoryProgress{progress: map[int]SegmentProgress{}}
+
+	// First run: 3 batches upload, then the segment fails. Progress was recorded after batch 2
+	first := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	first.SetProgressRecorder(recorder)
+	firstStream := &keepingStream{}
+	if _, _, err := first.streamSegment(seg, "test-key", &countingStream{stream: firstStream}, query(6)); !errors.Is(err, queryErr) {
+		t.Fatalf("first streamSegment() error = %v, want the query failure", err)
+	}
+	progress := recorder.progress[seg.Index]
+	if recorder.records != 1 || progress.LastHash != rows[3].Hash || progress.Parts != 2 || progress.Rows != 4 {
+		t.Fatalf("recorded %d times, progress = %+v, want once after 4 rows in 2 parts up to %s", recorder.records, progress, rows[3].Hash)
+	}
+
+	// Resumed run: the 2 recorded parts are kept, and the segment continues after the recorded cursor
+	cursors = nil
+	resumed := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	resumed.SetProgressRecorder(recorder)
+	stream := &keepingStream{}
+	exported, checksum, err := resumed.streamSegment(seg, "test-key", &countingStream{stream: stream}, query(len(rows)+1))
+	if err != nil {
+		t.Fatalf("resumed streamSegment() error = %v", err)
+	}
+	if stream.kept != 2 {
+		t.Errorf("kept %d parts, want 2", stream.kept)
+	}
+	if len(cursors) == 0 || cursors[0] != rows[3].Hash {
+		t.Errorf("resumed query cursors = %v, want to start after %s", cursors, rows[3].Hash)
+	}
+	var data strings.Builder
+	for _, part := range stream.parts {
+		data.Write(part)
+	}
+	for _, row := range rows[:4] {
+		if strings.Contains(data.String(), row.Hash) {
+			t.Errorf("row %s was exported again", row.Hash)
+		}
+	}
+	if strings.Contains(data.String(), "tenantid") {
+		t.Error("resumed segment repeated the CSV header")
+	}
+	if len(stream.parts) != 3 || exported != len(rows) {
+		t.Errorf("resumed run uploaded %d parts, exported %d rows, want 3 parts and %d rows", len(stream.parts), exported, len(rows))
+	}
+
+	// The digest covers the whole segment, as if it was never interrupted
+	digest := NewRowDigest()
+	for _, row := range rows {
+		digest.Add(row.Hash, row.Aggr)
+	}
+	if checksum != digest.Sum() {
+		t.Errorf("checksum = %s, want the digest of all %d rows %s", checksum, len(rows), digest.Sum())
+	}
+
+	// Progress that can't be continued exports the segment from its start
+	plain := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	plain.SetProgressRecorder(recorder)
+	cursors = nil
+	noKeeper := &mockMultipartUploadStream{}
+	if exported, _, err := plain.streamSegment(seg, "test-key", noKeeper, query(len(rows)+1)); err != nil || exported != len(rows) {
+		t.Fatalf("streamSegment() without PartKeeper = %d, %v, want all %d rows", exported, err, len(rows))
+	}
+	if cursors[0] != "" {
+		t.Errorf("first cursor = %q, want the segment start", cursors[0])
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"go.uber.org/zap"
+)
+
+// errNotReplica is returned when SHOW SLAVE STATUS is empty: -mariadb-replica-host is not a replica.
+var errNotReplica = errors.New("replica host returned no SHOW SLAVE STATUS (not a replica?)")
+
+// replicaLag returns Seconds_Behind_Master from SHOW SLAVE STATUS, or nil while replication is stopped (NULL).
+// MySQL 8.0.22+ also names the column Seconds_Behind_Source, which is read too.
+func replicaLag(ctx context.Context, db *sql.DB) (*time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read replica status: %w", err)
+		}
+		return nil, errNotReplica
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to read replica status: %w", err)
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Master" && column != "Seconds_Behind_Source" {
+			continue
+		}
+		if !values[i].Valid {
+			return nil, nil
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", column, values[i].String, err)
+		}
+		lag := time.Duration(seconds) * time.Second
+		return &lag, nil
+	}
+	return nil, fmt.Errorf("replica status has no Seconds_Behind_Master column")
+}
+
+// checkReplicaLag reports an error if lag exceeds maxLag, or is unknown because replication is stopped.
+func checkReplicaLag(lag *time.Duration, maxLag time.Duration) error {
+	if lag == nil {
+		return fmt.Errorf("replica lag is unknown (replication stopped), -replica-max-lag is %s", maxLag)
+	}
+	if *lag > maxLag {
+		return fmt.Errorf("replica lags %s behind the primary, more than -replica-max-lag %s", *lag, maxLag)
+	}
+	return nil
+}
+
+// checkReplica checks the lag of the read replica the exporter connected to (-replica-max-lag).
+// With -replica-lag-action warn a lagging replica is only logged; otherwise it fails the export,
+// retryably since the replica may catch up.
+func (e *Exporter) checkReplica(ctx context.Context) error {
+	lag, err := replicaLag(ctx, e.db)
+	if errors.Is(err, errNotReplica) {
+		return errs.Wrap(errs.ErrConfig, err)
+	}
+	if err != nil {
+		return errs.Wrap(errs.ErrSourceQuery, err)
+	}
+	if err := checkReplicaLag(lag, e.config.ReplicaMaxLag); err != nil {
+		if e.config.ReplicaLagAction == "warn" {
+			e.logger.Warn("Read replica lags, exporting anyway (-replica-lag-action warn)", zap.Error(err))
+			return nil
+		}
+		return errs.Wrap(errs.ErrSourceConnect, err)
+	}
+	e.logger.Info("Read replica lag is within -replica-max-lag",
+		zap.Duration("lag", *lag),
+		zap.Duration("max_lag", e.config.ReplicaMaxLag))
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCheckReplicaLag(t *testing.T) {
+	lag := func(d time.Duration) *time.Duration { return &d }
+	tests := []struct {
+		name    string
+		lag     *time.Duration
+		wantErr bool
+	}{
+		{name: "in sync", lag: lag(0)},
+		{name: "at the limit", lag: lag(30 * time.Second)},
+		{name: "over the limit", lag: lag(31 * time.Second), wantErr: true},
+		{name: "replication stopped", lag: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkReplicaLag(tt.lag, 30*time.Second); (err != nil) != tt.wantErr {
+				t.Errorf("checkReplicaLag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewExporter_ReplicaHost(t *testing.T) {
+	_, cleanup, connStr := setupTestDB(t)
+	defer cleanup()
+
+	parts := strings.Split(connStr, "@tcp(")
+	if len(parts) < 2 {
+		t.Fatalf("Invalid connection string format: %s", connStr)
+	}
+	hostPortPart := strings.Split(parts[1], ")/")[0]
+
+	// Nothing listens on the primary, so the exporter can only connect through the replica host
+	cfg := &config.Config{
+		TenantID:           1,
+		TableName:          "fis_aggr",
+		MariaDBDatabase:    "fis",
+		MariaDBHost:        "127.0.0.1:1",
+		MariaDBReplicaHost: hostPortPart,
+		MariaDBUser:        "root",
+		MariaDBPassword:    "testpassword",
+		ReplicaLagAction:   "abort",
+	}
+	exporter, err := NewExporter(cfg, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("NewExporter() with replica host error = %v", err)
+	}
+	exporter.Close()
+
+	// The test container is no replica, so the lag check can't pass
+	cfg.ReplicaMaxLag = 30 * time.Second
+	if _, err := NewExporter(cfg, zaptest.NewLogger(t)); !errors.Is(err, errs.ErrConfig) || !errors.Is(err, errNotReplica) {
+		t.Errorf("NewExporter() with lag check on a non-replica error = %v, want ErrConfig", err)
+	}
+
+	cfg.MariaDBReplicaHost = ""
+	if _, err := NewExporter(cfg, zaptest.NewLogger(t)); !errors.Is(err, errs.ErrSourceConnect) {
+		t.Errorf("NewExporter() without replica host error = %v, want ErrSourceConnect from the primary", err)
+	}
+}
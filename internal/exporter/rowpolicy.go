@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// Skip reasons reported for rows rejected by row-level policies.
+const (
+	SkipReasonShortHash   = "short_hash"
+	SkipReasonInvalidUTF8 = "invalid_utf8"
+	SkipReasonInvalidJSON = "invalid_json"
+)
+
+// -validate-json modes: what to do with a row whose aggr isn't valid JSON.
+const (
+	ValidateJSONSkip = "skip" // Skip the row like the other row-level policies
+	ValidateJSONFail = "fail" // Fail the segment
+)
+
+// checkRow applies row-level policies to a row read from the source table.
+// Returns an empty string if the row can be exported, otherwise the skip reason
+// (the row is skipped with -dead-letter and fails the segment without, see applyRowPolicies).
+func checkRow(row Row) string {
+	// Hashes shorter than the 2-char prefix cannot be placed in a hash segment
+	if len(row.Hash) < 2 {
+		return SkipReasonShortHash
+	}
+	// Aurora loads the CSV as utf8mb4, invalid byte sequences would fail the LOAD DATA
+	if !utf8.ValidString(row.Aggr) {
+		return SkipReasonInvalidUTF8
+	}
+	return ""
+}
+
+// checkRowJSON checks that a row's aggr is valid JSON, with -validate-json.
+// Returns SkipReasonInvalidJSON if it isn't and mode is skip, or an error if mode is fail.
+func checkRowJSON(row Row, seg segment.Segment, mode string) (string, error) {
+	if mode == "" || json.Valid([]byte(row.Aggr)) {
+		return "", nil
+	}
+	if mode == ValidateJSONFail {
+		return "", fmt.Errorf("segment %d: row %s has invalid JSON in aggr (-validate-json %s)", seg.Index, row.Hash, mode)
+	}
+	return SkipReasonInvalidJSON, nil
+}
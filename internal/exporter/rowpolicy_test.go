@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExportSegment_ValidateJSON(t *testing.T) {
+	rows := []Row{
+		{TenantID: 1234, Hash: "00abc", Aggr: `{"ok": 1}`},
+		{TenantID: 1234, Hash: "01abc", Aggr: "{not json"},
+		{TenantID: 1234, Hash: "02abc", Aggr: `[1, 2]`},
+	}
+	query := func(lastHash string) ([]Row, error) {
+		if lastHash != "" {
+			return nil, nil
+		}
+		return append([]Row(nil), rows...), nil
+	}
+	seg := segment.Segment{Index: 4, StartHex: "00", EndHex: "10"}
+
+	tests := []struct {
+		mode         string
+		wantExported int
+		wantErr      string
+	}{
+		{"", 3, ""},
+		{ValidateJSONSkip, 2, ""},
+		{ValidateJSONFail, 0, "segment 4: row 01abc has invalid JSON in aggr"},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			cfg := &config.Config{TenantID: 1234, TableName: "fis_aggr", BatchSize: 10, ValidateJSON: tt.mode}
+			exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+
+			stream := &mockMultipartUploadStream{}
+			exported, _, err := exp.streamSegment(seg, "test-key", stream, query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("streamSegment() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("streamSegment() error = %v", err)
+			}
+			if exported != tt.wantExported {
+				t.Errorf("exported %d rows, want %d", exported, tt.wantExported)
+			}
+			if skipped := !strings.Contains(string(stream.parts[0]), "01abc"); skipped != (tt.mode == ValidateJSONSkip) {
+				t.Errorf("row with invalid JSON skipped = %v, want %v", skipped, tt.mode == ValidateJSONSkip)
+			}
+		})
+	}
+}
+
+func TestApplyRowPolicies_InvalidJSONCount(t *testing.T) {
+	cfg := &config.Config{ValidateJSON: ValidateJSONSkip}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	sink, err := NewDeadLetterSink(filepath.Join(t.TempDir(), "dead-letter.jsonl"), nil, exp.logger)
+	if err != nil {
+		t.Fatalf("NewDeadLetterSink() error = %v", err)
+	}
+	defer sink.Close()
+	exp.SetDeadLetterSink(sink)
+	rows := []Row{
+		{Hash: "00abc", Aggr: "{not json"},
+		{Hash: "0", Aggr: "{}"},
+		{Hash: "01abc", Aggr: ""},
+		{Hash: "02abc", Aggr: `{"ok": true}`},
+	}
+	valid, skipped, err := exp.applyRowPolicies(rows, segment.Segment{})
+	if err != nil {
+		t.Fatalf("applyRowPolicies() error = %v", err)
+	}
+	if len(valid) != 1 || valid[0].Hash != "02abc" {
+		t.Errorf("valid rows = %v, want only 02abc", valid)
+	}
+	if skipped[SkipReasonInvalidJSON] != 2 || skipped[SkipReasonShortHash] != 1 {
+		t.Errorf("skipped = %v, want 2 %s and 1 %s", skipped, SkipReasonInvalidJSON, SkipReasonShortHash)
+	}
+}
+
+func TestApplyRowPolicies_FailOnInvalidRows(t *testing.T) {
+	cfg := &config.Config{}
+	exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+	for _, row := range []Row{{Hash: "0", Aggr: "{}"}, {Hash: "01abc", Aggr: "bad \xff utf8"}} {
+		// By default the row is skipped and logged
+		cfg.FailOnInvalidRows = false
+		valid, skipped, err := exp.applyRowPolicies([]Row{{Hash: "00abc", Aggr: "{}"}, row}, segment.Segment{Index: 2})
+		if err != nil || len(valid) != 1 || valid[0].Hash != "00abc" || len(skipped) != 1 {
+			t.Errorf("applyRowPolicies(%q) = %v, %v, %v, want it skipped", row.Hash, valid, skipped, err)
+		}
+
+		// With -fail-on-invalid-rows it fails the segment
+		cfg.FailOnInvalidRows = true
+		_, _, err = exp.applyRowPolicies([]Row{{Hash: "00abc", Aggr: "{}"}, row}, segment.Segment{Index: 2})
+		if err == nil || !strings.Contains(err.Error(), "-fail-on-invalid-rows") {
+			t.Errorf("applyRowPolicies(%q) error = %v, want the row to fail the segment", row.Hash, err)
+		}
+	}
+}
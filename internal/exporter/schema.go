@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ShowCreateTable returns the source table's CREATE TABLE statement (-dump-schema), as printed by SHOW CREATE TABLE.
+func (e *Exporter) ShowCreateTable() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var table, ddl string
+	if err := e.db.QueryRowContext(ctx, "SHOW CREATE TABLE "+tableRef(e.config)).Scan(&table, &ddl); err != nil {
+		return "", fmt.Errorf("failed to read the schema of %s: %w", tableRef(e.config), err)
+	}
+	return ddl, nil
+}
+
+var (
+	// MariaDB-only table options, MySQL rejects them
+	mariaDBTableOption = regexp.MustCompile(`(?i)\s+(PAGE_CHECKSUM|TRANSACTIONAL|PAGE_COMPRESSED|PAGE_COMPRESSION_LEVEL|ENCRYPTED|ENCRYPTION_KEY_ID|IETF_QUOTES)=\S+`)
+	tableEngine        = regexp.MustCompile(`(?i)\bENGINE=\w+`)
+	// MariaDB 11 collations, not known to MySQL
+	ucaCollation = regexp.MustCompile(`(?i)\butf8mb4_uca1400\w*`)
+	utf8mb3      = regexp.MustCompile(`(?i)\butf8mb3`)
+	// MariaDB's JSON type is an alias for LONGTEXT with a json_valid check
+	jsonColumn = regexp.MustCompile("^(\\s*)`([^`]+)` longtext\\b(.*) CHECK \\(json_valid\\(`([^`]+)`\\)\\)(,?)$")
+)
+
+// TranslateDDLForAurora rewrites MariaDB-specific syntax in a CREATE TABLE statement so Aurora MySQL accepts it
+// (-dump-schema-aurora):
+//   - Any engine becomes InnoDB, and MariaDB-only table options (PAGE_CHECKSUM, TRANSACTIONAL, ...) are dropped.
+//   - utf8mb4_uca1400 collations become utf8mb4_unicode_ci, and utf8mb3 becomes utf8.
+//   - LONGTEXT columns with a json_valid check (MariaDB's JSON) become JSON, keeping only NOT NULL or DEFAULT NULL.
+func TranslateDDLForAurora(ddl string) string {
+	lines := strings.Split(ddl, "\n")
+	for i, line := range lines {
+		m := jsonColumn.FindStringSubmatch(line)
+		if m == nil || m[2] != m[4] {
+			continue
+		}
+		column := m[1] + "`" + m[2] + "` json"
+		if strings.Contains(m[3], "NOT NULL") {
+			column += " NOT NULL"
+		} else if strings.Contains(m[3], "DEFAULT NULL") {
+			column += " DEFAULT NULL"
+		}
+		lines[i] = column + m[5]
+	}
+	ddl = strings.Join(lines, "\n")
+
+	ddl = mariaDBTableOption.ReplaceAllString(ddl, "")
+	ddl = tableEngine.ReplaceAllString(ddl, "ENGINE=InnoDB")
+	ddl = ucaCollation.ReplaceAllString(ddl, "utf8mb4_unicode_ci")
+	ddl = utf8mb3.ReplaceAllString(ddl, "utf8")
+	return ddl
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestShowCreateTable(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+	setupTestTable(t, db, 1)
+
+	// MariaDB's JSON alias on a non-InnoDB engine, which the Aurora translation rewrites
+	_, err := db.Exec(`
+		CREATE TABLE fis_aggr_json (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr JSON NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE KEY uk_tenant_hash (tenantid, hash)
+		) ENGINE=Aria PAGE_CHECKSUM=1
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create JSON table: %v", err)
+	}
+
+	for _, table := range []string{"fis_aggr", "fis_aggr_json"} {
+		t.Run(table, func(t *testing.T) {
+			cfg := &config.Config{TableName: table, MariaDBDatabase: "fis"}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+
+			ddl, err := exp.ShowCreateTable()
+			if err != nil {
+				t.Fatalf("ShowCreateTable() error = %v", err)
+			}
+			if !strings.HasPrefix(ddl, "CREATE TABLE `"+table+"`") {
+				t.Errorf("DDL does not start with CREATE TABLE `%s`:\n%s", table, ddl)
+			}
+			for _, column := range []string{"`tenantid` int", "`hash` varchar(255)", "`aggr`", "`last_modified` timestamp", "`version` int"} {
+				if !strings.Contains(ddl, column) {
+					t.Errorf("DDL is missing column %s:\n%s", column, ddl)
+				}
+			}
+
+			translated := TranslateDDLForAurora(ddl)
+			if !strings.Contains(translated, "ENGINE=InnoDB") || strings.Contains(translated, "PAGE_CHECKSUM") {
+				t.Errorf("translated DDL should use InnoDB without MariaDB table options:\n%s", translated)
+			}
+			if table == "fis_aggr_json" && !strings.Contains(translated, "`aggr` json NOT NULL") {
+				t.Errorf("translated DDL should declare aggr as json:\n%s", translated)
+			}
+		})
+	}
+
+	exp := &Exporter{db: db, config: &config.Config{TableName: "fis_aggr_missing", MariaDBDatabase: "fis"}, logger: zaptest.NewLogger(t)}
+	if _, err := exp.ShowCreateTable(); err == nil {
+		t.Error("ShowCreateTable() should fail for a missing table")
+	}
+}
+
+func TestTranslateDDLForAurora(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want string
+	}{
+		{
+			name: "json column",
+			ddl:  "  `aggr` longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL CHECK (json_valid(`aggr`)),",
+			want: "  `aggr` json NOT NULL,",
+		},
+		{
+			name: "nullable json column",
+			ddl:  "  `meta` longtext DEFAULT NULL CHECK (json_valid(`meta`))",
+			want: "  `meta` json DEFAULT NULL",
+		},
+		{
+			name: "check on another column is kept",
+			ddl:  "  `aggr` longtext NOT NULL CHECK (json_valid(`other`)),",
+			want: "  `aggr` longtext NOT NULL CHECK (json_valid(`other`)),",
+		},
+		{
+			name: "table options",
+			ddl:  ") ENGINE=Aria DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_uca1400_ai_ci PAGE_CHECKSUM=1 TRANSACTIONAL=1",
+			want: ") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
+		},
+		{
+			name: "utf8mb3",
+			ddl:  "  `hash` varchar(255) CHARACTER SET utf8mb3 COLLATE utf8mb3_general_ci NOT NULL,",
+			want: "  `hash` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL,",
+		},
+		{
+			name: "plain MySQL DDL is unchanged",
+			ddl:  "  `version` int(11) DEFAULT NULL,\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			want: "  `version` int(11) DEFAULT NULL,\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TranslateDDLForAurora(tt.ddl); got != tt.want {
+				t.Errorf("TranslateDDLForAurora() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
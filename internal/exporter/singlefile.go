@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// minPartSize is S3's minimum size of every multipart part but the last.
+const minPartSize = 5 * 1024 * 1024
+
+// ExportSingleFile exports segments one after another into a single CSV file (-single-file),
+// tenant-<id>.<table>.csv under <prefix>/tenant-<id>/<table>/, as one multipart upload.
+// Segments run sequentially in hash order so their parts are uploaded in order; each still gets
+// its own export transaction and -segment-timeout, and only the first segment with rows writes the CSV header.
+// Returns a CSVFile whose Segment spans all segments, or nil if no segment has rows.
+// On failure the upload is aborted.
+func (e *Exporter) ExportSingleFile(segments []segment.Segment, uploader MultipartUploadStreamCreator) (*CSVFile, error) {
+	if len(segments) == 0 {
+		return nil, nil
+	}
+	segments, span := singleFileSpan(segments)
+
+	filename := e.singleFilename()
+	s3Key, err := e.segmentS3Key(span, filename)
+	if err != nil {
+		return nil, err
+	}
+	stream, localPath, err := e.openSegmentStream(s3Key, filename, uploader)
+	if err != nil {
+		return nil, err
+	}
+	if uploader == nil {
+		s3Key = "" // Local-only output
+	}
+	var timing SegmentTiming
+	counted := &countingStream{stream: &timedStream{stream: stream, timing: &timing}}
+	stream = &coalescingStream{stream: counted}
+	if e.codec != nil {
+		stream = &compressedStream{stream: stream, codec: e.codec}
+	}
+	defer func() {
+		if err != nil {
+			stream.Abort()
+		}
+	}()
+
+	totalRows := 0
+	sourceChanged := false
+	for _, seg := range segments {
+		var rows int
+		var changed bool
+		rows, _, changed, err = e.exportSegmentRows(seg, s3Key, stream, e.config.CSVHeader && totalRows == 0, &timing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export segment %d into the single file: %w", seg.Index, err)
+		}
+		totalRows += rows
+		sourceChanged = sourceChanged || changed
+	}
+
+	if totalRows == 0 {
+		stream.Abort()
+		return nil, nil
+	}
+	if err := stream.Complete(); err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return &CSVFile{
+		FilePath:      localPath, // Empty unless -output-dir is set
+		S3Key:         s3Key,
+		Segment:       span,
+		RowCount:      totalRows,
+		SourceChanged: sourceChanged,
+		ByteSize:      counted.Bytes(),
+		Timing:        timing,
+	}, nil
+}
+
+// singleFileSpan returns a copy of segments in hash order, and the segment spanning them all.
+// segments must not be empty.
+func singleFileSpan(segments []segment.Segment) ([]segment.Segment, segment.Segment) {
+	segments = append([]segment.Segment(nil), segments...)
+	sort.Slice(segments, func(i, j int) bool { return segments[i].StartHex < segments[j].StartHex })
+	return segments, segment.Segment{Index: 0, StartHex: segments[0].StartHex, EndHex: segments[len(segments)-1].EndHex}
+}
+
+// singleFilename returns the name of the -single-file file, tenant-<id>.<table>.csv.
+func (e *Exporter) singleFilename() string {
+	return fmt.Sprintf("tenant-%d.%s%s", e.config.TenantID, e.config.TableName, e.fileExtension())
+}
+
+// coalescingStream buffers parts until they reach minPartSize before passing them on.
+// A segment's last batch is usually short, and in a single file it would otherwise be a middle part
+// below S3's minimum, failing the upload on Complete.
+type coalescingStream struct {
+	stream MultipartUploadStreamer
+	buf    bytes.Buffer
+}
+
+func (c *coalescingStream) UploadPart(data []byte) error {
+	c.buf.Write(data)
+	if c.buf.Len() < minPartSize {
+		return nil
+	}
+	part := c.buf.Bytes()
+	c.buf = bytes.Buffer{} // The part is handed on, not copied
+	return c.stream.UploadPart(part)
+}
+
+func (c *coalescingStream) Complete() error {
+	if c.buf.Len() > 0 {
+		if err := c.stream.UploadPart(c.buf.Bytes()); err != nil {
+			return err
+		}
+		c.buf = bytes.Buffer{}
+	}
+	return c.stream.Complete()
+}
+
+func (c *coalescingStream) Abort() {
+	c.buf = bytes.Buffer{}
+	c.stream.Abort()
+}
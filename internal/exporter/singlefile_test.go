@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExportSingleFile(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	tenantID := 585858
+	setupTestTable(t, db, tenantID)
+
+	cfg := &config.Config{TenantID: tenantID, TableName: "fis_aggr", MariaDBDatabase: "fis", BatchSize: 2,
+		S3Prefix: "test-prefix", CSVHeader: true}
+	exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t)}
+	segments, err := segment.SegmentHashSpace(16)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	uploader := newMockS3Uploader()
+	csvFile, err := exp.ExportSingleFile(segments, uploader)
+	if err != nil {
+		t.Fatalf("ExportSingleFile() error = %v", err)
+	}
+	if csvFile == nil || csvFile.RowCount != 9 {
+		t.Fatalf("ExportSingleFile() = %+v, want one file with the 9 seeded rows", csvFile)
+	}
+
+	// One object for all 16 segments
+	wantKey := "test-prefix/tenant-585858/fis_aggr/tenant-585858.fis_aggr.csv"
+	if len(uploader.streams) != 1 || csvFile.S3Key != wantKey {
+		t.Fatalf("uploaded %d objects to %s, want a single object at %s", len(uploader.streams), csvFile.S3Key, wantKey)
+	}
+	stream := uploader.streams[wantKey]
+	if !stream.completed {
+		t.Fatal("multipart upload of the single file was not completed")
+	}
+	if csvFile.Segment.StartHex != "00" || csvFile.Segment.EndHex != segments[len(segments)-1].EndHex {
+		t.Errorf("CSVFile segment = %+v, want it to span the hash space", csvFile.Segment)
+	}
+
+	// Small batches are coalesced, and the header is written once before the rows in hash order
+	if len(stream.parts) != 1 {
+		t.Errorf("got %d parts, want the small batches coalesced into one", len(stream.parts))
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.Join(stream.parts, nil))).ReadAll()
+	if err != nil {
+		t.Fatalf("single file is not valid CSV: %v", err)
+	}
+	if len(records) != 10 || records[0][1] != "hash" {
+		t.Fatalf("single file has %d records starting with %v, want the header and 9 rows", len(records), records[0])
+	}
+	for i := 2; i < len(records); i++ {
+		if records[i][1] <= records[i-1][1] {
+			t.Errorf("row %d hash %s is not after %s, want the segments in hash order", i, records[i][1], records[i-1][1])
+		}
+	}
+}
+
+func TestCoalescingStream(t *testing.T) {
+	mock := &mockMultipartUploadStream{}
+	stream := &coalescingStream{stream: mock}
+
+	small := bytes.Repeat([]byte("a"), 1024)
+	large := bytes.Repeat([]byte("b"), minPartSize)
+	for _, part := range [][]byte{small, small, large, small} {
+		if err := stream.UploadPart(part); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	// The two small parts ride along with the large one; the last stays buffered until Complete
+	if len(mock.parts) != 1 || len(mock.parts[0]) != 2*len(small)+len(large) {
+		t.Fatalf("got %d parts before Complete, want one of %d bytes", len(mock.parts), 2*len(small)+len(large))
+	}
+	if err := stream.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if len(mock.parts) != 2 || !bytes.Equal(mock.parts[1], small) || !mock.completed {
+		t.Errorf("got %d parts, completed = %v, want the buffered rest uploaded as the last part", len(mock.parts), mock.completed)
+	}
+	if !bytes.Equal(mock.parts[0][:len(small)], small) {
+		t.Error("the first part was overwritten after it was handed on")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExportSingleHash reads the tenant's row with exactly this hash, bypassing segmentation (-single-hash).
+// Returns nil without an error if the tenant has no such row.
+// This is a triage aid: -exclude-where, -where and the row policies are not applied.
+func (e *Exporter) ExportSingleHash(hash string) (*Row, error) {
+	query := fmt.Sprintf(`
+		SELECT %[1]s, hash, aggr, last_modified, version
+		FROM %[2]s
+		WHERE %[1]s = ?
+		  AND hash = ?`,
+		e.config.TenantColumnName(), tableRef(e.config))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	row, err := scanRow(e.db.QueryRowContext(ctx, query, e.config.TenantID, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash %s: %w", hash, err)
+	}
+	return &row, nil
+}
+
+// FormatCSV returns rows as CSV formatted as in the exported files, with the header unless -csv-header=false.
+func (e *Exporter) FormatCSV(rows []Row) ([]byte, error) {
+	return e.rowsToCSVBytes(rows, e.config.CSVHeader)
+}
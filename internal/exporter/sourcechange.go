@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// SourceChangeProbe samples the latest modification time of a segment in the source table.
+// This allows mocking in tests.
+type SourceChangeProbe interface {
+	MaxLastModified(seg segment.Segment) (*time.Time, error)
+}
+
+// dbSourceChangeProbe reads MAX(last_modified) for a segment outside the export snapshot,
+// so it sees writes committed by fis-updater while the segment is being exported.
+type dbSourceChangeProbe struct {
+	db     *sql.DB
+	config *config.Config
+}
+
+// MaxLastModified returns the latest last_modified in the segment, or nil if the segment is empty.
+func (p *dbSourceChangeProbe) MaxLastModified(seg segment.Segment) (*time.Time, error) {
+	hashCondition, boundArgs := segmentBoundsCondition(seg)
+	args := append([]interface{}{p.config.TenantID}, boundArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT MAX(last_modified)
+		FROM %s
+		WHERE %s = ?
+		  AND %s`,
+		tableRef(p.config), p.config.TenantColumnName(), hashCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var maxModified sql.NullTime
+	if err := p.db.QueryRowContext(ctx, query, args...).Scan(&maxModified); err != nil {
+		return nil, fmt.Errorf("failed to sample max last_modified: %w", err)
+	}
+	if !maxModified.Valid {
+		return nil, nil
+	}
+	return &maxModified.Time, nil
+}
+
+// SetSourceChangeProbe sets the probe used to detect source changes during segment export.
+// A nil probe disables detection.
+func (e *Exporter) SetSourceChangeProbe(probe SourceChangeProbe) {
+	e.changeProbe = probe
+}
+
+// sourceChangedSince samples the segment again and reports whether its max last_modified
+// moved past before (sampled when the export started).
+func (e *Exporter) sourceChangedSince(seg segment.Segment, before *time.Time) (bool, error) {
+	after, err := e.changeProbe.MaxLastModified(seg)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	switch {
+	case after == nil:
+		// Segment emptied out (deletes) since the first sample
+		changed = before != nil
+	case before == nil:
+		changed = true
+	default:
+		changed = after.After(*before)
+	}
+
+	if changed {
+		e.logger.Warn("Source data changed during segment export, segment may be inconsistent with live table",
+			zap.Int("segment", seg.Index),
+			zap.String("start_hex", seg.StartHex),
+			zap.String("end_hex", seg.EndHex),
+			zap.Timep("max_last_modified_before", before),
+			zap.Timep("max_last_modified_after", after))
+	}
+	return changed, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeSourceTable is an in-memory source table shared by the export and a concurrent writer
+type fakeSourceTable struct {
+	mu   sync.Mutex
+	rows []Row
+}
+
+func (f *fakeSourceTable) write(row Row) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows = append(f.rows, row)
+}
+
+func (f *fakeSourceTable) MaxLastModified(seg segment.Segment) (*time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var max *time.Time
+	for _, row := range f.rows {
+		prefix := row.Hash[:2]
+		if prefix < seg.StartHex || (!seg.IsLast() && prefix >= seg.EndHex) {
+			continue
+		}
+		if row.LastModified != nil && (max == nil || row.LastModified.After(*max)) {
+			max = row.LastModified
+		}
+	}
+	return max, nil
+}
+
+func TestExportSegment_DetectSourceChanges(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	cfg := &config.Config{
+		TenantID:            1234,
+		TableName:           "fis_aggr",
+		BatchSize:           2,
+		DetectSourceChanges: true,
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	modifiedAt := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+
+	table := &fakeSourceTable{}
+	snapshot := []Row{
+		{TenantID: 1234, Hash: "00aaa", Aggr: "{}", LastModified: modifiedAt(0)},
+		{TenantID: 1234, Hash: "01aaa", Aggr: "{}", LastModified: modifiedAt(1)},
+		{TenantID: 1234, Hash: "02aaa", Aggr: "{}", LastModified: modifiedAt(2)},
+		{TenantID: 1234, Hash: "10aaa", Aggr: "{}", LastModified: modifiedAt(3)},
+		{TenantID: 1234, Hash: "11aaa", Aggr: "{}", LastModified: modifiedAt(4)},
+	}
+	for _, row := range snapshot {
+		table.write(row)
+	}
+
+	exp := &Exporter{config: cfg, logger: logger}
+	exp.SetSourceChangeProbe(table)
+
+	tests := []struct {
+		name        string
+		seg         segment.Segment
+		rows        []Row
+		concurrent  *Row
+		wantChanged bool
+	}{
+		{
+			name:        "segment written to during export is flagged",
+			seg:         segment.Segment{Index: 0, StartHex: "00", EndHex: "10"},
+			rows:        snapshot[:3],
+			concurrent:  &Row{TenantID: 1234, Hash: "01bbb", Aggr: "{}", LastModified: modifiedAt(10)},
+			wantChanged: true,
+		},
+		{
+			name:        "untouched segment is not flagged",
+			seg:         segment.Segment{Index: 1, StartHex: "10", EndHex: "20"},
+			rows:        snapshot[3:],
+			wantChanged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := table.MaxLastModified(tt.seg)
+			if err != nil {
+				t.Fatalf("MaxLastModified() error = %v", err)
+			}
+
+			// The export reads from its snapshot while a writer commits to the live table
+			snapshotQuery, _ := newFakeQuerier(tt.rows, cfg.BatchSize)
+			var writer sync.WaitGroup
+			query := func(lastHash string) ([]Row, error) {
+				if lastHash == "" && tt.concurrent != nil {
+					writer.Add(1)
+					go func() {
+						defer writer.Done()
+						table.write(*tt.concurrent)
+					}()
+				}
+				return snapshotQuery(lastHash)
+			}
+
+			stream := &mockMultipartUploadStream{}
+			exported, _, err := exp.streamSegment(tt.seg, "test-key", stream, query)
+			if err != nil {
+				t.Fatalf("streamSegment() error = %v", err)
+			}
+			writer.Wait()
+
+			if exported != len(tt.rows) {
+				t.Errorf("expected %d exported rows, got %d", len(tt.rows), exported)
+			}
+
+			changed, err := exp.sourceChangedSince(tt.seg, before)
+			if err != nil {
+				t.Fatalf("sourceChangedSince() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("sourceChangedSince() = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
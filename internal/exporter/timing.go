@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SegmentTiming is where the export of a segment spent its time, to tell whether a slow run
+// waits on MariaDB, the CPU or the network.
+type SegmentTiming struct {
+	Query     time.Duration // Batch queries on MariaDB
+	Serialize time.Duration // Rows to CSV or Parquet, with the row policies, digest and -compress
+	Upload    time.Duration // Parts uploaded to S3 (or written locally) and the upload completed, or -direct-load inserts
+}
+
+// Add adds the durations of other to t.
+func (t *SegmentTiming) Add(other SegmentTiming) {
+	t.Query += other.Query
+	t.Serialize += other.Serialize
+	t.Upload += other.Upload
+}
+
+// Fields returns the durations as zap fields: query_time, serialize_time and upload_time.
+func (t SegmentTiming) Fields() []zap.Field {
+	return []zap.Field{
+		zap.Duration("query_time", t.Query),
+		zap.Duration("serialize_time", t.Serialize),
+		zap.Duration("upload_time", t.Upload),
+	}
+}
+
+// serializing starts timing the serialization of rows: the returned func adds the time since to t.Serialize,
+// less what t.Upload grew by meanwhile (parts uploaded as they fill up).
+func (t *SegmentTiming) serializing() func() {
+	start, upload := time.Now(), t.Upload
+	return func() {
+		t.Serialize += time.Since(start) - (t.Upload - upload)
+	}
+}
+
+// timedStream adds the time spent uploading parts and completing the upload to timing.Upload.
+// It wraps the stream below compression, so compressing counts as serializing.
+type timedStream struct {
+	stream MultipartUploadStreamer
+	timing *SegmentTiming
+}
+
+func (t *timedStream) UploadPart(data []byte) error {
+	defer t.timeSince(time.Now())
+	return t.stream.UploadPart(data)
+}
+
+func (t *timedStream) Complete() error {
+	defer t.timeSince(time.Now())
+	return t.stream.Complete()
+}
+
+func (t *timedStream) Abort() {
+	t.stream.Abort()
+}
+
+func (t *timedStream) KeepParts(n int32) error {
+	return KeepParts(t.stream, n)
+}
+
+func (t *timedStream) timeSince(start time.Time) {
+	t.timing.Upload += time.Since(start)
+}
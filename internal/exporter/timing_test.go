@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// slowStream is a mock stream taking delay for every part it uploads
+type slowStream struct {
+	mockMultipartUploadStream
+	delay time.Duration
+}
+
+func (s *slowStream) UploadPart(data []byte) error {
+	time.Sleep(s.delay)
+	return s.mockMultipartUploadStream.UploadPart(data)
+}
+
+func TestStreamSegment_Timing(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	var rows []Row
+	for i := 0; i < 6; i++ {
+		rows = append(rows, Row{TenantID: 1, Hash: fmt.Sprintf("00%02x", i), Aggr: fmt.Sprintf(`{"n":%d}`, i)})
+	}
+	// query pages through rows 2 at a time, taking delay for each batch
+	query := func(lastHash string) ([]Row, error) {
+		time.Sleep(delay)
+		start := 0
+		for start < len(rows) && rows[start].Hash <= lastHash {
+			start++
+		}
+		return rows[start:min(start+2, len(rows))], nil
+	}
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+
+	tests := []struct {
+		name       string
+		batchBytes int
+		wantParts  int
+	}{
+		{"part per batch", 0, 3},
+		// Parts are uploaded while the rows are serialized, that time is upload time only
+		{"batch bytes", 1, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{BatchSize: 2, CSVHeader: true, BatchBytes: tt.batchBytes}
+			exp := &Exporter{config: cfg, logger: zaptest.NewLogger(t)}
+			var timing SegmentTiming
+			stream := &slowStream{delay: delay}
+			if _, _, err := exp.streamSegmentRows(seg, "test-key", &timedStream{stream: stream, timing: &timing}, query, true, &timing); err != nil {
+				t.Fatalf("streamSegmentRows() error = %v", err)
+			}
+			if len(stream.parts) != tt.wantParts {
+				t.Fatalf("uploaded %d parts, want %d", len(stream.parts), tt.wantParts)
+			}
+
+			// 4 queries (the last finds no rows) and the part uploads, the rest is serialization
+			if timing.Query < 4*delay {
+				t.Errorf("query time = %s, want at least %s", timing.Query, 4*delay)
+			}
+			if want := time.Duration(tt.wantParts) * delay; timing.Upload < want {
+				t.Errorf("upload time = %s, want at least %s", timing.Upload, want)
+			}
+			if timing.Serialize <= 0 || timing.Serialize >= delay {
+				t.Errorf("serialize time = %s, want above 0 and without the upload time", timing.Serialize)
+			}
+		})
+	}
+}
+
+func TestExportSegment_DirectLoadTiming(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	rows := []Row{{TenantID: 1, Hash: "0001", Aggr: "{}"}, {TenantID: 1, Hash: "0002", Aggr: "{}"}}
+	loader := &slowLoader{delay: delay}
+	exp := &Exporter{config: &config.Config{BatchSize: 10}, logger: zaptest.NewLogger(t)}
+	exp.SetRowLoader(loader)
+
+	var timing SegmentTiming
+	query := func(lastHash string) ([]Row, error) { return rowsAfterCursor(rows, lastHash), nil }
+	if _, _, err := exp.streamSegmentRows(segment.Segment{}, "", nil, query, false, &timing); err != nil {
+		t.Fatalf("streamSegmentRows() error = %v", err)
+	}
+	// The inserts are the upload of -direct-load
+	if timing.Upload < delay || timing.Serialize >= delay {
+		t.Errorf("timing = %+v, want the %s insert as upload time", timing, delay)
+	}
+}
+
+func TestSegmentTiming_Add(t *testing.T) {
+	total := SegmentTiming{Query: time.Second}
+	total.Add(SegmentTiming{Query: 2 * time.Second, Serialize: time.Millisecond, Upload: time.Minute})
+	if want := (SegmentTiming{Query: 3 * time.Second, Serialize: time.Millisecond, Upload: time.Minute}); total != want {
+		t.Errorf("Add() = %+v, want %+v", total, want)
+	}
+}
+
+// slowLoader is a row loader taking delay for every batch
+type slowLoader struct {
+	delay time.Duration
+}
+
+func (l *slowLoader) LoadRows(rows []Row) error {
+	time.Sleep(l.delay)
+	return nil
+}
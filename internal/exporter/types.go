@@ -18,11 +18,16 @@ type Row struct {
 }
 
 // CSVFile represents a generated CSV file.
-// For streaming uploads, FilePath will be empty as data is streamed directly to S3.
+// For streaming uploads, FilePath will be empty as data is streamed directly to S3,
+// unless -output-dir is set. S3Key is empty in local-only mode.
 type CSVFile struct {
-	FilePath string // Empty for streaming uploads
-	S3Key    string
-	Segment  segment.Segment
-	RowCount int
+	FilePath      string // Empty for streaming uploads without -output-dir
+	S3Key         string // Empty for local-only output
+	Segment       segment.Segment
+	RowCount      int
+	SourceChanged bool          // Source rows were modified while the segment was exported (-detect-source-changes)
+	Checksum      string        // Row digest of the exported rows, recorded in the manifest (-manifest)
+	ByteSize      int64         // Bytes uploaded (or written locally), after compression; 0 with -direct-load or from a checkpoint
+	Timing        SegmentTiming // Where the export spent its time; zero from a checkpoint
 }
 
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// whereColumns are the columns -where can filter on without -allow-raw-where.
+var whereColumns = map[string]bool{"hash": true, "last_modified": true, "version": true}
+
+var (
+	whereAnd        = regexp.MustCompile(`(?i)\s+AND\s+`)
+	whereComparison = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*(<=|>=|<>|!=|=|<|>)\s*(.+)$`)
+	whereNullTest   = regexp.MustCompile(`(?i)^([A-Za-z_][A-Za-z0-9_]*)\s+IS\s+(NOT\s+)?NULL$`)
+)
+
+// ParseWhere parses -where, the filter selecting the subset of the tenant's rows to migrate,
+// into a predicate appended to the segment queries like -exclude-where.
+// The value is one or more terms joined with AND, each of them one of:
+//   - column op value, with op one of = != <> < <= > >= (e.g. version >= 3); the value is bound as a query arg,
+//     and must be single- or double-quoted if it has spaces (e.g. last_modified >= '2024-01-01 00:00:00')
+//   - column IS NULL or column IS NOT NULL
+//
+// Columns must be in whereColumns. With allowRaw the value is used as raw SQL instead, unchecked but for ';'.
+func ParseWhere(spec string, allowRaw bool) (ExcludeFilter, error) {
+	var filter ExcludeFilter
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return filter, nil
+	}
+	if allowRaw {
+		if strings.Contains(spec, ";") {
+			return ExcludeFilter{}, fmt.Errorf("invalid where %q: ';' is not allowed", spec)
+		}
+		filter.Condition = "(" + spec + ")"
+		return filter, nil
+	}
+
+	var conditions []string
+	for _, term := range whereAnd.Split(spec, -1) {
+		term = strings.TrimSpace(term)
+		if m := whereNullTest.FindStringSubmatch(term); m != nil {
+			if err := checkWhereColumn(m[1], spec); err != nil {
+				return ExcludeFilter{}, err
+			}
+			if m[2] != "" {
+				conditions = append(conditions, fmt.Sprintf("`%s` IS NOT NULL", m[1]))
+			} else {
+				conditions = append(conditions, fmt.Sprintf("`%s` IS NULL", m[1]))
+			}
+			continue
+		}
+		m := whereComparison.FindStringSubmatch(term)
+		if m == nil {
+			return ExcludeFilter{}, fmt.Errorf("invalid where term %q in %q (expected column op value or column IS [NOT] NULL, or use -allow-raw-where)", term, spec)
+		}
+		if err := checkWhereColumn(m[1], spec); err != nil {
+			return ExcludeFilter{}, err
+		}
+		op, value := m[2], strings.TrimSpace(m[3])
+		quoted := unquoteWhereValue(value)
+		if quoted == value && (strings.ContainsAny(value, " \t") || strings.ContainsAny(value[:1], "<>=!")) {
+			return ExcludeFilter{}, fmt.Errorf("invalid where value %q in %q (quote values with spaces or operators, or use -allow-raw-where)", value, spec)
+		}
+		if op == "!=" {
+			op = "<>"
+		}
+		conditions = append(conditions, fmt.Sprintf("`%s` %s ?", m[1], op))
+		filter.Args = append(filter.Args, quoted)
+	}
+
+	filter.Condition = strings.Join(conditions, " AND ")
+	return filter, nil
+}
+
+// checkWhereColumn rejects columns -where can't filter on without -allow-raw-where.
+func checkWhereColumn(column, spec string) error {
+	if !whereColumns[strings.ToLower(column)] {
+		return fmt.Errorf("invalid where column %q in %q (expected hash, last_modified or version, or use -allow-raw-where)", column, spec)
+	}
+	return nil
+}
+
+// unquoteWhereValue strips one pair of matching single or double quotes around a -where value.
+func unquoteWhereValue(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// sinceFilter returns the predicate of -since-checkpoint: rows with a last_modified at or after since,
+// the target's newest as Unix seconds, or no filter for "". Rows at since itself are exported again,
+// so none modified in the same second as the newest loaded row is missed; IGNORE or REPLACE reloads them harmlessly.
+// Rows with a NULL last_modified are left out.
+func sinceFilter(since string) ExcludeFilter {
+	if since == "" {
+		return ExcludeFilter{}
+	}
+	return ExcludeFilter{Condition: "`last_modified` >= FROM_UNIXTIME(?)", Args: []interface{}{since}}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package exporter
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		allowRaw      bool
+		wantCondition string
+		wantArgs      []interface{}
+		wantErr       bool
+	}{
+		{"empty", "", false, "", nil, false},
+		{"not null", "version IS NOT NULL", false, "`version` IS NOT NULL", nil, false},
+		{"null, lowercase", "last_modified is null", false, "`last_modified` IS NULL", nil, false},
+		{"comparison", "version >= 3", false, "`version` >= ?", []interface{}{"3"}, false},
+		{"several terms", "version>=3 and last_modified < '2024-01-01' AND hash != \"00ab\"", false,
+			"`version` >= ? AND `last_modified` < ? AND `hash` <> ?", []interface{}{"3", "2024-01-01", "00ab"}, false},
+		{"quoted value with spaces", "last_modified >= '2024-01-01 00:00:00'", false, "`last_modified` >= ?", []interface{}{"2024-01-01 00:00:00"}, false},
+		{"injection in quoted value is bound", "hash = 'x OR 1=1'", false, "`hash` = ?", []interface{}{"x OR 1=1"}, false},
+		{"unquoted value with spaces", "hash = x' OR '1'='1", false, "", nil, true},
+		{"column not allowed", "deleted_at IS NULL", false, "", nil, true},
+		{"operator not allowed", "version LIKE 3", false, "", nil, true},
+		{"expression", "version + 1 > 3", false, "", nil, true},
+		{"or", "version = 1 OR version = 2", false, "", nil, true},
+		{"missing value", "version >=", false, "", nil, true},
+		{"raw", "version IN (1, 2) OR deleted_at IS NULL", true, "(version IN (1, 2) OR deleted_at IS NULL)", nil, false},
+		{"raw second statement", "1=1; DROP TABLE fis_aggr", true, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseWhere(tt.spec, tt.allowRaw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWhere(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if filter.Condition != tt.wantCondition {
+				t.Errorf("Condition = %q, want %q", filter.Condition, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(filter.Args, tt.wantArgs) {
+				t.Errorf("Args = %v, want %v", filter.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestExportSegment_Where(t *testing.T) {
+	db, cleanup, _ := setupTestDB(t)
+	defer cleanup()
+
+	const tenantID = 787878
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr_where (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE KEY uk_tenant_hash (tenantid, hash)
+		)`,
+		`INSERT INTO fis_aggr_where (tenantid, hash, aggr, version) VALUES
+			(787878, '00aa', '{}', NULL),
+			(787878, '00bb', '{}', 1),
+			(787878, '00cc', '{}', NULL),
+			(787878, '00dd', '{}', 3),
+			(787878, '00ee', '{}', 5)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up table: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		where      string
+		allowRaw   bool
+		exclude    string
+		wantHashes []string
+	}{
+		{"no filter", "", false, "", []string{"00aa", "00bb", "00cc", "00dd", "00ee"}},
+		{"version is not null", "version IS NOT NULL", false, "", []string{"00bb", "00dd", "00ee"}},
+		{"version >= 3", "version >= 3", false, "", []string{"00dd", "00ee"}},
+		{"with exclude-where", "version IS NOT NULL", false, "version=5", []string{"00bb", "00dd"}},
+		{"raw", "version IN (1, 5)", true, "", []string{"00bb", "00ee"}},
+	}
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				TenantID:        tenantID,
+				TableName:       "fis_aggr_where",
+				MariaDBDatabase: "fis",
+				BatchSize:       2,
+				S3Prefix:        "test-prefix",
+				Where:           tt.where,
+				AllowRawWhere:   tt.allowRaw,
+			}
+			exclude, err := ParseExcludeWhere(tt.exclude)
+			if err != nil {
+				t.Fatalf("ParseExcludeWhere() error = %v", err)
+			}
+			where, err := ParseWhere(cfg.Where, cfg.AllowRawWhere)
+			if err != nil {
+				t.Fatalf("ParseWhere() error = %v", err)
+			}
+			exp := &Exporter{db: db, config: cfg, logger: zaptest.NewLogger(t), exclude: exclude, where: where}
+
+			count, err := exp.CountTenantRows()
+			if err != nil {
+				t.Fatalf("CountTenantRows() error = %v", err)
+			}
+			if count != int64(len(tt.wantHashes)) {
+				t.Errorf("CountTenantRows() = %d, want %d", count, len(tt.wantHashes))
+			}
+
+			uploader := newMockS3Uploader()
+			csvFile, err := exp.ExportSegment(seg, uploader)
+			if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			}
+			var data strings.Builder
+			for _, part := range uploader.streams[csvFile.S3Key].parts {
+				data.Write(part)
+			}
+			records, err := csv.NewReader(strings.NewReader(data.String())).ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse CSV: %v", err)
+			}
+			var hashes []string
+			for _, record := range records {
+				hashes = append(hashes, record[1])
+			}
+			if !reflect.DeepEqual(hashes, tt.wantHashes) || csvFile.RowCount != len(tt.wantHashes) {
+				t.Errorf("exported %d rows %v, want %v", csvFile.RowCount, hashes, tt.wantHashes)
+			}
+		})
+	}
+}
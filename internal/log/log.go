@@ -3,8 +3,10 @@
 package log
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -12,25 +14,17 @@ import (
 
 // NewLogger returns a logger using the Zap structured logger.
 // If stdout is false, a file-based logger is used. Otherwise a console logger is used.
-func NewLogger(logDir, logName string, debug, stdout bool) (*zap.Logger, error) {
-	cfg := zap.NewProductionEncoderConfig()
-	cfg.EncodeTime = zapcore.EpochTimeEncoder
-	cfg.LevelKey = "lv"
-	cfg.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
-		enc.AppendString(l.CapitalString()[:2])
-	}
-
-	level := zap.InfoLevel
-	if debug {
-		level = zap.DebugLevel
-		cfg.EncodeCaller = zapcore.ShortCallerEncoder
-		cfg.CallerKey = "call"
+// level is one of debug, info, warn or error (empty means info).
+// Any of the given secrets (passwords, AWS keys) appearing in log output is replaced with [REDACTED].
+func NewLogger(logDir, logName, level string, stdout bool, secrets ...string) (*zap.Logger, error) {
+	zapLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
 	}
 
-	var core zapcore.Core
+	var sink zapcore.WriteSyncer
 	if stdout {
-		core = zapcore.NewCore(zapcore.NewJSONEncoder(cfg),
-			zapcore.AddSync(os.Stdout), level)
+		sink = zapcore.AddSync(os.Stdout)
 	} else {
 		if logDir == "" {
 			logDir = "/tmp"
@@ -44,18 +38,48 @@ func NewLogger(logDir, logName string, debug, stdout bool) (*zap.Logger, error)
 		if err != nil {
 			return nil, err
 		}
+		sink = zapcore.AddSync(file)
+	}
+
+	return newLogger(newRedactingWriteSyncer(sink, secrets), zapLevel), nil
+}
+
+// ParseLevel maps a level name (debug, info, warn, error) to its zap level.
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zap.DebugLevel, nil
+	case "", "info":
+		return zap.InfoLevel, nil
+	case "warn", "warning":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	default:
+		return zap.InfoLevel, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+	}
+}
 
-		core = zapcore.NewCore(zapcore.NewJSONEncoder(cfg),
-			zapcore.AddSync(file), level)
+// newLogger builds the JSON logger writing to sink at the given level.
+// Debug loggers also record the caller.
+func newLogger(sink zapcore.WriteSyncer, level zapcore.Level) *zap.Logger {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.EpochTimeEncoder
+	cfg.LevelKey = "lv"
+	cfg.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(l.CapitalString()[:2])
 	}
 
-	var logger *zap.Logger
+	debug := level == zap.DebugLevel
 	if debug {
-		logger = zap.New(core, zap.AddCaller())
-	} else {
-		logger = zap.New(core)
+		cfg.EncodeCaller = zapcore.ShortCallerEncoder
+		cfg.CallerKey = "call"
 	}
 
-	return logger, nil
-}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(cfg), sink, level)
 
+	if debug {
+		return zap.New(core, zap.AddCaller())
+	}
+	return zap.New(core)
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2022 Netskope, Inc. All rights reserved.
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLogger_DebugLevel(t *testing.T) {
+	level, err := ParseLevel("debug")
+	if err != nil {
+		t.Fatalf("ParseLevel() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := newLogger(zapcore.AddSync(&buf), level)
+	logger.Debug("debug line", zap.Int("segment", 3))
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"debug line"`) {
+		t.Errorf("expected debug line in output, got %q", out)
+	}
+	if !strings.Contains(out, `"lv":"DE"`) {
+		t.Errorf("expected DE level in output, got %q", out)
+	}
+	if !strings.Contains(out, `"call":`) {
+		t.Errorf("expected caller in debug output, got %q", out)
+	}
+}
+
+func TestNewLogger_InfoLevelDropsDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(zapcore.AddSync(&buf), zap.InfoLevel)
+	logger.Debug("debug line")
+	logger.Info("info line")
+
+	out := buf.String()
+	if strings.Contains(out, "debug line") {
+		t.Errorf("debug line should be dropped at info level, got %q", out)
+	}
+	if !strings.Contains(out, "info line") {
+		t.Errorf("expected info line in output, got %q", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{"debug", zap.DebugLevel, false},
+		{"info", zap.InfoLevel, false},
+		{"", zap.InfoLevel, false},
+		{"WARN", zap.WarnLevel, false},
+		{"error", zap.ErrorLevel, false},
+		{"verbose", zap.InfoLevel, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogger_RedactsSecrets(t *testing.T) {
+	const password = `s3cr"et\pass`
+	const secretKey = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+
+	var buf bytes.Buffer
+	logger := newLogger(newRedactingWriteSyncer(zapcore.AddSync(&buf), []string{password, secretKey, "abc", ""}), zap.InfoLevel)
+	logger.Info("connecting with password "+password,
+		zap.String("dsn", "root:"+password+"@tcp(localhost:3306)/fis"),
+		zap.Error(fmt.Errorf("auth failed for key %s", secretKey)),
+		zap.String("note", "abc is too short to redact"))
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, secret := range []string{password, jsonEscape(password), secretKey} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output leaks secret %q: %s", secret, out)
+		}
+	}
+	if n := strings.Count(out, redacted); n != 3 {
+		t.Errorf("expected 3 redactions, got %d: %s", n, out)
+	}
+	if !strings.Contains(out, `"dsn":"root:[REDACTED]@tcp(localhost:3306)/fis"`) {
+		t.Errorf("expected redacted DSN field, got %s", out)
+	}
+	if !strings.Contains(out, "abc is too short") {
+		t.Errorf("short values should not be redacted, got %s", out)
+	}
+}
+
+func TestNewRedactingWriteSyncer_NoSecrets(t *testing.T) {
+	sink := zapcore.AddSync(&bytes.Buffer{})
+	if got := newRedactingWriteSyncer(sink, []string{"", "ab"}); got != sink {
+		t.Error("expected the sink unchanged when there is nothing to redact")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 Netskope, Inc. All rights reserved.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces secret values in log output.
+const redacted = "[REDACTED]"
+
+// minSecretLen is the shortest value that is redacted. Shorter values would scrub ordinary log text.
+const minSecretLen = 4
+
+// redactingWriteSyncer scrubs known secret values from encoded log entries before they reach the sink.
+// It works on the encoded output, so secrets are caught in messages, fields, errors and stack traces alike.
+type redactingWriteSyncer struct {
+	zapcore.WriteSyncer
+	replacer *strings.Replacer
+}
+
+// newRedactingWriteSyncer wraps sink so the given secrets never reach it.
+// Returns sink unchanged if there is nothing to redact.
+func newRedactingWriteSyncer(sink zapcore.WriteSyncer, secrets []string) zapcore.WriteSyncer {
+	seen := make(map[string]bool)
+	var values []string
+	for _, secret := range secrets {
+		if len(secret) < minSecretLen {
+			continue
+		}
+		// The JSON encoder escapes quotes, backslashes and control characters, so match the escaped form too
+		for _, value := range []string{secret, jsonEscape(secret)} {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return sink
+	}
+
+	// Longest first, so a secret containing another is replaced whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	var pairs []string
+	for _, value := range values {
+		pairs = append(pairs, value, redacted)
+	}
+	return &redactingWriteSyncer{WriteSyncer: sink, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (r *redactingWriteSyncer) Write(p []byte) (int, error) {
+	if _, err := r.WriteSyncer.Write([]byte(r.replacer.Replace(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// jsonEscape returns s as it appears inside a JSON string.
+func jsonEscape(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(buf.String(), "\n"), `"`), `"`)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// errRunAborted is the cause of a run stopped by -fail-fast-abort, and the error of its aborted uploads.
+var errRunAborted = errors.New("run aborted after a failed segment (-fail-fast-abort)")
+
+// abortingStreamCreator wraps a stream creator to track the uploads of the segments in flight,
+// so abort can abort them all at the first failed segment (-fail-fast-abort).
+// Once aborted no stream is created, and the open ones fail their next part, stopping their segments.
+type abortingStreamCreator struct {
+	creator exporter.MultipartUploadStreamCreator
+	mu      sync.Mutex
+	open    map[*abortingStream]struct{}
+	aborted bool
+}
+
+func newAbortingStreamCreator(creator exporter.MultipartUploadStreamCreator) *abortingStreamCreator {
+	return &abortingStreamCreator{creator: creator, open: make(map[*abortingStream]struct{})}
+}
+
+func (c *abortingStreamCreator) NewMultipartUploadStream(s3Key string) (exporter.MultipartUploadStreamer, error) {
+	if c.isAborted() {
+		return nil, errRunAborted
+	}
+	stream, err := c.creator.NewMultipartUploadStream(s3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &abortingStream{stream: stream, creator: c}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.aborted {
+		stream.Abort() // Aborted while it was being created
+		return nil, errRunAborted
+	}
+	c.open[s] = struct{}{}
+	return s, nil
+}
+
+func (c *abortingStreamCreator) isAborted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.aborted
+}
+
+// abort aborts the uploads in flight and every upload started after. Returns how many were in flight.
+func (c *abortingStreamCreator) abort() int {
+	c.mu.Lock()
+	c.aborted = true
+	open := c.open
+	c.open = map[*abortingStream]struct{}{}
+	c.mu.Unlock()
+
+	for s := range open {
+		s.Abort()
+	}
+	return len(open)
+}
+
+// closed stops tracking s once it completed or aborted.
+func (c *abortingStreamCreator) closed(s *abortingStream) {
+	c.mu.Lock()
+	delete(c.open, s)
+	c.mu.Unlock()
+}
+
+// abortOnFailure wraps process so the first failed segment cancels ctx with errRunAborted, which stops
+// dispatching segments (see dispatchSegments), and aborts the uploads of the segments in flight.
+// Every segment error counts: config validation rejects -fail-fast-abort with -continue-on-segment-error,
+// whose failed segments it would otherwise abort the run on.
+func (c *abortingStreamCreator) abortOnFailure(cancel context.CancelCauseFunc, process segmentProcessor, logger *zap.Logger) segmentProcessor {
+	var once sync.Once
+	return func(seg segment.Segment) ([]exporter.CSVFile, error) {
+		csvFiles, err := process(seg)
+		if err != nil && !errors.Is(err, errRunAborted) {
+			once.Do(func() {
+				cancel(errRunAborted)
+				logger.Error("Segment failed, aborting the uploads in flight (-fail-fast-abort)",
+					zap.Int("segment", seg.Index),
+					zap.Int("aborted_uploads", c.abort()),
+					zap.Error(err))
+			})
+		}
+		return csvFiles, err
+	}
+}
+
+// abortingStream is an upload tracked by abortingStreamCreator. An abort doesn't wait for the part
+// being uploaded: that part fails once it returns, and if it was stored anyway the upload is aborted
+// again, as S3 may keep a part uploaded during the abort. Complete and Abort are serialized, so an
+// abort never races a completing upload, and the segment's own Abort after an abort does nothing.
+type abortingStream struct {
+	stream  exporter.MultipartUploadStreamer
+	creator *abortingStreamCreator
+	mu      sync.Mutex
+	done    bool // Completed or aborted
+}
+
+func (s *abortingStream) UploadPart(data []byte) error {
+	if s.isDone() {
+		return errRunAborted
+	}
+	// Not under s.mu, so an abort doesn't wait for the network call
+	err := s.stream.UploadPart(data)
+	if s.isDone() {
+		if err == nil {
+			s.stream.Abort() // The part was stored after the abort
+		}
+		return errRunAborted
+	}
+	return err
+}
+
+func (s *abortingStream) isDone() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+func (s *abortingStream) Complete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return errRunAborted
+	}
+	err := s.stream.Complete()
+	if err == nil {
+		s.done = true
+		s.creator.closed(s)
+	}
+	return err
+}
+
+func (s *abortingStream) Abort() {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.mu.Unlock()
+	s.creator.closed(s)
+	s.stream.Abort()
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// recordingStream records whether it was completed and how often it was aborted
+type recordingStream struct {
+	completed atomic.Bool
+	aborts    atomic.Int32
+}
+
+func (s *recordingStream) UploadPart(data []byte) error { return nil }
+func (s *recordingStream) Complete() error              { s.completed.Store(true); return nil }
+func (s *recordingStream) Abort()                       { s.aborts.Add(1) }
+
+// recordingStreamCreator keeps the streams it created by key
+type recordingStreamCreator struct {
+	mu      sync.Mutex
+	streams map[string]*recordingStream
+}
+
+func (c *recordingStreamCreator) NewMultipartUploadStream(s3Key string) (exporter.MultipartUploadStreamer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stream := &recordingStream{}
+	c.streams[s3Key] = stream
+	return stream, nil
+}
+
+func TestDispatchSegments_FailFastAbort(t *testing.T) {
+	var segments []segment.Segment
+	for i := 0; i < 5; i++ {
+		segments = append(segments, segment.Segment{Index: i})
+	}
+	cfg := &config.Config{MaxParallelSegs: 4, FailFastAbort: true}
+	logger := zaptest.NewLogger(t)
+	recording := &recordingStreamCreator{streams: map[string]*recordingStream{}}
+	aborting := newAbortingStreamCreator(recording)
+	segmentErr := errors.New("query failed")
+
+	// Segment 0 fails once segment 1 completed and segments 2 and 3 are uploading.
+	// Those upload parts until their stream fails, then abort it as the exporter does; segment 4 is the next batch.
+	var ready sync.WaitGroup
+	ready.Add(3)
+	var processed sync.Map
+	process := func(seg segment.Segment) ([]exporter.CSVFile, error) {
+		processed.Store(seg.Index, true)
+		if seg.Index == 0 {
+			ready.Wait()
+			return nil, segmentErr
+		}
+		stream, err := aborting.NewMultipartUploadStream(fmt.Sprintf("segment-%d", seg.Index))
+		if err != nil {
+			return nil, err
+		}
+		if seg.Index == 1 {
+			err := stream.Complete()
+			ready.Done()
+			return nil, err
+		}
+		ready.Done()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if err := stream.UploadPart([]byte("part")); err != nil {
+				stream.Abort()
+				return nil, err
+			}
+		}
+		return nil, errors.New("stream was never aborted")
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	_, err := dispatchSegments(ctx, segments, cfg, nil, nil, aborting.abortOnFailure(cancel, process, logger), logger)
+	if !errors.Is(err, errRunAborted) {
+		t.Fatalf("dispatchSegments() error = %v, want errRunAborted", err)
+	}
+
+	// Aborted again if a part in flight during the abort was stored (see TestAbortingStream_PartInFlight)
+	for _, key := range []string{"segment-2", "segment-3"} {
+		if stream := recording.streams[key]; stream.aborts.Load() < 1 || stream.completed.Load() {
+			t.Errorf("%s aborted %d times (completed %v), want it aborted", key, stream.aborts.Load(), stream.completed.Load())
+		}
+	}
+	if stream := recording.streams["segment-1"]; stream.aborts.Load() != 0 || !stream.completed.Load() {
+		t.Errorf("completed segment-1 aborted %d times, want it left completed", stream.aborts.Load())
+	}
+	if _, ok := processed.Load(4); ok {
+		t.Error("segment 4 was dispatched after the run was aborted")
+	}
+	if _, err := aborting.NewMultipartUploadStream("segment-5"); !errors.Is(err, errRunAborted) {
+		t.Errorf("NewMultipartUploadStream() after abort error = %v, want errRunAborted", err)
+	}
+}
+
+// blockingStream holds its first part until released, like a slow S3 part upload
+type blockingStream struct {
+	recordingStream
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStream) UploadPart(data []byte) error {
+	close(s.started)
+	<-s.release
+	return nil
+}
+
+type blockingStreamCreator struct{ stream *blockingStream }
+
+func (c blockingStreamCreator) NewMultipartUploadStream(s3Key string) (exporter.MultipartUploadStreamer, error) {
+	return c.stream, nil
+}
+
+func TestAbortingStream_PartInFlight(t *testing.T) {
+	blocking := &blockingStream{started: make(chan struct{}), release: make(chan struct{})}
+	aborting := newAbortingStreamCreator(blockingStreamCreator{blocking})
+	stream, err := aborting.NewMultipartUploadStream("segment-0")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+
+	uploaded := make(chan error, 1)
+	go func() { uploaded <- stream.UploadPart([]byte("part")) }()
+	<-blocking.started
+
+	// The abort doesn't wait for the part being uploaded
+	aborted := make(chan int, 1)
+	go func() { aborted <- aborting.abort() }()
+	select {
+	case n := <-aborted:
+		if n != 1 {
+			t.Errorf("abort() = %d, want 1 upload in flight", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("abort() waited for the part in flight")
+	}
+	if n := blocking.aborts.Load(); n != 1 {
+		t.Fatalf("upload aborted %d times, want once", n)
+	}
+
+	// The part stored after the abort fails the segment and aborts the upload again
+	close(blocking.release)
+	if err := <-uploaded; !errors.Is(err, errRunAborted) {
+		t.Errorf("UploadPart() error = %v, want errRunAborted", err)
+	}
+	if n := blocking.aborts.Load(); n != 2 {
+		t.Errorf("upload aborted %d times, want twice", n)
+	}
+
+	// The segment's own Abort after it does nothing
+	stream.Abort()
+	if n := blocking.aborts.Load(); n != 2 {
+		t.Errorf("upload aborted %d times after the segment's Abort, want still twice", n)
+	}
+}
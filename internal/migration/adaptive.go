@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"sync"
+	"time"
+)
+
+// aimdController limits how many segments run at once (-adaptive-parallelism), adapting the limit
+// to the source's load: additive increase, multiplicative decrease, as in TCP congestion control.
+// The limit starts at 1 and grows by one each time a full window (limit segments in a row)
+// finishes within the target latency, up to max. A segment slower than the target halves it.
+// Only segments started after the last decrease can decrease it again, so the slow segments
+// already in flight when the source got loaded count as one signal.
+// A nil controller imposes no limit.
+type aimdController struct {
+	mu       sync.Mutex
+	room     *sync.Cond
+	limit    int
+	max      int
+	target   time.Duration
+	inFlight int
+	fast     int // Segments within target since the last change of limit
+	epoch    int // Incremented on every decrease
+}
+
+// aimdTicket identifies a running segment to the controller.
+type aimdTicket struct {
+	epoch int
+}
+
+// newAIMDController creates a controller that lets up to maxLimit segments run while latency stays under target.
+func newAIMDController(maxLimit int, target time.Duration) *aimdController {
+	if maxLimit < 1 {
+		maxLimit = 1
+	}
+	c := &aimdController{limit: 1, max: maxLimit, target: target}
+	c.room = sync.NewCond(&c.mu)
+	return c
+}
+
+// wait blocks until fewer segments than the limit are running.
+// Only the dispatcher starts segments, so there is still room when it calls start.
+func (c *aimdController) wait() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inFlight >= c.limit {
+		c.room.Wait()
+	}
+}
+
+// start records a segment starting.
+func (c *aimdController) start() aimdTicket {
+	if c == nil {
+		return aimdTicket{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight++
+	return aimdTicket{epoch: c.epoch}
+}
+
+// finish records a segment that took latency and adjusts the limit.
+// Returns the limits before and after, which differ if it changed.
+func (c *aimdController) finish(ticket aimdTicket, latency time.Duration) (before, after int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	before = c.limit
+
+	if latency > c.target {
+		if ticket.epoch == c.epoch {
+			c.limit = max(c.limit/2, 1)
+			c.fast = 0
+			c.epoch++
+		}
+	} else {
+		c.fast++
+		if c.fast >= c.limit && c.limit < c.max {
+			c.limit++
+			c.fast = 0
+		}
+	}
+	c.room.Broadcast()
+	return before, c.limit
+}
+
+// Limit returns the current limit (0 for a nil controller).
+func (c *aimdController) Limit() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAIMDController(t *testing.T) {
+	const target = time.Second
+	fast, slow := target/2, 2*target
+	c := newAIMDController(4, target)
+
+	// runWindow starts n segments and finishes them with latency, returning the limit afterwards
+	runWindow := func(n int, latency time.Duration) int {
+		tickets := make([]aimdTicket, n)
+		for i := range tickets {
+			tickets[i] = c.start()
+		}
+		for _, ticket := range tickets {
+			c.finish(ticket, latency)
+		}
+		return c.Limit()
+	}
+
+	if got := c.Limit(); got != 1 {
+		t.Fatalf("initial Limit() = %d, want 1", got)
+	}
+
+	// Additive increase: one more per full window of fast segments, capped at max
+	for _, want := range []int{2, 3, 4, 4} {
+		if got := runWindow(c.Limit(), fast); got != want {
+			t.Fatalf("Limit() after a fast window = %d, want %d", got, want)
+		}
+	}
+
+	// Multiplicative decrease: the first slow segment halves the limit, and the other slow ones
+	// started before that decrease don't halve it again
+	if got := runWindow(4, slow); got != 2 {
+		t.Fatalf("Limit() after a slow window at 4 = %d, want 2", got)
+	}
+	if got := runWindow(2, slow); got != 1 {
+		t.Fatalf("Limit() after a slow window at 2 = %d, want 1", got)
+	}
+	if got := runWindow(1, slow); got != 1 {
+		t.Fatalf("Limit() after a slow segment at 1 = %d, want 1 (the minimum)", got)
+	}
+
+	// Latency back under target: the limit grows again
+	if got := runWindow(1, fast); got != 2 {
+		t.Fatalf("Limit() after recovering = %d, want 2", got)
+	}
+
+	// A slow segment in a mixed window still backs off
+	ticket := c.start()
+	c.finish(c.start(), fast)
+	if before, after := c.finish(ticket, slow); before != 2 || after != 1 {
+		t.Errorf("finish() of a slow segment = %d -> %d, want 2 -> 1", before, after)
+	}
+
+	// Disabled
+	var disabled *aimdController
+	disabled.wait()
+	if before, after := disabled.finish(disabled.start(), slow); before != 0 || after != 0 || disabled.Limit() != 0 {
+		t.Error("nil controller should impose no limit")
+	}
+}
+
+func TestAIMDController_Wait(t *testing.T) {
+	c := newAIMDController(4, time.Second)
+	ticket := c.start()
+
+	// At the limit of 1, wait blocks until the running segment finishes
+	waited := make(chan struct{})
+	go func() {
+		c.wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("wait() returned while the limit was reached")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.finish(ticket, time.Millisecond)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("wait() didn't return after a segment finished")
+	}
+}
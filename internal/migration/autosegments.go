@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"go.uber.org/zap"
+)
+
+// maxAutoSegments caps the segment count -auto-segments picks, one segment per two-character hash prefix.
+const maxAutoSegments = 256
+
+// TenantRowCounter counts the tenant's rows in the source.
+// This allows mocking in tests.
+type TenantRowCounter interface {
+	CountTenantRows() (int64, error)
+}
+
+// RecommendSegmentCount returns the segment count giving about rowsPerSegment rows per segment:
+// ceil(rows / rowsPerSegment), clamped to [1, 256].
+func RecommendSegmentCount(rows int64, rowsPerSegment int) int {
+	if rowsPerSegment < 1 {
+		rowsPerSegment = 1
+	}
+	count := (rows + int64(rowsPerSegment) - 1) / int64(rowsPerSegment)
+	if count < 1 {
+		return 1
+	}
+	if count > maxAutoSegments {
+		return maxAutoSegments
+	}
+	return int(count)
+}
+
+// AutoSegments counts the tenant's rows and returns the segment count for -auto-segments.
+// Hashes are spread evenly, so each segment holds about rowsPerSegment rows (more once the 256 cap is hit).
+func AutoSegments(counter TenantRowCounter, rowsPerSegment int, logger *zap.Logger) (int, error) {
+	rows, err := counter.CountTenantRows()
+	if err != nil {
+		return 0, err
+	}
+	segments := RecommendSegmentCount(rows, rowsPerSegment)
+	logger.Info("Picked segment count from the tenant's row count",
+		zap.Int64("rows", rows),
+		zap.Int("rows_per_segment", rowsPerSegment),
+		zap.Int("segments", segments),
+		zap.Int64("expected_rows_per_segment", rows/int64(segments)),
+		zap.Bool("capped", segments == maxAutoSegments && rows > int64(maxAutoSegments)*int64(rowsPerSegment)))
+	return segments, nil
+}
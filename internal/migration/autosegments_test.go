@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeRowCounter returns a fixed tenant row count
+type fakeRowCounter struct {
+	rows int64
+	err  error
+}
+
+func (f fakeRowCounter) CountTenantRows() (int64, error) {
+	return f.rows, f.err
+}
+
+func TestAutoSegments(t *testing.T) {
+	tests := []struct {
+		name           string
+		rows           int64
+		rowsPerSegment int
+		want           int
+	}{
+		{"empty tenant", 0, 500000, 1},
+		{"below one segment", 1200, 500000, 1},
+		{"exactly one segment", 500000, 500000, 1},
+		{"rounds up", 500001, 500000, 2},
+		{"ten million rows", 10000000, 500000, 20},
+		{"odd split", 7300000, 500000, 15},
+		{"smaller target", 7300000, 100000, 73},
+		{"capped at 256", 500000000, 500000, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			got, err := AutoSegments(fakeRowCounter{rows: tt.rows}, tt.rowsPerSegment, zap.New(core))
+			if err != nil {
+				t.Fatalf("AutoSegments() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AutoSegments() = %d, want %d", got, tt.want)
+			}
+			// ceil(rows / rows-per-segment), clamped to [1, 256]
+			if formula := int((tt.rows + int64(tt.rowsPerSegment) - 1) / int64(tt.rowsPerSegment)); formula >= 1 && formula <= 256 && got != formula {
+				t.Errorf("AutoSegments() = %d, want ceil(%d / %d) = %d", got, tt.rows, tt.rowsPerSegment, formula)
+			}
+
+			entries := logs.FilterMessage("Picked segment count from the tenant's row count").All()
+			if len(entries) != 1 {
+				t.Fatalf("expected one log entry with the rationale, got %d", len(entries))
+			}
+			fields := entries[0].ContextMap()
+			if fields["rows"] != tt.rows || fields["segments"] != int64(tt.want) {
+				t.Errorf("logged rows = %v, segments = %v, want %d and %d", fields["rows"], fields["segments"], tt.rows, tt.want)
+			}
+		})
+	}
+
+	if _, err := AutoSegments(fakeRowCounter{err: errors.New("connection refused")}, 500000, zap.NewNop()); err == nil {
+		t.Error("AutoSegments() should return the count error")
+	}
+}
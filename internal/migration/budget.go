@@ -0,0 +1,186 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+)
+
+// Phase is a resource-consuming phase of the migration that draws from the concurrency budget.
+type Phase string
+
+const (
+	PhaseExport Phase = "export" // MariaDB segment reads
+	PhaseUpload Phase = "upload" // S3 part uploads
+	PhaseLoad   Phase = "load"   // Aurora LOAD DATA statements
+)
+
+// phases lists all budgeted phases in a fixed order.
+var phases = []Phase{PhaseExport, PhaseUpload, PhaseLoad}
+
+// DefaultConcurrencyWeights favours export, which also drives uploads while it streams.
+const DefaultConcurrencyWeights = "export=2,upload=1,load=1"
+
+// Budget divides one overall concurrency budget between the migration phases.
+// Every phase gets at least one slot and the shares never sum to more than the total,
+// so concurrent operations across all phases stay within the budget.
+// A nil Budget imposes no limits.
+type Budget struct {
+	total  int
+	shares map[Phase]int
+	slots  map[Phase]chan struct{}
+}
+
+// NewBudget creates a budget of total slots divided between phases by weights
+// (comma-separated phase=weight pairs, e.g. "export=2,upload=1,load=1").
+func NewBudget(total int, weights string) (*Budget, error) {
+	if total < len(phases) {
+		return nil, fmt.Errorf("concurrency budget must be at least %d (one slot per phase), got %d", len(phases), total)
+	}
+	if weights == "" {
+		weights = DefaultConcurrencyWeights
+	}
+	phaseWeights, err := ParseConcurrencyWeights(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	// One slot per phase, then the remainder split by weight (rounded down)
+	shares := make(map[Phase]int, len(phases))
+	totalWeight := 0
+	for _, phase := range phases {
+		shares[phase] = 1
+		totalWeight += phaseWeights[phase]
+	}
+	remaining := total - len(phases)
+	if totalWeight > 0 {
+		for _, phase := range phases {
+			shares[phase] += remaining * phaseWeights[phase] / totalWeight
+		}
+	}
+
+	slots := make(map[Phase]chan struct{}, len(phases))
+	for _, phase := range phases {
+		slots[phase] = make(chan struct{}, shares[phase])
+	}
+
+	return &Budget{total: total, shares: shares, slots: slots}, nil
+}
+
+// NewBudgetFromConfig creates the budget configured by -concurrency-budget.
+// Returns nil (no limits) if no budget is configured.
+func NewBudgetFromConfig(cfg *config.Config) (*Budget, error) {
+	if cfg.ConcurrencyBudget <= 0 {
+		return nil, nil
+	}
+	return NewBudget(cfg.ConcurrencyBudget, cfg.ConcurrencyWeights)
+}
+
+// ParseConcurrencyWeights parses comma-separated phase=weight pairs.
+// Phases that are not listed get weight 0 (they keep their single guaranteed slot).
+func ParseConcurrencyWeights(weights string) (map[Phase]int, error) {
+	result := make(map[Phase]int, len(phases))
+	for _, pair := range strings.Split(weights, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid concurrency weight %q (expected phase=weight)", pair)
+		}
+
+		phase := Phase(strings.TrimSpace(name))
+		known := false
+		for _, p := range phases {
+			if p == phase {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown phase %q in concurrency weights (expected export, upload or load)", phase)
+		}
+		if _, dup := result[phase]; dup {
+			return nil, fmt.Errorf("duplicate phase %q in concurrency weights", phase)
+		}
+
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for phase %s", value, phase)
+		}
+		result[phase] = weight
+	}
+	return result, nil
+}
+
+// Total returns the overall concurrency budget.
+func (b *Budget) Total() int {
+	return b.total
+}
+
+// Share returns the number of concurrent operations allowed for a phase.
+func (b *Budget) Share(phase Phase) int {
+	return b.shares[phase]
+}
+
+// Acquire blocks until a slot for phase is available.
+func (b *Budget) Acquire(phase Phase) {
+	if b == nil {
+		return
+	}
+	b.slots[phase] <- struct{}{}
+}
+
+// Release returns a slot acquired for phase.
+func (b *Budget) Release(phase Phase) {
+	if b == nil {
+		return
+	}
+	<-b.slots[phase]
+}
+
+// String describes the per-phase shares, e.g. "export=4,upload=2,load=2".
+func (b *Budget) String() string {
+	parts := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		parts = append(parts, fmt.Sprintf("%s=%d", phase, b.shares[phase]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// budgetedStreamCreator wraps a stream creator so each part upload draws from the upload share.
+type budgetedStreamCreator struct {
+	creator exporter.MultipartUploadStreamCreator
+	budget  *Budget
+}
+
+func (c *budgetedStreamCreator) NewMultipartUploadStream(s3Key string) (exporter.MultipartUploadStreamer, error) {
+	stream, err := c.creator.NewMultipartUploadStream(s3Key)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedStream{MultipartUploadStreamer: stream, budget: c.budget}, nil
+}
+
+// budgetedStream holds an upload slot for the duration of each part upload.
+type budgetedStream struct {
+	exporter.MultipartUploadStreamer
+	budget *Budget
+}
+
+func (s *budgetedStream) UploadPart(data []byte) error {
+	s.budget.Acquire(PhaseUpload)
+	defer s.budget.Release(PhaseUpload)
+	return s.MultipartUploadStreamer.UploadPart(data)
+}
+
+// KeepParts keeps the parts of a previous run, which upload nothing and so don't draw from the budget.
+func (s *budgetedStream) KeepParts(n int32) error {
+	return exporter.KeepParts(s.MultipartUploadStreamer, n)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+)
+
+// concurrencyTracker records in-flight operations per phase and the peak total
+type concurrencyTracker struct {
+	mu        sync.Mutex
+	inFlight  map[Phase]int
+	total     int
+	peakTotal int
+	peak      map[Phase]int
+}
+
+func newConcurrencyTracker() *concurrencyTracker {
+	return &concurrencyTracker{inFlight: make(map[Phase]int), peak: make(map[Phase]int)}
+}
+
+func (c *concurrencyTracker) run(phase Phase) {
+	c.mu.Lock()
+	c.inFlight[phase]++
+	c.total++
+	if c.inFlight[phase] > c.peak[phase] {
+		c.peak[phase] = c.inFlight[phase]
+	}
+	if c.total > c.peakTotal {
+		c.peakTotal = c.total
+	}
+	c.mu.Unlock()
+
+	time.Sleep(2 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight[phase]--
+	c.total--
+	c.mu.Unlock()
+}
+
+// trackedStream counts part uploads as upload-phase operations
+type trackedStream struct {
+	tracker *concurrencyTracker
+}
+
+func (s *trackedStream) UploadPart(data []byte) error {
+	s.tracker.run(PhaseUpload)
+	return nil
+}
+
+func (s *trackedStream) Complete() error { return nil }
+func (s *trackedStream) Abort()          {}
+
+type trackedStreamCreator struct {
+	tracker *concurrencyTracker
+}
+
+func (c *trackedStreamCreator) NewMultipartUploadStream(s3Key string) (exporter.MultipartUploadStreamer, error) {
+	return &trackedStream{tracker: c.tracker}, nil
+}
+
+func TestBudget_SharesStayWithinBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		weights string
+		want    map[Phase]int
+	}{
+		{"default weights", 10, "", map[Phase]int{PhaseExport: 4, PhaseUpload: 2, PhaseLoad: 2}},
+		{"minimum budget", 3, "export=10,upload=1,load=1", map[Phase]int{PhaseExport: 1, PhaseUpload: 1, PhaseLoad: 1}},
+		{"export heavy", 12, "export=3,upload=1", map[Phase]int{PhaseExport: 7, PhaseUpload: 3, PhaseLoad: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget, err := NewBudget(tt.total, tt.weights)
+			if err != nil {
+				t.Fatalf("NewBudget() error = %v", err)
+			}
+			sum := 0
+			for phase, want := range tt.want {
+				if got := budget.Share(phase); got != want {
+					t.Errorf("Share(%s) = %d, want %d", phase, got, want)
+				}
+				sum += budget.Share(phase)
+			}
+			if sum > tt.total {
+				t.Errorf("shares sum to %d, exceeding budget %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestBudget_ConcurrentPhasesWithinBudget(t *testing.T) {
+	const total = 6
+	budget, err := NewBudget(total, "export=2,upload=1,load=1")
+	if err != nil {
+		t.Fatalf("NewBudget() error = %v", err)
+	}
+
+	tracker := newConcurrencyTracker()
+	uploader := &budgetedStreamCreator{creator: &trackedStreamCreator{tracker: tracker}, budget: budget}
+
+	var wg sync.WaitGroup
+	// Segment exports: each holds an export slot while reading and streams parts through the budgeted uploader
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			budget.Acquire(PhaseExport)
+			defer budget.Release(PhaseExport)
+
+			tracker.run(PhaseExport)
+			stream, err := uploader.NewMultipartUploadStream("key")
+			if err != nil {
+				t.Errorf("NewMultipartUploadStream() error = %v", err)
+				return
+			}
+			for part := 0; part < 3; part++ {
+				if err := stream.UploadPart([]byte("data")); err != nil {
+					t.Errorf("UploadPart() error = %v", err)
+				}
+			}
+		}()
+	}
+	// Aurora loads running at the same time
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			budget.Acquire(PhaseLoad)
+			defer budget.Release(PhaseLoad)
+			tracker.run(PhaseLoad)
+		}()
+	}
+	wg.Wait()
+
+	if tracker.peakTotal > total {
+		t.Errorf("peak concurrent operations %d exceeded budget %d", tracker.peakTotal, total)
+	}
+	for _, phase := range phases {
+		if tracker.peak[phase] > budget.Share(phase) {
+			t.Errorf("phase %s peaked at %d, exceeding its share %d", phase, tracker.peak[phase], budget.Share(phase))
+		}
+		if tracker.peak[phase] == 0 {
+			t.Errorf("phase %s never ran", phase)
+		}
+	}
+}
+
+func TestNewBudget_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		weights string
+	}{
+		{"budget below phase count", 2, ""},
+		{"unknown phase", 10, "export=1,compress=1"},
+		{"negative weight", 10, "export=-1"},
+		{"duplicate phase", 10, "export=1,export=2"},
+		{"missing weight", 10, "export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBudget(tt.total, tt.weights); err == nil {
+				t.Errorf("NewBudget(%d, %q) should fail", tt.total, tt.weights)
+			}
+		})
+	}
+}
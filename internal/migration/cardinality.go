@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// cardinalityFactor is how far the segment count may drift from the distinct prefix count before warning.
+const cardinalityFactor = 4
+
+// PrefixSampler returns the distinct hash prefixes present in the source.
+// This allows mocking in tests.
+type PrefixSampler interface {
+	DistinctHashPrefixes(prefixLen int) ([]string, error)
+}
+
+// CardinalityReport compares the requested segment count with the hash prefixes actually present.
+type CardinalityReport struct {
+	PrefixLen      int // Hex characters per segment boundary
+	PrefixSpace    int // Possible prefixes of PrefixLen characters
+	Distinct       int // Prefixes present in the source
+	Requested      int // Segments requested with -segments
+	EmptySegments  int // Requested segments that contain none of the present prefixes
+	Recommended    int // Suggested -segments: one per present prefix
+	TooManyForData bool
+	TooFewForData  bool
+}
+
+// CheckSegmentCardinality samples the distinct hash prefixes and warns when the segment count
+// far exceeds them (many empty segments) or is far below them (few huge segments).
+// Returns the report with a recommended segment count.
+func CheckSegmentCardinality(segments []segment.Segment, sampler PrefixSampler, logger *zap.Logger) (*CardinalityReport, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no segments to check")
+	}
+	prefixLen := segments[0].PrefixLen()
+	prefixes, err := sampler.DistinctHashPrefixes(prefixLen)
+	if err != nil {
+		return nil, err
+	}
+
+	report := recommendSegments(segments, prefixes)
+	fields := []zap.Field{
+		zap.Int("requested_segments", report.Requested),
+		zap.Int("distinct_prefixes", report.Distinct),
+		zap.Int("prefix_len", report.PrefixLen),
+		zap.Int("prefix_space", report.PrefixSpace),
+		zap.Int("empty_segments", report.EmptySegments),
+		zap.Int("recommended_segments", report.Recommended),
+	}
+	switch {
+	case report.TooManyForData:
+		logger.Warn("Segment count far exceeds the hash prefixes present, many segments will be empty", fields...)
+	case report.TooFewForData:
+		logger.Warn("Segment count is far below the hash prefixes present, segments will be very large", fields...)
+	default:
+		logger.Info("Segment count matches hash prefix cardinality", fields...)
+	}
+	return report, nil
+}
+
+// recommendSegments builds the cardinality report for segments given the distinct prefixes present.
+func recommendSegments(segments []segment.Segment, prefixes []string) *CardinalityReport {
+	prefixLen := segments[0].PrefixLen()
+	prefixSpace := 1
+	for i := 0; i < prefixLen; i++ {
+		prefixSpace *= 16
+	}
+
+	report := &CardinalityReport{
+		PrefixLen:   prefixLen,
+		PrefixSpace: prefixSpace,
+		Distinct:    len(prefixes),
+		Requested:   len(segments),
+		Recommended: len(prefixes),
+	}
+	if report.Recommended < 1 {
+		report.Recommended = 1
+	}
+
+	// Prefixes are sorted, so walk segments and prefixes together
+	next := 0
+	for _, seg := range segments {
+		for next < len(prefixes) && prefixes[next] < seg.StartHex {
+			next++
+		}
+		if next >= len(prefixes) || !segment.HashInSegment(prefixes[next], seg) {
+			report.EmptySegments++
+		}
+	}
+
+	report.TooManyForData = report.Requested > cardinalityFactor*report.Recommended
+	report.TooFewForData = report.Requested*cardinalityFactor < report.Distinct
+	return report
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakePrefixSampler returns fixed prefixes and records the requested prefix length
+type fakePrefixSampler struct {
+	prefixes  []string
+	prefixLen int
+}
+
+func (f *fakePrefixSampler) DistinctHashPrefixes(prefixLen int) ([]string, error) {
+	f.prefixLen = prefixLen
+	return f.prefixes, nil
+}
+
+// evenPrefixes returns count prefixes of width hex chars spread evenly over the prefix space
+func evenPrefixes(count, width, space int) []string {
+	var prefixes []string
+	for i := 0; i < count; i++ {
+		prefixes = append(prefixes, fmt.Sprintf("%0*x", width, i*space/count))
+	}
+	return prefixes
+}
+
+func TestCheckSegmentCardinality(t *testing.T) {
+	tests := []struct {
+		name            string
+		segments        int
+		prefixes        []string
+		wantPrefixLen   int
+		wantRecommended int
+		wantEmpty       int
+		wantWarning     string
+	}{
+		{
+			name:            "few prefixes leave most segments empty",
+			segments:        16,
+			prefixes:        []string{"0a", "0b", "c3"},
+			wantPrefixLen:   2,
+			wantRecommended: 3,
+			wantEmpty:       14,
+			wantWarning:     "far exceeds",
+		},
+		{
+			name:            "no data",
+			segments:        16,
+			prefixes:        nil,
+			wantPrefixLen:   2,
+			wantRecommended: 1,
+			wantEmpty:       16,
+			wantWarning:     "far exceeds",
+		},
+		{
+			name:            "all prefixes with few segments",
+			segments:        16,
+			prefixes:        evenPrefixes(256, 2, 256),
+			wantPrefixLen:   2,
+			wantRecommended: 256,
+			wantEmpty:       0,
+			wantWarning:     "far below",
+		},
+		{
+			name:            "matching cardinality",
+			segments:        64,
+			prefixes:        evenPrefixes(64, 2, 256),
+			wantPrefixLen:   2,
+			wantRecommended: 64,
+			wantEmpty:       0,
+		},
+		{
+			name:            "wide prefixes",
+			segments:        1024,
+			prefixes:        []string{"000", "001", "fff"},
+			wantPrefixLen:   3,
+			wantRecommended: 3,
+			wantEmpty:       1022,
+			wantWarning:     "far exceeds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := segment.SegmentHashSpaceN(tt.segments, segment.PrefixLenForSegments(tt.segments))
+			if err != nil {
+				t.Fatalf("SegmentHashSpaceN() error = %v", err)
+			}
+			sampler := &fakePrefixSampler{prefixes: tt.prefixes}
+			core, logs := observer.New(zap.InfoLevel)
+
+			report, err := CheckSegmentCardinality(segments, sampler, zap.New(core))
+			if err != nil {
+				t.Fatalf("CheckSegmentCardinality() error = %v", err)
+			}
+
+			if sampler.prefixLen != tt.wantPrefixLen {
+				t.Errorf("sampled prefix length %d, want %d", sampler.prefixLen, tt.wantPrefixLen)
+			}
+			if report.Recommended != tt.wantRecommended {
+				t.Errorf("Recommended = %d, want %d", report.Recommended, tt.wantRecommended)
+			}
+			if report.EmptySegments != tt.wantEmpty {
+				t.Errorf("EmptySegments = %d, want %d", report.EmptySegments, tt.wantEmpty)
+			}
+
+			warnings := logs.FilterLevelExact(zap.WarnLevel)
+			if tt.wantWarning == "" && warnings.Len() > 0 {
+				t.Errorf("expected no warning, got %q", warnings.All()[0].Message)
+			}
+			if tt.wantWarning != "" && warnings.FilterMessageSnippet(tt.wantWarning).Len() != 1 {
+				t.Errorf("expected a %q warning, got %d warnings", tt.wantWarning, warnings.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// ErrTimeBudgetExhausted is returned when -max-runtime stopped the run before every segment was dispatched.
+// The completed segments are in the checkpoint, so a -resume run continues with the rest.
+var ErrTimeBudgetExhausted = errors.New("time budget exhausted")
+
+// Checkpoint records the segments a run completed, so a -resume run only exports the rest.
+// It is written when a run stops early (-max-runtime or failed segments) and removed once a run completes.
+// With -checkpoint-interval it also records how far the unfinished segments got, and is written each time.
+type Checkpoint struct {
+	TenantID  int                              `json:"tenant_id"`
+	Table     string                           `json:"table"`
+	Segments  int                              `json:"segments"` // -segments of the run, a resumed run must use the same
+	Completed []CheckpointSegment              `json:"completed"`
+	Progress  map[int]exporter.SegmentProgress `json:"progress,omitempty"` // Unfinished segments by index
+}
+
+// CheckpointSegment is a completed segment and its CSV file (no key or path if the segment had no rows).
+type CheckpointSegment struct {
+	Index         int    `json:"index"`
+	StartHex      string `json:"start_hex"`
+	EndHex        string `json:"end_hex"`
+	S3Key         string `json:"s3_key,omitempty"`
+	FilePath      string `json:"file_path,omitempty"`
+	Rows          int    `json:"rows"`
+	SourceChanged bool   `json:"source_changed,omitempty"`
+	Checksum      string `json:"checksum,omitempty"`
+}
+
+// CheckpointPath returns the checkpoint file of the tenant's table in cfg: <log-dir>/checkpoints/tenant-<id>.<table>.json.
+func CheckpointPath(cfg *config.Config) string {
+	return filepath.Join(cfg.LogDir, "checkpoints", fmt.Sprintf("tenant-%d.%s.json", cfg.TenantID, cfg.TableName))
+}
+
+// LoadCheckpoint reads the checkpoint at path. Returns nil if there is none.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to path atomically, creating its directory.
+func (c *Checkpoint) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// add records a completed segment with its CSV files (none if it had no rows).
+func (c *Checkpoint) add(seg segment.Segment, csvFiles []exporter.CSVFile) {
+	delete(c.Progress, seg.Index)
+	if len(csvFiles) == 0 {
+		c.Completed = append(c.Completed, CheckpointSegment{Index: seg.Index, StartHex: seg.StartHex, EndHex: seg.EndHex})
+		return
+	}
+	for _, csvFile := range csvFiles {
+		c.Completed = append(c.Completed, CheckpointSegment{
+			Index:         seg.Index,
+			StartHex:      seg.StartHex,
+			EndHex:        seg.EndHex,
+			S3Key:         csvFile.S3Key,
+			FilePath:      csvFile.FilePath,
+			Rows:          csvFile.RowCount,
+			SourceChanged: csvFile.SourceChanged,
+			Checksum:      csvFile.Checksum,
+		})
+	}
+}
+
+// CSVFiles returns the CSV files of the completed segments, for the SQL of a resumed run.
+func (c *Checkpoint) CSVFiles() []exporter.CSVFile {
+	var csvFiles []exporter.CSVFile
+	for _, completed := range c.Completed {
+		if completed.S3Key == "" && completed.FilePath == "" {
+			continue // Segment had no rows
+		}
+		csvFiles = append(csvFiles, exporter.CSVFile{
+			FilePath:      completed.FilePath,
+			S3Key:         completed.S3Key,
+			Segment:       segment.Segment{Index: completed.Index, StartHex: completed.StartHex, EndHex: completed.EndHex},
+			RowCount:      completed.Rows,
+			SourceChanged: completed.SourceChanged,
+			Checksum:      completed.Checksum,
+		})
+	}
+	return csvFiles
+}
+
+// remaining returns the segments the checkpoint doesn't list as completed.
+func (c *Checkpoint) remaining(segments []segment.Segment) []segment.Segment {
+	done := make(map[int]bool, len(c.Completed))
+	for _, completed := range c.Completed {
+		done[completed.Index] = true
+	}
+	var remaining []segment.Segment
+	for _, seg := range segments {
+		if !done[seg.Index] {
+			remaining = append(remaining, seg)
+		}
+	}
+	return remaining
+}
+
+// checkpointProgress records the progress of unfinished segments (-checkpoint-interval) in the checkpoint,
+// writing it each time so a run that crashes can be resumed from there too.
+type checkpointProgress struct {
+	mu         *sync.Mutex // Shared with the recording of completed segments
+	checkpoint *Checkpoint
+	path       string
+}
+
+func (p *checkpointProgress) SegmentProgress(seg segment.Segment) *exporter.SegmentProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	progress, ok := p.checkpoint.Progress[seg.Index]
+	if !ok {
+		return nil
+	}
+	return &progress
+}
+
+func (p *checkpointProgress) RecordProgress(seg segment.Segment, progress exporter.SegmentProgress) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.checkpoint.Progress == nil {
+		p.checkpoint.Progress = make(map[int]exporter.SegmentProgress)
+	}
+	p.checkpoint.Progress[seg.Index] = progress
+	return p.checkpoint.Save(p.path)
+}
+
+// loadResumeCheckpoint returns the checkpoint a -resume run continues from, or a new empty checkpoint.
+func loadResumeCheckpoint(cfg *config.Config, path string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{TenantID: cfg.TenantID, Table: cfg.TableName, Segments: cfg.Segments}
+	if !cfg.Resume {
+		return checkpoint, nil
+	}
+	saved, err := LoadCheckpoint(path)
+	if err != nil || saved == nil {
+		return checkpoint, err
+	}
+	if saved.TenantID != cfg.TenantID || saved.Table != cfg.TableName {
+		return nil, fmt.Errorf("checkpoint %s is for tenant %d table %s, not tenant %d table %s",
+			path, saved.TenantID, saved.Table, cfg.TenantID, cfg.TableName)
+	}
+	if saved.Segments != cfg.Segments {
+		return nil, fmt.Errorf("checkpoint %s was written with -segments %d, resume with the same (got %d)",
+			path, saved.Segments, cfg.Segments)
+	}
+	return saved, nil
+}
+
+// dispatchWithCheckpoint runs dispatchSegments, recording each completed segment in a checkpoint.
+// With -resume the segments a saved checkpoint lists are skipped and their CSV files returned first.
+// If dispatch fails or stops early, the checkpoint is saved for a -resume run; once it succeeds, it is removed.
+// With -checkpoint-interval exp (nil in tests) records segment progress in the checkpoint and continues from it.
+func dispatchWithCheckpoint(ctx context.Context, segments []segment.Segment, cfg *config.Config, budget *Budget, control *controlFile, exp *exporter.Exporter, process segmentProcessor, logger *zap.Logger) ([]exporter.CSVFile, error) {
+	path := CheckpointPath(cfg)
+	checkpoint, err := loadResumeCheckpoint(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	resumed := checkpoint.CSVFiles()
+	if len(checkpoint.Completed) > 0 || len(checkpoint.Progress) > 0 {
+		segments = checkpoint.remaining(segments)
+		logger.Info("Resuming from checkpoint",
+			zap.String("checkpoint", path),
+			zap.Int("completed_segments", len(checkpoint.Completed)),
+			zap.Int("unfinished_segments", len(checkpoint.Progress)),
+			zap.Int("remaining_segments", len(segments)))
+	}
+
+	var mu sync.Mutex
+	if cfg.CheckpointInterval > 0 && exp != nil {
+		exp.SetProgressRecorder(&checkpointProgress{mu: &mu, checkpoint: checkpoint, path: path})
+		defer exp.SetProgressRecorder(nil)
+	}
+	csvFiles, dispatchErr := dispatchSegments(ctx, segments, cfg, budget, control, func(seg segment.Segment) ([]exporter.CSVFile, error) {
+		csvFiles, err := process(seg)
+		if err == nil {
+			mu.Lock()
+			checkpoint.add(seg, csvFiles)
+			mu.Unlock()
+		}
+		return csvFiles, err
+	}, logger)
+
+	if dispatchErr != nil {
+		// Record the completed segments so a -resume run continues with the rest
+		if len(checkpoint.Completed) > 0 || len(checkpoint.Progress) > 0 {
+			if err := checkpoint.Save(path); err != nil {
+				logger.Error("Failed to write checkpoint", zap.String("checkpoint", path), zap.Error(err))
+			} else {
+				logger.Info("Wrote checkpoint of completed segments, re-run with -resume to continue",
+					zap.String("checkpoint", path),
+					zap.Int("completed_segments", len(checkpoint.Completed)),
+					zap.Int("unfinished_segments", len(checkpoint.Progress)))
+			}
+		}
+		return nil, dispatchErr
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove checkpoint", zap.String("checkpoint", path), zap.Error(err))
+	}
+	return append(resumed, csvFiles...), nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestDispatchWithCheckpoint_MaxRuntime(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	segments, err := segment.SegmentHashSpace(8)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", Segments: 8, MaxParallelSegs: 1, LogDir: t.TempDir()}
+
+	// Each segment takes 20ms; segment 1 has no rows
+	var mu sync.Mutex
+	var processed []int
+	process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		processed = append(processed, s.Index)
+		mu.Unlock()
+		if s.Index == 1 {
+			return nil, nil
+		}
+		return []exporter.CSVFile{{S3Key: fmt.Sprintf("key-%d", s.Index), Segment: s, RowCount: 10}}, nil
+	}
+
+	// A tiny budget stops the run well before all 8 segments
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dispatchWithCheckpoint(ctx, segments, cfg, nil, nil, nil, process, logger)
+	if !errors.Is(err, ErrTimeBudgetExhausted) {
+		t.Fatalf("dispatchWithCheckpoint() error = %v, want ErrTimeBudgetExhausted", err)
+	}
+	if len(processed) == 0 || len(processed) >= len(segments) {
+		t.Fatalf("processed segments %v, want the run to stop early", processed)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("run took %s, want it to stop near the 70ms budget", elapsed)
+	}
+
+	checkpoint, err := LoadCheckpoint(CheckpointPath(cfg))
+	if err != nil || checkpoint == nil {
+		t.Fatalf("LoadCheckpoint() = %v, %v, want the checkpoint", checkpoint, err)
+	}
+	var completed []int
+	for _, seg := range checkpoint.Completed {
+		completed = append(completed, seg.Index)
+	}
+	sort.Ints(completed)
+	if fmt.Sprint(completed) != fmt.Sprint(processed) {
+		t.Errorf("checkpoint lists segments %v, want the completed %v", completed, processed)
+	}
+
+	// -resume exports only the rest and returns every segment's CSV file
+	firstRun := len(processed)
+	processed = nil
+	cfg.Resume = true
+	csvFiles, err := dispatchWithCheckpoint(context.Background(), segments, cfg, nil, nil, nil, process, logger)
+	if err != nil {
+		t.Fatalf("dispatchWithCheckpoint() with -resume error = %v", err)
+	}
+	if len(processed) != len(segments)-firstRun || processed[0] != firstRun {
+		t.Errorf("resumed run processed %v, want segments %d-7", processed, firstRun)
+	}
+	if len(csvFiles) != 7 {
+		t.Errorf("expected 7 CSV files (segment 1 is empty), got %d", len(csvFiles))
+	}
+	if _, err := os.Stat(CheckpointPath(cfg)); !os.IsNotExist(err) {
+		t.Errorf("checkpoint should be removed after the run completes, stat error = %v", err)
+	}
+}
+
+func TestLoadResumeCheckpoint_Mismatch(t *testing.T) {
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", Segments: 16, Resume: true, LogDir: t.TempDir()}
+	path := CheckpointPath(cfg)
+	if err := (&Checkpoint{TenantID: 1, Table: "fis_aggr", Segments: 8}).Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := loadResumeCheckpoint(cfg, path); err == nil {
+		t.Error("loadResumeCheckpoint() should reject a checkpoint written with other -segments")
+	}
+
+	// Without -resume the saved checkpoint is ignored
+	cfg.Resume = false
+	checkpoint, err := loadResumeCheckpoint(cfg, path)
+	if err != nil || len(checkpoint.Completed) != 0 {
+		t.Errorf("loadResumeCheckpoint() without -resume = %+v, %v, want an empty checkpoint", checkpoint, err)
+	}
+}
+
+func TestCheckpointProgress(t *testing.T) {
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", Segments: 8, Resume: true, LogDir: t.TempDir()}
+	path := CheckpointPath(cfg)
+	seg := segment.Segment{Index: 2, StartHex: "40", EndHex: "60"}
+
+	var mu sync.Mutex
+	recorder := &checkpointProgress{mu: &mu, checkpoint: &Checkpoint{TenantID: 1, Table: "fis_aggr", Segments: 8}, path: path}
+	if progress := recorder.SegmentProgress(seg); progress != nil {
+		t.Fatalf("SegmentProgress() = %+v before any was recorded, want nil", progress)
+	}
+
+	// Each record is written, so a crashed run resumes from it
+	want := exporter.SegmentProgress{LastHash: "4f00", Parts: 3, Rows: 300, Digest: []byte{1, 2, 3}}
+	if err := recorder.RecordProgress(seg, want); err != nil {
+		t.Fatalf("RecordProgress() error = %v", err)
+	}
+	saved, err := loadResumeCheckpoint(cfg, path)
+	if err != nil {
+		t.Fatalf("loadResumeCheckpoint() error = %v", err)
+	}
+	resumed := &checkpointProgress{mu: &mu, checkpoint: saved, path: path}
+	got := resumed.SegmentProgress(seg)
+	if got == nil || got.LastHash != want.LastHash || got.Parts != want.Parts || got.Rows != want.Rows || string(got.Digest) != string(want.Digest) {
+		t.Fatalf("SegmentProgress() after reload = %+v, want %+v", got, want)
+	}
+
+	// The segment is still to export, and its progress is dropped once it completes
+	segments, _ := segment.SegmentHashSpace(8)
+	if remaining := saved.remaining(segments); len(remaining) != 8 {
+		t.Errorf("remaining() = %d segments, want all 8", len(remaining))
+	}
+	saved.add(seg, []exporter.CSVFile{{S3Key: "key-2", Segment: seg, RowCount: 500}})
+	if resumed.SegmentProgress(seg) != nil {
+		t.Error("progress of a completed segment should be dropped")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap"
+)
+
+// Control file commands written by operators.
+const (
+	ControlPause  = "pause"
+	ControlResume = "resume"
+)
+
+// controlFile lets operators pause segment dispatch without killing the migration.
+// When the file contains "pause", no new segments are dispatched (in-flight segments finish)
+// until it contains "resume" or is removed. A nil controlFile never pauses.
+type controlFile struct {
+	path     string
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// newControlFile returns the control file configured by -control-file, or nil if not set.
+func newControlFile(cfg *config.Config, logger *zap.Logger) *controlFile {
+	if cfg.ControlFile == "" {
+		return nil
+	}
+	interval := time.Duration(cfg.ControlPollInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &controlFile{path: cfg.ControlFile, interval: interval, logger: logger}
+}
+
+// paused reports whether the control file currently asks for a pause.
+// A missing or unreadable file means running.
+func (c *controlFile) paused() bool {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Warn("Failed to read control file, continuing",
+				zap.String("control_file", c.path),
+				zap.Error(err))
+		}
+		return false
+	}
+	return strings.ToLower(strings.TrimSpace(string(data))) == ControlPause
+}
+
+// waitWhilePaused blocks while the control file says pause, polling at the configured interval.
+// Returns early when ctx is done (-max-runtime ran out or -fail-fast-abort), for the caller to stop dispatching.
+func (c *controlFile) waitWhilePaused(ctx context.Context) {
+	if c == nil || !c.paused() {
+		return
+	}
+
+	c.logger.Warn("Migration paused by control file, waiting for resume",
+		zap.String("control_file", c.path),
+		zap.Duration("poll_interval", c.interval))
+	start := time.Now()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for c.paused() {
+		select {
+		case <-ctx.Done():
+			c.logger.Warn("Run stopped while paused by control file",
+				zap.String("control_file", c.path),
+				zap.Duration("paused_for", time.Since(start)),
+				zap.Error(context.Cause(ctx)))
+			return
+		case <-ticker.C:
+		}
+	}
+	c.logger.Info("Migration resumed by control file",
+		zap.String("control_file", c.path),
+		zap.Duration("paused_for", time.Since(start)))
+}
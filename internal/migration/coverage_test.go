@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestValidateCoverage(t *testing.T) {
+	// One CSV file per two-character prefix, as from -segments 256
+	segments, err := segment.SegmentHashSpace(256)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	var csvFiles []exporter.CSVFile
+	for _, seg := range segments {
+		csvFiles = append(csvFiles, exporter.CSVFile{Segment: seg, RowCount: 10 + seg.Index%3})
+	}
+	exported := int64(0)
+	for _, csvFile := range csvFiles {
+		exported += int64(csvFile.RowCount)
+	}
+
+	tests := []struct {
+		name      string
+		source    int64
+		wantDelta int64
+		wantLog   string
+	}{
+		{"rows covered", exported, 0, "Exported rows cover the tenant's row count"},
+		{"rows missed at a boundary", exported + 2, -2, "Exported rows don't add up to the tenant's row count"},
+		{"rows exported twice", exported - 1, 1, "Exported rows don't add up to the tenant's row count"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			report, err := ValidateCoverage(csvFiles, fakeRowCounter{rows: tt.source}, zap.New(core))
+			if err != nil {
+				t.Fatalf("ValidateCoverage() error = %v", err)
+			}
+			if report.Segments != 256 || report.Exported != exported || report.Source != tt.source {
+				t.Errorf("report = %+v, want 256 segments, %d exported and %d source rows", report, exported, tt.source)
+			}
+			if report.Delta() != tt.wantDelta {
+				t.Errorf("Delta() = %d, want %d", report.Delta(), tt.wantDelta)
+			}
+			entries := logs.FilterMessage(tt.wantLog).All()
+			if len(entries) != 1 {
+				t.Fatalf("expected one %q log entry, got %d", tt.wantLog, len(entries))
+			}
+			if got := entries[0].ContextMap()["delta"]; got != tt.wantDelta {
+				t.Errorf("logged delta = %v, want %d", got, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestValidateCoverage_CountFails(t *testing.T) {
+	_, err := ValidateCoverage(nil, fakeRowCounter{err: errors.New("connection refused")}, zap.NewNop())
+	if err == nil {
+		t.Fatal("ValidateCoverage() should fail when the source can't be counted")
+	}
+}
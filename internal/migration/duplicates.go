@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// DuplicateFinder returns the hashes of a segment held by more than one row.
+// This allows mocking in tests.
+type DuplicateFinder interface {
+	SegmentDuplicates(seg segment.Segment) ([]exporter.DuplicateHash, bool, error)
+}
+
+// SegmentDuplicateHashes are the duplicate hashes found in one segment.
+type SegmentDuplicateHashes struct {
+	Segment   segment.Segment
+	Hashes    []exporter.DuplicateHash
+	Truncated bool // The segment holds more than exporter.MaxDuplicateHashes duplicate hashes
+}
+
+// DuplicateReport lists the segments holding duplicate hashes (-detect-duplicates), in segment order.
+type DuplicateReport struct {
+	Segments []SegmentDuplicateHashes // Only segments with duplicates
+	Checked  int                      // Segments checked
+	Hashes   int                      // Duplicate hashes found
+	Extra    int64                    // Rows beyond the first of each duplicate hash
+}
+
+// DetectDuplicates finds the duplicate hashes of every segment with up to parallel concurrent queries.
+// Duplicates mean the source lost its unique (tenantid, hash) constraint. The export keeps them all,
+// but the target's unique key keeps one row per hash on load (the first with IGNORE, the last with REPLACE).
+func DetectDuplicates(segments []segment.Segment, finder DuplicateFinder, parallel int, logger *zap.Logger) (*DuplicateReport, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no segments to check")
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	found := make([]SegmentDuplicateHashes, len(segments))
+	errs := make([]error, len(segments))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, seg := range segments {
+		wg.Add(1)
+		go func(i int, seg segment.Segment) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			hashes, truncated, err := finder.SegmentDuplicates(seg)
+			found[i] = SegmentDuplicateHashes{Segment: seg, Hashes: hashes, Truncated: truncated}
+			errs[i] = err
+		}(i, seg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &DuplicateReport{Checked: len(segments)}
+	for _, segDuplicates := range found {
+		if len(segDuplicates.Hashes) == 0 {
+			continue
+		}
+		report.Segments = append(report.Segments, segDuplicates)
+		report.Hashes += len(segDuplicates.Hashes)
+		for _, duplicate := range segDuplicates.Hashes {
+			report.Extra += duplicate.Rows - 1
+		}
+		logger.Warn("Segment holds duplicate hashes, the target's unique key keeps one row of each",
+			zap.Int("segment", segDuplicates.Segment.Index),
+			zap.Int("duplicate_hashes", len(segDuplicates.Hashes)),
+			zap.Bool("truncated", segDuplicates.Truncated),
+			zap.String("first_hash", segDuplicates.Hashes[0].Hash))
+	}
+	logger.Info("Checked segments for duplicate hashes",
+		zap.Int("segments", report.Checked),
+		zap.Int("segments_with_duplicates", len(report.Segments)),
+		zap.Int("duplicate_hashes", report.Hashes),
+		zap.Int64("extra_rows", report.Extra))
+	return report, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// mockDuplicateFinder returns canned duplicates per segment index
+type mockDuplicateFinder struct {
+	duplicates map[int][]exporter.DuplicateHash
+	truncated  map[int]bool
+	err        error
+}
+
+func (m *mockDuplicateFinder) SegmentDuplicates(seg segment.Segment) ([]exporter.DuplicateHash, bool, error) {
+	return m.duplicates[seg.Index], m.truncated[seg.Index], m.err
+}
+
+func TestDetectDuplicates(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	t.Run("duplicates reported", func(t *testing.T) {
+		finder := &mockDuplicateFinder{
+			duplicates: map[int][]exporter.DuplicateHash{
+				1: {{Hash: "40abc123", Rows: 3}},
+				3: {{Hash: "c0abc123", Rows: 2}, {Hash: "ffabc123", Rows: 2}},
+			},
+			truncated: map[int]bool{3: true},
+		}
+		core, logs := observer.New(zap.InfoLevel)
+		report, err := DetectDuplicates(segments, finder, 2, zap.New(core))
+		if err != nil {
+			t.Fatalf("DetectDuplicates() error = %v", err)
+		}
+		if report.Checked != 4 || report.Hashes != 3 || report.Extra != 4 {
+			t.Errorf("report = %d checked, %d hashes, %d extra rows, want 4, 3 and 4", report.Checked, report.Hashes, report.Extra)
+		}
+		if len(report.Segments) != 2 || report.Segments[0].Segment.Index != 1 || report.Segments[1].Segment.Index != 3 {
+			t.Fatalf("report segments = %+v, want segments 1 and 3 in order", report.Segments)
+		}
+		if report.Segments[0].Truncated || !report.Segments[1].Truncated {
+			t.Errorf("report segments = %+v, want only segment 3 truncated", report.Segments)
+		}
+		if got := logs.FilterLevelExact(zap.WarnLevel).Len(); got != 2 {
+			t.Errorf("got %d warnings, want one per segment with duplicates", got)
+		}
+	})
+
+	t.Run("no duplicates", func(t *testing.T) {
+		core, logs := observer.New(zap.InfoLevel)
+		report, err := DetectDuplicates(segments, &mockDuplicateFinder{}, 4, zap.New(core))
+		if err != nil {
+			t.Fatalf("DetectDuplicates() error = %v", err)
+		}
+		if report.Checked != 4 || report.Hashes != 0 || len(report.Segments) != 0 {
+			t.Errorf("report = %+v, want 4 segments checked and no duplicates", report)
+		}
+		if logs.FilterLevelExact(zap.WarnLevel).Len() != 0 {
+			t.Error("got a warning without duplicates")
+		}
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		finder := &mockDuplicateFinder{err: errors.New("connection lost")}
+		if _, err := DetectDuplicates(segments, finder, 1, zap.NewNop()); err == nil {
+			t.Error("DetectDuplicates() should fail when a segment can't be checked")
+		}
+	})
+}
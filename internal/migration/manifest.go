@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
+	"go.uber.org/zap"
+)
+
+// Manifest lists every segment of a run with its row count and row digest (-manifest), so -verify-manifest
+// can later check the loaded Aurora table without reading the source again.
+type Manifest struct {
+	TenantID int               `json:"tenant_id"`
+	Table    string            `json:"table"`
+	Created  time.Time         `json:"created"`
+	Segments []ManifestSegment `json:"segments"`
+}
+
+// ManifestSegment is one segment of the manifest and its CSV file (no key or path if the segment had no rows).
+type ManifestSegment struct {
+	Index    int    `json:"index"`
+	StartHex string `json:"start_hex"`
+	EndHex   string `json:"end_hex"`
+	S3Key    string `json:"s3_key,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+	Rows     int    `json:"rows"`
+	Checksum string `json:"checksum"` // exporter.RowDigest of the exported rows
+}
+
+// Segment returns the hash range of the manifest segment.
+func (s ManifestSegment) Segment() segment.Segment {
+	return segment.Segment{Index: s.Index, StartHex: s.StartHex, EndHex: s.EndHex}
+}
+
+// ManifestStore uploads and downloads the manifest object.
+// This allows mocking in tests.
+type ManifestStore interface {
+	UploadFileWithRetry(filepath, s3Key string) error
+	GetObject(s3Key string) ([]byte, error)
+}
+
+// NewManifest builds the manifest of a run from its segments and their CSV files.
+// Segments without a CSV file had no rows and get the digest of no rows, so rows that appear
+// in them on the target are caught too.
+func NewManifest(cfg *config.Config, segments []segment.Segment, csvFiles []exporter.CSVFile) *Manifest {
+	byIndex := make(map[int]exporter.CSVFile, len(csvFiles))
+	for _, csvFile := range csvFiles {
+		byIndex[csvFile.Segment.Index] = csvFile
+	}
+
+	manifest := &Manifest{TenantID: cfg.TenantID, Table: cfg.TableName, Created: time.Now().UTC()}
+	for _, seg := range segments {
+		entry := ManifestSegment{Index: seg.Index, StartHex: seg.StartHex, EndHex: seg.EndHex, Checksum: exporter.NewRowDigest().Sum()}
+		if csvFile, ok := byIndex[seg.Index]; ok {
+			entry.S3Key = csvFile.S3Key
+			entry.FilePath = csvFile.FilePath
+			entry.Rows = csvFile.RowCount
+			entry.Checksum = csvFile.Checksum
+		}
+		manifest.Segments = append(manifest.Segments, entry)
+	}
+	return manifest
+}
+
+// ManifestS3Key returns the manifest object of the tenant's table in cfg: <s3-prefix>/manifest/tenant-<id>.<table>.json.
+func ManifestS3Key(cfg *config.Config) string {
+	return fmt.Sprintf("%s/manifest/tenant-%d.%s.json", cfg.S3Prefix, cfg.TenantID, cfg.TableName)
+}
+
+// ManifestPath returns the local manifest of the tenant's table in cfg: <output-dir>/manifest/tenant-<id>.<table>.json.
+func ManifestPath(cfg *config.Config) string {
+	return filepath.Join(cfg.OutputDir, "manifest", fmt.Sprintf("tenant-%d.%s.json", cfg.TenantID, cfg.TableName))
+}
+
+// SaveManifest writes the manifest to -output-dir (if set) and uploads it with store (nil for local-only output).
+// Returns where it was written, for the summary.
+func SaveManifest(manifest *Manifest, cfg *config.Config, store ManifestStore) ([]string, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	// The upload reads from a file: the -output-dir copy, or a temporary one
+	var locations []string
+	file := ManifestPath(cfg)
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create manifest directory: %w", err)
+		}
+		locations = append(locations, file)
+	} else {
+		tmp, err := os.CreateTemp("", "manifest-*.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		tmp.Close()
+		file = tmp.Name()
+		defer os.Remove(file)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if store != nil {
+		s3Key := ManifestS3Key(cfg)
+		if err := store.UploadFileWithRetry(file, s3Key); err != nil {
+			return nil, fmt.Errorf("failed to upload manifest: %w", err)
+		}
+		locations = append(locations, fmt.Sprintf("s3://%s/%s", cfg.S3Bucket, s3Key))
+	}
+	return locations, nil
+}
+
+// LoadManifest reads the manifest of the tenant's table in cfg: from -output-dir if set, otherwise from S3 with store.
+func LoadManifest(cfg *config.Config, store ManifestStore) (*Manifest, error) {
+	var data []byte
+	var err error
+	location := ManifestPath(cfg)
+	if cfg.OutputDir != "" {
+		data, err = os.ReadFile(location)
+	} else {
+		location = fmt.Sprintf("s3://%s/%s", cfg.S3Bucket, ManifestS3Key(cfg))
+		data, err = store.GetObject(ManifestS3Key(cfg))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", location, err)
+	}
+	if manifest.TenantID != cfg.TenantID || manifest.Table != cfg.TableName {
+		return nil, fmt.Errorf("manifest %s is for tenant %d table %s, not tenant %d table %s",
+			location, manifest.TenantID, manifest.Table, cfg.TenantID, cfg.TableName)
+	}
+	return &manifest, nil
+}
+
+// ManifestMismatch is a manifest segment whose rows on the target differ.
+type ManifestMismatch struct {
+	Segment        ManifestSegment
+	TargetRows     int64
+	TargetChecksum string
+}
+
+// ManifestReport lists the manifest segments whose target rows don't match the recorded digest.
+type ManifestReport struct {
+	Segments   int
+	Mismatched []ManifestMismatch
+}
+
+// CheckManifest recomputes each manifest segment's row digest on target and reports the segments that differ.
+func CheckManifest(manifest *Manifest, target exporter.SegmentDigester) (*ManifestReport, error) {
+	report := &ManifestReport{Segments: len(manifest.Segments)}
+	for _, entry := range manifest.Segments {
+		digest, err := target.SegmentDigest(entry.Segment())
+		if err != nil {
+			return nil, err
+		}
+		if digest.Rows() != int64(entry.Rows) || digest.Sum() != entry.Checksum {
+			report.Mismatched = append(report.Mismatched, ManifestMismatch{Segment: entry, TargetRows: digest.Rows(), TargetChecksum: digest.Sum()})
+		}
+	}
+	return report, nil
+}
+
+// VerifyManifest checks the loaded Aurora table against the manifest of the tenant's table in cfg (-verify-manifest).
+// Returns the report; segments whose rows differ are listed in Mismatched.
+func VerifyManifest(cfg *config.Config, store ManifestStore, logger *zap.Logger) (*ManifestReport, error) {
+	manifest, err := LoadManifest(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+
+	auroraClient, err := sqlgen.ConnectAurora(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer auroraClient.Close()
+
+	logger.Info("Checking Aurora rows against the manifest",
+		zap.Int("segments", len(manifest.Segments)),
+		zap.Time("manifest_created", manifest.Created))
+	report, err := CheckManifest(manifest,
+		exporter.NewTableChecksummer(auroraClient.GetDB(), cfg.TableName, cfg.TenantColumnName(), cfg.TenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check manifest: %w", err)
+	}
+	for _, m := range report.Mismatched {
+		logger.Error("Aurora rows differ from the manifest",
+			zap.Int("segment", m.Segment.Index),
+			zap.String("start_hex", m.Segment.StartHex),
+			zap.String("end_hex", m.Segment.EndHex),
+			zap.Int("manifest_rows", m.Segment.Rows),
+			zap.Int64("target_rows", m.TargetRows),
+			zap.String("manifest_checksum", m.Segment.Checksum),
+			zap.String("target_checksum", m.TargetChecksum))
+	}
+	logger.Info("Manifest verify complete",
+		zap.Int("segments", report.Segments),
+		zap.Int("mismatched", len(report.Mismatched)))
+	return report, nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+)
+
+// fakeManifestStore keeps uploaded objects in memory.
+type fakeManifestStore struct {
+	objects map[string][]byte
+}
+
+func (s *fakeManifestStore) UploadFileWithRetry(filepath, s3Key string) error {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+	s.objects[s3Key] = data
+	return nil
+}
+
+func (s *fakeManifestStore) GetObject(s3Key string) ([]byte, error) {
+	data, ok := s.objects[s3Key]
+	if !ok {
+		return nil, fmt.Errorf("no such key %s", s3Key)
+	}
+	return data, nil
+}
+
+// fakeDigester returns the digest of fixed rows per segment index.
+type fakeDigester struct {
+	rows map[int][][2]string
+}
+
+func (d *fakeDigester) SegmentDigest(seg segment.Segment) (*exporter.RowDigest, error) {
+	digest := exporter.NewRowDigest()
+	for _, row := range d.rows[seg.Index] {
+		digest.Add(row[0], row[1])
+	}
+	return digest, nil
+}
+
+func digestOf(rows ...[2]string) string {
+	digest := exporter.NewRowDigest()
+	for _, row := range rows {
+		digest.Add(row[0], row[1])
+	}
+	return digest.Sum()
+}
+
+func TestManifest_SaveLoadCheck(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(2)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	rows := [][2]string{{"00aa", `{"a":1}`}, {"00bb", `{"b":2}`}}
+	csvFiles := []exporter.CSVFile{{S3Key: "key-0", Segment: segments[0], RowCount: 2, Checksum: digestOf(rows...)}}
+
+	for _, tt := range []struct {
+		name      string
+		outputDir bool
+		store     bool
+	}{
+		{name: "s3", store: true},
+		{name: "output dir", outputDir: true},
+		{name: "output dir and s3", outputDir: true, store: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", S3Bucket: "bucket", S3Prefix: "prefix"}
+			if tt.outputDir {
+				cfg.OutputDir = t.TempDir()
+			}
+			fake := &fakeManifestStore{objects: map[string][]byte{}}
+			var store ManifestStore
+			if tt.store {
+				store = fake
+			}
+
+			locations, err := SaveManifest(NewManifest(cfg, segments, csvFiles), cfg, store)
+			if err != nil {
+				t.Fatalf("SaveManifest() error = %v", err)
+			}
+			want := 0
+			if tt.outputDir {
+				want++
+			}
+			if tt.store {
+				want++
+			}
+			if len(locations) != want {
+				t.Errorf("SaveManifest() locations = %v, want %d", locations, want)
+			}
+			if _, ok := fake.objects["prefix/manifest/tenant-7.fis_aggr.json"]; ok != tt.store {
+				t.Errorf("manifest uploaded = %v, want %v", ok, tt.store)
+			}
+
+			manifest, err := LoadManifest(cfg, fake)
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+			if len(manifest.Segments) != 2 {
+				t.Fatalf("manifest has %d segments, want 2 (including the one without rows)", len(manifest.Segments))
+			}
+			if got := manifest.Segments[1]; got.Rows != 0 || got.Checksum != digestOf() || got.S3Key != "" {
+				t.Errorf("segment without rows = %+v, want 0 rows with the empty digest", got)
+			}
+
+			// Matching target, then a changed aggr and an extra row in the empty segment
+			report, err := CheckManifest(manifest, &fakeDigester{rows: map[int][][2]string{0: rows}})
+			if err != nil {
+				t.Fatalf("CheckManifest() error = %v", err)
+			}
+			if report.Segments != 2 || len(report.Mismatched) != 0 {
+				t.Errorf("CheckManifest() = %+v, want 2 matching segments", report)
+			}
+			report, err = CheckManifest(manifest, &fakeDigester{rows: map[int][][2]string{
+				0: {{"00aa", `{"a":1}`}, {"00bb", `{"b":3}`}},
+				1: {{"80aa", `{}`}},
+			}})
+			if err != nil {
+				t.Fatalf("CheckManifest() error = %v", err)
+			}
+			if len(report.Mismatched) != 2 {
+				t.Fatalf("CheckManifest() mismatched %+v, want both segments", report.Mismatched)
+			}
+			if got := report.Mismatched[0]; got.TargetRows != 2 || got.TargetChecksum == got.Segment.Checksum {
+				t.Errorf("mismatch = %+v, want same rows with a different checksum", got)
+			}
+		})
+	}
+}
+
+func TestLoadManifest_OtherTenant(t *testing.T) {
+	cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", OutputDir: t.TempDir()}
+	if _, err := SaveManifest(NewManifest(cfg, nil, nil), cfg, nil); err != nil {
+		t.Fatalf("SaveManifest() error = %v", err)
+	}
+	other := *cfg
+	other.TableName = "fis_aggr_other"
+	if err := os.Rename(ManifestPath(cfg), ManifestPath(&other)); err != nil {
+		t.Fatalf("failed to move manifest: %v", err)
+	}
+	if _, err := LoadManifest(&other, nil); err == nil {
+		t.Error("LoadManifest() should reject a manifest of another table")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// Segment dispatch orders for -segment-order.
+const (
+	SegmentOrderNatural       = "natural"
+	SegmentOrderLargestFirst  = "largest-first"
+	SegmentOrderSmallestFirst = "smallest-first"
+)
+
+// SegmentCounter returns the row count of a segment.
+// This allows mocking in tests.
+type SegmentCounter interface {
+	CountSegmentRows(seg segment.Segment) (int64, error)
+}
+
+// OrderSegments returns segments in the dispatch order for the given strategy.
+// largest-first starts the biggest segments early so they don't become stragglers at the tail
+// of the run; smallest-first does the opposite. Natural order needs no pre-count.
+// Segments of equal size keep their natural order.
+func OrderSegments(segments []segment.Segment, order string, counter SegmentCounter, logger *zap.Logger) ([]segment.Segment, error) {
+	if order == "" || order == SegmentOrderNatural {
+		return segments, nil
+	}
+	if order != SegmentOrderLargestFirst && order != SegmentOrderSmallestFirst {
+		return nil, fmt.Errorf("unknown segment order %q", order)
+	}
+	if len(segments) <= 1 {
+		return segments, nil
+	}
+
+	counts := make(map[int]int64, len(segments))
+	for _, seg := range segments {
+		count, err := counter.CountSegmentRows(seg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pre-count segments for %s order: %w", order, err)
+		}
+		counts[seg.Index] = count
+	}
+
+	ordered := make([]segment.Segment, len(segments))
+	copy(ordered, segments)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if order == SegmentOrderLargestFirst {
+			return counts[ordered[i].Index] > counts[ordered[j].Index]
+		}
+		return counts[ordered[i].Index] < counts[ordered[j].Index]
+	})
+
+	logger.Info("Ordered segments for dispatch",
+		zap.String("order", order),
+		zap.Int("first_segment", ordered[0].Index),
+		zap.Int64("first_segment_rows", counts[ordered[0].Index]))
+
+	return ordered, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"go.uber.org/zap"
+)
+
+// ObjectLister lists and deletes objects under an S3 prefix.
+// This allows mocking in tests.
+type ObjectLister interface {
+	ListObjects(prefix string) ([]string, error)
+	DeleteObjects(keys []string) error
+}
+
+// OrphanReport lists the objects under the run's tenant table prefix that the run didn't produce.
+type OrphanReport struct {
+	Prefix  string
+	Listed  int
+	Orphans []string
+	Pruned  bool // Orphans were deleted (-prune)
+}
+
+// OrphanPrefix returns the prefix the run's CSV files are uploaded under: <s3-prefix>/tenant-<id>/<table>/
+// (with dt=<run date> after the S3 prefix for -partition-by-date).
+func OrphanPrefix(cfg *config.Config) string {
+	return fmt.Sprintf("%s/tenant-%d/%s/", cfg.CSVKeyPrefix(), cfg.TenantID, cfg.TableName)
+}
+
+// FindOrphanObjects lists the objects under the run's tenant table prefix (-list-orphan-objects) and reports
+// those that aren't one of csvFiles, e.g. leftovers of a run with another -segments count. With prune (-prune)
+// the orphans are deleted.
+func FindOrphanObjects(csvFiles []exporter.CSVFile, cfg *config.Config, lister ObjectLister, prune bool, logger *zap.Logger) (*OrphanReport, error) {
+	current := make(map[string]bool, len(csvFiles))
+	for _, csvFile := range csvFiles {
+		current[csvFile.S3Key] = true
+	}
+
+	prefix := OrphanPrefix(cfg)
+	keys, err := lister.ListObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+	report := &OrphanReport{Prefix: prefix, Listed: len(keys)}
+	for _, key := range keys {
+		if !current[key] {
+			report.Orphans = append(report.Orphans, key)
+			logger.Warn("Object is not part of this run", zap.String("s3_key", key))
+		}
+	}
+
+	if prune && len(report.Orphans) > 0 {
+		if err := lister.DeleteObjects(report.Orphans); err != nil {
+			return nil, fmt.Errorf("failed to prune orphan objects: %w", err)
+		}
+		report.Pruned = true
+	}
+	logger.Info("Orphan object audit complete",
+		zap.String("prefix", prefix),
+		zap.Int("objects", report.Listed),
+		zap.Int("orphans", len(report.Orphans)),
+		zap.Bool("pruned", report.Pruned))
+	return report, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeObjectLister serves keys from memory and records deletions.
+type fakeObjectLister struct {
+	keys    []string
+	deleted []string
+}
+
+func (l *fakeObjectLister) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	for _, key := range l.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (l *fakeObjectLister) DeleteObjects(keys []string) error {
+	l.deleted = append(l.deleted, keys...)
+	return nil
+}
+
+func TestFindOrphanObjects(t *testing.T) {
+	cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", S3Prefix: "prefix"}
+	csvFiles := []exporter.CSVFile{
+		{S3Key: "prefix/tenant-7/fis_aggr/tenant-7.fis_aggr.hash-00-80.csv"},
+		{S3Key: "prefix/tenant-7/fis_aggr/tenant-7.fis_aggr.hash-80-100.csv"},
+	}
+	keys := []string{
+		csvFiles[0].S3Key,
+		csvFiles[1].S3Key,
+		"prefix/tenant-7/fis_aggr/tenant-7.fis_aggr.hash-00-40.csv", // Left by a run with more segments
+		"prefix/tenant-7/fis_aggr_other/tenant-7.fis_aggr_other.hash-00-100.csv",
+		"prefix/tenant-8/fis_aggr/tenant-8.fis_aggr.hash-00-100.csv",
+		"prefix/sql/load-data-tenant-7.sql",
+	}
+	wantOrphans := []string{"prefix/tenant-7/fis_aggr/tenant-7.fis_aggr.hash-00-40.csv"}
+
+	for _, prune := range []bool{false, true} {
+		lister := &fakeObjectLister{keys: keys}
+		report, err := FindOrphanObjects(csvFiles, cfg, lister, prune, zaptest.NewLogger(t))
+		if err != nil {
+			t.Fatalf("FindOrphanObjects() error = %v", err)
+		}
+		if report.Prefix != "prefix/tenant-7/fis_aggr/" || report.Listed != 3 {
+			t.Errorf("FindOrphanObjects() listed %d under %q, want 3 under the tenant table prefix", report.Listed, report.Prefix)
+		}
+		if !reflect.DeepEqual(report.Orphans, wantOrphans) {
+			t.Errorf("FindOrphanObjects() orphans = %v, want %v", report.Orphans, wantOrphans)
+		}
+		if prune {
+			if !report.Pruned || !reflect.DeepEqual(lister.deleted, wantOrphans) {
+				t.Errorf("with prune deleted %v (pruned %v), want %v", lister.deleted, report.Pruned, wantOrphans)
+			}
+		} else if report.Pruned || len(lister.deleted) > 0 {
+			t.Errorf("without prune deleted %v, want nothing", lister.deleted)
+		}
+	}
+}
+
+func TestOrphanPrefix_PartitionByDate(t *testing.T) {
+	cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", S3Prefix: "prefix", PartitionByDate: true, RunDate: "2024-05-01"}
+	if got, want := OrphanPrefix(cfg), "prefix/dt=2024-05-01/tenant-7/fis_aggr/"; got != want {
+		t.Errorf("OrphanPrefix() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// ColumnRanger reads the value range of a column over the tenant's rows.
+// This allows mocking in tests.
+type ColumnRanger interface {
+	ColumnRange(column string) (min, max int64, ok bool, err error)
+}
+
+// RangeSegments splits the tenant's [min, max] of column into segments for -partition-strategy range.
+// A tenant with no rows gets one segment, which exports nothing.
+func RangeSegments(ranger ColumnRanger, column string, segments int, logger *zap.Logger) ([]segment.Segment, error) {
+	min, max, ok, err := ranger.ColumnRange(column)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		min, max, segments = 0, 0, 1
+	}
+	segs, err := segment.SegmentRange(column, min, max, segments)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to partition %s: %w", column, err))
+	}
+	logger.Info("Partitioned the tenant's column range",
+		zap.String("column", column),
+		zap.Int64("min", min),
+		zap.Int64("max", max),
+		zap.Int("segments", len(segs)))
+	return segs, nil
+}
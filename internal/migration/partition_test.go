@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeColumnRanger returns a fixed column range
+type fakeColumnRanger struct {
+	min, max int64
+	ok       bool
+	err      error
+}
+
+func (f fakeColumnRanger) ColumnRange(column string) (int64, int64, bool, error) {
+	return f.min, f.max, f.ok, f.err
+}
+
+func TestRangeSegments(t *testing.T) {
+	tests := []struct {
+		name         string
+		ranger       fakeColumnRanger
+		segments     int
+		wantSegments int
+		wantStart    int64
+		wantEnd      int64
+		wantErr      error
+	}{
+		{"splits the range", fakeColumnRanger{min: 1, max: 1000, ok: true}, 4, 4, 1, 1001, nil},
+		{"fewer values than segments", fakeColumnRanger{min: 5, max: 6, ok: true}, 16, 2, 5, 7, nil},
+		{"empty tenant", fakeColumnRanger{}, 16, 1, 0, 1, nil},
+		{"range error", fakeColumnRanger{err: errs.Wrap(errs.ErrSourceQuery, errors.New("boom"))}, 4, 0, 0, 0, errs.ErrSourceQuery},
+		{"range too wide", fakeColumnRanger{min: -1 << 63, max: 1<<63 - 1, ok: true}, 4, 0, 0, 0, errs.ErrConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, err := RangeSegments(tt.ranger, "id", tt.segments, zaptest.NewLogger(t))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RangeSegments() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RangeSegments() error = %v", err)
+			}
+			if len(segs) != tt.wantSegments {
+				t.Fatalf("RangeSegments() = %d segments, want %d", len(segs), tt.wantSegments)
+			}
+			first, last := segs[0].Range, segs[len(segs)-1].Range
+			if first.Start != tt.wantStart || last.End != tt.wantEnd || !last.Last {
+				t.Errorf("segments span %d-%d, want %d-%d with an open last segment", first.Start, last.End, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
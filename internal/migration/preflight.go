@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/s3"
+	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
+	"github.com/netSkope/fis-migration-tool/internal/util"
+	"go.uber.org/zap"
+)
+
+// Dependency checks run by Preflight (replaced in tests).
+var (
+	pingMariaDB    = pingMariaDBSource
+	checkS3Access  = checkS3BucketAccess
+	getSecretValue = util.GetPasswordFromSecretsManager
+	pingAurora     = sqlgen.PingAurora
+)
+
+// preflightTask is one dependency check to run.
+type preflightTask struct {
+	name string
+	run  func() error
+}
+
+// PreflightCheck is the outcome of checking one dependency.
+type PreflightCheck struct {
+	Name    string // "mariadb", "s3", "secretsmanager" or "aurora"
+	Latency time.Duration
+	Err     error // nil if the check passed
+}
+
+// PreflightReport lists the dependency checks in a fixed order.
+type PreflightReport struct {
+	Checks []PreflightCheck
+}
+
+// Failed returns the checks that did not pass.
+func (r *PreflightReport) Failed() []PreflightCheck {
+	var failed []PreflightCheck
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// Err returns one error naming every failed check, or nil if all passed.
+func (r *PreflightReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]string, len(failed))
+	for i, check := range failed {
+		messages[i] = fmt.Sprintf("%s: %v", check.Name, check.Err)
+	}
+	return fmt.Errorf("preflight failed: %s", strings.Join(messages, "; "))
+}
+
+// Preflight checks every dependency the run needs, concurrently, so a failure names all unreachable ones at once:
+// MariaDB ping, S3 HeadBucket and a put/delete of a tiny object (unless output is local-only or -direct-load), and with
+// -execute-sql or -direct-load the Secrets Manager secret (secretsmanager auth mode) and an Aurora ping.
+func Preflight(cfg *config.Config, logger *zap.Logger) *PreflightReport {
+	checks := []preflightTask{
+		{"mariadb", func() error { return pingMariaDB(cfg, logger) }},
+	}
+	if !cfg.LocalOutputOnly() && !cfg.DirectLoad {
+		checks = append(checks, preflightTask{"s3", func() error { return checkS3Access(cfg, logger) }})
+	}
+	if cfg.ExecuteSQL || cfg.DirectLoad {
+		// FIS_AWS_SQL_PASSWORD replaces the secret, so Secrets Manager isn't used then
+		if _, ok := os.LookupEnv(util.AWSSQLPasswordEnv); cfg.AuroraAuthMode != "iam" && !ok {
+			checks = append(checks, preflightTask{"secretsmanager", func() error {
+				_, err := getSecretValue(cfg.AuroraSecretsManagerSecret, cfg.AuroraRegion, cfg.SecretsMaxAttempts)
+				if err != nil && cfg.AuroraPassword != "" {
+					// The SQL phase falls back to -aurora-password, so don't fail the run here
+					logger.Warn("Secrets Manager is unreachable, Aurora will use the -aurora-password fallback", zap.Error(err))
+					return nil
+				}
+				return err
+			}})
+		}
+		checks = append(checks, preflightTask{"aurora", func() error { return pingAurora(cfg) }})
+	}
+
+	report := &PreflightReport{Checks: make([]PreflightCheck, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, name string, run func() error) {
+			defer wg.Done()
+			start := time.Now()
+			err := run()
+			report.Checks[i] = PreflightCheck{Name: name, Latency: time.Since(start), Err: err}
+		}(i, check.name, check.run)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Err != nil {
+			logger.Error("Preflight check failed",
+				zap.String("check", check.Name),
+				zap.Duration("latency", check.Latency),
+				zap.Error(check.Err))
+			continue
+		}
+		logger.Info("Preflight check passed",
+			zap.String("check", check.Name),
+			zap.Duration("latency", check.Latency))
+	}
+	return report
+}
+
+// pingMariaDBSource opens the source connection, which pings MariaDB.
+func pingMariaDBSource(cfg *config.Config, logger *zap.Logger) error {
+	exp, err := exporter.NewExporter(cfg, logger)
+	if err != nil {
+		return err
+	}
+	return exp.Close()
+}
+
+// checkS3BucketAccess checks that the S3 bucket exists and is writable.
+func checkS3BucketAccess(cfg *config.Config, logger *zap.Logger) error {
+	uploader, err := s3.NewUploader(cfg, logger)
+	if err != nil {
+		return err
+	}
+	return uploader.CheckAccess()
+}
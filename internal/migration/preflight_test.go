@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPreflight_BadBucket(t *testing.T) {
+	// S3 without the bucket: HeadBucket answers 404
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	// Reachable MariaDB
+	origPing := pingMariaDB
+	defer func() { pingMariaDB = origPing }()
+	pingMariaDB = func(cfg *config.Config, logger *zap.Logger) error { return nil }
+
+	cfg := &config.Config{TenantID: 1, TableName: "fis_aggr", S3Bucket: "missing-bucket", S3Prefix: "fis-migration", AWSRegion: "us-east-1"}
+	report := Preflight(cfg, zaptest.NewLogger(t))
+
+	if len(report.Checks) != 2 {
+		t.Fatalf("expected mariadb and s3 checks, got %+v", report.Checks)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Name != "s3" {
+		t.Fatalf("expected only s3 to fail, got %+v", report.Checks)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "s3: failed to access bucket missing-bucket") {
+		t.Errorf("Err() = %v, want the s3 failure", err)
+	}
+}
+
+func TestPreflight_SecretsFallback(t *testing.T) {
+	origPing, origS3, origSecret, origAurora := pingMariaDB, checkS3Access, getSecretValue, pingAurora
+	defer func() {
+		pingMariaDB, checkS3Access, getSecretValue, pingAurora = origPing, origS3, origSecret, origAurora
+	}()
+	pingMariaDB = func(cfg *config.Config, logger *zap.Logger) error { return nil }
+	checkS3Access = func(cfg *config.Config, logger *zap.Logger) error { return nil }
+	getSecretValue = func(secretName, region string, attempts int) (string, error) { return "", errors.New("i/o timeout") }
+	pingAurora = func(cfg *config.Config) error { return nil }
+
+	cfg := config.Config{S3Bucket: "bucket", ExecuteSQL: true, AuroraAuthMode: "secretsmanager"}
+	if err := Preflight(&cfg, zaptest.NewLogger(t)).Err(); err == nil || !strings.Contains(err.Error(), "secretsmanager: i/o timeout") {
+		t.Errorf("Err() = %v, want the secretsmanager failure", err)
+	}
+
+	// With -aurora-password the SQL phase falls back to it, so the preflight passes
+	cfg.AuroraPassword = "fallback-pwd"
+	if err := Preflight(&cfg, zaptest.NewLogger(t)).Err(); err != nil {
+		t.Errorf("Err() = %v, want no failure with a fallback password", err)
+	}
+}
+
+func TestPreflight_ExecuteSQL(t *testing.T) {
+	origPing, origS3, origSecret, origAurora := pingMariaDB, checkS3Access, getSecretValue, pingAurora
+	defer func() {
+		pingMariaDB, checkS3Access, getSecretValue, pingAurora = origPing, origS3, origSecret, origAurora
+	}()
+	pingMariaDB = func(cfg *config.Config, logger *zap.Logger) error { return errors.New("connection refused") }
+	checkS3Access = func(cfg *config.Config, logger *zap.Logger) error { return nil }
+	getSecretValue = func(secretName, region string, attempts int) (string, error) { return "pwd", nil }
+	pingAurora = func(cfg *config.Config) error { return errors.New("access denied") }
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		wantNames string
+	}{
+		{"secretsmanager", config.Config{S3Bucket: "bucket", ExecuteSQL: true, AuroraAuthMode: "secretsmanager"}, "mariadb,s3,secretsmanager,aurora"},
+		{"iam", config.Config{S3Bucket: "bucket", ExecuteSQL: true, AuroraAuthMode: "iam"}, "mariadb,s3,aurora"},
+		{"local-only", config.Config{OutputDir: "/tmp/out"}, "mariadb"},
+		{"direct-load", config.Config{DirectLoad: true, AuroraAuthMode: "iam"}, "mariadb,aurora"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Preflight(&tt.cfg, zaptest.NewLogger(t))
+
+			var names []string
+			for _, check := range report.Checks {
+				names = append(names, check.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Errorf("checks = %s, want %s", got, tt.wantNames)
+			}
+
+			err := report.Err()
+			if err == nil || !strings.Contains(err.Error(), "mariadb: connection refused") {
+				t.Errorf("Err() = %v, want the mariadb failure", err)
+			}
+			if (tt.cfg.ExecuteSQL || tt.cfg.DirectLoad) && !strings.Contains(err.Error(), "aurora: access denied") {
+				t.Errorf("Err() = %v, want the aurora failure too", err)
+			}
+		})
+	}
+}
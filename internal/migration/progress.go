@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"sync"
+	"time"
+)
+
+// progressTracker estimates the time remaining from the wall-clock durations of completed segments.
+type progressTracker struct {
+	mu        sync.Mutex
+	total     int
+	parallel  int
+	completed int
+	elapsed   time.Duration // Sum of completed segment durations
+}
+
+// progressEstimate is a snapshot of progress after a segment completes.
+type progressEstimate struct {
+	Completed       int
+	Remaining       int
+	AverageDuration time.Duration
+	ETA             time.Duration
+}
+
+// newProgressTracker creates a tracker for total segments dispatched parallel at a time.
+func newProgressTracker(total, parallel int) *progressTracker {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	return &progressTracker{total: total, parallel: parallel}
+}
+
+// averageDuration returns the average duration of the completed segments (0 before the first completes).
+func (p *progressTracker) averageDuration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.completed == 0 {
+		return 0
+	}
+	return p.elapsed / time.Duration(p.completed)
+}
+
+// complete records a segment that took d and returns the updated estimate.
+// Remaining segments run in batches of parallel, so the ETA is the average duration times the remaining batches.
+func (p *progressTracker) complete(d time.Duration) progressEstimate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.completed++
+	p.elapsed += d
+
+	est := progressEstimate{
+		Completed:       p.completed,
+		Remaining:       p.total - p.completed,
+		AverageDuration: p.elapsed / time.Duration(p.completed),
+	}
+	batches := (est.Remaining + p.parallel - 1) / p.parallel
+	est.ETA = est.AverageDuration * time.Duration(batches)
+	return est
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	// 10 segments, 2 in parallel
+	progress := newProgressTracker(10, 2)
+	durations := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 40 * time.Second}
+	want := []progressEstimate{
+		// avg 10s, 9 remaining = 5 batches
+		{Completed: 1, Remaining: 9, AverageDuration: 10 * time.Second, ETA: 50 * time.Second},
+		// avg 15s, 8 remaining = 4 batches
+		{Completed: 2, Remaining: 8, AverageDuration: 15 * time.Second, ETA: 60 * time.Second},
+		// avg 20s, 7 remaining = 4 batches
+		{Completed: 3, Remaining: 7, AverageDuration: 20 * time.Second, ETA: 80 * time.Second},
+		// avg 25s, 6 remaining = 3 batches
+		{Completed: 4, Remaining: 6, AverageDuration: 25 * time.Second, ETA: 75 * time.Second},
+	}
+
+	for i, d := range durations {
+		if got := progress.complete(d); got != want[i] {
+			t.Errorf("complete(%v) = %+v, want %+v", d, got, want[i])
+		}
+	}
+}
+
+func TestProgressTracker_Last(t *testing.T) {
+	progress := newProgressTracker(1, 0)
+	got := progress.complete(3 * time.Second)
+	if got.Remaining != 0 || got.ETA != 0 || got.AverageDuration != 3*time.Second {
+		t.Errorf("complete() = %+v, want no remaining segments and zero ETA", got)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+// SkewFactor is how many times the mean row count a segment may hold before it counts as a hotspot.
+const SkewFactor = 2
+
+// SegmentCount is the row count of one segment.
+type SegmentCount struct {
+	Segment segment.Segment
+	Rows    int64
+}
+
+// DistributionReport lists the row count of every segment (-report), in segment order.
+type DistributionReport struct {
+	Counts   []SegmentCount
+	Total    int64
+	Min      int64
+	Max      int64
+	Mean     float64
+	Hotspots []SegmentCount // Segments holding more than SkewFactor times the mean
+}
+
+// CountSegments counts the rows of every segment with up to parallel concurrent COUNT(*) queries,
+// and summarizes the distribution so hotspots show before a full export.
+func CountSegments(segments []segment.Segment, counter SegmentCounter, parallel int, logger *zap.Logger) (*DistributionReport, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no segments to count")
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	counts := make([]SegmentCount, len(segments))
+	errs := make([]error, len(segments))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, seg := range segments {
+		wg.Add(1)
+		go func(i int, seg segment.Segment) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			rows, err := counter.CountSegmentRows(seg)
+			counts[i] = SegmentCount{Segment: seg, Rows: rows}
+			errs[i] = err
+		}(i, seg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := summarizeCounts(counts)
+	logger.Info("Counted segment rows",
+		zap.Int("segments", len(report.Counts)),
+		zap.Int64("total_rows", report.Total),
+		zap.Int64("min_rows", report.Min),
+		zap.Int64("max_rows", report.Max),
+		zap.Float64("mean_rows", report.Mean),
+		zap.Int("hotspots", len(report.Hotspots)))
+	return report, nil
+}
+
+// summarizeCounts computes the totals and hotspots of counts, which must not be empty.
+func summarizeCounts(counts []SegmentCount) *DistributionReport {
+	report := &DistributionReport{Counts: counts, Min: counts[0].Rows, Max: counts[0].Rows}
+	for _, count := range counts {
+		report.Total += count.Rows
+		if count.Rows < report.Min {
+			report.Min = count.Rows
+		}
+		if count.Rows > report.Max {
+			report.Max = count.Rows
+		}
+	}
+	report.Mean = float64(report.Total) / float64(len(counts))
+
+	for _, count := range counts {
+		if float64(count.Rows) > SkewFactor*report.Mean {
+			report.Hotspots = append(report.Hotspots, count)
+		}
+	}
+	return report
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// trackingSegmentCounter returns seeded row counts by segment index and tracks concurrent calls
+type trackingSegmentCounter struct {
+	rows map[int]int64
+	err  error
+
+	mu           sync.Mutex
+	active, peak int
+}
+
+func (f *trackingSegmentCounter) CountSegmentRows(seg segment.Segment) (int64, error) {
+	f.mu.Lock()
+	f.active++
+	if f.active > f.peak {
+		f.peak = f.active
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.rows[seg.Index], nil
+}
+
+func TestCountSegments_Skewed(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(8)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	// Segment 5 holds most rows: mean 200, so only it is above 2x the mean
+	counter := &trackingSegmentCounter{rows: map[int]int64{0: 100, 1: 90, 2: 110, 3: 0, 4: 100, 5: 1000, 6: 100, 7: 100}}
+
+	report, err := CountSegments(segments, counter, 3, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("CountSegments() error = %v", err)
+	}
+	if len(report.Counts) != 8 {
+		t.Fatalf("expected 8 counts, got %d", len(report.Counts))
+	}
+	for i, count := range report.Counts {
+		if count.Segment.Index != i || count.Rows != counter.rows[i] {
+			t.Errorf("count %d = segment %d with %d rows, want segment %d with %d rows", i, count.Segment.Index, count.Rows, i, counter.rows[i])
+		}
+	}
+	if report.Total != 1600 || report.Min != 0 || report.Max != 1000 || report.Mean != 200 {
+		t.Errorf("total/min/max/mean = %d/%d/%d/%v, want 1600/0/1000/200", report.Total, report.Min, report.Max, report.Mean)
+	}
+	if len(report.Hotspots) != 1 || report.Hotspots[0].Segment.Index != 5 {
+		t.Errorf("hotspots = %+v, want only segment 5", report.Hotspots)
+	}
+	if counter.peak > 3 {
+		t.Errorf("%d concurrent counts, want at most 3", counter.peak)
+	}
+}
+
+func TestCountSegments_Even(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	counter := &trackingSegmentCounter{rows: map[int]int64{0: 50, 1: 60, 2: 40, 3: 50}}
+
+	report, err := CountSegments(segments, counter, 8, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("CountSegments() error = %v", err)
+	}
+	if len(report.Hotspots) != 0 {
+		t.Errorf("expected no hotspots, got %+v", report.Hotspots)
+	}
+
+	if _, err := CountSegments(segments, &trackingSegmentCounter{err: errors.New("connection lost")}, 2, zaptest.NewLogger(t)); err == nil {
+		t.Error("CountSegments() should fail when a count fails")
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/s3"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
+	"go.uber.org/zap"
+)
+
+// Steps of a Run, as named by a StepError.
+const (
+	StepSchemaCheck  = "schema-check"  // Aurora table schema check (-check-schema with -execute-sql)
+	StepSince        = "since"         // Newest last_modified in Aurora for -since-checkpoint
+	StepExport       = "export"        // Segment export and upload
+	StepCoverage     = "coverage"      // Tenant row count for -validate-coverage
+	StepManifest     = "manifest"      // -manifest write
+	StepSQLUpload    = "sql-upload"    // SQL file generation and upload
+	StepSampleVerify = "sample-verify" // -verify-sample re-download
+	StepOrphans      = "orphans"       // -list-orphan-objects audit
+	StepExecute      = "execute"       // LOAD DATA statements on Aurora (-execute-sql)
+	StepAuditUpload  = "audit-upload"  // -audit-log-upload
+)
+
+// StepError is the failure of a step of a Run, so callers can tell where the run stopped
+// (e.g. to pick an exit code). The message is that of the wrapped error.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return e.Err.Error() }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// stepError wraps err as the failure of step.
+func stepError(step string, err error) error {
+	return &StepError{Step: step, Err: err}
+}
+
+// Result is the outcome of a Run for one tenant's table.
+type Result struct {
+	TenantID          int
+	Table             string
+	Segments          []segment.Segment  // The segments migrated, after -only-segments and -skip-segments
+	CSVFiles          []exporter.CSVFile // The exported files, one per segment with rows
+	TotalRows         int
+	TotalBytes        int64                    // Bytes this run uploaded (or wrote locally), not counting segments resumed from a checkpoint
+	ExportDuration    time.Duration            // Time spent exporting the segments
+	Timing            exporter.SegmentTiming   // Query, serialization and upload time summed over the segments
+	SQLS3Key          string                   // The uploaded SQL file, empty if no SQL was generated
+	ManifestLocations []string                 // Where the manifest was written (-manifest)
+	Coverage          *CoverageReport          // -validate-coverage
+	Sample            *SampleReport            // -verify-sample
+	Orphans           *OrphanReport            // -list-orphan-objects
+	SQLErr            error                    // -execute-sql failure; the run still verifies before it fails
+	Verify            *exporter.ChecksumReport // -full-verify
+	Diff              *exporter.DiffReport     // -verify-diff
+	Completed         bool                     // Every step ran, even if the run then failed on SQLErr or a verify mismatch
+}
+
+// Run migrates the tenant's table in cfg: it generates the segments, exports them (ProcessSegmentsWithBudget),
+// generates and uploads the SQL file and, with -execute-sql, runs it on Aurora, followed by the configured checks.
+// No segment is dispatched once ctx is done (see ProcessSegmentsWithBudget).
+// Returns a nil Result if the run stopped before the segments were exported. Once they are, the Result holds
+// what was done so far, also when an error is returned: Completed tells whether every step ran.
+// Steps with their own failure class return a StepError; errors.Is(err, ErrTimeBudgetExhausted) reports a run
+// that ran out of -max-runtime.
+func Run(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Result, error) {
+	// The run sets the -since-checkpoint cursor and the -auto-segments count on its own copy,
+	// so running cfg again, or for another tenant, starts from what the caller set
+	runCfg := *cfg
+	cfg = &runCfg
+
+	logger.Info("Starting migration tool",
+		zap.Int("tenant_id", cfg.TenantID),
+		zap.String("table_name", cfg.TableName))
+
+	// Check the load target before spending time on the export, always with -exclude-columns:
+	// the target must be able to fill the columns left out
+	if (cfg.ExecuteSQL || cfg.DirectLoad) && (cfg.CheckSchema || cfg.ExcludeColumns != "") {
+		if err := sqlgen.CheckAuroraSchema(cfg, logger); err != nil {
+			return nil, stepError(StepSchemaCheck, fmt.Errorf("schema check failed: %w", err))
+		}
+	}
+
+	// Top up the target: export only the rows modified since the newest it already holds
+	if cfg.SinceCheckpoint {
+		since, err := sqlgen.TargetMaxLastModified(cfg, logger)
+		if err != nil {
+			return nil, stepError(StepSince, err)
+		}
+		cfg.SinceLastModified = since
+		if since == "" {
+			logger.Info("Target has no rows of the tenant, exporting all rows")
+		} else {
+			logger.Info("Exporting rows modified since the newest in the target", zap.String("since_unix", since))
+			if cfg.SQLDuplicateMode == "ignore" {
+				logger.Warn("Rows updated since the last run already exist in the target and are skipped with -sql-duplicate-mode ignore, use replace to update them")
+			}
+		}
+	}
+
+	segments, err := GenerateSegments(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// One concurrency budget shared by all phases (nil if -concurrency-budget is not set)
+	budget, err := NewBudgetFromConfig(cfg)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid concurrency budget: %w", err))
+	}
+
+	// Process segments (export + upload)
+	exportStart := time.Now()
+	csvFiles, err := ProcessSegmentsWithBudget(ctx, segments, cfg, budget, logger)
+	if errors.Is(err, ErrTimeBudgetExhausted) {
+		return nil, stepError(StepExport, err)
+	}
+	if err != nil {
+		return nil, stepError(StepExport, fmt.Errorf("failed to process segments: %w", err))
+	}
+
+	result := &Result{TenantID: cfg.TenantID, Table: cfg.TableName, Segments: segments, CSVFiles: csvFiles,
+		ExportDuration: time.Since(exportStart)}
+	for _, csvFile := range csvFiles {
+		result.TotalRows += csvFile.RowCount
+		result.TotalBytes += csvFile.ByteSize
+		result.Timing.Add(csvFile.Timing)
+	}
+
+	logger.Info("All segments processed", append([]zap.Field{
+		zap.Int("total_csv_files", len(csvFiles)),
+		zap.Int64("total_bytes", result.TotalBytes),
+		zap.Duration("duration", result.ExportDuration)},
+		result.Timing.Fields()...)...)
+
+	// Check the segments' row counts add up to the tenant's, before anything is loaded from them
+	if cfg.ValidateCoverage {
+		exp, err := exporter.NewExporter(cfg, logger)
+		if err != nil {
+			return result, stepError(StepCoverage, fmt.Errorf("failed to create exporter: %w", err))
+		}
+		result.Coverage, err = ValidateCoverage(csvFiles, exp, logger)
+		exp.Close()
+		if err != nil {
+			return result, stepError(StepCoverage, fmt.Errorf("coverage validation failed: %w", err))
+		}
+		if result.Coverage.Delta() != 0 {
+			return result, fmt.Errorf("coverage validation: %d segments exported %d rows, but the tenant has %d (delta %+d)",
+				result.Coverage.Segments, result.Coverage.Exported, result.Coverage.Source, result.Coverage.Delta())
+		}
+	}
+
+	// Record each segment's row count and digest for a later -verify-manifest
+	if cfg.Manifest {
+		var store ManifestStore
+		if !cfg.LocalOutputOnly() {
+			s3Uploader, err := s3.NewUploader(cfg, logger)
+			if err != nil {
+				return result, stepError(StepManifest, fmt.Errorf("failed to create S3 uploader for manifest: %w", err))
+			}
+			store = s3Uploader
+		}
+		result.ManifestLocations, err = SaveManifest(NewManifest(cfg, segments, csvFiles), cfg, store)
+		if err != nil {
+			return result, stepError(StepManifest, err)
+		}
+		logger.Info("Manifest written", zap.Strings("locations", result.ManifestLocations))
+	}
+
+	// Generate SQL file and upload to S3 (LOAD DATA FROM S3 can't read local-only output, and -skip-sql-gen leaves loading to another system)
+	if cfg.DirectLoad {
+		logger.Info("Rows inserted directly into Aurora, skipping SQL generation",
+			zap.String("table_name", cfg.TableName),
+			zap.Int("rows", result.TotalRows))
+	} else if cfg.LocalOutputOnly() {
+		logger.Info("Local-only output, skipping SQL generation",
+			zap.String("output_dir", cfg.OutputDir))
+	} else {
+		s3Uploader, err := s3.NewUploader(cfg, logger)
+		if err != nil {
+			return result, stepError(StepSQLUpload, fmt.Errorf("failed to create S3 uploader for SQL: %w", err))
+		}
+
+		if cfg.SkipSQLGen {
+			logger.Info("Skipping SQL generation (-skip-sql-gen)")
+		} else if cfg.Format == exporter.FormatParquet {
+			logger.Info("Parquet output, skipping SQL generation (LOAD DATA FROM S3 can't read Parquet)")
+		} else {
+			result.SQLS3Key, err = sqlgen.GenerateAndUploadSQL(csvFiles, cfg, s3Uploader, logger)
+			if err != nil {
+				return result, stepError(StepSQLUpload, fmt.Errorf("failed to generate and upload SQL file: %w", err))
+			}
+
+			logger.Info("SQL file generated and uploaded to S3",
+				zap.String("s3_key", result.SQLS3Key))
+		}
+
+		// Re-download a sample of the uploaded CSVs before anything loads them
+		if cfg.VerifySample > 0 {
+			result.Sample, err = VerifyS3Sample(csvFiles, cfg.VerifySample, s3Uploader, cfg, logger)
+			if err != nil {
+				return result, stepError(StepSampleVerify, fmt.Errorf("S3 sample verify failed: %w", err))
+			}
+			if failed := result.Sample.Failed; len(failed) > 0 {
+				return result, stepError(StepSampleVerify, fmt.Errorf("S3 sample verify: %d of %d sampled objects are malformed (first: %s: %v)",
+					len(failed), len(result.Sample.Objects), failed[0].CSVFile.S3Key, failed[0].Err))
+			}
+		}
+
+		// Report objects under the tenant table prefix this run didn't produce, deleting them with -prune
+		if cfg.ListOrphanObjects {
+			result.Orphans, err = FindOrphanObjects(csvFiles, cfg, s3Uploader, cfg.Prune, logger)
+			if err != nil {
+				return result, stepError(StepOrphans, fmt.Errorf("orphan object audit failed: %w", err))
+			}
+		}
+	}
+
+	// Execute SQL if requested
+	if cfg.ExecuteSQL {
+		logger.Info("Executing LOAD DATA FROM S3 on Aurora MySQL")
+
+		sqlStatements, err := sqlgen.GenerateLoadDataSQL(csvFiles, cfg)
+		if err != nil {
+			return result, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to generate SQL statements: %w", err))
+		}
+
+		// Statements run sequentially and hold one load slot
+		budget.Acquire(PhaseLoad)
+		result.SQLErr = sqlgen.ExecuteLoadDataSQL(sqlStatements, cfg, logger)
+		budget.Release(PhaseLoad)
+		if result.SQLErr != nil {
+			logger.Error("Failed to execute SQL statements", zap.Error(result.SQLErr))
+			// Don't stop here - verify first, then fail the run with the SQL error
+			logger.Warn("Some SQL statements may have failed, check logs above")
+		} else {
+			logger.Info("All SQL statements executed successfully")
+		}
+
+		// Keep the audit record next to the loaded data, whatever the statements' outcome
+		if cfg.AuditLogUpload {
+			s3Uploader, err := s3.NewUploader(cfg, logger)
+			if err != nil {
+				return result, stepError(StepAuditUpload, fmt.Errorf("failed to create S3 uploader for audit log: %w", err))
+			}
+			auditKey := sqlgen.AuditLogS3Key(cfg)
+			if err := s3Uploader.UploadFileWithRetry(cfg.AuditLog, auditKey); err != nil {
+				return result, stepError(StepAuditUpload, fmt.Errorf("failed to upload audit log: %w", err))
+			}
+			logger.Info("Audit log uploaded to S3", zap.String("s3_key", auditKey))
+		}
+	}
+
+	// Compare source and target content per segment if requested
+	if cfg.FullVerify {
+		result.Verify, err = FullVerify(segments, cfg, logger)
+		if err != nil {
+			return result, fmt.Errorf("full verify failed: %w", err)
+		}
+	}
+	if cfg.VerifyDiff {
+		result.Diff, err = VerifyDiff(segments, cfg, logger)
+		if err != nil {
+			return result, fmt.Errorf("verify diff failed: %w", err)
+		}
+	}
+	result.Completed = true
+
+	if result.Verify != nil && len(result.Verify.Mismatched) > 0 {
+		return result, fmt.Errorf("full verify: %d of %d segments differ between source and Aurora",
+			len(result.Verify.Mismatched), len(result.Verify.Segments))
+	}
+	if result.Diff != nil && result.Diff.Total() > 0 {
+		return result, fmt.Errorf("verify diff: %d rows differ between source and Aurora", result.Diff.Total())
+	}
+	if result.SQLErr != nil {
+		return result, stepError(StepExecute, fmt.Errorf("SQL execution failed: %w", result.SQLErr))
+	}
+	return result, nil
+}
+
+// GenerateSegments generates the segments to migrate, narrowed by -only-segments and -skip-segments.
+// With -auto-segments, cfg.Segments is first set from the tenant's row count.
+// With -partition-strategy range the segments split the tenant's range of -partition-column.
+func GenerateSegments(cfg *config.Config, logger *zap.Logger) ([]segment.Segment, error) {
+	// -auto-segments: size the segments from the tenant's row count
+	if cfg.AutoSegments {
+		exp, err := exporter.NewExporter(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create exporter: %w", err)
+		}
+		cfg.Segments, err = AutoSegments(exp, cfg.RowsPerSegment, logger)
+		exp.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var segments []segment.Segment
+	if cfg.PartitionStrategy == "range" {
+		exp, err := exporter.NewExporter(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create exporter: %w", err)
+		}
+		segments, err = RangeSegments(exp, cfg.PartitionColumn, cfg.Segments, logger)
+		exp.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Generate segments (wider hash prefixes for more than 256 segments)
+		var err error
+		segments, err = segment.SegmentHashSpaceN(cfg.Segments, segment.PrefixLenForSegments(cfg.Segments))
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to generate segments: %w", err))
+		}
+	}
+
+	logger.Info("Generated segments",
+		zap.Int("count", len(segments)),
+		zap.Int("max_parallel", cfg.MaxParallelSegs))
+
+	// Targeted re-runs of some segments (-only-segments / -skip-segments)
+	if cfg.OnlySegments != "" || cfg.SkipSegments != "" {
+		only, skip, err := cfg.SegmentSelection()
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, err)
+		}
+		segments = segment.Select(segments, only, skip)
+		if len(segments) == 0 {
+			return nil, errs.Wrap(errs.ErrConfig,
+				fmt.Errorf("no segments left after -only-segments %q and -skip-segments %q", cfg.OnlySegments, cfg.SkipSegments))
+		}
+		logger.Info("Selected segments",
+			zap.Int("count", len(segments)),
+			zap.String("only_segments", cfg.OnlySegments),
+			zap.String("skip_segments", cfg.SkipSegments))
+	}
+	return segments, nil
+}
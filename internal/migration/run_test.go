@@ -0,0 +1,187 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mariadb"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap/zaptest"
+)
+
+// setupRunTestDB starts a MariaDB container with a fis_aggr table holding a row for each of hashes for tenantID.
+// Returns: database connection, cleanup function, host:port
+func setupRunTestDB(t *testing.T, tenantID int, hashes []string) (*sql.DB, func(), string) {
+	if os.Getenv("SKIP_DOCKER_TESTS") == "true" {
+		t.Skip("Skipping Docker-based tests (SKIP_DOCKER_TESTS=true)")
+	}
+
+	// testcontainers panics when no Docker host is found
+	defer func() {
+		if r := recover(); r != nil {
+			if errStr, ok := r.(string); ok {
+				if strings.Contains(errStr, "Docker not found") || strings.Contains(errStr, "rootless Docker") {
+					t.Skipf("Skipping test: Docker not available: %v", r)
+				}
+			}
+			panic(r)
+		}
+	}()
+
+	ctx := context.Background()
+	container, err := mariadb.RunContainer(ctx,
+		testcontainers.WithImage("mariadb:10.11"),
+		mariadb.WithDatabase("fis"),
+		mariadb.WithUsername("root"),
+		mariadb.WithPassword("testpassword"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("ready for connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "Docker not found") || strings.Contains(err.Error(), "rootless Docker") {
+			t.Skipf("Skipping test: Docker not available: %v", err)
+		}
+		t.Fatalf("Failed to start MariaDB container: %v", err)
+	}
+
+	connStr, err := container.ConnectionString(ctx, "parseTime=true")
+	if err != nil {
+		container.Terminate(ctx)
+		t.Fatalf("Failed to get connection string: %v", err)
+	}
+	db, err := sql.Open("mysql", connStr)
+	if err != nil {
+		container.Terminate(ctx)
+		t.Fatalf("Failed to open database connection: %v", err)
+	}
+	for i := 0; ; i++ {
+		if err := db.Ping(); err == nil {
+			break
+		} else if i == 10 {
+			db.Close()
+			container.Terminate(ctx)
+			t.Fatalf("Failed to ping database: %v", err)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	cleanup := func() {
+		db.Close()
+		container.Terminate(ctx)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE fis_aggr (
+		tenantid INT NOT NULL,
+		hash VARCHAR(255) NOT NULL,
+		aggr LONGTEXT NOT NULL,
+		last_modified TIMESTAMP NULL,
+		version INT NULL,
+		UNIQUE(tenantid, hash)
+	)`); err != nil {
+		cleanup()
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	for _, hash := range hashes {
+		if _, err := db.Exec(`INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES (?, ?, '{"test": "data"}')`, tenantID, hash); err != nil {
+			cleanup()
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	hostPort := strings.Split(strings.Split(connStr, "@tcp(")[1], ")/")[0]
+	return db, cleanup, hostPort
+}
+
+func TestRun(t *testing.T) {
+	tenantID := 591591
+	hashes := []string{"00abc123", "3fabc123", "40abc123", "7fabc123", "80abc123", "bfabc123", "c0abc123", "ffabc123", "ffdef456"}
+	_, cleanup, hostPort := setupRunTestDB(t, tenantID, hashes)
+	defer cleanup()
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantSegments int
+		wantRows     int
+	}{
+		{"all segments", nil, 4, 9},
+		{"only some segments", []string{"-only-segments", "1,3"}, 2, 5},
+		{"with coverage validation", []string{"-validate-coverage"}, 4, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Local-only output: exported and checked, nothing uploaded or loaded
+			args := append([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "591591",
+				"-mariadb-host", hostPort, "-mariadb-user", "root", "-mariadb-password", "testpassword",
+				"-mariadb-database", "fis", "-output-dir", t.TempDir(), "-log-dir", t.TempDir(), "-segments", "4"}, tt.args...)
+			cfg, err := config.LoadConfigFromArgs(args)
+			if err != nil {
+				t.Fatalf("LoadConfigFromArgs() error = %v", err)
+			}
+
+			result, err := Run(context.Background(), cfg, zaptest.NewLogger(t))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !result.Completed || result.TenantID != tenantID || result.Table != "fis_aggr" {
+				t.Errorf("Result = completed %v, tenant %d, table %s, want a completed run of tenant %d fis_aggr",
+					result.Completed, result.TenantID, result.Table, tenantID)
+			}
+			if len(result.Segments) != tt.wantSegments || len(result.CSVFiles) != tt.wantSegments || result.TotalRows != tt.wantRows {
+				t.Errorf("Result has %d segments, %d CSV files and %d rows, want %d, %d and %d",
+					len(result.Segments), len(result.CSVFiles), result.TotalRows, tt.wantSegments, tt.wantSegments, tt.wantRows)
+			}
+			for _, csvFile := range result.CSVFiles {
+				if _, err := os.Stat(csvFile.FilePath); err != nil || csvFile.S3Key != "" {
+					t.Errorf("CSV file %+v should be written locally only: %v", csvFile, err)
+				}
+			}
+			if result.SQLS3Key != "" || result.SQLErr != nil {
+				t.Errorf("Result SQL file %q, error %v, want no SQL for local-only output", result.SQLS3Key, result.SQLErr)
+			}
+			if cfg.ValidateCoverage && (result.Coverage == nil || result.Coverage.Delta() != 0 || result.Coverage.Source != 9) {
+				t.Errorf("Coverage = %+v, want all 9 rows covered", result.Coverage)
+			}
+		})
+	}
+}
+
+func TestRun_KeepsCallerConfig(t *testing.T) {
+	tenantID := 591592
+	hashes := []string{"00abc123", "40abc123", "80abc123", "c0abc123", "ffabc123"}
+	_, cleanup, hostPort := setupRunTestDB(t, tenantID, hashes)
+	defer cleanup()
+
+	cfg, err := config.LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "591592",
+		"-mariadb-host", hostPort, "-mariadb-user", "root", "-mariadb-password", "testpassword",
+		"-mariadb-database", "fis", "-output-dir", t.TempDir(), "-log-dir", t.TempDir(),
+		"-auto-segments", "-rows-per-segment", "2"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	segments := cfg.Segments
+
+	// -auto-segments picks the count on the run's copy of the config, a second run picks it again
+	for run := 1; run <= 2; run++ {
+		result, err := Run(context.Background(), cfg, zaptest.NewLogger(t))
+		if err != nil {
+			t.Fatalf("Run() %d error = %v", run, err)
+		}
+		if len(result.Segments) != 3 || result.TotalRows != len(hashes) {
+			t.Errorf("Run() %d exported %d rows in %d segments, want %d in 3", run, result.TotalRows, len(result.Segments), len(hashes))
+		}
+		if cfg.Segments != segments {
+			t.Errorf("Run() %d set the caller's Segments to %d, want it left at %d", run, cfg.Segments, segments)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap"
+)
+
+const (
+	// sampleWindowBytes is how much of each sampled object is downloaded
+	sampleWindowBytes = 64 * 1024
+	// sampleRows is how many rows after the header are checked in a truncated window
+	sampleRows = 5
+)
+
+// ObjectRangeReader downloads a byte range of an S3 object.
+// This allows mocking in tests.
+type ObjectRangeReader interface {
+	GetObjectRange(s3Key string, start, end int64) ([]byte, error)
+}
+
+// SampledObject is the result of checking one sampled CSV object.
+type SampledObject struct {
+	CSVFile exporter.CSVFile
+	Rows    int   // Rows parsed from the downloaded window
+	Err     error // Why the object is malformed (nil if it parsed correctly)
+}
+
+// SampleReport lists the sampled objects and those that failed to parse.
+type SampleReport struct {
+	Objects []SampledObject
+	Failed  []SampledObject
+}
+
+// VerifyS3Sample downloads the start of n random uploaded CSV objects (-verify-sample) and checks that
+// each begins with the header followed by well-formed rows of cfg's tenant within the object's segment.
+// This catches multipart parts completed out of order, which row counts don't.
+// Objects are parsed with cfg's CSV format: -csv-delimiter, and a header naming -tenant-column
+// unless -csv-header=false.
+// Returns an error only if an object can't be downloaded; malformed objects are listed in Failed.
+func VerifyS3Sample(csvFiles []exporter.CSVFile, n int, reader ObjectRangeReader, cfg *config.Config, logger *zap.Logger) (*SampleReport, error) {
+	var uploaded []exporter.CSVFile
+	for _, csvFile := range csvFiles {
+		if csvFile.S3Key != "" {
+			uploaded = append(uploaded, csvFile)
+		}
+	}
+	if n > len(uploaded) {
+		n = len(uploaded)
+	}
+
+	report := &SampleReport{}
+	for _, i := range rand.Perm(len(uploaded))[:n] {
+		csvFile := uploaded[i]
+		data, err := reader.GetObjectRange(csvFile.S3Key, 0, sampleWindowBytes-1)
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := checkCSVSample(data, len(data) >= sampleWindowBytes, csvFile, cfg)
+		sampled := SampledObject{CSVFile: csvFile, Rows: rows, Err: err}
+		report.Objects = append(report.Objects, sampled)
+		if err != nil {
+			report.Failed = append(report.Failed, sampled)
+			logger.Error("Sampled S3 object is malformed",
+				zap.String("s3_key", csvFile.S3Key),
+				zap.Int("segment", csvFile.Segment.Index),
+				zap.Error(err))
+		}
+	}
+
+	logger.Info("S3 sample verify complete",
+		zap.Int("sampled", len(report.Objects)),
+		zap.Int("failed", len(report.Failed)))
+	return report, nil
+}
+
+// checkCSVSample parses the start of a CSV object, which must begin with the header unless -csv-header=false. If truncated, the partial last line
+// is dropped and only the first sampleRows rows are checked; otherwise every row is checked and counted against csvFile.RowCount.
+// Returns the number of rows checked.
+func checkCSVSample(data []byte, truncated bool, csvFile exporter.CSVFile, cfg *config.Config) (int, error) {
+	if truncated {
+		end := bytes.LastIndexByte(data, '\n')
+		if end < 0 {
+			return 0, fmt.Errorf("no complete line in the first %d bytes", len(data))
+		}
+		data = data[:end+1]
+	}
+
+	csvHeader := exporter.CSVHeader(cfg.TenantColumnName())
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = cfg.CSVComma()
+	reader.FieldsPerRecord = len(csvHeader)
+
+	if cfg.CSVHeader {
+		header, err := reader.Read()
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse header: %w", err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+			return 0, fmt.Errorf("unexpected header %q", strings.Join(header, ","))
+		}
+	}
+
+	rows := 0
+	for !truncated || rows < sampleRows {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to parse row %d: %w", rows+1, err)
+		}
+		if err := checkSampleRow(record, csvFile.Segment, cfg.TenantID); err != nil {
+			return rows, fmt.Errorf("row %d: %w", rows+1, err)
+		}
+		rows++
+	}
+
+	if !truncated && rows != csvFile.RowCount {
+		return rows, fmt.Errorf("object has %d rows, expected %d", rows, csvFile.RowCount)
+	}
+	return rows, nil
+}
+
+// checkSampleRow checks that a row belongs to the tenant and to the object's segment.
+func checkSampleRow(record []string, seg segment.Segment, tenantID int) error {
+	id, err := strconv.Atoi(record[0])
+	if err != nil {
+		return fmt.Errorf("invalid tenantid %q", record[0])
+	}
+	if id != tenantID {
+		return fmt.Errorf("tenantid %d, expected %d", id, tenantID)
+	}
+	// Source collations compare hashes case-insensitively, so segment bounds apply to the lowercase hash
+	if !segment.HashInSegment(strings.ToLower(record[1]), seg) {
+		return fmt.Errorf("hash %q outside segment %s-%s", record[1], seg.StartHex, seg.EndHex)
+	}
+	return nil
+}
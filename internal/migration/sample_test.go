@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeObjectReader serves object ranges from memory
+type fakeObjectReader struct {
+	objects map[string][]byte
+}
+
+func (f *fakeObjectReader) GetObjectRange(s3Key string, start, end int64) ([]byte, error) {
+	data, ok := f.objects[s3Key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	if end+1 < int64(len(data)) {
+		data = data[:end+1]
+	}
+	return data[start:], nil
+}
+
+func TestVerifyS3Sample(t *testing.T) {
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}
+	good := "tenantid,hash,aggr,last_modified,version\n" +
+		"7,00abc,\"{\"\"a\"\": 1}\",2024-01-01 00:00:00,1\n" +
+		"7,3FDEF,{},\\N,\\N\n"
+
+	tests := []struct {
+		name    string
+		object  string
+		rows    int
+		wantErr string
+	}{
+		{"well-formed", good, 2, ""},
+		{"parts out of order", "7,3FDEF,{},\\N,\\N\ntenantid,hash,aggr,last_modified,version\n7,00abc,{},\\N,\\N\n", 2, "unexpected header"},
+		{"missing column", "tenantid,hash,aggr,last_modified,version\n7,00abc,{},\\N\n", 1, "wrong number of fields"},
+		{"other tenant", strings.Replace(good, "7,3FDEF", "8,3FDEF", 1), 2, "tenantid 8"},
+		{"hash outside segment", strings.Replace(good, "7,3FDEF", "7,80def", 1), 2, "outside segment"},
+		{"rows missing", good, 3, "expected 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &fakeObjectReader{objects: map[string][]byte{"key": []byte(tt.object)}}
+			csvFiles := []exporter.CSVFile{{S3Key: "key", Segment: seg, RowCount: tt.rows}}
+
+			report, err := VerifyS3Sample(csvFiles, 5, reader, &config.Config{TenantID: 7, CSVHeader: true}, zaptest.NewLogger(t))
+			if err != nil {
+				t.Fatalf("VerifyS3Sample() error = %v", err)
+			}
+			if len(report.Objects) != 1 {
+				t.Fatalf("expected 1 sampled object, got %d", len(report.Objects))
+			}
+			if tt.wantErr == "" {
+				if len(report.Failed) != 0 {
+					t.Errorf("expected no failures, got %v", report.Failed[0].Err)
+				}
+				return
+			}
+			if len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Err.Error(), tt.wantErr) {
+				t.Errorf("expected failure containing %q, got %+v", tt.wantErr, report.Failed)
+			}
+		})
+	}
+}
+
+func TestVerifyS3Sample_NoHeader(t *testing.T) {
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}
+	reader := &fakeObjectReader{objects: map[string][]byte{
+		"headerless": []byte("7,00abc,{},\\N,\\N\n7,3FDEF,{},\\N,\\N\n"),
+		"header":     []byte("tenantid,hash,aggr,last_modified,version\n7,00abc,{},\\N,\\N\n"),
+	}}
+	csvFiles := []exporter.CSVFile{{S3Key: "headerless", Segment: seg, RowCount: 2}}
+
+	report, err := VerifyS3Sample(csvFiles, 1, reader, &config.Config{TenantID: 7}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Failed) != 0 || report.Objects[0].Rows != 2 {
+		t.Errorf("expected 2 well-formed rows, got %+v", report.Objects)
+	}
+
+	// A header line where rows are expected is not a valid row
+	csvFiles = []exporter.CSVFile{{S3Key: "header", Segment: seg, RowCount: 1}}
+	report, err = VerifyS3Sample(csvFiles, 1, reader, &config.Config{TenantID: 7}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Err.Error(), "invalid tenantid") {
+		t.Errorf("expected the header line to fail as a row, got %+v", report.Failed)
+	}
+}
+
+func TestVerifyS3Sample_Delimiter(t *testing.T) {
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "40"}
+	reader := &fakeObjectReader{objects: map[string][]byte{
+		"key": []byte("tenantid|hash|aggr|last_modified|version\n7|00abc|\"{\"\"a\"\": 1, \"\"b\"\": 2}\"|\\N|\\N\n"),
+	}}
+	csvFiles := []exporter.CSVFile{{S3Key: "key", Segment: seg, RowCount: 1}}
+
+	report, err := VerifyS3Sample(csvFiles, 1, reader, &config.Config{TenantID: 7, CSVHeader: true, CSVDelimiter: "|"}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Failed) != 0 {
+		t.Errorf("expected the |-delimited object to parse, got %v", report.Failed[0].Err)
+	}
+}
+
+func TestVerifyS3Sample_TruncatedWindow(t *testing.T) {
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "100"}
+	var object strings.Builder
+	object.WriteString("tenantid,hash,aggr,last_modified,version\n")
+	rows := 0
+	for object.Len() < 2*sampleWindowBytes {
+		fmt.Fprintf(&object, "7,%02x%06d,\"{\"\"n\"\": %d}\",\\N,\\N\n", rows%256, rows, rows)
+		rows++
+	}
+	reader := &fakeObjectReader{objects: map[string][]byte{"key": []byte(object.String())}}
+	// Unsampled local-only files are skipped
+	csvFiles := []exporter.CSVFile{{FilePath: "/tmp/local.csv"}, {S3Key: "key", Segment: seg, RowCount: rows}}
+
+	report, err := VerifyS3Sample(csvFiles, 5, reader, &config.Config{TenantID: 7, CSVHeader: true}, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Objects) != 1 || len(report.Failed) != 0 {
+		t.Fatalf("expected 1 object without failures, got %+v", report)
+	}
+	if report.Objects[0].Rows != sampleRows {
+		t.Errorf("expected %d rows checked in the window, got %d", sampleRows, report.Objects[0].Rows)
+	}
+
+	if _, err := VerifyS3Sample([]exporter.CSVFile{{S3Key: "missing", Segment: seg}}, 1, reader, &config.Config{TenantID: 7, CSVHeader: true}, zaptest.NewLogger(t)); err == nil {
+		t.Error("VerifyS3Sample() should fail when an object can't be downloaded")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
+	"go.uber.org/zap"
+)
+
+// FullVerify compares each segment's content checksum in the source with the loaded Aurora table (-full-verify).
+// Returns the report; segments whose checksums differ are listed in Mismatched.
+func FullVerify(segments []segment.Segment, cfg *config.Config, logger *zap.Logger) (*exporter.ChecksumReport, error) {
+	exp, err := exporter.NewExporter(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+	defer exp.Close()
+
+	auroraClient, err := sqlgen.ConnectAurora(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer auroraClient.Close()
+
+	logger.Info("Comparing segment checksums of source and Aurora", zap.Int("segments", len(segments)))
+	report, err := exporter.CompareSegmentChecksums(segments, exp,
+		exporter.NewTableChecksummer(auroraClient.GetDB(), cfg.TableName, cfg.TenantColumnName(), cfg.TenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare segment checksums: %w", err)
+	}
+	logChecksumReport(report, logger)
+	return report, nil
+}
+
+// VerifyDiff compares the source and the loaded Aurora table row by row (-verify-diff), reporting
+// every hash missing on one side or with different content.
+func VerifyDiff(segments []segment.Segment, cfg *config.Config, logger *zap.Logger) (*exporter.DiffReport, error) {
+	exp, err := exporter.NewExporter(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+	defer exp.Close()
+
+	auroraClient, err := sqlgen.ConnectAurora(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer auroraClient.Close()
+
+	logger.Info("Comparing rows of source and Aurora", zap.Int("segments", len(segments)))
+	report, err := exporter.DiffSegments(segments, exp,
+		exporter.NewTableChecksummer(auroraClient.GetDB(), cfg.TableName, cfg.TenantColumnName(), cfg.TenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff segments: %w", err)
+	}
+	logDiffReport(report, logger)
+	return report, nil
+}
+
+// logDiffReport logs each differing row and a summary of the diff.
+func logDiffReport(report *exporter.DiffReport, logger *zap.Logger) {
+	for _, d := range report.Diffs {
+		logger.Error("Row differs between source and Aurora",
+			zap.Int("segment", d.Segment.Index),
+			zap.String("hash", d.Hash),
+			zap.String("diff", d.Kind))
+	}
+	logger.Info("Verify diff complete",
+		zap.Int("segments", report.Segments),
+		zap.Int64("source_rows", report.SourceRows),
+		zap.Int64("target_rows", report.TargetRows),
+		zap.Int64("missing_in_target", report.Counts[exporter.DiffMissingInTarget]),
+		zap.Int64("extra_in_target", report.Counts[exporter.DiffExtraInTarget]),
+		zap.Int64("content_differs", report.Counts[exporter.DiffContent]),
+		zap.Bool("diffs_capped", report.DiffsCapped))
+}
+
+// logChecksumReport logs each mismatched segment and a summary of the comparison.
+func logChecksumReport(report *exporter.ChecksumReport, logger *zap.Logger) {
+	for _, c := range report.Mismatched {
+		logger.Error("Segment checksum mismatch between source and Aurora",
+			zap.Int("segment", c.Segment.Index),
+			zap.String("start_hex", c.Segment.StartHex),
+			zap.String("end_hex", c.Segment.EndHex),
+			zap.Int64("source_rows", c.Source.Rows),
+			zap.Int64("target_rows", c.Target.Rows),
+			zap.Uint64("source_checksum", c.Source.Checksum),
+			zap.Uint64("target_checksum", c.Target.Checksum))
+	}
+	logger.Info("Full verify complete",
+		zap.Int("segments", len(report.Segments)),
+		zap.Int("mismatched", len(report.Mismatched)))
+}
@@ -3,42 +3,204 @@
 package migration
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/netSkope/fis-migration-tool/internal/config"
 	"github.com/netSkope/fis-migration-tool/internal/exporter"
 	"github.com/netSkope/fis-migration-tool/internal/s3"
 	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/sqlgen"
 	"go.uber.org/zap"
 )
 
 // ProcessSegments processes all segments in parallel batches.
-func ProcessSegments(segments []segment.Segment, cfg *config.Config, logger *zap.Logger) ([]exporter.CSVFile, error) {
+// Concurrency is limited by the budget configured with -concurrency-budget, if any.
+func ProcessSegments(ctx context.Context, segments []segment.Segment, cfg *config.Config, logger *zap.Logger) ([]exporter.CSVFile, error) {
+	budget, err := NewBudgetFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ProcessSegmentsWithBudget(ctx, segments, cfg, budget, logger)
+}
+
+// ProcessSegmentsWithBudget processes all segments in parallel batches, drawing segment exports
+// and part uploads from budget so they don't oversubscribe the shared concurrency budget.
+// A nil budget only applies -max-parallel-segments.
+// No segment is dispatched once ctx is done or its deadline (-max-runtime) is too close; in-flight
+// segments finish. If the run stops early, the completed segments are written to the checkpoint,
+// and with -resume the segments a checkpoint lists are skipped and their CSV files returned as well.
+func ProcessSegmentsWithBudget(ctx context.Context, segments []segment.Segment, cfg *config.Config, budget *Budget, logger *zap.Logger) ([]exporter.CSVFile, error) {
 	exp, err := exporter.NewExporter(cfg, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create exporter: %w", err)
 	}
 	defer exp.Close()
 
-	s3Uploader, err := s3.NewUploader(cfg, logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 uploader: %w", err)
+	// Preflight: without a (tenantid, hash) index every batch query is a full scan
+	if err := exp.VerifySegmentIndex(); err != nil {
+		return nil, err
+	}
+
+	// Optional preflight: compare -segments with the hash prefixes actually present
+	if cfg.CheckSegmentCardinality {
+		if _, err := CheckSegmentCardinality(segments, exp, logger); err != nil {
+			return nil, fmt.Errorf("failed to check segment cardinality: %w", err)
+		}
+	}
+
+	// Local-only output (-output-dir without -s3-bucket) and -direct-load don't touch S3
+	var s3Uploader *s3.Uploader
+	if !cfg.LocalOutputOnly() && !cfg.DirectLoad {
+		s3Uploader, err = s3.NewUploader(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 uploader: %w", err)
+		}
+	}
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// -direct-load inserts each batch into Aurora instead of streaming CSV parts
+	if cfg.DirectLoad {
+		loader, err := sqlgen.NewDirectLoader(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create direct loader: %w", err)
+		}
+		defer loader.Close()
+		exp.SetRowLoader(loader)
+
+		// Row-by-row INSERTs are far slower than LOAD DATA for big tenants
+		if tenantRows, err := exp.CountTenantRows(); err != nil {
+			return nil, err
+		} else if cfg.DirectLoadWarnRows > 0 && tenantRows > int64(cfg.DirectLoadWarnRows) {
+			logger.Warn("Tenant is large for -direct-load; the S3 and LOAD DATA path is much faster at this size",
+				zap.Int64("rows", tenantRows),
+				zap.Int("direct_load_warn_rows", cfg.DirectLoadWarnRows))
+		}
+	}
+
+	// Optional dead-letter output for rows skipped by row-level policies
+	var deadLetter *exporter.DeadLetterSink
+	if cfg.DeadLetter != "" {
+		bucket, _, isS3, err := exporter.ParseDeadLetterTarget(cfg.DeadLetter)
+		if err != nil {
+			return nil, err
+		}
+		if isS3 && bucket != cfg.S3Bucket {
+			return nil, fmt.Errorf("dead-letter bucket %s must match s3-bucket %s", bucket, cfg.S3Bucket)
+		}
+
+		deadLetter, err = exporter.NewDeadLetterSink(cfg.DeadLetter, s3Uploader, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dead-letter sink: %w", err)
+		}
+		exp.SetDeadLetterSink(deadLetter)
+	}
+
+	// Part uploads draw from the upload share while segments stream (nil uploader writes locally only)
+	var uploader exporter.MultipartUploadStreamCreator
+	if s3Uploader != nil {
+		uploader = exporter.NewS3UploaderAdapter(s3Uploader)
+	}
+	if budget != nil && uploader != nil {
+		uploader = &budgetedStreamCreator{creator: uploader, budget: budget}
+		logger.Info("Using concurrency budget",
+			zap.Int("budget", budget.Total()),
+			zap.String("shares", budget.String()))
+	}
+	// -fail-fast-abort: the first failed segment aborts the uploads of the others in flight
+	var aborting *abortingStreamCreator
+	if cfg.FailFastAbort && uploader != nil {
+		aborting = newAbortingStreamCreator(uploader)
+		uploader = aborting
 	}
 
 	var allCSVFiles []exporter.CSVFile
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+	var dispatchErr error
+	if cfg.SingleFile {
+		// One multipart upload, so segments run one at a time and in order
+		budget.Acquire(PhaseExport)
+		allCSVFiles, dispatchErr = ProcessSingleFile(segments, exp, uploader, logger)
+		budget.Release(PhaseExport)
+	} else {
+		// Reorder dispatch so large segments don't start last and dominate total runtime
+		segments, err = OrderSegments(segments, cfg.SegmentOrder, exp, logger)
+		if err != nil {
+			return nil, err
+		}
+		process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+			return ProcessSegment(s, exp, uploader, cfg, logger)
+		}
+		if aborting != nil {
+			var cancel context.CancelCauseFunc
+			ctx, cancel = context.WithCancelCause(ctx)
+			defer cancel(nil)
+			process = aborting.abortOnFailure(cancel, process, logger)
+		}
+		allCSVFiles, dispatchErr = dispatchWithCheckpoint(ctx, segments, cfg, budget, newControlFile(cfg, logger), exp, process, logger)
+	}
 
-	// Process segments in batches
+	if deadLetter != nil {
+		if err := deadLetter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write dead-letter output: %w", err)
+		}
+	}
+	if dispatchErr != nil {
+		return nil, dispatchErr
+	}
+
+	logger.Info("All segments processed",
+		zap.Int("total_segments", len(segments)),
+		zap.Int("total_csv_files", len(allCSVFiles)))
+
+	return allCSVFiles, nil
+}
+
+// segmentProcessor exports a single segment.
+type segmentProcessor func(seg segment.Segment) ([]exporter.CSVFile, error)
+
+// dispatchSegments runs process for each segment in parallel batches of -max-parallel-segments,
+// drawing each segment from the budget's export share. Before each segment is dispatched,
+// waits while the control file (if any) says pause.
+// If any segment fails, returns an error listing the failed segment indices and wrapping their errors, unless
+// -continue-on-segment-error is set, in which case the successful segments are returned.
+// Stops dispatching when ctx runs out of time (see timeBudgetLeft) and returns ErrTimeBudgetExhausted
+// once the in-flight segments finish, or when ctx is cancelled with errRunAborted (-fail-fast-abort).
+// With -adaptive-parallelism segments aren't batched: each is dispatched as soon as the AIMD controller
+// has room, and -max-parallel-segments caps the controller's limit (see aimdController).
+func dispatchSegments(ctx context.Context, segments []segment.Segment, cfg *config.Config, budget *Budget, control *controlFile, process segmentProcessor, logger *zap.Logger) ([]exporter.CSVFile, error) {
 	maxParallel := cfg.MaxParallelSegs
 	if maxParallel <= 0 {
 		maxParallel = 8
 	}
 
+	var allCSVFiles []exporter.CSVFile
+	var failed []int
+	segmentErrs := make(map[int]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	progress := newProgressTracker(len(segments), maxParallel)
+	dispatched := 0
+	stopped := false
+
+	batchSize := maxParallel
+	var adaptive *aimdController
+	if cfg.AdaptiveParallelism {
+		adaptive = newAIMDController(maxParallel, time.Duration(cfg.AdaptiveTargetLatency)*time.Second)
+		batchSize = len(segments)
+	}
+
 	// Process segments in batches
-	for i := 0; i < len(segments); i += maxParallel {
-		batchEnd := i + maxParallel
+	for i := 0; i < len(segments) && !stopped; i += batchSize {
+		batchEnd := i + batchSize
 		if batchEnd > len(segments) {
 			batchEnd = len(segments)
 		}
@@ -51,15 +213,59 @@ func ProcessSegments(segments []segment.Segment, cfg *config.Config, logger *zap
 
 		// Process batch in parallel
 		for _, seg := range batch {
+			// Paused segments aren't dispatched; those already running finish normally
+			control.waitWhilePaused(ctx)
+			adaptive.wait()
+
+			// Nor are segments that would likely still be running when -max-runtime runs out
+			if !timeBudgetLeft(ctx, progress.averageDuration()) {
+				stopped = true
+				break
+			}
+			dispatched++
+			ticket := adaptive.start()
+
 			wg.Add(1)
 			go func(s segment.Segment) {
 				defer wg.Done()
 
-				csvFiles, err := ProcessSegment(s, exp, s3Uploader, cfg, logger)
+				budget.Acquire(PhaseExport)
+				defer budget.Release(PhaseExport)
+
+				start := time.Now()
+				csvFiles, err := process(s)
+				// Failed segments count too: they're done and took time. Logged after the segment's own outcome.
+				latency := time.Since(start)
+				est := progress.complete(latency)
+				parallelism := maxParallel
+				if adaptive != nil {
+					before, after := adaptive.finish(ticket, latency)
+					if after != before {
+						logger.Info("Adaptive parallelism changed",
+							zap.Int("segment", s.Index),
+							zap.Duration("segment_duration", latency),
+							zap.Int("adaptive_target_latency", cfg.AdaptiveTargetLatency),
+							zap.Int("from", before),
+							zap.Int("to", after))
+					}
+					parallelism = after
+				}
+				defer logger.Info("Migration progress",
+					zap.Int("completed_segments", est.Completed),
+					zap.Int("remaining_segments", est.Remaining),
+					zap.Int("total_segments", len(segments)),
+					zap.Int("parallelism", parallelism),
+					zap.Duration("avg_segment_duration", est.AverageDuration),
+					zap.Duration("eta", est.ETA))
+
 				if err != nil {
 					logger.Error("Failed to process segment",
 						zap.Int("segment", s.Index),
 						zap.Error(err))
+					mu.Lock()
+					failed = append(failed, s.Index)
+					segmentErrs[s.Index] = err
+					mu.Unlock()
 					return
 				}
 
@@ -77,26 +283,66 @@ func ProcessSegments(segments []segment.Segment, cfg *config.Config, logger *zap
 		wg.Wait()
 	}
 
-	logger.Info("All segments processed",
-		zap.Int("total_segments", len(segments)),
-		zap.Int("total_csv_files", len(allCSVFiles)))
+	aborted := errors.Is(context.Cause(ctx), errRunAborted)
+	if stopped && aborted {
+		logger.Warn("Stopped dispatching segments after a failed segment (-fail-fast-abort)",
+			zap.Int("dispatched_segments", dispatched),
+			zap.Int("not_dispatched_segments", len(segments)-dispatched))
+	} else if stopped {
+		logger.Warn("Time budget exhausted, stopped dispatching segments (-max-runtime)",
+			zap.Int("dispatched_segments", dispatched),
+			zap.Int("not_dispatched_segments", len(segments)-dispatched),
+			zap.Duration("max_runtime", cfg.MaxRuntime))
+	}
+
+	if len(failed) > 0 {
+		sort.Ints(failed)
+		// Joined so errors.Is and errs.KindOf see the segments' errors, e.g. a configuration error
+		joined := make([]error, 0, len(failed))
+		for _, index := range failed {
+			joined = append(joined, segmentErrs[index])
+		}
+		if aborted {
+			return nil, fmt.Errorf("%w: %d of %d segments failed or were aborted (segments %v), %d not dispatched: %w",
+				errRunAborted, len(failed), len(segments), failed, len(segments)-dispatched, errors.Join(joined...))
+		}
+		if !cfg.ContinueOnSegmentError {
+			return nil, fmt.Errorf("%d of %d segments failed (segments %v); use -continue-on-segment-error to keep partial results: %w",
+				len(failed), len(segments), failed, errors.Join(joined...))
+		}
+		logger.Warn("Continuing with failed segments missing from the export (-continue-on-segment-error)",
+			zap.Ints("failed_segments", failed),
+			zap.Int("total_segments", len(segments)))
+	}
+	if stopped {
+		return nil, fmt.Errorf("%w: %d of %d segments dispatched before -max-runtime ran out, re-run with -resume to continue",
+			ErrTimeBudgetExhausted, dispatched, len(segments))
+	}
 
 	return allCSVFiles, nil
 }
 
+// timeBudgetLeft reports whether another segment can start: ctx is not done, and the time left
+// before its deadline (if any) is more than avg, the average duration of the segments completed so far.
+func timeBudgetLeft(ctx context.Context, avg time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > avg
+}
+
 // ProcessSegment processes a single segment using streaming multipart upload.
 // Returns a slice with a single CSVFile (or empty if no data).
 // The export and upload happen together - each batch is uploaded as a multipart part.
-func ProcessSegment(seg segment.Segment, exp *exporter.Exporter, s3Uploader *s3.Uploader, cfg *config.Config, logger *zap.Logger) ([]exporter.CSVFile, error) {
+func ProcessSegment(seg segment.Segment, exp *exporter.Exporter, uploader exporter.MultipartUploadStreamCreator, cfg *config.Config, logger *zap.Logger) ([]exporter.CSVFile, error) {
 	logger.Info("Processing segment",
 		zap.Int("segment", seg.Index),
 		zap.String("start_hex", seg.StartHex),
 		zap.String("end_hex", seg.EndHex))
 
 	// Export segment using streaming multipart upload (upload happens during export)
-	// Use adapter to convert s3.Uploader to interface
-	uploaderAdapter := exporter.NewS3UploaderAdapter(s3Uploader)
-	csvFile, err := exp.ExportSegment(seg, uploaderAdapter)
+	csvFile, err := exp.ExportSegment(seg, uploader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to export segment: %w", err)
 	}
@@ -108,10 +354,37 @@ func ProcessSegment(seg segment.Segment, exp *exporter.Exporter, s3Uploader *s3.
 		return []exporter.CSVFile{}, nil
 	}
 
-	logger.Info("Segment completed",
+	logger.Info("Segment completed", append([]zap.Field{
 		zap.Int("segment", seg.Index),
 		zap.Int("rows", csvFile.RowCount),
-		zap.String("s3_key", csvFile.S3Key))
+		zap.String("s3_key", csvFile.S3Key),
+		zap.String("file_path", csvFile.FilePath)},
+		csvFile.Timing.Fields()...)...)
+
+	return []exporter.CSVFile{*csvFile}, nil
+}
+
+// ProcessSingleFile exports all segments sequentially into one CSV file (-single-file).
+// Returns a slice with the single CSVFile (or empty if no segment has data).
+func ProcessSingleFile(segments []segment.Segment, exp *exporter.Exporter, uploader exporter.MultipartUploadStreamCreator, logger *zap.Logger) ([]exporter.CSVFile, error) {
+	logger.Info("Exporting all segments into a single file",
+		zap.Int("segments", len(segments)))
+
+	csvFile, err := exp.ExportSingleFile(segments, uploader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export single file: %w", err)
+	}
+	if csvFile == nil {
+		logger.Info("No segment has data")
+		return []exporter.CSVFile{}, nil
+	}
+
+	logger.Info("Single file completed", append([]zap.Field{
+		zap.Int("segments", len(segments)),
+		zap.Int("rows", csvFile.RowCount),
+		zap.String("s3_key", csvFile.S3Key),
+		zap.String("file_path", csvFile.FilePath)},
+		csvFile.Timing.Fields()...)...)
 
 	return []exporter.CSVFile{*csvFile}, nil
 }
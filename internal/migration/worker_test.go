@@ -3,9 +3,21 @@
 package migration
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
 	"github.com/netSkope/fis-migration-tool/internal/segment"
 	"go.uber.org/zap/zaptest"
 )
@@ -24,7 +36,7 @@ func TestProcessSegments_EmptySegments(t *testing.T) {
 	// Test with empty segments - this will fail without a database connection
 	// Skip this test in unit test mode (requires testcontainers for full test)
 	segments := []segment.Segment{}
-	_, err := ProcessSegments(segments, cfg, logger)
+	_, err := ProcessSegments(context.Background(), segments, cfg, logger)
 	// We expect an error because we don't have a database connection
 	// This is expected behavior - the test validates the error handling
 	if err == nil {
@@ -42,7 +54,7 @@ func TestProcessSegments_InvalidConfig(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 
 	segments, _ := segment.SegmentHashSpace(4)
-	_, err := ProcessSegments(segments, cfg, logger)
+	_, err := ProcessSegments(context.Background(), segments, cfg, logger)
 	if err == nil {
 		t.Error("ProcessSegments() should fail with invalid config")
 	}
@@ -54,3 +66,341 @@ func TestProcessSegments_InvalidConfig(t *testing.T) {
 // 3. Test data setup
 // These are covered in exporter_test.go with testcontainers
 
+
+// fakeSegmentCounter returns known row counts per segment index
+type fakeSegmentCounter map[int]int64
+
+func (f fakeSegmentCounter) CountSegmentRows(seg segment.Segment) (int64, error) {
+	return f[seg.Index], nil
+}
+
+func TestOrderSegments(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	segments, err := segment.SegmentHashSpace(5)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	counter := fakeSegmentCounter{0: 100, 1: 5000, 2: 10, 3: 5000, 4: 700}
+
+	tests := []struct {
+		name      string
+		order     string
+		wantOrder []int
+	}{
+		{"natural", SegmentOrderNatural, []int{0, 1, 2, 3, 4}},
+		{"empty defaults to natural", "", []int{0, 1, 2, 3, 4}},
+		{"largest-first", SegmentOrderLargestFirst, []int{1, 3, 4, 0, 2}},
+		{"smallest-first", SegmentOrderSmallestFirst, []int{2, 0, 4, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := OrderSegments(segments, tt.order, counter, logger)
+			if err != nil {
+				t.Fatalf("OrderSegments() error = %v", err)
+			}
+			if len(ordered) != len(tt.wantOrder) {
+				t.Fatalf("expected %d segments, got %d", len(tt.wantOrder), len(ordered))
+			}
+			for i, seg := range ordered {
+				if seg.Index != tt.wantOrder[i] {
+					t.Errorf("dispatch position %d: expected segment %d, got %d", i, tt.wantOrder[i], seg.Index)
+				}
+			}
+		})
+	}
+
+	if _, err := OrderSegments(segments, "random", counter, logger); err == nil {
+		t.Error("OrderSegments() should fail for unknown order")
+	}
+}
+
+func TestDispatchSegments_FailedSegment(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	segments, err := segment.SegmentHashSpace(6)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	// Segments 2 and 4 fail, the rest export one file each
+	process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+		switch s.Index {
+		case 2:
+			return nil, fmt.Errorf("injected failure")
+		case 4:
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("injected configuration error"))
+		}
+		return []exporter.CSVFile{{Segment: s, RowCount: 10}}, nil
+	}
+
+	t.Run("fails the run by default", func(t *testing.T) {
+		cfg := &config.Config{MaxParallelSegs: 4}
+		csvFiles, err := dispatchSegments(context.Background(), segments, cfg, nil, nil, process, logger)
+		if err == nil {
+			t.Fatal("dispatchSegments() should return an aggregate error")
+		}
+		if !strings.Contains(err.Error(), "2 of 6 segments failed") || !strings.Contains(err.Error(), "[2 4]") {
+			t.Errorf("aggregate error should list failed segments, got: %v", err)
+		}
+		if !errors.Is(err, errs.ErrConfig) || errs.KindOf(err) != errs.ErrConfig {
+			t.Errorf("aggregate error should wrap the segments' errors, got: %v", err)
+		}
+		if csvFiles != nil {
+			t.Errorf("expected no CSV files on failure, got %d", len(csvFiles))
+		}
+	})
+
+	t.Run("continue-on-segment-error keeps partial results", func(t *testing.T) {
+		cfg := &config.Config{MaxParallelSegs: 4, ContinueOnSegmentError: true}
+		csvFiles, err := dispatchSegments(context.Background(), segments, cfg, nil, nil, process, logger)
+		if err != nil {
+			t.Fatalf("dispatchSegments() error = %v", err)
+		}
+		if len(csvFiles) != 4 {
+			t.Errorf("expected 4 CSV files, got %d", len(csvFiles))
+		}
+	})
+}
+
+func TestDispatchSegments_AdaptiveParallelism(t *testing.T) {
+	segments, err := segment.SegmentHashSpace(12)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		targetLatency int // Seconds; 0 makes every segment too slow
+		wantMinPeak   int
+		wantMaxPeak   int
+	}{
+		{"grows while segments are fast", 3600, 2, 4},
+		{"stays at 1 while segments are slow", 0, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var running, peak, first int
+			process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+				mu.Lock()
+				running++
+				if first == 0 {
+					first = running
+				}
+				peak = max(peak, running)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return []exporter.CSVFile{{Segment: s, RowCount: 1}}, nil
+			}
+
+			cfg := &config.Config{MaxParallelSegs: 4, AdaptiveParallelism: true, AdaptiveTargetLatency: tt.targetLatency}
+			csvFiles, err := dispatchSegments(context.Background(), segments, cfg, nil, nil, process, zaptest.NewLogger(t))
+			if err != nil {
+				t.Fatalf("dispatchSegments() error = %v", err)
+			}
+			if len(csvFiles) != len(segments) {
+				t.Errorf("got %d CSV files, want %d", len(csvFiles), len(segments))
+			}
+			if first != 1 {
+				t.Errorf("first segment ran with %d others, want it alone", first-1)
+			}
+			if peak < tt.wantMinPeak || peak > tt.wantMaxPeak {
+				t.Errorf("peak parallelism = %d, want %d-%d", peak, tt.wantMinPeak, tt.wantMaxPeak)
+			}
+		})
+	}
+}
+
+func TestDispatchSegments_OnlySegments(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	cfg, err := config.LoadConfigFromArgs([]string{"-config-file", "does-not-exist.yaml", "-tenant-id", "1",
+		"-mariadb-host", "localhost", "-s3-bucket", "bucket", "-aws-region", "us-east-1",
+		"-segments", "4", "-only-segments", "0,2"})
+	if err != nil {
+		t.Fatalf("LoadConfigFromArgs() error = %v", err)
+	}
+	segments, err := segment.SegmentHashSpace(cfg.Segments)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+	only, skip, err := cfg.SegmentSelection()
+	if err != nil {
+		t.Fatalf("SegmentSelection() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var processed []string
+	process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+		mu.Lock()
+		processed = append(processed, s.StartHex+"-"+s.EndHex)
+		mu.Unlock()
+		return []exporter.CSVFile{{Segment: s, RowCount: 1}}, nil
+	}
+
+	csvFiles, err := dispatchSegments(context.Background(), segment.Select(segments, only, skip), cfg, nil, nil, process, logger)
+	if err != nil {
+		t.Fatalf("dispatchSegments() error = %v", err)
+	}
+	sort.Strings(processed)
+	if fmt.Sprint(processed) != "[00-40 80-c0]" || len(csvFiles) != 2 {
+		t.Errorf("processed %v (%d files), want exactly segments 0 (00-40) and 2 (80-c0)", processed, len(csvFiles))
+	}
+}
+
+func TestDispatchSegments_ControlFile(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	segments, err := segment.SegmentHashSpace(6)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "control")
+	writeControl := func(cmd string) {
+		if err := os.WriteFile(path, []byte(cmd+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write control file: %v", err)
+		}
+	}
+	control := &controlFile{path: path, interval: 10 * time.Millisecond, logger: logger}
+
+	// Segment 2 pauses the run once it finishes
+	var processed atomic.Int32
+	process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+		if s.Index == 2 {
+			writeControl(ControlPause)
+		}
+		processed.Add(1)
+		return []exporter.CSVFile{{Segment: s, RowCount: 10}}, nil
+	}
+
+	// waitProcessed waits until want segments were processed, then checks no more get dispatched
+	waitProcessed := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for processed.Load() < want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if got := processed.Load(); got != want {
+			t.Fatalf("processed %d segments, want %d", got, want)
+		}
+	}
+
+	writeControl(ControlPause)
+	cfg := &config.Config{MaxParallelSegs: 1}
+	done := make(chan error, 1)
+	var csvFiles []exporter.CSVFile
+	go func() {
+		var err error
+		csvFiles, err = dispatchSegments(context.Background(), segments, cfg, nil, control, process, logger)
+		done <- err
+	}()
+
+	// Paused before the first segment
+	waitProcessed(0)
+
+	// Resumed, runs until segment 2 pauses again
+	writeControl(ControlResume)
+	waitProcessed(3)
+
+	// Removing the control file resumes the rest
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove control file: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("dispatchSegments() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatchSegments() did not resume after the control file was removed")
+	}
+	if len(csvFiles) != len(segments) {
+		t.Errorf("expected %d CSV files, got %d", len(segments), len(csvFiles))
+	}
+}
+
+func TestControlFile_Paused(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	path := filepath.Join(t.TempDir(), "control")
+	control := &controlFile{path: path, interval: time.Millisecond, logger: logger}
+
+	tests := []struct {
+		name    string
+		content *string
+		want    bool
+	}{
+		{"missing file", nil, false},
+		{"pause", strPtr("pause"), true},
+		{"pause with whitespace and case", strPtr("  PAUSE\n"), true},
+		{"resume", strPtr("resume"), false},
+		{"empty", strPtr(""), false},
+		{"unknown command", strPtr("stop"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(path)
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0644); err != nil {
+					t.Fatalf("Failed to write control file: %v", err)
+				}
+			}
+			if got := control.paused(); got != tt.want {
+				t.Errorf("paused() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A nil control file never blocks
+	var none *controlFile
+	none.waitWhilePaused(context.Background())
+}
+
+func TestDispatchSegments_CancelledWhilePaused(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	segments, err := segment.SegmentHashSpace(4)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "control")
+	if err := os.WriteFile(path, []byte(ControlPause), 0644); err != nil {
+		t.Fatalf("Failed to write control file: %v", err)
+	}
+	control := &controlFile{path: path, interval: time.Hour, logger: logger}
+
+	var processed atomic.Int32
+	process := func(s segment.Segment) ([]exporter.CSVFile, error) {
+		processed.Add(1)
+		return []exporter.CSVFile{{Segment: s, RowCount: 10}}, nil
+	}
+
+	// The run's time is up while paused: dispatch stops without waiting for the next poll
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := dispatchSegments(ctx, segments, &config.Config{MaxParallelSegs: 1}, nil, control, process, logger)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTimeBudgetExhausted) {
+			t.Errorf("dispatchSegments() error = %v, want ErrTimeBudgetExhausted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatchSegments() kept waiting on the paused control file after ctx was cancelled")
+	}
+	if got := processed.Load(); got != 0 {
+		t.Errorf("processed %d segments while paused, want 0", got)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,87 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhookTimeout bounds the whole webhook request, so a slow endpoint never holds up the exit (replaced in tests).
+var webhookTimeout = 5 * time.Second
+
+// Payload is the JSON body posted to -notify-webhook when a run finishes or fails.
+type Payload struct {
+	Text            string   `json:"text"`   // One-line summary, shown by Slack incoming webhooks
+	Status          string   `json:"status"` // "success" or "failure"
+	TenantIDs       []int    `json:"tenant_ids"`
+	Tables          []string `json:"tables"`
+	TotalRows       int      `json:"total_rows"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	ExitCode        int      `json:"exit_code"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// NewPayload builds the payload for a finished run; err is nil on success.
+// The Text summary is filled in from the other fields.
+func NewPayload(tenantIDs []int, tables []string, totalRows int, duration time.Duration, exitCode int, err error) Payload {
+	payload := Payload{
+		Status:          "success",
+		TenantIDs:       tenantIDs,
+		Tables:          tables,
+		TotalRows:       totalRows,
+		DurationSeconds: duration.Seconds(),
+		ExitCode:        exitCode,
+	}
+	if err != nil {
+		payload.Status = "failure"
+		payload.Error = err.Error()
+	}
+
+	tenants := fmt.Sprintf("tenant %d", tenantIDs[0])
+	if len(tenantIDs) > 1 {
+		tenants = fmt.Sprintf("%d tenants", len(tenantIDs))
+	}
+	payload.Text = fmt.Sprintf("FIS migration of %s %s: %d rows in %s", tenants, payload.Status, totalRows, duration.Round(time.Second))
+	if err != nil {
+		payload.Text += fmt.Sprintf(" (exit code %d: %v)", exitCode, err)
+	}
+	return payload
+}
+
+// Send posts payload as JSON to url. It is best-effort: failures are logged, never returned,
+// and the request gives up after webhookTimeout.
+func Send(url string, payload Payload, logger *zap.Logger) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Warn("Failed to encode webhook payload", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		logger.Warn("Failed to create webhook request", zap.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Warn("Webhook notification failed", zap.Error(err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logger.Warn("Webhook notification rejected", zap.Int("status", resp.StatusCode))
+		return
+	}
+	logger.Info("Webhook notification sent", zap.String("status", payload.Status))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    Payload
+		wantStatus string
+		wantError  string
+		wantText   string
+	}{
+		{
+			"success",
+			NewPayload([]int{1234}, []string{"fis_aggr"}, 250, 90*time.Second, 0, nil),
+			"success", "", "FIS migration of tenant 1234 success: 250 rows in 1m30s",
+		},
+		{
+			"failure",
+			NewPayload([]int{1, 2}, []string{"fis_aggr"}, 10, 3*time.Second, 3, errors.New("failed to process segments")),
+			"failure", "failed to process segments", "FIS migration of 2 tenants failure: 10 rows in 3s (exit code 3: failed to process segments)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contentType string
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Errorf("invalid JSON payload %q: %v", data, err)
+				}
+			}))
+			defer server.Close()
+
+			Send(server.URL, tt.payload, zaptest.NewLogger(t))
+
+			if contentType != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", contentType)
+			}
+			for _, key := range []string{"text", "status", "tenant_ids", "tables", "total_rows", "duration_seconds", "exit_code"} {
+				if _, ok := body[key]; !ok {
+					t.Errorf("payload is missing %q: %v", key, body)
+				}
+			}
+			if body["status"] != tt.wantStatus || body["text"] != tt.wantText {
+				t.Errorf("status = %v, text = %v, want %s, %q", body["status"], body["text"], tt.wantStatus, tt.wantText)
+			}
+			if got, _ := body["error"].(string); got != tt.wantError {
+				t.Errorf("error = %q, want %q", got, tt.wantError)
+			}
+			if body["total_rows"] != float64(tt.payload.TotalRows) || body["exit_code"] != float64(tt.payload.ExitCode) {
+				t.Errorf("total_rows = %v, exit_code = %v, want %d, %d", body["total_rows"], body["exit_code"], tt.payload.TotalRows, tt.payload.ExitCode)
+			}
+		})
+	}
+}
+
+func TestSend_Timeout(t *testing.T) {
+	orig := webhookTimeout
+	webhookTimeout = 50 * time.Millisecond
+	defer func() { webhookTimeout = orig }()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	Send(server.URL, NewPayload([]int{1}, []string{"fis_aggr"}, 0, time.Second, 0, nil), zaptest.NewLogger(t))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() took %s, want it to give up after the timeout", elapsed)
+	}
+
+	// Errors and rejections are logged, not returned or panicked on
+	Send("http://127.0.0.1:1", NewPayload([]int{1}, []string{"fis_aggr"}, 0, time.Second, 0, nil), zaptest.NewLogger(t))
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// circuitCooldown is how long a tripped circuit breaker fails part uploads fast before testing S3 again (replaced in tests).
+var circuitCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned for part uploads attempted while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("S3 circuit breaker open after repeated part upload failures")
+
+// circuitBreaker fails part uploads fast during an S3 outage instead of letting every part of every
+// segment run through all its retries. It is shared by all part uploads of an Uploader.
+// States: closed (uploads go through), open (uploads fail until the cooldown ends) and half-open
+// (one trial upload goes through; its success closes the breaker, its failure opens it again).
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int           // Consecutive part upload failures that trip the breaker
+	cooldown  time.Duration // Open time before a trial upload
+	failures  int           // Consecutive failures across all streams
+	openUntil time.Time     // End of the current open period (zero while closed)
+	trial     bool          // A half-open trial upload is in flight
+}
+
+// newCircuitBreaker returns a breaker tripping after threshold consecutive failures, or nil if threshold is 0 (disabled).
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: circuitCooldown}
+}
+
+// allow reports whether a part upload may be attempted now, or returns ErrCircuitOpen.
+// Once the cooldown has passed, a single caller is let through as the half-open trial.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.trial || time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// record counts the result of an attempted part upload. It reports whether this failure tripped the breaker open.
+func (b *circuitBreaker) record(err error) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasTrial := b.trial
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return false
+	}
+	b.failures++
+	if wasTrial || (b.openUntil.IsZero() && b.failures >= b.threshold) {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	origCooldown := circuitCooldown
+	circuitCooldown = 20 * time.Millisecond
+	defer func() { circuitCooldown = origCooldown }()
+
+	failure := errors.New("503 SlowDown")
+	b := newCircuitBreaker(3)
+
+	// A success resets the count, so only consecutive failures trip the breaker
+	for _, err := range []error{failure, failure, nil, failure, failure} {
+		if b.allow() != nil {
+			t.Fatal("breaker opened before 3 consecutive failures")
+		}
+		b.record(err)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() = %v before the third consecutive failure", err)
+	}
+	if !b.record(failure) {
+		t.Error("record() = false for the failure that trips the breaker")
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() = %v while open, want ErrCircuitOpen", err)
+	}
+
+	// Half-open after the cooldown: one trial, whose failure opens the breaker again
+	time.Sleep(circuitCooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() = %v after the cooldown, want a trial", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() = %v during the trial, want a single trial at a time", err)
+	}
+	if !b.record(failure) {
+		t.Error("record() = false for a failed trial, want the breaker opened again")
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() = %v after a failed trial, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes the breaker
+	time.Sleep(circuitCooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() = %v after the cooldown, want a trial", err)
+	}
+	b.record(nil)
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow() = %v after a successful trial, want the breaker closed", err)
+		}
+	}
+
+	// Disabled
+	disabled := newCircuitBreaker(0)
+	for i := 0; i < 10; i++ {
+		disabled.record(failure)
+	}
+	if err := disabled.allow(); err != nil {
+		t.Errorf("allow() = %v with the breaker disabled", err)
+	}
+}
+
+func TestUploader_CircuitBreakerTrips(t *testing.T) {
+	origDelay := initialRetryDelay
+	initialRetryDelay = time.Millisecond
+	defer func() { initialRetryDelay = origDelay }()
+
+	// A sustained S3 outage: every part upload fails
+	var partRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "":
+			partRequests.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		UsePathStyle:     true,
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+	const threshold = 3
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t),
+		breaker: newCircuitBreaker(threshold)}
+
+	// Several segments' streams share the uploader's breaker
+	for i := 0; i < 4; i++ {
+		stream := &MultipartUploadStream{uploader: uploader, bucket: "test-bucket", key: fmt.Sprintf("segment-%d", i),
+			uploadID: aws.String("upload-1"), partNumber: 1, logger: uploader.logger, ctx: context.Background()}
+		err := stream.UploadPart([]byte("tenantid,hash\n1,00ab\n"))
+		if !errors.Is(err, errs.ErrUploadFailed) || !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("stream %d: UploadPart() error = %v, want an upload failure from the open breaker", i, err)
+		}
+	}
+
+	// The breaker tripped after threshold failed attempts, and later parts never reached S3
+	if got := partRequests.Load(); got != threshold {
+		t.Errorf("S3 got %d part requests, want %d before the breaker opened (without it %d)", got, threshold, 4*maxS3Retries)
+	}
+}
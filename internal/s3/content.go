@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// contentEncodings maps compressed extensions to their Content-Encoding.
+var contentEncodings = map[string]string{
+	".gz":  "gzip",
+	".zst": "zstd",
+}
+
+// contentTypes maps the extensions of uploaded objects to their Content-Type.
+var contentTypes = map[string]string{
+	".csv":     "text/csv",
+	".parquet": "application/vnd.apache.parquet",
+	".jsonl":   "application/x-ndjson",
+	".json":    "application/json",
+	".sql":     "application/sql",
+}
+
+// dataExtensions are the extensions of the exported files, whose type -content-type replaces.
+var dataExtensions = map[string]bool{".csv": true, ".parquet": true}
+
+// contentHeaders returns the Content-Type and Content-Encoding of the object at key from its extensions,
+// e.g. text/csv and gzip for .csv.gz, so S3 doesn't serve it as binary/octet-stream.
+// Aurora's LOAD DATA FROM S3 reads an object with Content-Encoding gzip as gzip.
+// contentType (-content-type) replaces the type of the exported CSV and Parquet files.
+// Either is nil if unknown.
+func contentHeaders(key, contentType string) (*string, *string) {
+	var encoding *string
+	ext := path.Ext(key)
+	if coding, ok := contentEncodings[ext]; ok {
+		encoding = aws.String(coding)
+		ext = path.Ext(strings.TrimSuffix(key, ext))
+	}
+	if contentType != "" && dataExtensions[ext] {
+		return aws.String(contentType), encoding
+	}
+	if known, ok := contentTypes[ext]; ok {
+		return aws.String(known), encoding
+	}
+	return nil, encoding
+}
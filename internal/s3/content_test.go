@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestContentHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          string
+		contentType  string
+		wantType     string
+		wantEncoding string
+	}{
+		{"csv", "p/tenant-1/fis_aggr/tenant-1.fis_aggr.hash-00-10.csv", "", "text/csv", ""},
+		{"gzip csv", "p/a.csv.gz", "", "text/csv", "gzip"},
+		{"zstd csv", "p/a.csv.zst", "", "text/csv", "zstd"},
+		{"parquet", "p/a.parquet", "", "application/vnd.apache.parquet", ""},
+		{"dead letter", "p/dead-letter.jsonl", "", "application/x-ndjson", ""},
+		{"manifest", "p/manifest/tenant-1.fis_aggr.json", "", "application/json", ""},
+		{"sql", "p/sql/load-data-tenant-1.sql", "", "application/sql", ""},
+		{"unknown", "p/.preflight-1", "", "", ""},
+		{"override csv", "p/a.csv.gz", "text/plain; charset=utf-8", "text/plain; charset=utf-8", "gzip"},
+		{"override only data files", "p/sql/load-data-tenant-1.sql", "text/plain", "application/sql", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, contentEncoding := contentHeaders(tt.key, tt.contentType)
+			if aws.ToString(contentType) != tt.wantType || aws.ToString(contentEncoding) != tt.wantEncoding {
+				t.Errorf("contentHeaders(%q, %q) = %q, %q, want %q, %q", tt.key, tt.contentType,
+					aws.ToString(contentType), aws.ToString(contentEncoding), tt.wantType, tt.wantEncoding)
+			}
+		})
+	}
+}
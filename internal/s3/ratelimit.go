@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket of upload bytes shared by all concurrent uploads of an Uploader.
+// Tokens refill at rate bytes per second up to burst.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Bytes per second
+	burst  float64 // Max tokens (bytes) that can accumulate
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter for bytesPerSecond with a burst of 1/10th of a second
+// (at least 32KB), so concurrent readers are interleaved in small chunks.
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	burst := float64(bytesPerSecond) / 10
+	if burst < 32*1024 {
+		burst = 32 * 1024
+	}
+	return &rateLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// mbpsToBytesPerSecond converts megabits per second to bytes per second.
+func mbpsToBytesPerSecond(mbps int) int64 {
+	return int64(mbps) * 1000 * 1000 / 8
+}
+
+// maxChunk returns the largest read size that can be granted at once.
+func (l *rateLimiter) maxChunk() int {
+	return int(l.burst)
+}
+
+// wait blocks until n bytes worth of tokens are available and consumes them.
+// n must not exceed maxChunk.
+func (l *rateLimiter) wait(n int) {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		if l.tokens >= float64(n) {
+			l.tokens -= float64(n)
+			l.mu.Unlock()
+			return
+		}
+		missing := float64(n) - l.tokens
+		l.mu.Unlock()
+
+		time.Sleep(time.Duration(missing / l.rate * float64(time.Second)))
+	}
+}
+
+// rateLimitedReader throttles reads from a part body through a shared rateLimiter.
+// Seek is passed through so the SDK can rewind the body for retries and checksums;
+// bytes read again after a rewind are charged again, which keeps throughput under the cap.
+type rateLimitedReader struct {
+	reader  *bytes.Reader
+	limiter *rateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.maxChunk() {
+		p = p[:r.limiter.maxChunk()]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+	return n, err
+}
+
+func (r *rateLimitedReader) Seek(offset int64, whence int) (int64, error) {
+	return r.reader.Seek(offset, whence)
+}
+
+// partBody returns the upload body for a part, rate limited if -upload-rate-limit-mbps is set.
+func (u *Uploader) partBody(data []byte) io.ReadSeeker {
+	if u.rateLimiter == nil {
+		return bytes.NewReader(data)
+	}
+	return &rateLimitedReader{reader: bytes.NewReader(data), limiter: u.rateLimiter}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReader_Throughput(t *testing.T) {
+	const bytesPerSecond = 512 * 1024
+	const payloadSize = 256 * 1024
+
+	tests := []struct {
+		name    string
+		readers int
+	}{
+		{"single upload", 1},
+		{"concurrent uploads share the cap", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(bytesPerSecond)
+			payload := bytes.Repeat([]byte("x"), payloadSize/tt.readers)
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			total := 0
+			for i := 0; i < tt.readers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					reader := &rateLimitedReader{reader: bytes.NewReader(payload), limiter: limiter}
+					n, err := io.Copy(io.Discard, reader)
+					if err != nil {
+						t.Errorf("read error: %v", err)
+					}
+					mu.Lock()
+					total += int(n)
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+			elapsed := time.Since(start)
+
+			if total != len(payload)*tt.readers {
+				t.Fatalf("expected %d bytes read, got %d", len(payload)*tt.readers, total)
+			}
+			// The initial burst is free; everything after it must flow at or below the cap
+			throughput := (float64(total) - limiter.burst) / elapsed.Seconds()
+			if throughput > bytesPerSecond {
+				t.Errorf("throughput %.0f B/s exceeded cap %d B/s (elapsed %v)", throughput, bytesPerSecond, elapsed)
+			}
+		})
+	}
+}
+
+func TestMbpsToBytesPerSecond(t *testing.T) {
+	if got := mbpsToBytesPerSecond(8); got != 1000*1000 {
+		t.Errorf("mbpsToBytesPerSecond(8) = %d, want 1000000", got)
+	}
+}
+
+func TestUploader_PartBodyUnlimited(t *testing.T) {
+	u := &Uploader{}
+	if _, ok := u.partBody([]byte("data")).(*bytes.Reader); !ok {
+		t.Error("partBody() should return a plain reader when no rate limit is set")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
+	"go.uber.org/zap"
+)
+
+// uploadStateDir is the directory under -log-dir holding the -resume-uploads state files.
+const uploadStateDir = "upload-state"
+
+// uploadState records an in-progress multipart upload (-resume-uploads), so a re-run
+// can continue it instead of creating a new upload and uploading every part again.
+type uploadState struct {
+	Bucket   string      `json:"bucket"`
+	Key      string      `json:"key"`
+	UploadID string      `json:"upload_id"`
+	Parts    []statePart `json:"parts"`
+}
+
+// statePart is a part that was uploaded successfully.
+type statePart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int    `json:"size"`
+}
+
+// uploadStatePath returns the state file for an S3 key, one file per bucket and key.
+func uploadStatePath(dir, bucket, key string) string {
+	return filepath.Join(dir, url.PathEscape(bucket+"/"+key)+".json")
+}
+
+// loadUploadState reads the state file at path. Returns nil if there is none.
+func loadUploadState(path string) (*uploadState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %w", err)
+	}
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse upload state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// save writes the state file atomically, so a crash mid-write leaves the previous state.
+func (s *uploadState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
+
+// listUploadedParts returns the parts S3 holds for an upload, by part number.
+// Fails with a NoSuchUpload API error if the upload was completed, aborted or expired.
+func (u *Uploader) listUploadedParts(ctx context.Context, key, uploadID string) (map[int32]types.Part, error) {
+	parts := make(map[int32]types.Part)
+	paginator := s3.NewListPartsPaginator(u.s3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(u.config.S3Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range page.Parts {
+			parts[aws.ToInt32(part.PartNumber)] = part
+		}
+	}
+	return parts, nil
+}
+
+// partMatches reports whether an uploaded part holds exactly data, by size and MD5 ETag.
+// Parts encrypted with SSE-KMS have a non-MD5 ETag and never match, so they are uploaded again.
+func partMatches(part types.Part, data []byte) bool {
+	if aws.ToInt64(part.Size) != int64(len(data)) {
+		return false
+	}
+	sum := md5.Sum(data)
+	return strings.Trim(aws.ToString(part.ETag), `"`) == hex.EncodeToString(sum[:])
+}
+
+// resumeStateDir creates and returns the state file directory for -resume-uploads,
+// or returns "" when uploads are not resumable.
+func resumeStateDir(cfg *config.Config) (string, error) {
+	if !cfg.ResumeUploads {
+		return "", nil
+	}
+	dir := filepath.Join(cfg.LogDir, uploadStateDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create upload state directory: %w", err)
+	}
+	return dir, nil
+}
+
+// resumeMultipartUploadStream continues the upload recorded at statePath if S3 still has it.
+// Returns nil if there is nothing to resume.
+func (u *Uploader) resumeMultipartUploadStream(ctx context.Context, s3Key, statePath string) (*MultipartUploadStream, error) {
+	state, err := loadUploadState(statePath)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	if state.Bucket != u.config.S3Bucket || state.Key != s3Key || state.UploadID == "" {
+		return nil, nil
+	}
+
+	uploaded, err := u.listUploadedParts(ctx, s3Key, state.UploadID)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload" {
+			u.logger.Warn("Recorded multipart upload no longer exists, starting a new one",
+				zap.String("s3_key", s3Key),
+				zap.String("upload_id", state.UploadID))
+			return nil, nil
+		}
+		return nil, errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to list parts of multipart upload %s: %w", state.UploadID, err))
+	}
+
+	u.logger.Info("Resuming multipart upload stream",
+		zap.String("s3_key", s3Key),
+		zap.String("upload_id", state.UploadID),
+		zap.Int("uploaded_parts", len(uploaded)))
+
+	return &MultipartUploadStream{
+		uploader:   u,
+		bucket:     u.config.S3Bucket,
+		key:        s3Key,
+		uploadID:   aws.String(state.UploadID),
+		parts:      []types.CompletedPart{},
+		partNumber: 1,
+		logger:     u.logger,
+		ctx:        ctx,
+		statePath:  statePath,
+		state:      &uploadState{Bucket: state.Bucket, Key: s3Key, UploadID: state.UploadID},
+		uploaded:   uploaded,
+	}, nil
+}
+
+// recordPart adds a completed part to the state file. Callers must hold m.mu.
+// A failed write only costs re-uploading the part on resume, so it is logged, not returned.
+func (m *MultipartUploadStream) recordPart(partNumber int32, etag string, size int) {
+	if m.statePath == "" {
+		return
+	}
+	m.state.Parts = append(m.state.Parts, statePart{PartNumber: partNumber, ETag: etag, Size: size})
+	if err := m.state.save(m.statePath); err != nil {
+		m.logger.Warn("Failed to save upload state", zap.String("path", m.statePath), zap.Error(err))
+	}
+}
+
+// removeState deletes the state file once the upload is completed or aborted.
+func (m *MultipartUploadStream) removeState() {
+	if m.statePath == "" {
+		return
+	}
+	if err := os.Remove(m.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		m.logger.Warn("Failed to remove upload state", zap.String("path", m.statePath), zap.Error(err))
+	}
+}
+
+// KeepParts adds parts 1 to n of a resumed upload, as S3 holds them from a previous run, without their data,
+// so the next part uploaded is n+1. -checkpoint-interval continues a segment after the rows of those parts.
+// Fails, keeping no part, if any of them isn't in the upload.
+func (m *MultipartUploadStream) KeepParts(n int32) error {
+	for partNumber := int32(1); partNumber <= n; partNumber++ {
+		if _, ok := m.uploaded[partNumber]; !ok {
+			return fmt.Errorf("part %d is not in multipart upload %s", partNumber, aws.ToString(m.uploadID))
+		}
+	}
+	for partNumber := int32(1); partNumber <= n; partNumber++ {
+		part := m.uploaded[partNumber]
+		m.addPart(partNumber, part.ETag, int(aws.ToInt64(part.Size)))
+	}
+	m.logger.Info("Kept multipart parts of previous run",
+		zap.String("s3_key", m.key),
+		zap.Int32("parts", n))
+	return nil
+}
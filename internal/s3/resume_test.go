@@ -0,0 +1,245 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeResumableServer is a minimal S3 endpoint holding one multipart upload per upload ID
+type fakeResumableServer struct {
+	mu        sync.Mutex
+	creates   int
+	parts     map[string]map[int][]byte // Upload ID -> part number -> data
+	puts      map[int]int               // Uploads per part number
+	failPart  int                       // Part number answered with 500 (0 for none)
+	aborted   []string
+	completed []int32
+}
+
+func newFakeResumableServer() *fakeResumableServer {
+	return &fakeResumableServer{parts: map[string]map[int][]byte{}, puts: map[int]int{}}
+}
+
+func (f *fakeResumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	_, hasUploads := query["uploads"]
+	switch {
+	case r.Method == http.MethodPost && hasUploads:
+		f.creates++
+		uploadID = fmt.Sprintf("upload-%d", f.creates)
+		f.parts[uploadID] = map[int][]byte{}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>test-bucket</Bucket><Key>test-key</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, uploadID)
+	case f.parts[uploadID] == nil:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code><Message>The specified upload does not exist.</Message></Error>`)
+	case r.Method == http.MethodPut:
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		if partNumber == f.failPart {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		f.parts[uploadID][partNumber] = data
+		f.puts[partNumber]++
+		w.Header().Set("ETag", `"`+md5Hex(data)+`"`)
+	case r.Method == http.MethodGet:
+		fmt.Fprintf(w, `<ListPartsResult><Bucket>test-bucket</Bucket><Key>test-key</Key><UploadId>%s</UploadId><IsTruncated>false</IsTruncated>`, uploadID)
+		for partNumber, data := range f.parts[uploadID] {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%s"</ETag><Size>%d</Size></Part>`, partNumber, md5Hex(data), len(data))
+		}
+		fmt.Fprint(w, `</ListPartsResult>`)
+	case r.Method == http.MethodPost:
+		var body struct {
+			Parts []struct {
+				PartNumber int32 `xml:"PartNumber"`
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, part := range body.Parts {
+			f.completed = append(f.completed, part.PartNumber)
+		}
+		delete(f.parts, uploadID)
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>test-bucket</Bucket><Key>test-key</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete:
+		f.aborted = append(f.aborted, uploadID)
+		delete(f.parts, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotImplemented)
+	}
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMultipartUploadStream_Resume(t *testing.T) {
+	origDelay := initialRetryDelay
+	initialRetryDelay = time.Millisecond
+	defer func() { initialRetryDelay = origDelay }()
+
+	fake := newFakeResumableServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		UsePathStyle:     true,
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+	stateDir := t.TempDir()
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t), stateDir: stateDir}
+	statePath := uploadStatePath(stateDir, "test-bucket", "test-key")
+
+	// First run: two parts upload, then the third fails after retries
+	stream, err := uploader.NewMultipartUploadStream("test-key")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	for _, part := range []string{"part 1\n", "part 2\n"} {
+		if err := stream.UploadPart([]byte(part)); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	fake.failPart = 3
+	if err := stream.UploadPart([]byte("part 3\n")); err == nil {
+		t.Fatal("UploadPart() should fail when S3 keeps failing the part")
+	}
+	stream.Abort() // As ExportSegment does on failure
+	if len(fake.aborted) != 0 {
+		t.Fatalf("resumable upload was aborted: %v", fake.aborted)
+	}
+	state, err := loadUploadState(statePath)
+	if err != nil || state == nil {
+		t.Fatalf("loadUploadState() = %v, %v, want the recorded upload", state, err)
+	}
+	if state.UploadID != "upload-1" || len(state.Parts) != 2 {
+		t.Fatalf("state = %+v, want upload-1 with 2 parts", state)
+	}
+
+	// Re-run: unchanged part 1 is skipped, changed part 2 and new part 3 are uploaded
+	fake.failPart = 0
+	stream, err = uploader.NewMultipartUploadStream("test-key")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	if fake.creates != 1 || aws.ToString(stream.uploadID) != "upload-1" {
+		t.Fatalf("re-run created upload %s (%d creates), want to resume upload-1", aws.ToString(stream.uploadID), fake.creates)
+	}
+	for _, part := range []string{"part 1\n", "part 2 changed\n", "part 3\n"} {
+		if err := stream.UploadPart([]byte(part)); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	if err := stream.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if fake.puts[1] != 1 || fake.puts[2] != 2 || fake.puts[3] != 1 {
+		t.Errorf("part uploads = %v, want part 1 once, part 2 twice, part 3 once", fake.puts)
+	}
+	if fmt.Sprint(fake.completed) != "[1 2 3]" {
+		t.Errorf("CompleteMultipartUpload parts = %v, want [1 2 3]", fake.completed)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("state file should be removed after Complete, stat error = %v", err)
+	}
+
+	// A recorded upload S3 no longer has starts over
+	if err := (&uploadState{Bucket: "test-bucket", Key: "test-key", UploadID: "upload-1"}).save(statePath); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	stream, err = uploader.NewMultipartUploadStream("test-key")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	if aws.ToString(stream.uploadID) != "upload-2" {
+		t.Errorf("upload ID = %s, want a new upload-2", aws.ToString(stream.uploadID))
+	}
+	stream.Abort()
+	if len(fake.aborted) != 1 || fake.aborted[0] != "upload-2" {
+		t.Errorf("aborted = %v, want the empty upload-2 aborted", fake.aborted)
+	}
+}
+
+func TestMultipartUploadStream_KeepParts(t *testing.T) {
+	fake := newFakeResumableServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		UsePathStyle:     true,
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t), stateDir: t.TempDir()}
+
+	// First run: two parts upload before the run stops
+	stream, err := uploader.NewMultipartUploadStream("test-key")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	for _, part := range []string{"part 1\n", "part 2\n"} {
+		if err := stream.UploadPart([]byte(part)); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	stream.Abort()
+
+	// Re-run: a third part can't be kept, two are, and the next part is number 3
+	stream, err = uploader.NewMultipartUploadStream("test-key")
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	if err := stream.KeepParts(3); err == nil {
+		t.Fatal("KeepParts(3) should fail when the upload has 2 parts")
+	}
+	if err := stream.KeepParts(2); err != nil {
+		t.Fatalf("KeepParts(2) error = %v", err)
+	}
+	if err := stream.UploadPart([]byte("part 3\n")); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	if err := stream.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if fake.puts[1] != 1 || fake.puts[2] != 1 || fake.puts[3] != 1 {
+		t.Errorf("part uploads = %v, want each part once", fake.puts)
+	}
+	if fmt.Sprint(fake.completed) != "[1 2 3]" {
+		t.Errorf("CompleteMultipartUpload parts = %v, want [1 2 3]", fake.completed)
+	}
+	if stream.size != int64(len("part 1\npart 2\npart 3\n")) {
+		t.Errorf("size = %d, want the kept parts counted", stream.size)
+	}
+}
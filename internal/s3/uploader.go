@@ -3,19 +3,26 @@
 package s3
 
 import (
-	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"github.com/netSkope/fis-migration-tool/internal/util"
 	"go.uber.org/zap"
 )
@@ -25,20 +32,59 @@ const (
 	multipartThreshold = 5 * 1024 * 1024
 	// Max retries for S3 operations
 	maxS3Retries = 5
-	// Initial retry delay
-	initialRetryDelay = 1 * time.Second
+	// Max part number allowed by S3 multipart uploads
+	maxPartNumber = 10000
 )
 
+// initialRetryDelay is the delay before the first retry of an S3 operation (replaced in tests).
+var initialRetryDelay = 1 * time.Second
+
+// imdsRegionTimeout bounds the instance metadata lookup, which only answers on EC2/ECS.
+const imdsRegionTimeout = 5 * time.Second
+
+// imdsRegion returns the region of the instance from its metadata (replaced in tests).
+var imdsRegion = func(awsCfg aws.Config) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), imdsRegionTimeout)
+	defer cancel()
+	out, err := imds.NewFromConfig(awsCfg).GetRegion(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	if out.Region == "" {
+		return "", fmt.Errorf("no region in instance metadata")
+	}
+	return out.Region, nil
+}
+
 // Uploader handles S3 uploads with multipart support.
 type Uploader struct {
-	s3Client *s3.Client
-	uploader *manager.Uploader
-	config   *config.Config
-	logger   *zap.Logger
+	s3Client     *s3.Client
+	uploader     *manager.Uploader
+	config       *config.Config
+	logger       *zap.Logger
+	tagging      *string            // URL-encoded object tags (nil if none)
+	storageClass types.StorageClass // Empty uses the bucket default
+	rateLimiter  *rateLimiter       // Shared by all part uploads (nil if unlimited)
+	uploadSlots  chan struct{}      // One per in-flight part upload, -max-parallel-uploads (nil if unlimited)
+	breaker      *circuitBreaker    // Shared by all part uploads, -s3-circuit-threshold (nil if disabled)
+	stateDir     string             // -resume-uploads state files (empty if not resuming)
 }
 
 // NewUploader creates a new S3 uploader.
 func NewUploader(cfg *config.Config, logger *zap.Logger) (*Uploader, error) {
+	tagging, err := ParseTags(cfg.S3Tags)
+	if err != nil {
+		return nil, err
+	}
+	storageClass, err := ParseStorageClass(cfg.S3StorageClass)
+	if err != nil {
+		return nil, err
+	}
+	stateDir, err := resumeStateDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load AWS credentials with priority: CLI flags > Env vars > AWS SDK default chain > Vault files
 	// If CLI flags are provided, they are set as environment variables.
 	// Otherwise, the function checks existing environment variables, then falls back to
@@ -52,32 +98,46 @@ func NewUploader(cfg *config.Config, logger *zap.Logger) (*Uploader, error) {
 	// 3. IAM role (if running on EC2) - used automatically by SDK
 	// 4. Vault credentials (if LoadAWSCredentials set them as fallback)
 	ctx := context.Background()
-	awsCfgOptions := []func(*awsconfig.LoadOptions) error{
-		awsconfig.WithRegion(cfg.AWSRegion),
+	// Without -aws-region the SDK resolves the region from AWS_REGION or the shared config
+	var awsCfgOptions []func(*awsconfig.LoadOptions) error
+	if cfg.AWSRegion != "" {
+		awsCfgOptions = append(awsCfgOptions, awsconfig.WithRegion(cfg.AWSRegion))
 	}
 
-	// Support custom endpoint via environment variable (for LocalStack)
-	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+	// Support custom endpoint via -s3-endpoint or environment variable (for LocalStack, MinIO)
+	endpoint, pathStyle := resolveEndpoint(cfg)
+	if endpoint != "" {
 		awsCfgOptions = append(awsCfgOptions, awsconfig.WithBaseEndpoint(endpoint))
-		logger.Info("Using custom S3 endpoint", zap.String("endpoint", endpoint))
+		logger.Info("Using custom S3 endpoint",
+			zap.String("endpoint", endpoint),
+			zap.Bool("path_style", pathStyle))
 	}
 
 	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsCfgOptions...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("failed to load AWS config: %w", err))
+	}
+	if awsCfg.Region == "" {
+		// Then from instance metadata, when running on EC2/ECS
+		region, err := imdsRegion(awsCfg)
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("aws-region is required: none in AWS_REGION or the shared config, and instance metadata is unavailable: %w", err))
+		}
+		awsCfg.Region = region
+	}
+	if cfg.AWSRegion == "" {
+		logger.Info("Detected AWS region", zap.String("region", awsCfg.Region))
+		cfg.AWSRegion = awsCfg.Region
 	}
 
-	// For LocalStack, we need to configure the S3 client to use path-style addressing
-	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+	if endpoint != "" {
 		awsCfg.BaseEndpoint = aws.String(endpoint)
 	}
 
-	// Create S3 client with path-style addressing for LocalStack
+	// Create S3 client with path-style addressing for LocalStack and MinIO
 	s3Options := []func(*s3.Options){
 		func(o *s3.Options) {
-			if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
-				o.UsePathStyle = true // Required for LocalStack
-			}
+			o.UsePathStyle = pathStyle
 		},
 	}
 	s3Client := s3.NewFromConfig(awsCfg, s3Options...)
@@ -86,14 +146,117 @@ func NewUploader(cfg *config.Config, logger *zap.Logger) (*Uploader, error) {
 		u.Concurrency = 3              // 3 concurrent uploads
 	})
 
+	// One token bucket for all concurrent part uploads so the total stays under the cap
+	var limiter *rateLimiter
+	if cfg.UploadRateLimitMbps > 0 {
+		limiter = newRateLimiter(mbpsToBytesPerSecond(cfg.UploadRateLimitMbps))
+		logger.Info("Limiting S3 upload bandwidth",
+			zap.Int("mbps", cfg.UploadRateLimitMbps))
+	}
+	if cfg.MaxParallelUploads > 0 {
+		logger.Info("Limiting concurrent S3 part uploads",
+			zap.Int("max_parallel_uploads", cfg.MaxParallelUploads),
+			zap.Int("max_parallel_segments", cfg.MaxParallelSegs))
+	}
+
 	return &Uploader{
-		s3Client: s3Client,
-		uploader: uploader,
-		config:   cfg,
-		logger:   logger,
+		s3Client:     s3Client,
+		uploader:     uploader,
+		config:       cfg,
+		logger:       logger,
+		tagging:      tagging,
+		storageClass: storageClass,
+		stateDir:     stateDir,
+		rateLimiter:  limiter,
+		uploadSlots:  newUploadSlots(cfg.MaxParallelUploads),
+		breaker:      newCircuitBreaker(cfg.S3CircuitThreshold),
 	}, nil
 }
 
+// newUploadSlots returns the semaphore capping concurrent part uploads at max, or nil if max is 0 (unlimited).
+func newUploadSlots(max int) chan struct{} {
+	if max <= 0 {
+		return nil
+	}
+	return make(chan struct{}, max)
+}
+
+// acquireUploadSlot blocks until fewer than -max-parallel-uploads part uploads are in flight.
+// Every segment's stream shares the uploader, so the cap holds across segments.
+func (u *Uploader) acquireUploadSlot() {
+	if u.uploadSlots != nil {
+		u.uploadSlots <- struct{}{}
+	}
+}
+
+// releaseUploadSlot frees the slot taken by acquireUploadSlot.
+func (u *Uploader) releaseUploadSlot() {
+	if u.uploadSlots != nil {
+		<-u.uploadSlots
+	}
+}
+
+// resolveEndpoint returns the custom S3 endpoint (empty for AWS) and whether to use path-style addressing.
+// -s3-endpoint takes precedence over AWS_ENDPOINT_URL. With -s3-addressing auto an endpoint from
+// AWS_ENDPOINT_URL always uses path-style, as LocalStack requires; otherwise path-style follows -s3-path-style.
+// -s3-addressing path or virtual sets the style whatever the endpoint.
+func resolveEndpoint(cfg *config.Config) (string, bool) {
+	endpoint, pathStyle := cfg.S3Endpoint, cfg.S3PathStyle
+	if endpoint == "" {
+		if endpoint = os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+			pathStyle = true
+		}
+	}
+	switch cfg.S3Addressing {
+	case "path":
+		pathStyle = true
+	case "virtual":
+		pathStyle = false
+	}
+	return endpoint, pathStyle
+}
+
+// ParseTags converts comma-separated key=value pairs into the URL-encoded form used by
+// the S3 Tagging parameter. Returns nil for an empty value.
+func ParseTags(tags string) (*string, error) {
+	if strings.TrimSpace(tags) == "" {
+		return nil, nil
+	}
+
+	values := url.Values{}
+	for _, pair := range strings.Split(tags, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid S3 tag %q (expected key=value)", pair)
+		}
+		if values.Has(key) {
+			return nil, fmt.Errorf("duplicate S3 tag key %q", key)
+		}
+		values.Set(key, strings.TrimSpace(value))
+	}
+
+	// S3 allows at most 10 tags per object
+	if len(values) > 10 {
+		return nil, fmt.Errorf("too many S3 tags: %d (max 10)", len(values))
+	}
+
+	return aws.String(values.Encode()), nil
+}
+
+// ParseStorageClass validates an S3 storage class name. Returns "" for an empty value.
+func ParseStorageClass(storageClass string) (types.StorageClass, error) {
+	if storageClass == "" {
+		return "", nil
+	}
+	for _, known := range types.StorageClass("").Values() {
+		if string(known) == storageClass {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported S3 storage class %q", storageClass)
+}
+
 // UploadFile uploads a file to S3 with automatic multipart for large files.
 func (u *Uploader) UploadFile(filepath, s3Key string) error {
 	file, err := os.Open(filepath)
@@ -117,14 +280,19 @@ func (u *Uploader) UploadFile(filepath, s3Key string) error {
 	// Use manager.Uploader which handles multipart automatically
 	// It will use multipart upload for files > 5MB
 	ctx := context.Background()
+	contentType, contentEncoding := contentHeaders(s3Key, u.config.ContentType)
 	_, err = u.uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(u.config.S3Bucket),
-		Key:    aws.String(s3Key),
-		Body:   file,
+		Bucket:          aws.String(u.config.S3Bucket),
+		Key:             aws.String(s3Key),
+		Body:            file,
+		Tagging:         u.tagging,
+		StorageClass:    u.storageClass,
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to upload file: %w", err)
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to upload file: %w", err))
 	}
 
 	u.logger.Info("File uploaded successfully",
@@ -134,7 +302,7 @@ func (u *Uploader) UploadFile(filepath, s3Key string) error {
 	return nil
 }
 
-// UploadFileWithRetry uploads a file with retry logic.
+// UploadFileWithRetry uploads a file with retry logic. Only retryable failures (errs.IsRetryable) are retried.
 func (u *Uploader) UploadFileWithRetry(filepath, s3Key string) error {
 	var lastErr error
 	delay := initialRetryDelay
@@ -144,6 +312,9 @@ func (u *Uploader) UploadFileWithRetry(filepath, s3Key string) error {
 		if err == nil {
 			return nil
 		}
+		if !errs.IsRetryable(err) {
+			return err // e.g. the local file can't be read, retrying won't help
+		}
 
 		lastErr = err
 		if attempt < maxS3Retries {
@@ -161,6 +332,150 @@ func (u *Uploader) UploadFileWithRetry(filepath, s3Key string) error {
 	return fmt.Errorf("upload failed after %d attempts: %w", maxS3Retries, lastErr)
 }
 
+// GetObjectRange downloads bytes start through end (inclusive, as in an HTTP Range header) of an object.
+// Fewer bytes are returned if the object is shorter than the range.
+func (u *Uploader) GetObjectRange(s3Key string, start, end int64) ([]byte, error) {
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid byte range %d-%d", start, end)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	output, err := u.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.config.S3Bucket),
+		Key:    aws.String(s3Key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object range %s: %w", s3Key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object range %s: %w", s3Key, err)
+	}
+	return data, nil
+}
+
+// GetObject downloads a whole object.
+func (u *Uploader) GetObject(s3Key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	output, err := u.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.config.S3Bucket),
+		Key:    aws.String(s3Key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", s3Key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", s3Key, err)
+	}
+	return data, nil
+}
+
+// OpenObject opens a whole object for streaming, for objects too large to hold with GetObject.
+// The download is bounded by ctx; the caller must close the body.
+func (u *Uploader) OpenObject(ctx context.Context, s3Key string) (io.ReadCloser, error) {
+	output, err := u.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.config.S3Bucket),
+		Key:    aws.String(s3Key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", s3Key, err)
+	}
+	return output.Body, nil
+}
+
+// ListObjects returns the keys of all objects under prefix, in key order.
+func (u *Uploader) ListObjects(prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(u.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(u.config.S3Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to list objects under %s: %w", prefix, err))
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
+
+// maxDeleteKeys is the most keys one DeleteObjects request accepts.
+const maxDeleteKeys = 1000
+
+// DeleteObjects deletes the objects with keys, in batches of up to 1000.
+func (u *Uploader) DeleteObjects(keys []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for start := 0; start < len(keys); start += maxDeleteKeys {
+		end := start + maxDeleteKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+		output, err := u.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(u.config.S3Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to delete objects: %w", err))
+		}
+		if len(output.Errors) > 0 {
+			first := output.Errors[0]
+			return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to delete %d object(s), first %s: %s",
+				len(output.Errors), aws.ToString(first.Key), aws.ToString(first.Message)))
+		}
+	}
+	return nil
+}
+
+// CheckAccess checks that the bucket exists and is writable: HeadBucket, then a tiny object is
+// put and deleted under the S3 prefix.
+func (u *Uploader) CheckAccess() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bucket := aws.String(u.config.S3Bucket)
+	if _, err := u.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: bucket}); err != nil {
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to access bucket %s: %w", u.config.S3Bucket, err))
+	}
+
+	key := fmt.Sprintf("%s/.preflight-%d", strings.TrimSuffix(u.config.S3Prefix, "/"), time.Now().UnixNano())
+	if _, err := u.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: bucket,
+		Key:    aws.String(key),
+		Body:   strings.NewReader("preflight"),
+	}); err != nil {
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to put test object %s: %w", key, err))
+	}
+	if _, err := u.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: bucket,
+		Key:    aws.String(key),
+	}); err != nil {
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to delete test object %s: %w", key, err))
+	}
+	return nil
+}
+
 // UploadMultipartFile uploads a large file using multipart upload (manual implementation).
 // This is an alternative to manager.Uploader for more control.
 func (u *Uploader) UploadMultipartFile(filepath, s3Key string) error {
@@ -189,14 +504,19 @@ func (u *Uploader) UploadMultipartFile(filepath, s3Key string) error {
 	ctx := context.Background()
 
 	// Initiate multipart upload
+	contentType, contentEncoding := contentHeaders(s3Key, u.config.ContentType)
 	createInput := &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(u.config.S3Bucket),
-		Key:    aws.String(s3Key),
+		Bucket:          aws.String(u.config.S3Bucket),
+		Key:             aws.String(s3Key),
+		Tagging:         u.tagging,
+		StorageClass:    u.storageClass,
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
 	}
 
 	createOutput, err := u.s3Client.CreateMultipartUpload(ctx, createInput)
 	if err != nil {
-		return fmt.Errorf("failed to create multipart upload: %w", err)
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to create multipart upload: %w", err))
 	}
 
 	uploadID := createOutput.UploadId
@@ -229,13 +549,19 @@ func (u *Uploader) UploadMultipartFile(filepath, s3Key string) error {
 			Key:        aws.String(s3Key),
 			PartNumber: aws.Int32(partNumber),
 			UploadId:   uploadID,
-			Body:       bytes.NewReader(partData[:n]),
+			ContentMD5: contentMD5(partData[:n]),
 		}
 
 		// Retry logic for part upload
 		var partOutput *s3.UploadPartOutput
 		for attempt := 1; attempt <= maxS3Retries; attempt++ {
+			if err = u.breaker.allow(); err != nil {
+				break // S3 keeps failing, don't wait out the retries
+			}
+			// A failed attempt has read the body, each one gets a fresh reader
+			uploadPartInput.Body = u.partBody(partData[:n])
 			partOutput, err = u.s3Client.UploadPart(ctx, uploadPartInput)
+			u.recordPartResult(err)
 			if err == nil {
 				break
 			}
@@ -250,7 +576,7 @@ func (u *Uploader) UploadMultipartFile(filepath, s3Key string) error {
 
 		if err != nil {
 			u.abortMultipartUpload(ctx, u.config.S3Bucket, s3Key, uploadID)
-			return fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+			return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to upload part %d: %w", partNumber, err))
 		}
 
 		parts = append(parts, types.CompletedPart{
@@ -278,7 +604,7 @@ func (u *Uploader) UploadMultipartFile(filepath, s3Key string) error {
 	_, err = u.s3Client.CompleteMultipartUpload(ctx, completeInput)
 	if err != nil {
 		u.abortMultipartUpload(ctx, u.config.S3Bucket, s3Key, uploadID)
-		return fmt.Errorf("failed to complete multipart upload: %w", err)
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to complete multipart upload: %w", err))
 	}
 
 	u.logger.Info("Multipart upload completed",
@@ -312,35 +638,59 @@ func (u *Uploader) abortMultipartUpload(ctx context.Context, bucket, key string,
 
 // MultipartUploadStream manages a streaming multipart upload where each batch is uploaded as a part.
 // This is used for hash ranges where each 100k-row batch becomes a multipart part.
+// Parts may also be uploaded concurrently and out of order with UploadPartN.
 type MultipartUploadStream struct {
 	uploader   *Uploader
 	bucket     string
 	key        string
 	uploadID   *string
+	mu         sync.Mutex // Protects parts, partNumber and size
 	parts      []types.CompletedPart
-	partNumber int32
+	partNumber int32 // Next part number assigned by UploadPart
+	size       int64 // Sum of the part sizes, the size of the object once completed
 	logger     *zap.Logger
 	ctx        context.Context
+
+	// With -resume-uploads: the state file, and the parts S3 already holds from a previous run
+	statePath string
+	state     *uploadState
+	uploaded  map[int32]types.Part
 }
 
 // NewMultipartUploadStream initiates a new multipart upload for streaming.
+// With -resume-uploads it continues the upload recorded for s3Key by a previous run instead,
+// and parts S3 already holds with the same content are not uploaded again.
 func (u *Uploader) NewMultipartUploadStream(s3Key string) (*MultipartUploadStream, error) {
 	ctx := context.Background()
+	statePath := ""
+	if u.stateDir != "" {
+		statePath = uploadStatePath(u.stateDir, u.config.S3Bucket, s3Key)
+		stream, err := u.resumeMultipartUploadStream(ctx, s3Key, statePath)
+		if err != nil || stream != nil {
+			return stream, err
+		}
+	}
+
+	contentType, contentEncoding := contentHeaders(s3Key, u.config.ContentType)
 	createInput := &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(u.config.S3Bucket),
-		Key:    aws.String(s3Key),
+		Bucket:          aws.String(u.config.S3Bucket),
+		Key:             aws.String(s3Key),
+		Tagging:         u.tagging,
+		StorageClass:    u.storageClass,
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
 	}
 
 	createOutput, err := u.s3Client.CreateMultipartUpload(ctx, createInput)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create multipart upload: %w", err)
+		return nil, errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to create multipart upload: %w", err))
 	}
 
 	u.logger.Info("Initiated multipart upload stream",
 		zap.String("s3_key", s3Key),
 		zap.String("upload_id", *createOutput.UploadId))
 
-	return &MultipartUploadStream{
+	stream := &MultipartUploadStream{
 		uploader:   u,
 		bucket:     u.config.S3Bucket,
 		key:        s3Key,
@@ -349,35 +699,91 @@ func (u *Uploader) NewMultipartUploadStream(s3Key string) (*MultipartUploadStrea
 		partNumber: 1,
 		logger:     u.logger,
 		ctx:        ctx,
-	}, nil
+	}
+	if statePath != "" {
+		stream.statePath = statePath
+		stream.state = &uploadState{Bucket: u.config.S3Bucket, Key: s3Key, UploadID: *createOutput.UploadId}
+		if err := stream.state.save(statePath); err != nil {
+			stream.Abort()
+			return nil, err
+		}
+	}
+	return stream, nil
 }
 
 // UploadPart uploads a batch of data as a multipart part.
 // The data should be CSV content (can be a batch of rows).
+// Part numbers are assigned from an internal monotonic counter.
 func (m *MultipartUploadStream) UploadPart(data []byte) error {
 	if len(data) == 0 {
 		return nil // Skip empty parts
 	}
 
+	m.mu.Lock()
+	partNumber := m.partNumber
+	m.partNumber++
+	m.mu.Unlock()
+
+	return m.UploadPartN(partNumber, data)
+}
+
+// UploadPartN uploads data as the multipart part with an explicit part number (1-10000).
+// Parts produced in parallel can be numbered up front and uploaded out of order;
+// Complete orders them by part number. Safe for concurrent use.
+func (m *MultipartUploadStream) UploadPartN(partNumber int32, data []byte) error {
+	if len(data) == 0 {
+		return nil // Skip empty parts
+	}
+	if partNumber < 1 || partNumber > maxPartNumber {
+		return fmt.Errorf("invalid part number %d (must be between 1 and %d)", partNumber, maxPartNumber)
+	}
+
+	m.mu.Lock()
+	for _, part := range m.parts {
+		if aws.ToInt32(part.PartNumber) == partNumber {
+			m.mu.Unlock()
+			return fmt.Errorf("part %d already uploaded", partNumber)
+		}
+	}
+	m.mu.Unlock()
+
+	// Resumed upload: skip parts a previous run already uploaded with the same content
+	if prior, ok := m.uploaded[partNumber]; ok && partMatches(prior, data) {
+		m.addPart(partNumber, prior.ETag, len(data))
+		m.logger.Info("Skipped multipart part already uploaded",
+			zap.Int32("part", partNumber),
+			zap.Int("size", len(data)))
+		return nil
+	}
+
 	uploadPartInput := &s3.UploadPartInput{
 		Bucket:     aws.String(m.bucket),
 		Key:        aws.String(m.key),
-		PartNumber: aws.Int32(m.partNumber),
+		PartNumber: aws.Int32(partNumber),
 		UploadId:   m.uploadID,
-		Body:       bytes.NewReader(data),
+		ContentMD5: contentMD5(data),
 	}
 
 	// Retry logic for part upload
 	var partOutput *s3.UploadPartOutput
 	var err error
 	for attempt := 1; attempt <= maxS3Retries; attempt++ {
+		if err = m.uploader.breaker.allow(); err != nil {
+			break // S3 keeps failing, don't wait out the retries
+		}
+		// A failed attempt has read the body, each one gets a fresh reader
+		uploadPartInput.Body = m.uploader.partBody(data)
+		// The slot is held for the call only, not while backing off
+		m.uploader.acquireUploadSlot()
 		partOutput, err = m.uploader.s3Client.UploadPart(m.ctx, uploadPartInput)
+		m.uploader.releaseUploadSlot()
+		m.uploader.recordPartResult(err)
 		if err == nil {
 			break
 		}
 		if attempt < maxS3Retries {
 			m.logger.Warn("Part upload failed, retrying",
-				zap.Int32("part", m.partNumber),
+				zap.Int32("part", partNumber),
 				zap.Int("attempt", attempt),
 				zap.Error(err))
 			time.Sleep(initialRetryDelay * time.Duration(attempt))
@@ -385,31 +791,71 @@ func (m *MultipartUploadStream) UploadPart(data []byte) error {
 	}
 
 	if err != nil {
-		m.uploader.abortMultipartUpload(m.ctx, m.bucket, m.key, m.uploadID)
-		return fmt.Errorf("failed to upload part %d: %w", m.partNumber, err)
+		// Keep a resumable upload so a re-run continues from the parts uploaded so far
+		if m.statePath == "" {
+			m.uploader.abortMultipartUpload(m.ctx, m.bucket, m.key, m.uploadID)
+		}
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to upload part %d: %w", partNumber, err))
 	}
 
-	m.parts = append(m.parts, types.CompletedPart{
-		ETag:       partOutput.ETag,
-		PartNumber: aws.Int32(m.partNumber),
-	})
+	m.addPart(partNumber, partOutput.ETag, len(data))
 
 	m.logger.Info("Uploaded multipart part",
-		zap.Int32("part", m.partNumber),
+		zap.Int32("part", partNumber),
 		zap.Int("size", len(data)))
 
-	m.partNumber++
 	return nil
 }
 
+// recordPartResult counts a part upload attempt in the circuit breaker and logs when it trips open.
+func (u *Uploader) recordPartResult(err error) {
+	if u.breaker.record(err) {
+		u.logger.Error("S3 circuit breaker open, failing part uploads fast",
+			zap.Int("threshold", u.breaker.threshold),
+			zap.Duration("cooldown", u.breaker.cooldown),
+			zap.Error(err))
+	}
+}
+
+// contentMD5 returns the base64 MD5 of a part for its Content-MD5 header, so S3 rejects a part corrupted in transit.
+func contentMD5(data []byte) *string {
+	sum := md5.Sum(data)
+	return aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// addPart records an uploaded part for Complete and in the -resume-uploads state file.
+func (m *MultipartUploadStream) addPart(partNumber int32, etag *string, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.parts = append(m.parts, types.CompletedPart{
+		ETag:       etag,
+		PartNumber: aws.Int32(partNumber),
+	})
+	m.size += int64(size)
+	// Keep UploadPart numbering past explicitly numbered parts
+	if partNumber >= m.partNumber {
+		m.partNumber = partNumber + 1
+	}
+	m.recordPart(partNumber, aws.ToString(etag), size)
+}
+
 // Complete finalizes the multipart upload after all parts have been uploaded.
+// Parts are sorted by part number, as S3 requires ascending order.
 func (m *MultipartUploadStream) Complete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if len(m.parts) == 0 {
 		// No parts uploaded, abort the upload
 		m.uploader.abortMultipartUpload(m.ctx, m.bucket, m.key, m.uploadID)
+		m.removeState()
 		return fmt.Errorf("no parts uploaded")
 	}
 
+	sort.Slice(m.parts, func(i, j int) bool {
+		return aws.ToInt32(m.parts[i].PartNumber) < aws.ToInt32(m.parts[j].PartNumber)
+	})
+
 	completeInput := &s3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(m.bucket),
 		Key:      aws.String(m.key),
@@ -422,17 +868,31 @@ func (m *MultipartUploadStream) Complete() error {
 	_, err := m.uploader.s3Client.CompleteMultipartUpload(m.ctx, completeInput)
 	if err != nil {
 		m.uploader.abortMultipartUpload(m.ctx, m.bucket, m.key, m.uploadID)
-		return fmt.Errorf("failed to complete multipart upload: %w", err)
+		m.removeState()
+		return errs.Wrap(errs.ErrUploadFailed, fmt.Errorf("failed to complete multipart upload: %w", err))
 	}
+	m.removeState()
 
 	m.logger.Info("Completed multipart upload",
 		zap.String("s3_key", m.key),
-		zap.Int32("parts", m.partNumber-1))
+		zap.Int("parts", len(m.parts)),
+		zap.Int64("bytes", m.size))
 
 	return nil
 }
 
 // Abort cancels the multipart upload.
+// With -resume-uploads an upload that holds parts is kept for the next run to resume.
 func (m *MultipartUploadStream) Abort() {
+	m.mu.Lock()
+	resumable := m.statePath != "" && (len(m.parts) > 0 || len(m.uploaded) > 0)
+	m.mu.Unlock()
+	if resumable {
+		m.logger.Info("Keeping multipart upload to resume",
+			zap.String("s3_key", m.key),
+			zap.String("upload_id", aws.ToString(m.uploadID)))
+		return
+	}
 	m.uploader.abortMultipartUpload(m.ctx, m.bucket, m.key, m.uploadID)
+	m.removeState()
 }
@@ -3,10 +3,29 @@
 package s3
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -54,3 +73,541 @@ func TestMultipartUploadStream_Complete(t *testing.T) {
 		// In integration tests with LocalStack, we'll test the full flow
 	})
 }
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"single tag", "team=fis", "team=fis", false},
+		{"multiple tags with spaces", "team=fis, env=prod", "env=prod&team=fis", false},
+		{"escaped value", "owner=a b", "owner=a+b", false},
+		{"empty value", "archived=", "archived=", false},
+		{"missing value", "team", "", true},
+		{"missing key", "=fis", "", true},
+		{"duplicate key", "team=a,team=b", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotStr := ""
+			if got != nil {
+				gotStr = *got
+			}
+			if gotStr != tt.want {
+				t.Errorf("ParseTags() = %q, want %q", gotStr, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStorageClass(t *testing.T) {
+	if sc, err := ParseStorageClass("STANDARD_IA"); err != nil || sc != types.StorageClassStandardIa {
+		t.Errorf("ParseStorageClass(STANDARD_IA) = %q, %v", sc, err)
+	}
+	if sc, err := ParseStorageClass(""); err != nil || sc != "" {
+		t.Errorf("ParseStorageClass(\"\") = %q, %v", sc, err)
+	}
+	if _, err := ParseStorageClass("standard-ia"); err == nil {
+		t.Error("ParseStorageClass() should reject unknown storage class")
+	}
+}
+
+// fakeMultipartServer is a minimal S3 endpoint for UploadPart and CompleteMultipartUpload
+type fakeMultipartServer struct {
+	mu        sync.Mutex
+	completed []int32 // Part numbers in CompleteMultipartUpload order
+}
+
+func (f *fakeMultipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPut && query.Get("partNumber") != "":
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, query.Get("partNumber")))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		var body struct {
+			Parts []struct {
+				PartNumber int32 `xml:"PartNumber"`
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		for _, part := range body.Parts {
+			f.completed = append(f.completed, part.PartNumber)
+		}
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>test-bucket</Bucket><Key>test-key</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotImplemented)
+	}
+}
+
+func TestMultipartUploadStream_UploadPartNOutOfOrder(t *testing.T) {
+	fake := &fakeMultipartServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	logger := zaptest.NewLogger(t)
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: logger}
+	stream := &MultipartUploadStream{
+		uploader:   uploader,
+		bucket:     "test-bucket",
+		key:        "test-key",
+		uploadID:   aws.String("upload-1"),
+		partNumber: 1,
+		logger:     logger,
+		ctx:        context.Background(),
+	}
+
+	// Upload parts concurrently in reverse order
+	var wg sync.WaitGroup
+	for _, partNumber := range []int32{4, 3, 2, 1} {
+		wg.Add(1)
+		go func(n int32) {
+			defer wg.Done()
+			if err := stream.UploadPartN(n, []byte(fmt.Sprintf("part %d\n", n))); err != nil {
+				t.Errorf("UploadPartN(%d) error = %v", n, err)
+			}
+		}(partNumber)
+	}
+	wg.Wait()
+
+	if err := stream.UploadPartN(2, []byte("dup")); err == nil {
+		t.Error("UploadPartN() should reject a duplicate part number")
+	}
+	if err := stream.UploadPartN(0, []byte("bad")); err == nil {
+		t.Error("UploadPartN() should reject part number 0")
+	}
+
+	// UploadPart continues numbering after explicitly numbered parts
+	if err := stream.UploadPart([]byte("part 5\n")); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+
+	if err := stream.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	want := []int32{1, 2, 3, 4, 5}
+	if fmt.Sprint(fake.completed) != fmt.Sprint(want) {
+		t.Errorf("CompleteMultipartUpload parts = %v, want %v", fake.completed, want)
+	}
+}
+
+func TestMultipartUploadStream_MaxParallelUploads(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		io.Copy(io.Discard, r.Body)
+		time.Sleep(20 * time.Millisecond) // Keep uploads overlapping
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, r.URL.Query().Get("partNumber")))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	logger := zaptest.NewLogger(t)
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	const maxUploads = 2
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: logger,
+		uploadSlots: newUploadSlots(maxUploads)}
+
+	// 4 segments each uploading 5 parts at once share the cap
+	var wg sync.WaitGroup
+	for segment := 0; segment < 4; segment++ {
+		stream := &MultipartUploadStream{
+			uploader:   uploader,
+			bucket:     "test-bucket",
+			key:        fmt.Sprintf("segment-%d", segment),
+			uploadID:   aws.String(fmt.Sprintf("upload-%d", segment)),
+			partNumber: 1,
+			logger:     logger,
+			ctx:        context.Background(),
+		}
+		for part := int32(1); part <= 5; part++ {
+			wg.Add(1)
+			go func(n int32) {
+				defer wg.Done()
+				if err := stream.UploadPartN(n, []byte("part\n")); err != nil {
+					t.Errorf("UploadPartN(%d) error = %v", n, err)
+				}
+			}(part)
+		}
+	}
+	wg.Wait()
+
+	if maxInFlight > maxUploads {
+		t.Errorf("%d part uploads were in flight at once, want at most %d", maxInFlight, maxUploads)
+	}
+	if maxInFlight < maxUploads {
+		t.Errorf("at most %d part uploads were in flight, want the cap of %d reached", maxInFlight, maxUploads)
+	}
+	if newUploadSlots(0) != nil {
+		t.Error("newUploadSlots(0) should leave uploads unlimited")
+	}
+}
+
+// tamperTransport flips the first byte of every part body after the request was built, as corruption in transit would.
+type tamperTransport struct {
+	next http.RoundTripper
+}
+
+func (t tamperTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body[0] ^= 0xff
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return t.next.RoundTrip(r)
+}
+
+func TestUploader_PartContentMD5(t *testing.T) {
+	origDelay := initialRetryDelay
+	initialRetryDelay = time.Millisecond
+	defer func() { initialRetryDelay = origDelay }()
+
+	// Like S3, reject a part whose body doesn't match its Content-MD5
+	var mu sync.Mutex
+	parts, rejected := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && query.Get("partNumber") != "":
+			body, _ := io.ReadAll(r.Body)
+			sum := md5.Sum(body)
+			mu.Lock()
+			defer mu.Unlock()
+			parts++
+			if got := r.Header.Get("Content-MD5"); got != base64.StdEncoding.EncodeToString(sum[:]) {
+				rejected++
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `<Error><Code>BadDigest</Code><Message>Content-MD5 %q does not match the body</Message></Error>`, got)
+				return
+			}
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum))
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>test-key</Key></CompleteMultipartUploadResult>`)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	// A file above the multipart threshold goes through UploadMultipartFile's own part loop
+	file := filepath.Join(t.TempDir(), "large.csv")
+	if err := os.WriteFile(file, bytes.Repeat([]byte("a"), multipartThreshold+1), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	uploads := map[string]func(u *Uploader) error{
+		"stream": func(u *Uploader) error {
+			stream := &MultipartUploadStream{uploader: u, bucket: "test-bucket", key: "test-key", uploadID: aws.String("upload-1"),
+				partNumber: 1, logger: u.logger, ctx: context.Background()}
+			return stream.UploadPart([]byte("tenantid,hash\n1,00ab\n"))
+		},
+		"file": func(u *Uploader) error {
+			return u.UploadMultipartFile(file, "test-key")
+		},
+	}
+
+	for name, upload := range uploads {
+		for _, tamper := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s tampered=%v", name, tamper), func(t *testing.T) {
+				var transport http.RoundTripper = http.DefaultTransport
+				if tamper {
+					transport = tamperTransport{next: transport}
+				}
+				client := s3.New(s3.Options{
+					Region:           "us-east-1",
+					BaseEndpoint:     aws.String(server.URL),
+					UsePathStyle:     true,
+					Credentials:      aws.AnonymousCredentials{},
+					RetryMaxAttempts: 1,
+					HTTPClient:       &http.Client{Transport: transport},
+				})
+				uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t)}
+
+				parts, rejected = 0, 0
+				err := upload(uploader)
+				if !tamper {
+					if err != nil || parts != 1 || rejected != 0 {
+						t.Errorf("upload error = %v after %d parts (%d rejected), want the part accepted", err, parts, rejected)
+					}
+					return
+				}
+				if !errors.Is(err, errs.ErrUploadFailed) {
+					t.Errorf("upload error = %v, want the tampered part rejected as an upload failure", err)
+				}
+				if parts != maxS3Retries || rejected != parts {
+					t.Errorf("S3 rejected %d of %d part uploads, want all %d attempts rejected", rejected, parts, maxS3Retries)
+				}
+			})
+		}
+	}
+}
+
+func TestUploader_GetObjectRange(t *testing.T) {
+	var gotRange string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/test-bucket/test-key" {
+			http.Error(w, "unexpected request", http.StatusNotImplemented)
+			return
+		}
+		gotRange = r.Header.Get("Range")
+		w.Header().Set("Content-Range", "bytes 0-9/100")
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, "tenantid,h")
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t)}
+
+	data, err := uploader.GetObjectRange("test-key", 0, 9)
+	if err != nil {
+		t.Fatalf("GetObjectRange() error = %v", err)
+	}
+	if gotRange != "bytes=0-9" {
+		t.Errorf("Range header = %q, want bytes=0-9", gotRange)
+	}
+	if string(data) != "tenantid,h" {
+		t.Errorf("GetObjectRange() = %q, want %q", data, "tenantid,h")
+	}
+
+	if _, err := uploader.GetObjectRange("test-key", 10, 9); err == nil {
+		t.Error("GetObjectRange() should reject end before start")
+	}
+}
+
+func TestUploader_CheckAccess(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	uploader := &Uploader{s3Client: client, config: &config.Config{S3Bucket: "test-bucket", S3Prefix: "prefix"}, logger: zaptest.NewLogger(t)}
+
+	if err := uploader.CheckAccess(); err != nil {
+		t.Fatalf("CheckAccess() error = %v", err)
+	}
+	if len(requests) != 3 || requests[0] != "HEAD /test-bucket" ||
+		!strings.HasPrefix(requests[1], "PUT /test-bucket/prefix/.preflight-") ||
+		requests[2] != "DELETE"+strings.TrimPrefix(requests[1], "PUT") {
+		t.Errorf("unexpected requests %v, want HEAD bucket then PUT and DELETE of one test object", requests)
+	}
+}
+
+func TestUploader_UploadFileWithRetry_ErrorKinds(t *testing.T) {
+	origDelay := initialRetryDelay
+	initialRetryDelay = time.Millisecond
+	defer func() { initialRetryDelay = origDelay }()
+
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puts++
+		http.Error(w, "access denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		UsePathStyle:     true,
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	})
+	uploader := &Uploader{s3Client: client, uploader: manager.NewUploader(client), config: &config.Config{S3Bucket: "test-bucket"}, logger: zaptest.NewLogger(t)}
+
+	// A failing S3 request is an upload failure and is retried
+	path := filepath.Join(t.TempDir(), "file.sql")
+	if err := os.WriteFile(path, []byte("LOAD DATA"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	err := uploader.UploadFileWithRetry(path, "file.sql")
+	if !errors.Is(err, errs.ErrUploadFailed) {
+		t.Errorf("UploadFileWithRetry() error = %v, want ErrUploadFailed", err)
+	}
+	if puts != maxS3Retries {
+		t.Errorf("S3 got %d uploads, want %d attempts", puts, maxS3Retries)
+	}
+
+	// A missing local file is not an upload failure and is not retried
+	puts = 0
+	err = uploader.UploadFileWithRetry(filepath.Join(t.TempDir(), "missing.sql"), "missing.sql")
+	if err == nil || errors.Is(err, errs.ErrUploadFailed) || errs.IsRetryable(err) {
+		t.Errorf("UploadFileWithRetry() of a missing file error = %v, want a non-retryable error", err)
+	}
+	if puts != 0 {
+		t.Errorf("S3 got %d uploads for a missing file, want none", puts)
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		env           string
+		wantEndpoint  string
+		wantPathStyle bool
+	}{
+		{"aws", config.Config{}, "", "", false},
+		{"env var forces path-style", config.Config{}, "http://localstack:4566", "http://localstack:4566", true},
+		{"flag", config.Config{S3Endpoint: "https://s3.us-gov-west-1.amazonaws.com"}, "", "https://s3.us-gov-west-1.amazonaws.com", false},
+		{"flag with path-style", config.Config{S3Endpoint: "http://minio:9000", S3PathStyle: true}, "", "http://minio:9000", true},
+		{"flag overrides env var", config.Config{S3Endpoint: "http://minio:9000", S3PathStyle: true}, "http://localstack:4566", "http://minio:9000", true},
+		{"path-style without endpoint", config.Config{S3PathStyle: true}, "", "", true},
+		{"auto", config.Config{S3Addressing: "auto"}, "http://localstack:4566", "http://localstack:4566", true},
+		{"virtual overrides env var", config.Config{S3Addressing: "virtual"}, "http://localstack:4566", "http://localstack:4566", false},
+		{"virtual with endpoint", config.Config{S3Endpoint: "https://store.example.com", S3Addressing: "virtual"}, "", "https://store.example.com", false},
+		{"path", config.Config{S3Endpoint: "https://store.example.com", S3Addressing: "path"}, "", "https://store.example.com", true},
+		{"path without endpoint", config.Config{S3Addressing: "path"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_ENDPOINT_URL", tt.env)
+			endpoint, pathStyle := resolveEndpoint(&tt.cfg)
+			if endpoint != tt.wantEndpoint || pathStyle != tt.wantPathStyle {
+				t.Errorf("resolveEndpoint() = %q, %v, want %q, %v", endpoint, pathStyle, tt.wantEndpoint, tt.wantPathStyle)
+			}
+		})
+	}
+}
+
+func TestNewUploader_Region(t *testing.T) {
+	original := imdsRegion
+	defer func() { imdsRegion = original }()
+
+	tests := []struct {
+		name       string
+		flag       string
+		env        string
+		imds       string
+		wantRegion string
+		wantErr    bool
+	}{
+		{"flag", "us-east-1", "eu-west-1", "", "us-east-1", false},
+		{"AWS_REGION without the flag", "", "eu-west-1", "", "eu-west-1", false},
+		{"instance metadata", "", "", "ap-south-1", "ap-south-1", false},
+		{"undiscoverable", "", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No shared config, so the region can only come from the flag, the env or metadata
+			noFile := filepath.Join(t.TempDir(), "missing")
+			t.Setenv("AWS_CONFIG_FILE", noFile)
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", noFile)
+			t.Setenv("AWS_PROFILE", "")
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			t.Setenv("AWS_REGION", tt.env)
+			t.Setenv("AWS_ACCESS_KEY_ID", "test")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+			imdsRegion = func(aws.Config) (string, error) {
+				if tt.imds == "" {
+					return "", errors.New("not running on EC2")
+				}
+				return tt.imds, nil
+			}
+
+			cfg := &config.Config{S3Bucket: "test-bucket", AWSRegion: tt.flag}
+			uploader, err := NewUploader(cfg, zaptest.NewLogger(t))
+			if tt.wantErr {
+				if !errors.Is(err, errs.ErrConfig) || !strings.Contains(err.Error(), "aws-region is required") {
+					t.Errorf("NewUploader() error = %v, want a config error asking for aws-region", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewUploader() error = %v", err)
+			}
+			if got := uploader.s3Client.Options().Region; got != tt.wantRegion {
+				t.Errorf("client region = %q, want %q", got, tt.wantRegion)
+			}
+			if cfg.AWSRegion != tt.wantRegion {
+				t.Errorf("cfg.AWSRegion = %q, want %q", cfg.AWSRegion, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestNewUploader_Addressing(t *testing.T) {
+	tests := []struct {
+		addressing    string
+		wantPathStyle bool
+	}{
+		{"auto", true}, // AWS_ENDPOINT_URL
+		{"path", true},
+		{"virtual", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addressing, func(t *testing.T) {
+			t.Setenv("AWS_ENDPOINT_URL", "http://localstack:4566")
+			t.Setenv("AWS_ACCESS_KEY_ID", "test")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+			cfg := &config.Config{S3Bucket: "test-bucket", AWSRegion: "us-east-1", S3Addressing: tt.addressing}
+			uploader, err := NewUploader(cfg, zaptest.NewLogger(t))
+			if err != nil {
+				t.Fatalf("NewUploader() error = %v", err)
+			}
+			if got := uploader.s3Client.Options().UsePathStyle; got != tt.wantPathStyle {
+				t.Errorf("UsePathStyle = %v, want %v", got, tt.wantPathStyle)
+			}
+		})
+	}
+}
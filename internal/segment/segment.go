@@ -4,32 +4,85 @@ package segment
 
 import (
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
 )
 
-// Segment represents a hash range segment.
+// Segment represents a hash range segment, or a column range segment with -partition-strategy range.
 type Segment struct {
 	Index   int    // Segment index (0-based)
 	StartHex string // Start hex value (inclusive)
 	EndHex   string // End hex value (exclusive, except for last segment)
+	Range    *Range // Column bounds of a range segment (nil for hash segments, which have no Range)
+}
+
+// Range holds the bounds of a range segment on a numeric column.
+type Range struct {
+	Column string // Partition column
+	Start  int64  // Start value (inclusive)
+	End    int64  // End value (exclusive, except for last segment)
+	Last   bool   // Last segment of the range, with no upper bound
+}
+
+// DefaultPrefixLen is the number of leading hex chars partitioned by SegmentHashSpace.
+const DefaultPrefixLen = 2
+
+// MaxPrefixLen is the widest supported hash prefix (4 hex chars = 65536 segments).
+const MaxPrefixLen = 4
+
+// PrefixLen returns the number of hex chars in the segment's boundaries.
+func (s Segment) PrefixLen() int {
+	return len(s.StartHex)
+}
+
+// IsLast reports whether the segment is the last one of its hash space.
+// The last segment's EndHex is one past the largest prefix ("100" for 2 chars, "1000" for 3),
+// so it has no upper bound within the hash space.
+func (s Segment) IsLast() bool {
+	if s.Range != nil {
+		return s.Range.Last
+	}
+	return s.EndHex == intToHexN(prefixSpace(s.PrefixLen()), s.PrefixLen())
 }
 
 // SegmentHashSpace partitions the hash space [00, FF] into N segments.
 // Returns a slice of segments with hex boundaries.
 func SegmentHashSpace(segments int) ([]Segment, error) {
+	return SegmentHashSpaceN(segments, DefaultPrefixLen)
+}
+
+// PrefixLenForSegments returns the narrowest prefix width (at least DefaultPrefixLen)
+// that can be partitioned into the given number of segments.
+func PrefixLenForSegments(segments int) int {
+	prefixLen := DefaultPrefixLen
+	for prefixLen < MaxPrefixLen && segments > prefixSpace(prefixLen) {
+		prefixLen++
+	}
+	return prefixLen
+}
+
+// SegmentHashSpaceN partitions the hash space on the first prefixLen hex chars into N segments,
+// e.g. prefixLen 3 partitions [000, FFF] into up to 4096 segments.
+// Returns a slice of segments with hex boundaries of width prefixLen.
+func SegmentHashSpaceN(segments int, prefixLen int) ([]Segment, error) {
+	if prefixLen < 1 || prefixLen > MaxPrefixLen {
+		return nil, fmt.Errorf("prefix length must be between 1 and %d, got %d", MaxPrefixLen, prefixLen)
+	}
+	space := prefixSpace(prefixLen)
 	if segments <= 0 {
 		return nil, fmt.Errorf("segments must be positive, got %d", segments)
 	}
-	if segments > 256 {
-		return nil, fmt.Errorf("segments cannot exceed 256, got %d", segments)
+	if segments > space {
+		return nil, fmt.Errorf("segments cannot exceed %d for %d-char hash prefixes, got %d", space, prefixLen, segments)
 	}
 
 	segs := make([]Segment, segments)
 
-	// Total hash space: 0x00 to 0xFF (256 values)
-	// Each segment gets approximately 256/segments values
-	segmentSize := 256 / segments
-	remainder := 256 % segments
+	// Total hash space: 16^prefixLen values (0x00 to 0xFF for 2 chars)
+	// Each segment gets approximately space/segments values
+	segmentSize := space / segments
+	remainder := space % segments
 
 	start := 0
 	for i := 0; i < segments; i++ {
@@ -40,38 +93,95 @@ func SegmentHashSpace(segments int) ([]Segment, error) {
 		}
 
 		end := start + size
-		if end > 256 {
-			end = 256
+		if end > space {
+			end = space
 		}
 
+		// The last segment ends at space ("100" for 2 chars), one past the largest prefix
 		segs[i] = Segment{
 			Index:   i,
-			StartHex: intToHex(start),
-			EndHex:   intToHex(end),
+			StartHex: intToHexN(start, prefixLen),
+			EndHex:   intToHexN(end, prefixLen),
 		}
 
 		start = end
 	}
 
-	// Last segment should include FF (make end exclusive for all but last)
-	if len(segs) > 0 {
-		lastIdx := len(segs) - 1
-		// For the last segment, end should be "100" (exclusive) to include FF
-		if segs[lastIdx].EndHex == "ff" {
-			segs[lastIdx].EndHex = "100"
-		}
+	return segs, nil
+}
+
+// prefixSpace returns the number of distinct hash prefixes of the given width.
+func prefixSpace(prefixLen int) int {
+	return 1 << (4 * prefixLen)
+}
+
+// intToHexN converts an integer to a zero-padded hex string of width prefixLen.
+// The end of the space (16^prefixLen) is returned as "1" followed by prefixLen zeros.
+func intToHexN(val int, prefixLen int) string {
+	return fmt.Sprintf("%0*x", prefixLen, val)
+}
+
+// Bounds returns the segment's start and end for filenames and reports:
+// the hex prefixes of a hash segment, or the column values of a range segment in decimal.
+func (s Segment) Bounds() (start string, end string) {
+	if s.Range != nil {
+		return strconv.FormatInt(s.Range.Start, 10), strconv.FormatInt(s.Range.End, 10)
 	}
+	return s.StartHex, s.EndHex
+}
 
-	return segs, nil
+// Label describes the segment's bounds for messages, e.g. "hash 00-10" or "id 1000-2000".
+func (s Segment) Label() string {
+	start, end := s.Bounds()
+	if s.Range != nil {
+		return fmt.Sprintf("%s %s-%s", s.Range.Column, start, end)
+	}
+	return fmt.Sprintf("hash %s-%s", start, end)
 }
 
-// intToHex converts an integer (0-256) to a 2-digit hex string.
-// For values >= 256, returns "100" (to make range exclusive).
-func intToHex(val int) string {
-	if val >= 256 {
-		return "100"
+// SegmentRange partitions the values [min, max] of a numeric column into N segments
+// of about equal width, for -partition-strategy range. A range narrower than N values gets
+// one segment per value. The last segment ends at max+1 and has no upper bound,
+// so rows above max inserted during the migration are still exported.
+func SegmentRange(column string, min, max int64, segments int) ([]Segment, error) {
+	if segments <= 0 {
+		return nil, fmt.Errorf("segments must be positive, got %d", segments)
+	}
+	if max < min {
+		return nil, fmt.Errorf("range max %d is below min %d", max, min)
+	}
+	if max == math.MaxInt64 || uint64(max-min) >= math.MaxInt64 {
+		return nil, fmt.Errorf("range [%d, %d] is too wide to partition", min, max)
+	}
+
+	// The bounds are checked above, so the width fits in an int64
+	width := max - min + 1
+	if int64(segments) > width {
+		segments = int(width)
 	}
-	return fmt.Sprintf("%02x", val)
+
+	segs := make([]Segment, segments)
+	segmentSize := width / int64(segments)
+	remainder := width % int64(segments)
+
+	start := min
+	for i := 0; i < segments; i++ {
+		// Distribute remainder across first segments
+		size := segmentSize
+		if int64(i) < remainder {
+			size++
+		}
+		end := start + size
+
+		segs[i] = Segment{
+			Index: i,
+			Range: &Range{Column: column, Start: start, End: end, Last: i == segments-1},
+		}
+
+		start = end
+	}
+
+	return segs, nil
 }
 
 // SegmentToHexRange converts a segment index to hex boundaries.
@@ -95,16 +205,21 @@ func SegmentToHexRange(segmentIndex int, totalSegments int) (start string, end s
 
 // HashInSegment checks if a hash (hex string) falls within a segment's range.
 func HashInSegment(hash string, seg Segment) bool {
-	if len(hash) < 2 {
+	prefixLen := seg.PrefixLen()
+	if len(hash) < prefixLen {
 		return false
 	}
 
-	// Compare first 2 hex characters
-	hashPrefix := hash[:2]
+	// Compare the first prefixLen hex characters
+	hashPrefix := hash[:prefixLen]
+	if seg.IsLast() {
+		// "100" sorts before "ff" as a string, so the last segment has no upper bound check
+		return hashPrefix >= seg.StartHex
+	}
 	return hashPrefix >= seg.StartHex && hashPrefix < seg.EndHex
 }
 
-// HexToInt converts a 2-digit hex string to an integer.
+// HexToInt converts a hex string (any prefix width) to an integer.
 func HexToInt(hexStr string) (int, error) {
 	val := new(big.Int)
 	val, ok := val.SetString(hexStr, 16)
@@ -3,6 +3,7 @@
 package segment
 
 import (
+	"math"
 	"testing"
 )
 
@@ -177,3 +178,188 @@ func TestSegmentToHexRange(t *testing.T) {
 	}
 }
 
+
+func TestSegmentHashSpaceN(t *testing.T) {
+	tests := []struct {
+		name      string
+		segments  int
+		prefixLen int
+		wantErr   bool
+	}{
+		{"1024 segments on 3-char prefixes", 1024, 3, false},
+		{"4096 segments on 3-char prefixes", 4096, 3, false},
+		{"1000 segments (uneven split)", 1000, 3, false},
+		{"65536 segments on 4-char prefixes", 65536, 4, false},
+		{"2-char prefixes match SegmentHashSpace", 16, 2, false},
+		{"invalid: too many for prefix", 4097, 3, true},
+		{"invalid: prefix too wide", 16, 5, true},
+		{"invalid: zero prefix", 16, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, err := SegmentHashSpaceN(tt.segments, tt.prefixLen)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SegmentHashSpaceN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(segs) != tt.segments {
+				t.Fatalf("expected %d segments, got %d", tt.segments, len(segs))
+			}
+
+			// Ranges are contiguous, non-overlapping and cover the whole prefix space
+			space := 1 << (4 * tt.prefixLen)
+			prevEnd := 0
+			for i, seg := range segs {
+				if len(seg.StartHex) != tt.prefixLen {
+					t.Fatalf("segment %d: expected %d-char start, got %q", i, tt.prefixLen, seg.StartHex)
+				}
+				start, err := HexToInt(seg.StartHex)
+				if err != nil {
+					t.Fatalf("segment %d: HexToInt(%q) error = %v", i, seg.StartHex, err)
+				}
+				end, err := HexToInt(seg.EndHex)
+				if err != nil {
+					t.Fatalf("segment %d: HexToInt(%q) error = %v", i, seg.EndHex, err)
+				}
+				if start != prevEnd {
+					t.Fatalf("segment %d: starts at %d, expected %d (gap or overlap)", i, start, prevEnd)
+				}
+				if end <= start {
+					t.Fatalf("segment %d: empty range %s-%s", i, seg.StartHex, seg.EndHex)
+				}
+				if seg.IsLast() != (i == len(segs)-1) {
+					t.Errorf("segment %d: IsLast() = %v", i, seg.IsLast())
+				}
+				prevEnd = end
+			}
+			if prevEnd != space {
+				t.Errorf("segments end at %d, expected %d", prevEnd, space)
+			}
+
+			if tt.prefixLen == DefaultPrefixLen {
+				want, _ := SegmentHashSpace(tt.segments)
+				for i := range want {
+					if segs[i] != want[i] {
+						t.Errorf("segment %d: got %+v, want %+v", i, segs[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHashInSegment_WidePrefix(t *testing.T) {
+	segs, err := SegmentHashSpaceN(4096, 3)
+	if err != nil {
+		t.Fatalf("SegmentHashSpaceN() error = %v", err)
+	}
+
+	// Each hash falls in exactly one segment
+	for _, hash := range []string{"000abc", "0ffabc", "100abc", "abcdef", "fffabc"} {
+		matches := 0
+		for _, seg := range segs {
+			if HashInSegment(hash, seg) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			t.Errorf("hash %s matched %d segments, expected 1", hash, matches)
+		}
+	}
+
+	last := segs[len(segs)-1]
+	if last.StartHex != "fff" || last.EndHex != "1000" {
+		t.Errorf("last segment should be fff-1000, got %s-%s", last.StartHex, last.EndHex)
+	}
+}
+
+func TestPrefixLenForSegments(t *testing.T) {
+	tests := []struct {
+		segments int
+		want     int
+	}{
+		{1, 2},
+		{256, 2},
+		{257, 3},
+		{1024, 3},
+		{4096, 3},
+		{4097, 4},
+		{65536, 4},
+	}
+
+	for _, tt := range tests {
+		if got := PrefixLenForSegments(tt.segments); got != tt.want {
+			t.Errorf("PrefixLenForSegments(%d) = %d, want %d", tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentRange(t *testing.T) {
+	tests := []struct {
+		name         string
+		min, max     int64
+		segments     int
+		wantSegments int
+		wantErr      bool
+	}{
+		{"even split", 1, 1000, 4, 4, false},
+		{"uneven split", 1, 1001, 4, 4, false},
+		{"negative values", -500, 499, 3, 3, false},
+		{"single value", 42, 42, 4, 1, false},
+		{"fewer values than segments", 10, 12, 16, 3, false},
+		{"one segment", 0, 1 << 40, 1, 1, false},
+		{"invalid: zero segments", 1, 1000, 0, 0, true},
+		{"invalid: max below min", 10, 1, 4, 0, true},
+		{"invalid: too wide", math.MinInt64, math.MaxInt64 - 1, 4, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs, err := SegmentRange("id", tt.min, tt.max, tt.segments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SegmentRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(segs) != tt.wantSegments {
+				t.Fatalf("expected %d segments, got %d", tt.wantSegments, len(segs))
+			}
+
+			// Ranges are contiguous, non-overlapping and cover [min, max]
+			prevEnd := tt.min
+			for i, seg := range segs {
+				if seg.Index != i || seg.Range == nil || seg.Range.Column != "id" {
+					t.Fatalf("segment %d: got %+v, want a range segment on id with index %d", i, seg, i)
+				}
+				if seg.Range.Start != prevEnd {
+					t.Fatalf("segment %d: starts at %d, expected %d (gap or overlap)", i, seg.Range.Start, prevEnd)
+				}
+				if seg.Range.End <= seg.Range.Start {
+					t.Fatalf("segment %d: empty range %d-%d", i, seg.Range.Start, seg.Range.End)
+				}
+				if seg.IsLast() != (i == len(segs)-1) {
+					t.Errorf("segment %d: IsLast() = %v", i, seg.IsLast())
+				}
+				prevEnd = seg.Range.End
+			}
+			if prevEnd != tt.max+1 {
+				t.Errorf("segments end at %d, expected %d", prevEnd, tt.max+1)
+			}
+		})
+	}
+}
+
+func TestSegmentBounds(t *testing.T) {
+	hashSeg := Segment{Index: 0, StartHex: "00", EndHex: "10"}
+	if start, end := hashSeg.Bounds(); start != "00" || end != "10" {
+		t.Errorf("hash segment Bounds() = %s, %s, want 00, 10", start, end)
+	}
+	rangeSeg := Segment{Index: 1, Range: &Range{Column: "id", Start: -5, End: 250}}
+	if start, end := rangeSeg.Bounds(); start != "-5" || end != "250" {
+		t.Errorf("range segment Bounds() = %s, %s, want -5, 250", start, end)
+	}
+}
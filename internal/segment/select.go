@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package segment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseIndexList parses a comma-separated list of segment indices and inclusive ranges, e.g. "0,2,5-7".
+// Every index must be in [0, total). Returns the indices in the order given, without duplicates.
+func ParseIndexList(list string, total int) ([]int, error) {
+	var indices []int
+	seen := make(map[int]bool)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, fmt.Errorf("invalid segment list %q: empty entry", list)
+		}
+
+		start, end := item, item
+		if i := strings.Index(item, "-"); i >= 0 {
+			start, end = item[:i], item[i+1:]
+		}
+		first, err := strconv.Atoi(strings.TrimSpace(start))
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment list %q: bad entry %q", list, item)
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(end))
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment list %q: bad entry %q", list, item)
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid segment list %q: range %q is reversed", list, item)
+		}
+		if first < 0 || last >= total {
+			return nil, fmt.Errorf("invalid segment list %q: %q is outside segments 0-%d", list, item, total-1)
+		}
+
+		for index := first; index <= last; index++ {
+			if !seen[index] {
+				seen[index] = true
+				indices = append(indices, index)
+			}
+		}
+	}
+	return indices, nil
+}
+
+// Select returns the segments whose index is in only (all segments if only is empty) and not in skip.
+// Segments keep their order and original index, so output names match a full run.
+func Select(segments []Segment, only, skip []int) []Segment {
+	include := make(map[int]bool, len(only))
+	for _, index := range only {
+		include[index] = true
+	}
+	exclude := make(map[int]bool, len(skip))
+	for _, index := range skip {
+		exclude[index] = true
+	}
+
+	var selected []Segment
+	for _, seg := range segments {
+		if (len(only) == 0 || include[seg.Index]) && !exclude[seg.Index] {
+			selected = append(selected, seg)
+		}
+	}
+	return selected
+}
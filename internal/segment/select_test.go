@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package segment
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseIndexList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{"0,2", []int{0, 2}, false},
+		{"3-7", []int{3, 4, 5, 6, 7}, false},
+		{" 1, 4-5 ,4", []int{1, 4, 5}, false},
+		{"15", []int{15}, false},
+		{"16", nil, true},
+		{"-1", nil, true},
+		{"7-3", nil, true},
+		{"0,,1", nil, true},
+		{"a-b", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseIndexList(tt.list, 16)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIndexList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ParseIndexList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	segments, err := SegmentHashSpace(8)
+	if err != nil {
+		t.Fatalf("SegmentHashSpace() error = %v", err)
+	}
+
+	indices := func(segs []Segment) []int {
+		var out []int
+		for _, seg := range segs {
+			out = append(out, seg.Index)
+		}
+		return out
+	}
+
+	if got := indices(Select(segments, nil, nil)); len(got) != 8 {
+		t.Errorf("Select() with no filters = %v, want all 8", got)
+	}
+	if got := indices(Select(segments, []int{2, 0}, nil)); fmt.Sprint(got) != "[0 2]" {
+		t.Errorf("Select(only 2,0) = %v, want [0 2]", got)
+	}
+	if got := indices(Select(segments, nil, []int{3, 4, 5})); fmt.Sprint(got) != "[0 1 2 6 7]" {
+		t.Errorf("Select(skip 3-5) = %v, want [0 1 2 6 7]", got)
+	}
+	if got := indices(Select(segments, []int{1, 2, 3}, []int{2})); fmt.Sprint(got) != "[1 3]" {
+		t.Errorf("Select(only 1-3, skip 2) = %v, want [1 3]", got)
+	}
+	// Selected segments keep their hash range
+	if got := Select(segments, []int{2}, nil); got[0] != segments[2] {
+		t.Errorf("Select() changed segment 2: %+v", got[0])
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+)
+
+// Audit record outcomes.
+const (
+	AuditSuccess   = "success"
+	AuditDuplicate = "duplicate" // Failed on duplicate entries, counted as loaded (-sql-duplicate-mode ignore or replace)
+	AuditFailure   = "failure"
+)
+
+// AuditRecord is one statement run on Aurora, written to the -audit-log as a JSON line.
+type AuditRecord struct {
+	TenantID  int       `json:"tenant_id"`
+	Table     string    `json:"table"`
+	Statement int       `json:"statement"` // 1-based position in the run; 0 for BEGIN, COMMIT and ROLLBACK
+	Total     int       `json:"total"`
+	S3Path    string    `json:"s3_path,omitempty"` // Source of a LOAD DATA FROM S3 statement
+	SQL       string    `json:"sql"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	ElapsedMS int64     `json:"elapsed_ms"`
+	Attempts  int       `json:"attempts"` // More than 1 if the statement was retried after a dropped connection
+	Outcome   string    `json:"outcome"`  // success, duplicate or failure
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditLog appends a record of every statement run on Aurora to the -audit-log file, apart from the zap log.
+// The file is only ever appended to, so records of earlier runs are kept, and each record is synced to disk
+// before the next statement runs. A nil *AuditLog records nothing.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	cfg  *config.Config
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if needed.
+func OpenAuditLog(path string, cfg *config.Config) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{file: file, cfg: cfg}, nil
+}
+
+// loadDataSource matches the S3 path of a LOAD DATA FROM S3 statement.
+var loadDataSource = regexp.MustCompile(`^LOAD DATA FROM S3 '([^']*)'`)
+
+// Record writes the outcome of statement (the index-th of total) that ran from start until now.
+func (a *AuditLog) Record(index, total int, statement string, start time.Time, attempts int, outcome string, err error) error {
+	if a == nil {
+		return nil
+	}
+	end := time.Now()
+	record := AuditRecord{
+		TenantID:  a.cfg.TenantID,
+		Table:     a.cfg.TableName,
+		Statement: index,
+		Total:     total,
+		SQL:       statement,
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		ElapsedMS: end.Sub(start).Milliseconds(),
+		Attempts:  attempts,
+		Outcome:   outcome,
+	}
+	if match := loadDataSource.FindStringSubmatch(statement); match != nil {
+		record.S3Path = match[1]
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	line, jsonErr := json.Marshal(record)
+	if jsonErr != nil {
+		return fmt.Errorf("failed to encode audit record: %w", jsonErr)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := a.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// AuditLogS3Key returns where -audit-log-upload puts the audit log: <s3-prefix>/audit/<file name>.
+func AuditLogS3Key(cfg *config.Config) string {
+	return fmt.Sprintf("%s/audit/%s", cfg.S3Prefix, filepath.Base(cfg.AuditLog))
+}
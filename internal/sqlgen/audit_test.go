@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"go.uber.org/zap/zaptest"
+)
+
+// readAuditLog returns the records of the audit log at path.
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("audit log line %q is not a JSON record: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestExecuteLoadDataSQL_AuditLog(t *testing.T) {
+	reconnectBackoff = 0
+	statements := []string{"statement 1", "statement 2", "statement 3", "statement 4"}
+	cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", SQLExecTimeout: 5, SQLReconnectRetries: 1,
+		AuditLog: filepath.Join(t.TempDir(), "audit.jsonl")}
+	server := &fakeAurora{
+		attempts: make(map[int]int),
+		failures: map[int]error{
+			2: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '7-00ab' for key 'tenantid'"},
+			3: errors.New("table doesn't exist"),
+			4: mysql.ErrInvalidConn, // Retried on a new connection
+		},
+	}
+
+	audit, err := OpenAuditLog(cfg.AuditLog, cfg)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	if err := executeLoadDataSQL(statements, cfg, audit, server.connect, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("executeLoadDataSQL() should report the failed statement")
+	}
+	audit.Close()
+
+	records := readAuditLog(t, cfg.AuditLog)
+	if len(records) != len(statements) {
+		t.Fatalf("got %d audit records, want one per statement: %+v", len(records), records)
+	}
+	wantOutcomes := []string{AuditSuccess, AuditDuplicate, AuditFailure, AuditSuccess}
+	wantAttempts := []int{1, 1, 1, 2}
+	for i, record := range records {
+		if record.Statement != i+1 || record.Total != len(statements) || record.SQL != statements[i] {
+			t.Errorf("record %d is statement %d/%d %q, want %d/%d %q",
+				i, record.Statement, record.Total, record.SQL, i+1, len(statements), statements[i])
+		}
+		if record.Outcome != wantOutcomes[i] || record.Attempts != wantAttempts[i] {
+			t.Errorf("statement %d outcome = %s after %d attempts, want %s after %d",
+				i+1, record.Outcome, record.Attempts, wantOutcomes[i], wantAttempts[i])
+		}
+		if (record.Error != "") != (record.Outcome != AuditSuccess) {
+			t.Errorf("statement %d outcome %s has error %q", i+1, record.Outcome, record.Error)
+		}
+		if record.TenantID != 7 || record.Table != "fis_aggr" {
+			t.Errorf("statement %d recorded for tenant %d %s, want tenant 7 fis_aggr", i+1, record.TenantID, record.Table)
+		}
+		if record.End.Before(record.Start) || record.ElapsedMS != record.End.Sub(record.Start).Milliseconds() {
+			t.Errorf("statement %d ran %s to %s (%d ms), want consistent times", i+1, record.Start, record.End, record.ElapsedMS)
+		}
+	}
+}
+
+func TestExecuteTransactional_AuditLog(t *testing.T) {
+	statements := []string{"statement 1", "statement 2", "statement 3"}
+	cfg := &config.Config{SQLExecTimeout: 5, SQLTransactional: true, AuditLog: filepath.Join(t.TempDir(), "audit.jsonl")}
+	server := &fakeAurora{attempts: make(map[int]int), failures: map[int]error{2: errors.New("table doesn't exist")}}
+	conn, _ := server.connect()
+
+	audit, err := OpenAuditLog(cfg.AuditLog, cfg)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	if err := executeTransactional(statements, cfg, conn, audit, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("executeTransactional() should fail when a statement fails")
+	}
+	audit.Close()
+
+	// The rollback is on record, so statement 1 is known not to have stayed loaded
+	var got []string
+	for _, record := range readAuditLog(t, cfg.AuditLog) {
+		got = append(got, record.SQL+" "+record.Outcome)
+	}
+	want := []string{"BEGIN success", "statement 1 success", "statement 2 failure", "ROLLBACK success"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("audit records = %v, want %v", got, want)
+	}
+}
+
+func TestAuditLog_Record(t *testing.T) {
+	cfg := &config.Config{TenantID: 7, TableName: "fis_aggr", S3Bucket: "bucket", S3Prefix: "prefix",
+		AuditLog: filepath.Join(t.TempDir(), "audit.jsonl")}
+	statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/tenant-7/fis_aggr/hash-00-80.csv"}}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+
+	// A second run appends to the records of the first
+	for run := 0; run < 2; run++ {
+		audit, err := OpenAuditLog(cfg.AuditLog, cfg)
+		if err != nil {
+			t.Fatalf("OpenAuditLog() error = %v", err)
+		}
+		if err := audit.Record(1, 1, statements[0], time.Now(), 1, AuditSuccess, nil); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		audit.Close()
+	}
+
+	records := readAuditLog(t, cfg.AuditLog)
+	if len(records) != 2 {
+		t.Fatalf("got %d audit records, want both runs' records", len(records))
+	}
+	if got, want := records[0].S3Path, "s3://bucket/prefix/tenant-7/fis_aggr/hash-00-80.csv"; got != want {
+		t.Errorf("S3Path = %q, want %q", got, want)
+	}
+	if got, want := AuditLogS3Key(cfg), "prefix/audit/audit.jsonl"; got != want {
+		t.Errorf("AuditLogS3Key() = %q, want %q", got, want)
+	}
+
+	// A nil audit log records nothing
+	var none *AuditLog
+	if err := none.Record(1, 1, statements[0], time.Now(), 1, AuditSuccess, nil); err != nil {
+		t.Errorf("nil Record() error = %v", err)
+	}
+}
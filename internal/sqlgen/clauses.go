@@ -0,0 +1,96 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// LoadClauses holds optional LOAD DATA clauses supplied via -load-extra-clauses.
+// Each clause is emitted at its position in the LOAD DATA grammar:
+//
+//	LOAD DATA FROM S3 '...' IGNORE INTO TABLE t
+//	  [CHARACTER SET x]
+//	  FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' [ESCAPED BY 'c']
+//	  LINES [STARTING BY 's'] TERMINATED BY '\n'
+//	  [IGNORE n LINES]
+//	  (columns)
+type LoadClauses struct {
+	CharacterSet string // e.g. "CHARACTER SET utf8mb4"
+	EscapedBy    string // e.g. "ESCAPED BY '\\'"
+	StartingBy   string // e.g. "STARTING BY 'xxx'"
+	IgnoreLines  string // e.g. "IGNORE 1 LINES"
+}
+
+// sqlStringLiteral matches a single-quoted SQL string literal with backslash or doubled-quote escapes.
+const sqlStringLiteral = `'(?:[^'\\]|\\.|'')*'`
+
+var loadClausePatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+	set     func(c *LoadClauses, clause string)
+	get     func(c *LoadClauses) string
+}{
+	{
+		name:    "CHARACTER SET",
+		pattern: regexp.MustCompile(`(?i)^CHARACTER\s+SET\s+[a-z0-9_]+`),
+		set:     func(c *LoadClauses, clause string) { c.CharacterSet = clause },
+		get:     func(c *LoadClauses) string { return c.CharacterSet },
+	},
+	{
+		name:    "ESCAPED BY",
+		pattern: regexp.MustCompile(`(?i)^ESCAPED\s+BY\s+` + sqlStringLiteral),
+		set:     func(c *LoadClauses, clause string) { c.EscapedBy = clause },
+		get:     func(c *LoadClauses) string { return c.EscapedBy },
+	},
+	{
+		name:    "STARTING BY",
+		pattern: regexp.MustCompile(`(?i)^STARTING\s+BY\s+` + sqlStringLiteral),
+		set:     func(c *LoadClauses, clause string) { c.StartingBy = clause },
+		get:     func(c *LoadClauses) string { return c.StartingBy },
+	},
+	{
+		name:    "IGNORE LINES",
+		pattern: regexp.MustCompile(`(?i)^IGNORE\s+[0-9]+\s+(LINES|ROWS)`),
+		set:     func(c *LoadClauses, clause string) { c.IgnoreLines = clause },
+		get:     func(c *LoadClauses) string { return c.IgnoreLines },
+	},
+}
+
+// ParseLoadExtraClauses validates and splits the -load-extra-clauses value into known clauses.
+// Only CHARACTER SET, ESCAPED BY, STARTING BY and IGNORE n LINES|ROWS are accepted, each at most once,
+// so the generated statement cannot be broken by misplaced or unexpected SQL.
+func ParseLoadExtraClauses(extra string) (LoadClauses, error) {
+	var clauses LoadClauses
+	rest := strings.TrimSpace(extra)
+
+	for rest != "" {
+		matched := false
+		for _, p := range loadClausePatterns {
+			clause := p.pattern.FindString(rest)
+			if clause == "" {
+				continue
+			}
+			// The clause must end at a token boundary
+			next := rest[len(clause):]
+			if next != "" && !unicode.IsSpace(rune(next[0])) {
+				continue
+			}
+			if p.get(&clauses) != "" {
+				return LoadClauses{}, fmt.Errorf("duplicate LOAD DATA clause %s in %q", p.name, extra)
+			}
+			p.set(&clauses, clause)
+			rest = strings.TrimSpace(next)
+			matched = true
+			break
+		}
+		if !matched {
+			return LoadClauses{}, fmt.Errorf("unsupported LOAD DATA clause at %q (supported: CHARACTER SET, ESCAPED BY, STARTING BY, IGNORE n LINES)", rest)
+		}
+	}
+
+	return clauses, nil
+}
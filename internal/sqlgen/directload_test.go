@@ -0,0 +1,206 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestInsertRowsSQL(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	version := 7
+	rows := []exporter.Row{
+		{TenantID: 1234, Hash: "00aa", Aggr: `{"a": 1}`},
+		{TenantID: 1234, Hash: "00bb", Aggr: `{"b": 2}`, LastModified: &modified, Version: &version},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantSQL string
+	}{
+		{"ignore", config.Config{TableName: "fis_aggr", SQLDuplicateMode: "ignore"},
+			"INSERT IGNORE INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)"},
+		{"replace", config.Config{TableName: "fis_aggr", SQLDuplicateMode: "replace"},
+			"INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)" +
+				" ON DUPLICATE KEY UPDATE aggr = VALUES(aggr), last_modified = VALUES(last_modified), version = VALUES(version)"},
+		{"error", config.Config{TableName: "fis_aggr", SQLDuplicateMode: "error"},
+			"INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)"},
+		{"tenant column", config.Config{TableName: "fis_aggr", SQLDuplicateMode: "ignore", TenantColumn: "tenant_id"},
+			"INSERT IGNORE INTO fis_aggr (tenant_id, hash, aggr, last_modified, version) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := insertRowsSQL(rows, &tt.cfg)
+			if query != tt.wantSQL {
+				t.Errorf("insertRowsSQL() =\n%s\nwant\n%s", query, tt.wantSQL)
+			}
+			want := []interface{}{1234, "00aa", `{"a": 1}`, nil, nil, 1234, "00bb", `{"b": 2}`, modified, 7}
+			if len(args) != len(want) {
+				t.Fatalf("insertRowsSQL() has %d args, want %d", len(args), len(want))
+			}
+			for i := range want {
+				if args[i] != want[i] {
+					t.Errorf("arg %d = %v, want %v", i, args[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// recordingConn records the statements executed on it and how many rows each one inserted.
+type recordingConn struct {
+	rows []int
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.rows = append(c.rows, strings.Count(query, "(?, ?, ?, ?, ?)"))
+	return nil, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func TestDirectLoader_LoadRows(t *testing.T) {
+	rows := make([]exporter.Row, 2*directLoadRowsPerInsert+3)
+	for i := range rows {
+		rows[i] = exporter.Row{TenantID: 1234, Hash: "00aa", Aggr: "{}"}
+	}
+
+	conn := &recordingConn{}
+	loader := newDirectLoader(conn, &config.Config{TableName: "fis_aggr", SQLDuplicateMode: "ignore", SQLExecTimeout: 10}, zaptest.NewLogger(t))
+	if err := loader.LoadRows(rows); err != nil {
+		t.Fatalf("LoadRows() error = %v", err)
+	}
+	want := []int{directLoadRowsPerInsert, directLoadRowsPerInsert, 3}
+	if len(conn.rows) != len(want) {
+		t.Fatalf("LoadRows() ran %d statements %v, want %v", len(conn.rows), conn.rows, want)
+	}
+	for i := range want {
+		if conn.rows[i] != want[i] {
+			t.Errorf("statement %d inserted %d rows, want %d", i+1, conn.rows[i], want[i])
+		}
+	}
+}
+
+func TestDirectLoad_RoundTrip(t *testing.T) {
+	db, cleanup, hostPort := setupLoadTestDB(t)
+	defer cleanup()
+
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE(tenantid, hash)
+		)`,
+		`CREATE TABLE fis_aggr_loaded LIKE fis_aggr`,
+		`INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES
+			(1234, '00aa', '{"a": 1}', NULL, NULL),
+			(1234, '00bb', '{"b": 2}', '2024-01-02 03:04:05', 0),
+			(1234, '00cc', '{"c": 3}', NULL, 7),
+			(1234, '01dd', '{"d": 4}', NULL, NULL)`,
+		// Already in the target with stale content
+		`INSERT INTO fis_aggr_loaded (tenantid, hash, aggr) VALUES (1234, '00cc', '{"stale": true}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up tables: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		mode     string
+		wantAggr string // aggr of 00cc after the load
+	}{
+		{"ignore", `{"stale": true}`},
+		{"replace", `{"c": 3}`},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &config.Config{
+				TenantID:         1234,
+				TableName:        "fis_aggr",
+				MariaDBHost:      hostPort,
+				MariaDBUser:      "root",
+				MariaDBPassword:  "testpassword",
+				MariaDBDatabase:  "fis",
+				BatchSize:        2,
+				SQLDuplicateMode: tt.mode,
+				SQLExecTimeout:   30,
+				DirectLoad:       true,
+			}
+			loadCfg := *cfg
+			loadCfg.TableName = "fis_aggr_loaded"
+
+			exp, err := exporter.NewExporter(cfg, zaptest.NewLogger(t))
+			if err != nil {
+				t.Fatalf("NewExporter() error = %v", err)
+			}
+			defer exp.Close()
+			exp.SetRowLoader(newDirectLoader(db, &loadCfg, zaptest.NewLogger(t)))
+
+			csvFile, err := exp.ExportSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, nil)
+			if err != nil {
+				t.Fatalf("ExportSegment() error = %v", err)
+			}
+			if csvFile == nil || csvFile.RowCount != 3 || csvFile.S3Key != "" || csvFile.FilePath != "" {
+				t.Fatalf("ExportSegment() = %+v, want 3 rows loaded and no file", csvFile)
+			}
+
+			rows, err := db.Query(`SELECT hash, aggr, last_modified IS NULL, COALESCE(version, -1)
+				FROM fis_aggr_loaded WHERE tenantid = 1234 ORDER BY hash`)
+			if err != nil {
+				t.Fatalf("Failed to query loaded rows: %v", err)
+			}
+			defer rows.Close()
+
+			type loaded struct {
+				hash, aggr   string
+				nullModified bool
+				version      int
+			}
+			want := []loaded{
+				{"00aa", `{"a": 1}`, true, -1},
+				{"00bb", `{"b": 2}`, false, 0},
+				{"00cc", tt.wantAggr, true, -1},
+			}
+			if tt.mode == "replace" {
+				want[2].version = 7
+			}
+			var got []loaded
+			for rows.Next() {
+				var r loaded
+				if err := rows.Scan(&r.hash, &r.aggr, &r.nullModified, &r.version); err != nil {
+					t.Fatalf("Failed to scan loaded row: %v", err)
+				}
+				got = append(got, r)
+			}
+			// 01dd is outside the segment
+			if len(got) != len(want) {
+				t.Fatalf("loaded %d rows, want %d: %+v", len(got), len(want), got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+
+			var modified time.Time
+			if err := db.QueryRow(`SELECT last_modified FROM fis_aggr_loaded WHERE hash = '00bb'`).Scan(&modified); err != nil {
+				t.Fatalf("Failed to read last_modified: %v", err)
+			}
+			if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !modified.Equal(want) {
+				t.Errorf("last_modified = %v, want %v", modified, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/s3"
+	"go.uber.org/zap"
+)
+
+// errNoS3Role is the Aurora error for LOAD DATA FROM S3 on a cluster without an IAM role for S3.
+const errNoS3Role = 63985
+
+// isS3RoleError reports whether LOAD DATA FROM S3 failed because the cluster has no IAM role
+// (aurora_load_from_s3_role or aws_default_s3_role) to read the bucket with.
+func isS3RoleError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoS3Role {
+		return true
+	}
+	errorMsg := err.Error()
+	return strings.Contains(errorMsg, "aurora_load_from_s3_role") || strings.Contains(errorMsg, "aws_default_s3_role")
+}
+
+// loadFromS3Source matches the source line of a GenerateLoadDataSQL statement.
+var loadFromS3Source = regexp.MustCompile(`^LOAD DATA FROM S3 '(s3://[^']*)'`)
+
+// localLoadStatement rewrites a LOAD DATA FROM S3 statement of GenerateLoadDataSQL for -load-via-local:
+// the same LOAD DATA LOCAL INFILE from the driver reader named reader. Returns the S3 key it loaded.
+func localLoadStatement(stmt, bucket, reader string) (string, string, error) {
+	m := loadFromS3Source.FindStringSubmatch(stmt)
+	if m == nil {
+		return "", "", fmt.Errorf("not a LOAD DATA FROM S3 statement")
+	}
+	key, ok := strings.CutPrefix(m[1], "s3://"+bucket+"/")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("%s is not an object in s3-bucket %s", m[1], bucket)
+	}
+	return "LOAD DATA LOCAL INFILE " + quoteSQLString("Reader::"+reader) + stmt[len(m[0]):], key, nil
+}
+
+// objectOpener streams S3 objects for -load-via-local.
+// This allows mocking in tests.
+type objectOpener interface {
+	OpenObject(ctx context.Context, s3Key string) (io.ReadCloser, error)
+}
+
+// newObjectOpener creates the S3 client of -load-via-local (replaced in tests).
+var newObjectOpener = func(cfg *config.Config, logger *zap.Logger) (objectOpener, error) {
+	return s3.NewUploader(cfg, logger)
+}
+
+// localLoader runs LOAD DATA FROM S3 statements as LOAD DATA LOCAL INFILE (-load-via-local), for clusters
+// without an IAM role for S3: each CSV is streamed from S3 through this machine, gunzipped if needed,
+// and sent to Aurora by the driver. Aurora's local_infile parameter must be on.
+// The stream is registered as a driver reader, so the server can't ask for any local file.
+// The S3 client is created on first use.
+type localLoader struct {
+	cfg    *config.Config
+	logger *zap.Logger
+	opener objectOpener
+}
+
+// load runs stmt through this machine on conn, bounded by ctx.
+func (l *localLoader) load(ctx context.Context, conn auroraConn, stmt string) error {
+	const reader = "fis-migration-csv"
+	localStmt, key, err := localLoadStatement(stmt, l.cfg.S3Bucket, reader)
+	if err != nil {
+		return fmt.Errorf("-load-via-local can't load statement: %w", err)
+	}
+	if l.opener == nil {
+		if l.opener, err = newObjectOpener(l.cfg, l.logger); err != nil {
+			return fmt.Errorf("failed to create S3 client for -load-via-local: %w", err)
+		}
+	}
+
+	body, err := l.opener.OpenObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var csv io.Reader = body
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", key, err)
+		}
+		defer gz.Close()
+		csv = gz
+	}
+
+	l.logger.Info("Loading CSV with LOAD DATA LOCAL INFILE", zap.String("s3_key", key))
+	mysql.RegisterReaderHandler(reader, func() io.Reader { return csv })
+	defer mysql.DeregisterReaderHandler(reader)
+	if _, err := conn.ExecContext(ctx, localStmt); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1148 || mysqlErr.Number == 3948) {
+			return fmt.Errorf("LOAD DATA LOCAL INFILE of %s is disabled (set local_infile to 1 in the Aurora parameter group): %w", key, err)
+		}
+		return fmt.Errorf("LOAD DATA LOCAL INFILE of %s failed: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// noRoleAurora fails every LOAD DATA FROM S3 as a cluster without an IAM role does, and runs the rest
+type noRoleAurora struct {
+	executed []string
+	localErr error // Returned for LOAD DATA LOCAL INFILE
+}
+
+func (a *noRoleAurora) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if strings.HasPrefix(query, "LOAD DATA FROM S3") {
+		return nil, &mysql.MySQLError{Number: 63985, Message: "S3 API returned error: Both aurora_load_from_s3_role and aws_default_s3_role are not specified, please see documentation for more details"}
+	}
+	a.executed = append(a.executed, query)
+	return nil, a.localErr
+}
+
+func (a *noRoleAurora) Close() error { return nil }
+
+// fakeObjects serves S3 objects from memory, recording the keys opened and whether they were closed
+type fakeObjects struct {
+	objects map[string]string
+	opened  []string
+	open    int
+}
+
+type fakeObjectBody struct {
+	io.Reader
+	objects *fakeObjects
+}
+
+func (b *fakeObjectBody) Close() error {
+	b.objects.open--
+	return nil
+}
+
+func (f *fakeObjects) OpenObject(ctx context.Context, s3Key string) (io.ReadCloser, error) {
+	data, ok := f.objects[s3Key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	f.opened = append(f.opened, s3Key)
+	f.open++
+	return &fakeObjectBody{Reader: strings.NewReader(data), objects: f}, nil
+}
+
+func TestExecuteLoadDataSQL_LoadViaLocal(t *testing.T) {
+	cfg := &config.Config{S3Bucket: "bucket", TableName: "fis_aggr", SQLExecTimeout: 5, CSVDelimiter: ",", CSVQuote: `"`, NullMarker: `\N`, CSVHeader: true, SQLDuplicateMode: "ignore"}
+	csvFiles := []exporter.CSVFile{{S3Key: "prefix/tenant-1/segment-0.csv"}, {S3Key: "prefix/tenant-1/segment-1.csv.gz"}}
+	statements, err := GenerateLoadDataSQL(csvFiles, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte("tenantid,hash,aggr,last_modified,version\n"))
+	gz.Close()
+	objects := &fakeObjects{objects: map[string]string{
+		"prefix/tenant-1/segment-0.csv":    "tenantid,hash,aggr,last_modified,version\n",
+		"prefix/tenant-1/segment-1.csv.gz": gzipped.String(),
+	}}
+	origOpener := newObjectOpener
+	defer func() { newObjectOpener = origOpener }()
+	newObjectOpener = func(cfg *config.Config, logger *zap.Logger) (objectOpener, error) { return objects, nil }
+
+	// Without -load-via-local the role error fails the statements
+	aurora := &noRoleAurora{}
+	connect := func() (auroraConn, error) { return aurora, nil }
+	if err := executeLoadDataSQL(statements, cfg, nil, connect, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("executeLoadDataSQL() should fail without an IAM role")
+	}
+	if len(aurora.executed) != 0 || len(objects.opened) != 0 {
+		t.Fatalf("without -load-via-local ran %v and opened %v, want nothing", aurora.executed, objects.opened)
+	}
+
+	// With it each statement is run again as LOAD DATA LOCAL INFILE from its downloaded CSV
+	cfg.LoadViaLocal = true
+	if err := executeLoadDataSQL(statements, cfg, nil, connect, zaptest.NewLogger(t)); err != nil {
+		t.Fatalf("executeLoadDataSQL() with -load-via-local error = %v", err)
+	}
+	if len(aurora.executed) != 2 {
+		t.Fatalf("executed %d local loads, want 2: %v", len(aurora.executed), aurora.executed)
+	}
+	for i, stmt := range aurora.executed {
+		if !strings.HasPrefix(stmt, "LOAD DATA LOCAL INFILE 'Reader::") {
+			t.Errorf("statement %d = %q, want LOAD DATA LOCAL INFILE from a driver reader", i+1, stmt)
+		}
+		if want := statements[i][strings.Index(statements[i], "\n"):]; !strings.HasSuffix(stmt, want) {
+			t.Errorf("statement %d = %q, want the clauses of the S3 statement %q", i+1, stmt, want)
+		}
+	}
+	if strings.Join(objects.opened, " ") != "prefix/tenant-1/segment-0.csv prefix/tenant-1/segment-1.csv.gz" || objects.open != 0 {
+		t.Errorf("opened %v (%d still open), want both CSVs opened and closed", objects.opened, objects.open)
+	}
+
+	// A failed local load fails the statement
+	aurora = &noRoleAurora{localErr: &mysql.MySQLError{Number: 3948, Message: "Loading local data is disabled"}}
+	if err := executeLoadDataSQL(statements, cfg, nil, connect, zaptest.NewLogger(t)); err == nil {
+		t.Error("executeLoadDataSQL() should fail when local_infile is disabled")
+	}
+}
+
+func TestLocalLoadStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		stmt    string
+		want    string
+		wantKey string
+		wantErr bool
+	}{
+		{"load from s3", "LOAD DATA FROM S3 's3://bucket/a/b.csv'\nIGNORE\nINTO TABLE t\n(hash);",
+			"LOAD DATA LOCAL INFILE 'Reader::csv'\nIGNORE\nINTO TABLE t\n(hash);", "a/b.csv", false},
+		{"other bucket", "LOAD DATA FROM S3 's3://other/a.csv'\nINTO TABLE t;", "", "", true},
+		{"prefix load", "LOAD DATA FROM S3 PREFIX 's3://bucket/a'\nINTO TABLE t;", "", "", true},
+		{"not a load", "SELECT 1", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key, err := localLoadStatement(tt.stmt, "bucket", "csv")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("localLoadStatement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || key != tt.wantKey {
+				t.Errorf("localLoadStatement() = %q, %q, want %q, %q", got, key, tt.want, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mariadb"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap/zaptest"
+)
+
+// setupLoadTestDB starts a MariaDB container to load generated SQL into.
+// Returns: database connection, cleanup function, host:port
+func setupLoadTestDB(t *testing.T) (*sql.DB, func(), string) {
+	if os.Getenv("SKIP_DOCKER_TESTS") == "true" {
+		t.Skip("Skipping Docker-based tests (SKIP_DOCKER_TESTS=true)")
+	}
+
+	// testcontainers panics when no Docker host is found
+	defer func() {
+		if r := recover(); r != nil {
+			if errStr, ok := r.(string); ok {
+				if strings.Contains(errStr, "Docker not found") || strings.Contains(errStr, "rootless Docker") {
+					t.Skipf("Skipping test: Docker not available: %v", r)
+				}
+			}
+			panic(r)
+		}
+	}()
+
+	ctx := context.Background()
+	container, err := mariadb.RunContainer(ctx,
+		testcontainers.WithImage("mariadb:10.11"),
+		mariadb.WithDatabase("fis"),
+		mariadb.WithUsername("root"),
+		mariadb.WithPassword("testpassword"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("ready for connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "Docker not found") || strings.Contains(err.Error(), "rootless Docker") {
+			t.Skipf("Skipping test: Docker not available: %v", err)
+		}
+		t.Fatalf("Failed to start MariaDB container: %v", err)
+	}
+
+	connStr, err := container.ConnectionString(ctx, "parseTime=true")
+	if err != nil {
+		container.Terminate(ctx)
+		t.Fatalf("Failed to get connection string: %v", err)
+	}
+	db, err := sql.Open("mysql", connStr)
+	if err != nil {
+		container.Terminate(ctx)
+		t.Fatalf("Failed to open database connection: %v", err)
+	}
+	for i := 0; ; i++ {
+		if err := db.Ping(); err == nil {
+			break
+		} else if i == 10 {
+			db.Close()
+			container.Terminate(ctx)
+			t.Fatalf("Failed to ping database: %v", err)
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	hostPort := strings.Split(strings.Split(connStr, "@tcp(")[1], ")/")[0]
+	cleanup := func() {
+		db.Close()
+		container.Terminate(ctx)
+	}
+	return db, cleanup, hostPort
+}
+
+// loadLocalFile loads an exported CSV file into table with the generated statement, reading the local file instead of S3.
+func loadLocalFile(t *testing.T, db *sql.DB, csvFile exporter.CSVFile, cfg *config.Config, table string) {
+	t.Helper()
+	loadCfg := *cfg
+	loadCfg.TableName = table
+	statements, err := GenerateLoadDataSQL([]exporter.CSVFile{csvFile}, &loadCfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+	mysql.RegisterLocalFile(csvFile.FilePath)
+	defer mysql.DeregisterLocalFile(csvFile.FilePath)
+	load := "LOAD DATA LOCAL INFILE '" + csvFile.FilePath + "'\n" + strings.SplitN(statements[0], "\n", 2)[1]
+	if _, err := db.Exec(load); err != nil {
+		t.Fatalf("LOAD DATA error = %v\n%s", err, load)
+	}
+}
+
+func TestNullMarker_RoundTrip(t *testing.T) {
+	db, cleanup, hostPort := setupLoadTestDB(t)
+	defer cleanup()
+
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE(tenantid, hash)
+		)`,
+		`CREATE TABLE fis_aggr_loaded LIKE fis_aggr`,
+		`INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES
+			(1234, '00aa', '{"a": 1}', NULL, NULL),
+			(1234, '00bb', '{"b": 2}', '2024-01-02 03:04:05', 0),
+			(1234, '00cc', '{"c": 3}', NULL, 7)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up tables: %v", err)
+		}
+	}
+
+	// Export locally, with the default \N NULL marker
+	cfg := &config.Config{
+		TenantID:        1234,
+		TableName:       "fis_aggr",
+		MariaDBHost:     hostPort,
+		MariaDBUser:     "root",
+		MariaDBPassword: "testpassword",
+		MariaDBDatabase: "fis",
+		BatchSize:       1000,
+		OutputDir:       t.TempDir(),
+		NullMarker:      `\N`,
+		CSVHeader:       true,
+	}
+	exp, err := exporter.NewExporter(cfg, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	defer exp.Close()
+	csvFile, err := exp.ExportSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, nil)
+	if err != nil {
+		t.Fatalf("ExportSegment() error = %v", err)
+	}
+
+	loadLocalFile(t, db, *csvFile, cfg, "fis_aggr_loaded")
+
+	rows, err := db.Query(`SELECT hash, last_modified IS NULL, version IS NULL, COALESCE(version, -1)
+		FROM fis_aggr_loaded WHERE tenantid = 1234 ORDER BY hash`)
+	if err != nil {
+		t.Fatalf("Failed to query loaded rows: %v", err)
+	}
+	defer rows.Close()
+
+	type loaded struct {
+		hash                      string
+		nullModified, nullVersion bool
+		version                   int
+	}
+	want := []loaded{
+		{"00aa", true, true, -1},
+		{"00bb", false, false, 0},
+		{"00cc", true, false, 7},
+	}
+	var got []loaded
+	for rows.Next() {
+		var r loaded
+		if err := rows.Scan(&r.hash, &r.nullModified, &r.nullVersion, &r.version); err != nil {
+			t.Fatalf("Failed to scan loaded row: %v", err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCSVFormat_RoundTrip(t *testing.T) {
+	db, cleanup, hostPort := setupLoadTestDB(t)
+	defer cleanup()
+
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE(tenantid, hash)
+		)`,
+		`INSERT INTO fis_aggr (tenantid, hash, aggr) VALUES
+			(1234, '00aa', '{"a": 1}'),
+			(1234, '00bb', '{"b": 2}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up tables: %v", err)
+		}
+	}
+
+	for i, format := range []struct {
+		header    bool
+		delimiter string
+	}{{true, ","}, {false, ","}, {true, "|"}} {
+		cfg := &config.Config{
+			TenantID:        1234,
+			TableName:       "fis_aggr",
+			MariaDBHost:     hostPort,
+			MariaDBUser:     "root",
+			MariaDBPassword: "testpassword",
+			MariaDBDatabase: "fis",
+			BatchSize:       1000,
+			OutputDir:       t.TempDir(),
+			NullMarker:      `\N`,
+			CSVHeader:       format.header,
+			CSVDelimiter:    format.delimiter,
+		}
+		exp, err := exporter.NewExporter(cfg, zaptest.NewLogger(t))
+		if err != nil {
+			t.Fatalf("NewExporter() error = %v", err)
+		}
+		csvFile, err := exp.ExportSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, nil)
+		exp.Close()
+		if err != nil {
+			t.Fatalf("ExportSegment() error = %v", err)
+		}
+
+		// A loose target table, so a loaded header line would land as a row instead of failing
+		table := fmt.Sprintf("fis_aggr_loaded_%d", i)
+		if _, err := db.Exec("CREATE TABLE " + table + " (tenantid VARCHAR(255), hash VARCHAR(255), aggr LONGTEXT, last_modified VARCHAR(255), version VARCHAR(255))"); err != nil {
+			t.Fatalf("Failed to create %s: %v", table, err)
+		}
+		loadLocalFile(t, db, *csvFile, cfg, table)
+
+		var hashes []string
+		rows, err := db.Query("SELECT hash FROM " + table + " ORDER BY hash")
+		if err != nil {
+			t.Fatalf("Failed to query loaded rows: %v", err)
+		}
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				t.Fatalf("Failed to scan loaded row: %v", err)
+			}
+			hashes = append(hashes, hash)
+		}
+		rows.Close()
+		if got := strings.Join(hashes, ","); got != "00aa,00bb" {
+			t.Errorf("%+v: loaded hashes %s, want 00aa,00bb (no header row)", format, got)
+		}
+	}
+}
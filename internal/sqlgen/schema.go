@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"go.uber.org/zap"
+)
+
+// targetColumns returns the columns LOAD DATA writes, in the order the target table must have them.
+// Names are lowercase, as read from information_schema.
+func targetColumns(tenantColumn string) []string {
+	return exporter.CSVHeader(strings.ToLower(tenantColumn))
+}
+
+// CheckAuroraSchema connects to Aurora and checks the target table before anything is loaded (-check-schema).
+func CheckAuroraSchema(cfg *config.Config, logger *zap.Logger) error {
+	auroraClient, err := ConnectAurora(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer auroraClient.Close()
+
+	if err := CheckTargetSchema(auroraClient.GetDB(), cfg.AuroraDatabase, cfg.TableName, cfg.TenantColumnName(), cfg.ExcludedColumns()); err != nil {
+		return err
+	}
+	logger.Info("Target table schema is compatible",
+		zap.String("database", cfg.AuroraDatabase),
+		zap.String("table", cfg.TableName))
+	return nil
+}
+
+// CheckTargetSchema checks that database.table exists and has tenantColumn, hash, aggr, last_modified and
+// version in that order. Other columns may come between or after them.
+// Columns in excluded (-exclude-columns) aren't loaded: they may be missing, and must be nullable or have
+// a default if present.
+// Returns an error naming the first missing or misordered column.
+func CheckTargetSchema(db *sql.DB, database, table, tenantColumn string, excluded []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT LOWER(column_name), is_nullable = 'YES' OR column_default IS NOT NULL
+		FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ?
+		ORDER BY ordinal_position`, database, table)
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s.%s: %w", database, table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	fillable := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		var hasDefault bool
+		if err := rows.Scan(&column, &hasDefault); err != nil {
+			return fmt.Errorf("failed to read columns of %s.%s: %w", database, table, err)
+		}
+		columns = append(columns, column)
+		fillable[column] = hasDefault
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read columns of %s.%s: %w", database, table, err)
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("target table %s.%s does not exist", database, table)
+	}
+	if err := compareColumns(columns, exporter.ExportColumns(strings.ToLower(tenantColumn), excluded)); err != nil {
+		return fmt.Errorf("target table %s.%s is incompatible: %w (columns: %s)", database, table, err, strings.Join(columns, ", "))
+	}
+	if err := checkExcludedColumns(fillable, excluded); err != nil {
+		return fmt.Errorf("target table %s.%s is incompatible: %w", database, table, err)
+	}
+	return nil
+}
+
+// checkExcludedColumns checks that LOAD DATA can leave out the excluded columns: fillable maps the target's
+// columns to whether they are nullable or have a default. Excluded columns the target lacks are fine.
+func checkExcludedColumns(fillable map[string]bool, excluded []string) error {
+	for _, column := range excluded {
+		if hasDefault, ok := fillable[column]; ok && !hasDefault {
+			return fmt.Errorf("excluded column %s is NOT NULL without a default", column)
+		}
+	}
+	return nil
+}
+
+// compareColumns checks that every column of want is present in columns, in the order of want.
+func compareColumns(columns, want []string) error {
+	position := make(map[string]int, len(columns))
+	for i, column := range columns {
+		position[column] = i + 1
+	}
+	for _, column := range want {
+		if position[column] == 0 {
+			return fmt.Errorf("missing column %s", column)
+		}
+	}
+	for i := 1; i < len(want); i++ {
+		prev, column := want[i-1], want[i]
+		if position[column] < position[prev] {
+			return fmt.Errorf("column %s is at position %d, before %s at position %d",
+				column, position[column], prev, position[prev])
+		}
+	}
+	return nil
+}
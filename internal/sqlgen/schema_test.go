@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompareColumns(t *testing.T) {
+	tests := []struct {
+		name         string
+		tenantColumn string
+		columns      []string
+		wantErr      string
+	}{
+		{"exact", "tenantid", []string{"tenantid", "hash", "aggr", "last_modified", "version"}, ""},
+		{"extra columns", "tenantid", []string{"id", "tenantid", "hash", "aggr", "created", "last_modified", "version", "note"}, ""},
+		{"missing column", "tenantid", []string{"tenantid", "hash", "last_modified", "version"}, "missing column aggr"},
+		{"misordered", "tenantid", []string{"tenantid", "hash", "aggr", "version", "last_modified"}, "column version is at position 4, before last_modified at position 5"},
+		{"tenant column", "Tenant_ID", []string{"tenant_id", "hash", "aggr", "last_modified", "version"}, ""},
+		{"default tenant column missing", "tenant_id", []string{"tenantid", "hash", "aggr", "last_modified", "version"}, "missing column tenant_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareColumns(tt.columns, targetColumns(tt.tenantColumn))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("compareColumns() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compareColumns() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTargetSchema(t *testing.T) {
+	db, cleanup, _ := setupLoadTestDB(t)
+	defer cleanup()
+
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			PRIMARY KEY (tenantid, hash)
+		)`,
+		`CREATE TABLE fis_aggr_no_version (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL
+		)`,
+		`CREATE TABLE fis_aggr_metadata (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE fis_aggr_reordered (
+			tenantid INT NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL
+		)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+
+	tests := []struct {
+		table    string
+		excluded []string
+		wantErr  string
+	}{
+		{"fis_aggr", nil, ""},
+		{"fis_aggr_no_version", nil, "missing column version"},
+		{"fis_aggr_no_version", []string{"version"}, ""},
+		{"fis_aggr_reordered", nil, "column aggr is at position 2, before hash at position 3"},
+		{"fis_aggr_missing", nil, "does not exist"},
+		{"fis_aggr", []string{"aggr"}, "excluded column aggr is NOT NULL without a default"},
+		{"fis_aggr_metadata", []string{"aggr", "version"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.table, tt.excluded), func(t *testing.T) {
+			err := CheckTargetSchema(db, "fis", tt.table, "tenantid", tt.excluded)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckTargetSchema() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckTargetSchema() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckExcludedColumns(t *testing.T) {
+	// A target without version
+	fillable := map[string]bool{"tenantid": false, "hash": false, "aggr": false, "last_modified": true}
+	tests := []struct {
+		name     string
+		excluded []string
+		wantErr  string
+	}{
+		{"none", nil, ""},
+		{"nullable", []string{"last_modified"}, ""},
+		{"not null without default", []string{"version", "aggr"}, "excluded column aggr is NOT NULL without a default"},
+		{"missing in the target", []string{"version"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExcludedColumns(fillable, tt.excluded)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkExcludedColumns() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkExcludedColumns() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"go.uber.org/zap"
+)
+
+// TargetMaxLastModified connects to Aurora and returns the newest last_modified of the tenant's rows
+// in the target table (-since-checkpoint), as Unix seconds, or "" if the target has none.
+func TargetMaxLastModified(cfg *config.Config, logger *zap.Logger) (string, error) {
+	auroraClient, err := ConnectAurora(cfg, logger)
+	if err != nil {
+		return "", err
+	}
+	defer auroraClient.Close()
+	return targetMaxLastModified(auroraClient.GetDB(), cfg.TableName, cfg.TenantColumnName(), cfg.TenantID)
+}
+
+// targetMaxLastModified returns MAX(last_modified) of the tenant's rows in table as Unix seconds
+// (with the column's fractional digits), or "" if the tenant has no rows with a last_modified.
+// For a TIMESTAMP column Unix seconds compare the same on the source whatever the time_zone of either session.
+// A DATETIME column has no time zone: UNIX_TIMESTAMP here and FROM_UNIXTIME on the source (see sinceFilter)
+// read it in each session's time_zone, so both must match or rows are skipped or exported again.
+func targetMaxLastModified(db *sql.DB, table, tenantColumn string, tenantID int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var since sql.NullString
+	query := fmt.Sprintf("SELECT UNIX_TIMESTAMP(MAX(last_modified)) FROM %s WHERE %s = ?", table, tenantColumn)
+	if err := db.QueryRowContext(ctx, query, tenantID).Scan(&since); err != nil {
+		return "", fmt.Errorf("failed to read max last_modified of tenant %d in %s: %w", tenantID, table, err)
+	}
+	return since.String, nil
+}
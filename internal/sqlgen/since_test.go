@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package sqlgen
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSinceCheckpoint_ExportsNewerRows(t *testing.T) {
+	db, cleanup, hostPort := setupLoadTestDB(t)
+	defer cleanup()
+
+	for _, stmt := range []string{
+		`CREATE TABLE fis_aggr (
+			tenantid INT NOT NULL,
+			hash VARCHAR(255) NOT NULL,
+			aggr LONGTEXT NOT NULL,
+			last_modified TIMESTAMP NULL,
+			version INT NULL,
+			UNIQUE(tenantid, hash)
+		)`,
+		`CREATE TABLE fis_aggr_target LIKE fis_aggr`,
+		`INSERT INTO fis_aggr (tenantid, hash, aggr, last_modified, version) VALUES
+			(1234, '00aa', '{"a": 1}', '2024-01-01 00:00:00', 1),
+			(1234, '00bb', '{"b": 2}', '2024-01-02 03:04:05', 1),
+			(1234, '00cc', '{"c": 3}', '2024-01-03 00:00:00', 2),
+			(1234, '00dd', '{"d": 4}', '2024-01-04 00:00:00', 1),
+			(1234, '00ee', '{"e": 5}', NULL, 1)`,
+		// The target holds the rows of an earlier run, the newest modified at 2024-01-02 03:04:05
+		`INSERT INTO fis_aggr_target (tenantid, hash, aggr, last_modified, version) VALUES
+			(1234, '00aa', '{"a": 1}', '2024-01-01 00:00:00', 1),
+			(1234, '00bb', '{"b": 2}', '2024-01-02 03:04:05', 1),
+			(1234, '00cc', '{"c": 3}', '2024-01-01 12:00:00', 1),
+			(5678, '00ff', '{"f": 6}', '2025-01-01 00:00:00', 1)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up tables: %v", err)
+		}
+	}
+
+	since, err := targetMaxLastModified(db, "fis_aggr_target", "tenantid", 1234)
+	if err != nil {
+		t.Fatalf("targetMaxLastModified() error = %v", err)
+	}
+	if since == "" {
+		t.Fatal("targetMaxLastModified() = \"\", want the newest last_modified of tenant 1234")
+	}
+
+	// Only the rows modified at or after it are exported: the newest row again, and the updated and new ones
+	cfg := &config.Config{
+		TenantID:          1234,
+		TableName:         "fis_aggr",
+		MariaDBHost:       hostPort,
+		MariaDBUser:       "root",
+		MariaDBPassword:   "testpassword",
+		MariaDBDatabase:   "fis",
+		BatchSize:         1000,
+		OutputDir:         t.TempDir(),
+		NullMarker:        `\N`,
+		CSVHeader:         true,
+		SinceLastModified: since,
+	}
+	exp, err := exporter.NewExporter(cfg, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	defer exp.Close()
+	csvFile, err := exp.ExportSegment(segment.Segment{Index: 0, StartHex: "00", EndHex: "01"}, nil)
+	if err != nil {
+		t.Fatalf("ExportSegment() error = %v", err)
+	}
+	data, err := os.ReadFile(csvFile.FilePath)
+	if err != nil {
+		t.Fatalf("Failed to read exported CSV: %v", err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+		got = append(got, strings.Split(line, ",")[1])
+	}
+	if want := "00bb 00cc 00dd"; strings.Join(got, " ") != want || csvFile.RowCount != 3 {
+		t.Errorf("exported %v (%d rows), want %s", got, csvFile.RowCount, want)
+	}
+
+	// A target without rows of the tenant exports every row
+	if since, err := targetMaxLastModified(db, "fis_aggr_target", "tenantid", 9999); err != nil || since != "" {
+		t.Errorf("targetMaxLastModified() of an empty target = %q, %v, want \"\"", since, err)
+	}
+}
@@ -5,13 +5,21 @@ package sqlgen
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"github.com/netSkope/fis-migration-tool/internal/exporter"
 	"github.com/netSkope/fis-migration-tool/internal/s3"
 	"github.com/netSkope/fis-migration-tool/internal/store"
@@ -23,25 +31,84 @@ import (
 func GenerateLoadDataSQL(csvFiles []exporter.CSVFile, cfg *config.Config) ([]string, error) {
 	var sqlStatements []string
 
+	clauses, err := ParseLoadExtraClauses(cfg.LoadExtraClauses)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfig, fmt.Errorf("invalid load-extra-clauses: %w", err))
+	}
+
 	for _, csvFile := range csvFiles {
 		s3Path := fmt.Sprintf("s3://%s/%s", cfg.S3Bucket, csvFile.S3Key)
-		// Use IGNORE to skip duplicate entries (based on unique key: tenantid, hash)
+
+		var sql strings.Builder
+		// By default IGNORE skips duplicate entries (based on unique key: tenant column, hash)
 		// This allows re-running migration without failing on existing data
-		sql := fmt.Sprintf(`LOAD DATA FROM S3 '%s'
-IGNORE
-INTO TABLE %s
-FIELDS TERMINATED BY ','
-OPTIONALLY ENCLOSED BY '"'
-LINES TERMINATED BY '\n'
-(tenantid, hash, aggr, last_modified, version);`,
-			s3Path, cfg.TableName)
+		fmt.Fprintf(&sql, "LOAD DATA FROM S3 '%s'\n%sINTO TABLE %s\n", s3Path, duplicateClause(cfg.SQLDuplicateMode), cfg.TableName)
+		if clauses.CharacterSet != "" {
+			sql.WriteString(clauses.CharacterSet + "\n")
+		}
+		// Same delimiter and quote as the exporter's CSV writer
+		fmt.Fprintf(&sql, "FIELDS TERMINATED BY %s\nOPTIONALLY ENCLOSED BY %s\n",
+			quoteSQLString(string(cfg.CSVComma())), quoteSQLString(cfg.CSVEnclosure()))
+		if clauses.EscapedBy != "" {
+			sql.WriteString(clauses.EscapedBy + "\n")
+		}
+		sql.WriteString("LINES ")
+		if clauses.StartingBy != "" {
+			sql.WriteString(clauses.StartingBy + " ")
+		}
+		sql.WriteString("TERMINATED BY '\\n'\n")
+		// Skip the CSV header line, unless -load-extra-clauses already sets how many lines to skip
+		if clauses.IgnoreLines != "" {
+			sql.WriteString(clauses.IgnoreLines + "\n")
+		} else if cfg.CSVHeader {
+			sql.WriteString("IGNORE 1 LINES\n")
+		}
+		sql.WriteString(loadColumnsClause(cfg))
 
-		sqlStatements = append(sqlStatements, sql)
+		sqlStatements = append(sqlStatements, sql.String())
 	}
 
 	return sqlStatements, nil
 }
 
+// loadColumnsClause returns the LOAD DATA column list of the CSV columns, without the -exclude-columns
+// columns (the target fills them with their defaults), and the SET clause of its nullable columns.
+func loadColumnsClause(cfg *config.Config) string {
+	var columns, sets []string
+	for _, column := range exporter.ExportColumns(cfg.TenantColumnName(), cfg.ExcludedColumns()) {
+		switch column {
+		case "last_modified", "version":
+			// Nullable columns go through variables so the -null-marker loads as NULL, not '' or 0
+			columns = append(columns, "@"+column)
+			sets = append(sets, fmt.Sprintf("%[1]s = NULLIF(@%[1]s, %[2]s)", column, quoteSQLString(cfg.NullMarker)))
+		default:
+			columns = append(columns, column)
+		}
+	}
+	clause := "(" + strings.Join(columns, ", ") + ")"
+	if len(sets) > 0 {
+		clause += "\nSET " + strings.Join(sets, ", ")
+	}
+	return clause + ";"
+}
+
+// quoteSQLString returns s as a single-quoted SQL string literal.
+func quoteSQLString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// duplicateClause returns the LOAD DATA duplicate handling keyword line for -sql-duplicate-mode.
+func duplicateClause(mode string) string {
+	switch mode {
+	case "replace":
+		return "REPLACE\n"
+	case "error":
+		return ""
+	default:
+		return "IGNORE\n"
+	}
+}
+
 // WriteSQLFile writes SQL statements to a file.
 func WriteSQLFile(sqlStatements []string, filepath string) error {
 	file, err := os.Create(filepath)
@@ -50,87 +117,243 @@ func WriteSQLFile(sqlStatements []string, filepath string) error {
 	}
 	defer file.Close()
 
+	return WriteSQL(file, sqlStatements)
+}
+
+// WriteSQL writes SQL statements to w, separated by blank lines (the SQL file format, fit for mysql's stdin).
+func WriteSQL(w io.Writer, sqlStatements []string) error {
 	for _, sql := range sqlStatements {
-		if _, err := file.WriteString(sql + "\n\n"); err != nil {
+		if _, err := io.WriteString(w, sql+"\n\n"); err != nil {
 			return fmt.Errorf("failed to write SQL: %w", err)
 		}
 	}
-
 	return nil
 }
 
+// auroraConn executes statements on an Aurora MySQL connection pool.
+// This allows mocking in tests.
+type auroraConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Close() error
+}
+
+// auroraConnector opens a new Aurora connection.
+type auroraConnector func() (auroraConn, error)
+
+// pinnedConn runs every statement on one session, so BEGIN and COMMIT cover all of them.
+type pinnedConn struct {
+	*sql.Conn
+	client *store.SQLClient
+}
+
+func (p *pinnedConn) Close() error {
+	p.Conn.Close()
+	return p.client.Close()
+}
+
+// reconnectBackoff is the wait before the first reconnect; it grows linearly per attempt (replaced in tests).
+var reconnectBackoff = 1 * time.Second
+
 // ExecuteLoadDataSQL executes SQL statements on Aurora MySQL.
+// With -audit-log every statement and its outcome is appended to the audit log.
 func ExecuteLoadDataSQL(sqlStatements []string, cfg *config.Config, logger *zap.Logger) error {
 	if len(sqlStatements) == 0 {
 		return fmt.Errorf("no SQL statements to execute")
 	}
 
-	// Load AWS credentials with priority: CLI flags > Env vars > AWS SDK default chain > Vault files
-	util.LoadAWSCredentials(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+	var audit *AuditLog
+	if cfg.AuditLog != "" {
+		var err error
+		if audit, err = OpenAuditLog(cfg.AuditLog, cfg); err != nil {
+			return err
+		}
+		defer audit.Close()
+	}
 
-	// Resolve Aurora password from Secrets Manager
-	awsPwd, err := util.ResolveAWSDBPassword(cfg.AuroraSecretsManagerSecret, cfg.AuroraRegion)
-	if err != nil {
-		return fmt.Errorf("failed to get AWS password from Secrets Manager: %w", err)
+	if cfg.SQLTransactional {
+		auroraClient, err := ConnectAurora(cfg, logger)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.SQLExecTimeout)*time.Second)
+		defer cancel()
+		session, err := auroraClient.GetDB().Conn(ctx)
+		if err != nil {
+			auroraClient.Close()
+			return errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to open Aurora MySQL session: %w", err))
+		}
+		conn := &pinnedConn{Conn: session, client: auroraClient}
+		defer conn.Close()
+		return executeTransactional(sqlStatements, cfg, conn, audit, logger)
 	}
 
-	// Create Aurora MySQL client
-	hostname := cfg.AuroraHost
-	if cfg.AuroraPort > 0 && cfg.AuroraPort != 3306 {
-		hostname = fmt.Sprintf("%s:%d", cfg.AuroraHost, cfg.AuroraPort)
+	return executeLoadDataSQL(sqlStatements, cfg, audit, func() (auroraConn, error) {
+		auroraClient, err := ConnectAurora(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return auroraClient.GetDB(), nil
+	}, logger)
+}
+
+// statementTimeout returns the timeout for one statement: -sql-statement-timeout, or -sql-exec-timeout if unset.
+func statementTimeout(cfg *config.Config) time.Duration {
+	if cfg.SQLStatementTimeout > 0 {
+		return time.Duration(cfg.SQLStatementTimeout) * time.Second
 	}
+	return time.Duration(cfg.SQLExecTimeout) * time.Second
+}
 
-	auroraClient, err := store.NewSQLClient(hostname, cfg.AuroraUser, awsPwd, cfg.SQLExecTimeout, "aws-aurora", cfg.AuroraDatabase)
-	if err != nil {
-		return fmt.Errorf("failed to create Aurora MySQL client: %w", err)
+// totalContext returns the context bounding the whole statement run (-sql-total-timeout, unbounded if unset).
+func totalContext(cfg *config.Config) (context.Context, context.CancelFunc) {
+	if cfg.SQLTotalTimeout > 0 {
+		return context.WithTimeout(context.Background(), time.Duration(cfg.SQLTotalTimeout)*time.Second)
 	}
-	defer auroraClient.Close()
+	return context.WithCancel(context.Background())
+}
 
-	// Validate connection with retry
-	var lastErr error
-	delay := 1 * time.Second
-	for attempt := 1; attempt <= 3; attempt++ {
-		if err := auroraClient.Ping(); err == nil {
-			break
+// executeTransactional runs all statements in one transaction on conn (-sql-transactional).
+// Commits only if every statement succeeds; the first failure rolls back all of them.
+// BEGIN, COMMIT and ROLLBACK are audited too, so the audit log shows which loads were undone.
+func executeTransactional(sqlStatements []string, cfg *config.Config, conn auroraConn, audit *AuditLog, logger *zap.Logger) error {
+	total, cancelTotal := totalContext(cfg)
+	defer cancelTotal()
+
+	// execIn runs stmt (the index-th statement, 0 for transaction control) and audits its outcome
+	execIn := func(parent context.Context, index int, stmt string) error {
+		ctx, cancel := context.WithTimeout(parent, statementTimeout(cfg))
+		defer cancel()
+		start := time.Now()
+		_, err := conn.ExecContext(ctx, stmt)
+		outcome := AuditSuccess
+		if err != nil {
+			outcome = AuditFailure
 		}
-		lastErr = err
-		if attempt < 3 {
-			logger.Warn("Aurora MySQL ping failed, retrying",
-				zap.Int("attempt", attempt),
+		if auditErr := audit.Record(index, len(sqlStatements), stmt, start, 1, outcome, err); auditErr != nil && err == nil {
+			return auditErr
+		}
+		return err
+	}
+	exec := func(index int, stmt string) error { return execIn(total, index, stmt) }
+
+	if err := exec(0, "BEGIN"); err != nil {
+		return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+	logger.Info("Started transaction for LOAD DATA FROM S3", zap.Int("total", len(sqlStatements)))
+
+	for i, stmt := range sqlStatements {
+		logger.Info("Executing LOAD DATA FROM S3",
+			zap.Int("statement", i+1),
+			zap.Int("total", len(sqlStatements)))
+
+		startTime := time.Now()
+		if err := exec(i+1, stmt); err != nil {
+			logger.Error("LOAD DATA FROM S3 execution failed, rolling back transaction",
+				zap.Int("statement", i+1),
+				zap.Duration("elapsed", time.Since(startTime)),
 				zap.Error(err))
-			time.Sleep(delay)
-			delay = delay * 2 // Exponential backoff
+			// Roll back even if -sql-total-timeout has expired
+			if rbErr := execIn(context.Background(), 0, "ROLLBACK"); rbErr != nil {
+				return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("statement %d/%d failed: %w (rollback also failed: %v)", i+1, len(sqlStatements), err, rbErr))
+			}
+			return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("statement %d/%d failed, all statements rolled back: %w", i+1, len(sqlStatements), err))
 		}
+		logger.Info("LOAD DATA FROM S3 completed",
+			zap.Int("statement", i+1),
+			zap.Duration("elapsed", time.Since(startTime)))
 	}
 
-	if lastErr != nil {
-		return fmt.Errorf("failed to connect to Aurora MySQL after retries: %w", lastErr)
+	if err := exec(0, "COMMIT"); err != nil {
+		return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("failed to commit transaction: %w", err))
 	}
+	logger.Info("Transaction committed", zap.Int("statements", len(sqlStatements)))
+	return nil
+}
 
-	logger.Info("Connected to Aurora MySQL successfully")
+// executeLoadDataSQL runs the statements sequentially on connections from connect.
+// A statement that fails with a connection-level error is retried on a fresh connection up to
+// -sql-reconnect-retries times; with IGNORE or REPLACE, retrying a statement that committed just before the drop is harmless.
+// With -load-via-local a statement that fails for lack of an Aurora IAM role is run again through this machine (see localLoader).
+func executeLoadDataSQL(sqlStatements []string, cfg *config.Config, audit *AuditLog, connect auroraConnector, logger *zap.Logger) error {
+	total, cancelTotal := totalContext(cfg)
+	defer cancelTotal()
+	local := &localLoader{cfg: cfg, logger: logger}
+
+	conn, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
 
 	// Execute SQL statements sequentially
 	successCount := 0
 	failureCount := 0
 
 	for i, sql := range sqlStatements {
+		// -sql-total-timeout stops the run; statements not started are counted as failed
+		if total.Err() != nil {
+			return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("sql-total-timeout of %ds exceeded before statement %d/%d (%d succeeded, %d failed, %d not run)",
+				cfg.SQLTotalTimeout, i+1, len(sqlStatements), successCount, failureCount, len(sqlStatements)-i))
+		}
+
 		logger.Info("Executing LOAD DATA FROM S3",
 			zap.Int("statement", i+1),
 			zap.Int("total", len(sqlStatements)))
 
 		startTime := time.Now()
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.SQLExecTimeout)*time.Second)
-		defer cancel()
+		var err error
+		attempts := 0
+		for attempt := 1; ; attempt++ {
+			attempts = attempt
+			ctx, cancel := context.WithTimeout(total, statementTimeout(cfg))
+			_, err = conn.ExecContext(ctx, sql)
+			cancel()
+			if err == nil || !isConnectionError(err) || attempt > cfg.SQLReconnectRetries {
+				break
+			}
 
-		_, err := auroraClient.GetDB().ExecContext(ctx, sql)
+			logger.Warn("Aurora MySQL connection lost, reconnecting to retry statement",
+				zap.Int("statement", i+1),
+				zap.Int("attempt", attempt),
+				zap.Int("max_retries", cfg.SQLReconnectRetries),
+				zap.Error(err))
+			conn.Close()
+			conn = nil
+			time.Sleep(reconnectBackoff * time.Duration(attempt))
+			if conn, err = connect(); err != nil {
+				if auditErr := audit.Record(i+1, len(sqlStatements), sql, startTime, attempt, AuditFailure, err); auditErr != nil {
+					logger.Error("Failed to write audit log", zap.Error(auditErr))
+				}
+				return errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to reconnect to Aurora MySQL at statement %d/%d (%d succeeded, %d failed): %w",
+					i+1, len(sqlStatements), successCount, failureCount, err))
+			}
+		}
+		if err != nil && cfg.LoadViaLocal && isS3RoleError(err) {
+			logger.Warn("Aurora MySQL IAM role not configured, loading through this machine (-load-via-local)",
+				zap.Int("statement", i+1),
+				zap.Error(err))
+			ctx, cancel := context.WithTimeout(total, statementTimeout(cfg))
+			err = local.load(ctx, conn, sql)
+			cancel()
+		}
 		elapsed := time.Since(startTime)
 
+		// The outcome is on record before the next statement runs, a failed write stops the run
+		if auditErr := audit.Record(i+1, len(sqlStatements), sql, startTime, attempts, auditOutcome(err, cfg), err); auditErr != nil {
+			return auditErr
+		}
+
 		if err != nil {
 			errorMsg := err.Error()
 
 			// Check for duplicate entry errors - these are expected if data already exists
 			// With IGNORE keyword, duplicates should be skipped, but check anyway for safety
-			if strings.Contains(errorMsg, "Duplicate entry") || strings.Contains(errorMsg, "Error 1062") {
+			// (-sql-duplicate-mode error asks for duplicates to fail instead)
+			if isDuplicateError(err) && cfg.SQLDuplicateMode != "error" {
 				logger.Warn("LOAD DATA FROM S3 skipped duplicate entries (data may already exist)",
 					zap.Int("statement", i+1),
 					zap.Duration("elapsed", elapsed),
@@ -142,7 +365,7 @@ func ExecuteLoadDataSQL(sqlStatements []string, cfg *config.Config, logger *zap.
 			}
 
 			// Check for Aurora MySQL IAM role configuration error
-			if strings.Contains(errorMsg, "aurora_load_from_s3_role") || strings.Contains(errorMsg, "aws_default_s3_role") {
+			if isS3RoleError(err) {
 				failureCount++
 				logger.Error("LOAD DATA FROM S3 execution failed - Aurora MySQL IAM role not configured",
 					zap.Int("statement", i+1),
@@ -172,12 +395,176 @@ func ExecuteLoadDataSQL(sqlStatements []string, cfg *config.Config, logger *zap.
 		zap.Int("failure", failureCount))
 
 	if failureCount > 0 {
-		return fmt.Errorf("some SQL statements failed: %d/%d succeeded", successCount, len(sqlStatements))
+		return errs.Wrap(errs.ErrSQLExec, fmt.Errorf("some SQL statements failed: %d/%d succeeded", successCount, len(sqlStatements)))
+	}
+
+	return nil
+}
+
+// isDuplicateError reports whether a statement failed on duplicate entries.
+func isDuplicateError(err error) bool {
+	errorMsg := err.Error()
+	return strings.Contains(errorMsg, "Duplicate entry") || strings.Contains(errorMsg, "Error 1062")
+}
+
+// auditOutcome returns the audit log outcome of a statement that ended with err (nil on success).
+// Duplicates count as loaded unless -sql-duplicate-mode error makes them fail.
+func auditOutcome(err error, cfg *config.Config) string {
+	switch {
+	case err == nil:
+		return AuditSuccess
+	case isDuplicateError(err) && cfg.SQLDuplicateMode != "error":
+		return AuditDuplicate
+	default:
+		return AuditFailure
+	}
+}
+
+// isConnectionError reports whether err means the Aurora connection was lost, as opposed to
+// the statement itself failing (e.g. duplicate entry), so the statement is worth retrying on a new connection.
+func isConnectionError(err error) bool {
+	// -sql-exec-timeout expiring is not a dropped connection (context errors also satisfy net.Error)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1053, // ER_SERVER_SHUTDOWN (e.g. Aurora failover)
+			1927, // ER_CONNECTION_KILLED
+			2006, // CR_SERVER_GONE_ERROR
+			2013: // CR_SERVER_LOST
+			return true
+		}
+	}
+	return false
+}
+
+// rdsAuthToken builds an RDS IAM auth token for -aurora-auth-mode iam (replaced in tests).
+var rdsAuthToken = util.GenerateRDSAuthToken
+
+// openAuroraClient opens the Aurora MySQL client (replaced in tests).
+var openAuroraClient = store.NewSQLClientWithParams
+
+// resolveAuroraPassword fetches the Aurora password from Secrets Manager (replaced in tests).
+var resolveAuroraPassword = util.ResolveAWSDBPassword
+
+// auroraCredentials returns the Aurora password and extra DSN parameters for -aurora-auth-mode,
+// followed by -aurora-params. If the Secrets Manager lookup fails and -aurora-password is set,
+// that password is used instead with a warning.
+func auroraCredentials(cfg *config.Config, logger *zap.Logger) (string, string, error) {
+	if cfg.AuroraAuthMode == "iam" {
+		// Tokens expire after 15 minutes, so every (re)connect builds a fresh one
+		token, err := rdsAuthToken(fmt.Sprintf("%s:%d", cfg.AuroraHost, cfg.AuroraPort), cfg.AuroraRegion, cfg.AuroraUser)
+		if err != nil {
+			return "", "", errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to build RDS IAM auth token: %w", err))
+		}
+		// RDS requires TLS for IAM auth, and the token is sent with the cleartext auth plugin
+		params := "tls=true&allowCleartextPasswords=true"
+		if cfg.AuroraParams != "" {
+			params += "&" + cfg.AuroraParams
+		}
+		return token, params, nil
+	}
+
+	awsPwd, err := resolveAuroraPassword(cfg.AuroraSecretsManagerSecret, cfg.AuroraRegion, cfg.SecretsMaxAttempts)
+	if err != nil {
+		if cfg.AuroraPassword == "" {
+			return "", "", errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to get AWS password from Secrets Manager: %w", err))
+		}
+		// Restricted environments may not reach Secrets Manager; the secret stays the preferred source
+		logger.Warn("Failed to get Aurora password from Secrets Manager, falling back to -aurora-password",
+			zap.String("secret", cfg.AuroraSecretsManagerSecret),
+			zap.Error(err))
+		return cfg.AuroraPassword, cfg.AuroraParams, nil
+	}
+	return awsPwd, cfg.AuroraParams, nil
+}
+
+// auroraHostname returns the Aurora host, with the port appended unless it is the MySQL default.
+func auroraHostname(cfg *config.Config) string {
+	if cfg.AuroraPort > 0 && cfg.AuroraPort != 3306 {
+		return fmt.Sprintf("%s:%d", cfg.AuroraHost, cfg.AuroraPort)
+	}
+	return cfg.AuroraHost
+}
+
+// ConnectAurora resolves the Aurora password (Secrets Manager or IAM auth token) and connects to Aurora MySQL, retrying the ping.
+func ConnectAurora(cfg *config.Config, logger *zap.Logger) (*store.SQLClient, error) {
+	// Load AWS credentials with priority: CLI flags > Env vars > AWS SDK default chain > Vault files
+	util.LoadAWSCredentials(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+
+	awsPwd, params, err := auroraCredentials(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Aurora MySQL client
+	auroraClient, err := openAuroraClient(auroraHostname(cfg), cfg.AuroraUser, awsPwd, cfg.SQLExecTimeout, "aws-aurora", cfg.AuroraDatabase, params)
+	if err != nil {
+		return nil, errs.WrapConnect(errs.ErrTargetConnect, fmt.Errorf("failed to create Aurora MySQL client: %w", err))
+	}
+
+	// Validate connection with retry, unless the failure isn't retryable (e.g. access denied)
+	delay := 1 * time.Second
+	for attempt := 1; ; attempt++ {
+		err := errs.WrapConnect(errs.ErrTargetConnect, auroraClient.Ping())
+		if err == nil {
+			break
+		}
+		if attempt >= 3 || !errs.IsRetryable(err) {
+			auroraClient.Close()
+			return nil, fmt.Errorf("failed to connect to Aurora MySQL after %d attempts: %w", attempt, err)
+		}
+		logger.Warn("Aurora MySQL ping failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		time.Sleep(delay)
+		delay = delay * 2 // Exponential backoff
 	}
 
+	logger.Info("Connected to Aurora MySQL successfully")
+	return auroraClient, nil
+}
+
+// PingAurora resolves the Aurora credentials and pings Aurora MySQL once, without retries (used by the preflight).
+func PingAurora(cfg *config.Config) error {
+	util.LoadAWSCredentials(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+
+	// The preflight's secretsmanager check reports the lookup failure
+	awsPwd, params, err := auroraCredentials(cfg, zap.NewNop())
+	if err != nil {
+		return err
+	}
+	auroraClient, err := openAuroraClient(auroraHostname(cfg), cfg.AuroraUser, awsPwd, cfg.SQLExecTimeout, "aws-aurora", cfg.AuroraDatabase, params)
+	if err != nil {
+		return errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to create Aurora MySQL client: %w", err))
+	}
+	defer auroraClient.Close()
+
+	if err := auroraClient.Ping(); err != nil {
+		return errs.Wrap(errs.ErrTargetConnect, fmt.Errorf("failed to ping Aurora MySQL: %w", err))
+	}
 	return nil
 }
 
+// sqlFilename returns the SQL file name for the tenant. With several -tables the table is part of the name
+// so each table's SQL file is kept.
+func sqlFilename(cfg *config.Config) string {
+	if len(cfg.Tables) > 1 {
+		return fmt.Sprintf("load-data-tenant-%d.%s.sql", cfg.TenantID, cfg.TableName)
+	}
+	return fmt.Sprintf("load-data-tenant-%d.sql", cfg.TenantID)
+}
+
 // GenerateSQLFile generates and writes SQL file for LOAD DATA FROM S3 (local file).
 // Deprecated: Use GenerateAndUploadSQL for production.
 func GenerateSQLFile(csvFiles []exporter.CSVFile, cfg *config.Config) (string, error) {
@@ -186,7 +573,7 @@ func GenerateSQLFile(csvFiles []exporter.CSVFile, cfg *config.Config) (string, e
 		return "", fmt.Errorf("failed to generate SQL: %w", err)
 	}
 
-	filename := fmt.Sprintf("load-data-tenant-%d.sql", cfg.TenantID)
+	filename := sqlFilename(cfg)
 	// Use /tmp for consistency across platforms
 	filepath := filepath.Join("/tmp", filename)
 
@@ -213,7 +600,7 @@ func GenerateAndUploadSQL(csvFiles []exporter.CSVFile, cfg *config.Config, uploa
 	}
 
 	// Generate S3 key for SQL file
-	filename := fmt.Sprintf("load-data-tenant-%d.sql", cfg.TenantID)
+	filename := sqlFilename(cfg)
 	s3Key := fmt.Sprintf("%s/sql/%s", cfg.S3Prefix, filename)
 
 	logger.Info("Uploading SQL file to S3",
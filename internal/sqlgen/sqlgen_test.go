@@ -3,13 +3,23 @@
 package sqlgen
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/errs"
 	"github.com/netSkope/fis-migration-tool/internal/exporter"
 	"github.com/netSkope/fis-migration-tool/internal/segment"
+	"github.com/netSkope/fis-migration-tool/internal/store"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestGenerateLoadDataSQL(t *testing.T) {
@@ -96,3 +106,567 @@ func TestWriteSQLFile(t *testing.T) {
 	}
 }
 
+
+func TestWriteSQL(t *testing.T) {
+	cfg := &config.Config{S3Bucket: "bucket", TableName: "fis_aggr", NullMarker: `\N`, CSVHeader: true}
+	sqlStatements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}, {S3Key: "prefix/file2.csv"}}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+
+	var out strings.Builder
+	if err := WriteSQL(&out, sqlStatements); err != nil {
+		t.Fatalf("WriteSQL() error = %v", err)
+	}
+
+	// Piped into mysql: nothing but complete statements, separated by blank lines
+	chunks := strings.Split(strings.TrimSpace(out.String()), "\n\n")
+	if len(chunks) != len(sqlStatements) {
+		t.Fatalf("WriteSQL() wrote %d statements, want %d:\n%s", len(chunks), len(sqlStatements), out.String())
+	}
+	for i, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "LOAD DATA FROM S3 's3://bucket/prefix/file") || !strings.HasSuffix(chunk, ";") {
+			t.Errorf("statement %d = %q, want a complete LOAD DATA FROM S3 statement", i, chunk)
+		}
+	}
+}
+
+func TestGenerateLoadDataSQL_ExtraClauses(t *testing.T) {
+	cfg := &config.Config{
+		S3Bucket:         "test-bucket",
+		TableName:        "fis_aggr",
+		LoadExtraClauses: `ESCAPED BY '\\' CHARACTER SET utf8mb4 STARTING BY 'x'`,
+		NullMarker:       `\N`,
+	}
+	csvFiles := []exporter.CSVFile{{S3Key: "prefix/file1.csv"}}
+
+	sqlStatements, err := GenerateLoadDataSQL(csvFiles, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+
+	want := `LOAD DATA FROM S3 's3://test-bucket/prefix/file1.csv'
+IGNORE
+INTO TABLE fis_aggr
+CHARACTER SET utf8mb4
+FIELDS TERMINATED BY ','
+OPTIONALLY ENCLOSED BY '"'
+ESCAPED BY '\\'
+LINES STARTING BY 'x' TERMINATED BY '\n'
+(tenantid, hash, aggr, @last_modified, @version)
+SET last_modified = NULLIF(@last_modified, '\\N'), version = NULLIF(@version, '\\N');`
+	if sqlStatements[0] != want {
+		t.Errorf("unexpected SQL:\n%s\nwant:\n%s", sqlStatements[0], want)
+	}
+}
+
+func TestParseLoadExtraClauses(t *testing.T) {
+	tests := []struct {
+		name    string
+		extra   string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"escaped by", `ESCAPED BY '\\'`, false},
+		{"lowercase ignore lines", "ignore 1 lines", false},
+		{"quoted quote", `STARTING BY ''''`, false},
+		{"duplicate clause", `ESCAPED BY '\\' ESCAPED BY '|'`, true},
+		{"statement injection", `ESCAPED BY '\\'; DROP TABLE fis_aggr`, true},
+		{"unsupported clause", "SET version = 1", true},
+		{"unterminated literal", `ESCAPED BY '\\`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLoadExtraClauses(tt.extra)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLoadExtraClauses(%q) error = %v, wantErr %v", tt.extra, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeAurora hands out connections that fail statements as scripted by statement number
+type fakeAurora struct {
+	connects int
+	executed []string
+	failures map[int]error // statement number (1-based) -> error on its first attempt
+	attempts map[int]int
+	slow     map[int]bool // statement number -> blocks until its context is done
+	inTx     bool
+	pending  []string // Executed inside the open transaction, not yet committed
+}
+
+type fakeAuroraConn struct {
+	server  *fakeAurora
+	dropped bool
+}
+
+func (f *fakeAurora) connect() (auroraConn, error) {
+	f.connects++
+	return &fakeAuroraConn{server: f}, nil
+}
+
+func (c *fakeAuroraConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if c.dropped {
+		return nil, mysql.ErrInvalidConn
+	}
+	switch query {
+	case "BEGIN":
+		c.server.inTx = true
+		return nil, nil
+	case "COMMIT":
+		c.server.executed = append(c.server.executed, c.server.pending...)
+		c.server.inTx, c.server.pending = false, nil
+		return nil, nil
+	case "ROLLBACK":
+		c.server.inTx, c.server.pending = false, nil
+		return nil, nil
+	}
+	var n int
+	fmt.Sscanf(query, "statement %d", &n)
+	c.server.attempts[n]++
+	if c.server.slow[n] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err, ok := c.server.failures[n]; ok && c.server.attempts[n] == 1 {
+		if errors.Is(err, mysql.ErrInvalidConn) {
+			c.dropped = true
+		}
+		return nil, err
+	}
+	if c.server.inTx {
+		c.server.pending = append(c.server.pending, query)
+	} else {
+		c.server.executed = append(c.server.executed, query)
+	}
+	return nil, nil
+}
+
+func (c *fakeAuroraConn) Close() error { return nil }
+
+func TestExecuteLoadDataSQL_Reconnect(t *testing.T) {
+	reconnectBackoff = 0
+	statements := []string{"statement 1", "statement 2", "statement 3", "statement 4", "statement 5"}
+
+	tests := []struct {
+		name         string
+		failures     map[int]error
+		wantConnects int
+		wantExecuted int
+		wantErr      bool
+		mode         string
+	}{
+		{"no failures", nil, 1, 5, false, ""},
+		{"connection dropped on third statement", map[int]error{3: mysql.ErrInvalidConn}, 2, 5, false, ""},
+		{"duplicate entry is not retried", map[int]error{2: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}}, 1, 4, false, ""},
+		{"duplicate entry fails with duplicate mode error", map[int]error{2: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}}, 1, 4, true, "error"},
+		{"syntax error is not retried", map[int]error{4: &mysql.MySQLError{Number: 1064, Message: "syntax error"}}, 1, 4, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeAurora{failures: tt.failures, attempts: make(map[int]int)}
+			cfg := &config.Config{SQLExecTimeout: 5, SQLReconnectRetries: 3, SQLDuplicateMode: tt.mode}
+
+			err := executeLoadDataSQL(statements, cfg, nil, server.connect, zaptest.NewLogger(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("executeLoadDataSQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if server.connects != tt.wantConnects {
+				t.Errorf("connects = %d, want %d", server.connects, tt.wantConnects)
+			}
+			if len(server.executed) != tt.wantExecuted {
+				t.Errorf("executed %d statements, want %d: %v", len(server.executed), tt.wantExecuted, server.executed)
+			}
+		})
+	}
+}
+
+func TestExecuteLoadDataSQL_ReconnectRetriesExhausted(t *testing.T) {
+	reconnectBackoff = 0
+	server := &fakeAurora{attempts: make(map[int]int)}
+	connects := 0
+	// Every connection is already dead
+	connect := func() (auroraConn, error) {
+		connects++
+		return &fakeAuroraConn{server: server, dropped: true}, nil
+	}
+	cfg := &config.Config{SQLExecTimeout: 5, SQLReconnectRetries: 2}
+
+	if err := executeLoadDataSQL([]string{"statement 1"}, cfg, nil, connect, zaptest.NewLogger(t)); !errors.Is(err, errs.ErrSQLExec) {
+		t.Fatalf("executeLoadDataSQL() error = %v, want ErrSQLExec when the connection never recovers", err)
+	}
+	if connects != 3 {
+		t.Errorf("connects = %d, want initial connection plus 2 reconnects", connects)
+	}
+
+	failing := func() (auroraConn, error) { return nil, errors.New("connection refused") }
+	if err := executeLoadDataSQL([]string{"statement 1"}, cfg, nil, failing, zaptest.NewLogger(t)); err == nil {
+		t.Error("executeLoadDataSQL() should fail when Aurora is unreachable")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"invalid connection", mysql.ErrInvalidConn, true},
+		{"wrapped bad conn", fmt.Errorf("exec: %w", mysql.ErrInvalidConn), true},
+		{"server gone away", &mysql.MySQLError{Number: 2006}, true},
+		{"connection killed", &mysql.MySQLError{Number: 1927}, true},
+		{"duplicate entry", &mysql.MySQLError{Number: 1062}, false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"other", errors.New("table doesn't exist"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTransactional(t *testing.T) {
+	statements := []string{"statement 1", "statement 2", "statement 3"}
+	cfg := &config.Config{SQLExecTimeout: 5, SQLTransactional: true}
+
+	// All statements succeed: committed together
+	server := &fakeAurora{attempts: make(map[int]int)}
+	conn, _ := server.connect()
+	if err := executeTransactional(statements, cfg, conn, nil, zaptest.NewLogger(t)); err != nil {
+		t.Fatalf("executeTransactional() error = %v", err)
+	}
+	if len(server.executed) != 3 {
+		t.Errorf("expected 3 committed statements, got %v", server.executed)
+	}
+
+	// Statement two fails: the rollback discards statement one and statement three never runs
+	server = &fakeAurora{
+		failures: map[int]error{2: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}},
+		attempts: make(map[int]int),
+	}
+	conn, _ = server.connect()
+	if err := executeTransactional(statements, cfg, conn, nil, zaptest.NewLogger(t)); !errors.Is(err, errs.ErrSQLExec) {
+		t.Fatalf("executeTransactional() error = %v, want ErrSQLExec when a statement fails", err)
+	}
+	if len(server.executed) != 0 || server.inTx {
+		t.Errorf("expected an empty table after rollback, got %v (transaction open: %v)", server.executed, server.inTx)
+	}
+	if server.attempts[3] != 0 {
+		t.Error("statement 3 should not run after statement 2 failed")
+	}
+}
+
+func TestGenerateLoadDataSQL_DuplicateMode(t *testing.T) {
+	csvFiles := []exporter.CSVFile{{S3Key: "test/file.csv"}}
+	tests := []struct {
+		mode    string
+		want    string
+		notWant []string
+	}{
+		{"", "LOAD DATA FROM S3 's3://test-bucket/test/file.csv'\nIGNORE\nINTO TABLE fis_aggr", nil},
+		{"ignore", "\nIGNORE\nINTO TABLE", []string{"REPLACE"}},
+		{"replace", "\nREPLACE\nINTO TABLE", []string{"IGNORE"}},
+		{"error", "LOAD DATA FROM S3 's3://test-bucket/test/file.csv'\nINTO TABLE fis_aggr", []string{"IGNORE", "REPLACE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", SQLDuplicateMode: tt.mode}
+			statements, err := GenerateLoadDataSQL(csvFiles, cfg)
+			if err != nil {
+				t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+			}
+			if !strings.Contains(statements[0], tt.want) {
+				t.Errorf("expected %q in:\n%s", tt.want, statements[0])
+			}
+			for _, keyword := range tt.notWant {
+				if strings.Contains(statements[0], keyword) {
+					t.Errorf("unexpected %s in:\n%s", keyword, statements[0])
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateLoadDataSQL_NullMarker(t *testing.T) {
+	cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", NullMarker: "N'A"}
+	statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+	want := "(tenantid, hash, aggr, @last_modified, @version)\nSET last_modified = NULLIF(@last_modified, 'N\\'A'), version = NULLIF(@version, 'N\\'A');"
+	if !strings.HasSuffix(statements[0], want) {
+		t.Errorf("unexpected SQL:\n%s\nwant suffix:\n%s", statements[0], want)
+	}
+}
+
+func TestGenerateLoadDataSQL_ExcludeColumns(t *testing.T) {
+	tests := []struct {
+		excluded string
+		want     string
+	}{
+		{"aggr", "(tenantid, hash, @last_modified, @version)\nSET last_modified = NULLIF(@last_modified, '\\\\N'), version = NULLIF(@version, '\\\\N');"},
+		{"version", "(tenantid, hash, aggr, @last_modified)\nSET last_modified = NULLIF(@last_modified, '\\\\N');"},
+		{"aggr,last_modified,version", "(tenantid, hash);"},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", NullMarker: "\\N", ExcludeColumns: tt.excluded}
+		statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}}, cfg)
+		if err != nil {
+			t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+		}
+		if !strings.HasSuffix(statements[0], "\n"+tt.want) {
+			t.Errorf("-exclude-columns %s: unexpected SQL:\n%s\nwant suffix:\n%s", tt.excluded, statements[0], tt.want)
+		}
+	}
+}
+
+func TestGenerateLoadDataSQL_TenantColumn(t *testing.T) {
+	cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", TenantColumn: "tenant_id", NullMarker: "\\N"}
+	statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+	if !strings.Contains(statements[0], "(tenant_id, hash, aggr, @last_modified, @version)") {
+		t.Errorf("expected the tenant_id column list, got:\n%s", statements[0])
+	}
+}
+
+func TestGenerateLoadDataSQL_CSVHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    bool
+		extra     string
+		want      string
+		wantCount int
+	}{
+		{"header", true, "", "TERMINATED BY '\\n'\nIGNORE 1 LINES\n(", 1},
+		{"no header", false, "", "TERMINATED BY '\\n'\n(", 0},
+		{"extra clause wins", true, "IGNORE 2 LINES", "TERMINATED BY '\\n'\nIGNORE 2 LINES\n(", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", CSVHeader: tt.header, LoadExtraClauses: tt.extra}
+			statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}}, cfg)
+			if err != nil {
+				t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+			}
+			if !strings.Contains(statements[0], tt.want) {
+				t.Errorf("expected %q in:\n%s", tt.want, statements[0])
+			}
+			if got := strings.Count(statements[0], "IGNORE 1 LINES"); got != tt.wantCount {
+				t.Errorf("IGNORE 1 LINES appears %d times, want %d:\n%s", got, tt.wantCount, statements[0])
+			}
+		})
+	}
+}
+
+func TestGenerateLoadDataSQL_Delimiter(t *testing.T) {
+	cfg := &config.Config{S3Bucket: "test-bucket", TableName: "fis_aggr", CSVDelimiter: "|", CSVQuote: "\""}
+	statements, err := GenerateLoadDataSQL([]exporter.CSVFile{{S3Key: "prefix/file1.csv"}}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLoadDataSQL() error = %v", err)
+	}
+	if want := "FIELDS TERMINATED BY '|'\nOPTIONALLY ENCLOSED BY '\"'\n"; !strings.Contains(statements[0], want) {
+		t.Errorf("expected %q in:\n%s", want, statements[0])
+	}
+}
+
+func TestSQLFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want string
+	}{
+		{"single table", &config.Config{TenantID: 1, TableName: "fis_aggr"}, "load-data-tenant-1.sql"},
+		{"one -tables entry", &config.Config{TenantID: 1, TableName: "fis_aggr", Tables: []string{"fis_aggr"}}, "load-data-tenant-1.sql"},
+		{"several tables", &config.Config{TenantID: 1, TableName: "fis_aggr_v2", Tables: []string{"fis_aggr", "fis_aggr_v2"}}, "load-data-tenant-1.fis_aggr_v2.sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlFilename(tt.cfg); got != tt.want {
+				t.Errorf("sqlFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectAurora_IAMAuth(t *testing.T) {
+	origToken, origOpen := rdsAuthToken, openAuroraClient
+	defer func() { rdsAuthToken, openAuroraClient = origToken, origOpen }()
+
+	var endpoints []string
+	rdsAuthToken = func(endpoint, region, dbUser string) (string, error) {
+		endpoints = append(endpoints, endpoint)
+		return fmt.Sprintf("%s/?Action=connect&DBUser=%s&X-Amz-Signature=sig%d", endpoint, dbUser, len(endpoints)), nil
+	}
+	var dsns []string
+	openAuroraClient = func(hostname, user, pwd string, timeout int, dbType, dbName, params string) (*store.SQLClient, error) {
+		dsn, err := store.BuildDSN(hostname, user, pwd, dbType, dbName, params)
+		if err != nil {
+			return nil, err
+		}
+		dsns = append(dsns, dsn)
+		return nil, errors.New("not connecting in tests")
+	}
+
+	cfg := &config.Config{AuroraHost: "aurora.example.com", AuroraPort: 3306, AuroraUser: "loader",
+		AuroraRegion: "us-east-1", AuroraDatabase: "fis", AuroraAuthMode: "iam"}
+	logger := zaptest.NewLogger(t)
+
+	// Each (re)connect builds a new token
+	for i := 0; i < 2; i++ {
+		if _, err := ConnectAurora(cfg, logger); !errors.Is(err, errs.ErrTargetConnect) {
+			t.Fatalf("ConnectAurora() error = %v, want the open error as ErrTargetConnect", err)
+		}
+	}
+
+	if fmt.Sprint(endpoints) != "[aurora.example.com:3306 aurora.example.com:3306]" {
+		t.Errorf("token endpoints = %v, want host:port twice", endpoints)
+	}
+	for i, dsn := range dsns {
+		want := fmt.Sprintf("loader:aurora.example.com:3306/?Action=connect&DBUser=loader&X-Amz-Signature=sig%d@tcp(aurora.example.com)/fis?", i+1)
+		if !strings.HasPrefix(dsn, want) {
+			t.Errorf("DSN %d = %q, want prefix %q", i+1, dsn, want)
+		}
+		if !strings.Contains(dsn, "tls=true") || !strings.Contains(dsn, "allowCleartextPasswords=true") {
+			t.Errorf("DSN %d = %q, want TLS and cleartext auth for the IAM token", i+1, dsn)
+		}
+	}
+	if len(dsns) != 2 {
+		t.Errorf("expected 2 connection attempts, got %d", len(dsns))
+	}
+
+	rdsAuthToken = func(endpoint, region, dbUser string) (string, error) {
+		return "", errors.New("no credentials")
+	}
+	if _, err := ConnectAurora(cfg, logger); err == nil || !strings.Contains(err.Error(), "IAM auth token") {
+		t.Errorf("ConnectAurora() error = %v, want the token error", err)
+	}
+}
+
+func TestConnectAurora_Params(t *testing.T) {
+	origToken, origOpen := rdsAuthToken, openAuroraClient
+	defer func() { rdsAuthToken, openAuroraClient = origToken, origOpen }()
+
+	rdsAuthToken = func(endpoint, region, dbUser string) (string, error) {
+		return "token", nil
+	}
+	var dsn string
+	openAuroraClient = func(hostname, user, pwd string, timeout int, dbType, dbName, params string) (*store.SQLClient, error) {
+		var err error
+		if dsn, err = store.BuildDSN(hostname, user, pwd, dbType, dbName, params); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("not connecting in tests")
+	}
+
+	cfg := &config.Config{AuroraHost: "aurora.example.com", AuroraUser: "loader", AuroraRegion: "us-east-1",
+		AuroraDatabase: "fis", AuroraAuthMode: "iam", AuroraParams: "charset=utf8mb4&parseTime=true"}
+	if _, err := ConnectAurora(cfg, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("ConnectAurora() should return the open error")
+	}
+
+	// -aurora-params is merged after the IAM parameters, parseTime=true is kept once
+	for _, want := range []string{"?parseTime=true&", "charset=utf8mb4", "tls=true", "allowCleartextPasswords=true"} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("DSN = %q, want it to contain %q", dsn, want)
+		}
+	}
+	if n := strings.Count(dsn, "parseTime"); n != 1 {
+		t.Errorf("DSN = %q has parseTime %d times, want once", dsn, n)
+	}
+}
+
+func TestConnectAurora_PasswordFallback(t *testing.T) {
+	origResolve, origOpen := resolveAuroraPassword, openAuroraClient
+	defer func() { resolveAuroraPassword, openAuroraClient = origResolve, origOpen }()
+
+	resolveAuroraPassword = func(secretName, region string, attempts int) (string, error) {
+		return "", errors.New("dial tcp: lookup secretsmanager.us-east-1.amazonaws.com: i/o timeout")
+	}
+	var passwords []string
+	openAuroraClient = func(hostname, user, pwd string, timeout int, dbType, dbName, params string) (*store.SQLClient, error) {
+		passwords = append(passwords, pwd)
+		return nil, errors.New("not connecting in tests")
+	}
+
+	// Without a fallback the Secrets Manager error stops the SQL phase before connecting
+	cfg := &config.Config{AuroraHost: "aurora.example.com", AuroraUser: "loader", AuroraRegion: "us-east-1",
+		AuroraSecretsManagerSecret: "rds!cluster-1", AuroraAuthMode: "secretsmanager"}
+	if _, err := ConnectAurora(cfg, zaptest.NewLogger(t)); err == nil || !strings.Contains(err.Error(), "Secrets Manager") {
+		t.Fatalf("ConnectAurora() error = %v, want the Secrets Manager error", err)
+	}
+	if len(passwords) != 0 {
+		t.Fatalf("connected with %v, want no connection attempt", passwords)
+	}
+
+	// With -aurora-password (flag or FIS_MIGRATION_AURORA_PASSWORD) the connection proceeds with it
+	cfg.AuroraPassword = "fallback-pwd"
+	core, logs := observer.New(zap.WarnLevel)
+	if _, err := ConnectAurora(cfg, zap.New(core)); err == nil || !strings.Contains(err.Error(), "not connecting in tests") {
+		t.Fatalf("ConnectAurora() error = %v, want it to get as far as opening the client", err)
+	}
+	if fmt.Sprint(passwords) != "[fallback-pwd]" {
+		t.Errorf("connected with %v, want the fallback password", passwords)
+	}
+	if logs.FilterMessage("Failed to get Aurora password from Secrets Manager, falling back to -aurora-password").Len() != 1 {
+		t.Error("expected a warning about the fallback")
+	}
+
+	// Secrets Manager stays the preferred source
+	resolveAuroraPassword = func(secretName, region string, attempts int) (string, error) { return "secret-pwd", nil }
+	passwords = nil
+	if _, err := ConnectAurora(cfg, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("ConnectAurora() should return the open error")
+	}
+	if fmt.Sprint(passwords) != "[secret-pwd]" {
+		t.Errorf("connected with %v, want the Secrets Manager password", passwords)
+	}
+}
+
+func TestExecuteLoadDataSQL_StatementTimeout(t *testing.T) {
+	statements := []string{"statement 1", "statement 2", "statement 3"}
+	server := &fakeAurora{attempts: make(map[int]int), slow: map[int]bool{2: true}}
+	cfg := &config.Config{SQLExecTimeout: 60, SQLStatementTimeout: 1, SQLReconnectRetries: 3}
+
+	err := executeLoadDataSQL(statements, cfg, nil, server.connect, zaptest.NewLogger(t))
+	if err == nil {
+		t.Fatal("executeLoadDataSQL() should report the timed out statement")
+	}
+	// The slow statement is cancelled, not retried, and the others still run
+	if fmt.Sprint(server.executed) != "[statement 1 statement 3]" {
+		t.Errorf("executed = %v, want statements 1 and 3", server.executed)
+	}
+	if server.attempts[2] != 1 || server.connects != 1 {
+		t.Errorf("timed out statement attempts = %d, connects = %d, want 1 and 1", server.attempts[2], server.connects)
+	}
+}
+
+func TestExecuteLoadDataSQL_TotalTimeout(t *testing.T) {
+	statements := []string{"statement 1", "statement 2", "statement 3"}
+	server := &fakeAurora{attempts: make(map[int]int), slow: map[int]bool{2: true}}
+	cfg := &config.Config{SQLExecTimeout: 60, SQLTotalTimeout: 1}
+
+	err := executeLoadDataSQL(statements, cfg, nil, server.connect, zaptest.NewLogger(t))
+	if err == nil || !strings.Contains(err.Error(), "sql-total-timeout") {
+		t.Fatalf("executeLoadDataSQL() error = %v, want the total timeout", err)
+	}
+	if fmt.Sprint(server.executed) != "[statement 1]" || server.attempts[3] != 0 {
+		t.Errorf("executed = %v, statement 3 attempts = %d, want only statement 1 run", server.executed, server.attempts[3])
+	}
+
+	// A transaction cut short by the total timeout is still rolled back
+	server = &fakeAurora{attempts: make(map[int]int), slow: map[int]bool{2: true}}
+	if err := executeTransactional(statements, cfg, &fakeAuroraConn{server: server}, nil, zaptest.NewLogger(t)); err == nil {
+		t.Fatal("executeTransactional() should fail when the total timeout expires")
+	}
+	if server.inTx || len(server.executed) != 0 {
+		t.Errorf("expected a rolled back transaction, got inTx=%v executed=%v", server.inTx, server.executed)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -58,8 +59,14 @@ func (sc *SQLClient) Ping() error {
 }
 
 func NewSQLClient(hostname, user, pwd string, timeout int, dbType, dbName string) (*SQLClient, error) {
+	return NewSQLClientWithParams(hostname, user, pwd, timeout, dbType, dbName, "")
+}
+
+// BuildDSN returns the driver DSN for dbType, with params (a query string, e.g. "tls=true&charset=utf8mb4")
+// merged into the defaults. parseTime=true is always kept.
+func BuildDSN(hostname, user, pwd, dbType, dbName, params string) (string, error) {
 	if hostname == "" {
-		return nil, ErrBadHostname
+		return "", ErrBadHostname
 	}
 
 	if dbType == "" {
@@ -90,7 +97,31 @@ func NewSQLClient(hostname, user, pwd string, timeout int, dbType, dbName string
 			dsn = user + "@" + dsn
 		}
 	default:
-		return nil, fmt.Errorf("unsupported database type: %s (must be mp-mariadb or aws-aurora)", dbType)
+		return "", fmt.Errorf("unsupported database type: %s (must be mp-mariadb or aws-aurora)", dbType)
+	}
+
+	if params != "" {
+		values, err := url.ParseQuery(params)
+		if err != nil {
+			return "", fmt.Errorf("invalid DSN parameters %q: %w", params, err)
+		}
+		values.Del("parseTime") // Always parseTime=true, rows are scanned into time.Time
+		if len(values) > 0 {
+			dsn += "&" + values.Encode()
+		}
+	}
+	return dsn, nil
+}
+
+// NewSQLClientWithParams is NewSQLClient with extra DSN parameters.
+func NewSQLClientWithParams(hostname, user, pwd string, timeout int, dbType, dbName, params string) (*SQLClient, error) {
+	dsn, err := BuildDSN(hostname, user, pwd, dbType, dbName, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbType == "" {
+		dbType = "mp-mariadb"
 	}
 
 	db, err := sql.Open(dbDriver, dsn)
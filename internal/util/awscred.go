@@ -5,13 +5,16 @@ package util
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
 )
 
 // AWS IAM credential file paths (vault-injected for Aurora MySQL)
@@ -75,9 +78,19 @@ func LoadAWSCredentials(accessKeyID, secretAccessKey, sessionToken string) {
 	}
 }
 
-// GetPasswordFromSecretsManager retrieves the database password from AWS Secrets Manager.
+// secretsRetryBackoff is the delay before the first Secrets Manager retry, doubled after each attempt (replaced in tests).
+var secretsRetryBackoff = 1 * time.Second
+
+// SecretValueGetter fetches secret values.
+// This allows mocking in tests.
+type SecretValueGetter interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// GetPasswordFromSecretsManager retrieves the database password from AWS Secrets Manager,
+// making up to attempts calls while the error is retryable (throttling, service or network errors).
 // The secret JSON is expected to contain a "password" field.
-func GetPasswordFromSecretsManager(secretName, region string) (string, error) {
+func GetPasswordFromSecretsManager(secretName, region string, attempts int) (string, error) {
 	if secretName == "" {
 		return "", fmt.Errorf("secret name is required for Secrets Manager")
 	}
@@ -93,14 +106,41 @@ func GetPasswordFromSecretsManager(secretName, region string) (string, error) {
 		return "", fmt.Errorf("create AWS config: %w", err)
 	}
 
-	svc := secretsmanager.NewFromConfig(awsCfg)
-	out, err := svc.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
-		SecretId:     aws.String(secretName),
-		VersionStage: aws.String("AWSCURRENT"),
+	// Retries are done by getSecretPassword, which knows which errors are permanent
+	svc := secretsmanager.NewFromConfig(awsCfg, func(o *secretsmanager.Options) {
+		o.RetryMaxAttempts = 1
 	})
-	if err != nil {
-		return "", fmt.Errorf("get secret value: %w", err)
+	return getSecretPassword(ctx, svc, secretName, attempts)
+}
+
+// getSecretPassword reads the "password" field of secretName, retrying retryable errors with
+// exponential backoff. Permanent errors (e.g. the secret doesn't exist) are returned immediately.
+func getSecretPassword(ctx context.Context, svc SecretValueGetter, secretName string, attempts int) (string, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var out *secretsmanager.GetSecretValueOutput
+	delay := secretsRetryBackoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		out, err = svc.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId:     aws.String(secretName),
+			VersionStage: aws.String("AWSCURRENT"),
+		})
+		if err == nil {
+			break
+		}
+		if !isRetryableSecretsError(err) {
+			return "", permanentSecretsError(secretName, err)
+		}
+		if attempt >= attempts {
+			return "", fmt.Errorf("get secret value: still failing after %d attempts: %w", attempts, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
 	}
+
 	if out.SecretString == nil {
 		return "", fmt.Errorf("secret string empty for %s", secretName)
 	}
@@ -118,12 +158,41 @@ func GetPasswordFromSecretsManager(secretName, region string) (string, error) {
 	return payload.Password, nil
 }
 
+// isRetryableSecretsError reports whether a GetSecretValue error is transient: throttling, an internal
+// service error, or a failure to reach the service at all. Other API errors are permanent.
+func isRetryableSecretsError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return true // No response from the service (network error, timeout)
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded",
+		"InternalServiceError", "InternalFailure", "ServiceUnavailable":
+		return true
+	}
+	return false
+}
+
+// permanentSecretsError explains a non-retryable GetSecretValue error.
+func permanentSecretsError(secretName string, err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ResourceNotFoundException":
+			return fmt.Errorf("secret %s does not exist (check the secret name and region): %w", secretName, err)
+		case "AccessDeniedException":
+			return fmt.Errorf("access to secret %s denied (check the IAM policy): %w", secretName, err)
+		}
+	}
+	return fmt.Errorf("get secret value: %w", err)
+}
+
 // ResolveAWSDBPassword returns the AWS DB password. If AWSSQLPasswordEnv is set
 // (even to an empty string), that value is returned. Otherwise, the password is
-// fetched from AWS Secrets Manager using the provided secret and region.
-func ResolveAWSDBPassword(secretName, region string) (string, error) {
+// fetched from AWS Secrets Manager using the provided secret and region, in up to attempts calls.
+func ResolveAWSDBPassword(secretName, region string, attempts int) (string, error) {
 	if pwd, ok := os.LookupEnv(AWSSQLPasswordEnv); ok {
 		return pwd, nil
 	}
-	return GetPasswordFromSecretsManager(secretName, region)
+	return GetPasswordFromSecretsManager(secretName, region, attempts)
 }
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
+)
+
+// fakeSecretsManager returns the queued errors, then the secret
+type fakeSecretsManager struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"password": "s3cret"}`)}, nil
+}
+
+func TestGetSecretPassword_Retry(t *testing.T) {
+	orig := secretsRetryBackoff
+	secretsRetryBackoff = time.Millisecond
+	defer func() { secretsRetryBackoff = orig }()
+
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	notFound := &smithy.GenericAPIError{Code: "ResourceNotFoundException", Message: "Secrets Manager can't find the specified secret."}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantCalls int
+		wantErr   string
+	}{
+		{"throttled twice then succeeds", []error{throttled, throttled}, 5, 3, ""},
+		{"network error then succeeds", []error{errors.New("connection reset")}, 5, 2, ""},
+		{"throttled past the attempts", []error{throttled, throttled, throttled}, 3, 3, "still failing after 3 attempts"},
+		{"not found is permanent", []error{notFound}, 5, 1, "secret fis/aurora does not exist"},
+		{"access denied is permanent", []error{&smithy.GenericAPIError{Code: "AccessDeniedException"}}, 5, 1, "access to secret fis/aurora denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeSecretsManager{errs: tt.errs}
+			password, err := getSecretPassword(context.Background(), svc, "fis/aurora", tt.attempts)
+			if svc.calls != tt.wantCalls {
+				t.Errorf("GetSecretValue called %d times, want %d", svc.calls, tt.wantCalls)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("getSecretPassword() error = %v", err)
+				}
+				if password != "s3cret" {
+					t.Errorf("getSecretPassword() = %q, want s3cret", password)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("getSecretPassword() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const (
+	// rdsAuthTokenExpiry is how long an RDS IAM auth token is accepted for new connections
+	rdsAuthTokenExpiry = 15 * time.Minute
+	// emptyPayloadHash is the SHA-256 of an empty body, signed into presigned requests
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// BuildRDSAuthToken builds an RDS IAM database auth token for dbUser at endpoint (host:port).
+// The token is a SigV4-presigned "connect" request for the rds-db service, as built by
+// feature/rds/auth.BuildAuthToken, and is used as the MySQL password over TLS.
+func BuildRDSAuthToken(ctx context.Context, endpoint, region, dbUser string, creds aws.CredentialsProvider) (string, error) {
+	if !strings.Contains(endpoint, ":") {
+		return "", fmt.Errorf("endpoint %q must include a port", endpoint)
+	}
+	if region == "" {
+		return "", fmt.Errorf("region is required for an RDS auth token")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://"+endpoint+"/", nil)
+	if err != nil {
+		return "", fmt.Errorf("build auth token request: %w", err)
+	}
+	values := req.URL.Query()
+	values.Set("Action", "connect")
+	values.Set("DBUser", dbUser)
+	values.Set("X-Amz-Expires", strconv.Itoa(int(rdsAuthTokenExpiry/time.Second)))
+	req.URL.RawQuery = values.Encode()
+
+	credentials, err := creds.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("retrieve AWS credentials: %w", err)
+	}
+	signed, _, err := v4.NewSigner().PresignHTTP(ctx, credentials, req, emptyPayloadHash, "rds-db", region, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("sign auth token: %w", err)
+	}
+	return strings.TrimPrefix(signed, "https://"), nil
+}
+
+// GenerateRDSAuthToken builds an RDS IAM auth token using the AWS SDK default credential chain.
+func GenerateRDSAuthToken(endpoint, region, dbUser string) (string, error) {
+	ctx := context.Background()
+	awsCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+	)
+	if err != nil {
+		return "", fmt.Errorf("create AWS config: %w", err)
+	}
+	return BuildRDSAuthToken(ctx, endpoint, region, dbUser, awsCfg.Credentials)
+}
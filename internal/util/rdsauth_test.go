@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Netskope, Inc. All rights reserved.
+
+package util
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestBuildRDSAuthToken(t *testing.T) {
+	creds := credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "")
+
+	token, err := BuildRDSAuthToken(context.Background(), "aurora.example.com:3306", "us-east-1", "loader", creds)
+	if err != nil {
+		t.Fatalf("BuildRDSAuthToken() error = %v", err)
+	}
+	if !strings.HasPrefix(token, "aurora.example.com:3306/?") {
+		t.Fatalf("token %q should start with the endpoint and no scheme", token)
+	}
+
+	query, err := url.ParseQuery(token[strings.Index(token, "?")+1:])
+	if err != nil {
+		t.Fatalf("failed to parse token query: %v", err)
+	}
+	for key, want := range map[string]string{
+		"Action":          "connect",
+		"DBUser":          "loader",
+		"X-Amz-Expires":   "900",
+		"X-Amz-Algorithm": "AWS4-HMAC-SHA256",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if !strings.Contains(query.Get("X-Amz-Credential"), "/us-east-1/rds-db/aws4_request") {
+		t.Errorf("X-Amz-Credential = %q, want an rds-db scope", query.Get("X-Amz-Credential"))
+	}
+	if query.Get("X-Amz-Signature") == "" {
+		t.Error("token is not signed")
+	}
+
+	if _, err := BuildRDSAuthToken(context.Background(), "aurora.example.com", "us-east-1", "loader", creds); err == nil {
+		t.Error("BuildRDSAuthToken() should require a port")
+	}
+}
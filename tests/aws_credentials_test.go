@@ -1,9 +1,11 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,7 +18,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	_ "github.com/go-sql-driver/mysql"
+	fisconfig "github.com/netSkope/fis-migration-tool/internal/config"
+	"github.com/netSkope/fis-migration-tool/internal/exporter"
+	"github.com/netSkope/fis-migration-tool/internal/migration"
+	fiss3 "github.com/netSkope/fis-migration-tool/internal/s3"
+	"github.com/netSkope/fis-migration-tool/internal/segment"
 	"github.com/testcontainers/testcontainers-go/modules/compose"
+	"go.uber.org/zap/zaptest"
 )
 
 // detectReaperIssue checks if we need to disable the testcontainers reaper
@@ -806,6 +814,653 @@ func Test12IntegrationFullFlow(t *testing.T) {
 	}
 }
 
+// Test 13: S3 object tagging and storage class
+func Test13S3TagsAndStorageClass(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Skip("Requires MariaDB running")
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	prefix := "fis-migration-tagged"
+	args := []string{
+		migrationBin,
+		"-aws-access-key-id", "test",
+		"-aws-secret-access-key", "test",
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-s3-bucket", testBucket,
+		"-s3-prefix", prefix,
+		"-s3-tags", "team=fis,purpose=migration",
+		"-s3-storage-class", "STANDARD_IA",
+		"-aws-region", "us-east-1",
+		"-segments", "1",
+		"-max-parallel-segments", "1",
+		"-quiet",
+	}
+
+	output, exitCode, _ := runMigration(args)
+	if exitCode != 0 {
+		t.Fatalf("Test 13: FAILED - Migration command failed: %s", firstLine(output))
+	}
+
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	ctx := context.Background()
+
+	// Check both a streamed CSV object and the SQL file uploaded via the manager
+	keys := []string{fmt.Sprintf("%s/sql/load-data-tenant-%s.sql", prefix, testTenantID)}
+	listed, err := svc.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(testBucket),
+		Prefix: aws.String(fmt.Sprintf("%s/tenant-%s/", prefix, testTenantID)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	if len(listed.Contents) > 0 {
+		keys = append(keys, aws.ToString(listed.Contents[0].Key))
+	}
+
+	for _, key := range keys {
+		tagging, err := svc.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket: aws.String(testBucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			t.Fatalf("Failed to get tags for %s: %v", key, err)
+		}
+		tags := map[string]string{}
+		for _, tag := range tagging.TagSet {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		if tags["team"] != "fis" || tags["purpose"] != "migration" {
+			t.Errorf("Unexpected tags on %s: %v", key, tags)
+		}
+
+		head, err := svc.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(testBucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			t.Fatalf("Failed to head %s: %v", key, err)
+		}
+		if head.StorageClass != "STANDARD_IA" {
+			t.Errorf("Expected storage class STANDARD_IA on %s, got %q", key, head.StorageClass)
+		}
+	}
+
+	t.Logf("✅ Test 13: S3 Tags and Storage Class: PASSED - Verified %d object(s)", len(keys))
+}
+
+// Test 14: -verify-sample re-downloads and parses uploaded CSV objects
+func Test14VerifySample(t *testing.T) {
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	ctx := context.Background()
+
+	seg := segment.Segment{Index: 0, StartHex: "00", EndHex: "80"}
+	header := "tenantid,hash,aggr,last_modified,version\n"
+	rows := "1016,00abc,\"{\"\"k\"\": \"\"v\"\"}\",2024-01-01 00:00:00,1\n1016,7fdef,{},\\N,\\N\n"
+	objects := map[string]string{
+		"fis-migration-sample/good.csv":     header + rows,
+		"fis-migration-sample/reversed.csv": rows + header, // Parts completed in the wrong order
+	}
+	for key, body := range objects {
+		if _, err := svc.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(testBucket),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(body),
+		}); err != nil {
+			t.Fatalf("Failed to upload %s: %v", key, err)
+		}
+	}
+
+	logger := zaptest.NewLogger(t)
+	uploader, err := fiss3.NewUploader(&fisconfig.Config{
+		S3Bucket:           testBucket,
+		AWSRegion:          "us-east-1",
+		AWSAccessKeyID:     "test",
+		AWSSecretAccessKey: "test",
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+
+	data, err := uploader.GetObjectRange("fis-migration-sample/good.csv", 0, int64(len(header))-1)
+	if err != nil {
+		t.Fatalf("GetObjectRange() error = %v", err)
+	}
+	if string(data) != header {
+		t.Errorf("GetObjectRange() = %q, want the header %q", data, header)
+	}
+
+	sampleCfg := &fisconfig.Config{TenantID: 1016, CSVHeader: true}
+	report, err := migration.VerifyS3Sample([]exporter.CSVFile{
+		{S3Key: "fis-migration-sample/good.csv", Segment: seg, RowCount: 2},
+	}, 1, uploader, sampleCfg, logger)
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Failed) != 0 || report.Objects[0].Rows != 2 {
+		t.Fatalf("Test 14: FAILED - expected 2 well-formed rows, got %+v", report.Objects)
+	}
+
+	report, err = migration.VerifyS3Sample([]exporter.CSVFile{
+		{S3Key: "fis-migration-sample/reversed.csv", Segment: seg, RowCount: 2},
+	}, 1, uploader, sampleCfg, logger)
+	if err != nil {
+		t.Fatalf("VerifyS3Sample() error = %v", err)
+	}
+	if len(report.Failed) != 1 {
+		t.Fatalf("Test 14: FAILED - expected the out-of-order object to fail, got %+v", report.Objects)
+	}
+
+	t.Log("✅ Test 14: S3 Sample Verify: PASSED")
+}
+
+// Test 15: Preflight - a missing bucket is reported while MariaDB passes
+func Test15PreflightBadBucket(t *testing.T) {
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Fatal("MariaDB not available")
+	}
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	cfg := &fisconfig.Config{
+		TenantID:           1016,
+		TableName:          "fis_aggr",
+		MariaDBHost:        mariadbHost,
+		MariaDBUser:        "fis",
+		MariaDBPassword:    "testpass",
+		MariaDBDatabase:    "fis",
+		S3Bucket:           "fis-migration-missing-bucket",
+		S3Prefix:           "fis-migration",
+		AWSRegion:          "us-east-1",
+		AWSAccessKeyID:     "test",
+		AWSSecretAccessKey: "test",
+	}
+	report := migration.Preflight(cfg, zaptest.NewLogger(t))
+
+	failed := report.Failed()
+	if len(report.Checks) != 2 || len(failed) != 1 || failed[0].Name != "s3" {
+		t.Fatalf("Test 15: FAILED - expected only the s3 check to fail, got %+v", report.Checks)
+	}
+
+	cfg.S3Bucket = testBucket
+	if err := migration.Preflight(cfg, zaptest.NewLogger(t)).Err(); err != nil {
+		t.Fatalf("Test 15: FAILED - preflight against the test bucket: %v", err)
+	}
+
+	t.Log("✅ Test 15: Preflight: PASSED")
+}
+
+// Test 16: Exit Codes - failure categories the compose stack can simulate
+func Test16ExitCodes(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Fatal("MariaDB not available")
+	}
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+	os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	os.Setenv("FIS_AWS_SQL_PASSWORD", "test")
+	defer os.Unsetenv("FIS_AWS_SQL_PASSWORD")
+
+	base := []string{
+		migrationBin,
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-aws-region", "us-east-1",
+		"-segments", "4",
+		"-quiet",
+	}
+	// Nothing listens on port 1, so Aurora is unreachable
+	aurora := []string{"-execute-sql", "-aurora-host", "127.0.0.1", "-aurora-port", "1", "-aurora-user", "loader",
+		"-aurora-secret", "fis/aurora", "-aurora-region", "us-east-1"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{"missing bucket", []string{"-s3-bucket", "fis-migration-missing-bucket"}, 4},
+		{"Aurora unreachable at preflight", append([]string{"-s3-bucket", testBucket}, aurora...), 6},
+		{"LOAD DATA fails", append([]string{"-s3-bucket", testBucket, "-skip-preflight", "-check-schema=false"}, aurora...), 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{}, base...), tt.args...)
+			output, exitCode, _ := runMigration(args)
+			if exitCode != tt.wantCode {
+				t.Fatalf("Test 16: FAILED - exit code %d, want %d; output:\n%s", exitCode, tt.wantCode, output)
+			}
+			if want := fmt.Sprintf("Exit code: %d", tt.wantCode); !strings.Contains(output, want) {
+				t.Errorf("Test 16: FAILED - output should include %q", want)
+			}
+		})
+	}
+
+	t.Log("✅ Test 16: Exit Codes: PASSED")
+}
+
+// Test 17: -s3-endpoint and -s3-path-style without AWS_ENDPOINT_URL
+func Test17S3EndpointFlag(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Fatal("MariaDB not available")
+	}
+
+	os.Unsetenv("AWS_ENDPOINT_URL")
+	defer os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	args := []string{
+		migrationBin,
+		"-aws-access-key-id", "test",
+		"-aws-secret-access-key", "test",
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-s3-bucket", testBucket,
+		"-s3-endpoint", localstackEndpoint,
+		"-s3-path-style",
+		"-aws-region", "us-east-1",
+		"-segments", "4",
+		"-max-parallel-segments", "1",
+		"-quiet",
+	}
+
+	output, exitCode, _ := runMigration(args)
+	if exitCode != 0 {
+		t.Fatalf("Test 17: FAILED - Migration failed with exit code %d: %s", exitCode, output)
+	}
+	if count := countS3Files(localstackEndpoint, testBucket); count == 0 {
+		t.Fatalf("Test 17: FAILED - no objects uploaded to %s via -s3-endpoint", testBucket)
+	}
+
+	t.Log("✅ Test 17: S3 Endpoint Flag: PASSED")
+}
+
+// Test 18: Resume Uploads - a multipart upload left with two parts is resumed to completion
+func Test18ResumeUploads(t *testing.T) {
+	cleanupTest()
+
+	ctx := context.Background()
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	logger := zaptest.NewLogger(t)
+	cfg := &fisconfig.Config{
+		S3Bucket:           testBucket,
+		AWSRegion:          "us-east-1",
+		AWSAccessKeyID:     "test",
+		AWSSecretAccessKey: "test",
+		ResumeUploads:      true,
+		LogDir:             t.TempDir(),
+	}
+	key := "fis-migration/resume/tenant-1016.fis_aggr.csv"
+	// Every part but the last must be at least 5MB
+	parts := [][]byte{
+		bytes.Repeat([]byte("1016,00abc,{},2024-01-01 00:00:00,1\n"), 150000),
+		bytes.Repeat([]byte("1016,7fdef,{},2024-01-01 00:00:00,1\n"), 150000),
+		[]byte("1016,ffeee,{},\\N,\\N\n"),
+	}
+
+	// First run: two parts upload, then the run fails
+	uploader, err := fiss3.NewUploader(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	stream, err := uploader.NewMultipartUploadStream(key)
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	for _, part := range parts[:2] {
+		if err := stream.UploadPart(part); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	stream.Abort() // Kept for resume
+
+	uploads, err := svc.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{Bucket: aws.String(testBucket), Prefix: aws.String(key)})
+	if err != nil {
+		t.Fatalf("ListMultipartUploads() error = %v", err)
+	}
+	if len(uploads.Uploads) != 1 {
+		t.Fatalf("Test 18: FAILED - expected the failed upload to be kept, got %d uploads", len(uploads.Uploads))
+	}
+
+	// Re-run: resumes the same upload and completes it
+	uploader, err = fiss3.NewUploader(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	stream, err = uploader.NewMultipartUploadStream(key)
+	if err != nil {
+		t.Fatalf("NewMultipartUploadStream() error = %v", err)
+	}
+	for _, part := range parts {
+		if err := stream.UploadPart(part); err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+	}
+	if err := stream.Complete(); err != nil {
+		t.Fatalf("Test 18: FAILED - Complete() error = %v", err)
+	}
+
+	obj, err := svc.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(testBucket), Key: aws.String(key)})
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	defer obj.Body.Close()
+	data, err := io.ReadAll(obj.Body)
+	if err != nil {
+		t.Fatalf("Failed to read object: %v", err)
+	}
+	if !bytes.Equal(data, bytes.Join(parts, nil)) {
+		t.Fatalf("Test 18: FAILED - resumed object has %d bytes, want the %d bytes of all three parts", len(data), len(bytes.Join(parts, nil)))
+	}
+
+	uploads, err = svc.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{Bucket: aws.String(testBucket), Prefix: aws.String(key)})
+	if err != nil {
+		t.Fatalf("ListMultipartUploads() error = %v", err)
+	}
+	if len(uploads.Uploads) != 0 {
+		t.Errorf("Test 18: FAILED - %d multipart uploads left after completion", len(uploads.Uploads))
+	}
+	if states, _ := filepath.Glob(filepath.Join(cfg.LogDir, "upload-state", "*.json")); len(states) != 0 {
+		t.Errorf("Test 18: FAILED - upload state files left after completion: %v", states)
+	}
+
+	t.Log("✅ Test 18: Resume Uploads: PASSED")
+}
+
+// Test 19: -skip-sql-gen uploads the CSV files but no SQL file
+func Test19SkipSQLGen(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Skip("Requires MariaDB running")
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	prefix := "fis-migration-skip-sql"
+	args := []string{
+		migrationBin,
+		"-aws-access-key-id", "test",
+		"-aws-secret-access-key", "test",
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-s3-bucket", testBucket,
+		"-s3-prefix", prefix,
+		"-aws-region", "us-east-1",
+		"-segments", "1",
+		"-max-parallel-segments", "1",
+		"-skip-sql-gen",
+		"-quiet",
+	}
+
+	output, exitCode, _ := runMigration(args)
+	if exitCode != 0 {
+		t.Fatalf("Test 19: FAILED - Migration command failed: %s", firstLine(output))
+	}
+	if !strings.Contains(output, "SQL generation: Skipped (-skip-sql-gen)") {
+		t.Errorf("Test 19: FAILED - summary should note SQL generation was skipped:\n%s", output)
+	}
+
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	listed, err := svc.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(testBucket),
+		Prefix: aws.String(prefix + "/"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	csvObjects := 0
+	for _, object := range listed.Contents {
+		key := aws.ToString(object.Key)
+		if strings.HasPrefix(key, prefix+"/sql/") {
+			t.Errorf("Test 19: FAILED - SQL object %s uploaded with -skip-sql-gen", key)
+		}
+		if strings.HasSuffix(key, ".csv") {
+			csvObjects++
+		}
+	}
+	if csvObjects == 0 {
+		t.Fatalf("Test 19: FAILED - no CSV objects under %s/", prefix)
+	}
+
+	t.Logf("✅ Test 19: Skip SQL Generation: PASSED - %d CSV object(s), no SQL file", csvObjects)
+}
+
+// newLocalStackS3Client creates an S3 client for LocalStack with path-style addressing
+// Test 20: -list-orphan-objects reports an object under the tenant table prefix the run didn't produce, -prune deletes it
+func Test20ListOrphanObjects(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Skip("Requires MariaDB running")
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	prefix := "fis-migration-orphans"
+	staleKey := fmt.Sprintf("%s/tenant-%s/fis_aggr/tenant-%s.fis_aggr.hash-00-40.csv", prefix, testTenantID, testTenantID)
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	if _, err := svc.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(testBucket),
+		Key:    aws.String(staleKey),
+		Body:   strings.NewReader("stale"),
+	}); err != nil {
+		t.Fatalf("Failed to seed stale object: %v", err)
+	}
+
+	args := []string{
+		migrationBin,
+		"-aws-access-key-id", "test",
+		"-aws-secret-access-key", "test",
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-s3-bucket", testBucket,
+		"-s3-prefix", prefix,
+		"-aws-region", "us-east-1",
+		"-segments", "1",
+		"-max-parallel-segments", "1",
+		"-list-orphan-objects",
+		"-quiet",
+	}
+
+	output, exitCode, _ := runMigration(args)
+	if exitCode != 0 {
+		t.Fatalf("Test 20: FAILED - Migration command failed: %s", firstLine(output))
+	}
+	if !strings.Contains(output, "Orphan objects: 1 of") || !strings.Contains(output, staleKey) {
+		t.Fatalf("Test 20: FAILED - stale object %s should be reported as orphan:\n%s", staleKey, output)
+	}
+	if _, err := svc.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String(testBucket), Key: aws.String(staleKey)}); err != nil {
+		t.Errorf("Test 20: FAILED - orphan deleted without -prune: %v", err)
+	}
+
+	output, exitCode, _ = runMigration(append(args, "-prune"))
+	if exitCode != 0 {
+		t.Fatalf("Test 20: FAILED - Migration command with -prune failed: %s", firstLine(output))
+	}
+	if !strings.Contains(output, "deleted (-prune)") {
+		t.Errorf("Test 20: FAILED - summary should note the orphan was deleted:\n%s", output)
+	}
+	if _, err := svc.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String(testBucket), Key: aws.String(staleKey)}); err == nil {
+		t.Errorf("Test 20: FAILED - orphan %s still exists after -prune", staleKey)
+	}
+
+	// The run's own CSV files are kept
+	listed, err := svc.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(testBucket),
+		Prefix: aws.String(fmt.Sprintf("%s/tenant-%s/fis_aggr/", prefix, testTenantID)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	if len(listed.Contents) == 0 {
+		t.Fatalf("Test 20: FAILED - -prune deleted the run's CSV files")
+	}
+
+	t.Logf("✅ Test 20: List Orphan Objects: PASSED - stale object reported, then pruned")
+}
+
+// Test 21: -print-sql -quiet writes only the LOAD DATA statements to stdout
+func Test21PrintSQL(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Skip("Requires MariaDB running")
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+
+	cmd := exec.Command(migrationBin,
+		"-aws-access-key-id", "test",
+		"-aws-secret-access-key", "test",
+		"-tenant-id", testTenantID,
+		"-mariadb-host", mariadbHost,
+		"-mariadb-user", "fis",
+		"-mariadb-password", "testpass",
+		"-mariadb-database", "fis",
+		"-s3-bucket", testBucket,
+		"-s3-prefix", "fis-migration-print-sql",
+		"-aws-region", "us-east-1",
+		"-segments", "2",
+		"-max-parallel-segments", "1",
+		"-print-sql",
+		"-quiet",
+	)
+	cmd.Env = os.Environ()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Test 21: FAILED - Migration command failed: %v: %s", err, firstLine(stderr.String()))
+	}
+
+	// Every blank-line separated chunk must be a complete statement, nothing else may be mixed in
+	chunks := strings.Split(strings.TrimSpace(string(stdout)), "\n\n")
+	if len(stdout) == 0 {
+		t.Fatalf("Test 21: FAILED - no statements on stdout")
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, fmt.Sprintf("LOAD DATA FROM S3 's3://%s/", testBucket)) || !strings.HasSuffix(chunk, ";") {
+			t.Errorf("Test 21: FAILED - stdout holds something other than a LOAD DATA FROM S3 statement:\n%s", chunk)
+		}
+	}
+
+	t.Logf("✅ Test 21: Print SQL: PASSED - stdout holds only the LOAD DATA statements")
+}
+
+// Test 22: uploaded objects carry a Content-Type and, when compressed, a Content-Encoding
+func Test22ContentHeaders(t *testing.T) {
+	cleanupTest()
+
+	if !checkMariaDBAvailable(mariadbHost) {
+		t.Skip("Requires MariaDB running")
+	}
+
+	os.Setenv("AWS_ENDPOINT_URL", localstackEndpoint)
+	svc := newLocalStackS3Client(t, localstackEndpoint)
+	ctx := context.Background()
+
+	tests := []struct {
+		prefix  string
+		args    []string
+		csvType string
+	}{
+		{"fis-migration-content", nil, "text/csv"},
+		{"fis-migration-content-type", []string{"-content-type", "text/csv; charset=utf-8"}, "text/csv; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		args := append([]string{
+			migrationBin,
+			"-aws-access-key-id", "test",
+			"-aws-secret-access-key", "test",
+			"-tenant-id", testTenantID,
+			"-mariadb-host", mariadbHost,
+			"-mariadb-user", "fis",
+			"-mariadb-password", "testpass",
+			"-mariadb-database", "fis",
+			"-s3-bucket", testBucket,
+			"-s3-prefix", tt.prefix,
+			"-aws-region", "us-east-1",
+			"-segments", "1",
+			"-max-parallel-segments", "1",
+			"-compress", "gzip",
+			"-quiet",
+		}, tt.args...)
+		output, exitCode, _ := runMigration(args)
+		if exitCode != 0 {
+			t.Fatalf("Test 22: FAILED - Migration command failed: %s", firstLine(output))
+		}
+
+		listed, err := svc.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket: aws.String(testBucket),
+			Prefix: aws.String(fmt.Sprintf("%s/tenant-%s/", tt.prefix, testTenantID)),
+		})
+		if err != nil {
+			t.Fatalf("Failed to list objects: %v", err)
+		}
+		if len(listed.Contents) == 0 {
+			t.Fatalf("Test 22: FAILED - no CSV object under %s", tt.prefix)
+		}
+
+		// The streamed CSV object is gzip, the SQL file uploaded via the manager is plain
+		want := map[string][2]string{
+			aws.ToString(listed.Contents[0].Key):                                   {tt.csvType, "gzip"},
+			fmt.Sprintf("%s/sql/load-data-tenant-%s.sql", tt.prefix, testTenantID): {"application/sql", ""},
+		}
+		for key, headers := range want {
+			head, err := svc.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(testBucket),
+				Key:    aws.String(key),
+			})
+			if err != nil {
+				t.Fatalf("Failed to head %s: %v", key, err)
+			}
+			if aws.ToString(head.ContentType) != headers[0] || aws.ToString(head.ContentEncoding) != headers[1] {
+				t.Errorf("Test 22: FAILED - %s has Content-Type %q and Content-Encoding %q, want %q and %q",
+					key, aws.ToString(head.ContentType), aws.ToString(head.ContentEncoding), headers[0], headers[1])
+			}
+		}
+	}
+
+	t.Logf("✅ Test 22: Content Headers: PASSED - CSV and SQL objects carry their Content-Type and Content-Encoding")
+}
+
+func newLocalStackS3Client(t *testing.T, endpoint string) *s3.Client {
+	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
+		config.WithBaseEndpoint(endpoint),
+	)
+	if err != nil {
+		t.Fatalf("Failed to load AWS config: %v", err)
+	}
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+	})
+}
+
 func countS3Files(endpoint, bucket string) int {
 	ctx := context.Background()
 	awsCfg, err := config.LoadDefaultConfig(ctx,